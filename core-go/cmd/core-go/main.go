@@ -16,7 +16,11 @@ import (
 	"roller_hoops/core-go/internal/db"
 	"roller_hoops/core-go/internal/discoveryworker"
 	"roller_hoops/core-go/internal/httpapi"
+	"roller_hoops/core-go/internal/identity"
 	"roller_hoops/core-go/internal/metrics"
+	"roller_hoops/core-go/internal/monitor"
+	"roller_hoops/core-go/internal/retention"
+	"roller_hoops/core-go/internal/tagging"
 )
 
 func main() {
@@ -40,36 +44,104 @@ func main() {
 		pool = p
 	}
 
+	macIdentity, err := parseMACIdentity(
+		envOr("DISCOVERY_MAC_ROTATION_POLICY", identity.PolicyRotationAware),
+		envOr("DISCOVERY_MAC_ROTATION_CIDRS", ""),
+		envOrDuration("DISCOVERY_MAC_ROTATION_REUSE_WINDOW", identity.DefaultReuseWindow),
+	)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid MAC rotation policy")
+	}
+
+	retentionDefaults, err := retentionDefaultsFromEnv()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid retention policy")
+	}
+	retentionEnabled := envOrBool("RETENTION_ENABLED", false)
+
+	monitorTags, err := parseMonitorTags(envOr("MONITOR_TAGS", ""))
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid MONITOR_TAGS")
+	}
+	monitorMethod := strings.ToLower(strings.TrimSpace(envOr("MONITOR_DEFAULT_METHOD", "icmp")))
+	if monitorMethod != "icmp" && monitorMethod != "tcp" {
+		logger.Fatal().Str("method", monitorMethod).Msg("MONITOR_DEFAULT_METHOD must be icmp or tcp")
+	}
+
 	if pool != nil {
 		opts := discoveryworker.Options{
-			PollInterval:          envOrDuration("DISCOVERY_POLL_INTERVAL", 400*time.Millisecond),
-			RunDelay:              envOrDuration("DISCOVERY_RUN_DELAY", 0),
-			MaxRuntime:            envOrDuration("DISCOVERY_MAX_RUNTIME", 30*time.Second),
-			ARPTablePath:          envOr("DISCOVERY_ARP_TABLE_PATH", "/proc/net/arp"),
-			MaxTargets:            envOrInt("DISCOVERY_MAX_TARGETS", 1024),
-			PingTimeout:           envOrDuration("DISCOVERY_PING_TIMEOUT", 800*time.Millisecond),
-			PingWorkers:           envOrInt("DISCOVERY_PING_WORKERS", 16),
-			EnrichMaxTargets:      envOrInt("DISCOVERY_ENRICH_MAX_TARGETS", 64),
-			EnrichWorkers:         envOrInt("DISCOVERY_ENRICH_WORKERS", 8),
-			NameResolutionEnabled: envOrBool("DISCOVERY_NAME_RESOLUTION_ENABLED", true),
-			SNMPEnabled:           envOrBool("DISCOVERY_SNMP_ENABLED", false),
-			SNMPCommunity:         envOr("DISCOVERY_SNMP_COMMUNITY", "public"),
-			SNMPVersion:           envOr("DISCOVERY_SNMP_VERSION", "2c"),
-			SNMPTimeout:           envOrDuration("DISCOVERY_SNMP_TIMEOUT", 900*time.Millisecond),
-			SNMPRetries:           envOrInt("DISCOVERY_SNMP_RETRIES", 0),
-			SNMPPort:              uint16(envOrInt("DISCOVERY_SNMP_PORT", 161)),
-			TopologyLLDPEnabled:   envOrBool("DISCOVERY_TOPOLOGY_LLDP_ENABLED", false),
-			TopologyCDPEnabled:    envOrBool("DISCOVERY_TOPOLOGY_CDP_ENABLED", false),
-			TopologyAllowlist:     envOrPrefixList("DISCOVERY_TOPOLOGY_ALLOWLIST"),
-			PortScanEnabled:       envOrBool("DISCOVERY_PORT_SCAN_ENABLED", false),
-			PortScanAllowlist:     envOrPrefixList("DISCOVERY_PORT_SCAN_ALLOWLIST"),
-			PortScanPorts:         envOrPortList("DISCOVERY_PORT_SCAN_PORTS", []int{22, 80, 443}),
-			PortScanWorkers:       envOrInt("DISCOVERY_PORT_SCAN_WORKERS", 4),
-			PortScanTimeout:       envOrDuration("DISCOVERY_PORT_SCAN_TIMEOUT", 3*time.Second),
-			PortScanMaxTargets:    envOrInt("DISCOVERY_PORT_SCAN_MAX_TARGETS", 24),
+			PollInterval:             envOrDuration("DISCOVERY_POLL_INTERVAL", 400*time.Millisecond),
+			RunDelay:                 envOrDuration("DISCOVERY_RUN_DELAY", 0),
+			MaxRuntime:               envOrDuration("DISCOVERY_MAX_RUNTIME", 30*time.Second),
+			ARPTablePath:             envOr("DISCOVERY_ARP_TABLE_PATH", "/proc/net/arp"),
+			MaxTargets:               envOrInt("DISCOVERY_MAX_TARGETS", 1024),
+			PingTimeout:              envOrDuration("DISCOVERY_PING_TIMEOUT", 800*time.Millisecond),
+			PingWorkers:              envOrInt("DISCOVERY_PING_WORKERS", 16),
+			EnrichMaxTargets:         envOrInt("DISCOVERY_ENRICH_MAX_TARGETS", 64),
+			EnrichWorkers:            envOrInt("DISCOVERY_ENRICH_WORKERS", 8),
+			NameResolutionEnabled:    envOrBool("DISCOVERY_NAME_RESOLUTION_ENABLED", true),
+			SNMPEnabled:              envOrBool("DISCOVERY_SNMP_ENABLED", false),
+			SNMPCommunity:            envOr("DISCOVERY_SNMP_COMMUNITY", "public"),
+			SNMPVersion:              envOr("DISCOVERY_SNMP_VERSION", "2c"),
+			SNMPTimeout:              envOrDuration("DISCOVERY_SNMP_TIMEOUT", 900*time.Millisecond),
+			SNMPRetries:              envOrInt("DISCOVERY_SNMP_RETRIES", 0),
+			SNMPPort:                 uint16(envOrInt("DISCOVERY_SNMP_PORT", 161)),
+			TopologyLLDPEnabled:      envOrBool("DISCOVERY_TOPOLOGY_LLDP_ENABLED", false),
+			TopologyCDPEnabled:       envOrBool("DISCOVERY_TOPOLOGY_CDP_ENABLED", false),
+			TopologyInferenceEnabled: envOrBool("DISCOVERY_TOPOLOGY_INFERENCE_ENABLED", false),
+			TopologyAllowlist:        envOrPrefixList("DISCOVERY_TOPOLOGY_ALLOWLIST"),
+			PortScanEnabled:          envOrBool("DISCOVERY_PORT_SCAN_ENABLED", false),
+			PortScanAllowlist:        envOrPrefixList("DISCOVERY_PORT_SCAN_ALLOWLIST"),
+			PortScanPorts:            envOrPortList("DISCOVERY_PORT_SCAN_PORTS", []int{22, 80, 443}),
+			PortScanWorkers:          envOrInt("DISCOVERY_PORT_SCAN_WORKERS", 4),
+			PortScanTimeout:          envOrDuration("DISCOVERY_PORT_SCAN_TIMEOUT", 3*time.Second),
+			PortScanMaxTargets:       envOrInt("DISCOVERY_PORT_SCAN_MAX_TARGETS", 24),
+			PortScanVersionDetection: envOrBool("DISCOVERY_PORT_SCAN_VERSION_DETECTION", false),
+			UDPProbeEnabled:          envOrBool("DISCOVERY_UDP_PROBE_ENABLED", false),
+			UDPProbePorts:            envOrPortList("DISCOVERY_UDP_PROBE_PORTS", nil),
+			UDPProbeTimeout:          envOrDuration("DISCOVERY_UDP_PROBE_TIMEOUT", time.Second),
+			TLSInventoryEnabled:      envOrBool("DISCOVERY_TLS_INVENTORY_ENABLED", false),
+			TLSTimeout:               envOrDuration("DISCOVERY_TLS_TIMEOUT", 3*time.Second),
+			HTTPFingerprintEnabled:   envOrBool("DISCOVERY_HTTP_FINGERPRINT_ENABLED", false),
+			HTTPFingerprintTimeout:   envOrDuration("DISCOVERY_HTTP_FINGERPRINT_TIMEOUT", 4*time.Second),
+			SSHHostKeysEnabled:       envOrBool("DISCOVERY_SSH_HOST_KEYS_ENABLED", false),
+			SSHTimeout:               envOrDuration("DISCOVERY_SSH_TIMEOUT", 5*time.Second),
+			TracerouteEnabled:        envOrBool("DISCOVERY_TRACEROUTE_ENABLED", false),
+			TracerouteMode:           envOr("DISCOVERY_TRACEROUTE_MODE", "icmp"),
+			TracerouteMaxHops:        envOrInt("DISCOVERY_TRACEROUTE_MAX_HOPS", 20),
+			TracerouteTimeout:        envOrDuration("DISCOVERY_TRACEROUTE_TIMEOUT", time.Second),
+			DuplicateAnalysisEnabled: envOrBool("DISCOVERY_DUPLICATE_ANALYSIS_ENABLED", true),
+			FactStaleAfter:           envOrDuration("DISCOVERY_FACT_STALE_AFTER", 7*24*time.Hour),
+			FactDetachAfter:          envOrDuration("DISCOVERY_FACT_DETACH_AFTER", 30*24*time.Hour),
+			AvailabilityDownAfter:    envOrDuration("DISCOVERY_AVAILABILITY_DOWN_AFTER", time.Hour),
+			MACIdentity:              macIdentity,
 		}
 		worker := discoveryworker.New(logger, pool.Queries(), opts, sharedMetrics)
 		go worker.Run(ctx)
+
+		// Retention deletes history, so it stays off until an operator opts in; the dry-run report works either way.
+		if retentionEnabled {
+			job := retention.New(logger, pool.Queries(), retention.Options{
+				Interval:  envOrDuration("RETENTION_INTERVAL", time.Hour),
+				BatchSize: envOrInt("RETENTION_BATCH_SIZE", 5000),
+				Defaults:  retentionDefaults,
+			}, sharedMetrics)
+			go job.Run(ctx)
+		}
+
+		if envOrBool("MONITOR_ENABLED", true) {
+			mon := monitor.New(logger, pool.Queries(), monitor.Options{
+				Interval:           envOrDuration("MONITOR_INTERVAL", 30*time.Second),
+				Timeout:            envOrDuration("MONITOR_TIMEOUT", time.Second),
+				Workers:            envOrInt("MONITOR_WORKERS", 16),
+				Tags:               monitorTags,
+				DefaultMethod:      monitorMethod,
+				FailuresBeforeDown: envOrInt("MONITOR_FAILURES_BEFORE_DOWN", 3),
+				RawRetention:       envOrDuration("MONITOR_RAW_RETENTION", 48*time.Hour),
+				RollupRetention:    envOrDuration("MONITOR_ROLLUP_RETENTION", 90*24*time.Hour),
+			}, sharedMetrics)
+			go mon.Run(ctx)
+		}
 	}
 
 	defaultDiscoveryScope, err := parseDiscoveryDefaultScope(envOr("DISCOVERY_DEFAULT_SCOPE", ""))
@@ -78,6 +150,10 @@ func main() {
 	}
 	h := httpapi.NewHandlerWithOptions(logger, pool, sharedMetrics, httpapi.Options{
 		DiscoveryDefaultScope: defaultDiscoveryScope,
+		PcapImportMaxBytes:    int64(envOrInt("PCAP_IMPORT_MAX_BYTES", 256<<20)),
+		MACIdentity:           macIdentity,
+		RetentionDefaults:     retentionDefaults,
+		RetentionEnabled:      retentionEnabled,
 	})
 	srv := &http.Server{
 		Addr:              addr,
@@ -215,3 +291,50 @@ func parseDiscoveryDefaultScope(raw string) (*string, error) {
 
 	return nil, fmt.Errorf("must be a CIDR prefix or a single IP (got %q)", s)
 }
+
+func parseMACIdentity(policy, cidrs string, reuseWindow time.Duration) (identity.Config, error) {
+	policy = strings.ToLower(strings.TrimSpace(policy))
+	if !identity.ValidPolicy(policy) {
+		return identity.Config{}, fmt.Errorf("DISCOVERY_MAC_ROTATION_POLICY must be strict or rotation_aware (got %q)", policy)
+	}
+	rules, err := identity.ParseRules(cidrs)
+	if err != nil {
+		return identity.Config{}, fmt.Errorf("DISCOVERY_MAC_ROTATION_CIDRS: %w", err)
+	}
+	return identity.Config{Default: policy, Rules: rules, ReuseWindow: reuseWindow}, nil
+}
+
+// parseMonitorTags reads the comma-separated taxonomy tags whose devices the reachability monitor probes.
+func parseMonitorTags(raw string) ([]string, error) {
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		tag := tagging.NormalizeTag(part)
+		if tag == "" {
+			continue
+		}
+		if !tagging.IsValidTag(tag) {
+			return nil, fmt.Errorf("unknown tag %q", tag)
+		}
+		out = append(out, tag)
+	}
+	return tagging.NormalizeTagList(out), nil
+}
+
+// retentionDefaultsFromEnv reads the per-table RETENTION_<TABLE>_RAW_DAYS / _DAILY_DAYS defaults.
+func retentionDefaultsFromEnv() ([]retention.Policy, error) {
+	defaults := []retention.Policy{
+		{Table: "ip_observations", KeepRawDays: 30, KeepDailyDays: 365},
+		{Table: "mac_observations", KeepRawDays: 30, KeepDailyDays: 365},
+		{Table: "discovery_run_logs", KeepRawDays: 90},
+	}
+	for i := range defaults {
+		p := &defaults[i]
+		prefix := "RETENTION_" + strings.ToUpper(p.Table)
+		p.KeepRawDays = envOrInt(prefix+"_RAW_DAYS", p.KeepRawDays)
+		p.KeepDailyDays = envOrInt(prefix+"_DAILY_DAYS", p.KeepDailyDays)
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("%s_*: %w", prefix, err)
+		}
+	}
+	return defaults, nil
+}
@@ -0,0 +1,61 @@
+package discoveryworker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// runFactAging marks IP and MAC facts that discovery has not seen within the stale window, then detaches
+// (deletes, with a change event) those not seen within the detach window. A zero window disables that step.
+func (w *Worker) runFactAging(ctx context.Context, runID string, now time.Time) map[string]any {
+	if w.factStaleAfter <= 0 && w.factDetachAfter <= 0 {
+		return nil
+	}
+
+	var (
+		staleIPs, staleMACs       int64
+		detachedIPs, detachedMACs int64
+		firstErr                  error
+	)
+	keep := func(n int64, err error) int64 {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return n
+	}
+	if w.factStaleAfter > 0 {
+		before := now.Add(-w.factStaleAfter)
+		staleIPs = keep(w.q.MarkStaleIPAddresses(ctx, before))
+		staleMACs = keep(w.q.MarkStaleMACAddresses(ctx, before))
+	}
+	if w.factDetachAfter > 0 {
+		arg := sqlcgen.DetachStaleFactsParams{Before: now.Add(-w.factDetachAfter), RunID: &runID}
+		detachedIPs = keep(w.q.DetachStaleIPAddresses(ctx, arg))
+		detachedMACs = keep(w.q.DetachStaleMACAddresses(ctx, arg))
+	}
+
+	stats := map[string]any{
+		"ips_stale":     staleIPs,
+		"macs_stale":    staleMACs,
+		"ips_detached":  detachedIPs,
+		"macs_detached": detachedMACs,
+	}
+	if firstErr != nil {
+		stats["error"] = firstErr.Error()
+	}
+	return stats
+}
+
+func (w *Worker) factAgingLogMessage(stats map[string]any) string {
+	if stats == nil {
+		return ""
+	}
+	if msg, ok := stats["error"].(string); ok && msg != "" {
+		return fmt.Sprintf("fact aging failed: %s", msg)
+	}
+	return fmt.Sprintf("fact aging: ips_stale=%v macs_stale=%v ips_detached=%v macs_detached=%v",
+		stats["ips_stale"], stats["macs_stale"], stats["ips_detached"], stats["macs_detached"])
+}
@@ -0,0 +1,100 @@
+package discoveryworker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+func TestRunFactAging(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		staleAfter  time.Duration
+		detachAfter time.Duration
+		detachErr   error
+		wantNil     bool
+		wantStale   time.Time
+		wantDetach  time.Time
+		wantErr     bool
+	}{
+		{name: "disabled", wantNil: true},
+		{name: "stale only", staleAfter: 7 * 24 * time.Hour, wantStale: now.Add(-7 * 24 * time.Hour)},
+		{name: "detach only", detachAfter: 30 * 24 * time.Hour, wantDetach: now.Add(-30 * 24 * time.Hour)},
+		{
+			name:        "both",
+			staleAfter:  7 * 24 * time.Hour,
+			detachAfter: 30 * 24 * time.Hour,
+			wantStale:   now.Add(-7 * 24 * time.Hour),
+			wantDetach:  now.Add(-30 * 24 * time.Hour),
+		},
+		{
+			name:        "detach error is reported",
+			detachAfter: time.Hour,
+			detachErr:   errors.New("boom"),
+			wantDetach:  now.Add(-time.Hour),
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var staleBefore, detachBefore []time.Time
+			var runIDs []string
+			q := &fakeQueries{
+				markStaleIPsFn: func(ctx context.Context, before time.Time) (int64, error) {
+					staleBefore = append(staleBefore, before)
+					return 2, nil
+				},
+				markStaleMACsFn: func(ctx context.Context, before time.Time) (int64, error) {
+					staleBefore = append(staleBefore, before)
+					return 1, nil
+				},
+				detachIPsFn: func(ctx context.Context, arg sqlcgen.DetachStaleFactsParams) (int64, error) {
+					detachBefore = append(detachBefore, arg.Before)
+					runIDs = append(runIDs, *arg.RunID)
+					return 3, tc.detachErr
+				},
+				detachMACsFn: func(ctx context.Context, arg sqlcgen.DetachStaleFactsParams) (int64, error) {
+					detachBefore = append(detachBefore, arg.Before)
+					runIDs = append(runIDs, *arg.RunID)
+					return 0, nil
+				},
+			}
+			w := New(zerolog.Nop(), q, Options{FactStaleAfter: tc.staleAfter, FactDetachAfter: tc.detachAfter}, nil)
+			stats := w.runFactAging(context.Background(), "run-1", now)
+			if tc.wantNil {
+				if stats != nil || len(staleBefore)+len(detachBefore) != 0 {
+					t.Fatalf("expected no aging, got %v", stats)
+				}
+				return
+			}
+
+			if tc.wantStale.IsZero() {
+				if len(staleBefore) != 0 {
+					t.Fatalf("stale step should be skipped, got %v", staleBefore)
+				}
+			} else if len(staleBefore) != 2 || !staleBefore[0].Equal(tc.wantStale) || stats["ips_stale"] != int64(2) {
+				t.Fatalf("unexpected stale step %v (%v)", staleBefore, stats)
+			}
+			if tc.wantDetach.IsZero() {
+				if len(detachBefore) != 0 {
+					t.Fatalf("detach step should be skipped, got %v", detachBefore)
+				}
+			} else if len(detachBefore) != 2 || !detachBefore[1].Equal(tc.wantDetach) || runIDs[0] != "run-1" || stats["ips_detached"] != int64(3) {
+				t.Fatalf("unexpected detach step %v %v (%v)", detachBefore, runIDs, stats)
+			}
+			if _, failed := stats["error"]; failed != tc.wantErr {
+				t.Fatalf("expected error=%v, got %v", tc.wantErr, stats)
+			}
+			if msg := w.factAgingLogMessage(stats); msg == "" {
+				t.Fatalf("expected a log message")
+			}
+		})
+	}
+}
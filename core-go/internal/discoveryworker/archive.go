@@ -0,0 +1,27 @@
+package discoveryworker
+
+import (
+	"context"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// deviceUnarchiver reactivates archived devices that discovery sees again.
+type deviceUnarchiver interface {
+	UnarchiveReseenDevice(ctx context.Context, arg sqlcgen.UnarchiveReseenDeviceParams) (int64, error)
+}
+
+// unarchiveReseen reactivates the matched device if it was archived, reporting whether it was. Archived
+// devices are left out of IP and host-name matching, so an archived match always came from its MAC or
+// DHCP client ID.
+func unarchiveReseen(ctx context.Context, q deviceUnarchiver, m deviceMatch, runID string) (bool, error) {
+	if m.Created || m.ID == "" {
+		return false, nil
+	}
+	var run *string
+	if runID != "" {
+		run = &runID
+	}
+	n, err := q.UnarchiveReseenDevice(ctx, sqlcgen.UnarchiveReseenDeviceParams{DeviceID: m.ID, RunID: run})
+	return n > 0, err
+}
@@ -0,0 +1,55 @@
+package discoveryworker
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// runAvailability records an up/down transition for every device whose state changed: a device is up while any
+// of its facts was observed within the down window. Only devices with an IP inside the run's scope are evaluated;
+// a run without a scope read the whole ARP table. A zero window disables tracking.
+func (w *Worker) runAvailability(ctx context.Context, runID string, scope *netip.Prefix, now time.Time) map[string]any {
+	if w.availabilityDownAfter <= 0 {
+		return nil
+	}
+
+	changes, err := w.q.RecordDeviceAvailability(ctx, sqlcgen.RecordDeviceAvailabilityParams{
+		SeenAfter: now.Add(-w.availabilityDownAfter),
+		Now:       now,
+		RunID:     &runID,
+		Source:    "discovery",
+		Scope:     scopePrefixOrNil(scope),
+	})
+	var wentUp, wentDown int
+	for _, c := range changes {
+		switch c.State {
+		case sqlcgen.AvailabilityUp:
+			wentUp++
+		case sqlcgen.AvailabilityDown:
+			wentDown++
+		}
+	}
+
+	stats := map[string]any{
+		"went_up":   wentUp,
+		"went_down": wentDown,
+	}
+	if err != nil {
+		stats["error"] = err.Error()
+	}
+	return stats
+}
+
+func (w *Worker) availabilityLogMessage(stats map[string]any) string {
+	if stats == nil {
+		return ""
+	}
+	if msg, ok := stats["error"].(string); ok && msg != "" {
+		return fmt.Sprintf("availability tracking failed: %s", msg)
+	}
+	return fmt.Sprintf("availability: went_up=%v went_down=%v", stats["went_up"], stats["went_down"])
+}
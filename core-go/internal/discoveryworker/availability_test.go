@@ -0,0 +1,89 @@
+package discoveryworker
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+func TestRunAvailability(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	down := sqlcgen.AvailabilityDown
+
+	tests := []struct {
+		name      string
+		downAfter time.Duration
+		scope     string
+		changes   []sqlcgen.DeviceAvailabilityChange
+		err       error
+		wantNil   bool
+		wantUp    int
+		wantDown  int
+		wantErr   bool
+	}{
+		{name: "disabled", wantNil: true},
+		{
+			name:      "transitions are counted",
+			downAfter: time.Hour,
+			changes: []sqlcgen.DeviceAvailabilityChange{
+				{DeviceID: "a", State: sqlcgen.AvailabilityUp, PreviousState: &down},
+				{DeviceID: "b", State: sqlcgen.AvailabilityUp},
+				{DeviceID: "c", State: sqlcgen.AvailabilityDown},
+			},
+			wantUp:   2,
+			wantDown: 1,
+		},
+		{name: "scoped run", downAfter: time.Hour, scope: "10.0.1.0/24"},
+		{name: "error is reported", downAfter: time.Hour, err: errors.New("boom"), wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []sqlcgen.RecordDeviceAvailabilityParams
+			q := &fakeQueries{
+				recordAvailabilityFn: func(ctx context.Context, arg sqlcgen.RecordDeviceAvailabilityParams) ([]sqlcgen.DeviceAvailabilityChange, error) {
+					calls = append(calls, arg)
+					return tc.changes, tc.err
+				},
+			}
+			w := New(zerolog.Nop(), q, Options{AvailabilityDownAfter: tc.downAfter}, nil)
+			var scope *netip.Prefix
+			if tc.scope != "" {
+				p := netip.MustParsePrefix(tc.scope)
+				scope = &p
+			}
+			stats := w.runAvailability(context.Background(), "run-1", scope, now)
+			if tc.wantNil {
+				if stats != nil || len(calls) != 0 {
+					t.Fatalf("expected no availability tracking, got %v", stats)
+				}
+				return
+			}
+
+			if len(calls) != 1 {
+				t.Fatalf("expected one evaluation, got %d", len(calls))
+			}
+			if arg := calls[0]; !arg.SeenAfter.Equal(now.Add(-tc.downAfter)) || !arg.Now.Equal(now) || *arg.RunID != "run-1" || arg.Source != "discovery" {
+				t.Fatalf("unexpected params %+v", arg)
+			}
+			if got := calls[0].Scope; (got == nil) != (tc.scope == "") || (got != nil && *got != tc.scope) {
+				t.Fatalf("expected scope %q, got %v", tc.scope, got)
+			}
+			if stats["went_up"] != tc.wantUp || stats["went_down"] != tc.wantDown {
+				t.Fatalf("expected up=%d down=%d, got %v", tc.wantUp, tc.wantDown, stats)
+			}
+			if _, failed := stats["error"]; failed != tc.wantErr {
+				t.Fatalf("expected error=%v, got %v", tc.wantErr, stats)
+			}
+			if msg := w.availabilityLogMessage(stats); msg == "" {
+				t.Fatalf("expected a log message")
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package discoveryworker
+
+import (
+	"context"
+	"time"
+
+	"roller_hoops/core-go/internal/enrichment/snmp"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// applyBridging records a bridge's spanning tree view (common instance only) and its link aggregation
+// membership. It returns the number of STP ports and aggregate members written.
+func (w *Worker) applyBridging(ctx context.Context, deviceID string, stp snmp.STPInfo, lag map[int]int, ifIndexToInterfaceID map[int]string, now time.Time) (int, int) {
+	portsWritten := 0
+	if stp.BridgeAddress != nil || stp.RootAddress != nil || len(stp.Ports) > 0 {
+		var rootInterfaceID *string
+		if stp.RootIfIndex != nil {
+			rootInterfaceID = optionalString(ifIndexToInterfaceID[*stp.RootIfIndex])
+		}
+		_ = w.q.UpsertSTPBridge(ctx, sqlcgen.UpsertSTPBridgeParams{
+			DeviceID:        deviceID,
+			Instance:        0,
+			BridgeAddress:   stp.BridgeAddress,
+			Priority:        optionalInt32(stp.Priority),
+			RootAddress:     stp.RootAddress,
+			RootPriority:    optionalInt32(stp.RootPriority),
+			RootCost:        optionalInt32(stp.RootCost),
+			RootInterfaceID: rootInterfaceID,
+			ObservedAt:      now,
+		})
+		for ifIndex, port := range stp.Ports {
+			interfaceID := ifIndexToInterfaceID[ifIndex]
+			if interfaceID == "" {
+				continue
+			}
+			if err := w.q.UpsertSTPPort(ctx, sqlcgen.UpsertSTPPortParams{
+				InterfaceID: interfaceID,
+				Instance:    0,
+				DeviceID:    deviceID,
+				State:       port.State,
+				PathCost:    optionalInt32(port.PathCost),
+				ObservedAt:  now,
+			}); err == nil {
+				portsWritten++
+			}
+		}
+		_, _ = w.q.DeleteStaleSTPPorts(ctx, sqlcgen.DeleteStaleSTPPortsParams{DeviceID: deviceID, Before: now})
+	}
+
+	if lag == nil {
+		return portsWritten, 0
+	}
+	members := make([]string, 0, len(lag))
+	aggregates := make([]string, 0, len(lag))
+	for memberIfIndex, aggIfIndex := range lag {
+		memberID, aggID := ifIndexToInterfaceID[memberIfIndex], ifIndexToInterfaceID[aggIfIndex]
+		if memberID == "" || aggID == "" {
+			continue
+		}
+		members = append(members, memberID)
+		aggregates = append(aggregates, aggID)
+	}
+	_, _ = w.q.SyncInterfaceAggregates(ctx, sqlcgen.SyncInterfaceAggregatesParams{
+		DeviceID:              deviceID,
+		MemberInterfaceIDs:    members,
+		AggregateInterfaceIDs: aggregates,
+	})
+	return portsWritten, len(members)
+}
+
+func optionalInt32(v *int) *int32 {
+	if v == nil {
+		return nil
+	}
+	n := int32(*v)
+	return &n
+}
@@ -0,0 +1,89 @@
+package discoveryworker
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/enrichment/snmp"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+func TestApplyBridging_WritesSTPAndAggregates(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var bridge sqlcgen.UpsertSTPBridgeParams
+	var ports []sqlcgen.UpsertSTPPortParams
+	var sync sqlcgen.SyncInterfaceAggregatesParams
+	q := &fakeQueries{
+		upsertSTPBridgeFn: func(ctx context.Context, arg sqlcgen.UpsertSTPBridgeParams) error {
+			bridge = arg
+			return nil
+		},
+		upsertSTPPortFn: func(ctx context.Context, arg sqlcgen.UpsertSTPPortParams) error {
+			ports = append(ports, arg)
+			return nil
+		},
+		syncAggregatesFn: func(ctx context.Context, arg sqlcgen.SyncInterfaceAggregatesParams) (int64, error) {
+			sync = arg
+			return int64(len(arg.MemberInterfaceIDs)), nil
+		},
+	}
+	w := New(zerolog.Nop(), q, Options{}, nil)
+
+	rootMAC := "00:11:22:33:44:55"
+	prio, rootPrio, rootPort, cost := 32768, 4096, 1, 19
+	stp := snmp.STPInfo{
+		RootAddress:  &rootMAC,
+		Priority:     &prio,
+		RootPriority: &rootPrio,
+		RootIfIndex:  &rootPort,
+		Ports: map[int]snmp.STPPort{
+			1:  {State: "forwarding", PathCost: &cost},
+			2:  {State: "blocking"},
+			99: {State: "forwarding"}, // unknown ifIndex
+		},
+	}
+	lag := map[int]int{1: 10, 2: 10, 3: 99}
+	ifaces := map[int]string{1: "if-1", 2: "if-2", 3: "if-3", 10: "if-po1"}
+
+	gotPorts, gotMembers := w.applyBridging(context.Background(), "sw-1", stp, lag, ifaces, now)
+	if gotPorts != 2 || gotMembers != 2 {
+		t.Fatalf("expected 2 ports and 2 members, got %d and %d", gotPorts, gotMembers)
+	}
+	if bridge.DeviceID != "sw-1" || bridge.RootInterfaceID == nil || *bridge.RootInterfaceID != "if-1" {
+		t.Fatalf("unexpected bridge upsert: %+v", bridge)
+	}
+	if bridge.RootPriority == nil || *bridge.RootPriority != 4096 {
+		t.Fatalf("expected root priority 4096, got %v", bridge.RootPriority)
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].InterfaceID < ports[j].InterfaceID })
+	if ports[0].State != "forwarding" || ports[0].PathCost == nil || *ports[0].PathCost != 19 || ports[1].State != "blocking" {
+		t.Fatalf("unexpected port upserts: %+v", ports)
+	}
+	sort.Strings(sync.MemberInterfaceIDs)
+	if len(sync.MemberInterfaceIDs) != 2 || sync.MemberInterfaceIDs[0] != "if-1" || sync.AggregateInterfaceIDs[0] != "if-po1" {
+		t.Fatalf("unexpected aggregate sync: %+v", sync)
+	}
+}
+
+func TestApplyBridging_NoDataWritesNothing(t *testing.T) {
+	called := false
+	q := &fakeQueries{
+		upsertSTPBridgeFn: func(ctx context.Context, arg sqlcgen.UpsertSTPBridgeParams) error {
+			called = true
+			return nil
+		},
+		syncAggregatesFn: func(ctx context.Context, arg sqlcgen.SyncInterfaceAggregatesParams) (int64, error) {
+			called = true
+			return 0, nil
+		},
+	}
+	w := New(zerolog.Nop(), q, Options{}, nil)
+	ports, members := w.applyBridging(context.Background(), "sw-1", snmp.STPInfo{}, nil, map[int]string{1: "if-1"}, time.Now())
+	if ports != 0 || members != 0 || called {
+		t.Fatalf("expected no writes, got ports=%d members=%d called=%v", ports, members, called)
+	}
+}
@@ -0,0 +1,74 @@
+package discoveryworker
+
+import (
+	"context"
+	"time"
+
+	"roller_hoops/core-go/internal/enrichment/snmp"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// customOIDPoller is the SNMP call used for profile polling (satisfied by *snmp.Client).
+type customOIDPoller interface {
+	PollCustomOIDs(ctx context.Context, target snmp.Target, items []snmp.CustomOID) ([]snmp.CustomValue, error)
+}
+
+// pollCustomProfiles polls every enabled SNMP profile bound to one of the device's tags and stores the
+// values as custom facts. Values a successful poll no longer reports are dropped from the current facts
+// (their history stays). It returns the number of values written and how many of them were new or changed.
+func (w *Worker) pollCustomProfiles(ctx context.Context, client customOIDPoller, target snmp.Target, deviceID string, now time.Time) (int, int) {
+	profiles, err := w.q.ListSNMPProfilesForDevice(ctx, deviceID)
+	if err != nil || len(profiles) == 0 {
+		return 0, 0
+	}
+
+	written, changed := 0, 0
+	for _, profile := range profiles {
+		if ctx.Err() != nil {
+			break
+		}
+		items := make([]snmp.CustomOID, 0, len(profile.Items))
+		for _, item := range profile.Items {
+			items = append(items, snmp.CustomOID{
+				Key:   item.Key,
+				Label: item.Label,
+				OID:   item.OID,
+				Kind:  item.Kind,
+				Type:  item.Type,
+			})
+		}
+		values, err := client.PollCustomOIDs(ctx, target, items)
+		if err != nil {
+			continue
+		}
+		for _, v := range values {
+			var label *string
+			if v.Label != "" {
+				label = &v.Label
+			}
+			n, err := w.q.UpsertDeviceCustomFact(ctx, sqlcgen.UpsertDeviceCustomFactParams{
+				DeviceID:     deviceID,
+				ProfileID:    profile.ID,
+				FactKey:      v.Key,
+				Instance:     v.Instance,
+				Label:        label,
+				OID:          v.OID,
+				ValueType:    v.Type,
+				Value:        v.Value,
+				NumericValue: v.Numeric,
+				ObservedAt:   now,
+			})
+			if err != nil {
+				continue
+			}
+			written++
+			changed += int(n)
+		}
+		_, _ = w.q.DeleteStaleDeviceCustomFacts(ctx, sqlcgen.DeleteStaleDeviceCustomFactsParams{
+			DeviceID:  deviceID,
+			ProfileID: profile.ID,
+			Before:    now,
+		})
+	}
+	return written, changed
+}
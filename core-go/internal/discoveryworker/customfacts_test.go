@@ -0,0 +1,102 @@
+package discoveryworker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/enrichment/snmp"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+type fakeCustomPoller struct {
+	byProfile map[string][]snmp.CustomValue
+	failOID   string
+	polled    [][]snmp.CustomOID
+}
+
+func (f *fakeCustomPoller) PollCustomOIDs(ctx context.Context, target snmp.Target, items []snmp.CustomOID) ([]snmp.CustomValue, error) {
+	f.polled = append(f.polled, items)
+	if len(items) > 0 && items[0].OID == f.failOID {
+		return nil, errors.New("request timeout")
+	}
+	return f.byProfile[items[0].OID], nil
+}
+
+func TestPollCustomProfiles(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	level := float64(40)
+	poller := &fakeCustomPoller{
+		failOID: "1.3.6.1.2.1.33.1.2.4.0",
+		byProfile: map[string][]snmp.CustomValue{
+			"1.3.6.1.2.1.43.11.1.1.9": {
+				{Key: "supply_level", Label: "Supply level", Instance: "1.1", OID: "1.3.6.1.2.1.43.11.1.1.9.1.1", Type: snmp.CustomTypeGauge, Value: "40", Numeric: &level},
+				{Key: "supply_level", Instance: "1.2", OID: "1.3.6.1.2.1.43.11.1.1.9.1.2", Type: snmp.CustomTypeGauge, Value: "75"},
+			},
+		},
+	}
+	var upserts []sqlcgen.UpsertDeviceCustomFactParams
+	var stale []sqlcgen.DeleteStaleDeviceCustomFactsParams
+	q := &fakeQueries{
+		listProfilesFn: func(ctx context.Context, deviceID string) ([]sqlcgen.SNMPProfile, error) {
+			return []sqlcgen.SNMPProfile{
+				{ID: "prof-printer", Name: "printer supplies", Items: []sqlcgen.SNMPProfileItem{
+					{Key: "supply_level", Label: "Supply level", OID: "1.3.6.1.2.1.43.11.1.1.9", Kind: snmp.CustomOIDColumn, Type: snmp.CustomTypeGauge},
+				}},
+				{ID: "prof-ups", Name: "ups", Items: []sqlcgen.SNMPProfileItem{
+					{Key: "battery_minutes", OID: "1.3.6.1.2.1.33.1.2.4.0", Kind: snmp.CustomOIDScalar, Type: snmp.CustomTypeInteger},
+				}},
+			}, nil
+		},
+		upsertCustomFactFn: func(ctx context.Context, arg sqlcgen.UpsertDeviceCustomFactParams) (int64, error) {
+			upserts = append(upserts, arg)
+			if arg.Instance == "1.1" {
+				return 1, nil // changed
+			}
+			return 0, nil
+		},
+		deleteStaleCustomFn: func(ctx context.Context, arg sqlcgen.DeleteStaleDeviceCustomFactsParams) (int64, error) {
+			stale = append(stale, arg)
+			return 0, nil
+		},
+	}
+	w := New(zerolog.Nop(), q, Options{}, nil)
+
+	written, changed := w.pollCustomProfiles(context.Background(), poller, snmp.Target{ID: "dev-1", Address: "10.0.0.9"}, "dev-1", now)
+	if written != 2 || changed != 1 {
+		t.Fatalf("expected 2 written / 1 changed, got %d / %d", written, changed)
+	}
+	if len(poller.polled) != 2 || poller.polled[0][0].Kind != snmp.CustomOIDColumn {
+		t.Fatalf("expected both profiles to be polled, got %+v", poller.polled)
+	}
+
+	first := upserts[0]
+	if first.DeviceID != "dev-1" || first.ProfileID != "prof-printer" || first.FactKey != "supply_level" || first.Instance != "1.1" {
+		t.Fatalf("unexpected upsert: %+v", first)
+	}
+	if first.Label == nil || *first.Label != "Supply level" || first.NumericValue == nil || *first.NumericValue != 40 || !first.ObservedAt.Equal(now) {
+		t.Fatalf("unexpected upsert values: %+v", first)
+	}
+	if upserts[1].Label != nil {
+		t.Fatalf("expected empty label to be stored as NULL, got %q", *upserts[1].Label)
+	}
+
+	// Only the profile that answered is reconciled; a failed poll must not drop its current facts.
+	if len(stale) != 1 || stale[0].ProfileID != "prof-printer" || !stale[0].Before.Equal(now) {
+		t.Fatalf("unexpected stale cleanup: %+v", stale)
+	}
+}
+
+func TestPollCustomProfiles_NoBoundProfiles(t *testing.T) {
+	poller := &fakeCustomPoller{}
+	w := New(zerolog.Nop(), &fakeQueries{}, Options{}, nil)
+	if written, changed := w.pollCustomProfiles(context.Background(), poller, snmp.Target{}, "dev-1", time.Now()); written != 0 || changed != 0 {
+		t.Fatalf("expected nothing written, got %d / %d", written, changed)
+	}
+	if len(poller.polled) != 0 {
+		t.Fatalf("expected no SNMP polling without bound profiles")
+	}
+}
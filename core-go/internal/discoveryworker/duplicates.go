@@ -0,0 +1,77 @@
+package discoveryworker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"roller_hoops/core-go/internal/duplicates"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// duplicateMatchLimit bounds how many raw identity matches one analysis pass reads.
+const duplicateMatchLimit = 20000
+
+// runDuplicateAnalysis scores device pairs that share identity evidence and replaces the stored
+// duplicate suggestions with the result. Dismissed pairs are skipped by the store.
+func (w *Worker) runDuplicateAnalysis(ctx context.Context) map[string]any {
+	if !w.duplicateAnalysisEnabled {
+		return nil
+	}
+
+	rows, err := w.q.ListDuplicateMatches(ctx, duplicateMatchLimit)
+	if err != nil {
+		return map[string]any{"enabled": true, "error": err.Error()}
+	}
+	matches := make([]duplicates.Match, 0, len(rows))
+	for _, r := range rows {
+		matches = append(matches, duplicates.Match{
+			DeviceA: r.DeviceAID,
+			DeviceB: r.DeviceBID,
+			Signal:  r.Signal,
+			Value:   r.Value,
+		})
+	}
+	candidates := duplicates.Score(matches)
+
+	params := sqlcgen.ReplaceDeviceDuplicateCandidatesParams{
+		DeviceAIDs: make([]string, 0, len(candidates)),
+		DeviceBIDs: make([]string, 0, len(candidates)),
+		Scores:     make([]int32, 0, len(candidates)),
+		Evidence:   make([]string, 0, len(candidates)),
+		DetectedAt: time.Now(),
+	}
+	for _, c := range candidates {
+		evidence, err := json.Marshal(c.Evidence)
+		if err != nil {
+			continue
+		}
+		params.DeviceAIDs = append(params.DeviceAIDs, c.DeviceA)
+		params.DeviceBIDs = append(params.DeviceBIDs, c.DeviceB)
+		params.Scores = append(params.Scores, int32(c.Score))
+		params.Evidence = append(params.Evidence, string(evidence))
+	}
+
+	stored, err := w.q.ReplaceDeviceDuplicateCandidates(ctx, params)
+	stats := map[string]any{
+		"enabled":    true,
+		"matches":    len(rows),
+		"candidates": len(candidates),
+		"stored":     stored,
+	}
+	if err != nil {
+		stats["error"] = err.Error()
+	}
+	return stats
+}
+
+func (w *Worker) duplicateAnalysisLogMessage(stats map[string]any) string {
+	if stats == nil {
+		return ""
+	}
+	if msg, ok := stats["error"].(string); ok && msg != "" {
+		return fmt.Sprintf("duplicate analysis failed: %s", msg)
+	}
+	return fmt.Sprintf("duplicate analysis: matches=%v candidates=%v stored=%v", stats["matches"], stats["candidates"], stats["stored"])
+}
@@ -0,0 +1,91 @@
+package discoveryworker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+func TestRunDuplicateAnalysis(t *testing.T) {
+	tests := []struct {
+		name           string
+		enabled        bool
+		matches        []sqlcgen.DuplicateMatch
+		listErr        error
+		wantNil        bool
+		wantCandidates int
+		wantReplace    bool
+	}{
+		{name: "disabled", enabled: false, wantNil: true},
+		{
+			name:    "scores strong pairs and drops weak ones",
+			enabled: true,
+			matches: []sqlcgen.DuplicateMatch{
+				{DeviceAID: "b", DeviceBID: "a", Signal: "mac", Value: "aa:bb:cc:dd:ee:ff"},
+				{DeviceAID: "a", DeviceBID: "b", Signal: "sys_name", Value: "core-sw"},
+				{DeviceAID: "c", DeviceBID: "d", Signal: "ip_handoff", Value: "10.0.0.9"},
+			},
+			wantCandidates: 1,
+			wantReplace:    true,
+		},
+		{
+			name:        "no matches clears suggestions",
+			enabled:     true,
+			wantReplace: true,
+		},
+		{name: "list error", enabled: true, listErr: errors.New("boom")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var replaced *sqlcgen.ReplaceDeviceDuplicateCandidatesParams
+			q := &fakeQueries{
+				listDupMatchesFn: func(ctx context.Context, limit int32) ([]sqlcgen.DuplicateMatch, error) {
+					return tc.matches, tc.listErr
+				},
+				replaceDupFn: func(ctx context.Context, arg sqlcgen.ReplaceDeviceDuplicateCandidatesParams) (int64, error) {
+					replaced = &arg
+					return int64(len(arg.DeviceAIDs)), nil
+				},
+			}
+			w := New(zerolog.Nop(), q, Options{DuplicateAnalysisEnabled: tc.enabled}, nil)
+			stats := w.runDuplicateAnalysis(context.Background())
+			if tc.wantNil {
+				if stats != nil {
+					t.Fatalf("expected nil stats, got %v", stats)
+				}
+				return
+			}
+			if tc.listErr != nil {
+				if stats["error"] == nil || replaced != nil {
+					t.Fatalf("expected error and no replace, got %v", stats)
+				}
+				return
+			}
+			if (replaced != nil) != tc.wantReplace {
+				t.Fatalf("replace called=%v want %v", replaced != nil, tc.wantReplace)
+			}
+			if stats["candidates"] != tc.wantCandidates || len(replaced.DeviceAIDs) != tc.wantCandidates {
+				t.Fatalf("candidates=%v stored=%d want %d", stats["candidates"], len(replaced.DeviceAIDs), tc.wantCandidates)
+			}
+			if tc.wantCandidates == 0 {
+				return
+			}
+			if replaced.DeviceAIDs[0] != "a" || replaced.DeviceBIDs[0] != "b" || replaced.Scores[0] != 90 {
+				t.Fatalf("unexpected pair: %+v", replaced)
+			}
+			var evidence []map[string]any
+			if err := json.Unmarshal([]byte(replaced.Evidence[0]), &evidence); err != nil || len(evidence) != 2 {
+				t.Fatalf("unexpected evidence %q (%v)", replaced.Evidence[0], err)
+			}
+			if evidence[0]["signal"] != "mac" {
+				t.Fatalf("expected mac evidence first, got %v", evidence[0])
+			}
+		})
+	}
+}
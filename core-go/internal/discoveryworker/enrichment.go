@@ -21,7 +21,7 @@ type enrichmentTarget struct {
 	IP       netip.Addr
 }
 
-func (w *Worker) runEnrichment(ctx context.Context, targets []enrichmentTarget) map[string]any {
+func (w *Worker) runEnrichment(ctx context.Context, runID string, targets []enrichmentTarget) map[string]any {
 	if w == nil || w.q == nil {
 		return nil
 	}
@@ -30,11 +30,20 @@ func (w *Worker) runEnrichment(ctx context.Context, targets []enrichmentTarget)
 	}
 	if len(targets) == 0 {
 		return map[string]any{
-			"targets":       0,
-			"snmp_ok":       0,
-			"names_written": 0,
-			"vlans_written": 0,
-			"links_written": 0,
+			"targets":               0,
+			"snmp_ok":               0,
+			"names_written":         0,
+			"vlans_written":         0,
+			"links_written":         0,
+			"links_inferred":        0,
+			"stp_ports_written":     0,
+			"lag_members_written":   0,
+			"adjacency_transitions": 0,
+			"poe_ports_written":     0,
+			"poe_powered_devices":   0,
+			"custom_facts_written":  0,
+			"custom_facts_changed":  0,
+			"devices_unarchived":    0,
 		}
 	}
 
@@ -43,6 +52,7 @@ func (w *Worker) runEnrichment(ctx context.Context, targets []enrichmentTarget)
 	}
 
 	resolver := &mdns.Resolver{}
+	actor := sqlcgen.RunActor(runID)
 
 	var snmpClient *snmp.Client
 	var vlanCollector *vlan.Collector
@@ -61,6 +71,17 @@ func (w *Worker) runEnrichment(ctx context.Context, targets []enrichmentTarget)
 	var namesWritten int32
 	var vlansWritten int32
 	var linksWritten int32
+	var stpPortsWritten int32
+	var lagMembersWritten int32
+	var adjacencyTransitions int32
+	var poePortsWritten int32
+	var customFactsWritten int32
+	var customFactsChanged int32
+	var devicesUnarchived int32
+	inference := &inferenceInput{}
+	routers := &routingPolled{}
+	poe := &poeSwitches{}
+	startedAt := time.Now()
 
 	snmpAttempted := sync.Map{}
 	nameAttempted := sync.Map{}
@@ -140,12 +161,14 @@ func (w *Worker) runEnrichment(ctx context.Context, targets []enrichmentTarget)
 						Address:       ipPtr,
 						LastSuccessAt: nil,
 						LastError:     &msg,
+						Actor:         actor,
 					})
 
 					if displayName, ok := naming.ChooseBestDisplayName(deviceCandidates); ok {
 						_, _ = w.q.SetDeviceDisplayNameIfUnset(ctx, sqlcgen.SetDeviceDisplayNameIfUnsetParams{
 							ID:          t.DeviceID,
 							DisplayName: displayName,
+							Actor:       actor,
 						})
 					}
 					upsertSuggestions(t.DeviceID, tagging.MergeSuggestions(tagging.SuggestFromNames(deviceNames)))
@@ -164,6 +187,7 @@ func (w *Worker) runEnrichment(ctx context.Context, targets []enrichmentTarget)
 					SysLocation:   system.SysLocation,
 					LastSuccessAt: &now,
 					LastError:     nil,
+					Actor:         actor,
 				})
 
 				if system.SysName != nil && strings.TrimSpace(*system.SysName) != "" {
@@ -193,6 +217,7 @@ func (w *Worker) runEnrichment(ctx context.Context, targets []enrichmentTarget)
 					_, _ = w.q.SetDeviceDisplayNameIfUnset(ctx, sqlcgen.SetDeviceDisplayNameIfUnsetParams{
 						ID:          t.DeviceID,
 						DisplayName: displayName,
+						Actor:       actor,
 					})
 				}
 
@@ -238,6 +263,30 @@ func (w *Worker) runEnrichment(ctx context.Context, targets []enrichmentTarget)
 					}
 				}
 
+				if len(ifIndexToInterfaceID) > 0 {
+					// Bridges without BRIDGE-MIB / LAG-MIB simply report nothing here.
+					stp, _ := snmpClient.WalkSTP(ctx, target)
+					lag, _ := snmpClient.WalkLAGMembership(ctx, target)
+					ports, members := w.applyBridging(ctx, t.DeviceID, stp, lag, ifIndexToInterfaceID, time.Now())
+					atomic.AddInt32(&stpPortsWritten, int32(ports))
+					atomic.AddInt32(&lagMembersWritten, int32(members))
+
+					if ports, err := snmpClient.WalkPoEPorts(ctx, target); err == nil && len(ports) > 0 {
+						poe.add(t.DeviceID)
+						atomic.AddInt32(&poePortsWritten, int32(w.applyPoE(ctx, t.DeviceID, ports, ifaces, ifIndexToInterfaceID, time.Now())))
+					}
+				}
+
+				atomic.AddInt32(&adjacencyTransitions, int32(w.collectRoutingAdjacencies(ctx, snmpClient, target, t.DeviceID, routers, time.Now())))
+
+				customWritten, customChanged := w.pollCustomProfiles(ctx, snmpClient, target, t.DeviceID, time.Now())
+				atomic.AddInt32(&customFactsWritten, int32(customWritten))
+				atomic.AddInt32(&customFactsChanged, int32(customChanged))
+
+				if w.topologyInferenceEnabled && len(ifIndexToInterfaceID) > 0 && allowedByAllowlist(t.IP, w.topologyAllowlist) {
+					collectInferenceInput(ctx, snmpClient, target, ifaces, ifIndexToInterfaceID, inference)
+				}
+
 				if vlanCollector != nil && len(ifIndexToInterfaceID) > 0 {
 					pvidByIfIndex, err := vlanCollector.CollectPVIDByIfIndex(ctx, target)
 					if err != nil {
@@ -286,6 +335,10 @@ func (w *Worker) runEnrichment(ctx context.Context, targets []enrichmentTarget)
 							id, err := w.q.FindDeviceIDByMAC(ctx, *n.RemoteChassisMAC)
 							if err == nil {
 								remoteDeviceID = id
+								// A chassis MAC can match an archived device; advertising it means it is back.
+								if unarchived, err := unarchiveReseen(ctx, w.q, deviceMatch{ID: id, By: matchedByMAC}, runID); err == nil && unarchived {
+									atomic.AddInt32(&devicesUnarchived, 1)
+								}
 							}
 						}
 						if remoteDeviceID == "" && n.RemoteMgmtIP != nil && *n.RemoteMgmtIP != "" {
@@ -313,6 +366,7 @@ func (w *Worker) runEnrichment(ctx context.Context, targets []enrichmentTarget)
 								MAC:      *n.RemoteChassisMAC,
 							})
 						}
+						w.applyNeighborFacts(ctx, remoteDeviceID, t.DeviceID, n, now)
 						if n.RemoteDeviceName != nil && strings.TrimSpace(*n.RemoteDeviceName) != "" {
 							stored, display, score, ok := naming.NormalizeCandidate(n.Source, strings.TrimSpace(*n.RemoteDeviceName))
 							if !ok || score < 70 {
@@ -328,6 +382,7 @@ func (w *Worker) runEnrichment(ctx context.Context, targets []enrichmentTarget)
 								_, _ = w.q.SetDeviceDisplayNameIfUnset(ctx, sqlcgen.SetDeviceDisplayNameIfUnsetParams{
 									ID:          remoteDeviceID,
 									DisplayName: display,
+									Actor:       actor,
 								})
 							}
 						}
@@ -402,6 +457,7 @@ func (w *Worker) runEnrichment(ctx context.Context, targets []enrichmentTarget)
 					_, _ = w.q.SetDeviceDisplayNameIfUnset(ctx, sqlcgen.SetDeviceDisplayNameIfUnsetParams{
 						ID:          t.DeviceID,
 						DisplayName: displayName,
+						Actor:       actor,
 					})
 				}
 				upsertSuggestions(t.DeviceID, tagging.MergeSuggestions(tagging.SuggestFromNames(deviceNames)))
@@ -424,12 +480,21 @@ func (w *Worker) runEnrichment(ctx context.Context, targets []enrichmentTarget)
 			close(jobs)
 			wg.Wait()
 			return map[string]any{
-				"targets":       len(targets),
-				"snmp_ok":       int(snmpOK),
-				"names_written": int(namesWritten),
-				"vlans_written": int(vlansWritten),
-				"links_written": int(linksWritten),
-				"canceled":      true,
+				"targets":               len(targets),
+				"snmp_ok":               int(snmpOK),
+				"names_written":         int(namesWritten),
+				"vlans_written":         int(vlansWritten),
+				"links_written":         int(linksWritten),
+				"links_inferred":        0,
+				"stp_ports_written":     int(stpPortsWritten),
+				"lag_members_written":   int(lagMembersWritten),
+				"adjacency_transitions": int(adjacencyTransitions),
+				"poe_ports_written":     int(poePortsWritten),
+				"poe_powered_devices":   0,
+				"custom_facts_written":  int(customFactsWritten),
+				"custom_facts_changed":  int(customFactsChanged),
+				"devices_unarchived":    int(devicesUnarchived),
+				"canceled":              true,
 			}
 		case jobs <- t:
 		}
@@ -437,12 +502,31 @@ func (w *Worker) runEnrichment(ctx context.Context, targets []enrichmentTarget)
 	close(jobs)
 	wg.Wait()
 
+	// Inference runs after every target has been polled so it sees all bridge tables at once and any
+	// LLDP/CDP links written during this run.
+	linksInferred := 0
+	if w.topologyInferenceEnabled {
+		linksInferred = w.inferTopology(ctx, inference, time.Now())
+	}
+	transitions := int(adjacencyTransitions) + w.markStaleRoutingAdjacencies(ctx, routers, startedAt)
+	// Powered devices are resolved from links, so this also runs after inference.
+	poweredChanged := w.syncPoEPoweredDevices(ctx, poe)
+
 	return map[string]any{
-		"targets":       len(targets),
-		"snmp_ok":       int(snmpOK),
-		"names_written": int(namesWritten),
-		"vlans_written": int(vlansWritten),
-		"links_written": int(linksWritten),
+		"targets":               len(targets),
+		"snmp_ok":               int(snmpOK),
+		"names_written":         int(namesWritten),
+		"vlans_written":         int(vlansWritten),
+		"links_written":         int(linksWritten),
+		"links_inferred":        linksInferred,
+		"stp_ports_written":     int(stpPortsWritten),
+		"lag_members_written":   int(lagMembersWritten),
+		"adjacency_transitions": transitions,
+		"poe_ports_written":     int(poePortsWritten),
+		"poe_powered_devices":   poweredChanged,
+		"custom_facts_written":  int(customFactsWritten),
+		"custom_facts_changed":  int(customFactsChanged),
+		"devices_unarchived":    int(devicesUnarchived),
 	}
 }
 
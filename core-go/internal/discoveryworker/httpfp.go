@@ -0,0 +1,204 @@
+package discoveryworker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"roller_hoops/core-go/internal/enrichment/httpfp"
+	"roller_hoops/core-go/internal/naming"
+	"roller_hoops/core-go/internal/sqlcgen"
+	"roller_hoops/core-go/internal/tagging"
+)
+
+const httpFingerprintMaxServicesPerDevice = 16
+
+// runHTTPFingerprint fetches `/` from open web services on allowlisted targets and records the status,
+// <title>, Server / X-Powered-By headers and favicon hash on the service row.
+//
+// Titles become `http_title` name candidates and the fingerprint feeds auto tagging (NVRs, printer
+// EWS pages, NAS, firewalls). Redirects are followed only within the scanned host.
+func (w *Worker) runHTTPFingerprint(ctx context.Context, targets []enrichmentTarget) map[string]any {
+	if w == nil || w.q == nil || !w.httpFingerprintEnabled {
+		return nil
+	}
+	if len(w.portScanAllowlist) == 0 {
+		return map[string]any{"enabled": true, "available": false, "reason": "no_allowlist"}
+	}
+
+	scanTargets := w.portScanTargets(targets)
+	if len(scanTargets) == 0 {
+		return map[string]any{"enabled": true, "available": true, "targets": 0, "fingerprints_written": 0}
+	}
+
+	fetcher := httpfp.NewFetcher(httpfp.Config{
+		Timeout:      w.httpFingerprintTimeout,
+		MaxRedirects: 3,
+	})
+
+	var servicesTried int32
+	var fingerprintsWritten int32
+	var namesWritten int32
+	var tagsWritten int32
+
+	jobs := make(chan portScanTarget)
+	wg := sync.WaitGroup{}
+
+	worker := func() {
+		defer wg.Done()
+		for t := range jobs {
+			if ctx.Err() != nil {
+				return
+			}
+			services, err := w.q.ListOpenTCPServicesForDevice(ctx, t.DeviceID, tlsInventoryMaxServicesPerDevice)
+			if err != nil {
+				continue
+			}
+
+			ipStr := t.IP
+			tried := 0
+			for _, svc := range services {
+				if ctx.Err() != nil {
+					return
+				}
+				name := ""
+				if svc.Name != nil {
+					name = *svc.Name
+				}
+				if !httpfp.IsLikelyHTTP(int(svc.Port), name) {
+					continue
+				}
+				if tried >= httpFingerprintMaxServicesPerDevice {
+					break
+				}
+				tried++
+				atomic.AddInt32(&servicesTried, 1)
+
+				var res httpfp.Result
+				var fetchErr error
+				for _, scheme := range httpfp.SchemesFor(int(svc.Port), name) {
+					res, fetchErr = fetcher.Fetch(ctx, scheme, t.IP, int(svc.Port))
+					if fetchErr == nil {
+						break
+					}
+				}
+				if fetchErr != nil {
+					continue
+				}
+
+				status := int32(res.StatusCode)
+				if err := w.q.UpdateServiceHTTPFingerprint(ctx, sqlcgen.UpdateServiceHTTPFingerprintParams{
+					ID:          svc.ID,
+					Status:      &status,
+					Title:       optionalString(res.Title),
+					Server:      optionalString(res.Server),
+					PoweredBy:   optionalString(res.PoweredBy),
+					FaviconHash: res.FaviconHash,
+					FinalURL:    optionalString(res.FinalURL),
+					ObservedAt:  time.Now(),
+				}); err != nil {
+					continue
+				}
+				atomic.AddInt32(&fingerprintsWritten, 1)
+
+				if res.Title != "" {
+					if stored, _, _, ok := naming.NormalizeCandidate("http_title", res.Title); ok {
+						if err := w.q.InsertDeviceNameCandidate(ctx, sqlcgen.InsertDeviceNameCandidateParams{
+							DeviceID: t.DeviceID,
+							Name:     stored,
+							Source:   "http_title",
+							Address:  &ipStr,
+						}); err == nil {
+							atomic.AddInt32(&namesWritten, 1)
+						}
+					}
+				}
+
+				suggestions := tagging.MergeSuggestions(tagging.SuggestFromHTTP(tagging.HTTPFingerprint{
+					Port:        svc.Port,
+					Title:       res.Title,
+					Server:      res.Server,
+					PoweredBy:   res.PoweredBy,
+					FaviconHash: res.FaviconHash,
+				}))
+				for _, s := range suggestions {
+					s.Evidence["ip"] = t.IP
+					if err := w.q.UpsertDeviceTag(ctx, sqlcgen.UpsertDeviceTagParams{
+						DeviceID:   t.DeviceID,
+						Tag:        s.Tag,
+						Source:     "auto",
+						Confidence: int32(s.Confidence),
+						Evidence:   s.Evidence,
+					}); err == nil {
+						atomic.AddInt32(&tagsWritten, 1)
+					}
+				}
+			}
+		}
+	}
+
+	workers := w.portScanWorkers
+	if workers <= 0 {
+		workers = 4
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go worker()
+	}
+
+	for _, t := range scanTargets {
+		select {
+		case <-ctx.Done():
+			close(jobs)
+			wg.Wait()
+			return map[string]any{
+				"enabled":              true,
+				"available":            true,
+				"targets":              len(scanTargets),
+				"services_tried":       int(servicesTried),
+				"fingerprints_written": int(fingerprintsWritten),
+				"names_written":        int(namesWritten),
+				"tags_written":         int(tagsWritten),
+				"canceled":             true,
+			}
+		case jobs <- t:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return map[string]any{
+		"enabled":              true,
+		"available":            true,
+		"targets":              len(scanTargets),
+		"services_tried":       int(servicesTried),
+		"fingerprints_written": int(fingerprintsWritten),
+		"names_written":        int(namesWritten),
+		"tags_written":         int(tagsWritten),
+		"timeout":              w.httpFingerprintTimeout.String(),
+	}
+}
+
+func optionalString(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func (w *Worker) httpFingerprintLogMessage(stats map[string]any) string {
+	if stats == nil {
+		return ""
+	}
+	if avail, ok := stats["available"].(bool); ok && !avail {
+		if reason, ok := stats["reason"].(string); ok && reason != "" {
+			return fmt.Sprintf("http fingerprint skipped: %s", reason)
+		}
+		return "http fingerprint skipped"
+	}
+	return fmt.Sprintf("http fingerprint: targets=%v services=%v fingerprints=%v names=%v tags=%v", stats["targets"], stats["services_tried"], stats["fingerprints_written"], stats["names_written"], stats["tags_written"])
+}
@@ -0,0 +1,94 @@
+package discoveryworker
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+func TestWorker_RunHTTPFingerprint_WritesServiceNameAndTags(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx")
+		_, _ = w.Write([]byte(`<html><head><title>Synology DiskStation - nas01</title></head></html>`))
+	}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.Start()
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("parse url: %v", err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+	httpName := "http"
+	sshName := "ssh"
+
+	var mu sync.Mutex
+	var updates []sqlcgen.UpdateServiceHTTPFingerprintParams
+	var names []sqlcgen.InsertDeviceNameCandidateParams
+	var tags []sqlcgen.UpsertDeviceTagParams
+	q := &fakeQueries{
+		listOpenTCPFn: func(ctx context.Context, deviceID string, limit int32) ([]sqlcgen.OpenTCPService, error) {
+			return []sqlcgen.OpenTCPService{
+				{ID: "svc-ssh", Port: 22, Name: &sshName},
+				{ID: "svc-web", Port: int32(port), Name: &httpName},
+			}, nil
+		},
+		updateHTTPFn: func(ctx context.Context, arg sqlcgen.UpdateServiceHTTPFingerprintParams) error {
+			mu.Lock()
+			defer mu.Unlock()
+			updates = append(updates, arg)
+			return nil
+		},
+		insertNameCandidateFn: func(ctx context.Context, arg sqlcgen.InsertDeviceNameCandidateParams) error {
+			mu.Lock()
+			defer mu.Unlock()
+			names = append(names, arg)
+			return nil
+		},
+		upsertTagFn: func(ctx context.Context, arg sqlcgen.UpsertDeviceTagParams) error {
+			mu.Lock()
+			defer mu.Unlock()
+			tags = append(tags, arg)
+			return nil
+		},
+	}
+
+	w := New(zerolog.Nop(), q, Options{
+		PortScanAllowlist:      []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
+		HTTPFingerprintEnabled: true,
+		HTTPFingerprintTimeout: 2 * time.Second,
+	}, nil)
+
+	stats := w.runHTTPFingerprint(context.Background(), []enrichmentTarget{
+		{DeviceID: "dev-1", IP: netip.MustParseAddr("127.0.0.1")},
+	})
+
+	if stats["services_tried"] != 1 || stats["fingerprints_written"] != 1 {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+	if len(updates) != 1 || updates[0].ID != "svc-web" {
+		t.Fatalf("expected one fingerprint for svc-web, got %+v", updates)
+	}
+	if updates[0].Title == nil || *updates[0].Title != "Synology DiskStation - nas01" || *updates[0].Status != 200 {
+		t.Fatalf("unexpected fingerprint %+v", updates[0])
+	}
+	if len(names) != 1 || names[0].Source != "http_title" {
+		t.Fatalf("expected http_title name candidate, got %+v", names)
+	}
+	if len(tags) != 1 || tags[0].Tag != "nas" || tags[0].Source != "auto" {
+		t.Fatalf("expected auto nas tag, got %+v", tags)
+	}
+}
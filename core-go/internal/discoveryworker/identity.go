@@ -0,0 +1,133 @@
+package discoveryworker
+
+import (
+	"context"
+	"net/netip"
+	"time"
+
+	"roller_hoops/core-go/internal/enrichment/mdns"
+	"roller_hoops/core-go/internal/identity"
+	"roller_hoops/core-go/internal/naming"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// identityResolver adds the weak-identifier lookups used when a randomized MAC is not known yet.
+type identityResolver interface {
+	deviceResolver
+	FindDeviceIDByClientID(ctx context.Context, clientID string) (string, error)
+	FindDeviceIDByHostName(ctx context.Context, name string) (string, error)
+	FindRotatingDeviceIDByIP(ctx context.Context, arg sqlcgen.FindRotatingDeviceIDByIPParams) (string, error)
+}
+
+// How an observation was matched to an existing device.
+const (
+	matchedByMAC      = "mac"
+	matchedByIP       = "ip"
+	matchedByClientID = "client_id"
+	matchedByHostName = "host_name"
+	matchedByIPReuse  = "ip_reuse"
+)
+
+// deviceObservation is one MAC/IP sighting plus the identity evidence the host volunteered with it.
+type deviceObservation struct {
+	MAC      string
+	IP       netip.Addr
+	ClientID string
+	Names    []naming.Candidate
+	// LookupNames, when set, is asked for host-claimed names only once a rotated MAC turned out to be
+	// unknown (native runs resolve mDNS/NetBIOS names on demand instead of for every entry).
+	LookupNames func(ctx context.Context) []naming.Candidate
+}
+
+// deviceMatch is the device an observation resolved to. By is empty when the device was created.
+type deviceMatch struct {
+	ID      string
+	Created bool
+	By      string
+	// Rotating is set when the MAC was handled as a randomized (weak) identifier.
+	Rotating bool
+}
+
+// resolveObservedDevice matches an observation under the MAC policy of its address. Strict policy, and
+// any globally unique MAC, behaves like resolveDevice. Under the rotation-aware policy a locally
+// administered MAC only matches exactly; when it is new, the device is looked up by DHCP client ID,
+// then by a host-claimed name only one device carries, then by a rotating device that held the IP
+// within the reuse window, and only then created.
+func resolveObservedDevice(ctx context.Context, q identityResolver, cfg identity.Config, now time.Time, obs deviceObservation) (deviceMatch, error) {
+	if obs.MAC == "" || !identity.IsLocallyAdministered(obs.MAC) || cfg.PolicyFor(obs.IP) != identity.PolicyRotationAware {
+		return matchDevice(ctx, q, obs.MAC, obs.IP)
+	}
+
+	match := func(by string, id string, err error) (deviceMatch, bool, error) {
+		id, err = optionalDeviceID(id, err)
+		if err != nil || id == "" {
+			return deviceMatch{}, false, err
+		}
+		return deviceMatch{ID: id, By: by, Rotating: true}, true, nil
+	}
+
+	id, err := q.FindDeviceIDByMAC(ctx, obs.MAC)
+	if m, ok, err := match(matchedByMAC, id, err); ok || err != nil {
+		return m, err
+	}
+	if obs.ClientID != "" {
+		id, err := q.FindDeviceIDByClientID(ctx, obs.ClientID)
+		if m, ok, err := match(matchedByClientID, id, err); ok || err != nil {
+			return m, err
+		}
+	}
+
+	names := obs.Names
+	if len(names) == 0 && obs.LookupNames != nil {
+		names = obs.LookupNames(ctx)
+	}
+	seen := map[string]struct{}{}
+	for _, c := range names {
+		if !identity.IsHostNameSource(c.Source) {
+			continue
+		}
+		if _, _, _, ok := naming.NormalizeCandidate(c.Source, c.Name); !ok {
+			continue
+		}
+		key := identity.HostNameKey(c.Name)
+		if key == "" {
+			continue
+		}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		id, err := q.FindDeviceIDByHostName(ctx, key)
+		if m, ok, err := match(matchedByHostName, id, err); ok || err != nil {
+			return m, err
+		}
+	}
+
+	if obs.IP.IsValid() {
+		id, err := q.FindRotatingDeviceIDByIP(ctx, sqlcgen.FindRotatingDeviceIDByIPParams{
+			IP:    obs.IP.String(),
+			Since: now.Add(-cfg.Window()),
+		})
+		if m, ok, err := match(matchedByIPReuse, id, err); ok || err != nil {
+			return m, err
+		}
+	}
+
+	m, err := createDevice(ctx, q)
+	m.Rotating = true
+	return m, err
+}
+
+// lookupHostNames asks the host itself for its mDNS/NetBIOS names, with the same short budget the
+// enrichment stage uses.
+func lookupHostNames(ctx context.Context, ip string) []naming.Candidate {
+	ctx, cancel := context.WithTimeout(ctx, 250*time.Millisecond)
+	defer cancel()
+	resolver := &mdns.Resolver{}
+	cands, _ := resolver.LookupAddr(ctx, "", ip)
+	out := make([]naming.Candidate, 0, len(cands))
+	for _, c := range cands {
+		out = append(out, naming.Candidate{Name: c.Name, Source: c.Source})
+	}
+	return out
+}
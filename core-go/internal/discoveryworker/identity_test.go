@@ -0,0 +1,176 @@
+package discoveryworker
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/identity"
+	"roller_hoops/core-go/internal/naming"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+func TestResolveObservedDevice(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ip := netip.MustParseAddr("192.168.1.40")
+	const randomMAC = "da:a1:19:00:00:01"
+	const globalMAC = "00:11:22:33:44:55"
+	rotationAware := identity.Config{Default: identity.PolicyRotationAware}
+
+	cases := []struct {
+		name         string
+		cfg          identity.Config
+		obs          deviceObservation
+		byClientID   map[string]string
+		byHostName   map[string]string
+		byIP         string
+		rotatingByIP string
+		wantID       string
+		wantBy       string
+		wantCreated  bool
+		wantRotating bool
+	}{
+		{
+			name:   "strict policy falls back to ip",
+			cfg:    identity.Config{},
+			obs:    deviceObservation{MAC: randomMAC, IP: ip},
+			byIP:   "dev-by-ip",
+			wantID: "dev-by-ip",
+			wantBy: matchedByIP,
+		},
+		{
+			name:   "global mac keeps ip fallback",
+			cfg:    rotationAware,
+			obs:    deviceObservation{MAC: globalMAC, IP: ip},
+			byIP:   "dev-by-ip",
+			wantID: "dev-by-ip",
+			wantBy: matchedByIP,
+		},
+		{
+			name:         "client id",
+			cfg:          rotationAware,
+			obs:          deviceObservation{MAC: randomMAC, IP: ip, ClientID: "ff:00:01"},
+			byClientID:   map[string]string{"ff:00:01": "dev-phone"},
+			byIP:         "dev-other",
+			wantID:       "dev-phone",
+			wantBy:       matchedByClientID,
+			wantRotating: true,
+		},
+		{
+			name: "host-claimed name, reverse dns ignored",
+			cfg:  rotationAware,
+			obs: deviceObservation{MAC: randomMAC, IP: ip, Names: []naming.Candidate{
+				{Name: "printer.lan", Source: "reverse_dns"},
+				{Name: "Alices-iPhone.local", Source: "mdns"},
+			}},
+			byHostName:   map[string]string{"printer": "dev-printer", "alices-iphone": "dev-phone"},
+			wantID:       "dev-phone",
+			wantBy:       matchedByHostName,
+			wantRotating: true,
+		},
+		{
+			name:         "ip reuse within window",
+			cfg:          rotationAware,
+			obs:          deviceObservation{MAC: randomMAC, IP: ip},
+			rotatingByIP: "dev-phone",
+			wantID:       "dev-phone",
+			wantBy:       matchedByIPReuse,
+			wantRotating: true,
+		},
+		{
+			name:         "unknown randomized mac creates instead of taking the ip owner",
+			cfg:          rotationAware,
+			obs:          deviceObservation{MAC: randomMAC, IP: ip},
+			byIP:         "dev-printer",
+			wantID:       "dev-new",
+			wantCreated:  true,
+			wantRotating: true,
+		},
+		{
+			name: "scope rule overrides default",
+			cfg: identity.Config{Default: identity.PolicyRotationAware, Rules: []identity.Rule{
+				{Prefix: netip.MustParsePrefix("192.168.1.0/24"), Policy: identity.PolicyStrict},
+			}},
+			obs:    deviceObservation{MAC: randomMAC, IP: ip},
+			byIP:   "dev-by-ip",
+			wantID: "dev-by-ip",
+			wantBy: matchedByIP,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var since time.Time
+			q := &fakeQueries{
+				findByIPFn: func(ctx context.Context, ip string) (string, error) {
+					return lookupOrNoRows(tc.byIP)
+				},
+				findByClientIDFn: func(ctx context.Context, clientID string) (string, error) {
+					return lookupOrNoRows(tc.byClientID[clientID])
+				},
+				findByHostNameFn: func(ctx context.Context, name string) (string, error) {
+					return lookupOrNoRows(tc.byHostName[name])
+				},
+				findRotatingByIPFn: func(ctx context.Context, arg sqlcgen.FindRotatingDeviceIDByIPParams) (string, error) {
+					since = arg.Since
+					return lookupOrNoRows(tc.rotatingByIP)
+				},
+				createFn: func(ctx context.Context, displayName *string) (sqlcgen.Device, error) {
+					return sqlcgen.Device{ID: "dev-new"}, nil
+				},
+			}
+
+			got, err := resolveObservedDevice(context.Background(), q, tc.cfg, now, tc.obs)
+			if err != nil {
+				t.Fatalf("resolve: %v", err)
+			}
+			if got.ID != tc.wantID || got.By != tc.wantBy || got.Created != tc.wantCreated || got.Rotating != tc.wantRotating {
+				t.Fatalf("unexpected match %+v", got)
+			}
+			if tc.wantBy == matchedByIPReuse && !since.Equal(now.Add(-identity.DefaultReuseWindow)) {
+				t.Fatalf("expected reuse window from %s, got %s", now.Add(-identity.DefaultReuseWindow), since)
+			}
+		})
+	}
+}
+
+func TestResolveObservedDevice_LooksUpNamesOnlyForUnknownRandomizedMAC(t *testing.T) {
+	lookups := 0
+	obs := deviceObservation{
+		MAC: "da:a1:19:00:00:01",
+		IP:  netip.MustParseAddr("192.168.1.40"),
+		LookupNames: func(ctx context.Context) []naming.Candidate {
+			lookups++
+			return []naming.Candidate{{Name: "laptop", Source: "netbios"}}
+		},
+	}
+	cfg := identity.Config{Default: identity.PolicyRotationAware}
+
+	known := &fakeQueries{findByMacFn: func(ctx context.Context, mac string) (string, error) { return "dev-known", nil }}
+	if got, err := resolveObservedDevice(context.Background(), known, cfg, time.Now(), obs); err != nil || got.ID != "dev-known" || got.By != matchedByMAC {
+		t.Fatalf("unexpected match %+v (%v)", got, err)
+	}
+	if lookups != 0 {
+		t.Fatalf("expected no name lookup for a known mac, got %d", lookups)
+	}
+
+	unknown := &fakeQueries{findByHostNameFn: func(ctx context.Context, name string) (string, error) {
+		return lookupOrNoRows(map[string]string{"laptop": "dev-laptop"}[name])
+	}}
+	if got, err := resolveObservedDevice(context.Background(), unknown, cfg, time.Now(), obs); err != nil || got.ID != "dev-laptop" {
+		t.Fatalf("unexpected match %+v (%v)", got, err)
+	}
+	if lookups != 1 {
+		t.Fatalf("expected one name lookup, got %d", lookups)
+	}
+}
+
+func lookupOrNoRows(id string) (string, error) {
+	if id == "" {
+		return "", pgx.ErrNoRows
+	}
+	return id, nil
+}
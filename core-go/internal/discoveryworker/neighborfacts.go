@@ -0,0 +1,145 @@
+package discoveryworker
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"roller_hoops/core-go/internal/enrichment/snmp"
+	"roller_hoops/core-go/internal/sqlcgen"
+	"roller_hoops/core-go/internal/tagging"
+)
+
+// neighborOSRules map keywords in an advertised system description to vendor/OS family; first match wins,
+// so more specific keywords come first.
+var neighborOSRules = []struct {
+	match  string
+	vendor string
+	family string
+}{
+	{match: "ios xe", vendor: "Cisco", family: "IOS XE"},
+	{match: "ios-xe", vendor: "Cisco", family: "IOS XE"},
+	{match: "ios xr", vendor: "Cisco", family: "IOS XR"},
+	{match: "nx-os", vendor: "Cisco", family: "NX-OS"},
+	{match: "cisco adaptive security appliance", vendor: "Cisco", family: "ASA"},
+	{match: "cisco ios", vendor: "Cisco", family: "IOS"},
+	{match: "junos", vendor: "Juniper", family: "Junos"},
+	{match: "arubaos", vendor: "Aruba", family: "ArubaOS"},
+	{match: "arista", vendor: "Arista", family: "EOS"},
+	{match: "routeros", vendor: "MikroTik", family: "RouterOS"},
+	{match: "fortios", vendor: "Fortinet", family: "FortiOS"},
+	{match: "fortigate", vendor: "Fortinet", family: "FortiOS"},
+	{match: "pan-os", vendor: "Palo Alto Networks", family: "PAN-OS"},
+	{match: "unifi", vendor: "Ubiquiti", family: "UniFi"},
+	{match: "edgeos", vendor: "Ubiquiti", family: "EdgeOS"},
+	{match: "procurve", vendor: "HPE", family: "ProCurve"},
+	{match: "linux", family: "Linux"},
+	{match: "windows", vendor: "Microsoft", family: "Windows"},
+}
+
+var (
+	neighborVersionRe = regexp.MustCompile(`(?i)\bversion:?\s+([0-9][0-9A-Za-z.()_\-]*[0-9A-Za-z)])`)
+	neighborJunosRe   = regexp.MustCompile(`(?i)\bjunos\s+([0-9][0-9A-Za-z.\-]*[0-9A-Za-z])`)
+)
+
+// neighborOSGuess turns the software a device advertises about itself over LLDP/CDP (system description,
+// CDP version string or LLDP-MED software revision) into a self-reported OS guess. The source is the
+// neighbor protocol and accuracy is 100: the device said so itself.
+func neighborOSGuess(n snmp.Neighbor) (tagging.OSGuess, bool) {
+	text := ""
+	if n.RemoteSysDescr != nil {
+		text = strings.TrimSpace(*n.RemoteSysDescr)
+	}
+	if text == "" && n.Inventory != nil && n.Inventory.SoftwareRev != nil {
+		text = strings.TrimSpace(*n.Inventory.SoftwareRev)
+		if n.Inventory.Model != nil && strings.TrimSpace(*n.Inventory.Model) != "" {
+			text = strings.TrimSpace(*n.Inventory.Model) + " " + text
+		}
+	}
+	if text == "" {
+		return tagging.OSGuess{}, false
+	}
+
+	// Multi-line CDP version strings: the first line carries product + version.
+	name := text
+	if i := strings.IndexAny(name, "\r\n"); i >= 0 {
+		name = strings.TrimSpace(name[:i])
+	}
+	if len(name) > 200 {
+		name = name[:200]
+	}
+
+	guess := tagging.OSGuess{Name: name, Accuracy: 100}
+	lower := strings.ToLower(text)
+	for _, rule := range neighborOSRules {
+		if strings.Contains(lower, rule.match) {
+			guess.Vendor = rule.vendor
+			guess.Family = rule.family
+			break
+		}
+	}
+	if n.Inventory != nil && n.Inventory.Manufacturer != nil && strings.TrimSpace(*n.Inventory.Manufacturer) != "" {
+		guess.Vendor = strings.TrimSpace(*n.Inventory.Manufacturer)
+	}
+	if m := neighborVersionRe.FindStringSubmatch(text); m != nil {
+		guess.Generation = m[1]
+	} else if m := neighborJunosRe.FindStringSubmatch(text); m != nil {
+		guess.Generation = m[1]
+	}
+
+	caps := map[string]bool{}
+	for _, c := range n.Capabilities {
+		caps[c] = true
+	}
+	switch {
+	case caps["wlan_ap"]:
+		guess.DeviceType = "WAP"
+	case caps["telephone"]:
+		guess.DeviceType = "phone"
+	case caps["bridge"]:
+		guess.DeviceType = "switch"
+	case caps["router"]:
+		guess.DeviceType = "router"
+	}
+	return guess, true
+}
+
+// applyNeighborFacts records what a neighbor advertises about itself on the neighbor's device: auto tags
+// from its capabilities/platform/description and a self-reported OS guess.
+func (w *Worker) applyNeighborFacts(ctx context.Context, deviceID, reportedBy string, n snmp.Neighbor, now time.Time) {
+	info := tagging.NeighborInfo{Source: n.Source, Capabilities: n.Capabilities}
+	if n.RemotePlatform != nil {
+		info.Platform = *n.RemotePlatform
+	}
+	if n.RemoteSysDescr != nil {
+		info.SysDescr = *n.RemoteSysDescr
+	}
+	for _, s := range tagging.SuggestFromNeighbor(info) {
+		s.Evidence["reported_by"] = reportedBy
+		_ = w.q.UpsertDeviceTag(ctx, sqlcgen.UpsertDeviceTagParams{
+			DeviceID:   deviceID,
+			Tag:        s.Tag,
+			Source:     "auto",
+			Confidence: int32(s.Confidence),
+			Evidence:   s.Evidence,
+		})
+	}
+
+	guess, ok := neighborOSGuess(n)
+	if !ok {
+		return
+	}
+	_ = w.q.UpsertDeviceOSGuess(ctx, sqlcgen.UpsertDeviceOSGuessParams{
+		DeviceID:     deviceID,
+		Source:       n.Source,
+		Rank:         1,
+		Name:         guess.Name,
+		Accuracy:     int32(guess.Accuracy),
+		Vendor:       optionalString(guess.Vendor),
+		OSFamily:     optionalString(guess.Family),
+		OSGeneration: optionalString(guess.Generation),
+		DeviceType:   optionalString(guess.DeviceType),
+		ObservedAt:   now,
+	})
+}
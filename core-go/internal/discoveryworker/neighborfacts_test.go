@@ -0,0 +1,61 @@
+package discoveryworker
+
+import (
+	"testing"
+
+	"roller_hoops/core-go/internal/enrichment/snmp"
+	"roller_hoops/core-go/internal/tagging"
+)
+
+func TestNeighborOSGuess(t *testing.T) {
+	str := func(s string) *string { return &s }
+	cases := []struct {
+		name string
+		n    snmp.Neighbor
+		want tagging.OSGuess
+		ok   bool
+	}{
+		{
+			name: "cdp ios version string",
+			n: snmp.Neighbor{
+				Source:         "cdp",
+				RemoteSysDescr: str("Cisco IOS Software, C2960X Software (C2960X-UNIVERSALK9-M), Version 15.2(4)E8, RELEASE SOFTWARE (fc2)\nTechnical Support: http://www.cisco.com/techsupport"),
+				Capabilities:   []string{"bridge"},
+			},
+			want: tagging.OSGuess{Name: "Cisco IOS Software, C2960X Software (C2960X-UNIVERSALK9-M), Version 15.2(4)E8, RELEASE SOFTWARE (fc2)", Vendor: "Cisco", Family: "IOS", Generation: "15.2(4)E8", DeviceType: "switch", Accuracy: 100},
+			ok:   true,
+		},
+		{
+			name: "lldp junos",
+			n: snmp.Neighbor{
+				Source:         "lldp",
+				RemoteSysDescr: str("Juniper Networks, Inc. ex2300-24p Ethernet Switch, kernel JUNOS 18.4R2-S3.4"),
+				Capabilities:   []string{"bridge", "router"},
+			},
+			want: tagging.OSGuess{Name: "Juniper Networks, Inc. ex2300-24p Ethernet Switch, kernel JUNOS 18.4R2-S3.4", Vendor: "Juniper", Family: "Junos", Generation: "18.4R2-S3.4", DeviceType: "switch", Accuracy: 100},
+			ok:   true,
+		},
+		{
+			name: "lldp-med phone inventory",
+			n: snmp.Neighbor{
+				Source:       "lldp",
+				Capabilities: []string{"bridge", "telephone"},
+				Inventory:    &snmp.MEDInventory{SoftwareRev: str("sip88xx.14-1-1"), Manufacturer: str("Cisco Systems, Inc."), Model: str("CP-8845")},
+			},
+			want: tagging.OSGuess{Name: "CP-8845 sip88xx.14-1-1", Vendor: "Cisco Systems, Inc.", DeviceType: "phone", Accuracy: 100},
+			ok:   true,
+		},
+		{name: "nothing advertised", n: snmp.Neighbor{Source: "lldp", Capabilities: []string{"bridge"}}, ok: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := neighborOSGuess(tc.n)
+			if ok != tc.ok {
+				t.Fatalf("expected ok=%v, got %v", tc.ok, ok)
+			}
+			if ok && (got.Name != tc.want.Name || got.Vendor != tc.want.Vendor || got.Family != tc.want.Family || got.Generation != tc.want.Generation || got.DeviceType != tc.want.DeviceType || got.Accuracy != tc.want.Accuracy) {
+				t.Fatalf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package discoveryworker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"roller_hoops/core-go/internal/enrichment/pcap"
+	"roller_hoops/core-go/internal/identity"
+	"roller_hoops/core-go/internal/naming"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// pcapImportSource is the services source for SYN-ACK evidence taken from a capture.
+const pcapImportSource = "pcap"
+
+// PcapImportQueries is the DB interface needed to replay a packet capture as a discovery run.
+//
+// NOTE: *sqlcgen.Queries satisfies this.
+type PcapImportQueries interface {
+	ScanImportQueries
+	SetDeviceDisplayNameIfUnset(ctx context.Context, arg sqlcgen.SetDeviceDisplayNameIfUnsetParams) (int64, error)
+	UpsertInterfaceByName(ctx context.Context, arg sqlcgen.UpsertInterfaceByNameParams) (string, error)
+	UpsertLink(ctx context.Context, arg sqlcgen.UpsertLinkParams) error
+}
+
+// PcapImport describes one uploaded capture.
+type PcapImport struct {
+	Format string
+	Bytes  int64
+	// Origin is a free-form label for where the capture was taken (site, incident ticket).
+	Origin string
+	// CaptureDeviceID is the device the capture was taken on (usually a switch SPAN/mirror port or the
+	// host itself). When set, LLDP/CDP advertisements become links from it to the advertising device.
+	CaptureDeviceID string
+	// CaptureInterface names the capture device's interface the frames arrived on.
+	CaptureInterface string
+	// MACIdentity is the MAC matching policy, as for native runs.
+	MACIdentity identity.Config
+}
+
+func (in PcapImport) stats() map[string]any {
+	out := map[string]any{
+		"format": in.Format,
+		"bytes":  in.Bytes,
+	}
+	if origin := strings.TrimSpace(in.Origin); origin != "" {
+		out["origin"] = origin
+	}
+	if in.CaptureDeviceID != "" {
+		out["capture_device_id"] = in.CaptureDeviceID
+	}
+	if in.CaptureInterface != "" {
+		out["capture_interface"] = in.CaptureInterface
+	}
+	return out
+}
+
+// StartPcapImport records the run for an accepted capture so callers can hand its id back before the
+// (potentially long) decode in ImportPcap starts.
+func StartPcapImport(ctx context.Context, q PcapImportQueries, in PcapImport) (sqlcgen.DiscoveryRun, error) {
+	run, err := q.InsertDiscoveryRun(ctx, sqlcgen.InsertDiscoveryRunParams{
+		Status: "running",
+		Stats: map[string]any{
+			"stage":  "importing",
+			"method": "pcap",
+			"import": in.stats(),
+		},
+	})
+	if err != nil {
+		return sqlcgen.DiscoveryRun{}, err
+	}
+	_ = q.InsertDiscoveryRunLog(ctx, sqlcgen.InsertDiscoveryRunLogParams{
+		RunID:   run.ID,
+		Level:   "info",
+		Message: fmt.Sprintf("pcap import started: format=%s bytes=%d", in.Format, in.Bytes),
+	})
+	return run, nil
+}
+
+// ImportPcap streams a capture for a run created by StartPcapImport and applies the passive evidence:
+// ARP/DHCP bindings become IP/MAC observations, DHCP/mDNS/NetBIOS names become name candidates, SYN-ACKs
+// become open TCP services and LLDP/CDP advertisements become devices (and links when the capture
+// device is known). Devices are matched exactly like a native run (MAC, then IP).
+//
+// A capture cut off mid-record is applied up to the cut; a corrupt capture fails the run without
+// writing anything.
+func ImportPcap(ctx context.Context, q PcapImportQueries, runID string, r io.Reader, in PcapImport) (sqlcgen.DiscoveryRun, error) {
+	importStats := in.stats()
+	counts := map[string]int{}
+
+	res, err := pcap.Read(r, pcap.Config{MaxHosts: ScanImportMaxHosts})
+	if err != nil {
+		return failPcapImport(ctx, q, runID, err, importStats, counts)
+	}
+	importStats["packets"] = res.Stats.Packets
+	importStats["arp"] = res.Stats.ARP
+	importStats["dhcp"] = res.Stats.DHCP
+	importStats["mdns"] = res.Stats.MDNS
+	importStats["netbios"] = res.Stats.NetBIOS
+	importStats["lldp"] = res.Stats.LLDP
+	importStats["cdp"] = res.Stats.CDP
+	importStats["syn_ack"] = res.Stats.SYNACK
+	importStats["unsupported_frames"] = res.Stats.UnsupportedFrames
+	importStats["hosts_dropped"] = res.Stats.HostsDropped
+	importStats["truncated"] = res.Stats.Truncated
+	if !res.Stats.FirstPacket.IsZero() {
+		importStats["capture_started_at"] = res.Stats.FirstPacket
+		importStats["capture_ended_at"] = res.Stats.LastPacket
+	}
+
+	now := time.Now()
+	for _, h := range res.Hosts {
+		if ctx.Err() != nil {
+			return failPcapImport(ctx, q, runID, ctx.Err(), importStats, counts)
+		}
+		host := scanImportHost{IP: h.IP, MAC: h.MAC, ClientID: h.ClientID}
+		for _, n := range h.Names {
+			host.addName(naming.Candidate{Name: n.Name, Source: n.Source})
+		}
+		for _, p := range h.TCPPorts {
+			host.addPort(scanImportPort{Protocol: "tcp", Port: p})
+		}
+		if err := importScanHost(ctx, q, in.MACIdentity, runID, pcapImportSource, now, host, counts); err != nil {
+			return failPcapImport(ctx, q, runID, err, importStats, counts)
+		}
+	}
+
+	var captureIfID *string
+	if in.CaptureDeviceID != "" && in.CaptureInterface != "" {
+		ifaceID, err := q.UpsertInterfaceByName(ctx, sqlcgen.UpsertInterfaceByNameParams{
+			DeviceID: in.CaptureDeviceID,
+			Name:     in.CaptureInterface,
+		})
+		if err != nil {
+			return failPcapImport(ctx, q, runID, err, importStats, counts)
+		}
+		captureIfID = &ifaceID
+	}
+	for _, n := range res.Neighbors {
+		if ctx.Err() != nil {
+			return failPcapImport(ctx, q, runID, ctx.Err(), importStats, counts)
+		}
+		if err := importPcapNeighbor(ctx, q, runID, in.CaptureDeviceID, captureIfID, n, counts); err != nil {
+			return failPcapImport(ctx, q, runID, err, importStats, counts)
+		}
+	}
+
+	// Imported tags and links only become history once snapshotted; a failure here leaves the import intact.
+	_, _ = q.RecordDeviceStateSnapshots(ctx, sqlcgen.RecordDeviceStateSnapshotsParams{Actor: sqlcgen.RunActor(runID)})
+
+	for k, v := range counts {
+		importStats[k] = v
+	}
+	completedAt := time.Now()
+	run, err := q.UpdateDiscoveryRun(ctx, sqlcgen.UpdateDiscoveryRunParams{
+		ID:     runID,
+		Status: "succeeded",
+		Stats: map[string]any{
+			"stage":           "completed",
+			"method":          "pcap",
+			"devices_seen":    counts["devices_seen"],
+			"devices_created": counts["devices_created"],
+			"import":          importStats,
+		},
+		CompletedAt: &completedAt,
+	})
+	if err != nil {
+		return failPcapImport(ctx, q, runID, err, importStats, counts)
+	}
+
+	_ = q.InsertDiscoveryRunLog(ctx, sqlcgen.InsertDiscoveryRunLogParams{
+		RunID: runID,
+		Level: "info",
+		Message: fmt.Sprintf("pcap import completed: packets=%d truncated=%t devices_seen=%d devices_created=%d services=%d names=%d neighbors=%d links=%d",
+			res.Stats.Packets, res.Stats.Truncated, counts["devices_seen"], counts["devices_created"], counts["services_written"], counts["names_written"], counts["neighbors_seen"], counts["links_written"]),
+	})
+	return run, nil
+}
+
+// importPcapNeighbor applies one LLDP/CDP advertisement the same way the SNMP neighbor walk does: match
+// the advertising device by chassis MAC then management IP (creating it otherwise), record its name
+// and port, and link it to the capture device when one was given.
+func importPcapNeighbor(ctx context.Context, q PcapImportQueries, runID, captureDeviceID string, captureIfID *string, n pcap.Neighbor, counts map[string]int) error {
+	mac := n.ChassisMAC
+	if mac == "" {
+		mac = n.SourceMAC
+	}
+	match, err := matchDevice(ctx, q, mac, n.MgmtIP)
+	if err != nil {
+		return err
+	}
+	deviceID := match.ID
+	if match.Created {
+		counts["devices_created"]++
+	}
+	unarchived, err := unarchiveReseen(ctx, q, match, runID)
+	if err != nil {
+		return err
+	}
+	if unarchived {
+		counts["devices_unarchived"]++
+	}
+	counts["neighbors_seen"]++
+
+	if mac != "" {
+		if err := q.UpsertImportedDeviceMAC(ctx, sqlcgen.UpsertDeviceMACParams{DeviceID: deviceID, MAC: mac}); err != nil {
+			return err
+		}
+		if err := q.InsertMACObservation(ctx, sqlcgen.InsertMACObservationParams{RunID: runID, DeviceID: deviceID, MAC: mac}); err != nil {
+			return err
+		}
+	}
+	if n.MgmtIP.IsValid() {
+		ip := n.MgmtIP.String()
+		if err := q.UpsertImportedDeviceIP(ctx, sqlcgen.UpsertDeviceIPParams{DeviceID: deviceID, IP: ip}); err != nil {
+			return err
+		}
+		if err := q.InsertIPObservation(ctx, sqlcgen.InsertIPObservationParams{RunID: runID, DeviceID: deviceID, IP: ip}); err != nil {
+			return err
+		}
+	}
+
+	if name := strings.TrimSpace(n.SystemName); name != "" {
+		if stored, display, score, ok := naming.NormalizeCandidate(n.Protocol, name); ok && score >= 70 {
+			if err := q.InsertDeviceNameCandidate(ctx, sqlcgen.InsertDeviceNameCandidateParams{
+				DeviceID: deviceID,
+				Name:     stored,
+				Source:   n.Protocol,
+			}); err == nil {
+				counts["names_written"]++
+			}
+			if display != "" {
+				_, _ = q.SetDeviceDisplayNameIfUnset(ctx, sqlcgen.SetDeviceDisplayNameIfUnsetParams{
+					ID:          deviceID,
+					DisplayName: display,
+					Actor:       sqlcgen.RunActor(runID),
+				})
+			}
+		}
+	}
+
+	var remoteIfID *string
+	if port := strings.TrimSpace(n.PortID); port != "" {
+		ifaceID, err := q.UpsertInterfaceByName(ctx, sqlcgen.UpsertInterfaceByNameParams{DeviceID: deviceID, Name: port})
+		if err == nil && ifaceID != "" {
+			remoteIfID = &ifaceID
+		}
+	}
+
+	if captureDeviceID == "" || captureDeviceID == deviceID {
+		return nil
+	}
+	linkType := "ethernet"
+	observedAt := n.LastSeen
+	if observedAt.IsZero() {
+		observedAt = time.Now()
+	}
+	aDev, aIf, bDev, bIf := canonicalizeLinkEndpoints(captureDeviceID, captureIfID, deviceID, remoteIfID)
+	if err := q.UpsertLink(ctx, sqlcgen.UpsertLinkParams{
+		LinkKey:      makeLinkKey(n.Protocol, aDev, aIf, bDev, bIf),
+		ADeviceID:    aDev,
+		AInterfaceID: aIf,
+		BDeviceID:    bDev,
+		BInterfaceID: bIf,
+		LinkType:     &linkType,
+		Source:       n.Protocol,
+		ObservedAt:   &observedAt,
+	}); err == nil {
+		counts["links_written"]++
+	}
+	return nil
+}
+
+func failPcapImport(ctx context.Context, q PcapImportQueries, runID string, cause error, importStats map[string]any, counts map[string]int) (sqlcgen.DiscoveryRun, error) {
+	for k, v := range counts {
+		importStats[k] = v
+	}
+	if ctx.Err() != nil {
+		bg, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		ctx = bg
+	}
+	completedAt := time.Now()
+	msg := cause.Error()
+	run, err := q.UpdateDiscoveryRun(ctx, sqlcgen.UpdateDiscoveryRunParams{
+		ID:     runID,
+		Status: "failed",
+		Stats: map[string]any{
+			"stage":  "failed",
+			"method": "pcap",
+			"import": importStats,
+		},
+		CompletedAt: &completedAt,
+		LastError:   &msg,
+	})
+	_ = q.InsertDiscoveryRunLog(ctx, sqlcgen.InsertDiscoveryRunLogParams{
+		RunID:   runID,
+		Level:   "error",
+		Message: "pcap import failed: " + msg,
+	})
+	if err != nil {
+		return sqlcgen.DiscoveryRun{}, err
+	}
+	return run, cause
+}
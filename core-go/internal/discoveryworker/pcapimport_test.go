@@ -0,0 +1,187 @@
+package discoveryworker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// testPcap wraps Ethernet frames in a little-endian, microsecond pcap file.
+func testPcap(frames ...[]byte) []byte {
+	var b bytes.Buffer
+	hdr := make([]byte, 24)
+	binary.LittleEndian.PutUint32(hdr[0:4], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(hdr[4:6], 2)
+	binary.LittleEndian.PutUint16(hdr[6:8], 4)
+	binary.LittleEndian.PutUint32(hdr[16:20], 65535)
+	binary.LittleEndian.PutUint32(hdr[20:24], 1)
+	b.Write(hdr)
+	for i, f := range frames {
+		rec := make([]byte, 16)
+		binary.LittleEndian.PutUint32(rec[0:4], 1772366400+uint32(i))
+		binary.LittleEndian.PutUint32(rec[8:12], uint32(len(f)))
+		binary.LittleEndian.PutUint32(rec[12:16], uint32(len(f)))
+		b.Write(rec)
+		b.Write(f)
+	}
+	return b.Bytes()
+}
+
+var (
+	// ARP reply: 00:11:22:33:44:55 is at 10.1.0.20.
+	testARPFrame = []byte{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x08, 0x06,
+		0x00, 0x01, 0x08, 0x00, 0x06, 0x04, 0x00, 0x02,
+		0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 10, 1, 0, 20,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	// LLDP from chassis 00:1b:54:00:00:01, port "Gi1/0/24", system name "sw1", mgmt 10.1.0.2.
+	testLLDPFrame = []byte{
+		0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e, 0x00, 0x1b, 0x54, 0x00, 0x00, 0x18, 0x88, 0xcc,
+		0x02, 0x07, 0x04, 0x00, 0x1b, 0x54, 0x00, 0x00, 0x01,
+		0x04, 0x09, 0x05, 'G', 'i', '1', '/', '0', '/', '2', '4',
+		0x06, 0x02, 0x00, 0x78,
+		0x0a, 0x03, 's', 'w', '1',
+		0x10, 0x0c, 0x05, 0x01, 10, 1, 0, 2, 0x02, 0x00, 0x00, 0x00, 0x01, 0x00,
+		0x00, 0x00,
+	}
+)
+
+func TestImportPcap_AppliesEvidenceAndLinksCaptureDevice(t *testing.T) {
+	var final sqlcgen.UpdateDiscoveryRunParams
+	var ipObs []sqlcgen.InsertIPObservationParams
+	var names []sqlcgen.InsertDeviceNameCandidateParams
+	var links []sqlcgen.UpsertLinkParams
+	var ifaces []sqlcgen.UpsertInterfaceByNameParams
+	created := 0
+
+	q := &fakeScanImportQueries{fakeQueries: &fakeQueries{
+		updateFn: func(ctx context.Context, arg sqlcgen.UpdateDiscoveryRunParams) (sqlcgen.DiscoveryRun, error) {
+			final = arg
+			return sqlcgen.DiscoveryRun{ID: arg.ID, Status: arg.Status, Stats: arg.Stats}, nil
+		},
+		insertFn: func(ctx context.Context, arg sqlcgen.InsertDiscoveryRunLogParams) error { return nil },
+		findByIPFn: func(ctx context.Context, ip string) (string, error) {
+			if ip == "10.1.0.20" {
+				return "dev-host", nil
+			}
+			return "", pgx.ErrNoRows
+		},
+		createFn: func(ctx context.Context, displayName *string) (sqlcgen.Device, error) {
+			created++
+			return sqlcgen.Device{ID: "dev-switch"}, nil
+		},
+		insertIPObs: func(ctx context.Context, arg sqlcgen.InsertIPObservationParams) error {
+			ipObs = append(ipObs, arg)
+			return nil
+		},
+		insertNameCandidateFn: func(ctx context.Context, arg sqlcgen.InsertDeviceNameCandidateParams) error {
+			names = append(names, arg)
+			return nil
+		},
+		upsertIfaceByNameFn: func(ctx context.Context, arg sqlcgen.UpsertInterfaceByNameParams) (string, error) {
+			ifaces = append(ifaces, arg)
+			return "if-" + arg.Name, nil
+		},
+		upsertLinkFn: func(ctx context.Context, arg sqlcgen.UpsertLinkParams) error {
+			links = append(links, arg)
+			return nil
+		},
+	}}
+
+	run, err := ImportPcap(context.Background(), q, "run-pcap", bytes.NewReader(testPcap(testARPFrame, testLLDPFrame)), PcapImport{
+		Format:           "pcap",
+		Origin:           "site-7",
+		CaptureDeviceID:  "dev-host",
+		CaptureInterface: "eth0",
+	})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if run.Status != "succeeded" || final.Stats["method"] != "pcap" || final.CompletedAt == nil {
+		t.Fatalf("unexpected final update %+v", final)
+	}
+	stats, _ := final.Stats["import"].(map[string]any)
+	if stats["packets"] != 2 || stats["arp"] != 1 || stats["lldp"] != 1 || stats["neighbors_seen"] != 1 || stats["links_written"] != 1 || stats["origin"] != "site-7" {
+		t.Fatalf("unexpected import stats %v", stats)
+	}
+	if created != 1 {
+		t.Fatalf("expected only the LLDP neighbor to be created, got %d", created)
+	}
+	if len(ipObs) != 2 || ipObs[0].DeviceID != "dev-host" || ipObs[1].DeviceID != "dev-switch" || ipObs[1].IP != "10.1.0.2" {
+		t.Fatalf("unexpected ip observations %+v", ipObs)
+	}
+	if len(names) != 1 || names[0].Source != "lldp" || names[0].DeviceID != "dev-switch" {
+		t.Fatalf("unexpected names %+v", names)
+	}
+	if len(ifaces) != 2 || ifaces[0].DeviceID != "dev-host" || ifaces[0].Name != "eth0" || ifaces[1].Name != "Gi1/0/24" {
+		t.Fatalf("unexpected interfaces %+v", ifaces)
+	}
+	l := links[0]
+	if len(links) != 1 || l.Source != "lldp" || l.ADeviceID != "dev-host" || *l.AInterfaceID != "if-eth0" || l.BDeviceID != "dev-switch" || *l.BInterfaceID != "if-Gi1/0/24" {
+		t.Fatalf("unexpected links %+v", links)
+	}
+}
+
+func TestImportPcap_NeighborMatchingArchivedDeviceUnarchivesIt(t *testing.T) {
+	var final sqlcgen.UpdateDiscoveryRunParams
+	var unarchived []sqlcgen.UnarchiveReseenDeviceParams
+	q := &fakeScanImportQueries{fakeQueries: &fakeQueries{
+		updateFn: func(ctx context.Context, arg sqlcgen.UpdateDiscoveryRunParams) (sqlcgen.DiscoveryRun, error) {
+			final = arg
+			return sqlcgen.DiscoveryRun{ID: arg.ID, Status: arg.Status, Stats: arg.Stats}, nil
+		},
+		insertFn: func(ctx context.Context, arg sqlcgen.InsertDiscoveryRunLogParams) error { return nil },
+		findByMacFn: func(ctx context.Context, mac string) (string, error) {
+			if mac == "00:1b:54:00:00:01" {
+				return "dev-archived-switch", nil
+			}
+			return "", pgx.ErrNoRows
+		},
+		unarchiveFn: func(ctx context.Context, arg sqlcgen.UnarchiveReseenDeviceParams) (int64, error) {
+			unarchived = append(unarchived, arg)
+			return 1, nil
+		},
+	}}
+
+	if _, err := ImportPcap(context.Background(), q, "run-pcap", bytes.NewReader(testPcap(testLLDPFrame)), PcapImport{Format: "pcap"}); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if len(unarchived) != 1 || unarchived[0].DeviceID != "dev-archived-switch" || unarchived[0].RunID == nil || *unarchived[0].RunID != "run-pcap" {
+		t.Fatalf("expected the neighbor's device to be unarchived by the run, got %+v", unarchived)
+	}
+	if stats, _ := final.Stats["import"].(map[string]any); stats["devices_unarchived"] != 1 {
+		t.Fatalf("unexpected import stats %v", final.Stats["import"])
+	}
+}
+
+func TestImportPcap_CorruptCaptureFailsRun(t *testing.T) {
+	var final sqlcgen.UpdateDiscoveryRunParams
+	q := &fakeScanImportQueries{fakeQueries: &fakeQueries{
+		updateFn: func(ctx context.Context, arg sqlcgen.UpdateDiscoveryRunParams) (sqlcgen.DiscoveryRun, error) {
+			final = arg
+			return sqlcgen.DiscoveryRun{ID: arg.ID, Status: arg.Status}, nil
+		},
+		insertFn: func(ctx context.Context, arg sqlcgen.InsertDiscoveryRunLogParams) error { return nil },
+		createFn: func(ctx context.Context, displayName *string) (sqlcgen.Device, error) {
+			t.Fatalf("no device should be written")
+			return sqlcgen.Device{}, nil
+		},
+	}}
+
+	data := testPcap(testARPFrame)
+	// Claim a record far larger than any real frame.
+	binary.LittleEndian.PutUint32(data[24+8:24+12], 1<<30)
+	run, err := ImportPcap(context.Background(), q, "run-pcap", bytes.NewReader(data), PcapImport{Format: "pcap"})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if run.Status != "failed" || final.LastError == nil || final.Stats["method"] != "pcap" {
+		t.Fatalf("unexpected final update %+v", final)
+	}
+}
@@ -0,0 +1,72 @@
+package discoveryworker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"roller_hoops/core-go/internal/enrichment/snmp"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// poeSwitches records which devices reported PSE ports, so their powered devices can be resolved once
+// every target (and any link inference) has been processed.
+type poeSwitches struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (p *poeSwitches) add(deviceID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ids = append(p.ids, deviceID)
+}
+
+// applyPoE records the PSE ports of a PoE switch against its interfaces and drops ports no longer
+// reported. It returns the number of ports written.
+func (w *Worker) applyPoE(ctx context.Context, deviceID string, ports []snmp.PoEPort, ifaces map[int]snmp.InterfaceInfo, ifIndexToInterfaceID map[int]string, now time.Time) int {
+	if len(ports) == 0 {
+		return 0
+	}
+	resolved := snmp.ResolvePoEPortIfIndex(ports, ifaces)
+	written := 0
+	for _, p := range ports {
+		ifIndex, ok := resolved[[2]int{p.Group, p.Port}]
+		if !ok {
+			continue
+		}
+		interfaceID := ifIndexToInterfaceID[ifIndex]
+		if interfaceID == "" {
+			continue
+		}
+		if err := w.q.UpsertInterfacePoE(ctx, sqlcgen.UpsertInterfacePoEParams{
+			InterfaceID:     interfaceID,
+			DeviceID:        deviceID,
+			PSEGroup:        int32(p.Group),
+			PSEPort:         int32(p.Port),
+			AdminEnabled:    p.AdminEnabled,
+			DetectionStatus: p.DetectionStatus,
+			PowerClass:      p.PowerClass,
+			PowerMW:         optionalInt32(p.PowerMilliwatts),
+			ObservedAt:      now,
+		}); err == nil {
+			written++
+		}
+	}
+	_, _ = w.q.DeleteStaleInterfacePoE(ctx, sqlcgen.DeleteStaleInterfacePoEParams{DeviceID: deviceID, Before: now})
+	return written
+}
+
+// syncPoEPoweredDevices points the PoE ports of the polled switches at the devices linked to them. It
+// returns the number of ports whose powered device changed.
+func (w *Worker) syncPoEPoweredDevices(ctx context.Context, switches *poeSwitches) int {
+	if switches == nil || len(switches.ids) == 0 {
+		return 0
+	}
+	n, err := w.q.SyncPoEPoweredDevices(ctx, switches.ids)
+	if err != nil {
+		w.log.Debug().Err(err).Msg("poe powered device sync failed")
+		return 0
+	}
+	return int(n)
+}
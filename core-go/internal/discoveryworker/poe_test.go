@@ -0,0 +1,67 @@
+package discoveryworker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/enrichment/snmp"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+func TestApplyPoE_WritesResolvedPorts(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var written []sqlcgen.UpsertInterfacePoEParams
+	var synced []string
+	q := &fakeQueries{
+		upsertPoEFn: func(ctx context.Context, arg sqlcgen.UpsertInterfacePoEParams) error {
+			written = append(written, arg)
+			return nil
+		},
+		syncPoweredFn: func(ctx context.Context, deviceIDs []string) (int64, error) {
+			synced = deviceIDs
+			return 1, nil
+		},
+	}
+	w := New(zerolog.Nop(), q, Options{}, nil)
+
+	name := func(s string) *string { return &s }
+	enabled, mw, class := true, 6400, "class3"
+	ports := []snmp.PoEPort{
+		{Group: 1, Port: 1, AdminEnabled: &enabled, DetectionStatus: "delivering_power", PowerClass: &class, PowerMilliwatts: &mw},
+		{Group: 1, Port: 2, DetectionStatus: "searching"},
+		{Group: 1, Port: 48, DetectionStatus: "searching"}, // no matching interface
+	}
+	ifaces := map[int]snmp.InterfaceInfo{
+		10101: {Name: name("Gi1/0/1")},
+		10102: {Name: name("Gi1/0/2")},
+		1:     {Name: name("Vlan1")},
+	}
+	ifIndexToInterfaceID := map[int]string{10101: "if-1", 10102: "if-2", 1: "if-vlan1"}
+
+	if got := w.applyPoE(context.Background(), "sw-1", ports, ifaces, ifIndexToInterfaceID, now); got != 2 {
+		t.Fatalf("expected 2 ports written, got %d", got)
+	}
+	byInterface := map[string]sqlcgen.UpsertInterfacePoEParams{}
+	for _, p := range written {
+		byInterface[p.InterfaceID] = p
+	}
+	first, ok := byInterface["if-1"]
+	if !ok || first.DeviceID != "sw-1" || first.DetectionStatus != "delivering_power" {
+		t.Fatalf("unexpected upsert for if-1: %+v", first)
+	}
+	if first.PowerMW == nil || *first.PowerMW != 6400 || first.PowerClass == nil || *first.PowerClass != "class3" {
+		t.Fatalf("expected power fields on if-1, got %+v", first)
+	}
+	if _, ok := byInterface["if-2"]; !ok {
+		t.Fatalf("expected if-2 to be written, got %+v", written)
+	}
+
+	switches := &poeSwitches{}
+	switches.add("sw-1")
+	if got := w.syncPoEPoweredDevices(context.Background(), switches); got != 1 || len(synced) != 1 || synced[0] != "sw-1" {
+		t.Fatalf("expected sync for sw-1, got %d %v", got, synced)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/xml"
 	"fmt"
+	"os"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -20,7 +21,31 @@ type nmapRun struct {
 }
 
 type nmapHost struct {
-	Ports []nmapPort `xml:"ports>port"`
+	Status     nmapState        `xml:"status"`
+	Addresses  []nmapAddress    `xml:"address"`
+	Hostnames  []nmapHostname   `xml:"hostnames>hostname"`
+	Ports      []nmapPort       `xml:"ports>port"`
+	ExtraPorts []nmapExtraPorts `xml:"ports>extraports"`
+	OSMatches  []nmapOSMatch    `xml:"os>osmatch"`
+}
+
+// nmapAddress is one <address> of a host; addrtype is ipv4, ipv6 or mac (with an OUI vendor).
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+	Vendor   string `xml:"vendor,attr"`
+}
+
+// nmapHostname is a name nmap associated with the host: "user" (the target as given) or "PTR".
+type nmapHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// nmapExtraPorts is nmap's collapsed summary for ports that share a (non-open) state.
+type nmapExtraPorts struct {
+	State string `xml:"state,attr"`
+	Count int    `xml:"count,attr"`
 }
 
 type nmapPort struct {
@@ -35,27 +60,43 @@ type nmapState struct {
 }
 
 type nmapService struct {
-	Name string `xml:"name,attr"`
+	Name      string   `xml:"name,attr"`
+	Product   string   `xml:"product,attr"`
+	Version   string   `xml:"version,attr"`
+	ExtraInfo string   `xml:"extrainfo,attr"`
+	CPE       []string `xml:"cpe"`
+}
+
+// nmapOSMatch is one -O guess; nmap lists them best-first with one or more classes each.
+type nmapOSMatch struct {
+	Name     string        `xml:"name,attr"`
+	Accuracy int           `xml:"accuracy,attr"`
+	Classes  []nmapOSClass `xml:"osclass"`
 }
 
+type nmapOSClass struct {
+	Type     string   `xml:"type,attr"`
+	Vendor   string   `xml:"vendor,attr"`
+	OSFamily string   `xml:"osfamily,attr"`
+	OSGen    string   `xml:"osgen,attr"`
+	Accuracy int      `xml:"accuracy,attr"`
+	CPE      []string `xml:"cpe"`
+}
+
+const (
+	// nmapVersionScanMinTimeout is the per-host budget floor once -sV/-O probes are added.
+	nmapVersionScanMinTimeout = 30 * time.Second
+	nmapOSGuessLimit          = 3
+)
+
 type portScanTarget struct {
 	DeviceID string
 	IP       string
 }
 
-func (w *Worker) runPortScan(ctx context.Context, targets []enrichmentTarget) map[string]any {
-	if w == nil || w.q == nil || !w.portScanEnabled {
-		return nil
-	}
-	if len(w.portScanAllowlist) == 0 || len(w.portScanPorts) == 0 {
-		return map[string]any{"enabled": true, "available": false, "reason": "no_allowlist_or_ports"}
-	}
-
-	nmapPath, err := exec.LookPath("nmap")
-	if err != nil {
-		return map[string]any{"enabled": true, "available": false, "reason": "nmap_not_found"}
-	}
-
+// portScanTargets picks one allowlisted address per device, capped at portScanMaxTargets.
+// Active service scans (TCP and UDP) share the same allowlist and target budget.
+func (w *Worker) portScanTargets(targets []enrichmentTarget) []portScanTarget {
 	seenDevice := map[string]struct{}{}
 	scanTargets := make([]portScanTarget, 0, len(targets))
 	for _, t := range targets {
@@ -71,25 +112,177 @@ func (w *Worker) runPortScan(ctx context.Context, targets []enrichmentTarget) ma
 			break
 		}
 	}
+	return scanTargets
+}
+
+// nmapScanStates maps each scanned TCP port to open|closed|filtered for a single-host nmap run.
+//
+// nmap lists interesting ports individually and folds the rest into <extraports>; ports it did not
+// list inherit the extraports state when there is exactly one group. ok=false means nmap reported no
+// host (e.g. host timeout), so callers must not reconcile anything.
+func nmapScanStates(run nmapRun, scanned []int) (map[int]string, bool) {
+	if len(run.Hosts) == 0 {
+		return nil, false
+	}
+
+	out := make(map[int]string, len(scanned))
+	fallback := ""
+	for _, h := range run.Hosts {
+		for _, p := range h.Ports {
+			if strings.ToLower(strings.TrimSpace(p.Protocol)) != "tcp" {
+				continue
+			}
+			out[p.PortID] = normalizeNmapState(p.State.State)
+		}
+		if len(h.ExtraPorts) == 1 {
+			fallback = normalizeNmapState(h.ExtraPorts[0].State)
+		}
+	}
+	for _, port := range scanned {
+		if _, ok := out[port]; ok {
+			continue
+		}
+		if fallback == "" {
+			continue
+		}
+		out[port] = fallback
+	}
+	return out, true
+}
+
+// nmapPortScanArgs builds the nmap command line for a single host. Version detection adds -sV; OS
+// detection (-O) needs raw sockets, so callers only request it when running privileged.
+func nmapPortScanArgs(ip, ports string, timeout time.Duration, versionDetection, osDetection bool) []string {
+	args := []string{
+		"-oX", "-",
+		"-Pn",
+		"-sT",
+	}
+	if versionDetection {
+		args = append(args, "-sV")
+	}
+	if osDetection {
+		args = append(args, "-O", "--osscan-limit")
+	}
+	return append(args,
+		"--host-timeout", timeout.String(),
+		"--max-retries", "1",
+		"-p", ports,
+		ip,
+	)
+}
+
+// nmapOSGuesses flattens the best -O matches (at most limit) using each match's most accurate class.
+func nmapOSGuesses(run nmapRun, limit int) []tagging.OSGuess {
+	var out []tagging.OSGuess
+	for _, h := range run.Hosts {
+		for _, m := range h.OSMatches {
+			name := strings.TrimSpace(m.Name)
+			if name == "" {
+				continue
+			}
+			guess := tagging.OSGuess{Name: name, Accuracy: m.Accuracy}
+			best := -1
+			for i, c := range m.Classes {
+				if best < 0 || c.Accuracy > m.Classes[best].Accuracy {
+					best = i
+				}
+			}
+			if best >= 0 {
+				c := m.Classes[best]
+				guess.Vendor = strings.TrimSpace(c.Vendor)
+				guess.Family = strings.TrimSpace(c.OSFamily)
+				guess.Generation = strings.TrimSpace(c.OSGen)
+				guess.DeviceType = strings.TrimSpace(c.Type)
+			}
+			for _, c := range m.Classes {
+				guess.CPE = appendUnique(guess.CPE, c.CPE...)
+			}
+			out = append(out, guess)
+			if len(out) >= limit {
+				return out
+			}
+		}
+	}
+	return out
+}
+
+func appendUnique(dst []string, values ...string) []string {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		dup := false
+		for _, existing := range dst {
+			if existing == v {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			dst = append(dst, v)
+		}
+	}
+	return dst
+}
+
+func normalizeNmapState(state string) string {
+	switch strings.ToLower(strings.TrimSpace(state)) {
+	case "open":
+		return "open"
+	case "closed":
+		return "closed"
+	default:
+		// filtered, open|filtered, closed|filtered, unfiltered: no definitive answer.
+		return "filtered"
+	}
+}
+
+func (w *Worker) runPortScan(ctx context.Context, targets []enrichmentTarget) map[string]any {
+	if w == nil || w.q == nil || !w.portScanEnabled {
+		return nil
+	}
+	if len(w.portScanAllowlist) == 0 || len(w.portScanPorts) == 0 {
+		return map[string]any{"enabled": true, "available": false, "reason": "no_allowlist_or_ports"}
+	}
+
+	nmapPath, err := exec.LookPath("nmap")
+	if err != nil {
+		return map[string]any{"enabled": true, "available": false, "reason": "nmap_not_found"}
+	}
+
+	scanTargets := w.portScanTargets(targets)
 	if len(scanTargets) == 0 {
 		return map[string]any{"enabled": true, "available": true, "targets": 0, "services_written": 0}
 	}
 
 	ports := make([]string, 0, len(w.portScanPorts))
+	scannedPorts := make([]int, 0, len(w.portScanPorts))
 	for _, p := range w.portScanPorts {
 		if p <= 0 || p > 65535 {
 			continue
 		}
 		ports = append(ports, strconv.Itoa(p))
+		scannedPorts = append(scannedPorts, p)
 	}
 	if len(ports) == 0 {
 		return map[string]any{"enabled": true, "available": true, "targets": len(scanTargets), "services_written": 0}
 	}
 	portArg := strings.Join(ports, ",")
 
+	versionDetection := w.portScanVersionDetection
+	osDetection := versionDetection && os.Geteuid() == 0
+	scanTimeout := w.portScanTimeout
+	if versionDetection {
+		scanTimeout = maxDuration(scanTimeout, nmapVersionScanMinTimeout)
+	}
+
 	var attempted int32
 	var succeeded int32
 	var servicesWritten int32
+	var servicesClosed int32
+	var osGuessesWritten int32
 
 	jobs := make(chan portScanTarget)
 	wg := sync.WaitGroup{}
@@ -102,17 +295,8 @@ func (w *Worker) runPortScan(ctx context.Context, targets []enrichmentTarget) ma
 			}
 			atomic.AddInt32(&attempted, 1)
 
-			scanCtx, cancel := context.WithTimeout(ctx, w.portScanTimeout)
-			out, err := exec.CommandContext(scanCtx, nmapPath,
-				"-oX", "-",
-				"-Pn",
-				"-sT",
-				"--host-timeout", w.portScanTimeout.String(),
-				"--max-retries", "1",
-				"--open",
-				"-p", portArg,
-				t.IP,
-			).Output()
+			scanCtx, cancel := context.WithTimeout(ctx, scanTimeout)
+			out, err := exec.CommandContext(scanCtx, nmapPath, nmapPortScanArgs(t.IP, portArg, scanTimeout, versionDetection, osDetection)...).Output()
 			cancel()
 			if err != nil {
 				continue
@@ -124,45 +308,107 @@ func (w *Worker) runPortScan(ctx context.Context, targets []enrichmentTarget) ma
 				continue
 			}
 
+			states, ok := nmapScanStates(run, scannedPorts)
+			if !ok {
+				continue
+			}
+
 			now := time.Now()
 			source := "nmap"
 			state := "open"
 			openPorts := make([]int32, 0, 8)
 
+			services := map[int]nmapService{}
+			var cpes []string
 			for _, h := range run.Hosts {
 				for _, p := range h.Ports {
-					if strings.ToLower(p.State.State) != "open" {
-						continue
-					}
-					proto := strings.ToLower(strings.TrimSpace(p.Protocol))
-					if proto != "tcp" && proto != "udp" {
-						continue
-					}
-					if p.PortID <= 0 || p.PortID > 65535 {
-						continue
-					}
-					openPorts = append(openPorts, int32(p.PortID))
-					var name *string
-					if s := strings.TrimSpace(p.Service.Name); s != "" {
-						name = &s
+					services[p.PortID] = p.Service
+					if normalizeNmapState(p.State.State) == "open" {
+						cpes = appendUnique(cpes, p.Service.CPE...)
 					}
+				}
+			}
 
-					if err := w.q.UpsertServiceFromScan(ctx, sqlcgen.UpsertServiceFromScanParams{
+			for _, port := range scannedPorts {
+				portState, ok := states[port]
+				if !ok {
+					continue
+				}
+				if portState != "open" {
+					// Reconcile: previously open ports that no longer answer are closed (RST) or filtered (no reply).
+					if n, err := w.q.MarkServiceNotOpen(ctx, sqlcgen.MarkServiceNotOpenParams{
 						DeviceID:   t.DeviceID,
-						Protocol:   proto,
-						Port:       int32(p.PortID),
-						Name:       name,
-						State:      &state,
+						Protocol:   "tcp",
+						Port:       int32(port),
+						State:      portState,
 						Source:     &source,
 						ObservedAt: now,
-					}); err == nil {
-						atomic.AddInt32(&servicesWritten, 1)
+					}); err == nil && n > 0 {
+						atomic.AddInt32(&servicesClosed, int32(n))
 					}
+					continue
+				}
+
+				openPorts = append(openPorts, int32(port))
+				svc := services[port]
+				var cpe []string
+				if len(svc.CPE) > 0 {
+					cpe = appendUnique(nil, svc.CPE...)
+				}
+
+				if err := w.q.UpsertServiceFromScan(ctx, sqlcgen.UpsertServiceFromScanParams{
+					DeviceID:   t.DeviceID,
+					Protocol:   "tcp",
+					Port:       int32(port),
+					Name:       optionalString(svc.Name),
+					State:      &state,
+					Source:     &source,
+					ObservedAt: now,
+					Product:    optionalString(svc.Product),
+					Version:    optionalString(svc.Version),
+					ExtraInfo:  optionalString(svc.ExtraInfo),
+					CPE:        cpe,
+				}); err == nil {
+					atomic.AddInt32(&servicesWritten, 1)
 				}
 			}
 
+			guesses := nmapOSGuesses(run, nmapOSGuessLimit)
+			for i, g := range guesses {
+				if err := w.q.UpsertDeviceOSGuess(ctx, sqlcgen.UpsertDeviceOSGuessParams{
+					DeviceID:     t.DeviceID,
+					Source:       source,
+					Rank:         int32(i + 1),
+					Name:         g.Name,
+					Accuracy:     int32(g.Accuracy),
+					Vendor:       optionalString(g.Vendor),
+					OSFamily:     optionalString(g.Family),
+					OSGeneration: optionalString(g.Generation),
+					DeviceType:   optionalString(g.DeviceType),
+					CPE:          g.CPE,
+					ObservedAt:   now,
+				}); err == nil {
+					atomic.AddInt32(&osGuessesWritten, 1)
+				}
+			}
+			if len(guesses) > 0 {
+				_ = w.q.DeleteDeviceOSGuessesAboveRank(ctx, sqlcgen.DeleteDeviceOSGuessesAboveRankParams{
+					DeviceID: t.DeviceID,
+					Source:   source,
+					Rank:     int32(len(guesses)),
+				})
+			}
+
+			var groups [][]tagging.Suggestion
 			if len(openPorts) > 0 {
-				suggestions := tagging.MergeSuggestions(tagging.SuggestFromOpenPorts(openPorts))
+				groups = append(groups, tagging.SuggestFromOpenPorts(openPorts))
+			}
+			if len(guesses) > 0 {
+				groups = append(groups, tagging.SuggestFromOS(guesses[0]))
+				cpes = appendUnique(cpes, guesses[0].CPE...)
+			}
+			groups = append(groups, tagging.SuggestFromCPE(cpes))
+			if suggestions := tagging.MergeSuggestions(groups...); len(suggestions) > 0 {
 				for _, s := range suggestions {
 					if s.Evidence == nil {
 						s.Evidence = map[string]any{}
@@ -202,6 +448,8 @@ func (w *Worker) runPortScan(ctx context.Context, targets []enrichmentTarget) ma
 				"attempted":        int(attempted),
 				"succeeded":        int(succeeded),
 				"services_written": int(servicesWritten),
+				"services_closed":  int(servicesClosed),
+				"os_guesses":       int(osGuessesWritten),
 				"canceled":         true,
 			}
 		case jobs <- t:
@@ -211,14 +459,18 @@ func (w *Worker) runPortScan(ctx context.Context, targets []enrichmentTarget) ma
 	wg.Wait()
 
 	return map[string]any{
-		"enabled":          true,
-		"available":        true,
-		"targets":          len(scanTargets),
-		"attempted":        int(attempted),
-		"succeeded":        int(succeeded),
-		"services_written": int(servicesWritten),
-		"ports":            portArg,
-		"timeout":          w.portScanTimeout.String(),
+		"enabled":           true,
+		"available":         true,
+		"targets":           len(scanTargets),
+		"attempted":         int(attempted),
+		"succeeded":         int(succeeded),
+		"services_written":  int(servicesWritten),
+		"services_closed":   int(servicesClosed),
+		"os_guesses":        int(osGuessesWritten),
+		"version_detection": versionDetection,
+		"os_detection":      osDetection,
+		"ports":             portArg,
+		"timeout":           scanTimeout.String(),
 	}
 }
 
@@ -232,5 +484,5 @@ func (w *Worker) portScanLogMessage(stats map[string]any) string {
 		}
 		return "port scan skipped"
 	}
-	return fmt.Sprintf("port scan: targets=%v attempted=%v succeeded=%v services=%v", stats["targets"], stats["attempted"], stats["succeeded"], stats["services_written"])
+	return fmt.Sprintf("port scan: targets=%v attempted=%v succeeded=%v services=%v closed=%v", stats["targets"], stats["attempted"], stats["succeeded"], stats["services_written"], stats["services_closed"])
 }
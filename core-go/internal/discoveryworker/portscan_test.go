@@ -0,0 +1,148 @@
+package discoveryworker
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func TestNmapScanStates(t *testing.T) {
+	cases := []struct {
+		name    string
+		xml     string
+		scanned []int
+		want    map[int]string
+		ok      bool
+	}{
+		{
+			name:    "host timed out",
+			xml:     `<nmaprun></nmaprun>`,
+			scanned: []int{22, 80},
+			ok:      false,
+		},
+		{
+			name: "explicit ports plus extraports fallback",
+			xml: `<nmaprun><host><ports>
+				<extraports state="closed" count="1"></extraports>
+				<port protocol="tcp" portid="22"><state state="open"/><service name="ssh"/></port>
+				<port protocol="tcp" portid="443"><state state="filtered"/></port>
+			</ports></host></nmaprun>`,
+			scanned: []int{22, 80, 443},
+			want:    map[int]string{22: "open", 80: "closed", 443: "filtered"},
+			ok:      true,
+		},
+		{
+			name: "ambiguous extraports leaves unlisted ports alone",
+			xml: `<nmaprun><host><ports>
+				<extraports state="closed" count="1"></extraports>
+				<extraports state="filtered" count="1"></extraports>
+				<port protocol="tcp" portid="22"><state state="open|filtered"/></port>
+			</ports></host></nmaprun>`,
+			scanned: []int{22, 80, 443},
+			want:    map[int]string{22: "filtered"},
+			ok:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var run nmapRun
+			if err := xml.Unmarshal([]byte(tc.xml), &run); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			got, ok := nmapScanStates(run, tc.scanned)
+			if ok != tc.ok {
+				t.Fatalf("expected ok=%v, got %v", tc.ok, ok)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+			for port, state := range tc.want {
+				if got[port] != state {
+					t.Fatalf("port %d: expected %q, got %q (%v)", port, state, got[port], got)
+				}
+			}
+		})
+	}
+}
+
+func TestNmapPortScanArgs(t *testing.T) {
+	cases := []struct {
+		name    string
+		version bool
+		os      bool
+		want    []string
+		absent  []string
+	}{
+		{name: "plain connect scan", absent: []string{"-sV", "-O"}},
+		{name: "version detection", version: true, want: []string{"-sV"}, absent: []string{"-O"}},
+		{name: "version and os detection", version: true, os: true, want: []string{"-sV", "-O", "--osscan-limit"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := nmapPortScanArgs("192.0.2.10", "22,80", 30*time.Second, tc.version, tc.os)
+			has := map[string]bool{}
+			for _, a := range args {
+				has[a] = true
+			}
+			for _, w := range tc.want {
+				if !has[w] {
+					t.Fatalf("expected %s in %v", w, args)
+				}
+			}
+			for _, a := range tc.absent {
+				if has[a] {
+					t.Fatalf("did not expect %s in %v", a, args)
+				}
+			}
+			if args[len(args)-1] != "192.0.2.10" || !has["-sT"] || !has["30s"] {
+				t.Fatalf("unexpected args %v", args)
+			}
+		})
+	}
+}
+
+func TestNmapOSGuessesAndServiceDetails(t *testing.T) {
+	raw := `<nmaprun><host>
+		<ports>
+			<port protocol="tcp" portid="22"><state state="open"/>
+				<service name="ssh" product="OpenSSH" version="9.6p1 Ubuntu 3ubuntu13" extrainfo="Ubuntu Linux; protocol 2.0">
+					<cpe>cpe:/a:openbsd:openssh:9.6p1</cpe><cpe>cpe:/o:linux:linux_kernel</cpe>
+				</service>
+			</port>
+		</ports>
+		<os>
+			<osmatch name="Linux 5.0 - 5.14" accuracy="98">
+				<osclass type="general purpose" vendor="Linux" osfamily="Linux" osgen="5.X" accuracy="98"><cpe>cpe:/o:linux:linux_kernel:5</cpe></osclass>
+			</osmatch>
+			<osmatch name="MikroTik RouterOS 7.2 - 7.5 (Linux 5.6.3)" accuracy="91">
+				<osclass type="router" vendor="MikroTik" osfamily="RouterOS" osgen="7.X" accuracy="91"><cpe>cpe:/o:mikrotik:routeros:7</cpe></osclass>
+				<osclass type="general purpose" vendor="Linux" osfamily="Linux" osgen="5.X" accuracy="88"><cpe>cpe:/o:linux:linux_kernel:5.6.3</cpe></osclass>
+			</osmatch>
+			<osmatch name="Linux 4.15 - 5.8" accuracy="90"><osclass type="general purpose" vendor="Linux" osfamily="Linux" osgen="4.X" accuracy="90"/></osmatch>
+			<osmatch name="Linux 2.6.32" accuracy="85"><osclass type="general purpose" vendor="Linux" osfamily="Linux" osgen="2.6.X" accuracy="85"/></osmatch>
+		</os>
+	</host></nmaprun>`
+
+	var run nmapRun
+	if err := xml.Unmarshal([]byte(raw), &run); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	svc := run.Hosts[0].Ports[0].Service
+	if svc.Product != "OpenSSH" || svc.Version != "9.6p1 Ubuntu 3ubuntu13" || len(svc.CPE) != 2 {
+		t.Fatalf("unexpected service details %+v", svc)
+	}
+
+	guesses := nmapOSGuesses(run, nmapOSGuessLimit)
+	if len(guesses) != 3 {
+		t.Fatalf("expected 3 guesses, got %d", len(guesses))
+	}
+	if guesses[0].Family != "Linux" || guesses[0].Generation != "5.X" || guesses[0].Accuracy != 98 {
+		t.Fatalf("unexpected best guess %+v", guesses[0])
+	}
+	second := guesses[1]
+	if second.DeviceType != "router" || second.Vendor != "MikroTik" || len(second.CPE) != 2 {
+		t.Fatalf("expected most accurate class and merged cpe, got %+v", second)
+	}
+}
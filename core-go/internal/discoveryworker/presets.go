@@ -87,33 +87,45 @@ func applyScanPreset(w *Worker, preset string) func() {
 	}
 
 	prev := struct {
-		maxRuntime          time.Duration
-		maxTargets          int
-		pingTimeout         time.Duration
-		pingWorkers         int
-		enrichMaxTargets    int
-		enrichWorkers       int
-		snmpEnabled         bool
-		topologyLLDPEnabled bool
-		topologyCDPEnabled  bool
-		portScanEnabled     bool
-		portScanWorkers     int
-		portScanTimeout     time.Duration
-		portScanMaxTargets  int
+		maxRuntime               time.Duration
+		maxTargets               int
+		pingTimeout              time.Duration
+		pingWorkers              int
+		enrichMaxTargets         int
+		enrichWorkers            int
+		snmpEnabled              bool
+		topologyLLDPEnabled      bool
+		topologyCDPEnabled       bool
+		portScanEnabled          bool
+		portScanWorkers          int
+		portScanTimeout          time.Duration
+		portScanMaxTargets       int
+		portScanVersionDetection bool
+		udpProbeEnabled          bool
+		tlsInventoryEnabled      bool
+		httpFingerprintEnabled   bool
+		sshHostKeysEnabled       bool
+		tracerouteEnabled        bool
 	}{
-		maxRuntime:          w.maxRuntime,
-		maxTargets:          w.maxTargets,
-		pingTimeout:         w.pingTimeout,
-		pingWorkers:         w.pingWorkers,
-		enrichMaxTargets:    w.enrichMaxTargets,
-		enrichWorkers:       w.enrichWorkers,
-		snmpEnabled:         w.snmpEnabled,
-		topologyLLDPEnabled: w.topologyLLDPEnabled,
-		topologyCDPEnabled:  w.topologyCDPEnabled,
-		portScanEnabled:     w.portScanEnabled,
-		portScanWorkers:     w.portScanWorkers,
-		portScanTimeout:     w.portScanTimeout,
-		portScanMaxTargets:  w.portScanMaxTargets,
+		maxRuntime:               w.maxRuntime,
+		maxTargets:               w.maxTargets,
+		pingTimeout:              w.pingTimeout,
+		pingWorkers:              w.pingWorkers,
+		enrichMaxTargets:         w.enrichMaxTargets,
+		enrichWorkers:            w.enrichWorkers,
+		snmpEnabled:              w.snmpEnabled,
+		topologyLLDPEnabled:      w.topologyLLDPEnabled,
+		topologyCDPEnabled:       w.topologyCDPEnabled,
+		portScanEnabled:          w.portScanEnabled,
+		portScanWorkers:          w.portScanWorkers,
+		portScanTimeout:          w.portScanTimeout,
+		portScanMaxTargets:       w.portScanMaxTargets,
+		portScanVersionDetection: w.portScanVersionDetection,
+		udpProbeEnabled:          w.udpProbeEnabled,
+		tlsInventoryEnabled:      w.tlsInventoryEnabled,
+		httpFingerprintEnabled:   w.httpFingerprintEnabled,
+		sshHostKeysEnabled:       w.sshHostKeysEnabled,
+		tracerouteEnabled:        w.tracerouteEnabled,
 	}
 
 	switch preset {
@@ -128,6 +140,12 @@ func applyScanPreset(w *Worker, preset string) func() {
 		w.topologyLLDPEnabled = false
 		w.topologyCDPEnabled = false
 		w.portScanEnabled = false
+		w.portScanVersionDetection = false
+		w.udpProbeEnabled = false
+		w.tlsInventoryEnabled = false
+		w.httpFingerprintEnabled = false
+		w.sshHostKeysEnabled = false
+		w.tracerouteEnabled = false
 	case ScanPresetDeep:
 		w.maxRuntime = maxDuration(w.maxRuntime, 2*time.Minute)
 		w.maxTargets = maxInt(w.maxTargets, 4096)
@@ -142,6 +160,12 @@ func applyScanPreset(w *Worker, preset string) func() {
 		w.portScanWorkers = maxInt(w.portScanWorkers, 8)
 		w.portScanTimeout = maxDuration(w.portScanTimeout, 5*time.Second)
 		w.portScanMaxTargets = maxInt(w.portScanMaxTargets, 64)
+		w.portScanVersionDetection = true
+		w.udpProbeEnabled = true
+		w.tlsInventoryEnabled = true
+		w.httpFingerprintEnabled = true
+		w.sshHostKeysEnabled = true
+		w.tracerouteEnabled = true
 	default:
 		// normal: preserve configured values
 	}
@@ -160,5 +184,11 @@ func applyScanPreset(w *Worker, preset string) func() {
 		w.portScanWorkers = prev.portScanWorkers
 		w.portScanTimeout = prev.portScanTimeout
 		w.portScanMaxTargets = prev.portScanMaxTargets
+		w.portScanVersionDetection = prev.portScanVersionDetection
+		w.udpProbeEnabled = prev.udpProbeEnabled
+		w.tlsInventoryEnabled = prev.tlsInventoryEnabled
+		w.httpFingerprintEnabled = prev.httpFingerprintEnabled
+		w.sshHostKeysEnabled = prev.sshHostKeysEnabled
+		w.tracerouteEnabled = prev.tracerouteEnabled
 	}
 }
@@ -0,0 +1,153 @@
+package discoveryworker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"roller_hoops/core-go/internal/enrichment/snmp"
+	"roller_hoops/core-go/internal/sqlcgen"
+	"roller_hoops/core-go/internal/tagging"
+)
+
+// Routing adjacency link types.
+const (
+	linkTypeOSPF = "ospf"
+	linkTypeBGP  = "bgp"
+)
+
+// routingPolled records which routers had their OSPF / BGP tables read, so adjacencies nobody reported
+// can be marked down once every target has been polled.
+type routingPolled struct {
+	mu   sync.Mutex
+	ospf []string
+	bgp  []string
+}
+
+func (p *routingPolled) add(linkType, deviceID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch linkType {
+	case linkTypeOSPF:
+		p.ospf = append(p.ospf, deviceID)
+	case linkTypeBGP:
+		p.bgp = append(p.bgp, deviceID)
+	}
+}
+
+// routingAdjacency is one neighbor/peer row as reported by the polled router.
+type routingAdjacency struct {
+	linkType     string
+	peerAddrs    []string // neighbor address first, then router ID / BGP identifier
+	state        string
+	localAS      *int64
+	peerAS       *int64
+	area         *string
+	lastChangeAt *time.Time
+}
+
+// collectRoutingAdjacencies reads the OSPF neighbor and BGP peer tables of a router-tagged device and
+// upserts one adjacency link per resolved peer. It returns the number of state transitions written.
+func (w *Worker) collectRoutingAdjacencies(ctx context.Context, client *snmp.Client, target snmp.Target, deviceID string, polled *routingPolled, now time.Time) int {
+	if ok, err := w.q.DeviceHasTag(ctx, deviceID, tagging.TagRouter); err != nil || !ok {
+		return 0
+	}
+
+	var adjacencies []routingAdjacency
+	if neighbors, err := client.WalkOSPFNeighbors(ctx, target); err == nil {
+		polled.add(linkTypeOSPF, deviceID)
+		for _, n := range neighbors {
+			adj := routingAdjacency{linkType: linkTypeOSPF, peerAddrs: []string{n.IP}, state: n.State, area: n.Area}
+			if n.RouterID != nil {
+				adj.peerAddrs = append(adj.peerAddrs, *n.RouterID)
+			}
+			adjacencies = append(adjacencies, adj)
+		}
+	}
+	if peers, err := client.WalkBGPPeers(ctx, target); err == nil {
+		polled.add(linkTypeBGP, deviceID)
+		for _, p := range peers {
+			adj := routingAdjacency{linkType: linkTypeBGP, peerAddrs: []string{p.RemoteAddr}, state: p.State, localAS: p.LocalAS, peerAS: p.RemoteAS}
+			if p.Identifier != nil {
+				adj.peerAddrs = append(adj.peerAddrs, *p.Identifier)
+			}
+			if p.StateAge != nil {
+				at := now.Add(-time.Duration(*p.StateAge) * time.Second)
+				adj.lastChangeAt = &at
+			}
+			adjacencies = append(adjacencies, adj)
+		}
+	}
+	return w.applyRoutingAdjacencies(ctx, deviceID, adjacencies, now)
+}
+
+// applyRoutingAdjacencies resolves each peer to a known device (by neighbor address, then router ID) and
+// writes the adjacency. Peers that match no device are skipped.
+func (w *Worker) applyRoutingAdjacencies(ctx context.Context, deviceID string, adjacencies []routingAdjacency, now time.Time) int {
+	transitions := 0
+	for _, adj := range adjacencies {
+		peerID := ""
+		for _, addr := range adj.peerAddrs {
+			if id, err := w.q.FindDeviceIDByIP(ctx, addr); err == nil && id != "" {
+				peerID = id
+				break
+			}
+		}
+		if peerID == "" || peerID == deviceID {
+			continue
+		}
+
+		aDev, _, bDev, _ := canonicalizeLinkEndpoints(deviceID, nil, peerID, nil)
+		aAS, bAS := adj.localAS, adj.peerAS
+		if aDev != deviceID {
+			aAS, bAS = bAS, aAS
+		}
+		n, err := w.q.UpsertRoutingAdjacency(ctx, sqlcgen.UpsertRoutingAdjacencyParams{
+			LinkKey:      makeLinkKey(adj.linkType, aDev, nil, bDev, nil),
+			ADeviceID:    aDev,
+			BDeviceID:    bDev,
+			LinkType:     adj.linkType,
+			State:        adj.state,
+			AAS:          aAS,
+			BAS:          bAS,
+			Area:         adj.area,
+			LastChangeAt: adj.lastChangeAt,
+			ObservedAt:   now,
+		})
+		if err != nil {
+			w.log.Debug().Err(err).Str("device_id", deviceID).Str("peer_device_id", peerID).Str("link_type", adj.linkType).Msg("routing adjacency upsert failed")
+			continue
+		}
+		transitions += int(n)
+	}
+	return transitions
+}
+
+// markStaleRoutingAdjacencies marks adjacencies of the polled routers that were not reported during this
+// run (by either end) as down.
+func (w *Worker) markStaleRoutingAdjacencies(ctx context.Context, polled *routingPolled, before time.Time) int {
+	if polled == nil {
+		return 0
+	}
+	transitions := 0
+	for _, linkType := range []string{linkTypeOSPF, linkTypeBGP} {
+		deviceIDs := polled.ospf
+		if linkType == linkTypeBGP {
+			deviceIDs = polled.bgp
+		}
+		if len(deviceIDs) == 0 {
+			continue
+		}
+		n, err := w.q.MarkStaleRoutingAdjacenciesDown(ctx, sqlcgen.MarkStaleRoutingAdjacenciesDownParams{
+			DeviceIDs: deviceIDs,
+			LinkType:  linkType,
+			Before:    before,
+		})
+		if err != nil {
+			w.log.Debug().Err(err).Str("link_type", linkType).Msg("mark stale routing adjacencies failed")
+			continue
+		}
+		transitions += int(n)
+	}
+	return transitions
+}
@@ -0,0 +1,89 @@
+package discoveryworker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+func TestApplyRoutingAdjacencies_ResolvesPeersAndOrientsAS(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var upserts []sqlcgen.UpsertRoutingAdjacencyParams
+	q := &fakeQueries{
+		findByIPFn: func(ctx context.Context, ip string) (string, error) {
+			switch ip {
+			case "10.255.0.2": // router ID (loopback) only
+				return "dev-b", nil
+			case "192.0.2.9":
+				return "dev-0", nil
+			case "10.0.0.1":
+				return "dev-r", nil
+			}
+			return "", pgx.ErrNoRows
+		},
+		upsertAdjacencyFn: func(ctx context.Context, arg sqlcgen.UpsertRoutingAdjacencyParams) (int64, error) {
+			upserts = append(upserts, arg)
+			return 1, nil
+		},
+	}
+	w := New(zerolog.Nop(), q, Options{}, nil)
+
+	localAS, peerAS := int64(65001), int64(65009)
+	area := "0.0.0.0"
+	changed := now.Add(-time.Hour)
+	got := w.applyRoutingAdjacencies(context.Background(), "dev-r", []routingAdjacency{
+		{linkType: linkTypeOSPF, peerAddrs: []string{"10.0.0.2", "10.255.0.2"}, state: "full", area: &area},
+		{linkType: linkTypeBGP, peerAddrs: []string{"192.0.2.9"}, state: "established", localAS: &localAS, peerAS: &peerAS, lastChangeAt: &changed},
+		{linkType: linkTypeBGP, peerAddrs: []string{"198.51.100.1"}, state: "active"}, // unknown peer
+		{linkType: linkTypeOSPF, peerAddrs: []string{"10.0.0.1"}, state: "full"},      // resolves to itself
+	}, now)
+	if got != 2 || len(upserts) != 2 {
+		t.Fatalf("expected 2 adjacencies written, got %d (%d upserts)", got, len(upserts))
+	}
+
+	ospf := upserts[0]
+	if ospf.LinkKey != "ospf:dev-b:-:dev-r:-" || ospf.ADeviceID != "dev-b" || ospf.BDeviceID != "dev-r" || ospf.State != "full" {
+		t.Fatalf("unexpected ospf upsert: %+v", ospf)
+	}
+	if ospf.Area == nil || *ospf.Area != "0.0.0.0" || ospf.LastChangeAt != nil {
+		t.Fatalf("unexpected ospf area/last change: %+v", ospf)
+	}
+
+	bgp := upserts[1]
+	if bgp.ADeviceID != "dev-0" || bgp.BDeviceID != "dev-r" {
+		t.Fatalf("unexpected bgp endpoints: %+v", bgp)
+	}
+	// dev-r sorts second, so its local AS belongs to the b side.
+	if bgp.AAS == nil || *bgp.AAS != 65009 || bgp.BAS == nil || *bgp.BAS != 65001 {
+		t.Fatalf("expected a_as=65009 b_as=65001, got %v %v", bgp.AAS, bgp.BAS)
+	}
+	if bgp.LastChangeAt == nil || !bgp.LastChangeAt.Equal(changed) {
+		t.Fatalf("expected last change %v, got %v", changed, bgp.LastChangeAt)
+	}
+}
+
+func TestMarkStaleRoutingAdjacencies_PerProtocol(t *testing.T) {
+	before := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var calls []sqlcgen.MarkStaleRoutingAdjacenciesDownParams
+	q := &fakeQueries{
+		markAdjacenciesDownFn: func(ctx context.Context, arg sqlcgen.MarkStaleRoutingAdjacenciesDownParams) (int64, error) {
+			calls = append(calls, arg)
+			return 1, nil
+		},
+	}
+	w := New(zerolog.Nop(), q, Options{}, nil)
+
+	polled := &routingPolled{}
+	polled.add(linkTypeOSPF, "dev-r")
+	if got := w.markStaleRoutingAdjacencies(context.Background(), polled, before); got != 1 {
+		t.Fatalf("expected 1 transition, got %d", got)
+	}
+	if len(calls) != 1 || calls[0].LinkType != linkTypeOSPF || calls[0].DeviceIDs[0] != "dev-r" || !calls[0].Before.Equal(before) {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+}
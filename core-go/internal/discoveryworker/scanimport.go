@@ -0,0 +1,613 @@
+package discoveryworker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"roller_hoops/core-go/internal/identity"
+	"roller_hoops/core-go/internal/naming"
+	"roller_hoops/core-go/internal/sqlcgen"
+	"roller_hoops/core-go/internal/tagging"
+)
+
+// Offline scan formats accepted by ImportScan.
+const (
+	ScanImportFormatNmapXML     = "nmap_xml"
+	ScanImportFormatMasscanJSON = "masscan_json"
+	ScanImportFormatARPScan     = "arp_scan"
+)
+
+// ScanImportMaxHosts bounds a single import so one upload cannot monopolise the database.
+const ScanImportMaxHosts = 16384
+
+// ErrInvalidScanImport wraps every error caused by the uploaded content rather than the database.
+var ErrInvalidScanImport = errors.New("invalid scan import")
+
+// ScanImportQueries is the DB interface needed to replay offline scan results as a discovery run.
+//
+// NOTE: *sqlcgen.Queries satisfies this.
+type ScanImportQueries interface {
+	InsertDiscoveryRun(ctx context.Context, arg sqlcgen.InsertDiscoveryRunParams) (sqlcgen.DiscoveryRun, error)
+	UpdateDiscoveryRun(ctx context.Context, arg sqlcgen.UpdateDiscoveryRunParams) (sqlcgen.DiscoveryRun, error)
+	InsertDiscoveryRunLog(ctx context.Context, arg sqlcgen.InsertDiscoveryRunLogParams) error
+	CreateDevice(ctx context.Context, displayName *string) (sqlcgen.Device, error)
+	FindDeviceIDByMAC(ctx context.Context, mac string) (string, error)
+	FindDeviceIDByIP(ctx context.Context, ip string) (string, error)
+	FindDeviceIDByClientID(ctx context.Context, clientID string) (string, error)
+	FindDeviceIDByHostName(ctx context.Context, name string) (string, error)
+	FindRotatingDeviceIDByIP(ctx context.Context, arg sqlcgen.FindRotatingDeviceIDByIPParams) (string, error)
+	UpsertDeviceClientID(ctx context.Context, arg sqlcgen.UpsertDeviceClientIDParams) error
+	UnarchiveReseenDevice(ctx context.Context, arg sqlcgen.UnarchiveReseenDeviceParams) (int64, error)
+	UpsertImportedDeviceIP(ctx context.Context, arg sqlcgen.UpsertDeviceIPParams) error
+	UpsertImportedDeviceMAC(ctx context.Context, arg sqlcgen.UpsertDeviceMACParams) error
+	InsertIPObservation(ctx context.Context, arg sqlcgen.InsertIPObservationParams) error
+	InsertMACObservation(ctx context.Context, arg sqlcgen.InsertMACObservationParams) error
+	InsertDeviceNameCandidate(ctx context.Context, arg sqlcgen.InsertDeviceNameCandidateParams) error
+	UpsertServiceFromScan(ctx context.Context, arg sqlcgen.UpsertServiceFromScanParams) error
+	UpsertDeviceOSGuess(ctx context.Context, arg sqlcgen.UpsertDeviceOSGuessParams) error
+	DeleteDeviceOSGuessesAboveRank(ctx context.Context, arg sqlcgen.DeleteDeviceOSGuessesAboveRankParams) error
+	UpsertDeviceTag(ctx context.Context, arg sqlcgen.UpsertDeviceTagParams) error
+	RecordDeviceStateSnapshots(ctx context.Context, arg sqlcgen.RecordDeviceStateSnapshotsParams) (int64, error)
+}
+
+// ScanImport describes one uploaded scan result.
+type ScanImport struct {
+	Format  string
+	Content []byte
+	// Scope optionally restricts the import to a CIDR; hosts outside it are skipped.
+	Scope *string
+	// Origin is a free-form label for where the scan was taken (jump host, team).
+	Origin string
+	// MACIdentity is the MAC matching policy, as for native runs.
+	MACIdentity identity.Config
+}
+
+type scanImportHost struct {
+	IP  netip.Addr
+	MAC string
+	// IP may be invalid for hosts only seen at layer 2 (pcap imports); IP writes are skipped then.
+	Names     []naming.Candidate
+	ClientID  string // DHCP client identifier (pcap imports only)
+	Ports     []scanImportPort
+	OSGuesses []tagging.OSGuess
+}
+
+type scanImportPort struct {
+	Protocol  string
+	Port      int
+	Name      string
+	Product   string
+	Version   string
+	ExtraInfo string
+	CPE       []string
+}
+
+// ImportScan parses offline scanner output and records it as a synthetic, already-completed discovery
+// run: the same device matching (MAC, then IP), IP/MAC observations, services and OS guesses as a
+// native run, so imported facts show up in history and the change feed.
+//
+// Only positive results are applied; an import never marks services closed because the scan's port
+// coverage is unknown. Content errors are returned wrapped in ErrInvalidScanImport before any run is
+// created.
+func ImportScan(ctx context.Context, q ScanImportQueries, in ScanImport) (sqlcgen.DiscoveryRun, error) {
+	hosts, err := parseScanImport(in.Format, in.Content)
+	if err != nil {
+		return sqlcgen.DiscoveryRun{}, fmt.Errorf("%w: %s", ErrInvalidScanImport, err.Error())
+	}
+	if len(hosts) == 0 {
+		return sqlcgen.DiscoveryRun{}, fmt.Errorf("%w: no hosts found", ErrInvalidScanImport)
+	}
+	if len(hosts) > ScanImportMaxHosts {
+		return sqlcgen.DiscoveryRun{}, fmt.Errorf("%w: %d hosts exceeds limit of %d", ErrInvalidScanImport, len(hosts), ScanImportMaxHosts)
+	}
+	scope, err := parseDiscoveryScope(in.Scope)
+	if err != nil {
+		return sqlcgen.DiscoveryRun{}, fmt.Errorf("%w: invalid scope: %s", ErrInvalidScanImport, err.Error())
+	}
+
+	importStats := map[string]any{
+		"format": in.Format,
+		"hosts":  len(hosts),
+	}
+	if origin := strings.TrimSpace(in.Origin); origin != "" {
+		importStats["origin"] = origin
+	}
+	run, err := q.InsertDiscoveryRun(ctx, sqlcgen.InsertDiscoveryRunParams{
+		Status: "running",
+		Scope:  scopePrefixOrNil(scope),
+		Stats: map[string]any{
+			"stage":  "importing",
+			"method": "import",
+			"import": importStats,
+		},
+	})
+	if err != nil {
+		return sqlcgen.DiscoveryRun{}, err
+	}
+
+	_ = q.InsertDiscoveryRunLog(ctx, sqlcgen.InsertDiscoveryRunLogParams{
+		RunID:   run.ID,
+		Level:   "info",
+		Message: fmt.Sprintf("scan import started: format=%s hosts=%d", in.Format, len(hosts)),
+	})
+
+	source := scanImportSource(in.Format)
+	now := time.Now()
+	counts := map[string]int{}
+	for _, h := range hosts {
+		if scope != nil && !scope.Contains(h.IP) {
+			counts["hosts_skipped"]++
+			continue
+		}
+		if err := importScanHost(ctx, q, in.MACIdentity, run.ID, source, now, h, counts); err != nil {
+			return failScanImport(ctx, q, run.ID, err, importStats, counts)
+		}
+	}
+
+	// Imported tags only become history once snapshotted; a failure here leaves the import itself intact.
+	_, _ = q.RecordDeviceStateSnapshots(ctx, sqlcgen.RecordDeviceStateSnapshotsParams{Actor: sqlcgen.RunActor(run.ID)})
+
+	for k, v := range counts {
+		importStats[k] = v
+	}
+	completedAt := time.Now()
+	run, err = q.UpdateDiscoveryRun(ctx, sqlcgen.UpdateDiscoveryRunParams{
+		ID:     run.ID,
+		Status: "succeeded",
+		Stats: map[string]any{
+			"stage":           "completed",
+			"method":          "import",
+			"scope":           scopePrefixOrNil(scope),
+			"devices_seen":    counts["devices_seen"],
+			"devices_created": counts["devices_created"],
+			"import":          importStats,
+		},
+		CompletedAt: &completedAt,
+	})
+	if err != nil {
+		return failScanImport(ctx, q, run.ID, err, importStats, counts)
+	}
+
+	_ = q.InsertDiscoveryRunLog(ctx, sqlcgen.InsertDiscoveryRunLogParams{
+		RunID: run.ID,
+		Level: "info",
+		Message: fmt.Sprintf("scan import completed: devices_seen=%d devices_created=%d services=%d os_guesses=%d names=%d skipped=%d",
+			counts["devices_seen"], counts["devices_created"], counts["services_written"], counts["os_guesses_written"], counts["names_written"], counts["hosts_skipped"]),
+	})
+	return run, nil
+}
+
+func importScanHost(ctx context.Context, q ScanImportQueries, macIdentity identity.Config, runID, source string, now time.Time, h scanImportHost, counts map[string]int) error {
+	match, err := resolveObservedDevice(ctx, q, macIdentity, now, deviceObservation{
+		MAC:      h.MAC,
+		IP:       h.IP,
+		ClientID: h.ClientID,
+		Names:    h.Names,
+	})
+	if err != nil {
+		return err
+	}
+	deviceID := match.ID
+	if match.Created {
+		counts["devices_created"]++
+	}
+	if match.Rotating {
+		counts["randomized_macs"]++
+		if match.By != "" && match.By != matchedByMAC {
+			counts["rotation_matches"]++
+		}
+	}
+	unarchived, err := unarchiveReseen(ctx, q, match, runID)
+	if err != nil {
+		return err
+	}
+	if unarchived {
+		counts["devices_unarchived"]++
+	}
+	counts["devices_seen"]++
+
+	var ip string
+	var address *string
+	if h.IP.IsValid() {
+		ip = h.IP.String()
+		address = &ip
+	}
+	if h.MAC != "" {
+		if err := q.UpsertImportedDeviceMAC(ctx, sqlcgen.UpsertDeviceMACParams{DeviceID: deviceID, MAC: h.MAC}); err != nil {
+			return err
+		}
+		if err := q.InsertMACObservation(ctx, sqlcgen.InsertMACObservationParams{RunID: runID, DeviceID: deviceID, MAC: h.MAC}); err != nil {
+			return err
+		}
+	}
+	if h.ClientID != "" {
+		if err := q.UpsertDeviceClientID(ctx, sqlcgen.UpsertDeviceClientIDParams{DeviceID: deviceID, ClientID: h.ClientID, ObservedAt: now}); err != nil {
+			return err
+		}
+	}
+	if address != nil {
+		if err := q.UpsertImportedDeviceIP(ctx, sqlcgen.UpsertDeviceIPParams{DeviceID: deviceID, IP: ip}); err != nil {
+			return err
+		}
+		if err := q.InsertIPObservation(ctx, sqlcgen.InsertIPObservationParams{RunID: runID, DeviceID: deviceID, IP: ip}); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range h.Names {
+		stored, _, _, ok := naming.NormalizeCandidate(c.Source, c.Name)
+		if !ok {
+			continue
+		}
+		if err := q.InsertDeviceNameCandidate(ctx, sqlcgen.InsertDeviceNameCandidateParams{
+			DeviceID: deviceID,
+			Name:     stored,
+			Source:   c.Source,
+			Address:  address,
+		}); err == nil {
+			counts["names_written"]++
+		}
+	}
+
+	state := "open"
+	var openTCP []int32
+	var cpes []string
+	for _, p := range h.Ports {
+		var cpe []string
+		if len(p.CPE) > 0 {
+			cpe = appendUnique(nil, p.CPE...)
+			cpes = appendUnique(cpes, p.CPE...)
+		}
+		if err := q.UpsertServiceFromScan(ctx, sqlcgen.UpsertServiceFromScanParams{
+			DeviceID:   deviceID,
+			Protocol:   p.Protocol,
+			Port:       int32(p.Port),
+			Name:       optionalString(p.Name),
+			State:      &state,
+			Source:     &source,
+			ObservedAt: now,
+			Product:    optionalString(p.Product),
+			Version:    optionalString(p.Version),
+			ExtraInfo:  optionalString(p.ExtraInfo),
+			CPE:        cpe,
+		}); err == nil {
+			counts["services_written"]++
+		}
+		if p.Protocol == "tcp" {
+			openTCP = append(openTCP, int32(p.Port))
+		}
+	}
+
+	for i, g := range h.OSGuesses {
+		if err := q.UpsertDeviceOSGuess(ctx, sqlcgen.UpsertDeviceOSGuessParams{
+			DeviceID:     deviceID,
+			Source:       source,
+			Rank:         int32(i + 1),
+			Name:         g.Name,
+			Accuracy:     int32(g.Accuracy),
+			Vendor:       optionalString(g.Vendor),
+			OSFamily:     optionalString(g.Family),
+			OSGeneration: optionalString(g.Generation),
+			DeviceType:   optionalString(g.DeviceType),
+			CPE:          g.CPE,
+			ObservedAt:   now,
+		}); err == nil {
+			counts["os_guesses_written"]++
+		}
+	}
+	if len(h.OSGuesses) > 0 {
+		_ = q.DeleteDeviceOSGuessesAboveRank(ctx, sqlcgen.DeleteDeviceOSGuessesAboveRankParams{
+			DeviceID: deviceID,
+			Source:   source,
+			Rank:     int32(len(h.OSGuesses)),
+		})
+	}
+
+	var groups [][]tagging.Suggestion
+	if len(openTCP) > 0 {
+		groups = append(groups, tagging.SuggestFromOpenPorts(openTCP))
+	}
+	if len(h.OSGuesses) > 0 {
+		groups = append(groups, tagging.SuggestFromOS(h.OSGuesses[0]))
+		cpes = appendUnique(cpes, h.OSGuesses[0].CPE...)
+	}
+	groups = append(groups, tagging.SuggestFromCPE(cpes))
+	for _, s := range tagging.MergeSuggestions(groups...) {
+		if s.Evidence == nil {
+			s.Evidence = map[string]any{}
+		}
+		if address != nil {
+			s.Evidence["ip"] = ip
+		}
+		s.Evidence["scanner"] = source
+		_ = q.UpsertDeviceTag(ctx, sqlcgen.UpsertDeviceTagParams{
+			DeviceID:   deviceID,
+			Tag:        s.Tag,
+			Source:     "auto",
+			Confidence: int32(s.Confidence),
+			Evidence:   s.Evidence,
+		})
+	}
+	return nil
+}
+
+func failScanImport(ctx context.Context, q ScanImportQueries, runID string, cause error, importStats map[string]any, counts map[string]int) (sqlcgen.DiscoveryRun, error) {
+	for k, v := range counts {
+		importStats[k] = v
+	}
+	// The request context may be the reason we failed; still try to close the run out.
+	if ctx.Err() != nil {
+		bg, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		ctx = bg
+	}
+	completedAt := time.Now()
+	msg := cause.Error()
+	run, err := q.UpdateDiscoveryRun(ctx, sqlcgen.UpdateDiscoveryRunParams{
+		ID:     runID,
+		Status: "failed",
+		Stats: map[string]any{
+			"stage":  "failed",
+			"method": "import",
+			"import": importStats,
+		},
+		CompletedAt: &completedAt,
+		LastError:   &msg,
+	})
+	_ = q.InsertDiscoveryRunLog(ctx, sqlcgen.InsertDiscoveryRunLogParams{
+		RunID:   runID,
+		Level:   "error",
+		Message: "scan import failed: " + msg,
+	})
+	if err != nil {
+		return sqlcgen.DiscoveryRun{}, err
+	}
+	return run, cause
+}
+
+// scanImportSource is the services/OS guess source for imported results; it is kept distinct from
+// native scans so an import never overwrites (or gets reconciled by) the worker's own nmap rows.
+func scanImportSource(format string) string {
+	switch format {
+	case ScanImportFormatNmapXML:
+		return "nmap_import"
+	case ScanImportFormatMasscanJSON:
+		return "masscan_import"
+	default:
+		return "arp_scan_import"
+	}
+}
+
+func parseScanImport(format string, content []byte) ([]scanImportHost, error) {
+	switch format {
+	case ScanImportFormatNmapXML:
+		return parseNmapImport(content)
+	case ScanImportFormatMasscanJSON:
+		return parseMasscanImport(content)
+	case ScanImportFormatARPScan:
+		return parseARPScanImport(string(content))
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// scanImportHosts merges per-IP records (masscan emits one record per port) preserving first-seen order.
+type scanImportHosts struct {
+	order []netip.Addr
+	byIP  map[netip.Addr]*scanImportHost
+}
+
+func (s *scanImportHosts) get(ip netip.Addr) *scanImportHost {
+	if s.byIP == nil {
+		s.byIP = map[netip.Addr]*scanImportHost{}
+	}
+	h, ok := s.byIP[ip]
+	if !ok {
+		h = &scanImportHost{IP: ip}
+		s.byIP[ip] = h
+		s.order = append(s.order, ip)
+	}
+	return h
+}
+
+func (s *scanImportHosts) list() []scanImportHost {
+	out := make([]scanImportHost, 0, len(s.order))
+	for _, ip := range s.order {
+		out = append(out, *s.byIP[ip])
+	}
+	return out
+}
+
+func (h *scanImportHost) addPort(p scanImportPort) {
+	p.Protocol = strings.ToLower(strings.TrimSpace(p.Protocol))
+	if (p.Protocol != "tcp" && p.Protocol != "udp") || p.Port <= 0 || p.Port > 65535 {
+		return
+	}
+	for _, existing := range h.Ports {
+		if existing.Protocol == p.Protocol && existing.Port == p.Port {
+			return
+		}
+	}
+	h.Ports = append(h.Ports, p)
+}
+
+func (h *scanImportHost) addName(c naming.Candidate) {
+	for _, existing := range h.Names {
+		if existing == c {
+			return
+		}
+	}
+	h.Names = append(h.Names, c)
+}
+
+func normalizeImportMAC(raw string) string {
+	mac := strings.ToLower(strings.TrimSpace(raw))
+	if mac == "" || mac == "00:00:00:00:00:00" {
+		return ""
+	}
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		return ""
+	}
+	return hw.String()
+}
+
+// parseNmapImport reads `nmap -oX` output: every host that is up with an IP address, its MAC (only
+// present for on-link scans), PTR names, open ports with -sV details and -O guesses.
+func parseNmapImport(content []byte) ([]scanImportHost, error) {
+	var run nmapRun
+	if err := xml.Unmarshal(content, &run); err != nil {
+		return nil, fmt.Errorf("parse nmap xml: %w", err)
+	}
+
+	var hosts scanImportHosts
+	for _, nh := range run.Hosts {
+		if state := strings.ToLower(nh.Status.State); state != "" && state != "up" {
+			continue
+		}
+		var ip netip.Addr
+		mac := ""
+		for _, a := range nh.Addresses {
+			switch strings.ToLower(a.AddrType) {
+			case "ipv4", "ipv6":
+				if !ip.IsValid() {
+					if parsed, err := netip.ParseAddr(strings.TrimSpace(a.Addr)); err == nil {
+						ip = parsed.Unmap()
+					}
+				}
+			case "mac":
+				mac = normalizeImportMAC(a.Addr)
+			}
+		}
+		if !ip.IsValid() {
+			continue
+		}
+
+		h := hosts.get(ip)
+		if mac != "" {
+			h.MAC = mac
+		}
+		for _, hn := range nh.Hostnames {
+			if strings.EqualFold(hn.Type, "PTR") && strings.TrimSpace(hn.Name) != "" {
+				h.addName(naming.Candidate{Name: strings.TrimSpace(hn.Name), Source: "reverse_dns"})
+			}
+		}
+		for _, p := range nh.Ports {
+			if normalizeNmapState(p.State.State) != "open" {
+				continue
+			}
+			h.addPort(scanImportPort{
+				Protocol:  p.Protocol,
+				Port:      p.PortID,
+				Name:      p.Service.Name,
+				Product:   p.Service.Product,
+				Version:   p.Service.Version,
+				ExtraInfo: p.Service.ExtraInfo,
+				CPE:       p.Service.CPE,
+			})
+		}
+		if len(h.OSGuesses) == 0 {
+			h.OSGuesses = nmapOSGuesses(nmapRun{Hosts: []nmapHost{nh}}, nmapOSGuessLimit)
+		}
+	}
+	return hosts.list(), nil
+}
+
+type masscanRecord struct {
+	IP    string        `json:"ip"`
+	Ports []masscanPort `json:"ports"`
+}
+
+type masscanPort struct {
+	Port    int    `json:"port"`
+	Proto   string `json:"proto"`
+	Status  string `json:"status"`
+	Service *struct {
+		Name string `json:"name"`
+	} `json:"service"`
+}
+
+// parseMasscanImport reads `masscan -oJ` output. Older masscan releases write a JSON array with a
+// trailing comma before `]`, so anything that fails strict decoding is retried one record per line.
+func parseMasscanImport(content []byte) ([]scanImportHost, error) {
+	var records []masscanRecord
+	if err := json.Unmarshal(content, &records); err != nil {
+		strictErr := err
+		records = nil
+		s := bufio.NewScanner(strings.NewReader(string(content)))
+		s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for s.Scan() {
+			line := strings.TrimSpace(s.Text())
+			line = strings.TrimSpace(strings.Trim(line, "[],"))
+			if !strings.HasPrefix(line, "{") {
+				continue
+			}
+			var rec masscanRecord
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				// e.g. the `{finished: 1}` trailer some versions emit.
+				continue
+			}
+			records = append(records, rec)
+		}
+		if err := s.Err(); err != nil {
+			return nil, fmt.Errorf("parse masscan json: %w", err)
+		}
+		if len(records) == 0 {
+			return nil, fmt.Errorf("parse masscan json: %w", strictErr)
+		}
+	}
+
+	var hosts scanImportHosts
+	for _, rec := range records {
+		ip, err := netip.ParseAddr(strings.TrimSpace(rec.IP))
+		if err != nil {
+			continue
+		}
+		h := hosts.get(ip.Unmap())
+		for _, p := range rec.Ports {
+			// Banner records carry a service but no status; a banner implies the port answered.
+			if p.Status != "" && !strings.EqualFold(p.Status, "open") {
+				continue
+			}
+			h.addPort(scanImportPort{Protocol: p.Proto, Port: p.Port})
+		}
+	}
+	return hosts.list(), nil
+}
+
+// parseARPScanImport reads `arp-scan` text output: tab separated "IP MAC vendor" lines, ignoring the
+// interface banner, summary footer and anything else that does not start with an IP and a MAC.
+func parseARPScanImport(content string) ([]scanImportHost, error) {
+	var hosts scanImportHosts
+	s := bufio.NewScanner(strings.NewReader(content))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		ip, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			continue
+		}
+		mac := normalizeImportMAC(fields[1])
+		if mac == "" {
+			continue
+		}
+		h := hosts.get(ip.Unmap())
+		if h.MAC == "" {
+			h.MAC = mac
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("parse arp-scan output: %w", err)
+	}
+	return hosts.list(), nil
+}
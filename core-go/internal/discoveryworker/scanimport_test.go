@@ -0,0 +1,310 @@
+package discoveryworker
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/naming"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+const nmapImportSample = `<?xml version="1.0"?>
+<nmaprun scanner="nmap">
+  <host>
+    <status state="up" reason="arp-response"/>
+    <address addr="10.20.0.5" addrtype="ipv4"/>
+    <address addr="AA:BB:CC:00:11:22" addrtype="mac" vendor="Cisco Systems"/>
+    <hostnames>
+      <hostname name="10.20.0.5" type="user"/>
+      <hostname name="core-sw1.example.net" type="PTR"/>
+    </hostnames>
+    <ports>
+      <port protocol="tcp" portid="22"><state state="open"/><service name="ssh" product="Cisco SSH" version="1.25"/></port>
+      <port protocol="tcp" portid="23"><state state="closed"/><service name="telnet"/></port>
+      <port protocol="udp" portid="161"><state state="open"/><service name="snmp"/></port>
+    </ports>
+    <os>
+      <osmatch name="Cisco IOS 15.X" accuracy="96">
+        <osclass type="switch" vendor="Cisco" osfamily="IOS" osgen="15.X" accuracy="96"><cpe>cpe:/o:cisco:ios:15</cpe></osclass>
+      </osmatch>
+    </os>
+  </host>
+  <host>
+    <status state="down"/>
+    <address addr="10.20.0.6" addrtype="ipv4"/>
+  </host>
+  <host>
+    <status state="up"/>
+    <address addr="10.30.0.9" addrtype="ipv4"/>
+    <ports><port protocol="tcp" portid="443"><state state="open"/><service name="https"/></port></ports>
+  </host>
+</nmaprun>`
+
+func TestParseScanImport(t *testing.T) {
+	cases := []struct {
+		name      string
+		format    string
+		content   string
+		wantHosts []scanImportHost
+		wantErr   bool
+	}{
+		{
+			name:    "nmap xml with addresses, ptr names, open ports and os",
+			format:  ScanImportFormatNmapXML,
+			content: nmapImportSample,
+			wantHosts: []scanImportHost{
+				{
+					IP:    netip.MustParseAddr("10.20.0.5"),
+					MAC:   "aa:bb:cc:00:11:22",
+					Names: []naming.Candidate{{Name: "core-sw1.example.net", Source: "reverse_dns"}},
+					Ports: []scanImportPort{
+						{Protocol: "tcp", Port: 22, Name: "ssh", Product: "Cisco SSH", Version: "1.25"},
+						{Protocol: "udp", Port: 161, Name: "snmp"},
+					},
+				},
+				{
+					IP:    netip.MustParseAddr("10.30.0.9"),
+					Ports: []scanImportPort{{Protocol: "tcp", Port: 443, Name: "https"}},
+				},
+			},
+		},
+		{
+			name:    "masscan array with trailing comma and banner records",
+			format:  ScanImportFormatMasscanJSON,
+			content: "[\n{   \"ip\": \"10.40.0.1\",   \"timestamp\": \"1700000000\", \"ports\": [ {\"port\": 80, \"proto\": \"tcp\", \"status\": \"open\", \"reason\": \"syn-ack\", \"ttl\": 64} ] },\n{   \"ip\": \"10.40.0.1\",   \"timestamp\": \"1700000001\", \"ports\": [ {\"port\": 22, \"proto\": \"tcp\", \"service\": {\"name\": \"ssh\", \"banner\": \"SSH-2.0-OpenSSH_9.6\"} } ] },\n{   \"ip\": \"10.40.0.2\",   \"timestamp\": \"1700000002\", \"ports\": [ {\"port\": 80, \"proto\": \"tcp\", \"status\": \"closed\"} ] },\n]\n",
+			wantHosts: []scanImportHost{
+				{
+					IP:    netip.MustParseAddr("10.40.0.1"),
+					Ports: []scanImportPort{{Protocol: "tcp", Port: 80}, {Protocol: "tcp", Port: 22}},
+				},
+				{IP: netip.MustParseAddr("10.40.0.2")},
+			},
+		},
+		{
+			name:    "masscan strict json",
+			format:  ScanImportFormatMasscanJSON,
+			content: `[{"ip":"10.40.0.3","ports":[{"port":53,"proto":"udp","status":"open"}]}]`,
+			wantHosts: []scanImportHost{
+				{IP: netip.MustParseAddr("10.40.0.3"), Ports: []scanImportPort{{Protocol: "udp", Port: 53}}},
+			},
+		},
+		{
+			name:   "arp-scan text with banner, duplicates and footer",
+			format: ScanImportFormatARPScan,
+			content: "Interface: eth0, type: EN10MB, MAC: 00:11:22:33:44:55, IPv4: 10.50.0.10\n" +
+				"Starting arp-scan 1.10.0 with 256 hosts (https://github.com/royhills/arp-scan)\n" +
+				"10.50.0.1\t00:0c:29:aa:bb:cc\tVMware, Inc.\n" +
+				"10.50.0.7\tB8:27:EB:01:02:03\tRaspberry Pi Foundation\n" +
+				"10.50.0.7\tb8:27:eb:01:02:03\tRaspberry Pi Foundation (DUP: 2)\n" +
+				"\n" +
+				"3 packets received by filter, 0 packets dropped by kernel\n" +
+				"Ending arp-scan 1.10.0: 256 hosts scanned in 1.870 seconds (136.90 hosts/sec). 2 responded\n",
+			wantHosts: []scanImportHost{
+				{IP: netip.MustParseAddr("10.50.0.1"), MAC: "00:0c:29:aa:bb:cc"},
+				{IP: netip.MustParseAddr("10.50.0.7"), MAC: "b8:27:eb:01:02:03"},
+			},
+		},
+		{name: "malformed nmap xml", format: ScanImportFormatNmapXML, content: "<nmaprun><host>", wantErr: true},
+		{name: "malformed masscan json", format: ScanImportFormatMasscanJSON, content: "not json", wantErr: true},
+		{name: "unknown format", format: "zmap_csv", content: "10.0.0.1", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hosts, err := parseScanImport(tc.format, []byte(tc.content))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got hosts %+v", hosts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(hosts) != len(tc.wantHosts) {
+				t.Fatalf("expected %d hosts, got %+v", len(tc.wantHosts), hosts)
+			}
+			for i, want := range tc.wantHosts {
+				got := hosts[i]
+				if got.IP != want.IP || got.MAC != want.MAC {
+					t.Fatalf("host %d: expected %s/%q, got %s/%q", i, want.IP, want.MAC, got.IP, got.MAC)
+				}
+				if len(got.Names) != len(want.Names) || (len(want.Names) > 0 && got.Names[0] != want.Names[0]) {
+					t.Fatalf("host %d: unexpected names %v", i, got.Names)
+				}
+				if len(got.Ports) != len(want.Ports) {
+					t.Fatalf("host %d: expected ports %+v, got %+v", i, want.Ports, got.Ports)
+				}
+				for j, wp := range want.Ports {
+					gp := got.Ports[j]
+					if gp.Protocol != wp.Protocol || gp.Port != wp.Port || gp.Name != wp.Name || gp.Product != wp.Product || gp.Version != wp.Version {
+						t.Fatalf("host %d port %d: expected %+v, got %+v", i, j, wp, gp)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseNmapImport_OSGuesses(t *testing.T) {
+	hosts, err := parseNmapImport([]byte(nmapImportSample))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(hosts[0].OSGuesses) != 1 {
+		t.Fatalf("expected 1 os guess, got %+v", hosts[0].OSGuesses)
+	}
+	g := hosts[0].OSGuesses[0]
+	if g.Name != "Cisco IOS 15.X" || g.Accuracy != 96 || g.DeviceType != "switch" {
+		t.Fatalf("unexpected os guess %+v", g)
+	}
+	if len(hosts[1].OSGuesses) != 0 {
+		t.Fatalf("expected no os guesses for second host, got %+v", hosts[1].OSGuesses)
+	}
+}
+
+type fakeScanImportQueries struct {
+	*fakeQueries
+	insertRunFn  func(ctx context.Context, arg sqlcgen.InsertDiscoveryRunParams) (sqlcgen.DiscoveryRun, error)
+	importedIPs  []sqlcgen.UpsertDeviceIPParams
+	importedMACs []sqlcgen.UpsertDeviceMACParams
+}
+
+func (f *fakeScanImportQueries) InsertDiscoveryRun(ctx context.Context, arg sqlcgen.InsertDiscoveryRunParams) (sqlcgen.DiscoveryRun, error) {
+	return f.insertRunFn(ctx, arg)
+}
+
+func (f *fakeScanImportQueries) UpsertImportedDeviceIP(ctx context.Context, arg sqlcgen.UpsertDeviceIPParams) error {
+	f.importedIPs = append(f.importedIPs, arg)
+	return nil
+}
+
+func (f *fakeScanImportQueries) UpsertImportedDeviceMAC(ctx context.Context, arg sqlcgen.UpsertDeviceMACParams) error {
+	f.importedMACs = append(f.importedMACs, arg)
+	return nil
+}
+
+func TestImportScan_RecordsSyntheticRun(t *testing.T) {
+	var inserted sqlcgen.InsertDiscoveryRunParams
+	var final sqlcgen.UpdateDiscoveryRunParams
+	var logs []string
+	var ipObs []sqlcgen.InsertIPObservationParams
+	var macObs []sqlcgen.InsertMACObservationParams
+	var services []sqlcgen.UpsertServiceFromScanParams
+	var names []sqlcgen.InsertDeviceNameCandidateParams
+	created := 0
+
+	q := &fakeScanImportQueries{
+		fakeQueries: &fakeQueries{
+			updateFn: func(ctx context.Context, arg sqlcgen.UpdateDiscoveryRunParams) (sqlcgen.DiscoveryRun, error) {
+				final = arg
+				return sqlcgen.DiscoveryRun{ID: arg.ID, Status: arg.Status, Stats: arg.Stats}, nil
+			},
+			insertFn: func(ctx context.Context, arg sqlcgen.InsertDiscoveryRunLogParams) error {
+				logs = append(logs, arg.Message)
+				return nil
+			},
+			findByMacFn: func(ctx context.Context, mac string) (string, error) {
+				if mac == "aa:bb:cc:00:11:22" {
+					return "dev-existing", nil
+				}
+				return "", pgx.ErrNoRows
+			},
+			createFn: func(ctx context.Context, displayName *string) (sqlcgen.Device, error) {
+				created++
+				return sqlcgen.Device{ID: "dev-new"}, nil
+			},
+			insertIPObs: func(ctx context.Context, arg sqlcgen.InsertIPObservationParams) error {
+				ipObs = append(ipObs, arg)
+				return nil
+			},
+			insertMACObs: func(ctx context.Context, arg sqlcgen.InsertMACObservationParams) error {
+				macObs = append(macObs, arg)
+				return nil
+			},
+			upsertServiceFn: func(ctx context.Context, arg sqlcgen.UpsertServiceFromScanParams) error {
+				services = append(services, arg)
+				return nil
+			},
+			insertNameCandidateFn: func(ctx context.Context, arg sqlcgen.InsertDeviceNameCandidateParams) error {
+				names = append(names, arg)
+				return nil
+			},
+		},
+		insertRunFn: func(ctx context.Context, arg sqlcgen.InsertDiscoveryRunParams) (sqlcgen.DiscoveryRun, error) {
+			inserted = arg
+			return sqlcgen.DiscoveryRun{ID: "run-import", Status: arg.Status, Scope: arg.Scope, Stats: arg.Stats}, nil
+		},
+	}
+
+	scope := "10.20.0.0/16"
+	run, err := ImportScan(context.Background(), q, ScanImport{
+		Format:  ScanImportFormatNmapXML,
+		Content: []byte(nmapImportSample),
+		Scope:   &scope,
+		Origin:  "jump-host-a",
+	})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if run.ID != "run-import" || run.Status != "succeeded" {
+		t.Fatalf("unexpected run %+v", run)
+	}
+	if inserted.Status != "running" || inserted.Scope == nil || *inserted.Scope != "10.20.0.0/16" {
+		t.Fatalf("unexpected run insert %+v", inserted)
+	}
+	if final.CompletedAt == nil || final.Stats["method"] != "import" {
+		t.Fatalf("unexpected final update %+v", final)
+	}
+	stats, _ := final.Stats["import"].(map[string]any)
+	if stats["origin"] != "jump-host-a" || stats["hosts_skipped"] != 1 || stats["devices_seen"] != 1 || stats["services_written"] != 2 {
+		t.Fatalf("unexpected import stats %v", stats)
+	}
+	if created != 0 {
+		t.Fatalf("expected MAC match to reuse existing device, created %d", created)
+	}
+	if len(ipObs) != 1 || ipObs[0].RunID != "run-import" || ipObs[0].DeviceID != "dev-existing" || ipObs[0].IP != "10.20.0.5" {
+		t.Fatalf("unexpected ip observations %+v", ipObs)
+	}
+	if len(macObs) != 1 || macObs[0].MAC != "aa:bb:cc:00:11:22" {
+		t.Fatalf("unexpected mac observations %+v", macObs)
+	}
+	// Scan results are written as imported addresses, which fact aging leaves alone.
+	if len(q.importedIPs) != 1 || q.importedIPs[0].IP != "10.20.0.5" || len(q.importedMACs) != 1 {
+		t.Fatalf("expected the addresses written as imported, got %+v %+v", q.importedIPs, q.importedMACs)
+	}
+	if len(services) != 2 || *services[0].Source != "nmap_import" || services[1].Protocol != "udp" {
+		t.Fatalf("unexpected services %+v", services)
+	}
+	if len(names) != 1 || names[0].Source != "reverse_dns" {
+		t.Fatalf("unexpected name candidates %+v", names)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("expected start and completion logs, got %v", logs)
+	}
+}
+
+func TestImportScan_InvalidContentCreatesNoRun(t *testing.T) {
+	q := &fakeScanImportQueries{
+		fakeQueries: &fakeQueries{},
+		insertRunFn: func(ctx context.Context, arg sqlcgen.InsertDiscoveryRunParams) (sqlcgen.DiscoveryRun, error) {
+			t.Fatalf("run must not be created for invalid content")
+			return sqlcgen.DiscoveryRun{}, nil
+		},
+	}
+
+	badScope := "not-a-cidr"
+	for _, in := range []ScanImport{
+		{Format: ScanImportFormatARPScan, Content: []byte("nothing useful here\n")},
+		{Format: "csv", Content: []byte("10.0.0.1")},
+		{Format: ScanImportFormatARPScan, Content: []byte("10.0.0.1\t00:11:22:33:44:55\n"), Scope: &badScope},
+	} {
+		if _, err := ImportScan(context.Background(), q, in); !errors.Is(err, ErrInvalidScanImport) {
+			t.Fatalf("expected ErrInvalidScanImport for %+v, got %v", in, err)
+		}
+	}
+}
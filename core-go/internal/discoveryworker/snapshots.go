@@ -0,0 +1,31 @@
+package discoveryworker
+
+import (
+	"context"
+	"fmt"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// runStateSnapshots records the tag sets, VLAN memberships and links that changed during the run, so they can
+// be read back as of any later instant. It runs after every stage that rewrites them.
+func (w *Worker) runStateSnapshots(ctx context.Context, runID string) map[string]any {
+	recorded, err := w.q.RecordDeviceStateSnapshots(ctx, sqlcgen.RecordDeviceStateSnapshotsParams{
+		Actor: sqlcgen.RunActor(runID),
+	})
+	stats := map[string]any{"recorded": recorded}
+	if err != nil {
+		stats["error"] = err.Error()
+	}
+	return stats
+}
+
+func (w *Worker) stateSnapshotsLogMessage(stats map[string]any) string {
+	if stats == nil {
+		return ""
+	}
+	if msg, ok := stats["error"].(string); ok && msg != "" {
+		return fmt.Sprintf("state snapshots failed: %s", msg)
+	}
+	return fmt.Sprintf("state snapshots: recorded=%v", stats["recorded"])
+}
@@ -0,0 +1,56 @@
+package discoveryworker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+func TestRunStateSnapshots(t *testing.T) {
+	tests := []struct {
+		name     string
+		recorded int64
+		err      error
+		wantErr  bool
+	}{
+		{name: "changes are counted", recorded: 3},
+		{name: "error is reported", err: errors.New("boom"), wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []sqlcgen.RecordDeviceStateSnapshotsParams
+			q := &fakeQueries{
+				recordSnapshotsFn: func(ctx context.Context, arg sqlcgen.RecordDeviceStateSnapshotsParams) (int64, error) {
+					calls = append(calls, arg)
+					return tc.recorded, tc.err
+				},
+			}
+			w := New(zerolog.Nop(), q, Options{}, nil)
+			stats := w.runStateSnapshots(context.Background(), "run-1")
+			if len(calls) != 1 {
+				t.Fatalf("expected one snapshot pass, got %d", len(calls))
+			}
+			arg := calls[0]
+			if arg.DeviceIDs != nil {
+				t.Fatalf("expected every device to be snapshotted, got %v", arg.DeviceIDs)
+			}
+			if arg.Actor.Type != sqlcgen.DeviceEventActorRun || arg.Actor.RunID == nil || *arg.Actor.RunID != "run-1" {
+				t.Fatalf("expected the run as actor, got %+v", arg.Actor)
+			}
+			if stats["recorded"] != tc.recorded {
+				t.Fatalf("expected recorded=%d, got %v", tc.recorded, stats)
+			}
+			if _, failed := stats["error"]; failed != tc.wantErr {
+				t.Fatalf("expected error=%v, got %v", tc.wantErr, stats)
+			}
+			if msg := w.stateSnapshotsLogMessage(stats); msg == "" {
+				t.Fatalf("expected a log message")
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package discoveryworker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"roller_hoops/core-go/internal/enrichment/sshkey"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+const sshHostKeysMaxServicesPerDevice = 4
+
+// runSSHHostKeys runs an SSH key exchange (no authentication) against port 22 and any other open service
+// that looks like SSH on allowlisted targets, recording each host key type and fingerprint per device.
+//
+// Host key changes surface as `ssh_host_key` change events; a key already recorded on another device is
+// counted as shared (possible duplicate device or moved host).
+func (w *Worker) runSSHHostKeys(ctx context.Context, targets []enrichmentTarget) map[string]any {
+	if w == nil || w.q == nil || !w.sshHostKeysEnabled {
+		return nil
+	}
+	if len(w.portScanAllowlist) == 0 {
+		return map[string]any{"enabled": true, "available": false, "reason": "no_allowlist"}
+	}
+
+	scanTargets := w.portScanTargets(targets)
+	if len(scanTargets) == 0 {
+		return map[string]any{"enabled": true, "available": true, "targets": 0, "keys_written": 0}
+	}
+
+	cfg := sshkey.Config{Timeout: w.sshTimeout}
+
+	var servicesTried int32
+	var keysWritten int32
+	var sharedKeys int32
+
+	jobs := make(chan portScanTarget)
+	wg := sync.WaitGroup{}
+
+	worker := func() {
+		defer wg.Done()
+		for t := range jobs {
+			if ctx.Err() != nil {
+				return
+			}
+			services, err := w.q.ListOpenTCPServicesForDevice(ctx, t.DeviceID, tlsInventoryMaxServicesPerDevice)
+			if err != nil {
+				continue
+			}
+
+			tried := 0
+			for _, svc := range services {
+				if ctx.Err() != nil {
+					return
+				}
+				name, summary := "", ""
+				if svc.Name != nil {
+					name = *svc.Name
+				}
+				if svc.Summary != nil {
+					summary = *svc.Summary
+				}
+				if !sshkey.IsLikelySSH(int(svc.Port), name, summary) {
+					continue
+				}
+				if tried >= sshHostKeysMaxServicesPerDevice {
+					break
+				}
+				tried++
+				atomic.AddInt32(&servicesTried, 1)
+
+				res, err := sshkey.Collect(ctx, cfg, t.IP, int(svc.Port))
+				if err != nil {
+					continue
+				}
+
+				serviceID := svc.ID
+				observedAt := time.Now()
+				for _, key := range res.Keys {
+					row, err := w.q.UpsertSSHHostKey(ctx, sqlcgen.UpsertSSHHostKeyParams{
+						DeviceID:          t.DeviceID,
+						ServiceID:         &serviceID,
+						KeyType:           key.KeyType,
+						FingerprintSHA256: key.FingerprintSHA256,
+						PublicKey:         key.PublicKey,
+						Banner:            optionalString(res.Banner),
+						ObservedAt:        observedAt,
+					})
+					if err != nil {
+						continue
+					}
+					atomic.AddInt32(&keysWritten, 1)
+					if row.SharedDeviceCount > 0 {
+						atomic.AddInt32(&sharedKeys, 1)
+						w.log.Warn().
+							Str("device_id", t.DeviceID).
+							Str("ip", t.IP).
+							Str("key_type", key.KeyType).
+							Str("fingerprint", key.FingerprintSHA256).
+							Int32("other_devices", row.SharedDeviceCount).
+							Msg("ssh host key already known on another device")
+					}
+				}
+			}
+		}
+	}
+
+	workers := w.portScanWorkers
+	if workers <= 0 {
+		workers = 4
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go worker()
+	}
+
+	for _, t := range scanTargets {
+		select {
+		case <-ctx.Done():
+			close(jobs)
+			wg.Wait()
+			return map[string]any{
+				"enabled":        true,
+				"available":      true,
+				"targets":        len(scanTargets),
+				"services_tried": int(servicesTried),
+				"keys_written":   int(keysWritten),
+				"shared_keys":    int(sharedKeys),
+				"canceled":       true,
+			}
+		case jobs <- t:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return map[string]any{
+		"enabled":        true,
+		"available":      true,
+		"targets":        len(scanTargets),
+		"services_tried": int(servicesTried),
+		"keys_written":   int(keysWritten),
+		"shared_keys":    int(sharedKeys),
+		"timeout":        w.sshTimeout.String(),
+	}
+}
+
+func (w *Worker) sshHostKeysLogMessage(stats map[string]any) string {
+	if stats == nil {
+		return ""
+	}
+	if avail, ok := stats["available"].(bool); ok && !avail {
+		if reason, ok := stats["reason"].(string); ok && reason != "" {
+			return fmt.Sprintf("ssh host keys skipped: %s", reason)
+		}
+		return "ssh host keys skipped"
+	}
+	return fmt.Sprintf("ssh host keys: targets=%v services=%v keys=%v shared=%v", stats["targets"], stats["services_tried"], stats["keys_written"], stats["shared_keys"])
+}
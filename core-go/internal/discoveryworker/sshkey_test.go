@@ -0,0 +1,139 @@
+package discoveryworker
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+func sshTestString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func sshTestPacket(payload []byte) []byte {
+	padding := 8 - (5+len(payload))%8
+	if padding < 4 {
+		padding += 8
+	}
+	out := binary.BigEndian.AppendUint32(nil, uint32(1+len(payload)+padding))
+	out = append(out, byte(padding))
+	out = append(out, payload...)
+	return append(out, make([]byte, padding)...)
+}
+
+// startFakeSSHServer answers every connection with a banner, a KEXINIT offering only ssh-ed25519, and a
+// KEXDH_REPLY carrying a fixed host key blob. The client's messages are drained but not inspected.
+func startFakeSSHServer(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	kexInit := append([]byte{20}, make([]byte, 16)...)
+	kexInit = sshTestString(kexInit, "curve25519-sha256")
+	kexInit = sshTestString(kexInit, "ssh-ed25519")
+	for i := 0; i < 8; i++ {
+		kexInit = sshTestString(kexInit, "none")
+	}
+	kexInit = append(kexInit, 0, 0, 0, 0, 0)
+
+	blob := sshTestString(sshTestString(nil, "ssh-ed25519"), "0123456789abcdef0123456789abcdef")
+	reply := sshTestString([]byte{31}, string(blob))
+	reply = sshTestString(reply, "server-ephemeral")
+	reply = sshTestString(reply, "signature")
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+				out := []byte("SSH-2.0-OpenSSH_9.6\r\n")
+				out = append(out, sshTestPacket(kexInit)...)
+				out = append(out, sshTestPacket(reply)...)
+				_, _ = conn.Write(out)
+				_, _ = io.Copy(io.Discard, conn)
+			}(conn)
+		}
+	}()
+
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestWorker_RunSSHHostKeys_RecordsKeysAndSharedCount(t *testing.T) {
+	port := startFakeSSHServer(t)
+	sshName := "ssh"
+
+	var mu sync.Mutex
+	var written []sqlcgen.UpsertSSHHostKeyParams
+	q := &fakeQueries{
+		listOpenTCPFn: func(ctx context.Context, deviceID string, limit int32) ([]sqlcgen.OpenTCPService, error) {
+			return []sqlcgen.OpenTCPService{
+				{ID: "svc-http", Port: 80},
+				{ID: "svc-ssh", Port: int32(port), Name: &sshName},
+			}, nil
+		},
+		upsertSSHHostKeyFn: func(ctx context.Context, arg sqlcgen.UpsertSSHHostKeyParams) (sqlcgen.UpsertSSHHostKeyRow, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			written = append(written, arg)
+			return sqlcgen.UpsertSSHHostKeyRow{ID: "key-1", SharedDeviceCount: 1}, nil
+		},
+	}
+
+	w := New(zerolog.Nop(), q, Options{
+		PortScanAllowlist:  []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
+		SSHHostKeysEnabled: true,
+		SSHTimeout:         2 * time.Second,
+	}, nil)
+
+	stats := w.runSSHHostKeys(context.Background(), []enrichmentTarget{
+		{DeviceID: "dev-1", IP: netip.MustParseAddr("127.0.0.1")},
+	})
+
+	if stats["services_tried"] != 1 || stats["keys_written"] != 1 || stats["shared_keys"] != 1 {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+	if len(written) != 1 {
+		t.Fatalf("expected 1 key, got %d", len(written))
+	}
+	got := written[0]
+	if got.DeviceID != "dev-1" || got.ServiceID == nil || *got.ServiceID != "svc-ssh" {
+		t.Fatalf("unexpected linkage %+v", got)
+	}
+	if got.KeyType != "ssh-ed25519" || len(got.FingerprintSHA256) != len("SHA256:")+43 {
+		t.Fatalf("unexpected key %+v", got)
+	}
+	if got.Banner == nil || *got.Banner != "SSH-2.0-OpenSSH_9.6" {
+		t.Fatalf("unexpected banner %v", got.Banner)
+	}
+}
+
+func TestWorker_RunSSHHostKeys_RequiresAllowlist(t *testing.T) {
+	w := New(zerolog.Nop(), &fakeQueries{}, Options{SSHHostKeysEnabled: true}, nil)
+
+	stats := w.runSSHHostKeys(context.Background(), []enrichmentTarget{
+		{DeviceID: "dev-1", IP: netip.MustParseAddr("127.0.0.1")},
+	})
+	if stats["available"] != false || stats["reason"] != "no_allowlist" {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+	if msg := w.sshHostKeysLogMessage(stats); msg != "ssh host keys skipped: no_allowlist" {
+		t.Fatalf("unexpected log message %q", msg)
+	}
+}
@@ -58,29 +58,44 @@ func applyScanTags(w *Worker, tags []string) func() {
 	}
 
 	prev := struct {
-		nameResolutionEnabled bool
-		snmpEnabled           bool
-		topologyLLDPEnabled   bool
-		topologyCDPEnabled    bool
-		portScanEnabled       bool
+		nameResolutionEnabled    bool
+		snmpEnabled              bool
+		topologyLLDPEnabled      bool
+		topologyCDPEnabled       bool
+		topologyInferenceEnabled bool
+		portScanEnabled          bool
+		udpProbeEnabled          bool
+		tlsInventoryEnabled      bool
+		httpFingerprintEnabled   bool
+		sshHostKeysEnabled       bool
 	}{
-		nameResolutionEnabled: w.nameResolutionEnabled,
-		snmpEnabled:           w.snmpEnabled,
-		topologyLLDPEnabled:   w.topologyLLDPEnabled,
-		topologyCDPEnabled:    w.topologyCDPEnabled,
-		portScanEnabled:       w.portScanEnabled,
+		nameResolutionEnabled:    w.nameResolutionEnabled,
+		snmpEnabled:              w.snmpEnabled,
+		topologyLLDPEnabled:      w.topologyLLDPEnabled,
+		topologyCDPEnabled:       w.topologyCDPEnabled,
+		topologyInferenceEnabled: w.topologyInferenceEnabled,
+		portScanEnabled:          w.portScanEnabled,
+		udpProbeEnabled:          w.udpProbeEnabled,
+		tlsInventoryEnabled:      w.tlsInventoryEnabled,
+		httpFingerprintEnabled:   w.httpFingerprintEnabled,
+		sshHostKeysEnabled:       w.sshHostKeysEnabled,
 	}
 
 	for _, tag := range tags {
 		switch tag {
 		case ScanTagPorts:
 			w.portScanEnabled = true
+			w.udpProbeEnabled = true
+			w.tlsInventoryEnabled = true
+			w.httpFingerprintEnabled = true
+			w.sshHostKeysEnabled = true
 		case ScanTagSNMP:
 			w.snmpEnabled = true
 		case ScanTagTopology:
 			w.snmpEnabled = true
 			w.topologyLLDPEnabled = true
 			w.topologyCDPEnabled = true
+			w.topologyInferenceEnabled = true
 		case ScanTagNames:
 			w.nameResolutionEnabled = true
 		}
@@ -91,6 +106,11 @@ func applyScanTags(w *Worker, tags []string) func() {
 		w.snmpEnabled = prev.snmpEnabled
 		w.topologyLLDPEnabled = prev.topologyLLDPEnabled
 		w.topologyCDPEnabled = prev.topologyCDPEnabled
+		w.topologyInferenceEnabled = prev.topologyInferenceEnabled
 		w.portScanEnabled = prev.portScanEnabled
+		w.udpProbeEnabled = prev.udpProbeEnabled
+		w.tlsInventoryEnabled = prev.tlsInventoryEnabled
+		w.httpFingerprintEnabled = prev.httpFingerprintEnabled
+		w.sshHostKeysEnabled = prev.sshHostKeysEnabled
 	}
 }
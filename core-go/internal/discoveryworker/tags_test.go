@@ -30,11 +30,11 @@ func TestApplyScanTags_Restores(t *testing.T) {
 	if !w.snmpEnabled {
 		t.Fatalf("expected snmp enabled")
 	}
-	if !w.topologyLLDPEnabled || !w.topologyCDPEnabled {
+	if !w.topologyLLDPEnabled || !w.topologyCDPEnabled || !w.topologyInferenceEnabled {
 		t.Fatalf("expected topology enabled")
 	}
-	if !w.portScanEnabled {
-		t.Fatalf("expected port scan enabled")
+	if !w.portScanEnabled || !w.udpProbeEnabled || !w.tlsInventoryEnabled || !w.httpFingerprintEnabled || !w.sshHostKeysEnabled {
+		t.Fatalf("expected port scan, udp probe, tls inventory, http fingerprint, and ssh host keys enabled")
 	}
 	if !w.nameResolutionEnabled {
 		t.Fatalf("expected name resolution enabled")
@@ -42,7 +42,7 @@ func TestApplyScanTags_Restores(t *testing.T) {
 
 	restore()
 
-	if w.snmpEnabled || w.topologyLLDPEnabled || w.topologyCDPEnabled || w.portScanEnabled || w.nameResolutionEnabled {
+	if w.snmpEnabled || w.topologyLLDPEnabled || w.topologyCDPEnabled || w.topologyInferenceEnabled || w.portScanEnabled || w.udpProbeEnabled || w.tlsInventoryEnabled || w.httpFingerprintEnabled || w.sshHostKeysEnabled || w.nameResolutionEnabled {
 		t.Fatalf("expected restore to reset flags, got %+v", w)
 	}
 }
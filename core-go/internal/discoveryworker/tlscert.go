@@ -0,0 +1,167 @@
+package discoveryworker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"roller_hoops/core-go/internal/enrichment/tlscert"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+const tlsInventoryMaxServicesPerDevice = 32
+
+// runTLSInventory attempts a TLS handshake against every open TCP service on allowlisted targets and
+// records the leaf certificate. Known TLS ports are tried first; other ports are recorded only if the
+// handshake completes.
+//
+// It runs after the port scan so it can reuse the services written earlier in the same run.
+func (w *Worker) runTLSInventory(ctx context.Context, targets []enrichmentTarget) map[string]any {
+	if w == nil || w.q == nil || !w.tlsInventoryEnabled {
+		return nil
+	}
+	if len(w.portScanAllowlist) == 0 {
+		return map[string]any{"enabled": true, "available": false, "reason": "no_allowlist"}
+	}
+
+	scanTargets := w.portScanTargets(targets)
+	if len(scanTargets) == 0 {
+		return map[string]any{"enabled": true, "available": true, "targets": 0, "certificates_written": 0}
+	}
+
+	cfg := tlscert.Config{Timeout: w.tlsTimeout}
+
+	var servicesTried int32
+	var handshakes int32
+	var certificatesWritten int32
+	var expiringSoon int32
+
+	jobs := make(chan portScanTarget)
+	wg := sync.WaitGroup{}
+
+	worker := func() {
+		defer wg.Done()
+		for t := range jobs {
+			if ctx.Err() != nil {
+				return
+			}
+			services, err := w.q.ListOpenTCPServicesForDevice(ctx, t.DeviceID, tlsInventoryMaxServicesPerDevice)
+			if err != nil || len(services) == 0 {
+				continue
+			}
+			services = orderTLSCandidates(services)
+
+			for _, svc := range services {
+				if ctx.Err() != nil {
+					return
+				}
+				atomic.AddInt32(&servicesTried, 1)
+
+				cert, err := tlscert.Fetch(ctx, cfg, t.IP, int(svc.Port))
+				if err != nil {
+					continue
+				}
+				atomic.AddInt32(&handshakes, 1)
+
+				now := time.Now()
+				var cn *string
+				if cert.CommonName != "" {
+					cn = &cert.CommonName
+				}
+				if err := w.q.UpsertServiceCertificate(ctx, sqlcgen.UpsertServiceCertificateParams{
+					ServiceID:         svc.ID,
+					DeviceID:          t.DeviceID,
+					FingerprintSHA256: cert.FingerprintSHA256,
+					Subject:           cert.Subject,
+					CommonName:        cn,
+					SANs:              cert.SANs,
+					Issuer:            cert.Issuer,
+					SerialNumber:      cert.SerialNumber,
+					NotBefore:         cert.NotBefore,
+					NotAfter:          cert.NotAfter,
+					KeyType:           cert.KeyType,
+					SelfSigned:        cert.SelfSigned,
+					ObservedAt:        now,
+				}); err == nil {
+					atomic.AddInt32(&certificatesWritten, 1)
+					if cert.NotAfter.Before(now.Add(30 * 24 * time.Hour)) {
+						atomic.AddInt32(&expiringSoon, 1)
+					}
+				}
+			}
+		}
+	}
+
+	workers := w.portScanWorkers
+	if workers <= 0 {
+		workers = 4
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go worker()
+	}
+
+	for _, t := range scanTargets {
+		select {
+		case <-ctx.Done():
+			close(jobs)
+			wg.Wait()
+			return map[string]any{
+				"enabled":              true,
+				"available":            true,
+				"targets":              len(scanTargets),
+				"services_tried":       int(servicesTried),
+				"handshakes":           int(handshakes),
+				"certificates_written": int(certificatesWritten),
+				"expiring_30d":         int(expiringSoon),
+				"canceled":             true,
+			}
+		case jobs <- t:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return map[string]any{
+		"enabled":              true,
+		"available":            true,
+		"targets":              len(scanTargets),
+		"services_tried":       int(servicesTried),
+		"handshakes":           int(handshakes),
+		"certificates_written": int(certificatesWritten),
+		"expiring_30d":         int(expiringSoon),
+		"timeout":              w.tlsTimeout.String(),
+	}
+}
+
+// orderTLSCandidates moves well-known TLS ports to the front so a tight runtime budget is spent on
+// the services most likely to present a certificate.
+func orderTLSCandidates(services []sqlcgen.OpenTCPService) []sqlcgen.OpenTCPService {
+	out := make([]sqlcgen.OpenTCPService, 0, len(services))
+	for _, svc := range services {
+		if tlscert.IsKnownTLSPort(int(svc.Port)) {
+			out = append(out, svc)
+		}
+	}
+	for _, svc := range services {
+		if !tlscert.IsKnownTLSPort(int(svc.Port)) {
+			out = append(out, svc)
+		}
+	}
+	return out
+}
+
+func (w *Worker) tlsInventoryLogMessage(stats map[string]any) string {
+	if stats == nil {
+		return ""
+	}
+	if avail, ok := stats["available"].(bool); ok && !avail {
+		if reason, ok := stats["reason"].(string); ok && reason != "" {
+			return fmt.Sprintf("tls inventory skipped: %s", reason)
+		}
+		return "tls inventory skipped"
+	}
+	return fmt.Sprintf("tls inventory: targets=%v services=%v handshakes=%v certificates=%v expiring_30d=%v", stats["targets"], stats["services_tried"], stats["handshakes"], stats["certificates_written"], stats["expiring_30d"])
+}
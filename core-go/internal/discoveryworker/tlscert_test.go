@@ -0,0 +1,82 @@
+package discoveryworker
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+func TestWorker_RunTLSInventory_RecordsLeafCertificate(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("parse url: %v", err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+
+	var mu sync.Mutex
+	var written []sqlcgen.UpsertServiceCertificateParams
+	q := &fakeQueries{
+		listOpenTCPFn: func(ctx context.Context, deviceID string, limit int32) ([]sqlcgen.OpenTCPService, error) {
+			return []sqlcgen.OpenTCPService{{ID: "svc-1", Port: int32(port)}}, nil
+		},
+		upsertCertificateFn: func(ctx context.Context, arg sqlcgen.UpsertServiceCertificateParams) error {
+			mu.Lock()
+			defer mu.Unlock()
+			written = append(written, arg)
+			return nil
+		},
+	}
+
+	w := New(zerolog.Nop(), q, Options{
+		PortScanAllowlist:   []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
+		TLSInventoryEnabled: true,
+		TLSTimeout:          2 * time.Second,
+	}, nil)
+
+	stats := w.runTLSInventory(context.Background(), []enrichmentTarget{
+		{DeviceID: "dev-1", IP: netip.MustParseAddr("127.0.0.1")},
+		{DeviceID: "dev-2", IP: netip.MustParseAddr("192.0.2.1")},
+	})
+
+	if stats["targets"] != 1 || stats["handshakes"] != 1 || stats["certificates_written"] != 1 {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+	if len(written) != 1 {
+		t.Fatalf("expected 1 certificate, got %d", len(written))
+	}
+	got := written[0]
+	if got.ServiceID != "svc-1" || got.DeviceID != "dev-1" {
+		t.Fatalf("unexpected linkage %+v", got)
+	}
+	if len(got.FingerprintSHA256) != 64 || got.NotAfter.IsZero() {
+		t.Fatalf("unexpected certificate %+v", got)
+	}
+}
+
+func TestOrderTLSCandidates(t *testing.T) {
+	in := []sqlcgen.OpenTCPService{{ID: "a", Port: 22}, {ID: "b", Port: 443}, {ID: "c", Port: 8080}, {ID: "d", Port: 8443}}
+	got := orderTLSCandidates(in)
+	want := []string{"b", "d", "a", "c"}
+	for i, id := range want {
+		if got[i].ID != id {
+			t.Fatalf("expected order %v, got %+v", want, got)
+		}
+	}
+}
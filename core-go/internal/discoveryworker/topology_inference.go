@@ -0,0 +1,140 @@
+package discoveryworker
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"roller_hoops/core-go/internal/enrichment/snmp"
+	"roller_hoops/core-go/internal/sqlcgen"
+	"roller_hoops/core-go/internal/topology"
+)
+
+// maxInferenceMACs bounds the owner lookups one inference pass performs; MACs past the cap stay unknown,
+// which only makes the attachment rules more conservative.
+const maxInferenceMACs = 4096
+
+// inferenceInput accumulates bridge tables and ARP caches from the enrichment workers.
+type inferenceInput struct {
+	mu       sync.Mutex
+	switches []topology.Switch
+	arp      map[string]string
+}
+
+// collectInferenceInput walks the bridge FDB and ARP cache of one SNMP target. Devices without a
+// forwarding database still contribute their ARP entries for MAC -> IP resolution.
+func collectInferenceInput(ctx context.Context, client *snmp.Client, target snmp.Target, ifaces map[int]snmp.InterfaceInfo, ifIndexToInterfaceID map[int]string, in *inferenceInput) {
+	arp, _ := client.WalkARP(ctx, target)
+	fdb, _ := client.WalkBridgeFDB(ctx, target)
+
+	var sw *topology.Switch
+	if len(fdb) > 0 {
+		sw = &topology.Switch{DeviceID: target.ID}
+		for _, info := range ifaces {
+			if info.MAC != nil && *info.MAC != "" {
+				sw.MACs = append(sw.MACs, *info.MAC)
+			}
+		}
+		portIndex := map[string]int{}
+		for _, e := range fdb {
+			interfaceID := ifIndexToInterfaceID[e.IfIndex]
+			if interfaceID == "" {
+				continue
+			}
+			i, ok := portIndex[interfaceID]
+			if !ok {
+				i = len(sw.Ports)
+				portIndex[interfaceID] = i
+				sw.Ports = append(sw.Ports, topology.Port{InterfaceID: interfaceID})
+			}
+			sw.Ports[i].MACs = append(sw.Ports[i].MACs, e.MAC)
+		}
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if sw != nil && len(sw.Ports) > 0 {
+		in.switches = append(in.switches, *sw)
+	}
+	if len(arp) > 0 && in.arp == nil {
+		in.arp = make(map[string]string, len(arp))
+	}
+	for mac, ip := range arp {
+		in.arp[mac] = ip
+	}
+}
+
+// inferTopology resolves the owners of learned MACs (known device MACs first, then ARP-derived IPs),
+// writes the inferred links and prunes inferred links of the polled switches that were not re-inferred.
+// Links observed over LLDP/CDP (or entered manually) take precedence; see UpsertInferredLink.
+func (w *Worker) inferTopology(ctx context.Context, in *inferenceInput, now time.Time) int {
+	if in == nil || len(in.switches) == 0 {
+		return 0
+	}
+
+	own := map[string]bool{}
+	for _, sw := range in.switches {
+		for _, mac := range sw.MACs {
+			own[strings.ToLower(mac)] = true
+		}
+	}
+	owners := map[string]string{}
+	looked := map[string]bool{}
+	for _, sw := range in.switches {
+		for _, p := range sw.Ports {
+			for _, mac := range p.MACs {
+				mac = strings.ToLower(mac)
+				if own[mac] || looked[mac] || len(looked) >= maxInferenceMACs {
+					continue
+				}
+				if ctx.Err() != nil {
+					return 0
+				}
+				looked[mac] = true
+				if id, err := w.q.FindDeviceIDByMAC(ctx, mac); err == nil && id != "" {
+					owners[mac] = id
+					continue
+				}
+				if ip := in.arp[mac]; ip != "" {
+					if id, err := w.q.FindDeviceIDByIP(ctx, ip); err == nil && id != "" {
+						owners[mac] = id
+					}
+				}
+			}
+		}
+	}
+
+	linkType := "ethernet"
+	written := 0
+	for _, l := range topology.Infer(in.switches, owners) {
+		aIf := l.AInterfaceID
+		aDev, aIfPtr, bDev, bIfPtr := canonicalizeLinkEndpoints(l.ADeviceID, &aIf, l.BDeviceID, optionalString(l.BInterfaceID))
+		n, err := w.q.UpsertInferredLink(ctx, sqlcgen.UpsertInferredLinkParams{
+			LinkKey:      makeLinkKey("inferred", aDev, aIfPtr, bDev, bIfPtr),
+			ADeviceID:    aDev,
+			AInterfaceID: aIfPtr,
+			BDeviceID:    bDev,
+			BInterfaceID: bIfPtr,
+			LinkType:     &linkType,
+			Confidence:   int32(l.Confidence),
+			ObservedAt:   now,
+		})
+		if err != nil {
+			w.log.Debug().Err(err).Str("a_device_id", aDev).Str("b_device_id", bDev).Msg("inferred link upsert failed")
+			continue
+		}
+		if n > 0 {
+			written++
+		}
+	}
+
+	deviceIDs := make([]string, 0, len(in.switches))
+	for _, sw := range in.switches {
+		deviceIDs = append(deviceIDs, sw.DeviceID)
+	}
+	if _, err := w.q.DeleteStaleInferredLinks(ctx, sqlcgen.DeleteStaleInferredLinksParams{DeviceIDs: deviceIDs, Before: now}); err != nil {
+		w.log.Debug().Err(err).Msg("prune stale inferred links failed")
+	}
+	return written
+}
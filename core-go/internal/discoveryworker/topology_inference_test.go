@@ -0,0 +1,75 @@
+package discoveryworker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+	"roller_hoops/core-go/internal/topology"
+)
+
+func TestInferTopology_ResolvesOwnersAndPrunes(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var upserts []sqlcgen.UpsertInferredLinkParams
+	var pruned sqlcgen.DeleteStaleInferredLinksParams
+	q := &fakeQueries{
+		findByMacFn: func(ctx context.Context, mac string) (string, error) {
+			if mac == "00:00:00:00:01:01" {
+				return "dev-a", nil
+			}
+			return "", pgx.ErrNoRows
+		},
+		findByIPFn: func(ctx context.Context, ip string) (string, error) {
+			if ip == "10.0.0.22" {
+				return "dev-b", nil
+			}
+			return "", pgx.ErrNoRows
+		},
+		upsertInferredLinkFn: func(ctx context.Context, arg sqlcgen.UpsertInferredLinkParams) (int64, error) {
+			upserts = append(upserts, arg)
+			if arg.BDeviceID == "sw-1" && arg.ADeviceID == "dev-b" {
+				// An LLDP link already covers this port.
+				return 0, nil
+			}
+			return 1, nil
+		},
+		deleteStaleLinksFn: func(ctx context.Context, arg sqlcgen.DeleteStaleInferredLinksParams) (int64, error) {
+			pruned = arg
+			return 0, nil
+		},
+	}
+	w := New(zerolog.Nop(), q, Options{TopologyInferenceEnabled: true}, nil)
+
+	in := &inferenceInput{
+		switches: []topology.Switch{{
+			DeviceID: "sw-1",
+			MACs:     []string{"00:00:00:00:00:01"},
+			Ports: []topology.Port{
+				{InterfaceID: "if-1", MACs: []string{"00:00:00:00:01:01"}},
+				{InterfaceID: "if-2", MACs: []string{"00:00:00:00:01:02"}},
+			},
+		}},
+		arp: map[string]string{"00:00:00:00:01:02": "10.0.0.22"},
+	}
+	if got := w.inferTopology(context.Background(), in, now); got != 1 {
+		t.Fatalf("expected 1 link written, got %d", got)
+	}
+
+	ifID := func(s string) *string { return &s }
+	ethernet := "ethernet"
+	want := []sqlcgen.UpsertInferredLinkParams{
+		{LinkKey: "inferred:dev-a:-:sw-1:if-1", ADeviceID: "dev-a", BDeviceID: "sw-1", BInterfaceID: ifID("if-1"), LinkType: &ethernet, Confidence: topology.ConfidenceHost, ObservedAt: now},
+		{LinkKey: "inferred:dev-b:-:sw-1:if-2", ADeviceID: "dev-b", BDeviceID: "sw-1", BInterfaceID: ifID("if-2"), LinkType: &ethernet, Confidence: topology.ConfidenceHost, ObservedAt: now},
+	}
+	if !reflect.DeepEqual(upserts, want) {
+		t.Fatalf("unexpected upserts %+v", upserts)
+	}
+	if !reflect.DeepEqual(pruned, sqlcgen.DeleteStaleInferredLinksParams{DeviceIDs: []string{"sw-1"}, Before: now}) {
+		t.Fatalf("unexpected prune %+v", pruned)
+	}
+}
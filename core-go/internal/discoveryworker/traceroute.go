@@ -0,0 +1,209 @@
+package discoveryworker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"roller_hoops/core-go/internal/enrichment/traceroute"
+	"roller_hoops/core-go/internal/sqlcgen"
+	"roller_hoops/core-go/internal/tagging"
+)
+
+// tracerouteRouterConfidence is deliberately below the SNMP/port based router signals: forwarding a probe
+// proves the hop routes, but an L3 switch or firewall answers the same way.
+const tracerouteRouterConfidence = 60
+
+// runTraceroute probes the routed path toward a scope that is not directly connected to this host and stores
+// each answering hop as a device (auto-tagged as a router) plus the ordered path for the L3 map.
+// It runs at most once per discovery run.
+func (w *Worker) runTraceroute(ctx context.Context, runID string, scope *netip.Prefix) map[string]any {
+	if w == nil || w.q == nil || !w.tracerouteEnabled {
+		return nil
+	}
+	if scope == nil {
+		return map[string]any{"enabled": true, "available": false, "reason": "no_scope"}
+	}
+	if !scope.Addr().Unmap().Is4() {
+		return map[string]any{"enabled": true, "available": false, "reason": "ipv6_unsupported"}
+	}
+	if scopeIsDirectlyConnected(*scope, localPrefixes()) {
+		return map[string]any{"enabled": true, "available": false, "reason": "directly_connected"}
+	}
+
+	target := tracerouteTarget(*scope)
+	tracer := traceroute.New(traceroute.Config{
+		Mode:    w.tracerouteMode,
+		MaxHops: w.tracerouteMaxHops,
+		Timeout: w.tracerouteTimeout,
+	})
+	path, err := tracer.Trace(ctx, target)
+	if err != nil {
+		if errors.Is(err, traceroute.ErrNotPermitted) {
+			return map[string]any{"enabled": true, "available": false, "reason": "not_permitted"}
+		}
+		if len(path.Hops) == 0 {
+			return map[string]any{"enabled": true, "available": false, "reason": "trace_failed", "error": err.Error()}
+		}
+		// Keep whatever was traced before the context ran out.
+	}
+
+	stats, err := recordTraceroutePath(ctx, w.q, runID, *scope, w.tracerouteMode, path, time.Now())
+	if err != nil {
+		stats["error"] = err.Error()
+	}
+	stats["enabled"] = true
+	stats["available"] = true
+	stats["target"] = target.String()
+	stats["mode"] = w.tracerouteMode
+	return stats
+}
+
+// recordTraceroutePath resolves answering hops to devices, records their IPs as observations, tags
+// intermediate hops as routers and stores the path. Silent hops are kept in the path with a NULL address so
+// the map can show the gap.
+func recordTraceroutePath(ctx context.Context, q Queries, runID string, scope netip.Prefix, mode string, path traceroute.Path, now time.Time) (map[string]any, error) {
+	stats := map[string]any{
+		"hops":            len(path.Hops),
+		"hops_responding": 0,
+		"devices_created": 0,
+		"routers_tagged":  0,
+		"reached":         path.Reached,
+	}
+	if len(path.Hops) == 0 {
+		return stats, nil
+	}
+
+	params := sqlcgen.InsertTraceroutePathParams{
+		Scope:      scope.Masked().String(),
+		Target:     path.Target.String(),
+		Mode:       mode,
+		Reached:    path.Reached,
+		TTLs:       make([]int32, 0, len(path.Hops)),
+		IPs:        make([]*string, 0, len(path.Hops)),
+		DeviceIDs:  make([]*string, 0, len(path.Hops)),
+		RTTMs:      make([]*float32, 0, len(path.Hops)),
+		ObservedAt: now,
+	}
+	if runID != "" {
+		params.RunID = &runID
+	}
+
+	var responding, created, tagged int
+	for _, hop := range path.Hops {
+		params.TTLs = append(params.TTLs, int32(hop.TTL))
+		if !hop.Addr.IsValid() {
+			params.IPs = append(params.IPs, nil)
+			params.DeviceIDs = append(params.DeviceIDs, nil)
+			params.RTTMs = append(params.RTTMs, nil)
+			continue
+		}
+		responding++
+		ip := hop.Addr.String()
+		rtt := float32(hop.RTT.Microseconds()) / 1000
+		params.IPs = append(params.IPs, &ip)
+		params.RTTMs = append(params.RTTMs, &rtt)
+
+		deviceID, isNew, err := resolveDevice(ctx, q, "", hop.Addr)
+		if err != nil {
+			return stats, err
+		}
+		if isNew {
+			created++
+		}
+		params.DeviceIDs = append(params.DeviceIDs, &deviceID)
+
+		if err := q.UpsertDeviceIP(ctx, sqlcgen.UpsertDeviceIPParams{DeviceID: deviceID, IP: ip}); err != nil {
+			return stats, err
+		}
+		if runID != "" {
+			if err := q.InsertIPObservation(ctx, sqlcgen.InsertIPObservationParams{
+				RunID:    runID,
+				DeviceID: deviceID,
+				IP:       ip,
+			}); err != nil {
+				return stats, err
+			}
+		}
+		if hop.Addr != path.Target {
+			if err := q.UpsertDeviceTag(ctx, sqlcgen.UpsertDeviceTagParams{
+				DeviceID:   deviceID,
+				Tag:        tagging.TagRouter,
+				Source:     "auto",
+				Confidence: tracerouteRouterConfidence,
+				Evidence:   map[string]any{"signal": "traceroute", "ttl": hop.TTL, "scope": params.Scope},
+			}); err == nil {
+				tagged++
+			}
+		}
+	}
+
+	stats["hops_responding"] = responding
+	stats["devices_created"] = created
+	stats["routers_tagged"] = tagged
+	if err := q.InsertTraceroutePath(ctx, params); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// scopeIsDirectlyConnected reports whether scope overlaps a prefix assigned to one of this host's interfaces.
+func scopeIsDirectlyConnected(scope netip.Prefix, local []netip.Prefix) bool {
+	scope = scope.Masked()
+	for _, p := range local {
+		if p.Addr().IsLoopback() {
+			continue
+		}
+		if p.Masked().Overlaps(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+func localPrefixes() []netip.Prefix {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	out := make([]netip.Prefix, 0, len(addrs))
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		addr, ok := netip.AddrFromSlice(ipNet.IP)
+		if !ok {
+			continue
+		}
+		ones, _ := ipNet.Mask.Size()
+		if p, err := addr.Unmap().Prefix(ones); err == nil {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// tracerouteTarget picks the first usable host of the scope (the address itself for /31 and /32).
+func tracerouteTarget(scope netip.Prefix) netip.Addr {
+	if scope.Bits() >= scope.Addr().BitLen()-1 {
+		return scope.Addr()
+	}
+	return scope.Masked().Addr().Next()
+}
+
+func (w *Worker) tracerouteLogMessage(stats map[string]any) string {
+	if stats == nil {
+		return ""
+	}
+	if avail, ok := stats["available"].(bool); ok && !avail {
+		if reason, ok := stats["reason"].(string); ok && reason != "" {
+			return fmt.Sprintf("traceroute skipped: %s", reason)
+		}
+		return "traceroute skipped"
+	}
+	return fmt.Sprintf("traceroute: target=%v hops=%v responding=%v reached=%v devices_created=%v", stats["target"], stats["hops"], stats["hops_responding"], stats["reached"], stats["devices_created"])
+}
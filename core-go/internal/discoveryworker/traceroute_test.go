@@ -0,0 +1,144 @@
+package discoveryworker
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/enrichment/traceroute"
+	"roller_hoops/core-go/internal/sqlcgen"
+	"roller_hoops/core-go/internal/tagging"
+)
+
+func TestRecordTraceroutePath(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	known := map[string]string{"10.0.0.1": "gw-1"}
+	var created int
+	var tagged []sqlcgen.UpsertDeviceTagParams
+	var observed []string
+	var stored sqlcgen.InsertTraceroutePathParams
+	q := &fakeQueries{
+		findByIPFn: func(ctx context.Context, ip string) (string, error) {
+			if id, ok := known[ip]; ok {
+				return id, nil
+			}
+			return "", pgx.ErrNoRows
+		},
+		createFn: func(ctx context.Context, displayName *string) (sqlcgen.Device, error) {
+			created++
+			return sqlcgen.Device{ID: "new-" + string(rune('0'+created))}, nil
+		},
+		insertIPObs: func(ctx context.Context, arg sqlcgen.InsertIPObservationParams) error {
+			observed = append(observed, arg.IP)
+			return nil
+		},
+		upsertTagFn: func(ctx context.Context, arg sqlcgen.UpsertDeviceTagParams) error {
+			tagged = append(tagged, arg)
+			return nil
+		},
+		insertTracerouteFn: func(ctx context.Context, arg sqlcgen.InsertTraceroutePathParams) error {
+			stored = arg
+			return nil
+		},
+	}
+
+	path := traceroute.Path{
+		Target:  netip.MustParseAddr("192.168.50.1"),
+		Reached: true,
+		Hops: []traceroute.Hop{
+			{TTL: 1, Addr: netip.MustParseAddr("10.0.0.1"), RTT: 1500 * time.Microsecond},
+			{TTL: 2},
+			{TTL: 3, Addr: netip.MustParseAddr("172.16.0.9"), RTT: 8 * time.Millisecond},
+			{TTL: 4, Addr: netip.MustParseAddr("192.168.50.1"), RTT: 9 * time.Millisecond},
+		},
+	}
+	scope := netip.MustParsePrefix("192.168.50.0/24")
+
+	stats, err := recordTraceroutePath(context.Background(), q, "run-1", scope, traceroute.ModeICMP, path, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats["hops"] != 4 || stats["hops_responding"] != 3 || stats["devices_created"] != 2 || stats["routers_tagged"] != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if len(observed) != 3 {
+		t.Fatalf("expected 3 ip observations, got %v", observed)
+	}
+
+	// Only intermediate hops are routers; the target itself is not tagged.
+	for _, tag := range tagged {
+		if tag.Tag != tagging.TagRouter || tag.Source != "auto" || tag.Evidence["signal"] != "traceroute" {
+			t.Fatalf("unexpected tag: %+v", tag)
+		}
+	}
+	if tagged[0].DeviceID != "gw-1" || tagged[1].DeviceID != "new-1" {
+		t.Fatalf("unexpected tagged devices: %+v", tagged)
+	}
+
+	if stored.Scope != "192.168.50.0/24" || stored.Target != "192.168.50.1" || !stored.Reached || stored.RunID == nil {
+		t.Fatalf("unexpected stored path: %+v", stored)
+	}
+	if len(stored.TTLs) != 4 || len(stored.IPs) != 4 || len(stored.DeviceIDs) != 4 || len(stored.RTTMs) != 4 {
+		t.Fatalf("expected parallel hop arrays of length 4, got %+v", stored)
+	}
+	if stored.IPs[1] != nil || stored.DeviceIDs[1] != nil || stored.RTTMs[1] != nil {
+		t.Fatalf("expected silent hop to be stored with nulls, got %+v", stored)
+	}
+	if *stored.DeviceIDs[3] != "new-2" || *stored.RTTMs[0] != 1.5 {
+		t.Fatalf("unexpected hop values: device=%s rtt=%v", *stored.DeviceIDs[3], *stored.RTTMs[0])
+	}
+}
+
+func TestRecordTraceroutePath_EmptyPathIsNotStored(t *testing.T) {
+	q := &fakeQueries{
+		insertTracerouteFn: func(ctx context.Context, arg sqlcgen.InsertTraceroutePathParams) error {
+			t.Fatalf("empty path should not be stored")
+			return nil
+		},
+	}
+	path := traceroute.Path{Target: netip.MustParseAddr("192.168.50.1")}
+	stats, err := recordTraceroutePath(context.Background(), q, "run-1", netip.MustParsePrefix("192.168.50.0/24"), traceroute.ModeICMP, path, time.Now())
+	if err != nil || stats["hops"] != 0 {
+		t.Fatalf("unexpected result: %+v %v", stats, err)
+	}
+}
+
+func TestScopeIsDirectlyConnected(t *testing.T) {
+	local := []netip.Prefix{
+		netip.MustParsePrefix("127.0.0.1/8"),
+		netip.MustParsePrefix("10.0.0.5/24"),
+	}
+	cases := []struct {
+		scope string
+		want  bool
+	}{
+		{"10.0.0.0/24", true},
+		{"10.0.0.128/25", true},
+		{"10.0.0.0/16", true},
+		{"10.0.1.0/24", false},
+		{"127.0.0.0/24", false},
+		{"192.168.50.0/24", false},
+	}
+	for _, tc := range cases {
+		if got := scopeIsDirectlyConnected(netip.MustParsePrefix(tc.scope), local); got != tc.want {
+			t.Fatalf("%s: expected %v, got %v", tc.scope, tc.want, got)
+		}
+	}
+}
+
+func TestTracerouteTarget(t *testing.T) {
+	cases := map[string]string{
+		"192.168.50.0/24":  "192.168.50.1",
+		"192.168.50.77/24": "192.168.50.1",
+		"10.9.9.9/32":      "10.9.9.9",
+		"10.9.9.8/31":      "10.9.9.8",
+	}
+	for scope, want := range cases {
+		if got := tracerouteTarget(netip.MustParsePrefix(scope)).String(); got != want {
+			t.Fatalf("%s: expected %s, got %s", scope, want, got)
+		}
+	}
+}
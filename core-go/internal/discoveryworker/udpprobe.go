@@ -0,0 +1,162 @@
+package discoveryworker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"roller_hoops/core-go/internal/enrichment/udpprobe"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// runUDPProbe sends protocol-specific UDP payloads to allowlisted targets and records only ports that reply.
+// Previously open UDP services are reconciled to closed only when the host answers with ICMP port unreachable;
+// silence ("filtered") is what a lost datagram or a busy responder looks like too, so it leaves them as they are.
+//
+// It shares the port scan allowlist, target cap and worker count so operators have a single active-scan policy.
+func (w *Worker) runUDPProbe(ctx context.Context, targets []enrichmentTarget) map[string]any {
+	if w == nil || w.q == nil || !w.udpProbeEnabled {
+		return nil
+	}
+	if len(w.portScanAllowlist) == 0 || len(w.udpProbePorts) == 0 {
+		return map[string]any{"enabled": true, "available": false, "reason": "no_allowlist_or_ports"}
+	}
+
+	scanTargets := w.portScanTargets(targets)
+	if len(scanTargets) == 0 {
+		return map[string]any{"enabled": true, "available": true, "targets": 0, "services_written": 0}
+	}
+
+	prober := udpprobe.NewProber(udpprobe.Config{
+		Timeout:       w.udpProbeTimeout,
+		Retries:       1,
+		SNMPCommunity: w.snmpCommunity,
+	})
+
+	ports := make([]string, 0, len(w.udpProbePorts))
+	for _, p := range w.udpProbePorts {
+		ports = append(ports, strconv.Itoa(p))
+	}
+
+	var attempted int32
+	var responsive int32
+	var servicesWritten int32
+	var servicesClosed int32
+
+	jobs := make(chan portScanTarget)
+	wg := sync.WaitGroup{}
+
+	worker := func() {
+		defer wg.Done()
+		for t := range jobs {
+			if ctx.Err() != nil {
+				return
+			}
+			atomic.AddInt32(&attempted, 1)
+
+			results := prober.ProbeHost(ctx, t.IP, w.udpProbePorts)
+
+			now := time.Now()
+			source := "udp_probe"
+			state := "open"
+			replied := false
+			for _, res := range results {
+				if res.State == "filtered" {
+					continue
+				}
+				if res.State != "open" {
+					// Reconcile previously open UDP services the host now refuses.
+					if n, err := w.q.MarkServiceNotOpen(ctx, sqlcgen.MarkServiceNotOpenParams{
+						DeviceID:   t.DeviceID,
+						Protocol:   "udp",
+						Port:       int32(res.Port),
+						State:      res.State,
+						Source:     &source,
+						ObservedAt: now,
+					}); err == nil && n > 0 {
+						atomic.AddInt32(&servicesClosed, int32(n))
+					}
+					continue
+				}
+
+				replied = true
+				name := res.Name
+				summary := res.Summary
+				if err := w.q.UpsertServiceFromScan(ctx, sqlcgen.UpsertServiceFromScanParams{
+					DeviceID:   t.DeviceID,
+					Protocol:   "udp",
+					Port:       int32(res.Port),
+					Name:       &name,
+					State:      &state,
+					Source:     &source,
+					Summary:    &summary,
+					ObservedAt: now,
+				}); err == nil {
+					atomic.AddInt32(&servicesWritten, 1)
+				}
+			}
+			if replied {
+				atomic.AddInt32(&responsive, 1)
+			}
+		}
+	}
+
+	workers := w.portScanWorkers
+	if workers <= 0 {
+		workers = 4
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go worker()
+	}
+
+	for _, t := range scanTargets {
+		select {
+		case <-ctx.Done():
+			close(jobs)
+			wg.Wait()
+			return map[string]any{
+				"enabled":          true,
+				"available":        true,
+				"targets":          len(scanTargets),
+				"attempted":        int(attempted),
+				"responsive":       int(responsive),
+				"services_written": int(servicesWritten),
+				"services_closed":  int(servicesClosed),
+				"canceled":         true,
+			}
+		case jobs <- t:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return map[string]any{
+		"enabled":          true,
+		"available":        true,
+		"targets":          len(scanTargets),
+		"attempted":        int(attempted),
+		"responsive":       int(responsive),
+		"services_written": int(servicesWritten),
+		"services_closed":  int(servicesClosed),
+		"ports":            strings.Join(ports, ","),
+		"timeout":          w.udpProbeTimeout.String(),
+	}
+}
+
+func (w *Worker) udpProbeLogMessage(stats map[string]any) string {
+	if stats == nil {
+		return ""
+	}
+	if avail, ok := stats["available"].(bool); ok && !avail {
+		if reason, ok := stats["reason"].(string); ok && reason != "" {
+			return fmt.Sprintf("udp probe skipped: %s", reason)
+		}
+		return "udp probe skipped"
+	}
+	return fmt.Sprintf("udp probe: targets=%v attempted=%v responsive=%v services=%v closed=%v", stats["targets"], stats["attempted"], stats["responsive"], stats["services_written"], stats["services_closed"])
+}
@@ -0,0 +1,87 @@
+package discoveryworker
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// listenUDPProbeTarget binds a loopback UDP port the prober targets. Replying listeners answer every datagram
+// with an SSDP response; silent ones read and drop, which the prober sees as filtered.
+func listenUDPProbeTarget(t *testing.T, addr string, reply bool) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		t.Skipf("cannot bind %s: %v", addr, err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			_, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if reply {
+				_, _ = conn.WriteTo([]byte("HTTP/1.1 200 OK\r\nST: upnp:rootdevice\r\nSERVER: test/1.0\r\n\r\n"), from)
+			}
+		}
+	}()
+}
+
+func TestWorker_RunUDPProbe_RecordsOnlyRepliedPorts(t *testing.T) {
+	// 127.0.0.2 keeps the test clear of any local SSDP/mDNS responders on 127.0.0.1.
+	listenUDPProbeTarget(t, "127.0.0.2:1900", true)
+	listenUDPProbeTarget(t, "127.0.0.2:5353", false)
+
+	var mu sync.Mutex
+	var opened []sqlcgen.UpsertServiceFromScanParams
+	notOpen := map[int32]string{}
+	q := &fakeQueries{
+		upsertServiceFn: func(ctx context.Context, arg sqlcgen.UpsertServiceFromScanParams) error {
+			mu.Lock()
+			defer mu.Unlock()
+			opened = append(opened, arg)
+			return nil
+		},
+		markServiceNotOpenFn: func(ctx context.Context, arg sqlcgen.MarkServiceNotOpenParams) (int64, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			notOpen[arg.Port] = arg.State
+			return 0, nil
+		},
+	}
+
+	w := New(zerolog.Nop(), q, Options{
+		PortScanAllowlist: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
+		UDPProbeEnabled:   true,
+		UDPProbePorts:     []int{69, 1900, 5353},
+		UDPProbeTimeout:   200 * time.Millisecond,
+	}, nil)
+
+	stats := w.runUDPProbe(context.Background(), []enrichmentTarget{
+		{DeviceID: "dev-1", IP: netip.MustParseAddr("127.0.0.2")},
+	})
+
+	if len(opened) != 1 || opened[0].Port != 1900 || opened[0].Protocol != "udp" || opened[0].State == nil || *opened[0].State != "open" {
+		t.Fatalf("expected only the replying port to be recorded open, got %+v", opened)
+	}
+	if notOpen[69] != "closed" {
+		t.Fatalf("expected the refused port to be reconciled to closed, got %v", notOpen)
+	}
+	// A single silent probe may just be a lost datagram: an open service stays open.
+	if state, ok := notOpen[5353]; ok {
+		t.Fatalf("expected the silent port to be left alone, got %q", state)
+	}
+	if stats["services_written"] != 1 || stats["responsive"] != 1 {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+}
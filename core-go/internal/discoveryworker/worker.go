@@ -18,7 +18,11 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog"
 
+	"roller_hoops/core-go/internal/enrichment/traceroute"
+	"roller_hoops/core-go/internal/enrichment/udpprobe"
+	"roller_hoops/core-go/internal/identity"
 	"roller_hoops/core-go/internal/metrics"
+	"roller_hoops/core-go/internal/naming"
 	"roller_hoops/core-go/internal/sqlcgen"
 )
 
@@ -32,6 +36,10 @@ type Queries interface {
 	CreateDevice(ctx context.Context, displayName *string) (sqlcgen.Device, error)
 	FindDeviceIDByMAC(ctx context.Context, mac string) (string, error)
 	FindDeviceIDByIP(ctx context.Context, ip string) (string, error)
+	FindDeviceIDByClientID(ctx context.Context, clientID string) (string, error)
+	FindDeviceIDByHostName(ctx context.Context, name string) (string, error)
+	FindRotatingDeviceIDByIP(ctx context.Context, arg sqlcgen.FindRotatingDeviceIDByIPParams) (string, error)
+	UnarchiveReseenDevice(ctx context.Context, arg sqlcgen.UnarchiveReseenDeviceParams) (int64, error)
 	UpsertDeviceIP(ctx context.Context, arg sqlcgen.UpsertDeviceIPParams) error
 	UpsertDeviceMAC(ctx context.Context, arg sqlcgen.UpsertDeviceMACParams) error
 	InsertIPObservation(ctx context.Context, arg sqlcgen.InsertIPObservationParams) error
@@ -47,66 +55,141 @@ type Queries interface {
 	LinkDeviceMACToInterface(ctx context.Context, arg sqlcgen.LinkDeviceMACToInterfaceParams) (int64, error)
 	UpsertInterfaceVLAN(ctx context.Context, arg sqlcgen.UpsertInterfaceVLANParams) error
 	UpsertLink(ctx context.Context, arg sqlcgen.UpsertLinkParams) error
+	UpsertInferredLink(ctx context.Context, arg sqlcgen.UpsertInferredLinkParams) (int64, error)
+	DeleteStaleInferredLinks(ctx context.Context, arg sqlcgen.DeleteStaleInferredLinksParams) (int64, error)
+	UpsertSTPBridge(ctx context.Context, arg sqlcgen.UpsertSTPBridgeParams) error
+	UpsertSTPPort(ctx context.Context, arg sqlcgen.UpsertSTPPortParams) error
+	DeleteStaleSTPPorts(ctx context.Context, arg sqlcgen.DeleteStaleSTPPortsParams) (int64, error)
+	SyncInterfaceAggregates(ctx context.Context, arg sqlcgen.SyncInterfaceAggregatesParams) (int64, error)
+	DeviceHasTag(ctx context.Context, deviceID string, tag string) (bool, error)
+	UpsertRoutingAdjacency(ctx context.Context, arg sqlcgen.UpsertRoutingAdjacencyParams) (int64, error)
+	MarkStaleRoutingAdjacenciesDown(ctx context.Context, arg sqlcgen.MarkStaleRoutingAdjacenciesDownParams) (int64, error)
+	UpsertInterfacePoE(ctx context.Context, arg sqlcgen.UpsertInterfacePoEParams) error
+	DeleteStaleInterfacePoE(ctx context.Context, arg sqlcgen.DeleteStaleInterfacePoEParams) (int64, error)
+	SyncPoEPoweredDevices(ctx context.Context, deviceIDs []string) (int64, error)
+	ListSNMPProfilesForDevice(ctx context.Context, deviceID string) ([]sqlcgen.SNMPProfile, error)
+	UpsertDeviceCustomFact(ctx context.Context, arg sqlcgen.UpsertDeviceCustomFactParams) (int64, error)
+	DeleteStaleDeviceCustomFacts(ctx context.Context, arg sqlcgen.DeleteStaleDeviceCustomFactsParams) (int64, error)
 	UpsertServiceFromScan(ctx context.Context, arg sqlcgen.UpsertServiceFromScanParams) error
+	MarkServiceNotOpen(ctx context.Context, arg sqlcgen.MarkServiceNotOpenParams) (int64, error)
+	ListOpenTCPServicesForDevice(ctx context.Context, deviceID string, limit int32) ([]sqlcgen.OpenTCPService, error)
+	UpsertServiceCertificate(ctx context.Context, arg sqlcgen.UpsertServiceCertificateParams) error
+	UpdateServiceHTTPFingerprint(ctx context.Context, arg sqlcgen.UpdateServiceHTTPFingerprintParams) error
+	UpsertSSHHostKey(ctx context.Context, arg sqlcgen.UpsertSSHHostKeyParams) (sqlcgen.UpsertSSHHostKeyRow, error)
+	UpsertDeviceOSGuess(ctx context.Context, arg sqlcgen.UpsertDeviceOSGuessParams) error
+	DeleteDeviceOSGuessesAboveRank(ctx context.Context, arg sqlcgen.DeleteDeviceOSGuessesAboveRankParams) error
+	InsertTraceroutePath(ctx context.Context, arg sqlcgen.InsertTraceroutePathParams) error
+	ListDuplicateMatches(ctx context.Context, limit int32) ([]sqlcgen.DuplicateMatch, error)
+	ReplaceDeviceDuplicateCandidates(ctx context.Context, arg sqlcgen.ReplaceDeviceDuplicateCandidatesParams) (int64, error)
+	MarkStaleIPAddresses(ctx context.Context, before time.Time) (int64, error)
+	MarkStaleMACAddresses(ctx context.Context, before time.Time) (int64, error)
+	DetachStaleIPAddresses(ctx context.Context, arg sqlcgen.DetachStaleFactsParams) (int64, error)
+	DetachStaleMACAddresses(ctx context.Context, arg sqlcgen.DetachStaleFactsParams) (int64, error)
+	RecordDeviceAvailability(ctx context.Context, arg sqlcgen.RecordDeviceAvailabilityParams) ([]sqlcgen.DeviceAvailabilityChange, error)
+	RecordDeviceStateSnapshots(ctx context.Context, arg sqlcgen.RecordDeviceStateSnapshotsParams) (int64, error)
 }
 
 type Worker struct {
-	log                   zerolog.Logger
-	q                     Queries
-	pollInterval          time.Duration
-	runDelay              time.Duration
-	maxRuntime            time.Duration
-	arpTablePath          string
-	maxTargets            int
-	pingTimeout           time.Duration
-	pingWorkers           int
-	enrichMaxTargets      int
-	enrichWorkers         int
-	nameResolutionEnabled bool
-	snmpEnabled           bool
-	snmpCommunity         string
-	snmpVersion           string
-	snmpTimeout           time.Duration
-	snmpRetries           int
-	snmpPort              uint16
-	topologyLLDPEnabled   bool
-	topologyCDPEnabled    bool
-	topologyAllowlist     []netip.Prefix
-	portScanEnabled       bool
-	portScanAllowlist     []netip.Prefix
-	portScanPorts         []int
-	portScanWorkers       int
-	portScanTimeout       time.Duration
-	portScanMaxTargets    int
-	metrics               *metrics.Metrics
+	log                      zerolog.Logger
+	q                        Queries
+	pollInterval             time.Duration
+	runDelay                 time.Duration
+	maxRuntime               time.Duration
+	arpTablePath             string
+	maxTargets               int
+	pingTimeout              time.Duration
+	pingWorkers              int
+	enrichMaxTargets         int
+	enrichWorkers            int
+	nameResolutionEnabled    bool
+	snmpEnabled              bool
+	snmpCommunity            string
+	snmpVersion              string
+	snmpTimeout              time.Duration
+	snmpRetries              int
+	snmpPort                 uint16
+	topologyLLDPEnabled      bool
+	topologyCDPEnabled       bool
+	topologyInferenceEnabled bool
+	topologyAllowlist        []netip.Prefix
+	portScanEnabled          bool
+	portScanAllowlist        []netip.Prefix
+	portScanPorts            []int
+	portScanWorkers          int
+	portScanTimeout          time.Duration
+	portScanMaxTargets       int
+	portScanVersionDetection bool
+	udpProbeEnabled          bool
+	udpProbePorts            []int
+	udpProbeTimeout          time.Duration
+	tlsInventoryEnabled      bool
+	tlsTimeout               time.Duration
+	httpFingerprintEnabled   bool
+	httpFingerprintTimeout   time.Duration
+	sshHostKeysEnabled       bool
+	sshTimeout               time.Duration
+	tracerouteEnabled        bool
+	tracerouteMode           string
+	tracerouteMaxHops        int
+	tracerouteTimeout        time.Duration
+	duplicateAnalysisEnabled bool
+	factStaleAfter           time.Duration
+	factDetachAfter          time.Duration
+	availabilityDownAfter    time.Duration
+	macIdentity              identity.Config
+	hostNameLookup           func(ctx context.Context, ip string) []naming.Candidate
+	metrics                  *metrics.Metrics
 }
 
 type Options struct {
-	PollInterval          time.Duration
-	RunDelay              time.Duration
-	MaxRuntime            time.Duration
-	ARPTablePath          string
-	MaxTargets            int
-	PingTimeout           time.Duration
-	PingWorkers           int
-	EnrichMaxTargets      int
-	EnrichWorkers         int
-	NameResolutionEnabled bool
-	SNMPEnabled           bool
-	SNMPCommunity         string
-	SNMPVersion           string
-	SNMPTimeout           time.Duration
-	SNMPRetries           int
-	SNMPPort              uint16
-	TopologyLLDPEnabled   bool
-	TopologyCDPEnabled    bool
-	TopologyAllowlist     []netip.Prefix
-	PortScanEnabled       bool
-	PortScanAllowlist     []netip.Prefix
-	PortScanPorts         []int
-	PortScanWorkers       int
-	PortScanTimeout       time.Duration
-	PortScanMaxTargets    int
+	PollInterval             time.Duration
+	RunDelay                 time.Duration
+	MaxRuntime               time.Duration
+	ARPTablePath             string
+	MaxTargets               int
+	PingTimeout              time.Duration
+	PingWorkers              int
+	EnrichMaxTargets         int
+	EnrichWorkers            int
+	NameResolutionEnabled    bool
+	SNMPEnabled              bool
+	SNMPCommunity            string
+	SNMPVersion              string
+	SNMPTimeout              time.Duration
+	SNMPRetries              int
+	SNMPPort                 uint16
+	TopologyLLDPEnabled      bool
+	TopologyCDPEnabled       bool
+	TopologyInferenceEnabled bool
+	TopologyAllowlist        []netip.Prefix
+	PortScanEnabled          bool
+	PortScanAllowlist        []netip.Prefix
+	PortScanPorts            []int
+	PortScanWorkers          int
+	PortScanTimeout          time.Duration
+	PortScanMaxTargets       int
+	PortScanVersionDetection bool
+	UDPProbeEnabled          bool
+	UDPProbePorts            []int
+	UDPProbeTimeout          time.Duration
+	TLSInventoryEnabled      bool
+	TLSTimeout               time.Duration
+	HTTPFingerprintEnabled   bool
+	HTTPFingerprintTimeout   time.Duration
+	SSHHostKeysEnabled       bool
+	SSHTimeout               time.Duration
+	TracerouteEnabled        bool
+	TracerouteMode           string
+	TracerouteMaxHops        int
+	TracerouteTimeout        time.Duration
+	DuplicateAnalysisEnabled bool
+	// FactStaleAfter and FactDetachAfter age IP/MAC facts that discovery stops seeing; zero disables each step.
+	FactStaleAfter  time.Duration
+	FactDetachAfter time.Duration
+	// AvailabilityDownAfter is how long a device can go unobserved before it is recorded as down; zero disables tracking.
+	AvailabilityDownAfter time.Duration
+	// MACIdentity selects, per address, whether randomized MACs are matched strictly or rotation-aware.
+	MACIdentity identity.Config
 }
 
 func New(log zerolog.Logger, q Queries, opts Options, m *metrics.Metrics) *Worker {
@@ -182,35 +265,89 @@ func New(log zerolog.Logger, q Queries, opts Options, m *metrics.Metrics) *Worke
 		portScanMaxTargets = 24
 	}
 
+	udpProbePorts := opts.UDPProbePorts
+	if len(udpProbePorts) == 0 {
+		udpProbePorts = udpprobe.DefaultPorts()
+	}
+	udpProbeTimeout := opts.UDPProbeTimeout
+	if udpProbeTimeout <= 0 {
+		udpProbeTimeout = time.Second
+	}
+	tlsTimeout := opts.TLSTimeout
+	if tlsTimeout <= 0 {
+		tlsTimeout = 3 * time.Second
+	}
+	httpFingerprintTimeout := opts.HTTPFingerprintTimeout
+	if httpFingerprintTimeout <= 0 {
+		httpFingerprintTimeout = 4 * time.Second
+	}
+	sshTimeout := opts.SSHTimeout
+	if sshTimeout <= 0 {
+		sshTimeout = 5 * time.Second
+	}
+	tracerouteMode := strings.ToLower(strings.TrimSpace(opts.TracerouteMode))
+	if tracerouteMode != traceroute.ModeUDP {
+		tracerouteMode = traceroute.ModeICMP
+	}
+	tracerouteMaxHops := opts.TracerouteMaxHops
+	if tracerouteMaxHops <= 0 {
+		tracerouteMaxHops = 20
+	}
+	tracerouteTimeout := opts.TracerouteTimeout
+	if tracerouteTimeout <= 0 {
+		tracerouteTimeout = time.Second
+	}
+
 	return &Worker{
-		log:                   log,
-		q:                     q,
-		pollInterval:          pi,
-		runDelay:              rd,
-		maxRuntime:            mr,
-		arpTablePath:          arpPath,
-		maxTargets:            maxTargets,
-		pingTimeout:           pingTimeout,
-		pingWorkers:           pingWorkers,
-		enrichMaxTargets:      enrichMaxTargets,
-		enrichWorkers:         enrichWorkers,
-		nameResolutionEnabled: opts.NameResolutionEnabled,
-		snmpEnabled:           opts.SNMPEnabled,
-		snmpCommunity:         snmpCommunity,
-		snmpVersion:           snmpVersion,
-		snmpTimeout:           snmpTimeout,
-		snmpRetries:           snmpRetries,
-		snmpPort:              snmpPort,
-		topologyLLDPEnabled:   opts.TopologyLLDPEnabled,
-		topologyCDPEnabled:    opts.TopologyCDPEnabled,
-		topologyAllowlist:     opts.TopologyAllowlist,
-		portScanEnabled:       opts.PortScanEnabled,
-		portScanAllowlist:     opts.PortScanAllowlist,
-		portScanPorts:         opts.PortScanPorts,
-		portScanWorkers:       portScanWorkers,
-		portScanTimeout:       portScanTimeout,
-		portScanMaxTargets:    portScanMaxTargets,
-		metrics:               m,
+		log:                      log,
+		q:                        q,
+		pollInterval:             pi,
+		runDelay:                 rd,
+		maxRuntime:               mr,
+		arpTablePath:             arpPath,
+		maxTargets:               maxTargets,
+		pingTimeout:              pingTimeout,
+		pingWorkers:              pingWorkers,
+		enrichMaxTargets:         enrichMaxTargets,
+		enrichWorkers:            enrichWorkers,
+		nameResolutionEnabled:    opts.NameResolutionEnabled,
+		snmpEnabled:              opts.SNMPEnabled,
+		snmpCommunity:            snmpCommunity,
+		snmpVersion:              snmpVersion,
+		snmpTimeout:              snmpTimeout,
+		snmpRetries:              snmpRetries,
+		snmpPort:                 snmpPort,
+		topologyLLDPEnabled:      opts.TopologyLLDPEnabled,
+		topologyCDPEnabled:       opts.TopologyCDPEnabled,
+		topologyInferenceEnabled: opts.TopologyInferenceEnabled,
+		topologyAllowlist:        opts.TopologyAllowlist,
+		portScanEnabled:          opts.PortScanEnabled,
+		portScanAllowlist:        opts.PortScanAllowlist,
+		portScanPorts:            opts.PortScanPorts,
+		portScanWorkers:          portScanWorkers,
+		portScanTimeout:          portScanTimeout,
+		portScanMaxTargets:       portScanMaxTargets,
+		portScanVersionDetection: opts.PortScanVersionDetection,
+		udpProbeEnabled:          opts.UDPProbeEnabled,
+		udpProbePorts:            udpProbePorts,
+		udpProbeTimeout:          udpProbeTimeout,
+		tlsInventoryEnabled:      opts.TLSInventoryEnabled,
+		tlsTimeout:               tlsTimeout,
+		httpFingerprintEnabled:   opts.HTTPFingerprintEnabled,
+		httpFingerprintTimeout:   httpFingerprintTimeout,
+		sshHostKeysEnabled:       opts.SSHHostKeysEnabled,
+		sshTimeout:               sshTimeout,
+		tracerouteEnabled:        opts.TracerouteEnabled,
+		tracerouteMode:           tracerouteMode,
+		tracerouteMaxHops:        tracerouteMaxHops,
+		tracerouteTimeout:        tracerouteTimeout,
+		duplicateAnalysisEnabled: opts.DuplicateAnalysisEnabled,
+		factStaleAfter:           opts.FactStaleAfter,
+		factDetachAfter:          opts.FactDetachAfter,
+		availabilityDownAfter:    opts.AvailabilityDownAfter,
+		macIdentity:              opts.MACIdentity,
+		hostNameLookup:           lookupHostNames,
+		metrics:                  m,
 	}
 }
 
@@ -441,7 +578,16 @@ func (w *Worker) runOnce(ctx context.Context) (bool, error) {
 		})
 	}
 
-	enrichmentStats := w.runEnrichment(execCtx, result.Targets)
+	tracerouteStats := w.runTraceroute(execCtx, run.ID, scopePrefix)
+	if msg := w.tracerouteLogMessage(tracerouteStats); msg != "" {
+		_ = w.q.InsertDiscoveryRunLog(execCtx, sqlcgen.InsertDiscoveryRunLogParams{
+			RunID:   run.ID,
+			Level:   "info",
+			Message: msg,
+		})
+	}
+
+	enrichmentStats := w.runEnrichment(execCtx, run.ID, result.Targets)
 	if enrichmentStats != nil {
 		_ = w.q.InsertDiscoveryRunLog(execCtx, sqlcgen.InsertDiscoveryRunLogParams{
 			RunID:   run.ID,
@@ -459,21 +605,115 @@ func (w *Worker) runOnce(ctx context.Context) (bool, error) {
 		})
 	}
 
+	udpProbeStats := w.runUDPProbe(execCtx, result.Targets)
+	if msg := w.udpProbeLogMessage(udpProbeStats); msg != "" {
+		_ = w.q.InsertDiscoveryRunLog(execCtx, sqlcgen.InsertDiscoveryRunLogParams{
+			RunID:   run.ID,
+			Level:   "info",
+			Message: msg,
+		})
+	}
+
+	tlsStats := w.runTLSInventory(execCtx, result.Targets)
+	if msg := w.tlsInventoryLogMessage(tlsStats); msg != "" {
+		_ = w.q.InsertDiscoveryRunLog(execCtx, sqlcgen.InsertDiscoveryRunLogParams{
+			RunID:   run.ID,
+			Level:   "info",
+			Message: msg,
+		})
+	}
+
+	httpStats := w.runHTTPFingerprint(execCtx, result.Targets)
+	if msg := w.httpFingerprintLogMessage(httpStats); msg != "" {
+		_ = w.q.InsertDiscoveryRunLog(execCtx, sqlcgen.InsertDiscoveryRunLogParams{
+			RunID:   run.ID,
+			Level:   "info",
+			Message: msg,
+		})
+	}
+
+	sshStats := w.runSSHHostKeys(execCtx, result.Targets)
+	if msg := w.sshHostKeysLogMessage(sshStats); msg != "" {
+		_ = w.q.InsertDiscoveryRunLog(execCtx, sqlcgen.InsertDiscoveryRunLogParams{
+			RunID:   run.ID,
+			Level:   "info",
+			Message: msg,
+		})
+	}
+
+	agingStats := w.runFactAging(execCtx, run.ID, time.Now())
+	if msg := w.factAgingLogMessage(agingStats); msg != "" {
+		level := "info"
+		if _, failed := agingStats["error"]; failed {
+			level = "error"
+		}
+		_ = w.q.InsertDiscoveryRunLog(execCtx, sqlcgen.InsertDiscoveryRunLogParams{
+			RunID:   run.ID,
+			Level:   level,
+			Message: msg,
+		})
+	}
+
+	availabilityStats := w.runAvailability(execCtx, run.ID, scopePrefix, time.Now())
+	if msg := w.availabilityLogMessage(availabilityStats); msg != "" {
+		level := "info"
+		if _, failed := availabilityStats["error"]; failed {
+			level = "error"
+		}
+		_ = w.q.InsertDiscoveryRunLog(execCtx, sqlcgen.InsertDiscoveryRunLogParams{
+			RunID:   run.ID,
+			Level:   level,
+			Message: msg,
+		})
+	}
+
+	snapshotStats := w.runStateSnapshots(execCtx, run.ID)
+	if msg := w.stateSnapshotsLogMessage(snapshotStats); msg != "" {
+		level := "info"
+		if _, failed := snapshotStats["error"]; failed {
+			level = "error"
+		}
+		_ = w.q.InsertDiscoveryRunLog(execCtx, sqlcgen.InsertDiscoveryRunLogParams{
+			RunID:   run.ID,
+			Level:   level,
+			Message: msg,
+		})
+	}
+
+	duplicateStats := w.runDuplicateAnalysis(execCtx)
+	if msg := w.duplicateAnalysisLogMessage(duplicateStats); msg != "" {
+		level := "info"
+		if _, failed := duplicateStats["error"]; failed {
+			level = "error"
+		}
+		_ = w.q.InsertDiscoveryRunLog(execCtx, sqlcgen.InsertDiscoveryRunLogParams{
+			RunID:   run.ID,
+			Level:   level,
+			Message: msg,
+		})
+	}
+
 	completedAt := time.Now()
 	stats := map[string]any{
-		"stage":             "completed",
-		"preset":            preset,
-		"method":            discoveryMethod(ping),
-		"scope":             scopePrefixOrNil(scopePrefix),
-		"scope_targets":     scopeTargets,
-		"max_targets":       w.maxTargets,
-		"runtime_budget_ms": int(w.maxRuntime.Milliseconds()),
-		"ping_available":    ping.Available,
-		"ping_attempted":    ping.Attempted,
-		"ping_succeeded":    ping.Succeeded,
-		"arp_entries":       result.ARPEntries,
-		"devices_seen":      result.DevicesSeen,
-		"devices_created":   result.DevicesCreated,
+		"stage":              "completed",
+		"preset":             preset,
+		"method":             discoveryMethod(ping),
+		"scope":              scopePrefixOrNil(scopePrefix),
+		"scope_targets":      scopeTargets,
+		"max_targets":        w.maxTargets,
+		"runtime_budget_ms":  int(w.maxRuntime.Milliseconds()),
+		"ping_available":     ping.Available,
+		"ping_attempted":     ping.Attempted,
+		"ping_succeeded":     ping.Succeeded,
+		"arp_entries":        result.ARPEntries,
+		"devices_seen":       result.DevicesSeen,
+		"devices_created":    result.DevicesCreated,
+		"randomized_macs":    result.RandomizedMACs,
+		"rotation_matches":   result.RotationMatches,
+		"devices_unarchived": result.DevicesUnarchived,
+	}
+	if tracerouteStats != nil {
+		stats["traceroute"] = tracerouteStats
 	}
 	if enrichmentStats != nil {
 		stats["enrichment"] = enrichmentStats
@@ -481,6 +721,28 @@ func (w *Worker) runOnce(ctx context.Context) (bool, error) {
 	if portScanStats != nil {
 		stats["port_scan"] = portScanStats
 	}
+	if udpProbeStats != nil {
+		stats["udp_probe"] = udpProbeStats
+	}
+	if tlsStats != nil {
+		stats["tls"] = tlsStats
+	}
+	if httpStats != nil {
+		stats["http_fingerprint"] = httpStats
+	}
+	if sshStats != nil {
+		stats["ssh_host_keys"] = sshStats
+	}
+	if agingStats != nil {
+		stats["fact_aging"] = agingStats
+	}
+	if availabilityStats != nil {
+		stats["availability"] = availabilityStats
+	}
+	stats["state_snapshots"] = snapshotStats
+	if duplicateStats != nil {
+		stats["duplicates"] = duplicateStats
+	}
 	if len(tags) > 0 {
 		stats["tags"] = tags
 	}
@@ -558,7 +820,13 @@ type arpScrapeResult struct {
 	ARPEntries     int
 	DevicesSeen    int
 	DevicesCreated int
-	Targets        []enrichmentTarget
+	// RandomizedMACs counts entries whose MAC was handled as rotating; RotationMatches counts those
+	// matched to an existing device by something other than the MAC itself.
+	RandomizedMACs  int
+	RotationMatches int
+	// DevicesUnarchived counts archived devices seen again (and reactivated) by this scrape.
+	DevicesUnarchived int
+	Targets           []enrichmentTarget
 }
 
 type pingSweepResult struct {
@@ -696,6 +964,59 @@ func parseProcNetARP(content string) ([]arpEntry, error) {
 	return out, nil
 }
 
+// deviceResolver is the subset of queries used to map an observed MAC/IP pair onto a device.
+type deviceResolver interface {
+	CreateDevice(ctx context.Context, displayName *string) (sqlcgen.Device, error)
+	FindDeviceIDByMAC(ctx context.Context, mac string) (string, error)
+	FindDeviceIDByIP(ctx context.Context, ip string) (string, error)
+}
+
+// resolveDevice matches by MAC first, then IP, and creates a new device when neither is known.
+// An empty mac skips the MAC lookup (e.g. routed scan results); an invalid ip skips the IP lookup.
+func resolveDevice(ctx context.Context, q deviceResolver, mac string, ip netip.Addr) (string, bool, error) {
+	m, err := matchDevice(ctx, q, mac, ip)
+	if err != nil {
+		return "", false, err
+	}
+	return m.ID, m.Created, nil
+}
+
+// matchDevice is resolveDevice reporting which identifier matched.
+func matchDevice(ctx context.Context, q deviceResolver, mac string, ip netip.Addr) (deviceMatch, error) {
+	if mac != "" {
+		id, err := optionalDeviceID(q.FindDeviceIDByMAC(ctx, mac))
+		if err != nil || id != "" {
+			return deviceMatch{ID: id, By: matchedByMAC}, err
+		}
+	}
+	if ip.IsValid() {
+		id, err := optionalDeviceID(q.FindDeviceIDByIP(ctx, ip.String()))
+		if err != nil || id != "" {
+			return deviceMatch{ID: id, By: matchedByIP}, err
+		}
+	}
+	return createDevice(ctx, q)
+}
+
+func createDevice(ctx context.Context, q deviceResolver) (deviceMatch, error) {
+	row, err := q.CreateDevice(ctx, nil)
+	if err != nil {
+		return deviceMatch{}, err
+	}
+	return deviceMatch{ID: row.ID, Created: true}, nil
+}
+
+// optionalDeviceID turns a lookup's pgx.ErrNoRows into an empty ID.
+func optionalDeviceID(id string, err error) (string, error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
 func (w *Worker) scrapeARP(ctx context.Context, runID string, scope *netip.Prefix) (arpScrapeResult, error) {
 	if w == nil {
 		return arpScrapeResult{}, nil
@@ -716,6 +1037,7 @@ func (w *Worker) scrapeARP(ctx context.Context, runID string, scope *netip.Prefi
 
 	var result arpScrapeResult
 	seenTargets := make(map[string]struct{})
+	now := time.Now()
 
 	for _, e := range entries {
 		if scope != nil && !scope.Contains(e.IP) {
@@ -723,23 +1045,33 @@ func (w *Worker) scrapeARP(ctx context.Context, runID string, scope *netip.Prefi
 		}
 		result.ARPEntries++
 
-		deviceID, err := w.q.FindDeviceIDByMAC(ctx, e.MAC)
-		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		obs := deviceObservation{MAC: e.MAC, IP: e.IP}
+		if w.nameResolutionEnabled && w.hostNameLookup != nil {
+			ip := e.IP.String()
+			obs.LookupNames = func(ctx context.Context) []naming.Candidate {
+				return w.hostNameLookup(ctx, ip)
+			}
+		}
+		match, err := resolveObservedDevice(ctx, w.q, w.macIdentity, now, obs)
+		if err != nil {
 			return result, err
 		}
-		if errors.Is(err, pgx.ErrNoRows) {
-			deviceID, err = w.q.FindDeviceIDByIP(ctx, e.IP.String())
-			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-				return result, err
-			}
+		deviceID := match.ID
+		if match.Created {
+			result.DevicesCreated++
 		}
-		if deviceID == "" {
-			row, err := w.q.CreateDevice(ctx, nil)
-			if err != nil {
-				return result, err
+		if match.Rotating {
+			result.RandomizedMACs++
+			if match.By != "" && match.By != matchedByMAC {
+				result.RotationMatches++
 			}
-			deviceID = row.ID
-			result.DevicesCreated++
+		}
+		unarchived, err := unarchiveReseen(ctx, w.q, match, runID)
+		if err != nil {
+			return result, err
+		}
+		if unarchived {
+			result.DevicesUnarchived++
 		}
 
 		if err := w.q.UpsertDeviceMAC(ctx, sqlcgen.UpsertDeviceMACParams{
@@ -24,6 +24,11 @@ type fakeQueries struct {
 	createFn              func(ctx context.Context, displayName *string) (sqlcgen.Device, error)
 	findByMacFn           func(ctx context.Context, mac string) (string, error)
 	findByIPFn            func(ctx context.Context, ip string) (string, error)
+	findByClientIDFn      func(ctx context.Context, clientID string) (string, error)
+	findByHostNameFn      func(ctx context.Context, name string) (string, error)
+	findRotatingByIPFn    func(ctx context.Context, arg sqlcgen.FindRotatingDeviceIDByIPParams) (string, error)
+	upsertClientIDFn      func(ctx context.Context, arg sqlcgen.UpsertDeviceClientIDParams) error
+	unarchiveFn           func(ctx context.Context, arg sqlcgen.UnarchiveReseenDeviceParams) (int64, error)
 	upsertIPFn            func(ctx context.Context, arg sqlcgen.UpsertDeviceIPParams) error
 	upsertMACFn           func(ctx context.Context, arg sqlcgen.UpsertDeviceMACParams) error
 	insertIPObs           func(ctx context.Context, arg sqlcgen.InsertIPObservationParams) error
@@ -39,7 +44,35 @@ type fakeQueries struct {
 	linkMacFn             func(ctx context.Context, arg sqlcgen.LinkDeviceMACToInterfaceParams) (int64, error)
 	upsertVlanFn          func(ctx context.Context, arg sqlcgen.UpsertInterfaceVLANParams) error
 	upsertLinkFn          func(ctx context.Context, arg sqlcgen.UpsertLinkParams) error
+	upsertInferredLinkFn  func(ctx context.Context, arg sqlcgen.UpsertInferredLinkParams) (int64, error)
+	deleteStaleLinksFn    func(ctx context.Context, arg sqlcgen.DeleteStaleInferredLinksParams) (int64, error)
+	upsertSTPBridgeFn     func(ctx context.Context, arg sqlcgen.UpsertSTPBridgeParams) error
+	upsertSTPPortFn       func(ctx context.Context, arg sqlcgen.UpsertSTPPortParams) error
+	syncAggregatesFn      func(ctx context.Context, arg sqlcgen.SyncInterfaceAggregatesParams) (int64, error)
+	deviceHasTagFn        func(ctx context.Context, deviceID string, tag string) (bool, error)
+	upsertAdjacencyFn     func(ctx context.Context, arg sqlcgen.UpsertRoutingAdjacencyParams) (int64, error)
+	markAdjacenciesDownFn func(ctx context.Context, arg sqlcgen.MarkStaleRoutingAdjacenciesDownParams) (int64, error)
+	upsertPoEFn           func(ctx context.Context, arg sqlcgen.UpsertInterfacePoEParams) error
+	syncPoweredFn         func(ctx context.Context, deviceIDs []string) (int64, error)
+	listProfilesFn        func(ctx context.Context, deviceID string) ([]sqlcgen.SNMPProfile, error)
+	upsertCustomFactFn    func(ctx context.Context, arg sqlcgen.UpsertDeviceCustomFactParams) (int64, error)
+	deleteStaleCustomFn   func(ctx context.Context, arg sqlcgen.DeleteStaleDeviceCustomFactsParams) (int64, error)
 	upsertServiceFn       func(ctx context.Context, arg sqlcgen.UpsertServiceFromScanParams) error
+	markServiceNotOpenFn  func(ctx context.Context, arg sqlcgen.MarkServiceNotOpenParams) (int64, error)
+	listOpenTCPFn         func(ctx context.Context, deviceID string, limit int32) ([]sqlcgen.OpenTCPService, error)
+	upsertCertificateFn   func(ctx context.Context, arg sqlcgen.UpsertServiceCertificateParams) error
+	updateHTTPFn          func(ctx context.Context, arg sqlcgen.UpdateServiceHTTPFingerprintParams) error
+	upsertOSGuessFn       func(ctx context.Context, arg sqlcgen.UpsertDeviceOSGuessParams) error
+	upsertSSHHostKeyFn    func(ctx context.Context, arg sqlcgen.UpsertSSHHostKeyParams) (sqlcgen.UpsertSSHHostKeyRow, error)
+	insertTracerouteFn    func(ctx context.Context, arg sqlcgen.InsertTraceroutePathParams) error
+	listDupMatchesFn      func(ctx context.Context, limit int32) ([]sqlcgen.DuplicateMatch, error)
+	replaceDupFn          func(ctx context.Context, arg sqlcgen.ReplaceDeviceDuplicateCandidatesParams) (int64, error)
+	markStaleIPsFn        func(ctx context.Context, before time.Time) (int64, error)
+	markStaleMACsFn       func(ctx context.Context, before time.Time) (int64, error)
+	detachIPsFn           func(ctx context.Context, arg sqlcgen.DetachStaleFactsParams) (int64, error)
+	detachMACsFn          func(ctx context.Context, arg sqlcgen.DetachStaleFactsParams) (int64, error)
+	recordAvailabilityFn  func(ctx context.Context, arg sqlcgen.RecordDeviceAvailabilityParams) ([]sqlcgen.DeviceAvailabilityChange, error)
+	recordSnapshotsFn     func(ctx context.Context, arg sqlcgen.RecordDeviceStateSnapshotsParams) (int64, error)
 }
 
 func (f *fakeQueries) ClaimNextDiscoveryRun(ctx context.Context, stats map[string]any) (sqlcgen.DiscoveryRun, error) {
@@ -75,6 +108,41 @@ func (f *fakeQueries) FindDeviceIDByIP(ctx context.Context, ip string) (string,
 	return f.findByIPFn(ctx, ip)
 }
 
+func (f *fakeQueries) FindDeviceIDByClientID(ctx context.Context, clientID string) (string, error) {
+	if f.findByClientIDFn == nil {
+		return "", pgx.ErrNoRows
+	}
+	return f.findByClientIDFn(ctx, clientID)
+}
+
+func (f *fakeQueries) FindDeviceIDByHostName(ctx context.Context, name string) (string, error) {
+	if f.findByHostNameFn == nil {
+		return "", pgx.ErrNoRows
+	}
+	return f.findByHostNameFn(ctx, name)
+}
+
+func (f *fakeQueries) FindRotatingDeviceIDByIP(ctx context.Context, arg sqlcgen.FindRotatingDeviceIDByIPParams) (string, error) {
+	if f.findRotatingByIPFn == nil {
+		return "", pgx.ErrNoRows
+	}
+	return f.findRotatingByIPFn(ctx, arg)
+}
+
+func (f *fakeQueries) UpsertDeviceClientID(ctx context.Context, arg sqlcgen.UpsertDeviceClientIDParams) error {
+	if f.upsertClientIDFn == nil {
+		return nil
+	}
+	return f.upsertClientIDFn(ctx, arg)
+}
+
+func (f *fakeQueries) UnarchiveReseenDevice(ctx context.Context, arg sqlcgen.UnarchiveReseenDeviceParams) (int64, error) {
+	if f.unarchiveFn == nil {
+		return 0, nil
+	}
+	return f.unarchiveFn(ctx, arg)
+}
+
 func (f *fakeQueries) UpsertDeviceIP(ctx context.Context, arg sqlcgen.UpsertDeviceIPParams) error {
 	if f.upsertIPFn == nil {
 		return nil
@@ -180,6 +248,105 @@ func (f *fakeQueries) UpsertLink(ctx context.Context, arg sqlcgen.UpsertLinkPara
 	return f.upsertLinkFn(ctx, arg)
 }
 
+func (f *fakeQueries) UpsertInferredLink(ctx context.Context, arg sqlcgen.UpsertInferredLinkParams) (int64, error) {
+	if f.upsertInferredLinkFn == nil {
+		return 1, nil
+	}
+	return f.upsertInferredLinkFn(ctx, arg)
+}
+
+func (f *fakeQueries) DeleteStaleInferredLinks(ctx context.Context, arg sqlcgen.DeleteStaleInferredLinksParams) (int64, error) {
+	if f.deleteStaleLinksFn == nil {
+		return 0, nil
+	}
+	return f.deleteStaleLinksFn(ctx, arg)
+}
+
+func (f *fakeQueries) UpsertSTPBridge(ctx context.Context, arg sqlcgen.UpsertSTPBridgeParams) error {
+	if f.upsertSTPBridgeFn == nil {
+		return nil
+	}
+	return f.upsertSTPBridgeFn(ctx, arg)
+}
+
+func (f *fakeQueries) UpsertSTPPort(ctx context.Context, arg sqlcgen.UpsertSTPPortParams) error {
+	if f.upsertSTPPortFn == nil {
+		return nil
+	}
+	return f.upsertSTPPortFn(ctx, arg)
+}
+
+func (f *fakeQueries) DeleteStaleSTPPorts(ctx context.Context, arg sqlcgen.DeleteStaleSTPPortsParams) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeQueries) SyncInterfaceAggregates(ctx context.Context, arg sqlcgen.SyncInterfaceAggregatesParams) (int64, error) {
+	if f.syncAggregatesFn == nil {
+		return 0, nil
+	}
+	return f.syncAggregatesFn(ctx, arg)
+}
+
+func (f *fakeQueries) DeviceHasTag(ctx context.Context, deviceID string, tag string) (bool, error) {
+	if f.deviceHasTagFn == nil {
+		return false, nil
+	}
+	return f.deviceHasTagFn(ctx, deviceID, tag)
+}
+
+func (f *fakeQueries) UpsertRoutingAdjacency(ctx context.Context, arg sqlcgen.UpsertRoutingAdjacencyParams) (int64, error) {
+	if f.upsertAdjacencyFn == nil {
+		return 0, nil
+	}
+	return f.upsertAdjacencyFn(ctx, arg)
+}
+
+func (f *fakeQueries) MarkStaleRoutingAdjacenciesDown(ctx context.Context, arg sqlcgen.MarkStaleRoutingAdjacenciesDownParams) (int64, error) {
+	if f.markAdjacenciesDownFn == nil {
+		return 0, nil
+	}
+	return f.markAdjacenciesDownFn(ctx, arg)
+}
+
+func (f *fakeQueries) UpsertInterfacePoE(ctx context.Context, arg sqlcgen.UpsertInterfacePoEParams) error {
+	if f.upsertPoEFn == nil {
+		return nil
+	}
+	return f.upsertPoEFn(ctx, arg)
+}
+
+func (f *fakeQueries) DeleteStaleInterfacePoE(ctx context.Context, arg sqlcgen.DeleteStaleInterfacePoEParams) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeQueries) SyncPoEPoweredDevices(ctx context.Context, deviceIDs []string) (int64, error) {
+	if f.syncPoweredFn == nil {
+		return 0, nil
+	}
+	return f.syncPoweredFn(ctx, deviceIDs)
+}
+
+func (f *fakeQueries) ListSNMPProfilesForDevice(ctx context.Context, deviceID string) ([]sqlcgen.SNMPProfile, error) {
+	if f.listProfilesFn == nil {
+		return nil, nil
+	}
+	return f.listProfilesFn(ctx, deviceID)
+}
+
+func (f *fakeQueries) UpsertDeviceCustomFact(ctx context.Context, arg sqlcgen.UpsertDeviceCustomFactParams) (int64, error) {
+	if f.upsertCustomFactFn == nil {
+		return 0, nil
+	}
+	return f.upsertCustomFactFn(ctx, arg)
+}
+
+func (f *fakeQueries) DeleteStaleDeviceCustomFacts(ctx context.Context, arg sqlcgen.DeleteStaleDeviceCustomFactsParams) (int64, error) {
+	if f.deleteStaleCustomFn == nil {
+		return 0, nil
+	}
+	return f.deleteStaleCustomFn(ctx, arg)
+}
+
 func (f *fakeQueries) UpsertServiceFromScan(ctx context.Context, arg sqlcgen.UpsertServiceFromScanParams) error {
 	if f.upsertServiceFn == nil {
 		return nil
@@ -187,6 +354,115 @@ func (f *fakeQueries) UpsertServiceFromScan(ctx context.Context, arg sqlcgen.Ups
 	return f.upsertServiceFn(ctx, arg)
 }
 
+func (f *fakeQueries) MarkServiceNotOpen(ctx context.Context, arg sqlcgen.MarkServiceNotOpenParams) (int64, error) {
+	if f.markServiceNotOpenFn == nil {
+		return 0, nil
+	}
+	return f.markServiceNotOpenFn(ctx, arg)
+}
+
+func (f *fakeQueries) ListOpenTCPServicesForDevice(ctx context.Context, deviceID string, limit int32) ([]sqlcgen.OpenTCPService, error) {
+	if f.listOpenTCPFn == nil {
+		return nil, nil
+	}
+	return f.listOpenTCPFn(ctx, deviceID, limit)
+}
+
+func (f *fakeQueries) UpsertServiceCertificate(ctx context.Context, arg sqlcgen.UpsertServiceCertificateParams) error {
+	if f.upsertCertificateFn == nil {
+		return nil
+	}
+	return f.upsertCertificateFn(ctx, arg)
+}
+
+func (f *fakeQueries) UpdateServiceHTTPFingerprint(ctx context.Context, arg sqlcgen.UpdateServiceHTTPFingerprintParams) error {
+	if f.updateHTTPFn == nil {
+		return nil
+	}
+	return f.updateHTTPFn(ctx, arg)
+}
+
+func (f *fakeQueries) UpsertSSHHostKey(ctx context.Context, arg sqlcgen.UpsertSSHHostKeyParams) (sqlcgen.UpsertSSHHostKeyRow, error) {
+	if f.upsertSSHHostKeyFn == nil {
+		return sqlcgen.UpsertSSHHostKeyRow{}, nil
+	}
+	return f.upsertSSHHostKeyFn(ctx, arg)
+}
+
+func (f *fakeQueries) UpsertDeviceOSGuess(ctx context.Context, arg sqlcgen.UpsertDeviceOSGuessParams) error {
+	if f.upsertOSGuessFn == nil {
+		return nil
+	}
+	return f.upsertOSGuessFn(ctx, arg)
+}
+
+func (f *fakeQueries) DeleteDeviceOSGuessesAboveRank(ctx context.Context, arg sqlcgen.DeleteDeviceOSGuessesAboveRankParams) error {
+	return nil
+}
+
+func (f *fakeQueries) InsertTraceroutePath(ctx context.Context, arg sqlcgen.InsertTraceroutePathParams) error {
+	if f.insertTracerouteFn == nil {
+		return nil
+	}
+	return f.insertTracerouteFn(ctx, arg)
+}
+
+func (f *fakeQueries) ListDuplicateMatches(ctx context.Context, limit int32) ([]sqlcgen.DuplicateMatch, error) {
+	if f.listDupMatchesFn == nil {
+		return nil, nil
+	}
+	return f.listDupMatchesFn(ctx, limit)
+}
+
+func (f *fakeQueries) ReplaceDeviceDuplicateCandidates(ctx context.Context, arg sqlcgen.ReplaceDeviceDuplicateCandidatesParams) (int64, error) {
+	if f.replaceDupFn == nil {
+		return int64(len(arg.DeviceAIDs)), nil
+	}
+	return f.replaceDupFn(ctx, arg)
+}
+
+func (f *fakeQueries) MarkStaleIPAddresses(ctx context.Context, before time.Time) (int64, error) {
+	if f.markStaleIPsFn == nil {
+		return 0, nil
+	}
+	return f.markStaleIPsFn(ctx, before)
+}
+
+func (f *fakeQueries) MarkStaleMACAddresses(ctx context.Context, before time.Time) (int64, error) {
+	if f.markStaleMACsFn == nil {
+		return 0, nil
+	}
+	return f.markStaleMACsFn(ctx, before)
+}
+
+func (f *fakeQueries) DetachStaleIPAddresses(ctx context.Context, arg sqlcgen.DetachStaleFactsParams) (int64, error) {
+	if f.detachIPsFn == nil {
+		return 0, nil
+	}
+	return f.detachIPsFn(ctx, arg)
+}
+
+func (f *fakeQueries) DetachStaleMACAddresses(ctx context.Context, arg sqlcgen.DetachStaleFactsParams) (int64, error) {
+	if f.detachMACsFn == nil {
+		return 0, nil
+	}
+	return f.detachMACsFn(ctx, arg)
+}
+
+func (f *fakeQueries) RecordDeviceAvailability(ctx context.Context, arg sqlcgen.RecordDeviceAvailabilityParams) ([]sqlcgen.DeviceAvailabilityChange, error) {
+	if f.recordAvailabilityFn == nil {
+		return nil, nil
+	}
+	return f.recordAvailabilityFn(ctx, arg)
+}
+
+func (f *fakeQueries) RecordDeviceStateSnapshots(ctx context.Context, arg sqlcgen.RecordDeviceStateSnapshotsParams) (int64, error) {
+	if f.recordSnapshotsFn == nil {
+		return 0, nil
+	}
+	return f.recordSnapshotsFn(ctx, arg)
+}
+
 func writeTempARPFile(t *testing.T, content string) string {
 	t.Helper()
 	f, err := os.CreateTemp("", "arp-*.txt")
@@ -472,6 +748,41 @@ func TestWorker_RunOnce_ARPEntryReusesDeviceByIP(t *testing.T) {
 	}
 }
 
+func TestWorker_RunOnce_ARPEntryUnarchivesReseenDevice(t *testing.T) {
+	arpPath := writeTempARPFile(t, "IP address       HW type     Flags       HW address            Mask     Device\n10.0.0.7          0x1         0x2         aa:bb:cc:dd:ee:22     *        eth0\n")
+
+	var unarchived []sqlcgen.UnarchiveReseenDeviceParams
+	var stats map[string]any
+	q := &fakeQueries{
+		claimFn: func(ctx context.Context, stats map[string]any) (sqlcgen.DiscoveryRun, error) {
+			return sqlcgen.DiscoveryRun{ID: "run-arp", Status: "running"}, nil
+		},
+		updateFn: func(ctx context.Context, arg sqlcgen.UpdateDiscoveryRunParams) (sqlcgen.DiscoveryRun, error) {
+			stats = arg.Stats
+			return sqlcgen.DiscoveryRun{ID: arg.ID, Status: arg.Status}, nil
+		},
+		insertFn: func(ctx context.Context, arg sqlcgen.InsertDiscoveryRunLogParams) error { return nil },
+		findByMacFn: func(ctx context.Context, mac string) (string, error) {
+			return "dev-archived", nil
+		},
+		unarchiveFn: func(ctx context.Context, arg sqlcgen.UnarchiveReseenDeviceParams) (int64, error) {
+			unarchived = append(unarchived, arg)
+			return 1, nil
+		},
+	}
+
+	w := New(zerolog.Nop(), q, Options{ARPTablePath: arpPath}, nil)
+	if _, err := w.runOnce(context.Background()); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(unarchived) != 1 || unarchived[0].DeviceID != "dev-archived" || unarchived[0].RunID == nil || *unarchived[0].RunID != "run-arp" {
+		t.Fatalf("expected the re-seen device to be unarchived once, got %#v", unarchived)
+	}
+	if stats["devices_unarchived"] != 1 {
+		t.Fatalf("expected devices_unarchived=1 in run stats, got %v", stats["devices_unarchived"])
+	}
+}
+
 func TestWorker_PingSweep_ErrorsWhenPingNotFound(t *testing.T) {
 	t.Setenv("PATH", t.TempDir())
 	w := New(zerolog.Nop(), nil, Options{MaxTargets: 8, PingTimeout: 100 * time.Millisecond}, nil)
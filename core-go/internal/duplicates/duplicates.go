@@ -0,0 +1,139 @@
+package duplicates
+
+import (
+	"sort"
+	"strings"
+)
+
+// Signals that suggest two device rows describe the same box.
+const (
+	SignalSerial        = "serial"
+	SignalSSHHostKey    = "ssh_host_key"
+	SignalMAC           = "mac"
+	SignalInterfaceMAC  = "interface_mac"
+	SignalSysName       = "sys_name"
+	SignalNameCandidate = "name_candidate"
+	SignalIPHandoff     = "ip_handoff"
+)
+
+// weights are the score each signal contributes once per pair, however many values match.
+var weights = map[string]int{
+	SignalSerial:        60,
+	SignalSSHHostKey:    50,
+	SignalMAC:           50,
+	SignalInterfaceMAC:  50,
+	SignalSysName:       40,
+	SignalNameCandidate: 25,
+	SignalIPHandoff:     20,
+}
+
+// MinScore is the lowest score that is suggested as a duplicate.
+const MinScore = 40
+
+// maxEvidenceValues caps the values kept per signal so one noisy signal cannot bloat a suggestion.
+const maxEvidenceValues = 5
+
+// Match is one shared value between two devices, as found by the signal queries.
+type Match struct {
+	DeviceA string
+	DeviceB string
+	Signal  string
+	Value   string
+}
+
+// Evidence groups the values of one signal for a pair.
+type Evidence struct {
+	Signal string   `json:"signal"`
+	Weight int      `json:"weight"`
+	Values []string `json:"values"`
+}
+
+// Candidate is a scored device pair; DeviceA sorts before DeviceB.
+type Candidate struct {
+	DeviceA  string
+	DeviceB  string
+	Score    int
+	Evidence []Evidence
+}
+
+// Weight returns the score a signal contributes, or 0 for unknown signals.
+func Weight(signal string) int {
+	return weights[signal]
+}
+
+// PairKey orders two device IDs the way candidates and dismissals store them.
+func PairKey(a, b string) (string, string) {
+	a = strings.ToLower(strings.TrimSpace(a))
+	b = strings.ToLower(strings.TrimSpace(b))
+	if b < a {
+		return b, a
+	}
+	return a, b
+}
+
+// Score groups matches by device pair and keeps pairs scoring at least MinScore, best first.
+func Score(matches []Match) []Candidate {
+	type pair struct{ a, b string }
+	bySignal := map[pair]map[string][]string{}
+	for _, m := range matches {
+		if Weight(m.Signal) == 0 {
+			continue
+		}
+		a, b := PairKey(m.DeviceA, m.DeviceB)
+		if a == "" || b == "" || a == b {
+			continue
+		}
+		p := pair{a, b}
+		if bySignal[p] == nil {
+			bySignal[p] = map[string][]string{}
+		}
+		values := bySignal[p][m.Signal]
+		value := strings.TrimSpace(m.Value)
+		if len(values) < maxEvidenceValues && !contains(values, value) {
+			values = append(values, value)
+		}
+		bySignal[p][m.Signal] = values
+	}
+
+	out := make([]Candidate, 0, len(bySignal))
+	for p, signals := range bySignal {
+		c := Candidate{DeviceA: p.a, DeviceB: p.b}
+		for signal, values := range signals {
+			sort.Strings(values)
+			c.Score += Weight(signal)
+			c.Evidence = append(c.Evidence, Evidence{Signal: signal, Weight: Weight(signal), Values: values})
+		}
+		if c.Score > 100 {
+			c.Score = 100
+		}
+		if c.Score < MinScore {
+			continue
+		}
+		sort.Slice(c.Evidence, func(i, j int) bool {
+			if c.Evidence[i].Weight != c.Evidence[j].Weight {
+				return c.Evidence[i].Weight > c.Evidence[j].Weight
+			}
+			return c.Evidence[i].Signal < c.Evidence[j].Signal
+		})
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		if out[i].DeviceA != out[j].DeviceA {
+			return out[i].DeviceA < out[j].DeviceA
+		}
+		return out[i].DeviceB < out[j].DeviceB
+	})
+	return out
+}
+
+func contains(values []string, v string) bool {
+	for _, existing := range values {
+		if existing == v {
+			return true
+		}
+	}
+	return false
+}
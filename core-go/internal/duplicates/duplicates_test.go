@@ -0,0 +1,79 @@
+package duplicates
+
+import "testing"
+
+func TestScore(t *testing.T) {
+	const (
+		a = "00000000-0000-0000-0000-00000000000a"
+		b = "00000000-0000-0000-0000-00000000000b"
+		c = "00000000-0000-0000-0000-00000000000c"
+	)
+	cases := []struct {
+		name      string
+		matches   []Match
+		wantPairs int
+		wantScore int
+		wantFirst string
+	}{
+		{
+			name: "shared mac and sysName",
+			matches: []Match{
+				{DeviceA: b, DeviceB: a, Signal: SignalMAC, Value: "00:11:22:33:44:55"},
+				{DeviceA: a, DeviceB: b, Signal: SignalMAC, Value: "00:11:22:33:44:55"},
+				{DeviceA: a, DeviceB: b, Signal: SignalSysName, Value: "core-sw-1"},
+			},
+			wantPairs: 1,
+			wantScore: 90,
+			wantFirst: SignalMAC,
+		},
+		{
+			name:      "weak signal alone is not suggested",
+			matches:   []Match{{DeviceA: a, DeviceB: c, Signal: SignalIPHandoff, Value: "192.0.2.10"}},
+			wantPairs: 0,
+		},
+		{
+			name: "score is capped at 100",
+			matches: []Match{
+				{DeviceA: a, DeviceB: b, Signal: SignalSerial, Value: "FOC1234"},
+				{DeviceA: a, DeviceB: b, Signal: SignalSSHHostKey, Value: "SHA256:abc"},
+			},
+			wantPairs: 1,
+			wantScore: 100,
+			wantFirst: SignalSerial,
+		},
+		{
+			name: "self pairs and unknown signals are ignored",
+			matches: []Match{
+				{DeviceA: a, DeviceB: a, Signal: SignalMAC, Value: "00:11:22:33:44:55"},
+				{DeviceA: a, DeviceB: b, Signal: "vibes", Value: "x"},
+			},
+			wantPairs: 0,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Score(tc.matches)
+			if len(got) != tc.wantPairs {
+				t.Fatalf("expected %d pairs, got %+v", tc.wantPairs, got)
+			}
+			if tc.wantPairs == 0 {
+				return
+			}
+			if got[0].DeviceA != a || got[0].Score != tc.wantScore || got[0].Evidence[0].Signal != tc.wantFirst {
+				t.Fatalf("unexpected candidate %+v", got[0])
+			}
+			for _, ev := range got[0].Evidence {
+				if len(ev.Values) != 1 {
+					t.Fatalf("expected deduplicated evidence values, got %+v", ev)
+				}
+			}
+		})
+	}
+}
+
+func TestPairKey(t *testing.T) {
+	a, b := PairKey(" B ", "a")
+	if a != "a" || b != "b" {
+		t.Fatalf("expected ordered pair, got %q %q", a, b)
+	}
+}
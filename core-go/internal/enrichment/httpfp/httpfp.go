@@ -0,0 +1,286 @@
+package httpfp
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config controls how services are fetched.
+type Config struct {
+	Timeout      time.Duration
+	MaxRedirects int
+	MaxBodyBytes int64
+}
+
+// Result is the fingerprint of a single HTTP(S) service.
+type Result struct {
+	Scheme      string
+	StatusCode  int
+	Title       string
+	Server      string
+	PoweredBy   string
+	FaviconHash *int32
+	FinalURL    string
+}
+
+var knownHTTPPorts = map[int]struct{}{
+	80:    {},
+	81:    {},
+	443:   {},
+	591:   {},
+	5000:  {},
+	5001:  {},
+	7080:  {},
+	7443:  {},
+	8000:  {},
+	8006:  {},
+	8008:  {},
+	8080:  {},
+	8081:  {},
+	8088:  {},
+	8123:  {},
+	8443:  {},
+	8888:  {},
+	9000:  {},
+	9080:  {},
+	9443:  {},
+	10443: {},
+}
+
+var httpsPorts = map[int]struct{}{
+	443:   {},
+	5001:  {},
+	7443:  {},
+	8006:  {},
+	8443:  {},
+	9443:  {},
+	10443: {},
+}
+
+// IsLikelyHTTP reports whether an open TCP service is worth fingerprinting, based on the port and the
+// scanner-provided service name.
+func IsLikelyHTTP(port int, name string) bool {
+	n := strings.ToLower(strings.TrimSpace(name))
+	if strings.Contains(n, "http") || strings.Contains(n, "www") {
+		return true
+	}
+	_, ok := knownHTTPPorts[port]
+	return ok
+}
+
+// SchemesFor returns the schemes to try, most likely first.
+func SchemesFor(port int, name string) []string {
+	n := strings.ToLower(name)
+	if _, ok := httpsPorts[port]; ok || strings.Contains(n, "https") || strings.Contains(n, "ssl") {
+		return []string{"https", "http"}
+	}
+	return []string{"http", "https"}
+}
+
+// Fetcher fetches `/` (and the favicon) from a single host without leaving it.
+type Fetcher struct {
+	cfg Config
+}
+
+func NewFetcher(cfg Config) *Fetcher {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 4 * time.Second
+	}
+	if cfg.MaxRedirects < 0 {
+		cfg.MaxRedirects = 0
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 256 << 10
+	}
+	return &Fetcher{cfg: cfg}
+}
+
+func (f *Fetcher) client(host string) *http.Client {
+	transport := &http.Transport{
+		Proxy:               nil,
+		DialContext:         (&net.Dialer{Timeout: f.cfg.Timeout}).DialContext,
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		TLSHandshakeTimeout: f.cfg.Timeout,
+		DisableKeepAlives:   true,
+	}
+	maxRedirects := f.cfg.MaxRedirects
+	return &http.Client{
+		Transport: transport,
+		Timeout:   f.cfg.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// Never follow redirects off the scanned host (e.g. to a cloud login page).
+			if len(via) > maxRedirects || req.URL.Hostname() != host {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+}
+
+// Fetch requests scheme://ip:port/ and returns the fingerprint of the final same-host response.
+func (f *Fetcher) Fetch(ctx context.Context, scheme, ip string, port int) (Result, error) {
+	if scheme != "http" && scheme != "https" {
+		return Result{}, errors.New("unsupported scheme")
+	}
+	base := &url.URL{Scheme: scheme, Host: net.JoinHostPort(ip, strconv.Itoa(port)), Path: "/"}
+	client := f.client(ip)
+
+	resp, body, err := f.get(ctx, client, base.String())
+	if err != nil {
+		return Result{}, err
+	}
+
+	res := Result{
+		Scheme:     scheme,
+		StatusCode: resp.StatusCode,
+		Title:      ParseTitle(body),
+		Server:     truncate(resp.Header.Get("Server"), 200),
+		PoweredBy:  truncate(resp.Header.Get("X-Powered-By"), 200),
+		FinalURL:   resp.Request.URL.String(),
+	}
+
+	iconURL := faviconURL(resp.Request.URL, body)
+	if iconURL != nil && iconURL.Hostname() == ip {
+		if iconResp, icon, err := f.get(ctx, client, iconURL.String()); err == nil && iconResp.StatusCode == http.StatusOK && len(icon) > 0 {
+			h := FaviconHash(icon)
+			res.FaviconHash = &h
+		}
+	}
+
+	return res, nil
+}
+
+func (f *Fetcher) get(ctx context.Context, client *http.Client, target string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", "roller_hoops-discovery/1")
+	req.Header.Set("Accept", "text/html,*/*;q=0.5")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.cfg.MaxBodyBytes))
+	if err != nil && len(body) == 0 {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+var (
+	titleRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	linkRe  = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+	relRe   = regexp.MustCompile(`(?is)\brel\s*=\s*["']?([^"'>]+)`)
+	hrefRe  = regexp.MustCompile(`(?is)\bhref\s*=\s*["']?([^"' >]+)`)
+)
+
+// ParseTitle extracts the HTML <title>, unescaped and with whitespace collapsed.
+func ParseTitle(body []byte) string {
+	m := titleRe.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	title := html.UnescapeString(string(m[1]))
+	title = strings.Join(strings.Fields(title), " ")
+	return truncate(title, 200)
+}
+
+func faviconURL(page *url.URL, body []byte) *url.URL {
+	for _, tag := range linkRe.FindAll(body, -1) {
+		rel := relRe.FindSubmatch(tag)
+		if rel == nil || !strings.Contains(strings.ToLower(string(rel[1])), "icon") {
+			continue
+		}
+		href := hrefRe.FindSubmatch(tag)
+		if href == nil {
+			continue
+		}
+		ref, err := url.Parse(html.UnescapeString(string(href[1])))
+		if err != nil {
+			continue
+		}
+		return page.ResolveReference(ref)
+	}
+	return page.ResolveReference(&url.URL{Path: "/favicon.ico"})
+}
+
+// FaviconHash returns the Shodan-compatible favicon hash: MurmurHash3 (x86, 32-bit, seed 0) over the
+// MIME-style base64 encoding (76-char lines, trailing newline) of the icon bytes.
+func FaviconHash(icon []byte) int32 {
+	enc := base64.StdEncoding.EncodeToString(icon)
+	var b strings.Builder
+	for len(enc) > 76 {
+		b.WriteString(enc[:76])
+		b.WriteByte('\n')
+		enc = enc[76:]
+	}
+	b.WriteString(enc)
+	b.WriteByte('\n')
+	return int32(murmur3(b.String()))
+}
+
+func murmur3(data string) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+	var h uint32
+	n := len(data)
+	i := 0
+	for ; i+4 <= n; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= c1
+		k = k<<15 | k>>17
+		k *= c2
+		h ^= k
+		h = h<<13 | h>>19
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	switch n - i {
+	case 3:
+		k ^= uint32(data[i+2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(data[i+1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(data[i])
+		k *= c1
+		k = k<<15 | k>>17
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(n)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+func truncate(value string, limit int) string {
+	value = strings.TrimSpace(value)
+	if len(value) <= limit {
+		return value
+	}
+	return strings.ToValidUTF8(value[:limit], "")
+}
@@ -0,0 +1,115 @@
+package httpfp
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMurmur3(t *testing.T) {
+	cases := map[string]uint32{
+		"": 0,
+		"The quick brown fox jumps over the lazy dog": 0x2e4ff723,
+	}
+	for in, want := range cases {
+		if got := murmur3(in); got != want {
+			t.Fatalf("murmur3(%q): expected %#x, got %#x", in, want, got)
+		}
+	}
+}
+
+func TestParseTitle(t *testing.T) {
+	cases := []struct {
+		body string
+		want string
+	}{
+		{body: `<html><head><TITLE>
+			Synology &amp; DiskStation
+		</TITLE></head></html>`, want: "Synology & DiskStation"},
+		{body: `<title data-x="1">HP LaserJet M404</title>`, want: "HP LaserJet M404"},
+		{body: `<html><body>no title</body></html>`, want: ""},
+	}
+	for _, tc := range cases {
+		if got := ParseTitle([]byte(tc.body)); got != tc.want {
+			t.Fatalf("expected %q, got %q", tc.want, got)
+		}
+	}
+}
+
+func TestIsLikelyHTTP(t *testing.T) {
+	cases := []struct {
+		port int
+		name string
+		want bool
+	}{
+		{port: 80, want: true},
+		{port: 8443, want: true},
+		{port: 22, name: "ssh", want: false},
+		{port: 10000, name: "snet-sensor-mgmt", want: false},
+		{port: 10000, name: "http-alt", want: true},
+	}
+	for _, tc := range cases {
+		if got := IsLikelyHTTP(tc.port, tc.name); got != tc.want {
+			t.Fatalf("%d/%s: expected %v, got %v", tc.port, tc.name, tc.want, got)
+		}
+	}
+	if s := SchemesFor(8443, ""); s[0] != "https" {
+		t.Fatalf("expected https first for 8443, got %v", s)
+	}
+	if s := SchemesFor(8080, "http-proxy"); s[0] != "http" {
+		t.Fatalf("expected http first for 8080, got %v", s)
+	}
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	icon := []byte{0x00, 0x00, 0x01, 0x00, 0x01, 0x00}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "lighttpd/1.4")
+		w.Header().Set("X-Powered-By", "PHP/8.1")
+		_, _ = w.Write([]byte(`<html><head><title>NVR Login</title><link rel="shortcut icon" href="/static/fav.ico"></head></html>`))
+	})
+	mux.HandleFunc("/static/fav.ico", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(icon)
+	})
+	srv := httptest.NewUnstartedServer(mux)
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.Start()
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	f := NewFetcher(Config{Timeout: 2 * time.Second, MaxRedirects: 3})
+	got, err := f.Fetch(context.Background(), "http", u.Hostname(), port)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if got.StatusCode != 200 || got.Title != "NVR Login" || got.Server != "lighttpd/1.4" || got.PoweredBy != "PHP/8.1" {
+		t.Fatalf("unexpected result %+v", got)
+	}
+	if got.FinalURL != srv.URL+"/login" {
+		t.Fatalf("expected final url %s/login, got %s", srv.URL, got.FinalURL)
+	}
+	if got.FaviconHash == nil || *got.FaviconHash != FaviconHash(icon) {
+		t.Fatalf("expected favicon hash, got %v", got.FaviconHash)
+	}
+
+	noRedirect := NewFetcher(Config{Timeout: 2 * time.Second, MaxRedirects: 0})
+	got, err = noRedirect.Fetch(context.Background(), "http", u.Hostname(), port)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if got.StatusCode != http.StatusFound {
+		t.Fatalf("expected redirect limit to stop at 302, got %d", got.StatusCode)
+	}
+}
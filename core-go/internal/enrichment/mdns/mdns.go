@@ -191,6 +191,16 @@ func lookupNetBIOS(ctx context.Context, address string) ([]string, error) {
 	return names, nil
 }
 
+// NetBIOSNodeStatusRequest builds a wildcard NBSTAT query and returns it with its transaction ID.
+func NetBIOSNodeStatusRequest() ([]byte, uint16, error) {
+	return buildNetBIOSNodeStatusRequest()
+}
+
+// ParseNetBIOSNodeStatus extracts unique (non-group) names from an NBSTAT response.
+func ParseNetBIOSNodeStatus(resp []byte, txID uint16) ([]string, error) {
+	return parseNetBIOSNodeStatusResponse(resp, txID)
+}
+
 func buildNetBIOSNodeStatusRequest() ([]byte, uint16, error) {
 	txID, err := randomUint16()
 	if err != nil {
@@ -0,0 +1,711 @@
+package pcap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/miekg/dns"
+)
+
+// Config bounds how much state one capture may accumulate.
+type Config struct {
+	MaxHosts     int
+	MaxNeighbors int
+}
+
+// Name is a hostname claimed by a host in its own traffic.
+type Name struct {
+	Name   string
+	Source string // "dhcp" | "mdns" | "netbios"
+}
+
+// Host is the passive evidence for one address: an IP with the MAC it was bound to on the wire (ARP,
+// DHCP, link-local multicast), or a MAC alone when only a DHCP exchange without an ACK was captured.
+type Host struct {
+	IP       netip.Addr
+	MAC      string
+	Names    []Name
+	ClientID string // DHCP option 61 as hex (type byte first), unless it only repeats the MAC
+	TCPPorts []int  // ports that answered a SYN with SYN-ACK
+	LastSeen time.Time
+}
+
+// Neighbor is one LLDP or CDP advertisement (the latest per device/port).
+type Neighbor struct {
+	Protocol          string // "lldp" | "cdp"
+	SourceMAC         string // frame source (usually the advertising port's MAC)
+	ChassisMAC        string // LLDP chassis ID when it is a MAC address
+	ChassisID         string
+	SystemName        string
+	SystemDescription string
+	Platform          string
+	PortID            string
+	PortDescription   string
+	MgmtIP            netip.Addr
+	LastSeen          time.Time
+}
+
+// Stats counts what was seen while reading; per-protocol counters are frames, not unique hosts.
+type Stats struct {
+	Packets           int
+	UnsupportedFrames int
+	ARP               int
+	DHCP              int
+	MDNS              int
+	NetBIOS           int
+	LLDP              int
+	CDP               int
+	SYNACK            int
+	HostsDropped      int
+	Truncated         bool
+	FirstPacket       time.Time
+	LastPacket        time.Time
+}
+
+// Result is everything extracted from one capture.
+type Result struct {
+	Format    string
+	Hosts     []Host
+	Neighbors []Neighbor
+	Stats     Stats
+}
+
+// Read streams a pcap or pcapng capture and extracts ARP bindings, DHCP/mDNS/NetBIOS names, LLDP/CDP
+// advertisements and TCP SYN-ACK service evidence. Ethernet (with 802.1Q tags) and Linux cooked
+// captures are decoded; IPv4 only. A capture cut off mid-record (tcpdump killed) is not an error: the
+// result covers what was read and Stats.Truncated is set.
+func Read(r io.Reader, cfg Config) (Result, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	pr, format, err := newPacketReader(br)
+	if err != nil {
+		return Result{}, err
+	}
+
+	a := newAggregator(cfg)
+	for {
+		pkt, err := pr.next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				a.stats.Truncated = true
+				break
+			}
+			res := a.result(format)
+			return res, err
+		}
+		a.packet(pkt)
+	}
+	return a.result(format), nil
+}
+
+type hostKey struct {
+	ip  netip.Addr
+	mac string
+}
+
+type aggregator struct {
+	cfg       Config
+	stats     Stats
+	hosts     map[hostKey]*Host
+	order     []hostKey
+	ipToMAC   map[netip.Addr]string
+	neighbors map[string]*Neighbor
+	nOrder    []string
+	ts        time.Time
+}
+
+func newAggregator(cfg Config) *aggregator {
+	if cfg.MaxHosts <= 0 {
+		cfg.MaxHosts = 16384
+	}
+	if cfg.MaxNeighbors <= 0 {
+		cfg.MaxNeighbors = 1024
+	}
+	return &aggregator{
+		cfg:       cfg,
+		hosts:     map[hostKey]*Host{},
+		ipToMAC:   map[netip.Addr]string{},
+		neighbors: map[string]*Neighbor{},
+	}
+}
+
+func (a *aggregator) packet(pkt packet) {
+	a.stats.Packets++
+	a.ts = pkt.Timestamp
+	if !pkt.Timestamp.IsZero() {
+		if a.stats.FirstPacket.IsZero() || pkt.Timestamp.Before(a.stats.FirstPacket) {
+			a.stats.FirstPacket = pkt.Timestamp
+		}
+		if pkt.Timestamp.After(a.stats.LastPacket) {
+			a.stats.LastPacket = pkt.Timestamp
+		}
+	}
+
+	data := pkt.Data
+	switch pkt.LinkType {
+	case linkTypeEthernet:
+		if len(data) < 14 {
+			return
+		}
+		etherType := binary.BigEndian.Uint16(data[12:14])
+		payload := data[14:]
+		for etherType == 0x8100 || etherType == 0x88a8 {
+			if len(payload) < 4 {
+				return
+			}
+			etherType = binary.BigEndian.Uint16(payload[2:4])
+			payload = payload[4:]
+		}
+		group := data[0]&0x01 != 0
+		if etherType <= 1500 {
+			// 802.3 length field: an LLC frame (CDP rides on SNAP).
+			if int(etherType) < len(payload) {
+				payload = payload[:etherType]
+			}
+			a.llc(data[6:12], payload)
+			return
+		}
+		a.ether(data[6:12], group, etherType, payload)
+	case linkTypeLinuxSLL:
+		if len(data) < 16 {
+			return
+		}
+		pktType := binary.BigEndian.Uint16(data[0:2])
+		addrLen := int(binary.BigEndian.Uint16(data[4:6]))
+		if addrLen > 8 {
+			addrLen = 8
+		}
+		src := data[6 : 6+addrLen]
+		proto := binary.BigEndian.Uint16(data[14:16])
+		if proto == 0x0004 {
+			a.llc(src, data[16:])
+			return
+		}
+		a.ether(src, pktType == 1 || pktType == 2, proto, data[16:])
+	default:
+		a.stats.UnsupportedFrames++
+	}
+}
+
+func (a *aggregator) ether(src []byte, group bool, etherType uint16, payload []byte) {
+	switch etherType {
+	case 0x0806:
+		a.arp(payload)
+	case 0x0800:
+		a.ipv4(src, group, payload)
+	case 0x88cc:
+		a.lldp(src, payload)
+	}
+}
+
+func macString(b []byte) string {
+	if len(b) != 6 {
+		return ""
+	}
+	mac := net.HardwareAddr(b).String()
+	if mac == "00:00:00:00:00:00" || mac == "ff:ff:ff:ff:ff:ff" || b[0]&0x01 != 0 {
+		return ""
+	}
+	return mac
+}
+
+func usableIP(ip netip.Addr) bool {
+	return ip.IsValid() && !ip.IsUnspecified() && !ip.IsMulticast() && !ip.IsLoopback() && ip != netip.AddrFrom4([4]byte{255, 255, 255, 255})
+}
+
+// host returns the entry for ip (or for mac alone when ip is invalid), creating it within the host cap.
+func (a *aggregator) host(ip netip.Addr, mac string) *Host {
+	key := hostKey{ip: ip}
+	if !ip.IsValid() {
+		if mac == "" {
+			return nil
+		}
+		key.mac = mac
+	}
+	h, ok := a.hosts[key]
+	if !ok {
+		if len(a.hosts) >= a.cfg.MaxHosts {
+			a.stats.HostsDropped++
+			return nil
+		}
+		h = &Host{IP: ip}
+		a.hosts[key] = h
+		a.order = append(a.order, key)
+	}
+	if mac != "" {
+		h.MAC = mac
+	}
+	if a.ts.After(h.LastSeen) {
+		h.LastSeen = a.ts
+	}
+	return h
+}
+
+// bind records an authoritative IP→MAC binding (ARP sender, DHCP ACK, link-local multicast source).
+func (a *aggregator) bind(ip netip.Addr, mac string) *Host {
+	if !usableIP(ip) || mac == "" {
+		return nil
+	}
+	a.ipToMAC[ip] = mac
+	return a.host(ip, mac)
+}
+
+func (h *Host) addName(name, source string) {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".")
+	if name == "" {
+		return
+	}
+	for _, n := range h.Names {
+		if n.Source == source && strings.EqualFold(n.Name, name) {
+			return
+		}
+	}
+	h.Names = append(h.Names, Name{Name: name, Source: source})
+}
+
+func (a *aggregator) arp(p []byte) {
+	// Ethernet/IPv4 only: htype 1, ptype 0x0800, hlen 6, plen 4.
+	if len(p) < 28 || binary.BigEndian.Uint16(p[0:2]) != 1 || binary.BigEndian.Uint16(p[2:4]) != 0x0800 || p[4] != 6 || p[5] != 4 {
+		return
+	}
+	a.stats.ARP++
+	a.bind(netip.AddrFrom4([4]byte(p[14:18])), macString(p[8:14]))
+}
+
+func (a *aggregator) ipv4(src []byte, group bool, p []byte) {
+	if len(p) < 20 || p[0]>>4 != 4 {
+		return
+	}
+	ihl := int(p[0]&0x0f) * 4
+	total := int(binary.BigEndian.Uint16(p[2:4]))
+	if ihl < 20 || total < ihl || len(p) < ihl {
+		return
+	}
+	if total < len(p) {
+		p = p[:total]
+	}
+	if binary.BigEndian.Uint16(p[6:8])&0x1fff != 0 {
+		// Non-first fragment: no transport header.
+		return
+	}
+	proto := p[9]
+	srcIP := netip.AddrFrom4([4]byte(p[12:16]))
+	l4 := p[ihl:]
+
+	switch proto {
+	case 17:
+		if len(l4) < 8 {
+			return
+		}
+		sport := binary.BigEndian.Uint16(l4[0:2])
+		dport := binary.BigEndian.Uint16(l4[2:4])
+		payload := l4[8:]
+		switch {
+		case sport == 67 || sport == 68 || dport == 67 || dport == 68:
+			a.dhcp(payload)
+		case sport == 5353:
+			a.mdns(srcIP, src, group, payload)
+		case sport == 137 && dport == 137:
+			a.netbios(srcIP, src, group, payload)
+		}
+	case 6:
+		if len(l4) < 14 {
+			return
+		}
+		flags := l4[13]
+		// SYN+ACK without RST: the source port is listening.
+		if flags&0x12 == 0x12 && flags&0x04 == 0 && usableIP(srcIP) {
+			a.stats.SYNACK++
+			if h := a.host(srcIP, ""); h != nil {
+				port := int(binary.BigEndian.Uint16(l4[0:2]))
+				for _, existing := range h.TCPPorts {
+					if existing == port {
+						return
+					}
+				}
+				h.TCPPorts = append(h.TCPPorts, port)
+			}
+		}
+	}
+}
+
+func (a *aggregator) dhcp(p []byte) {
+	if len(p) < 240 || p[1] != 1 || p[2] != 6 || binary.BigEndian.Uint32(p[236:240]) != 0x63825363 {
+		return
+	}
+	a.stats.DHCP++
+	op := p[0]
+	ciaddr := netip.AddrFrom4([4]byte(p[12:16]))
+	yiaddr := netip.AddrFrom4([4]byte(p[16:20]))
+	mac := macString(p[28:34])
+	if mac == "" {
+		return
+	}
+
+	var msgType byte
+	var hostname, fqdn, clientID string
+	opts := p[240:]
+	for len(opts) > 0 {
+		code := opts[0]
+		if code == 0 {
+			opts = opts[1:]
+			continue
+		}
+		if code == 255 || len(opts) < 2 || 2+int(opts[1]) > len(opts) {
+			break
+		}
+		v := opts[2 : 2+int(opts[1])]
+		switch code {
+		case 53:
+			if len(v) == 1 {
+				msgType = v[0]
+			}
+		case 12:
+			hostname = string(v)
+		case 61:
+			clientID = dhcpClientID(v, p[28:34])
+		case 81:
+			// Client FQDN: flags, two deprecated rcode bytes, then the name (wire format when E is set).
+			if len(v) > 3 {
+				if v[0]&0x04 != 0 {
+					if name, _, err := dns.UnpackDomainName(v[3:], 0); err == nil {
+						fqdn = name
+					}
+				} else {
+					fqdn = string(v[3:])
+				}
+			}
+		}
+		opts = opts[2+len(v):]
+	}
+
+	var h *Host
+	switch {
+	case op == 2 && msgType == 5:
+		h = a.bind(yiaddr, mac)
+	case usableIP(ciaddr):
+		h = a.bind(ciaddr, mac)
+	case op == 1:
+		h = a.host(netip.Addr{}, mac)
+	}
+	if h == nil || op != 1 {
+		return
+	}
+	if hostname != "" {
+		h.addName(hostname, "dhcp")
+	} else if fqdn != "" {
+		h.addName(fqdn, "dhcp")
+	}
+	if clientID != "" {
+		h.ClientID = clientID
+	}
+}
+
+// dhcpClientID formats option 61. A type-1 identifier that equals chaddr carries nothing beyond the
+// (possibly randomized) MAC and is dropped; DUID-based identifiers survive MAC rotation.
+func dhcpClientID(v, chaddr []byte) string {
+	if len(v) < 2 {
+		return ""
+	}
+	if v[0] == 1 && len(v) == 7 && bytes.Equal(v[1:], chaddr) {
+		return ""
+	}
+	parts := make([]string, len(v))
+	for i, b := range v {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+func (a *aggregator) mdns(srcIP netip.Addr, src []byte, group bool, p []byte) {
+	var msg dns.Msg
+	if err := msg.Unpack(p); err != nil || !msg.Response {
+		return
+	}
+	a.stats.MDNS++
+	mac := ""
+	if group {
+		mac = macString(src)
+	}
+	for _, rr := range append(msg.Answer, msg.Extra...) {
+		rec, ok := rr.(*dns.A)
+		if !ok {
+			continue
+		}
+		ip, ok := netip.AddrFromSlice(rec.A.To4())
+		// Only trust records a host publishes about itself.
+		if !ok || ip != srcIP || !usableIP(ip) {
+			continue
+		}
+		var h *Host
+		if mac != "" {
+			h = a.bind(ip, mac)
+		} else {
+			h = a.host(ip, "")
+		}
+		if h != nil {
+			h.addName(rec.Hdr.Name, "mdns")
+		}
+	}
+}
+
+// netbios records names from NBNS registration and refresh broadcasts (what Windows hosts send at boot
+// and periodically). Group names (WORKGROUP, domain browse lists) are not device identities and are skipped.
+func (a *aggregator) netbios(srcIP netip.Addr, src []byte, group bool, p []byte) {
+	if len(p) < 12+34+4 {
+		return
+	}
+	flags := binary.BigEndian.Uint16(p[2:4])
+	opcode := (flags >> 11) & 0x0f
+	if flags&0x8000 != 0 || (opcode != 5 && opcode != 8 && opcode != 9) {
+		return
+	}
+	if binary.BigEndian.Uint16(p[4:6]) == 0 || p[12] != 0x20 {
+		return
+	}
+	a.stats.NetBIOS++
+
+	var raw [16]byte
+	for i := 0; i < 16; i++ {
+		hi, lo := p[13+2*i]-'A', p[14+2*i]-'A'
+		if hi > 15 || lo > 15 {
+			return
+		}
+		raw[i] = hi<<4 | lo
+	}
+	name := strings.TrimSpace(string(raw[:15]))
+	suffix := raw[15]
+	if name == "" || (suffix != 0x00 && suffix != 0x20) {
+		return
+	}
+
+	// Question: encoded name (34 bytes incl. length + root), type, class. The additional record that follows
+	// carries NB_FLAGS (group bit) and the registered address.
+	off := 12 + 34 + 4
+	if binary.BigEndian.Uint16(p[10:12]) > 0 && len(p) >= off+2 {
+		if p[off]&0xc0 == 0xc0 {
+			off += 2
+		} else {
+			off += 34
+		}
+		if len(p) >= off+10+6 {
+			rdata := p[off+10:]
+			if binary.BigEndian.Uint16(rdata[0:2])&0x8000 != 0 {
+				return
+			}
+			if addr := netip.AddrFrom4([4]byte(rdata[2:6])); addr != srcIP {
+				// Registered on behalf of another address (WINS relay); the frame source tells us nothing.
+				return
+			}
+		}
+	}
+	if !usableIP(srcIP) {
+		return
+	}
+	var h *Host
+	if mac := macString(src); group && mac != "" {
+		h = a.bind(srcIP, mac)
+	} else {
+		h = a.host(srcIP, "")
+	}
+	if h != nil {
+		h.addName(name, "netbios")
+	}
+}
+
+func (a *aggregator) neighbor(n Neighbor) {
+	key := n.Protocol + "|" + n.ChassisID + "|" + n.SystemName + "|" + n.PortID
+	if n.ChassisID == "" && n.SystemName == "" {
+		key += "|" + n.SourceMAC
+	}
+	if _, ok := a.neighbors[key]; !ok {
+		if len(a.neighbors) >= a.cfg.MaxNeighbors {
+			return
+		}
+		a.nOrder = append(a.nOrder, key)
+	}
+	n.LastSeen = a.ts
+	a.neighbors[key] = &n
+}
+
+func (a *aggregator) lldp(src []byte, p []byte) {
+	n := Neighbor{Protocol: "lldp", SourceMAC: macString(src)}
+	for len(p) >= 2 {
+		typ := p[0] >> 1
+		l := int(binary.BigEndian.Uint16(p[0:2]) & 0x01ff)
+		if typ == 0 || 2+l > len(p) {
+			break
+		}
+		v := p[2 : 2+l]
+		switch typ {
+		case 1:
+			if len(v) > 1 {
+				if v[0] == 4 && len(v) == 7 {
+					n.ChassisMAC = macString(v[1:])
+					n.ChassisID = net.HardwareAddr(v[1:]).String()
+				} else {
+					n.ChassisID = tlvString(v[1:])
+				}
+			}
+		case 2:
+			if len(v) > 1 {
+				if v[0] == 3 && len(v) == 7 {
+					n.PortID = net.HardwareAddr(v[1:]).String()
+				} else {
+					n.PortID = tlvString(v[1:])
+				}
+			}
+		case 4:
+			n.PortDescription = tlvString(v)
+		case 5:
+			n.SystemName = tlvString(v)
+		case 6:
+			n.SystemDescription = tlvString(v)
+		case 8:
+			// Management address: string length (subtype + address), subtype (1 = IPv4), address.
+			if len(v) >= 6 && v[0] == 5 && v[1] == 1 && !n.MgmtIP.IsValid() {
+				if ip := netip.AddrFrom4([4]byte(v[2:6])); usableIP(ip) {
+					n.MgmtIP = ip
+				}
+			}
+		}
+		p = p[2+l:]
+	}
+	if n.ChassisID == "" && n.SystemName == "" {
+		return
+	}
+	a.stats.LLDP++
+	a.neighbor(n)
+}
+
+func (a *aggregator) llc(src []byte, p []byte) {
+	// SNAP with Cisco OUI 00:00:0c and protocol 0x2000 = CDP.
+	if len(p) < 12 || p[0] != 0xaa || p[1] != 0xaa || p[2] != 0x03 || p[3] != 0 || p[4] != 0 || p[5] != 0x0c || binary.BigEndian.Uint16(p[6:8]) != 0x2000 {
+		return
+	}
+	n := Neighbor{Protocol: "cdp", SourceMAC: macString(src)}
+	tlvs := p[12:]
+	for len(tlvs) >= 4 {
+		typ := binary.BigEndian.Uint16(tlvs[0:2])
+		l := int(binary.BigEndian.Uint16(tlvs[2:4]))
+		if l < 4 || l > len(tlvs) {
+			break
+		}
+		v := tlvs[4:l]
+		switch typ {
+		case 0x0001:
+			n.ChassisID = tlvString(v)
+			n.SystemName = n.ChassisID
+		case 0x0002, 0x0016:
+			if !n.MgmtIP.IsValid() {
+				n.MgmtIP = cdpFirstIPv4(v)
+			}
+		case 0x0003:
+			n.PortID = tlvString(v)
+		case 0x0005:
+			n.SystemDescription = tlvString(v)
+		case 0x0006:
+			n.Platform = tlvString(v)
+		}
+		tlvs = tlvs[l:]
+	}
+	if n.ChassisID == "" {
+		return
+	}
+	a.stats.CDP++
+	a.neighbor(n)
+}
+
+// cdpFirstIPv4 returns the first NLPID IPv4 address in a CDP address list.
+func cdpFirstIPv4(v []byte) netip.Addr {
+	if len(v) < 4 {
+		return netip.Addr{}
+	}
+	count := int(binary.BigEndian.Uint32(v[0:4]))
+	v = v[4:]
+	for i := 0; i < count && len(v) >= 2; i++ {
+		protoType, protoLen := v[0], int(v[1])
+		if len(v) < 2+protoLen+2 {
+			break
+		}
+		proto := v[2 : 2+protoLen]
+		addrLen := int(binary.BigEndian.Uint16(v[2+protoLen : 4+protoLen]))
+		if len(v) < 4+protoLen+addrLen {
+			break
+		}
+		addr := v[4+protoLen : 4+protoLen+addrLen]
+		if protoType == 1 && protoLen == 1 && proto[0] == 0xcc && addrLen == 4 {
+			if ip := netip.AddrFrom4([4]byte(addr)); usableIP(ip) {
+				return ip
+			}
+		}
+		v = v[4+protoLen+addrLen:]
+	}
+	return netip.Addr{}
+}
+
+// tlvString returns printable TLV text, or colon-separated hex for binary identifiers.
+func tlvString(v []byte) string {
+	s := strings.TrimRight(string(v), "\x00")
+	for _, r := range s {
+		if r == unicode.ReplacementChar || (!unicode.IsPrint(r) && !unicode.IsSpace(r)) {
+			parts := make([]string, len(v))
+			for i, b := range v {
+				parts[i] = fmt.Sprintf("%02x", b)
+			}
+			return strings.Join(parts, ":")
+		}
+	}
+	return strings.TrimSpace(s)
+}
+
+func (a *aggregator) result(format string) Result {
+	res := Result{Format: format, Stats: a.stats}
+
+	// Fold MAC-only entries (DHCP without an ACK) into an IP entry for the same MAC when one exists,
+	// and attach MACs learned later in the capture to IP-only entries.
+	byMAC := map[string]*Host{}
+	for _, key := range a.order {
+		h := a.hosts[key]
+		if h.IP.IsValid() {
+			if h.MAC == "" {
+				h.MAC = a.ipToMAC[h.IP]
+			}
+			if h.MAC != "" {
+				if _, ok := byMAC[h.MAC]; !ok {
+					byMAC[h.MAC] = h
+				}
+			}
+		}
+	}
+	for _, key := range a.order {
+		h := a.hosts[key]
+		if !h.IP.IsValid() {
+			if target, ok := byMAC[h.MAC]; ok {
+				for _, n := range h.Names {
+					target.addName(n.Name, n.Source)
+				}
+				if target.ClientID == "" {
+					target.ClientID = h.ClientID
+				}
+				continue
+			}
+		}
+		res.Hosts = append(res.Hosts, *h)
+	}
+	for _, key := range a.nOrder {
+		res.Neighbors = append(res.Neighbors, *a.neighbors[key])
+	}
+	return res
+}
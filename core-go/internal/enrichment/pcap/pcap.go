@@ -0,0 +1,282 @@
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Capture file formats understood by Read.
+const (
+	FormatPcap   = "pcap"
+	FormatPcapNG = "pcapng"
+)
+
+// Link-layer header types (https://www.tcpdump.org/linktypes.html) the frame decoder understands.
+const (
+	linkTypeEthernet = 1
+	linkTypeLinuxSLL = 113
+)
+
+const (
+	// maxRecordBytes bounds a single packet record; anything larger is treated as corruption.
+	maxRecordBytes = 256 << 10
+	// maxBlockBytes bounds a single pcapng block (options-heavy section headers stay far below this).
+	maxBlockBytes = 16 << 20
+)
+
+// ErrUnknownFormat is returned when the input starts with neither a pcap nor a pcapng magic number.
+var ErrUnknownFormat = errors.New("pcap: not a pcap or pcapng capture")
+
+// Detect reports the capture format from the first four bytes of a file.
+func Detect(magic []byte) (string, bool) {
+	if len(magic) < 4 {
+		return "", false
+	}
+	switch {
+	case magic[0] == 0x0a && magic[1] == 0x0d && magic[2] == 0x0d && magic[3] == 0x0a:
+		return FormatPcapNG, true
+	case pcapByteOrder(magic) != nil:
+		return FormatPcap, true
+	}
+	return "", false
+}
+
+type packet struct {
+	Timestamp time.Time
+	LinkType  uint32
+	Data      []byte
+}
+
+// packetReader yields packets in file order and io.EOF at the end. Data is only valid until the next call.
+type packetReader interface {
+	next() (packet, error)
+}
+
+func newPacketReader(r *bufio.Reader) (packetReader, string, error) {
+	magic, err := r.Peek(4)
+	if err != nil {
+		return nil, "", ErrUnknownFormat
+	}
+	format, ok := Detect(magic)
+	if !ok {
+		return nil, "", ErrUnknownFormat
+	}
+	if format == FormatPcapNG {
+		return &pcapngReader{r: r}, format, nil
+	}
+	pr, err := newPcapReader(r)
+	return pr, format, err
+}
+
+func pcapByteOrder(magic []byte) binary.ByteOrder {
+	switch {
+	case magic[0] == 0xd4 && magic[1] == 0xc3 && magic[2] == 0xb2 && magic[3] == 0xa1,
+		magic[0] == 0x4d && magic[1] == 0x3c && magic[2] == 0xb2 && magic[3] == 0xa1:
+		return binary.LittleEndian
+	case magic[0] == 0xa1 && magic[1] == 0xb2 && magic[2] == 0xc3 && magic[3] == 0xd4,
+		magic[0] == 0xa1 && magic[1] == 0xb2 && magic[2] == 0x3c && magic[3] == 0x4d:
+		return binary.BigEndian
+	}
+	return nil
+}
+
+// pcapReader reads the classic libpcap format (microsecond or nanosecond timestamps, either byte order).
+type pcapReader struct {
+	r        *bufio.Reader
+	order    binary.ByteOrder
+	nano     bool
+	linkType uint32
+	buf      []byte
+}
+
+func newPcapReader(r *bufio.Reader) (*pcapReader, error) {
+	hdr := make([]byte, 24)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, fmt.Errorf("pcap: read file header: %w", err)
+	}
+	order := pcapByteOrder(hdr)
+	if order == nil {
+		return nil, ErrUnknownFormat
+	}
+	return &pcapReader{
+		r:     r,
+		order: order,
+		nano:  hdr[2] == 0x3c || hdr[1] == 0x3c,
+		// The upper bits of the link type field carry FCS metadata.
+		linkType: order.Uint32(hdr[20:24]) & 0x0fffffff,
+	}, nil
+}
+
+func (p *pcapReader) next() (packet, error) {
+	var hdr [16]byte
+	if _, err := io.ReadFull(p.r, hdr[:]); err != nil {
+		return packet{}, err
+	}
+	incl := p.order.Uint32(hdr[8:12])
+	if incl > maxRecordBytes {
+		return packet{}, fmt.Errorf("pcap: record of %d bytes exceeds limit", incl)
+	}
+	if cap(p.buf) < int(incl) {
+		p.buf = make([]byte, incl)
+	}
+	data := p.buf[:incl]
+	if _, err := io.ReadFull(p.r, data); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return packet{}, err
+	}
+	sec := int64(p.order.Uint32(hdr[0:4]))
+	frac := int64(p.order.Uint32(hdr[4:8]))
+	if !p.nano {
+		frac *= int64(time.Microsecond)
+	}
+	return packet{Timestamp: time.Unix(sec, frac).UTC(), LinkType: p.linkType, Data: data}, nil
+}
+
+type pcapngInterface struct {
+	linkType uint32
+	// tsResol is the raw if_tsresol option: high bit clear = 10^-n seconds, set = 2^-n seconds.
+	tsResol byte
+}
+
+// pcapngReader reads pcapng sections, tracking per-interface link types and timestamp resolution.
+// Only enhanced and simple packet blocks produce packets; every other block type is skipped.
+type pcapngReader struct {
+	r      *bufio.Reader
+	order  binary.ByteOrder
+	ifaces []pcapngInterface
+	buf    []byte
+}
+
+const (
+	pcapngBlockSHB = 0x0a0d0d0a
+	pcapngBlockIDB = 0x00000001
+	pcapngBlockSPB = 0x00000003
+	pcapngBlockEPB = 0x00000006
+)
+
+func (p *pcapngReader) next() (packet, error) {
+	for {
+		head, err := p.r.Peek(12)
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(head) > 0 {
+				return packet{}, io.ErrUnexpectedEOF
+			}
+			return packet{}, err
+		}
+		if binary.BigEndian.Uint32(head[0:4]) == pcapngBlockSHB {
+			switch binary.BigEndian.Uint32(head[8:12]) {
+			case 0x1a2b3c4d:
+				p.order = binary.BigEndian
+			case 0x4d3c2b1a:
+				p.order = binary.LittleEndian
+			default:
+				return packet{}, errors.New("pcapng: invalid byte-order magic")
+			}
+			p.ifaces = p.ifaces[:0]
+		}
+		if p.order == nil {
+			return packet{}, errors.New("pcapng: missing section header block")
+		}
+
+		blockType := p.order.Uint32(head[0:4])
+		total := p.order.Uint32(head[4:8])
+		if total < 12 || total%4 != 0 || total > maxBlockBytes {
+			return packet{}, fmt.Errorf("pcapng: invalid block length %d", total)
+		}
+		if cap(p.buf) < int(total) {
+			p.buf = make([]byte, total)
+		}
+		block := p.buf[:total]
+		if _, err := io.ReadFull(p.r, block); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return packet{}, err
+		}
+		body := block[8 : total-4]
+
+		switch blockType {
+		case pcapngBlockIDB:
+			if len(body) < 8 {
+				return packet{}, errors.New("pcapng: short interface description block")
+			}
+			iface := pcapngInterface{linkType: uint32(p.order.Uint16(body[0:2])), tsResol: 6}
+			if v, ok := p.option(body[8:], 9); ok && len(v) >= 1 {
+				iface.tsResol = v[0]
+			}
+			p.ifaces = append(p.ifaces, iface)
+		case pcapngBlockEPB:
+			if len(body) < 20 {
+				return packet{}, errors.New("pcapng: short enhanced packet block")
+			}
+			ifID := p.order.Uint32(body[0:4])
+			if int(ifID) >= len(p.ifaces) {
+				return packet{}, fmt.Errorf("pcapng: packet references unknown interface %d", ifID)
+			}
+			capLen := p.order.Uint32(body[12:16])
+			if int(capLen) > len(body)-20 {
+				return packet{}, errors.New("pcapng: enhanced packet block data truncated")
+			}
+			iface := p.ifaces[ifID]
+			ticks := uint64(p.order.Uint32(body[4:8]))<<32 | uint64(p.order.Uint32(body[8:12]))
+			return packet{
+				Timestamp: pcapngTimestamp(ticks, iface.tsResol),
+				LinkType:  iface.linkType,
+				Data:      body[20 : 20+capLen],
+			}, nil
+		case pcapngBlockSPB:
+			if len(body) < 4 || len(p.ifaces) == 0 {
+				return packet{}, errors.New("pcapng: invalid simple packet block")
+			}
+			data := body[4:]
+			if orig := p.order.Uint32(body[0:4]); int(orig) < len(data) {
+				data = data[:orig]
+			}
+			// Simple packet blocks carry no timestamp.
+			return packet{LinkType: p.ifaces[0].linkType, Data: data}, nil
+		}
+	}
+}
+
+// option returns the value of the first option with the given code in a pcapng options list.
+func (p *pcapngReader) option(opts []byte, code uint16) ([]byte, bool) {
+	for len(opts) >= 4 {
+		c := p.order.Uint16(opts[0:2])
+		l := int(p.order.Uint16(opts[2:4]))
+		if c == 0 || 4+l > len(opts) {
+			return nil, false
+		}
+		if c == code {
+			return opts[4 : 4+l], true
+		}
+		n := 4 + (l+3)&^3
+		if n > len(opts) {
+			return nil, false
+		}
+		opts = opts[n:]
+	}
+	return nil, false
+}
+
+func pcapngTimestamp(ticks uint64, resol byte) time.Time {
+	exp := uint64(resol & 0x7f)
+	if resol&0x80 != 0 && exp <= 30 {
+		frac := ticks & (1<<exp - 1)
+		return time.Unix(int64(ticks>>exp), int64(frac*uint64(time.Second)>>exp)).UTC()
+	}
+	if resol&0x80 != 0 || exp > 9 {
+		// Resolutions we cannot represent fall back to the pcapng default (microseconds).
+		exp = 6
+	}
+	div := uint64(1)
+	for i := uint64(0); i < exp; i++ {
+		div *= 10
+	}
+	return time.Unix(int64(ticks/div), int64((ticks%div)*(uint64(time.Second)/div))).UTC()
+}
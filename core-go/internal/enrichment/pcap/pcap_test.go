@@ -0,0 +1,397 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+var (
+	testHostMAC   = []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	testPhoneMAC  = []byte{0x02, 0xaa, 0xbb, 0xcc, 0xdd, 0x01}
+	testSwitchMAC = []byte{0x00, 0x1b, 0x54, 0x00, 0x00, 0x01}
+	broadcastMAC  = []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+)
+
+func ethernetFrame(dst, src []byte, etherType uint16, payload []byte) []byte {
+	f := append(append(append([]byte{}, dst...), src...), byte(etherType>>8), byte(etherType))
+	return append(f, payload...)
+}
+
+func ipv4Packet(src, dst netip.Addr, proto byte, l4 []byte) []byte {
+	p := make([]byte, 20)
+	p[0] = 0x45
+	binary.BigEndian.PutUint16(p[2:4], uint16(20+len(l4)))
+	p[8] = 64
+	p[9] = proto
+	s, d := src.As4(), dst.As4()
+	copy(p[12:16], s[:])
+	copy(p[16:20], d[:])
+	return append(p, l4...)
+}
+
+func udpDatagram(sport, dport uint16, payload []byte) []byte {
+	u := make([]byte, 8)
+	binary.BigEndian.PutUint16(u[0:2], sport)
+	binary.BigEndian.PutUint16(u[2:4], dport)
+	binary.BigEndian.PutUint16(u[4:6], uint16(8+len(payload)))
+	return append(u, payload...)
+}
+
+func arpReply(mac []byte, ip netip.Addr) []byte {
+	p := []byte{0, 1, 8, 0, 6, 4, 0, 2}
+	a := ip.As4()
+	p = append(p, mac...)
+	p = append(p, a[:]...)
+	p = append(p, make([]byte, 10)...)
+	return ethernetFrame(broadcastMAC, mac, 0x0806, p)
+}
+
+func dhcpMessage(op byte, mac []byte, yiaddr netip.Addr, msgType byte, hostname string, extraOpts ...byte) []byte {
+	p := make([]byte, 240)
+	p[0], p[1], p[2] = op, 1, 6
+	if yiaddr.IsValid() {
+		a := yiaddr.As4()
+		copy(p[16:20], a[:])
+	}
+	copy(p[28:34], mac)
+	binary.BigEndian.PutUint32(p[236:240], 0x63825363)
+	p = append(p, 53, 1, msgType)
+	if hostname != "" {
+		p = append(p, 12, byte(len(hostname)))
+		p = append(p, hostname...)
+	}
+	p = append(p, extraOpts...)
+	return append(p, 255)
+}
+
+func dhcpFrame(op byte, mac []byte, yiaddr netip.Addr, msgType byte, hostname string, extraOpts ...byte) []byte {
+	sport, dport := uint16(68), uint16(67)
+	if op == 2 {
+		sport, dport = 67, 68
+	}
+	udp := udpDatagram(sport, dport, dhcpMessage(op, mac, yiaddr, msgType, hostname, extraOpts...))
+	return ethernetFrame(broadcastMAC, mac, 0x0800, ipv4Packet(netip.IPv4Unspecified(), netip.MustParseAddr("255.255.255.255"), 17, udp))
+}
+
+func mdnsFrame(t *testing.T, mac []byte, ip netip.Addr, name string) []byte {
+	t.Helper()
+	msg := &dns.Msg{}
+	msg.Response = true
+	msg.Authoritative = true
+	msg.Answer = []dns.RR{
+		&dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 120}, A: net.IP(ip.AsSlice())},
+		&dns.A{Hdr: dns.RR_Header{Name: "someone-else.local.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 120}, A: net.IPv4(10, 9, 9, 9)},
+	}
+	payload, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("pack mdns: %v", err)
+	}
+	udp := udpDatagram(5353, 5353, payload)
+	return ethernetFrame([]byte{0x01, 0x00, 0x5e, 0x00, 0x00, 0xfb}, mac, 0x0800, ipv4Packet(ip, netip.MustParseAddr("224.0.0.251"), 17, udp))
+}
+
+func nbnsRegistrationFrame(mac []byte, ip netip.Addr, name string, suffix byte, groupName bool) []byte {
+	p := make([]byte, 12)
+	binary.BigEndian.PutUint16(p[0:2], 0x1234)
+	binary.BigEndian.PutUint16(p[2:4], 5<<11|0x0110) // registration, RD, broadcast
+	binary.BigEndian.PutUint16(p[4:6], 1)
+	binary.BigEndian.PutUint16(p[10:12], 1)
+
+	var raw [16]byte
+	copy(raw[:], []byte(name + "                ")[:15])
+	raw[15] = suffix
+	p = append(p, 0x20)
+	for _, b := range raw {
+		p = append(p, 'A'+b>>4, 'A'+b&0x0f)
+	}
+	p = append(p, 0, 0, 0x20, 0, 1)
+	// Additional record: pointer to the question name, NB, IN, TTL, RDLENGTH 6, NB_FLAGS + address.
+	p = append(p, 0xc0, 0x0c, 0, 0x20, 0, 1, 0, 0, 0x0e, 0x10, 0, 6)
+	nbFlags := uint16(0)
+	if groupName {
+		nbFlags = 0x8000
+	}
+	p = binary.BigEndian.AppendUint16(p, nbFlags)
+	a := ip.As4()
+	p = append(p, a[:]...)
+
+	udp := udpDatagram(137, 137, p)
+	return ethernetFrame(broadcastMAC, mac, 0x0800, ipv4Packet(ip, netip.MustParseAddr("10.1.0.255"), 17, udp))
+}
+
+func synAckFrame(srcMAC []byte, src netip.Addr, sport uint16, dst netip.Addr) []byte {
+	tcp := make([]byte, 20)
+	binary.BigEndian.PutUint16(tcp[0:2], sport)
+	binary.BigEndian.PutUint16(tcp[2:4], 51000)
+	tcp[12] = 5 << 4
+	tcp[13] = 0x12
+	return ethernetFrame(testHostMAC, srcMAC, 0x0800, ipv4Packet(src, dst, 6, tcp))
+}
+
+func lldpFrame() []byte {
+	tlv := func(typ byte, v []byte) []byte {
+		h := uint16(typ)<<9 | uint16(len(v))
+		return append([]byte{byte(h >> 8), byte(h)}, v...)
+	}
+	var p []byte
+	p = append(p, tlv(1, append([]byte{4}, testSwitchMAC...))...)
+	p = append(p, tlv(2, append([]byte{5}, "Gi1/0/24"...))...)
+	p = append(p, tlv(3, []byte{0, 120})...)
+	p = append(p, tlv(4, []byte("uplink to desk"))...)
+	p = append(p, tlv(5, []byte("access-sw1.example.net"))...)
+	p = append(p, tlv(6, []byte("Cisco IOS Software, C2960X"))...)
+	p = append(p, tlv(8, []byte{5, 1, 10, 1, 0, 2, 2, 0, 0, 0, 1, 0})...)
+	p = append(p, 0, 0)
+	portMAC := []byte{0x00, 0x1b, 0x54, 0x00, 0x00, 0x18}
+	return ethernetFrame([]byte{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e}, portMAC, 0x88cc, p)
+}
+
+func cdpFrame() []byte {
+	tlv := func(typ uint16, v []byte) []byte {
+		out := binary.BigEndian.AppendUint16(nil, typ)
+		out = binary.BigEndian.AppendUint16(out, uint16(4+len(v)))
+		return append(out, v...)
+	}
+	addrs := []byte{0, 0, 0, 1, 1, 1, 0xcc, 0, 4, 10, 1, 0, 3}
+	body := []byte{0xaa, 0xaa, 0x03, 0, 0, 0x0c, 0x20, 0x00, 2, 180, 0, 0}
+	body = append(body, tlv(0x0001, []byte("core-rtr1"))...)
+	body = append(body, tlv(0x0002, addrs)...)
+	body = append(body, tlv(0x0003, []byte("GigabitEthernet0/1"))...)
+	body = append(body, tlv(0x0006, []byte("cisco ISR4331"))...)
+	frame := append(append([]byte{0x01, 0x00, 0x0c, 0xcc, 0xcc, 0xcc}, 0x00, 0x1b, 0x54, 0x00, 0x00, 0x99), byte(len(body)>>8), byte(len(body)))
+	return append(frame, body...)
+}
+
+func testFrames(t *testing.T) [][]byte {
+	hostIP := netip.MustParseAddr("10.1.0.20")
+	phoneIP := netip.MustParseAddr("10.1.0.31")
+	return [][]byte{
+		arpReply(testHostMAC, hostIP),
+		dhcpFrame(1, testPhoneMAC, netip.Addr{}, 3, "pixel-7", 61, 9, 0xff, 0, 0, 0, 1, 0, 4, 0xbe, 0xef),
+		dhcpFrame(2, testPhoneMAC, phoneIP, 5, ""),
+		mdnsFrame(t, testHostMAC, hostIP, "studio-mac.local."),
+		nbnsRegistrationFrame(testHostMAC, hostIP, "STUDIO", 0x00, false),
+		nbnsRegistrationFrame(testHostMAC, hostIP, "WORKGROUP", 0x00, true),
+		synAckFrame(testSwitchMAC, netip.MustParseAddr("10.2.0.5"), 443, hostIP),
+		synAckFrame(testSwitchMAC, netip.MustParseAddr("10.2.0.5"), 443, hostIP),
+		lldpFrame(),
+		cdpFrame(),
+	}
+}
+
+var testEpoch = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func buildPcap(frames [][]byte) []byte {
+	var b bytes.Buffer
+	hdr := make([]byte, 24)
+	binary.LittleEndian.PutUint32(hdr[0:4], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(hdr[4:6], 2)
+	binary.LittleEndian.PutUint16(hdr[6:8], 4)
+	binary.LittleEndian.PutUint32(hdr[16:20], 65535)
+	binary.LittleEndian.PutUint32(hdr[20:24], linkTypeEthernet)
+	b.Write(hdr)
+	for i, f := range frames {
+		rec := make([]byte, 16)
+		binary.LittleEndian.PutUint32(rec[0:4], uint32(testEpoch.Unix())+uint32(i))
+		binary.LittleEndian.PutUint32(rec[4:8], 250000)
+		binary.LittleEndian.PutUint32(rec[8:12], uint32(len(f)))
+		binary.LittleEndian.PutUint32(rec[12:16], uint32(len(f)))
+		b.Write(rec)
+		b.Write(f)
+	}
+	return b.Bytes()
+}
+
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+func pcapngBlock(order byteOrder, typ uint32, body []byte) []byte {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	total := uint32(12 + len(body))
+	out := order.AppendUint32(nil, typ)
+	out = order.AppendUint32(out, total)
+	out = append(out, body...)
+	return order.AppendUint32(out, total)
+}
+
+func buildPcapNG(order byteOrder, frames [][]byte) []byte {
+	var b bytes.Buffer
+	shb := order.AppendUint32(nil, 0x1a2b3c4d)
+	shb = order.AppendUint16(shb, 1)
+	shb = order.AppendUint16(shb, 0)
+	shb = append(shb, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	b.Write(pcapngBlock(order, pcapngBlockSHB, shb))
+
+	// A non-Ethernet interface first, so packets must be routed by interface id.
+	idb0 := order.AppendUint16(nil, 147)
+	idb0 = append(idb0, 0, 0, 0, 0, 0, 0)
+	b.Write(pcapngBlock(order, pcapngBlockIDB, idb0))
+	idb1 := order.AppendUint16(nil, linkTypeEthernet)
+	idb1 = append(idb1, 0, 0, 0, 0, 0, 0)
+	idb1 = order.AppendUint16(idb1, 9)
+	idb1 = order.AppendUint16(idb1, 1)
+	idb1 = append(idb1, 9, 0, 0, 0) // if_tsresol = nanoseconds
+	idb1 = append(idb1, 0, 0, 0, 0)
+	b.Write(pcapngBlock(order, pcapngBlockIDB, idb1))
+
+	// Blocks the reader must skip.
+	b.Write(pcapngBlock(order, 0x00000005, []byte{0, 0, 0, 0}))
+
+	for i, f := range frames {
+		ts := uint64(testEpoch.Add(time.Duration(i) * time.Second).UnixNano())
+		epb := order.AppendUint32(nil, 1)
+		epb = order.AppendUint32(epb, uint32(ts>>32))
+		epb = order.AppendUint32(epb, uint32(ts))
+		epb = order.AppendUint32(epb, uint32(len(f)))
+		epb = order.AppendUint32(epb, uint32(len(f)))
+		epb = append(epb, f...)
+		b.Write(pcapngBlock(order, pcapngBlockEPB, epb))
+	}
+	return b.Bytes()
+}
+
+func TestRead_ExtractsPassiveEvidence(t *testing.T) {
+	frames := testFrames(t)
+	cases := []struct {
+		name   string
+		data   []byte
+		format string
+	}{
+		{name: "pcap", data: buildPcap(frames), format: FormatPcap},
+		{name: "pcapng little endian", data: buildPcapNG(binary.LittleEndian, frames), format: FormatPcapNG},
+		{name: "pcapng big endian", data: buildPcapNG(binary.BigEndian, frames), format: FormatPcapNG},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := Read(bytes.NewReader(tc.data), Config{})
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if res.Format != tc.format || res.Stats.Packets != len(frames) || res.Stats.Truncated {
+				t.Fatalf("unexpected format/stats %s %+v", res.Format, res.Stats)
+			}
+			if !res.Stats.FirstPacket.Equal(testEpoch) && !res.Stats.FirstPacket.Equal(testEpoch.Add(250*time.Millisecond)) {
+				t.Fatalf("unexpected first packet time %v", res.Stats.FirstPacket)
+			}
+			st := res.Stats
+			if st.ARP != 1 || st.DHCP != 2 || st.MDNS != 1 || st.NetBIOS != 2 || st.SYNACK != 2 || st.LLDP != 1 || st.CDP != 1 {
+				t.Fatalf("unexpected protocol counters %+v", st)
+			}
+
+			if len(res.Hosts) != 3 {
+				t.Fatalf("expected 3 hosts, got %+v", res.Hosts)
+			}
+			host := res.Hosts[0]
+			if host.IP != netip.MustParseAddr("10.1.0.20") || host.MAC != "00:11:22:33:44:55" {
+				t.Fatalf("unexpected first host %+v", host)
+			}
+			if len(host.Names) != 2 || host.Names[0] != (Name{Name: "studio-mac.local", Source: "mdns"}) || host.Names[1] != (Name{Name: "STUDIO", Source: "netbios"}) {
+				t.Fatalf("unexpected names %+v", host.Names)
+			}
+			phone := res.Hosts[1]
+			if phone.IP != netip.MustParseAddr("10.1.0.31") || phone.MAC != "02:aa:bb:cc:dd:01" || len(phone.Names) != 1 || phone.Names[0].Name != "pixel-7" {
+				t.Fatalf("expected DHCP request name folded into ACK binding, got %+v", phone)
+			}
+			if phone.ClientID != "ff:00:00:00:01:00:04:be:ef" || host.ClientID != "" {
+				t.Fatalf("expected the DUID client ID on the phone only, got %q / %q", phone.ClientID, host.ClientID)
+			}
+			server := res.Hosts[2]
+			if server.IP != netip.MustParseAddr("10.2.0.5") || server.MAC != "" || len(server.TCPPorts) != 1 || server.TCPPorts[0] != 443 {
+				t.Fatalf("unexpected syn-ack host %+v", server)
+			}
+
+			if len(res.Neighbors) != 2 {
+				t.Fatalf("expected 2 neighbors, got %+v", res.Neighbors)
+			}
+			lldp := res.Neighbors[0]
+			if lldp.Protocol != "lldp" || lldp.ChassisMAC != "00:1b:54:00:00:01" || lldp.SystemName != "access-sw1.example.net" || lldp.PortID != "Gi1/0/24" || lldp.MgmtIP != netip.MustParseAddr("10.1.0.2") {
+				t.Fatalf("unexpected lldp neighbor %+v", lldp)
+			}
+			cdp := res.Neighbors[1]
+			if cdp.Protocol != "cdp" || cdp.SystemName != "core-rtr1" || cdp.PortID != "GigabitEthernet0/1" || cdp.Platform != "cisco ISR4331" || cdp.MgmtIP != netip.MustParseAddr("10.1.0.3") || cdp.SourceMAC != "00:1b:54:00:00:99" {
+				t.Fatalf("unexpected cdp neighbor %+v", cdp)
+			}
+		})
+	}
+}
+
+func TestRead_TruncatedCaptureKeepsEvidence(t *testing.T) {
+	data := buildPcap(testFrames(t))
+	res, err := Read(bytes.NewReader(data[:len(data)-7]), Config{})
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !res.Stats.Truncated || res.Stats.Packets != 9 || res.Stats.LLDP != 1 || res.Stats.CDP != 0 {
+		t.Fatalf("unexpected stats %+v", res.Stats)
+	}
+}
+
+func TestRead_HostCap(t *testing.T) {
+	var frames [][]byte
+	for i := 1; i <= 5; i++ {
+		frames = append(frames, arpReply([]byte{0x00, 0x11, 0x22, 0x33, 0x44, byte(i)}, netip.AddrFrom4([4]byte{10, 0, 0, byte(i)})))
+	}
+	res, err := Read(bytes.NewReader(buildPcap(frames)), Config{MaxHosts: 3})
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(res.Hosts) != 3 || res.Stats.HostsDropped != 2 {
+		t.Fatalf("expected host cap to apply, got %d hosts, stats %+v", len(res.Hosts), res.Stats)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		magic []byte
+		want  string
+		ok    bool
+	}{
+		{magic: []byte{0xd4, 0xc3, 0xb2, 0xa1}, want: FormatPcap, ok: true},
+		{magic: []byte{0xa1, 0xb2, 0xc3, 0xd4}, want: FormatPcap, ok: true},
+		{magic: []byte{0x4d, 0x3c, 0xb2, 0xa1}, want: FormatPcap, ok: true},
+		{magic: []byte{0x0a, 0x0d, 0x0d, 0x0a}, want: FormatPcapNG, ok: true},
+		{magic: []byte("<?xm"), ok: false},
+		{magic: []byte{0xd4}, ok: false},
+	}
+	for _, tc := range cases {
+		got, ok := Detect(tc.magic)
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("Detect(% x) = %q, %v; want %q, %v", tc.magic, got, ok, tc.want, tc.ok)
+		}
+	}
+	if _, err := Read(bytes.NewReader([]byte("not a capture at all")), Config{}); err != ErrUnknownFormat {
+		t.Fatalf("expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestDHCPClientID(t *testing.T) {
+	chaddr := []byte{0x02, 0xaa, 0xbb, 0xcc, 0xdd, 0x01}
+	cases := []struct {
+		name string
+		opt  []byte
+		want string
+	}{
+		{name: "hardware type repeating chaddr", opt: append([]byte{1}, chaddr...), want: ""},
+		{name: "hardware type with another mac", opt: []byte{1, 0x3c, 0x22, 0xfb, 0x01, 0x02, 0x03}, want: "01:3c:22:fb:01:02:03"},
+		{name: "duid", opt: []byte{0xff, 0, 0, 0, 1, 0, 4, 0xbe, 0xef}, want: "ff:00:00:00:01:00:04:be:ef"},
+		{name: "too short", opt: []byte{0}, want: ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := dhcpClientID(tc.opt, chaddr); got != tc.want {
+				t.Fatalf("dhcpClientID = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
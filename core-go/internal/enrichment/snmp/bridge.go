@@ -0,0 +1,185 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+const (
+	oidDot1dBaseBridgeAddress0 = "1.3.6.1.2.1.17.1.1.0"
+	oidDot1dStpPriority0       = "1.3.6.1.2.1.17.2.2.0"
+	oidDot1dStpDesignatedRoot0 = "1.3.6.1.2.1.17.2.5.0"
+	oidDot1dStpRootCost0       = "1.3.6.1.2.1.17.2.6.0"
+	oidDot1dStpRootPort0       = "1.3.6.1.2.1.17.2.7.0"
+	oidDot1dStpPortState       = "1.3.6.1.2.1.17.2.15.1.3"
+	oidDot1dStpPortPathCost    = "1.3.6.1.2.1.17.2.15.1.5"
+
+	// IEEE8023-LAG-MIB dot3adAggPortAttachedAggID (index: member ifIndex -> value: aggregator ifIndex).
+	oidDot3adAggPortAttachedAggID = "1.2.840.10006.300.43.1.2.1.1.13"
+)
+
+// STP port states as named by BRIDGE-MIB dot1dStpPortState.
+var stpPortStates = map[int]string{
+	1: "disabled",
+	2: "blocking",
+	3: "listening",
+	4: "learning",
+	5: "forwarding",
+	6: "broken",
+}
+
+// STPInfo is the spanning tree view of one bridge. BRIDGE-MIB exposes a single (common) spanning tree
+// instance; per-VLAN instances are not collected.
+type STPInfo struct {
+	BridgeAddress *string
+	Priority      *int
+	RootAddress   *string
+	RootPriority  *int
+	RootCost      *int
+	RootIfIndex   *int
+	Ports         map[int]STPPort // keyed by ifIndex
+}
+
+// STPPort is one bridge port's spanning tree state.
+type STPPort struct {
+	State    string
+	PathCost *int
+}
+
+// parseBridgeID splits an 8-byte bridge identifier (2-byte priority + MAC) into its parts.
+func parseBridgeID(b []byte) (int, string, bool) {
+	if len(b) != 8 {
+		return 0, "", false
+	}
+	mac := strings.ToLower(net.HardwareAddr(b[2:]).String())
+	if mac == "00:00:00:00:00:00" {
+		return 0, "", false
+	}
+	return int(b[0])<<8 | int(b[1]), mac, true
+}
+
+// WalkSTP reads the bridge's spanning tree scalars and per-port states. Bridges without STP (or without
+// BRIDGE-MIB) return an empty STPInfo.
+func (c *Client) WalkSTP(ctx context.Context, target Target) (STPInfo, error) {
+	if c == nil {
+		return STPInfo{}, errors.New("snmp client is nil")
+	}
+	_ = ctx
+
+	s, err := c.connect(target)
+	if err != nil {
+		return STPInfo{}, err
+	}
+	defer s.Conn.Close()
+
+	portIfIndex := map[int]int{}
+	basePorts, err := s.BulkWalkAll(oidDot1dBasePortIfIndex)
+	if err != nil {
+		return STPInfo{}, err
+	}
+	for _, p := range basePorts {
+		port, ok := lastOIDIndexInt(p.Name)
+		if !ok {
+			continue
+		}
+		if v, ok := pduInt32(p); ok && v != nil && *v > 0 {
+			portIfIndex[port] = int(*v)
+		}
+	}
+
+	var out STPInfo
+	pkt, err := s.Get([]string{oidDot1dBaseBridgeAddress0, oidDot1dStpPriority0, oidDot1dStpDesignatedRoot0, oidDot1dStpRootCost0, oidDot1dStpRootPort0})
+	if err != nil {
+		return STPInfo{}, err
+	}
+	for _, v := range pkt.Variables {
+		switch strings.TrimPrefix(v.Name, ".") {
+		case oidDot1dBaseBridgeAddress0:
+			out.BridgeAddress, _ = pduMAC(v)
+		case oidDot1dStpPriority0:
+			if n, ok := pduInt32(v); ok && n != nil {
+				p := int(*n)
+				out.Priority = &p
+			}
+		case oidDot1dStpDesignatedRoot0:
+			if b, ok := pduBytes(v); ok {
+				if prio, mac, ok := parseBridgeID(b); ok {
+					out.RootPriority = &prio
+					out.RootAddress = &mac
+				}
+			}
+		case oidDot1dStpRootCost0:
+			if n, ok := pduInt32(v); ok && n != nil {
+				cost := int(*n)
+				out.RootCost = &cost
+			}
+		case oidDot1dStpRootPort0:
+			if n, ok := pduInt32(v); ok && n != nil {
+				if ifIndex, ok := portIfIndex[int(*n)]; ok {
+					out.RootIfIndex = &ifIndex
+				}
+			}
+		}
+	}
+
+	states, err := s.BulkWalkAll(oidDot1dStpPortState)
+	if err != nil || len(states) == 0 {
+		return out, nil
+	}
+	costs := map[int]int{}
+	if pdus, err := s.BulkWalkAll(oidDot1dStpPortPathCost); err == nil {
+		for _, p := range pdus {
+			port, ok := lastOIDIndexInt(p.Name)
+			if !ok {
+				continue
+			}
+			if v, ok := pduInt32(p); ok && v != nil {
+				costs[port] = int(*v)
+			}
+		}
+	}
+	out.Ports = make(map[int]STPPort, len(states))
+	for _, p := range states {
+		port, ok := lastOIDIndexInt(p.Name)
+		if !ok {
+			continue
+		}
+		ifIndex, ok := portIfIndex[port]
+		if !ok {
+			continue
+		}
+		v, ok := pduInt32(p)
+		if !ok || v == nil {
+			continue
+		}
+		state, ok := stpPortStates[int(*v)]
+		if !ok {
+			continue
+		}
+		sp := STPPort{State: state}
+		if cost, ok := costs[port]; ok {
+			sp.PathCost = &cost
+		}
+		out.Ports[ifIndex] = sp
+	}
+	return out, nil
+}
+
+// WalkLAGMembership maps each aggregated member port (ifIndex) to its aggregator's ifIndex using
+// IEEE8023-LAG-MIB. Ports not attached to an aggregator (value 0) are omitted.
+func (c *Client) WalkLAGMembership(ctx context.Context, target Target) (map[int]int, error) {
+	members, err := c.WalkIntTable(ctx, target, oidDot3adAggPortAttachedAggID)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int]int, len(members))
+	for member, agg := range members {
+		if agg <= 0 || agg == member {
+			continue
+		}
+		out[member] = agg
+	}
+	return out, nil
+}
@@ -0,0 +1,26 @@
+package snmp
+
+import "testing"
+
+func TestParseBridgeID(t *testing.T) {
+	cases := []struct {
+		name     string
+		in       []byte
+		priority int
+		mac      string
+		ok       bool
+	}{
+		{name: "default priority", in: []byte{0x80, 0x00, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55}, priority: 32768, mac: "00:11:22:33:44:55", ok: true},
+		{name: "priority with vlan extension", in: []byte{0x10, 0x01, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}, priority: 4097, mac: "aa:bb:cc:dd:ee:ff", ok: true},
+		{name: "zero mac", in: []byte{0x80, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "short", in: []byte{0x80, 0x00, 0x11}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			priority, mac, ok := parseBridgeID(tc.in)
+			if ok != tc.ok || priority != tc.priority || mac != tc.mac {
+				t.Fatalf("expected (%d, %q, %v), got (%d, %q, %v)", tc.priority, tc.mac, tc.ok, priority, mac, ok)
+			}
+		})
+	}
+}
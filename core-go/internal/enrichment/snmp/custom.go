@@ -0,0 +1,292 @@
+package snmp
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// Custom OID kinds.
+const (
+	CustomOIDScalar = "scalar"
+	CustomOIDColumn = "column"
+)
+
+// Custom OID type hints: how a polled value is rendered (and whether it gets a numeric value).
+const (
+	CustomTypeString    = "string"
+	CustomTypeInteger   = "integer"
+	CustomTypeGauge     = "gauge"
+	CustomTypeCounter   = "counter"
+	CustomTypeTimeticks = "timeticks"
+	CustomTypeHex       = "hex"
+	CustomTypeOID       = "oid"
+)
+
+var customTypes = []string{
+	CustomTypeString,
+	CustomTypeInteger,
+	CustomTypeGauge,
+	CustomTypeCounter,
+	CustomTypeTimeticks,
+	CustomTypeHex,
+	CustomTypeOID,
+}
+
+// maxCustomColumnRows caps the rows kept per walked column so one large table cannot flood the facts.
+const maxCustomColumnRows = 256
+
+// CustomTypes lists the supported type hints.
+func CustomTypes() []string {
+	out := make([]string, len(customTypes))
+	copy(out, customTypes)
+	return out
+}
+
+// IsCustomType reports whether hint is a supported type hint.
+func IsCustomType(hint string) bool {
+	for _, t := range customTypes {
+		if t == hint {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeOID trims whitespace and a leading dot and reports whether the result is a numeric dotted OID.
+func NormalizeOID(oid string) (string, bool) {
+	oid = strings.TrimPrefix(strings.TrimSpace(oid), ".")
+	parts := strings.Split(oid, ".")
+	if len(parts) < 2 {
+		return "", false
+	}
+	for _, p := range parts {
+		if p == "" {
+			return "", false
+		}
+		if _, err := strconv.ParseUint(p, 10, 32); err != nil {
+			return "", false
+		}
+	}
+	return oid, true
+}
+
+// CustomOID is one admin-defined item of a polling profile.
+type CustomOID struct {
+	Key   string
+	Label string
+	OID   string
+	Kind  string // CustomOIDScalar | CustomOIDColumn
+	Type  string // one of CustomTypes()
+}
+
+// CustomValue is one polled value. Instance is the row index below the column OID ("" for scalars).
+type CustomValue struct {
+	Key      string
+	Label    string
+	Instance string
+	OID      string
+	Type     string
+	Value    string
+	Numeric  *float64
+}
+
+// PollCustomOIDs reads the scalars with GET and walks the columns of a profile. OIDs the agent does not
+// implement are skipped; an error is only returned when the agent could not be polled at all.
+func (c *Client) PollCustomOIDs(ctx context.Context, target Target, items []CustomOID) ([]CustomValue, error) {
+	if c == nil {
+		return nil, errors.New("snmp client is nil")
+	}
+	_ = ctx
+
+	s, err := c.connect(target)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Conn.Close()
+
+	var out []CustomValue
+	var firstErr error
+	answered := false
+
+	scalars := make(map[string]CustomOID)
+	var scalarOIDs []string
+	for _, item := range items {
+		oid, ok := NormalizeOID(item.OID)
+		if !ok {
+			continue
+		}
+		item.OID = oid
+		switch item.Kind {
+		case CustomOIDColumn:
+			pdus, err := s.BulkWalkAll(oid)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			answered = true
+			rows := 0
+			for _, p := range pdus {
+				if rows >= maxCustomColumnRows {
+					break
+				}
+				instance, ok := customInstance(oid, p.Name)
+				if !ok {
+					continue
+				}
+				if v, ok := customValue(item, p); ok {
+					v.Instance = instance
+					v.OID = oid + "." + instance
+					out = append(out, v)
+					rows++
+				}
+			}
+		default:
+			if _, dup := scalars[oid]; !dup {
+				scalarOIDs = append(scalarOIDs, oid)
+			}
+			scalars[oid] = item
+		}
+	}
+
+	for start := 0; start < len(scalarOIDs); start += gosnmp.MaxOids {
+		end := min(start+gosnmp.MaxOids, len(scalarOIDs))
+		pkt, err := s.Get(scalarOIDs[start:end])
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		answered = true
+		for _, p := range pkt.Variables {
+			oid := strings.TrimPrefix(p.Name, ".")
+			item, ok := scalars[oid]
+			if !ok {
+				continue
+			}
+			if v, ok := customValue(item, p); ok {
+				v.OID = oid
+				out = append(out, v)
+			}
+		}
+	}
+
+	if !answered && firstErr != nil {
+		return nil, firstErr
+	}
+	return out, nil
+}
+
+// customInstance returns the row index of a walked PDU below columnOID.
+func customInstance(columnOID, name string) (string, bool) {
+	name = strings.TrimPrefix(name, ".")
+	instance, ok := strings.CutPrefix(name, columnOID+".")
+	if !ok || instance == "" {
+		return "", false
+	}
+	return instance, true
+}
+
+// customValue renders a PDU according to the item's type hint. Exceptions (noSuchObject, noSuchInstance,
+// endOfMibView) and NULLs yield no value.
+func customValue(item CustomOID, p gosnmp.SnmpPDU) (CustomValue, bool) {
+	switch p.Type {
+	case gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView, gosnmp.Null:
+		return CustomValue{}, false
+	}
+	out := CustomValue{Key: item.Key, Label: item.Label, Type: item.Type}
+
+	number := func() (*big.Int, bool) {
+		switch p.Type {
+		case gosnmp.Integer, gosnmp.Counter32, gosnmp.Gauge32, gosnmp.TimeTicks, gosnmp.Counter64, gosnmp.Uinteger32:
+			return gosnmp.ToBigInt(p.Value), true
+		}
+		if s, ok := pduText(p); ok {
+			if n, ok := new(big.Int).SetString(strings.TrimSpace(s), 10); ok {
+				return n, true
+			}
+		}
+		return nil, false
+	}
+
+	switch item.Type {
+	case CustomTypeInteger, CustomTypeGauge, CustomTypeCounter:
+		n, ok := number()
+		if !ok {
+			return CustomValue{}, false
+		}
+		f, _ := new(big.Float).SetInt(n).Float64()
+		out.Value = n.String()
+		out.Numeric = &f
+	case CustomTypeTimeticks:
+		n, ok := number()
+		if !ok || !n.IsInt64() {
+			return CustomValue{}, false
+		}
+		seconds := float64(n.Int64()) / 100
+		out.Value = (time.Duration(n.Int64()) * 10 * time.Millisecond).String()
+		out.Numeric = &seconds
+	case CustomTypeHex:
+		b, ok := pduBytes(p)
+		if !ok {
+			return CustomValue{}, false
+		}
+		out.Value = hex.EncodeToString(b)
+	case CustomTypeOID:
+		s, ok := p.Value.(string)
+		if !ok {
+			return CustomValue{}, false
+		}
+		out.Value = strings.TrimPrefix(s, ".")
+	default:
+		out.Type = CustomTypeString
+		if n, ok := number(); ok && p.Type != gosnmp.OctetString {
+			out.Value = n.String()
+			break
+		}
+		if s, ok := pduText(p); ok {
+			out.Value = s
+			break
+		}
+		if b, ok := pduBytes(p); ok {
+			out.Value = hex.EncodeToString(b)
+			break
+		}
+		return CustomValue{}, false
+	}
+	return out, true
+}
+
+// pduText returns printable string content; binary octet strings (MACs, bitmaps) are rejected.
+func pduText(p gosnmp.SnmpPDU) (string, bool) {
+	var s string
+	switch v := p.Value.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return "", false
+	}
+	s = strings.TrimRight(s, "\x00")
+	if !utf8.ValidString(s) {
+		return "", false
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return "", false
+		}
+	}
+	return strings.TrimSpace(s), true
+}
@@ -0,0 +1,85 @@
+package snmp
+
+import (
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+func TestNormalizeOID(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"1.3.6.1.2.1.43.11.1.1.9", "1.3.6.1.2.1.43.11.1.1.9", true},
+		{" .1.3.6.1.2.1.33.1.2.4.0 ", "1.3.6.1.2.1.33.1.2.4.0", true},
+		{"1", "", false},
+		{"1.3..6", "", false},
+		{"1.3.6.x", "", false},
+		{"iso.3.6", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := NormalizeOID(tc.in)
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("NormalizeOID(%q) = %q, %v; want %q, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestCustomInstance(t *testing.T) {
+	column := "1.3.6.1.2.1.43.11.1.1.9"
+	if got, ok := customInstance(column, ".1.3.6.1.2.1.43.11.1.1.9.1.3"); !ok || got != "1.3" {
+		t.Fatalf("expected instance 1.3, got %q %v", got, ok)
+	}
+	if _, ok := customInstance(column, ".1.3.6.1.2.1.43.11.1.1.90.1"); ok {
+		t.Fatalf("expected sibling column to be rejected")
+	}
+	if _, ok := customInstance(column, ".1.3.6.1.2.1.43.11.1.1.9"); ok {
+		t.Fatalf("expected column without index to be rejected")
+	}
+}
+
+func TestCustomValue(t *testing.T) {
+	cases := []struct {
+		name    string
+		hint    string
+		pdu     gosnmp.SnmpPDU
+		ok      bool
+		value   string
+		numeric *float64
+	}{
+		{"gauge", CustomTypeGauge, gosnmp.SnmpPDU{Type: gosnmp.Gauge32, Value: uint(87)}, true, "87", ptrFloat(87)},
+		{"integer negative", CustomTypeInteger, gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: -3}, true, "-3", ptrFloat(-3)},
+		{"counter64", CustomTypeCounter, gosnmp.SnmpPDU{Type: gosnmp.Counter64, Value: uint64(1) << 40}, true, "1099511627776", ptrFloat(1099511627776)},
+		{"integer from text", CustomTypeInteger, gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte(" 42 ")}, true, "42", ptrFloat(42)},
+		{"integer from text fails", CustomTypeInteger, gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte("n/a")}, false, "", nil},
+		{"timeticks", CustomTypeTimeticks, gosnmp.SnmpPDU{Type: gosnmp.TimeTicks, Value: uint32(360000)}, true, "1h0m0s", ptrFloat(3600)},
+		{"string", CustomTypeString, gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte("Black Toner\x00")}, true, "Black Toner", nil},
+		{"string from integer", "", gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: 2}, true, "2", nil},
+		{"binary string falls back to hex", CustomTypeString, gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte{0x00, 0x1b, 0xff}}, true, "001bff", nil},
+		{"hex", CustomTypeHex, gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte{0xde, 0xad}}, true, "dead", nil},
+		{"oid", CustomTypeOID, gosnmp.SnmpPDU{Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1.9"}, true, "1.3.6.1.4.1.9", nil},
+		{"no such instance", CustomTypeGauge, gosnmp.SnmpPDU{Type: gosnmp.NoSuchInstance}, false, "", nil},
+		{"null", CustomTypeString, gosnmp.SnmpPDU{Type: gosnmp.Null}, false, "", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := customValue(CustomOID{Key: "k", Type: tc.hint}, tc.pdu)
+			if ok != tc.ok {
+				t.Fatalf("expected ok=%v, got %v (%+v)", tc.ok, ok, got)
+			}
+			if !ok {
+				return
+			}
+			if got.Value != tc.value {
+				t.Fatalf("expected value %q, got %q", tc.value, got.Value)
+			}
+			if (tc.numeric == nil) != (got.Numeric == nil) || (tc.numeric != nil && *tc.numeric != *got.Numeric) {
+				t.Fatalf("expected numeric %v, got %v", tc.numeric, got.Numeric)
+			}
+		})
+	}
+}
+
+func ptrFloat(v float64) *float64 { return &v }
@@ -0,0 +1,182 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+const (
+	oidDot1dBasePortIfIndex = "1.3.6.1.2.1.17.1.4.1.2"
+
+	// Q-BRIDGE-MIB dot1qTpFdbTable (indexed by fdbId + MAC), preferred on VLAN-aware switches.
+	oidDot1qTpFdbPort   = "1.3.6.1.2.1.17.7.1.2.2.1.2"
+	oidDot1qTpFdbStatus = "1.3.6.1.2.1.17.7.1.2.2.1.3"
+
+	// BRIDGE-MIB dot1dTpFdbTable (indexed by MAC), the fallback for single-FDB bridges.
+	oidDot1dTpFdbPort   = "1.3.6.1.2.1.17.4.3.1.2"
+	oidDot1dTpFdbStatus = "1.3.6.1.2.1.17.4.3.1.3"
+
+	// IP-MIB ipNetToMediaPhysAddress (indexed by ifIndex + IPv4 address).
+	oidIPNetToMediaPhysAddress = "1.3.6.1.2.1.4.22.1.2"
+
+	// dot1dTpFdbStatus / dot1qTpFdbStatus values.
+	fdbStatusLearned = 3
+)
+
+// FDBEntry is one MAC address a bridge has learned on a port.
+type FDBEntry struct {
+	MAC     string
+	IfIndex int
+}
+
+// fdbIndexMAC extracts the MAC address from the trailing six sub-identifiers of an FDB table OID.
+func fdbIndexMAC(oid string) (string, bool) {
+	ints, ok := lastOIDInts(oid, 6)
+	if !ok {
+		return "", false
+	}
+	parts := make([]string, 0, 6)
+	for _, v := range ints {
+		if v < 0 || v > 255 {
+			return "", false
+		}
+		parts = append(parts, fmt.Sprintf("%02x", v))
+	}
+	mac := strings.Join(parts, ":")
+	if mac == "00:00:00:00:00:00" {
+		return "", false
+	}
+	return mac, true
+}
+
+// fdbIndexKey is the FDB row index (everything after the column OID), shared by the port and status columns.
+func fdbIndexKey(columnOID, oid string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(oid), "."), columnOID+".")
+}
+
+// arpIndexIP extracts the IPv4 address from the trailing four sub-identifiers of an ipNetToMedia OID.
+func arpIndexIP(oid string) (string, bool) {
+	ints, ok := lastOIDInts(oid, 4)
+	if !ok {
+		return "", false
+	}
+	var b [4]byte
+	for i, v := range ints {
+		if v < 0 || v > 255 {
+			return "", false
+		}
+		b[i] = byte(v)
+	}
+	return netip.AddrFrom4(b).String(), true
+}
+
+// WalkBridgeFDB returns the learned entries of the bridge forwarding database, mapped from bridge port to
+// ifIndex. The Q-BRIDGE table is tried first; the BRIDGE-MIB table is used when it is empty. Static, self
+// and management entries are skipped, as are rows whose bridge port has no ifIndex.
+func (c *Client) WalkBridgeFDB(ctx context.Context, target Target) ([]FDBEntry, error) {
+	if c == nil {
+		return nil, errors.New("snmp client is nil")
+	}
+	_ = ctx
+
+	s, err := c.connect(target)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Conn.Close()
+
+	portIfIndex := map[int]int{}
+	basePorts, err := s.BulkWalkAll(oidDot1dBasePortIfIndex)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range basePorts {
+		port, ok := lastOIDIndexInt(p.Name)
+		if !ok {
+			continue
+		}
+		if v, ok := pduInt32(p); ok && v != nil && *v > 0 {
+			portIfIndex[port] = int(*v)
+		}
+	}
+
+	portOID, statusOID := oidDot1qTpFdbPort, oidDot1qTpFdbStatus
+	ports, err := s.BulkWalkAll(portOID)
+	if err != nil || len(ports) == 0 {
+		portOID, statusOID = oidDot1dTpFdbPort, oidDot1dTpFdbStatus
+		ports, err = s.BulkWalkAll(portOID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	status := map[string]int{}
+	if pdus, err := s.BulkWalkAll(statusOID); err == nil {
+		for _, p := range pdus {
+			if v, ok := pduInt32(p); ok && v != nil {
+				status[fdbIndexKey(statusOID, p.Name)] = int(*v)
+			}
+		}
+	}
+
+	seen := map[FDBEntry]bool{}
+	out := make([]FDBEntry, 0, len(ports))
+	for _, p := range ports {
+		if st, ok := status[fdbIndexKey(portOID, p.Name)]; ok && st != fdbStatusLearned {
+			continue
+		}
+		mac, ok := fdbIndexMAC(p.Name)
+		if !ok {
+			continue
+		}
+		port, ok := pduInt32(p)
+		if !ok || port == nil || *port <= 0 {
+			continue
+		}
+		ifIndex, ok := portIfIndex[int(*port)]
+		if !ok {
+			continue
+		}
+		e := FDBEntry{MAC: mac, IfIndex: ifIndex}
+		if seen[e] {
+			continue
+		}
+		seen[e] = true
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// WalkARP returns the device's IPv4 ARP cache as MAC -> IP.
+func (c *Client) WalkARP(ctx context.Context, target Target) (map[string]string, error) {
+	if c == nil {
+		return nil, errors.New("snmp client is nil")
+	}
+	_ = ctx
+
+	s, err := c.connect(target)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Conn.Close()
+
+	pdus, err := s.BulkWalkAll(oidIPNetToMediaPhysAddress)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(pdus))
+	for _, p := range pdus {
+		ip, ok := arpIndexIP(p.Name)
+		if !ok {
+			continue
+		}
+		mac, ok := pduMAC(p)
+		if !ok || mac == nil {
+			continue
+		}
+		out[*mac] = ip
+	}
+	return out, nil
+}
@@ -0,0 +1,32 @@
+package snmp
+
+import "testing"
+
+func TestFDBAndARPIndexParsing(t *testing.T) {
+	cases := []struct {
+		name string
+		fn   func(string) (string, bool)
+		oid  string
+		want string
+		ok   bool
+	}{
+		{name: "dot1q mac", fn: fdbIndexMAC, oid: ".1.3.6.1.2.1.17.7.1.2.2.1.2.10.0.17.34.51.68.85", want: "00:11:22:33:44:55", ok: true},
+		{name: "dot1d mac", fn: fdbIndexMAC, oid: "1.3.6.1.2.1.17.4.3.1.2.170.187.204.221.238.255", want: "aa:bb:cc:dd:ee:ff", ok: true},
+		{name: "zero mac", fn: fdbIndexMAC, oid: "1.3.6.1.2.1.17.4.3.1.2.0.0.0.0.0.0"},
+		{name: "out of range", fn: fdbIndexMAC, oid: "1.3.6.1.2.1.17.4.3.1.2.0.0.0.0.0.300"},
+		{name: "arp ipv4", fn: arpIndexIP, oid: ".1.3.6.1.2.1.4.22.1.2.3.10.1.0.20", want: "10.1.0.20", ok: true},
+		{name: "arp too short", fn: arpIndexIP, oid: "1.2.3"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := tc.fn(tc.oid)
+			if ok != tc.ok || got != tc.want {
+				t.Fatalf("expected (%q, %v), got (%q, %v)", tc.want, tc.ok, got, ok)
+			}
+		})
+	}
+
+	if got := fdbIndexKey(oidDot1qTpFdbPort, "."+oidDot1qTpFdbPort+".10.0.17.34.51.68.85"); got != "10.0.17.34.51.68.85" {
+		t.Fatalf("unexpected fdb index key %q", got)
+	}
+}
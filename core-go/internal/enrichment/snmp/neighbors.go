@@ -14,9 +14,29 @@ type Neighbor struct {
 	LocalIfIndex     *int
 	RemoteDeviceName *string
 	RemotePortName   *string
+	RemotePortDescr  *string
 	RemoteChassisMAC *string
 	RemoteMgmtIP     *string
-	Source           string // "lldp" | "cdp"
+	// RemoteSysDescr is the LLDP system description or the CDP software version string.
+	RemoteSysDescr *string
+	// RemotePlatform is the CDP platform (e.g. "cisco WS-C2960X-48FPD-L") or the LLDP-MED model name.
+	RemotePlatform *string
+	// Capabilities are the enabled system capabilities, normalized across LLDP and CDP:
+	// "repeater", "bridge", "wlan_ap", "router", "telephone", "docsis", "station", "other".
+	Capabilities []string
+	Inventory    *MEDInventory
+	Source       string // "lldp" | "cdp"
+}
+
+// MEDInventory is the LLDP-MED inventory advertised by endpoints such as IP phones and access points.
+type MEDInventory struct {
+	HardwareRev  *string
+	FirmwareRev  *string
+	SoftwareRev  *string
+	SerialNumber *string
+	Manufacturer *string
+	Model        *string
+	AssetID      *string
 }
 
 const (
@@ -24,12 +44,117 @@ const (
 	oidLLDPRemPortID    = "1.0.8802.1.1.2.1.4.1.1.7"
 	oidLLDPRemPortDesc  = "1.0.8802.1.1.2.1.4.1.1.8"
 	oidLLDPRemSysName   = "1.0.8802.1.1.2.1.4.1.1.9"
+	oidLLDPRemSysDesc   = "1.0.8802.1.1.2.1.4.1.1.10"
+	oidLLDPRemSysCapSup = "1.0.8802.1.1.2.1.4.1.1.11"
+	oidLLDPRemSysCapEn  = "1.0.8802.1.1.2.1.4.1.1.12"
+	// lldpRemManAddrIfSubtype; the management address itself is encoded in the row index.
+	oidLLDPRemManAddrIfSubtype = "1.0.8802.1.1.2.1.4.2.1.3"
+	// lldpXMedRemInventoryTable columns 1..7 (hardware, firmware, software rev, serial, mfg, model, asset).
+	oidLLDPMEDRemInventory = "1.0.8802.1.1.2.1.5.4795.1.3.3.1"
 
-	oidCDPCacheAddress    = "1.3.6.1.4.1.9.9.23.1.2.1.1.4"
-	oidCDPCacheDeviceID   = "1.3.6.1.4.1.9.9.23.1.2.1.1.6"
-	oidCDPCacheDevicePort = "1.3.6.1.4.1.9.9.23.1.2.1.1.7"
+	oidCDPCacheAddress      = "1.3.6.1.4.1.9.9.23.1.2.1.1.4"
+	oidCDPCacheVersion      = "1.3.6.1.4.1.9.9.23.1.2.1.1.5"
+	oidCDPCacheDeviceID     = "1.3.6.1.4.1.9.9.23.1.2.1.1.6"
+	oidCDPCacheDevicePort   = "1.3.6.1.4.1.9.9.23.1.2.1.1.7"
+	oidCDPCachePlatform     = "1.3.6.1.4.1.9.9.23.1.2.1.1.8"
+	oidCDPCacheCapabilities = "1.3.6.1.4.1.9.9.23.1.2.1.1.9"
 )
 
+// lldpCapabilityNames maps LldpSystemCapabilitiesMap bits (bit 0 = most significant bit of the first octet).
+var lldpCapabilityNames = []string{"other", "repeater", "bridge", "wlan_ap", "router", "telephone", "docsis", "station"}
+
+// cdpCapabilityBits maps the CDP capabilities bitmask onto the LLDP vocabulary.
+var cdpCapabilityBits = []struct {
+	bit  uint32
+	name string
+}{
+	{0x01, "router"},
+	{0x02, "bridge"}, // transparent bridge
+	{0x04, "bridge"}, // source-route bridge
+	{0x08, "bridge"}, // switch
+	{0x10, "station"},
+	{0x40, "repeater"},
+	{0x80, "telephone"},
+}
+
+// parseLLDPCapabilities decodes an LLDP capabilities BITS value.
+func parseLLDPCapabilities(b []byte) []string {
+	var out []string
+	for i, name := range lldpCapabilityNames {
+		if i/8 < len(b) && b[i/8]&(0x80>>(i%8)) != 0 {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// parseCDPCapabilities decodes cdpCacheCapabilities (a 4-octet big-endian bitmask).
+func parseCDPCapabilities(b []byte) []string {
+	if len(b) == 0 || len(b) > 4 {
+		return nil
+	}
+	var mask uint32
+	for _, v := range b {
+		mask = mask<<8 | uint32(v)
+	}
+	var out []string
+	for _, c := range cdpCapabilityBits {
+		if mask&c.bit == 0 {
+			continue
+		}
+		dup := false
+		for _, existing := range out {
+			if existing == c.name {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			out = append(out, c.name)
+		}
+	}
+	return out
+}
+
+// parseLLDPManAddrIndex extracts (localPort, remIndex, address) from a lldpRemManAddrTable column OID,
+// whose index is <timeMark>.<localPort>.<remIndex>.<addrSubtype>.<addrLen>.<addr octets...>.
+func parseLLDPManAddrIndex(columnOID, oid string) (int, int, string, bool) {
+	oid = strings.TrimPrefix(strings.TrimSpace(oid), ".")
+	prefix := strings.TrimPrefix(columnOID, ".") + "."
+	if !strings.HasPrefix(oid, prefix) {
+		return 0, 0, "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(oid, prefix), ".")
+	if len(parts) < 5 {
+		return 0, 0, "", false
+	}
+	ints := make([]int, len(parts))
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil || v < 0 {
+			return 0, 0, "", false
+		}
+		ints[i] = v
+	}
+	subtype, length := ints[3], ints[4]
+	addr := ints[5:]
+	if len(addr) != length {
+		return 0, 0, "", false
+	}
+	b := make([]byte, length)
+	for i, v := range addr {
+		if v > 255 {
+			return 0, 0, "", false
+		}
+		b[i] = byte(v)
+	}
+	switch {
+	case subtype == 1 && length == 4, subtype == 2 && length == 16:
+		return ints[1], ints[2], net.IP(b).String(), true
+	}
+	return 0, 0, "", false
+}
+
 func lastOIDInts(oid string, n int) ([]int, bool) {
 	oid = strings.TrimSpace(oid)
 	if oid == "" || n <= 0 {
@@ -144,6 +269,7 @@ func (c *Client) WalkLLDPNeighbors(ctx context.Context, target Target) ([]Neighb
 		if s, ok := pduString(p); ok {
 			n := ensure(k)
 			n.RemotePortName = s
+			n.RemotePortDescr = s
 			if n.LocalIfIndex == nil {
 				i := k.LocalPort
 				n.LocalIfIndex = &i
@@ -180,6 +306,80 @@ func (c *Client) WalkLLDPNeighbors(ctx context.Context, target Target) ([]Neighb
 		}
 	})
 
+	// The remaining columns only enrich rows that already exist.
+	existing := func(k key) *Neighbor {
+		return rows[k]
+	}
+	_ = walk(oidLLDPRemSysDesc, func(k key, p gosnmp.SnmpPDU) {
+		if n := existing(k); n != nil {
+			if s, ok := pduString(p); ok {
+				n.RemoteSysDescr = s
+			}
+		}
+	})
+	_ = walk(oidLLDPRemSysCapEn, func(k key, p gosnmp.SnmpPDU) {
+		if n := existing(k); n != nil {
+			if b, ok := pduBytes(p); ok {
+				n.Capabilities = parseLLDPCapabilities(b)
+			}
+		}
+	})
+	_ = walk(oidLLDPRemSysCapSup, func(k key, p gosnmp.SnmpPDU) {
+		// Agents that leave the enabled set empty still tell us what the device can do.
+		if n := existing(k); n != nil && len(n.Capabilities) == 0 {
+			if b, ok := pduBytes(p); ok {
+				n.Capabilities = parseLLDPCapabilities(b)
+			}
+		}
+	})
+	if pdus, err := s.BulkWalkAll(oidLLDPRemManAddrIfSubtype); err == nil {
+		for _, p := range pdus {
+			localPort, remIndex, ip, ok := parseLLDPManAddrIndex(oidLLDPRemManAddrIfSubtype, p.Name)
+			if !ok {
+				continue
+			}
+			n := existing(key{LocalPort: localPort, RemIndex: remIndex})
+			// Prefer IPv4 when a neighbor advertises several addresses.
+			if n != nil && (n.RemoteMgmtIP == nil || (strings.Contains(*n.RemoteMgmtIP, ":") && !strings.Contains(ip, ":"))) {
+				n.RemoteMgmtIP = &ip
+			}
+		}
+	}
+	if pdus, err := s.BulkWalkAll(oidLLDPMEDRemInventory); err == nil {
+		for _, p := range pdus {
+			// <column>.<timeMark>.<localPort>.<remIndex>
+			ints, ok := lastOIDInts(p.Name, 4)
+			if !ok {
+				continue
+			}
+			n := existing(key{LocalPort: ints[2], RemIndex: ints[3]})
+			v, ok := pduString(p)
+			if n == nil || !ok || v == nil {
+				continue
+			}
+			if n.Inventory == nil {
+				n.Inventory = &MEDInventory{}
+			}
+			switch ints[0] {
+			case 1:
+				n.Inventory.HardwareRev = v
+			case 2:
+				n.Inventory.FirmwareRev = v
+			case 3:
+				n.Inventory.SoftwareRev = v
+			case 4:
+				n.Inventory.SerialNumber = v
+			case 5:
+				n.Inventory.Manufacturer = v
+			case 6:
+				n.Inventory.Model = v
+				n.RemotePlatform = v
+			case 7:
+				n.Inventory.AssetID = v
+			}
+		}
+	}
+
 	var out []Neighbor
 	for _, n := range rows {
 		if n == nil {
@@ -257,6 +457,27 @@ func (c *Client) WalkCDPNeighbors(ctx context.Context, target Target) ([]Neighbo
 			n.RemoteMgmtIP = &ip
 		}
 	})
+	_ = walk(oidCDPCacheVersion, func(k key, p gosnmp.SnmpPDU) {
+		if n := rows[k]; n != nil {
+			if s, ok := pduString(p); ok {
+				n.RemoteSysDescr = s
+			}
+		}
+	})
+	_ = walk(oidCDPCachePlatform, func(k key, p gosnmp.SnmpPDU) {
+		if n := rows[k]; n != nil {
+			if s, ok := pduString(p); ok {
+				n.RemotePlatform = s
+			}
+		}
+	})
+	_ = walk(oidCDPCacheCapabilities, func(k key, p gosnmp.SnmpPDU) {
+		if n := rows[k]; n != nil {
+			if b, ok := pduBytes(p); ok {
+				n.Capabilities = parseCDPCapabilities(b)
+			}
+		}
+	})
 
 	var out []Neighbor
 	for _, n := range rows {
@@ -0,0 +1,51 @@
+package snmp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCapabilities(t *testing.T) {
+	cases := []struct {
+		name string
+		fn   func([]byte) []string
+		in   []byte
+		want []string
+	}{
+		{name: "lldp bridge+router", fn: parseLLDPCapabilities, in: []byte{0x28, 0x00}, want: []string{"bridge", "router"}},
+		{name: "lldp phone", fn: parseLLDPCapabilities, in: []byte{0x24}, want: []string{"bridge", "telephone"}},
+		{name: "lldp ap", fn: parseLLDPCapabilities, in: []byte{0x10, 0x00}, want: []string{"wlan_ap"}},
+		{name: "lldp empty", fn: parseLLDPCapabilities, in: nil, want: nil},
+		{name: "cdp router+switch+igmp", fn: parseCDPCapabilities, in: []byte{0, 0, 0, 0x29}, want: []string{"router", "bridge"}},
+		{name: "cdp phone host", fn: parseCDPCapabilities, in: []byte{0, 0, 0x04, 0x90}, want: []string{"station", "telephone"}},
+		{name: "cdp oversized", fn: parseCDPCapabilities, in: []byte{0, 0, 0, 0, 1}, want: nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.fn(tc.in); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseLLDPManAddrIndex(t *testing.T) {
+	cases := []struct {
+		oid       string
+		ok        bool
+		port, idx int
+		ip        string
+	}{
+		{oid: ".1.0.8802.1.1.2.1.4.2.1.3.0.12.3.1.4.10.1.0.2", ok: true, port: 12, idx: 3, ip: "10.1.0.2"},
+		{oid: "1.0.8802.1.1.2.1.4.2.1.3.0.5.1.2.16.32.1.13.184.0.0.0.0.0.0.0.0.0.0.0.1", ok: true, port: 5, idx: 1, ip: "2001:db8::1"},
+		{oid: "1.0.8802.1.1.2.1.4.2.1.3.0.12.3.1.4.10.1.0", ok: false},
+		{oid: "1.0.8802.1.1.2.1.4.2.1.4.0.12.3.1.4.10.1.0.2", ok: false},
+		{oid: "1.0.8802.1.1.2.1.4.2.1.3.0.12.3.6.4.10.1.0.2", ok: false},
+	}
+	for _, tc := range cases {
+		port, idx, ip, ok := parseLLDPManAddrIndex(oidLLDPRemManAddrIfSubtype, tc.oid)
+		if ok != tc.ok || port != tc.port || idx != tc.idx || ip != tc.ip {
+			t.Fatalf("%s: got %d %d %q %v", tc.oid, port, idx, ip, ok)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// POWER-ETHERNET-MIB pethPsePortTable (index: pethPsePortGroupIndex + pethPsePortIndex).
+	oidPethPsePortAdminEnable          = "1.3.6.1.2.1.105.1.1.1.3"
+	oidPethPsePortDetectionStatus      = "1.3.6.1.2.1.105.1.1.1.6"
+	oidPethPsePortPowerClassifications = "1.3.6.1.2.1.105.1.1.1.10"
+
+	// CISCO-POWER-ETHERNET-EXT-MIB cpeExtPsePortPwrConsumption (milliwatts), same index. The standard MIB
+	// has no per-port power reading, so this is only available on agents that implement the extension.
+	oidCpeExtPsePortPwrConsumption = "1.3.6.1.4.1.9.9.402.1.2.1.11"
+)
+
+// PoE detection states as named by POWER-ETHERNET-MIB pethPsePortDetectionStatus.
+var poeDetectionStates = map[int]string{
+	1: "disabled",
+	2: "searching",
+	3: "delivering_power",
+	4: "fault",
+	5: "test",
+	6: "other_fault",
+}
+
+// PoE power classes as named by POWER-ETHERNET-MIB pethPsePortPowerClassifications.
+var poePowerClasses = map[int]string{
+	1: "class0",
+	2: "class1",
+	3: "class2",
+	4: "class3",
+	5: "class4",
+}
+
+// PoEPort is one power sourcing equipment (PSE) port.
+type PoEPort struct {
+	Group           int
+	Port            int
+	AdminEnabled    *bool
+	DetectionStatus string
+	PowerClass      *string
+	PowerMilliwatts *int
+}
+
+// poeIndex splits the trailing "group.port" index of a pethPsePortTable OID.
+func poeIndex(oid string) (int, int, bool) {
+	ints, ok := lastOIDInts(oid, 2)
+	if !ok || ints[0] <= 0 || ints[1] <= 0 {
+		return 0, 0, false
+	}
+	return ints[0], ints[1], true
+}
+
+// WalkPoEPorts returns the PSE ports of a PoE switch. Devices without POWER-ETHERNET-MIB return no rows.
+func (c *Client) WalkPoEPorts(ctx context.Context, target Target) ([]PoEPort, error) {
+	if c == nil {
+		return nil, errors.New("snmp client is nil")
+	}
+	_ = ctx
+
+	s, err := c.connect(target)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Conn.Close()
+
+	states, err := s.BulkWalkAll(oidPethPsePortDetectionStatus)
+	if err != nil || len(states) == 0 {
+		return nil, err
+	}
+
+	intColumn := func(oid string) map[[2]int]int {
+		out := map[[2]int]int{}
+		pdus, err := s.BulkWalkAll(oid)
+		if err != nil {
+			return out
+		}
+		for _, p := range pdus {
+			group, port, ok := poeIndex(p.Name)
+			if !ok {
+				continue
+			}
+			if v, ok := pduInt32(p); ok && v != nil {
+				out[[2]int{group, port}] = int(*v)
+			}
+		}
+		return out
+	}
+	admin := intColumn(oidPethPsePortAdminEnable)
+	classes := intColumn(oidPethPsePortPowerClassifications)
+	power := intColumn(oidCpeExtPsePortPwrConsumption)
+
+	out := make([]PoEPort, 0, len(states))
+	for _, p := range states {
+		group, port, ok := poeIndex(p.Name)
+		if !ok {
+			continue
+		}
+		v, ok := pduInt32(p)
+		if !ok || v == nil {
+			continue
+		}
+		status, ok := poeDetectionStates[int(*v)]
+		if !ok {
+			continue
+		}
+		key := [2]int{group, port}
+		row := PoEPort{Group: group, Port: port, DetectionStatus: status}
+		if a, ok := admin[key]; ok && (a == 1 || a == 2) {
+			enabled := a == 1
+			row.AdminEnabled = &enabled
+		}
+		if c, ok := poePowerClasses[classes[key]]; ok {
+			row.PowerClass = &c
+		}
+		if mw, ok := power[key]; ok && mw >= 0 {
+			row.PowerMilliwatts = &mw
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+// nonPhysicalPrefixes are interface name prefixes that never carry PoE (logical/virtual interfaces).
+var nonPhysicalPrefixes = []string{"vlan", "lo", "po", "port-channel", "tunnel", "tu", "null", "trk", "lag", "ae", "bond", "mgmt", "br", "vl"}
+
+// nameNumbers returns the letter prefix and the numeric components of an interface name
+// ("GigabitEthernet1/0/12" -> "gigabitethernet", [1 0 12]).
+func nameNumbers(name string) (string, []int) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	prefix := strings.TrimRightFunc(strings.SplitN(name, "/", 2)[0], unicode.IsDigit)
+	prefix = strings.TrimSpace(strings.TrimRight(prefix, " -_."))
+	var nums []int
+	for _, f := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsDigit(r) }) {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return prefix, nil
+		}
+		nums = append(nums, n)
+	}
+	return prefix, nums
+}
+
+// ResolvePoEPortIfIndex maps PSE ports (group, port) to ifIndex. POWER-ETHERNET-MIB has no standard link
+// to the interface table, so this uses, in order:
+//   - a unique interface whose name ends in the port number and starts with the group (stack member /
+//     module), e.g. group 2 port 7 -> Gi2/0/7;
+//   - on single-group switches, a unique physical-looking interface whose only number is the port
+//     ("Port 7", "eth7", "7");
+//   - on single-group switches, the interface whose ifIndex equals the port number.
+//
+// Ports that stay ambiguous are left out.
+func ResolvePoEPortIfIndex(ports []PoEPort, ifaces map[int]InterfaceInfo) map[[2]int]int {
+	groups := map[int]bool{}
+	for _, p := range ports {
+		groups[p.Group] = true
+	}
+	singleGroup := len(groups) == 1
+
+	type parsed struct {
+		ifIndex  int
+		prefix   string
+		numbers  []int
+		physical bool
+	}
+	names := make([]parsed, 0, len(ifaces))
+	for ifIndex, info := range ifaces {
+		name := ""
+		switch {
+		case info.Name != nil && strings.TrimSpace(*info.Name) != "":
+			name = *info.Name
+		case info.Descr != nil:
+			name = *info.Descr
+		}
+		prefix, nums := nameNumbers(name)
+		physical := true
+		for _, np := range nonPhysicalPrefixes {
+			if prefix == np {
+				physical = false
+				break
+			}
+		}
+		names = append(names, parsed{ifIndex: ifIndex, prefix: prefix, numbers: nums, physical: physical})
+	}
+
+	out := make(map[[2]int]int, len(ports))
+	for _, p := range ports {
+		match, count := 0, 0
+		// Uplink modules reuse port numbers (Gi1/0/1 vs Te1/1/1); access ports sit on sub-slot 0.
+		accessMatch, accessCount := 0, 0
+		for _, n := range names {
+			if !n.physical || len(n.numbers) < 2 {
+				continue
+			}
+			if n.numbers[len(n.numbers)-1] != p.Port || n.numbers[0] != p.Group {
+				continue
+			}
+			match, count = n.ifIndex, count+1
+			access := true
+			for _, mid := range n.numbers[1 : len(n.numbers)-1] {
+				if mid != 0 {
+					access = false
+				}
+			}
+			if access {
+				accessMatch, accessCount = n.ifIndex, accessCount+1
+			}
+		}
+		if count > 1 && accessCount == 1 {
+			match, count = accessMatch, 1
+		}
+		if count != 1 && singleGroup {
+			match, count = 0, 0
+			for _, n := range names {
+				if n.physical && len(n.numbers) == 1 && n.numbers[0] == p.Port {
+					match, count = n.ifIndex, count+1
+				}
+			}
+		}
+		if count != 1 && singleGroup {
+			match, count = 0, 0
+			for _, n := range names {
+				if n.physical && n.ifIndex == p.Port {
+					match, count = n.ifIndex, 1
+				}
+			}
+		}
+		if count == 1 {
+			out[[2]int{p.Group, p.Port}] = match
+		}
+	}
+	return out
+}
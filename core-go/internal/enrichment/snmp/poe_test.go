@@ -0,0 +1,63 @@
+package snmp
+
+import "testing"
+
+func TestResolvePoEPortIfIndex(t *testing.T) {
+	name := func(s string) *string { return &s }
+	cases := []struct {
+		name   string
+		ports  []PoEPort
+		ifaces map[int]InterfaceInfo
+		want   map[[2]int]int
+	}{
+		{
+			name:  "cisco stack names",
+			ports: []PoEPort{{Group: 1, Port: 1}, {Group: 2, Port: 7}},
+			ifaces: map[int]InterfaceInfo{
+				10101: {Name: name("Gi1/0/1")},
+				10149: {Name: name("Te1/1/1")},
+				10207: {Name: name("Gi2/0/7")},
+				1:     {Name: name("Vlan1")},
+			},
+			want: map[[2]int]int{{1, 1}: 10101, {2, 7}: 10207},
+		},
+		{
+			name:  "single group port names",
+			ports: []PoEPort{{Group: 1, Port: 3}},
+			ifaces: map[int]InterfaceInfo{
+				3:  {Name: name("vlan3")},
+				53: {Name: name("Port 3")},
+			},
+			want: map[[2]int]int{{1, 3}: 53},
+		},
+		{
+			name:  "single group ifindex fallback",
+			ports: []PoEPort{{Group: 1, Port: 4}},
+			ifaces: map[int]InterfaceInfo{
+				4: {Name: name("switch port"), Descr: name("unit 1 port 4")},
+			},
+			want: map[[2]int]int{{1, 4}: 4},
+		},
+		{
+			name:  "multi group without names stays unresolved",
+			ports: []PoEPort{{Group: 1, Port: 4}, {Group: 2, Port: 4}},
+			ifaces: map[int]InterfaceInfo{
+				4: {Name: name("eth4")},
+			},
+			want: map[[2]int]int{},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ResolvePoEPortIfIndex(tc.ports, tc.ifaces)
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+			for k, v := range tc.want {
+				if got[k] != v {
+					t.Fatalf("expected %v, got %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// OSPF-MIB ospfNbrTable (index: ospfNbrIpAddr + ospfNbrAddressLessIndex).
+	oidOSPFNbrRtrID = "1.3.6.1.2.1.14.10.1.3"
+	oidOSPFNbrState = "1.3.6.1.2.1.14.10.1.6"
+
+	// OSPF-MIB ospfIfAreaId (index: ospfIfIpAddress + ospfAddressLessIf).
+	oidOSPFIfAreaID = "1.3.6.1.2.1.14.7.1.3"
+
+	// IP-MIB ipAdEntNetMask (index: local IPv4 address).
+	oidIPAdEntNetMask = "1.3.6.1.2.1.4.20.1.3"
+
+	// BGP4-MIB bgpLocalAs and bgpPeerTable (index: bgpPeerRemoteAddr).
+	oidBGPLocalAS0               = "1.3.6.1.2.1.15.2.0"
+	oidBGPPeerIdentifier         = "1.3.6.1.2.1.15.3.1.1"
+	oidBGPPeerState              = "1.3.6.1.2.1.15.3.1.2"
+	oidBGPPeerRemoteAS           = "1.3.6.1.2.1.15.3.1.9"
+	oidBGPPeerFsmEstablishedTime = "1.3.6.1.2.1.15.3.1.16"
+)
+
+// OSPF neighbor states as named by OSPF-MIB ospfNbrState.
+var ospfNbrStates = map[int]string{
+	1: "down",
+	2: "attempt",
+	3: "init",
+	4: "two_way",
+	5: "exchange_start",
+	6: "exchange",
+	7: "loading",
+	8: "full",
+}
+
+// BGP peer states as named by BGP4-MIB bgpPeerState.
+var bgpPeerStates = map[int]string{
+	1: "idle",
+	2: "connect",
+	3: "active",
+	4: "open_sent",
+	5: "open_confirm",
+	6: "established",
+}
+
+// OSPFNeighbor is one row of a router's OSPF neighbor table.
+type OSPFNeighbor struct {
+	IP       string
+	RouterID *string
+	State    string
+	Area     *string // area of the local OSPF interface facing the neighbor, when it can be determined
+}
+
+// BGPPeer is one row of a router's BGP peer table.
+type BGPPeer struct {
+	RemoteAddr string
+	Identifier *string
+	State      string
+	RemoteAS   *int64
+	LocalAS    *int64
+	// StateAge is bgpPeerFsmEstablishedTime: seconds the session has been established, or seconds since
+	// it last left the established state.
+	StateAge *int64
+}
+
+// pduIP reads an IpAddress value (gosnmp renders it as a dotted string; some agents send raw octets).
+// 0.0.0.0 is returned as-is: it is the backbone area ID.
+func pduIP(p gosnmp.SnmpPDU) (string, bool) {
+	switch v := p.Value.(type) {
+	case string:
+		addr, err := netip.ParseAddr(strings.TrimSpace(v))
+		if err != nil || !addr.Is4() {
+			return "", false
+		}
+		return addr.String(), true
+	case []byte:
+		if len(v) != 4 {
+			return "", false
+		}
+		return netip.AddrFrom4([4]byte{v[0], v[1], v[2], v[3]}).String(), true
+	default:
+		return "", false
+	}
+}
+
+// ipv4Index parses the IPv4 address made of the four sub-identifiers after columnOID.
+func ipv4Index(columnOID, oid string) (string, bool) {
+	parts := strings.Split(fdbIndexKey(columnOID, oid), ".")
+	if len(parts) < 4 {
+		return "", false
+	}
+	var b [4]byte
+	for i := 0; i < 4; i++ {
+		v, err := strconv.Atoi(parts[i])
+		if err != nil || v < 0 || v > 255 {
+			return "", false
+		}
+		b[i] = byte(v)
+	}
+	return netip.AddrFrom4(b).String(), true
+}
+
+// ospfAreaFor picks the area of the local OSPF interface whose subnet contains the neighbor address.
+func ospfAreaFor(neighbor string, ifAreas map[string]string, masks map[string]string) *string {
+	nbr, err := netip.ParseAddr(neighbor)
+	if err != nil {
+		return nil
+	}
+	for local, area := range ifAreas {
+		mask, ok := masks[local]
+		if !ok {
+			continue
+		}
+		m, err := netip.ParseAddr(mask)
+		if err != nil || !m.Is4() {
+			continue
+		}
+		mb := m.As4()
+		bits := 0
+		for _, b := range mb {
+			for i := 7; i >= 0 && b&(1<<i) != 0; i-- {
+				bits++
+			}
+		}
+		prefix, err := netip.MustParseAddr(local).Prefix(bits)
+		if err == nil && prefix.Contains(nbr) {
+			a := area
+			return &a
+		}
+	}
+	if len(ifAreas) > 0 {
+		// Single-area routers are common; fall back to the only area when there is exactly one.
+		var only string
+		for _, area := range ifAreas {
+			if only != "" && area != only {
+				return nil
+			}
+			only = area
+		}
+		return &only
+	}
+	return nil
+}
+
+// WalkOSPFNeighbors returns the router's OSPF neighbors (OSPF-MIB). Routers without OSPF return no rows.
+func (c *Client) WalkOSPFNeighbors(ctx context.Context, target Target) ([]OSPFNeighbor, error) {
+	if c == nil {
+		return nil, errors.New("snmp client is nil")
+	}
+	_ = ctx
+
+	s, err := c.connect(target)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Conn.Close()
+
+	states, err := s.BulkWalkAll(oidOSPFNbrState)
+	if err != nil || len(states) == 0 {
+		return nil, err
+	}
+
+	routerIDs := map[string]string{}
+	if pdus, err := s.BulkWalkAll(oidOSPFNbrRtrID); err == nil {
+		for _, p := range pdus {
+			ip, ok := ipv4Index(oidOSPFNbrRtrID, p.Name)
+			if !ok {
+				continue
+			}
+			if id, ok := pduIP(p); ok && id != "0.0.0.0" {
+				routerIDs[ip] = id
+			}
+		}
+	}
+	ifAreas := map[string]string{}
+	if pdus, err := s.BulkWalkAll(oidOSPFIfAreaID); err == nil {
+		for _, p := range pdus {
+			local, ok := ipv4Index(oidOSPFIfAreaID, p.Name)
+			if !ok || local == "0.0.0.0" {
+				continue
+			}
+			if area, ok := pduIP(p); ok {
+				ifAreas[local] = area
+			}
+		}
+	}
+	masks := map[string]string{}
+	if len(ifAreas) > 0 {
+		if pdus, err := s.BulkWalkAll(oidIPAdEntNetMask); err == nil {
+			for _, p := range pdus {
+				local, ok := ipv4Index(oidIPAdEntNetMask, p.Name)
+				if !ok {
+					continue
+				}
+				if mask, ok := pduIP(p); ok {
+					masks[local] = mask
+				}
+			}
+		}
+	}
+
+	out := make([]OSPFNeighbor, 0, len(states))
+	for _, p := range states {
+		ip, ok := ipv4Index(oidOSPFNbrState, p.Name)
+		if !ok {
+			continue
+		}
+		v, ok := pduInt32(p)
+		if !ok || v == nil {
+			continue
+		}
+		state, ok := ospfNbrStates[int(*v)]
+		if !ok {
+			continue
+		}
+		n := OSPFNeighbor{IP: ip, State: state, Area: ospfAreaFor(ip, ifAreas, masks)}
+		if id, ok := routerIDs[ip]; ok {
+			n.RouterID = &id
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// WalkBGPPeers returns the router's BGP peers (BGP4-MIB, IPv4 peers only). Routers without BGP return no rows.
+func (c *Client) WalkBGPPeers(ctx context.Context, target Target) ([]BGPPeer, error) {
+	if c == nil {
+		return nil, errors.New("snmp client is nil")
+	}
+	_ = ctx
+
+	s, err := c.connect(target)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Conn.Close()
+
+	states, err := s.BulkWalkAll(oidBGPPeerState)
+	if err != nil || len(states) == 0 {
+		return nil, err
+	}
+
+	var localAS *int64
+	if pkt, err := s.Get([]string{oidBGPLocalAS0}); err == nil {
+		for _, v := range pkt.Variables {
+			if n, ok := pduInt64(v); ok && n != nil && *n > 0 {
+				localAS = n
+			}
+		}
+	}
+	column := func(oid string) map[string]gosnmp.SnmpPDU {
+		out := map[string]gosnmp.SnmpPDU{}
+		pdus, err := s.BulkWalkAll(oid)
+		if err != nil {
+			return out
+		}
+		for _, p := range pdus {
+			if ip, ok := ipv4Index(oid, p.Name); ok {
+				out[ip] = p
+			}
+		}
+		return out
+	}
+	identifiers := column(oidBGPPeerIdentifier)
+	remoteAS := column(oidBGPPeerRemoteAS)
+	ages := column(oidBGPPeerFsmEstablishedTime)
+
+	out := make([]BGPPeer, 0, len(states))
+	for _, p := range states {
+		ip, ok := ipv4Index(oidBGPPeerState, p.Name)
+		if !ok {
+			continue
+		}
+		v, ok := pduInt32(p)
+		if !ok || v == nil {
+			continue
+		}
+		state, ok := bgpPeerStates[int(*v)]
+		if !ok {
+			continue
+		}
+		peer := BGPPeer{RemoteAddr: ip, State: state, LocalAS: localAS}
+		if pdu, ok := identifiers[ip]; ok {
+			if id, ok := pduIP(pdu); ok && id != "0.0.0.0" {
+				peer.Identifier = &id
+			}
+		}
+		if pdu, ok := remoteAS[ip]; ok {
+			if n, ok := pduInt64(pdu); ok && n != nil && *n > 0 {
+				peer.RemoteAS = n
+			}
+		}
+		if pdu, ok := ages[ip]; ok {
+			if n, ok := pduInt64(pdu); ok && n != nil && *n >= 0 {
+				peer.StateAge = n
+			}
+		}
+		out = append(out, peer)
+	}
+	return out, nil
+}
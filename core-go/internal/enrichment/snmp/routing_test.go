@@ -0,0 +1,59 @@
+package snmp
+
+import "testing"
+
+func TestIPv4Index(t *testing.T) {
+	cases := []struct {
+		name string
+		oid  string
+		want string
+		ok   bool
+	}{
+		{name: "ospf neighbor", oid: ".1.3.6.1.2.1.14.10.1.6.10.0.0.2.0", want: "10.0.0.2", ok: true},
+		{name: "bgp peer", oid: "1.3.6.1.2.1.15.3.1.2.192.0.2.1", want: "192.0.2.1", ok: true},
+		{name: "short", oid: "1.3.6.1.2.1.15.3.1.2.192.0", ok: false},
+		{name: "out of range", oid: "1.3.6.1.2.1.15.3.1.2.300.0.0.1", ok: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			column := oidBGPPeerState
+			if tc.name == "ospf neighbor" {
+				column = oidOSPFNbrState
+			}
+			got, ok := ipv4Index(column, tc.oid)
+			if ok != tc.ok || got != tc.want {
+				t.Fatalf("expected (%q, %v), got (%q, %v)", tc.want, tc.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestOSPFAreaFor(t *testing.T) {
+	ifAreas := map[string]string{"10.0.0.1": "0.0.0.0", "10.1.0.1": "0.0.0.1"}
+	masks := map[string]string{"10.0.0.1": "255.255.255.252", "10.1.0.1": "255.255.255.0"}
+	cases := []struct {
+		name     string
+		neighbor string
+		ifAreas  map[string]string
+		want     string
+	}{
+		{name: "backbone link", neighbor: "10.0.0.2", ifAreas: ifAreas, want: "0.0.0.0"},
+		{name: "area 1 link", neighbor: "10.1.0.20", ifAreas: ifAreas, want: "0.0.0.1"},
+		{name: "no matching subnet", neighbor: "172.16.0.1", ifAreas: ifAreas, want: ""},
+		{name: "single area fallback", neighbor: "172.16.0.1", ifAreas: map[string]string{"10.0.0.1": "0.0.0.5"}, want: "0.0.0.5"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ospfAreaFor(tc.neighbor, tc.ifAreas, masks)
+			if tc.want == "" {
+				if got != nil {
+					t.Fatalf("expected no area, got %q", *got)
+				}
+				return
+			}
+			if got == nil || *got != tc.want {
+				t.Fatalf("expected %q, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,458 @@
+package sshkey
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config controls how host keys are collected.
+type Config struct {
+	Timeout time.Duration
+}
+
+// HostKey is a single server host key as presented during key exchange.
+type HostKey struct {
+	// KeyType is the key format from the public key blob (e.g. "ssh-ed25519", "ssh-rsa").
+	KeyType string
+	// FingerprintSHA256 uses the OpenSSH format ("SHA256:<unpadded base64>").
+	FingerprintSHA256 string
+	// PublicKey is the base64-encoded public key blob (authorized_keys form).
+	PublicKey string
+}
+
+// Result is everything learned from one SSH endpoint.
+type Result struct {
+	Banner string
+	Keys   []HostKey
+}
+
+const clientVersion = "SSH-2.0-roller_hoops_discovery"
+
+const (
+	msgDisconnect    = 1
+	msgIgnore        = 2
+	msgUnimplemented = 3
+	msgDebug         = 4
+	msgKexInit       = 20
+	msgKexDHInit     = 30
+	msgKexDHReply    = 31
+
+	maxPacketLength = 35000
+)
+
+var (
+	kexAlgorithms = []string{
+		"curve25519-sha256",
+		"curve25519-sha256@libssh.org",
+		"ecdh-sha2-nistp256",
+		"ecdh-sha2-nistp384",
+		"ecdh-sha2-nistp521",
+		"diffie-hellman-group14-sha256",
+		"diffie-hellman-group14-sha1",
+		"diffie-hellman-group1-sha1",
+	}
+	hostKeyAlgorithms = []string{
+		"ssh-ed25519",
+		"ecdsa-sha2-nistp256",
+		"ecdsa-sha2-nistp384",
+		"ecdsa-sha2-nistp521",
+		"rsa-sha2-512",
+		"rsa-sha2-256",
+		"ssh-rsa",
+		"ssh-dss",
+	}
+	cipherAlgorithms = "chacha20-poly1305@openssh.com,aes128-ctr,aes192-ctr,aes256-ctr,aes128-gcm@openssh.com,aes256-gcm@openssh.com,aes128-cbc,aes256-cbc,3des-cbc"
+	macAlgorithms    = "hmac-sha2-256-etm@openssh.com,hmac-sha2-512-etm@openssh.com,hmac-sha2-256,hmac-sha2-512,hmac-sha1,hmac-sha1-96"
+	compAlgorithms   = "none,zlib@openssh.com,zlib"
+)
+
+// RFC 2409 (group 1, 1024-bit) and RFC 3526 (group 14, 2048-bit) MODP primes; generator 2.
+var (
+	group1Prime  = mustHex("FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7EDEE386BFB5A899FA5AE9F24117C4B1FE649286651ECE65381FFFFFFFFFFFFFFFF")
+	group14Prime = mustHex("FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7EDEE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3BE39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF6955817183995497CEA956AE515D2261898FA051015728E5A8AACAA68FFFFFFFFFFFFFFFF")
+)
+
+// ErrNoCommonAlgorithm is returned when the server offers no key exchange or host key algorithm we speak.
+var ErrNoCommonAlgorithm = errors.New("no common ssh algorithm")
+
+// IsLikelySSH reports whether an open TCP service should be probed for host keys: port 22, a scanner
+// service name mentioning ssh, or a recorded banner starting with `SSH-`.
+func IsLikelySSH(port int, name, banner string) bool {
+	if port == 22 {
+		return true
+	}
+	if strings.Contains(strings.ToLower(name), "ssh") {
+		return true
+	}
+	return strings.HasPrefix(strings.TrimSpace(banner), "SSH-")
+}
+
+// Fingerprint returns the OpenSSH-style SHA-256 fingerprint of a public key blob.
+func Fingerprint(blob []byte) string {
+	sum := sha256.Sum256(blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// Collect runs key exchanges against ip:port (never authenticating) until every host key type the
+// server advertises has been seen. The first exchange offers all supported algorithms; follow-up
+// exchanges pin the host key algorithm to pick up the remaining key types.
+func Collect(ctx context.Context, cfg Config, ip string, port int) (Result, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	addr := net.JoinHostPort(ip, strconv.Itoa(port))
+
+	first, err := exchange(ctx, cfg, addr, hostKeyAlgorithms)
+	if err != nil {
+		return Result{Banner: first.banner}, err
+	}
+
+	res := Result{Banner: first.banner}
+	seen := map[string]bool{}
+	add := func(blob []byte) {
+		keyType, ok := blobKeyType(blob)
+		if !ok || seen[keyType] {
+			return
+		}
+		seen[keyType] = true
+		res.Keys = append(res.Keys, HostKey{
+			KeyType:           keyType,
+			FingerprintSHA256: Fingerprint(blob),
+			PublicKey:         base64.StdEncoding.EncodeToString(blob),
+		})
+	}
+	add(first.hostKey)
+
+	for _, alg := range followUpAlgorithms(first.serverHostKeyAlgs, seen) {
+		if ctx.Err() != nil {
+			break
+		}
+		ex, err := exchange(ctx, cfg, addr, []string{alg})
+		if err != nil {
+			continue
+		}
+		add(ex.hostKey)
+	}
+
+	return res, nil
+}
+
+// followUpAlgorithms returns one supported algorithm per not-yet-seen key type advertised by the server,
+// in our preference order (rsa-sha2-512 before ssh-rsa for RSA keys).
+func followUpAlgorithms(serverAlgs []string, seen map[string]bool) []string {
+	offered := map[string]bool{}
+	for _, alg := range serverAlgs {
+		offered[alg] = true
+	}
+	var out []string
+	picked := map[string]bool{}
+	for _, alg := range hostKeyAlgorithms {
+		keyType := keyTypeForAlgorithm(alg)
+		if !offered[alg] || seen[keyType] || picked[keyType] {
+			continue
+		}
+		picked[keyType] = true
+		out = append(out, alg)
+	}
+	return out
+}
+
+func keyTypeForAlgorithm(alg string) string {
+	switch alg {
+	case "rsa-sha2-256", "rsa-sha2-512":
+		return "ssh-rsa"
+	default:
+		return alg
+	}
+}
+
+type exchangeResult struct {
+	banner            string
+	serverHostKeyAlgs []string
+	hostKey           []byte
+}
+
+func exchange(ctx context.Context, cfg Config, addr string, hostKeyAlgs []string) (exchangeResult, error) {
+	var out exchangeResult
+
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return out, err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	if _, err := io.WriteString(conn, clientVersion+"\r\n"); err != nil {
+		return out, err
+	}
+	r := bufio.NewReader(conn)
+	banner, err := readBanner(r)
+	if err != nil {
+		return out, err
+	}
+	out.banner = banner
+
+	if err := writePacket(conn, kexInitPayload(hostKeyAlgs)); err != nil {
+		return out, err
+	}
+
+	serverInit, err := readMessage(r, msgKexInit)
+	if err != nil {
+		return out, err
+	}
+	serverKex, serverHostKeys, err := parseKexInit(serverInit)
+	if err != nil {
+		return out, err
+	}
+	out.serverHostKeyAlgs = serverHostKeys
+
+	kexAlg := negotiate(kexAlgorithms, serverKex)
+	if kexAlg == "" || negotiate(hostKeyAlgs, serverHostKeys) == "" {
+		return out, ErrNoCommonAlgorithm
+	}
+
+	initPayload, err := kexDHInitPayload(kexAlg)
+	if err != nil {
+		return out, err
+	}
+	if err := writePacket(conn, initPayload); err != nil {
+		return out, err
+	}
+
+	reply, err := readMessage(r, msgKexDHReply)
+	if err != nil {
+		return out, err
+	}
+	blob, _, ok := readString(reply[1:])
+	if !ok || len(blob) == 0 {
+		return out, errors.New("malformed kex reply")
+	}
+	out.hostKey = blob
+	return out, nil
+}
+
+// readBanner reads the server identification string, skipping any pre-banner lines (RFC 4253 §4.2).
+func readBanner(r *bufio.Reader) (string, error) {
+	for i := 0; i < 32; i++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if !strings.HasPrefix(line, "SSH-") {
+			continue
+		}
+		if len(line) > 255 {
+			line = line[:255]
+		}
+		if !strings.HasPrefix(line, "SSH-2.0-") && !strings.HasPrefix(line, "SSH-1.99-") {
+			return line, fmt.Errorf("unsupported ssh protocol version %q", line)
+		}
+		return line, nil
+	}
+	return "", errors.New("ssh banner not found")
+}
+
+func kexInitPayload(hostKeyAlgs []string) []byte {
+	cookie := make([]byte, 16)
+	_, _ = rand.Read(cookie)
+
+	b := []byte{msgKexInit}
+	b = append(b, cookie...)
+	b = appendString(b, []byte(strings.Join(kexAlgorithms, ",")))
+	b = appendString(b, []byte(strings.Join(hostKeyAlgs, ",")))
+	b = appendString(b, []byte(cipherAlgorithms))
+	b = appendString(b, []byte(cipherAlgorithms))
+	b = appendString(b, []byte(macAlgorithms))
+	b = appendString(b, []byte(macAlgorithms))
+	b = appendString(b, []byte(compAlgorithms))
+	b = appendString(b, []byte(compAlgorithms))
+	b = appendString(b, nil)
+	b = appendString(b, nil)
+	b = append(b, 0)          // first_kex_packet_follows
+	b = append(b, 0, 0, 0, 0) // reserved
+	return b
+}
+
+func parseKexInit(payload []byte) (kex []string, hostKeys []string, err error) {
+	if len(payload) < 17 {
+		return nil, nil, errors.New("short kexinit")
+	}
+	rest := payload[17:]
+	kexList, rest, ok := readString(rest)
+	if !ok {
+		return nil, nil, errors.New("malformed kexinit")
+	}
+	hostKeyList, _, ok := readString(rest)
+	if !ok {
+		return nil, nil, errors.New("malformed kexinit")
+	}
+	return splitNameList(kexList), splitNameList(hostKeyList), nil
+}
+
+func kexDHInitPayload(kexAlg string) ([]byte, error) {
+	b := []byte{msgKexDHInit}
+	var curve ecdh.Curve
+	switch kexAlg {
+	case "curve25519-sha256", "curve25519-sha256@libssh.org":
+		curve = ecdh.X25519()
+	case "ecdh-sha2-nistp256":
+		curve = ecdh.P256()
+	case "ecdh-sha2-nistp384":
+		curve = ecdh.P384()
+	case "ecdh-sha2-nistp521":
+		curve = ecdh.P521()
+	case "diffie-hellman-group14-sha256", "diffie-hellman-group14-sha1":
+		return appendMPInt(b, dhPublic(group14Prime)), nil
+	case "diffie-hellman-group1-sha1":
+		return appendMPInt(b, dhPublic(group1Prime)), nil
+	default:
+		return nil, fmt.Errorf("unsupported kex %q", kexAlg)
+	}
+	priv, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return appendString(b, priv.PublicKey().Bytes()), nil
+}
+
+func dhPublic(p *big.Int) *big.Int {
+	// The shared secret is never used; only e = g^x mod p needs to be in range for the server to reply.
+	x, err := rand.Int(rand.Reader, new(big.Int).Sub(p, big.NewInt(2)))
+	if err != nil || x.Sign() == 0 {
+		x = big.NewInt(2)
+	}
+	return new(big.Int).Exp(big.NewInt(2), x, p)
+}
+
+// readMessage reads packets until one of the wanted type arrives, skipping transport noise.
+func readMessage(r *bufio.Reader, want byte) ([]byte, error) {
+	for i := 0; i < 16; i++ {
+		payload, err := readPacket(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(payload) == 0 {
+			continue
+		}
+		switch payload[0] {
+		case want:
+			return payload, nil
+		case msgIgnore, msgDebug, msgUnimplemented:
+			continue
+		case msgDisconnect:
+			return nil, errors.New("server disconnected")
+		default:
+			return nil, fmt.Errorf("unexpected ssh message %d", payload[0])
+		}
+	}
+	return nil, errors.New("too many unexpected ssh messages")
+}
+
+func readPacket(r io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	padding := uint32(header[4])
+	if length < 1+padding || length > maxPacketLength {
+		return nil, errors.New("invalid ssh packet length")
+	}
+	body := make([]byte, length-1)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body[:len(body)-int(padding)], nil
+}
+
+func writePacket(w io.Writer, payload []byte) error {
+	padding := 8 - (5+len(payload))%8
+	if padding < 4 {
+		padding += 8
+	}
+	packet := make([]byte, 5+len(payload)+padding)
+	binary.BigEndian.PutUint32(packet, uint32(1+len(payload)+padding))
+	packet[4] = byte(padding)
+	copy(packet[5:], payload)
+	_, _ = rand.Read(packet[5+len(payload):])
+	_, err := w.Write(packet)
+	return err
+}
+
+func negotiate(client, server []string) string {
+	for _, c := range client {
+		for _, s := range server {
+			if c == s {
+				return c
+			}
+		}
+	}
+	return ""
+}
+
+func blobKeyType(blob []byte) (string, bool) {
+	keyType, _, ok := readString(blob)
+	if !ok || len(keyType) == 0 || len(keyType) > 64 {
+		return "", false
+	}
+	return string(keyType), true
+}
+
+func splitNameList(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	return strings.Split(string(b), ",")
+}
+
+func readString(b []byte) (value []byte, rest []byte, ok bool) {
+	if len(b) < 4 {
+		return nil, nil, false
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint64(n) > uint64(len(b)-4) {
+		return nil, nil, false
+	}
+	return b[4 : 4+n], b[4+n:], true
+}
+
+func appendString(b []byte, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func appendMPInt(b []byte, n *big.Int) []byte {
+	v := n.Bytes()
+	if len(v) > 0 && v[0]&0x80 != 0 {
+		v = append([]byte{0}, v...)
+	}
+	return appendString(b, v)
+}
+
+func mustHex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("sshkey: invalid prime")
+	}
+	return n
+}
@@ -0,0 +1,161 @@
+package sshkey
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testBlob(keyType string, body string) []byte {
+	b := appendString(nil, []byte(keyType))
+	return appendString(b, []byte(body))
+}
+
+// serveFakeSSH answers each connection with a banner, a KEXINIT advertising the given host key
+// algorithms, and a KEXDH_REPLY carrying the key blob for the algorithm the client pinned.
+func serveFakeSSH(t *testing.T, ln net.Listener, serverHostKeys []string, blobs map[string][]byte) {
+	t.Helper()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+			r := bufio.NewReader(conn)
+			_, _ = conn.Write([]byte("pre-banner noise\r\nSSH-2.0-OpenSSH_9.6 Test\r\n"))
+			if _, err := r.ReadString('\n'); err != nil {
+				return
+			}
+			clientInit, err := readPacket(r)
+			if err != nil {
+				return
+			}
+			_, clientHostKeys, err := parseKexInit(clientInit)
+			if err != nil {
+				return
+			}
+
+			init := []byte{msgKexInit}
+			init = append(init, make([]byte, 16)...)
+			init = appendString(init, []byte("sntrup761x25519-sha512@openssh.com,curve25519-sha256"))
+			init = appendString(init, []byte(strings.Join(serverHostKeys, ",")))
+			for i := 0; i < 8; i++ {
+				init = appendString(init, []byte("none"))
+			}
+			init = append(init, 0, 0, 0, 0, 0)
+			_ = writePacket(conn, []byte{msgIgnore})
+			_ = writePacket(conn, init)
+
+			dhInit, err := readPacket(r)
+			if err != nil || dhInit[0] != msgKexDHInit {
+				return
+			}
+			if q, _, ok := readString(dhInit[1:]); !ok || len(q) != 32 {
+				return
+			}
+
+			alg := negotiate(clientHostKeys, serverHostKeys)
+			reply := []byte{msgKexDHReply}
+			reply = appendString(reply, blobs[keyTypeForAlgorithm(alg)])
+			reply = appendString(reply, make([]byte, 32))
+			reply = appendString(reply, []byte("sig"))
+			_ = writePacket(conn, reply)
+		}(conn)
+	}
+}
+
+func TestCollect_AllAdvertisedKeyTypes(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	blobs := map[string][]byte{
+		"ssh-ed25519":         testBlob("ssh-ed25519", "ed25519-key"),
+		"ecdsa-sha2-nistp256": testBlob("ecdsa-sha2-nistp256", "ecdsa-key"),
+		"ssh-rsa":             testBlob("ssh-rsa", "rsa-key"),
+	}
+	go serveFakeSSH(t, ln, []string{"rsa-sha2-512", "rsa-sha2-256", "ecdsa-sha2-nistp256", "ssh-ed25519"}, blobs)
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	res, err := Collect(context.Background(), Config{Timeout: 2 * time.Second}, "127.0.0.1", port)
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	if res.Banner != "SSH-2.0-OpenSSH_9.6 Test" {
+		t.Fatalf("unexpected banner %q", res.Banner)
+	}
+
+	want := []string{"ssh-ed25519", "ecdsa-sha2-nistp256", "ssh-rsa"}
+	if len(res.Keys) != len(want) {
+		t.Fatalf("expected %d keys, got %+v", len(want), res.Keys)
+	}
+	for i, keyType := range want {
+		got := res.Keys[i]
+		if got.KeyType != keyType {
+			t.Fatalf("key %d: expected %s, got %s", i, keyType, got.KeyType)
+		}
+		if got.FingerprintSHA256 != Fingerprint(blobs[keyType]) {
+			t.Fatalf("key %d: unexpected fingerprint %s", i, got.FingerprintSHA256)
+		}
+		if got.PublicKey != base64.StdEncoding.EncodeToString(blobs[keyType]) {
+			t.Fatalf("key %d: unexpected public key %s", i, got.PublicKey)
+		}
+	}
+}
+
+func TestCollect_NoCommonHostKeyAlgorithm(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go serveFakeSSH(t, ln, []string{"ssh-ed448"}, nil)
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	res, err := Collect(context.Background(), Config{Timeout: 2 * time.Second}, "127.0.0.1", port)
+	if err != ErrNoCommonAlgorithm {
+		t.Fatalf("expected ErrNoCommonAlgorithm, got %v", err)
+	}
+	if res.Banner == "" {
+		t.Fatalf("expected banner to be reported even without a key")
+	}
+}
+
+func TestFingerprint_OpenSSHFormat(t *testing.T) {
+	blob := testBlob("ssh-ed25519", "key")
+	sum := sha256.Sum256(blob)
+	want := "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+	if got := Fingerprint(blob); got != want || strings.HasSuffix(got, "=") {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestIsLikelySSH(t *testing.T) {
+	cases := []struct {
+		port   int
+		name   string
+		banner string
+		want   bool
+	}{
+		{port: 22, want: true},
+		{port: 2222, name: "ssh", want: true},
+		{port: 830, name: "netconf-ssh", want: true},
+		{port: 8022, banner: "SSH-2.0-dropbear_2022.83", want: true},
+		{port: 443, name: "https", want: false},
+		{port: 23, banner: "login:", want: false},
+	}
+	for _, tc := range cases {
+		if got := IsLikelySSH(tc.port, tc.name, tc.banner); got != tc.want {
+			t.Fatalf("IsLikelySSH(%d, %q, %q) = %v, want %v", tc.port, tc.name, tc.banner, got, tc.want)
+		}
+	}
+}
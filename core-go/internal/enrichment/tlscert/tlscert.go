@@ -0,0 +1,160 @@
+package tlscert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config controls how the TLS handshake is performed.
+type Config struct {
+	Timeout time.Duration
+}
+
+// Certificate is the inventory view of a leaf certificate presented by a TLS service.
+type Certificate struct {
+	FingerprintSHA256 string
+	Subject           string
+	CommonName        string
+	SANs              []string
+	Issuer            string
+	SerialNumber      string
+	NotBefore         time.Time
+	NotAfter          time.Time
+	KeyType           string
+	SelfSigned        bool
+}
+
+var knownTLSPorts = map[int]struct{}{
+	443:  {},
+	465:  {},
+	636:  {},
+	853:  {},
+	990:  {},
+	992:  {},
+	993:  {},
+	995:  {},
+	2376: {},
+	5061: {},
+	5986: {},
+	6443: {},
+	8006: {},
+	8443: {},
+	8834: {},
+	9443: {},
+}
+
+// IsKnownTLSPort reports whether the port conventionally speaks TLS from the first byte (no STARTTLS).
+func IsKnownTLSPort(port int) bool {
+	_, ok := knownTLSPorts[port]
+	return ok
+}
+
+// Fetch completes a TLS handshake with ip:port and returns the leaf certificate.
+//
+// Verification is intentionally disabled: the goal is to inventory whatever the service presents
+// (self-signed, expired, wrong hostname), not to decide whether it is trustworthy.
+func Fetch(ctx context.Context, cfg Config, ip string, port int) (Certificate, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		Config: &tls.Config{
+			InsecureSkipVerify: true,
+			MinVersion:         tls.VersionTLS10,
+		},
+	}
+
+	hctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := dialer.DialContext(hctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		return Certificate{}, err
+	}
+	defer conn.Close()
+
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return Certificate{}, errors.New("not a tls connection")
+	}
+	peers := tlsConn.ConnectionState().PeerCertificates
+	if len(peers) == 0 {
+		return Certificate{}, errors.New("no peer certificate")
+	}
+	return FromX509(peers[0]), nil
+}
+
+// FromX509 extracts the inventory fields from a parsed certificate.
+func FromX509(cert *x509.Certificate) Certificate {
+	sum := sha256.Sum256(cert.Raw)
+
+	sans := make([]string, 0, len(cert.DNSNames)+len(cert.IPAddresses)+len(cert.EmailAddresses)+len(cert.URIs))
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+
+	return Certificate{
+		FingerprintSHA256: hex.EncodeToString(sum[:]),
+		Subject:           cert.Subject.String(),
+		CommonName:        strings.TrimSpace(cert.Subject.CommonName),
+		SANs:              sans,
+		Issuer:            cert.Issuer.String(),
+		SerialNumber:      formatSerial(cert),
+		NotBefore:         cert.NotBefore.UTC(),
+		NotAfter:          cert.NotAfter.UTC(),
+		KeyType:           keyType(cert.PublicKey),
+		SelfSigned:        isSelfSigned(cert),
+	}
+}
+
+func formatSerial(cert *x509.Certificate) string {
+	if cert.SerialNumber == nil {
+		return ""
+	}
+	return strings.ToLower(cert.SerialNumber.Text(16))
+}
+
+func keyType(pub any) string {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("rsa-%d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		if k.Curve == nil {
+			return "ecdsa"
+		}
+		name := strings.ToLower(strings.ReplaceAll(k.Curve.Params().Name, "-", ""))
+		return "ecdsa-" + name
+	case ed25519.PublicKey:
+		return "ed25519"
+	default:
+		return "unknown"
+	}
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	if cert.Subject.String() != cert.Issuer.String() {
+		return false
+	}
+	// CheckSignatureFrom would reject appliance certs that are not marked as CAs; verify the raw signature instead.
+	return cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
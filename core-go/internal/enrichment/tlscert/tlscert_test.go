@@ -0,0 +1,101 @@
+package tlscert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFromX509(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	notBefore := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(0xbeef),
+		Subject:      pkix.Name{CommonName: "nas01.lan", Organization: []string{"Home"}},
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(90 * 24 * time.Hour),
+		DNSNames:     []string{"nas01.lan", "nas01"},
+		IPAddresses:  []net.IP{net.ParseIP("192.0.2.10")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+
+	got := FromX509(parsed)
+	if got.CommonName != "nas01.lan" || got.Subject != "CN=nas01.lan,O=Home" {
+		t.Fatalf("unexpected subject %q / %q", got.Subject, got.CommonName)
+	}
+	if len(got.SANs) != 3 || got.SANs[2] != "192.0.2.10" {
+		t.Fatalf("unexpected SANs %v", got.SANs)
+	}
+	if got.SerialNumber != "beef" {
+		t.Fatalf("unexpected serial %q", got.SerialNumber)
+	}
+	if got.KeyType != "ecdsa-p256" {
+		t.Fatalf("unexpected key type %q", got.KeyType)
+	}
+	if !got.SelfSigned {
+		t.Fatalf("expected self-signed certificate")
+	}
+	if len(got.FingerprintSHA256) != 64 {
+		t.Fatalf("unexpected fingerprint %q", got.FingerprintSHA256)
+	}
+	if !got.NotAfter.Equal(notBefore.Add(90 * 24 * time.Hour)) {
+		t.Fatalf("unexpected not_after %v", got.NotAfter)
+	}
+}
+
+func TestFetch_LocalTLSServer(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("parse url: %v", err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+
+	got, err := Fetch(context.Background(), Config{Timeout: 2 * time.Second}, u.Hostname(), port)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	want := FromX509(srv.Certificate())
+	if got.FingerprintSHA256 != want.FingerprintSHA256 {
+		t.Fatalf("expected fingerprint %s, got %s", want.FingerprintSHA256, got.FingerprintSHA256)
+	}
+	if got.KeyType == "" || got.KeyType == "unknown" {
+		t.Fatalf("unexpected key type %q", got.KeyType)
+	}
+}
+
+func TestIsKnownTLSPort(t *testing.T) {
+	cases := map[int]bool{443: true, 8443: true, 993: true, 22: false, 80: false}
+	for port, want := range cases {
+		if got := IsKnownTLSPort(port); got != want {
+			t.Fatalf("port %d: expected %v, got %v", port, want, got)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package traceroute
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Probe modes.
+const (
+	ModeICMP = "icmp"
+	ModeUDP  = "udp"
+)
+
+const (
+	protoICMP = 1
+	protoUDP  = 17
+
+	icmpEchoReply       = 0
+	icmpDestUnreachable = 3
+	icmpEchoRequest     = 8
+	icmpTimeExceeded    = 11
+
+	// udpBasePort is the classic traceroute destination port; probe n goes to udpBasePort+n-1.
+	udpBasePort = 33434
+)
+
+// ErrNotPermitted is returned when the process cannot open a raw ICMP socket.
+var ErrNotPermitted = errors.New("traceroute needs raw ICMP sockets (run as root or grant CAP_NET_RAW)")
+
+// Config controls how a path is probed.
+type Config struct {
+	// Mode is ModeICMP (echo requests) or ModeUDP (datagrams to high ports). Both read ICMP replies.
+	Mode string
+	// MaxHops bounds the TTL.
+	MaxHops int
+	// Timeout is how long to wait for the reply to a single probe.
+	Timeout time.Duration
+	// MaxGap stops the trace after this many consecutive unanswered hops.
+	MaxGap int
+}
+
+// Hop is one TTL step of a path. Addr is invalid when the hop did not answer.
+type Hop struct {
+	TTL  int
+	Addr netip.Addr
+	RTT  time.Duration
+}
+
+// Path is the ordered list of hops toward Target. Reached reports whether Target itself answered.
+type Path struct {
+	Target  netip.Addr
+	Hops    []Hop
+	Reached bool
+}
+
+// Tracer probes paths with increasing TTLs from this process (no external traceroute binary).
+type Tracer struct {
+	cfg Config
+}
+
+// New returns a Tracer with defaults filled in (ICMP, 20 hops, 1s per probe, stop after 3 silent hops).
+func New(cfg Config) *Tracer {
+	cfg.Mode = strings.ToLower(strings.TrimSpace(cfg.Mode))
+	if cfg.Mode != ModeUDP {
+		cfg.Mode = ModeICMP
+	}
+	if cfg.MaxHops <= 0 || cfg.MaxHops > 64 {
+		cfg.MaxHops = 20
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Second
+	}
+	if cfg.MaxGap <= 0 {
+		cfg.MaxGap = 3
+	}
+	return &Tracer{cfg: cfg}
+}
+
+// probe identifies one outstanding probe so replies can be matched to it.
+type probe struct {
+	proto   int
+	dst     netip.Addr
+	id      int // ICMP echo identifier
+	seq     int // ICMP echo sequence
+	srcPort int // UDP source port
+	dstPort int // UDP destination port
+}
+
+// reply is the outcome of one probe. addr is invalid when nothing answered in time.
+type reply struct {
+	addr    netip.Addr
+	rtt     time.Duration
+	reached bool // the target itself answered
+	final   bool // a destination unreachable ended the path
+}
+
+// Trace probes the path to dst (IPv4 only).
+func (t *Tracer) Trace(ctx context.Context, dst netip.Addr) (Path, error) {
+	if t == nil {
+		return Path{}, errors.New("tracer is nil")
+	}
+	dst = dst.Unmap()
+	if !dst.Is4() {
+		return Path{Target: dst}, errors.New("only IPv4 targets are supported")
+	}
+
+	icmpConn, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) {
+			return Path{Target: dst}, ErrNotPermitted
+		}
+		return Path{Target: dst}, err
+	}
+	defer icmpConn.Close()
+
+	var udpConn net.PacketConn
+	if t.cfg.Mode == ModeUDP {
+		udpConn, err = net.ListenPacket("udp4", "0.0.0.0:0")
+		if err != nil {
+			return Path{Target: dst}, err
+		}
+		defer udpConn.Close()
+	}
+
+	id := os.Getpid() & 0xffff
+	send := func(ctx context.Context, ttl int) (reply, error) {
+		p := probe{proto: protoICMP, dst: dst, id: id, seq: ttl}
+		sendConn := icmpConn
+		var payload []byte
+		var to net.Addr = &net.IPAddr{IP: net.IP(dst.AsSlice())}
+		if udpConn != nil {
+			local, _ := udpConn.LocalAddr().(*net.UDPAddr)
+			p = probe{proto: protoUDP, dst: dst, dstPort: udpBasePort + ttl - 1}
+			if local != nil {
+				p.srcPort = local.Port
+			}
+			sendConn = udpConn
+			payload = []byte("roller_hoops")
+			to = &net.UDPAddr{IP: net.IP(dst.AsSlice()), Port: p.dstPort}
+		} else {
+			payload = echoRequest(id, ttl, []byte("roller_hoops"))
+		}
+		if err := setTTL(sendConn, ttl); err != nil {
+			return reply{}, err
+		}
+		return exchange(ctx, icmpConn, func() error {
+			_, err := sendConn.WriteTo(payload, to)
+			return err
+		}, p, t.cfg.Timeout)
+	}
+	return tracePath(ctx, dst, t.cfg, send)
+}
+
+// tracePath runs the TTL loop. Trailing unanswered hops are dropped from the result.
+func tracePath(ctx context.Context, dst netip.Addr, cfg Config, send func(ctx context.Context, ttl int) (reply, error)) (Path, error) {
+	path := Path{Target: dst}
+	gap := 0
+	for ttl := 1; ttl <= cfg.MaxHops; ttl++ {
+		if err := ctx.Err(); err != nil {
+			return trimSilent(path), err
+		}
+		r, err := send(ctx, ttl)
+		if err != nil {
+			return trimSilent(path), err
+		}
+		path.Hops = append(path.Hops, Hop{TTL: ttl, Addr: r.addr, RTT: r.rtt})
+		if !r.addr.IsValid() {
+			gap++
+			if cfg.MaxGap > 0 && gap >= cfg.MaxGap {
+				break
+			}
+			continue
+		}
+		gap = 0
+		if r.reached {
+			path.Reached = true
+			break
+		}
+		if r.final {
+			break
+		}
+	}
+	return trimSilent(path), nil
+}
+
+func trimSilent(path Path) Path {
+	for len(path.Hops) > 0 && !path.Hops[len(path.Hops)-1].Addr.IsValid() {
+		path.Hops = path.Hops[:len(path.Hops)-1]
+	}
+	return path
+}
+
+// exchange sends one probe and reads ICMP messages until a matching reply arrives or the timeout passes.
+func exchange(ctx context.Context, conn net.PacketConn, write func() error, p probe, timeout time.Duration) (reply, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return reply{}, err
+	}
+	start := time.Now()
+	if err := write(); err != nil {
+		return reply{}, fmt.Errorf("send probe: %w", err)
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				return reply{}, nil
+			}
+			return reply{}, err
+		}
+		ipAddr, ok := from.(*net.IPAddr)
+		if !ok {
+			continue
+		}
+		peer, ok := netip.AddrFromSlice(ipAddr.IP)
+		if !ok {
+			continue
+		}
+		peer = peer.Unmap()
+		// Raw ip4 sockets hand back the ICMP message without the IPv4 header.
+		r, ok := matchReply(buf[:n], peer, p)
+		if !ok {
+			continue
+		}
+		r.rtt = time.Since(start)
+		return r, nil
+	}
+}
+
+// matchReply reports whether the ICMP message msg, received from peer, answers probe p: an echo reply from
+// the target, or a time exceeded / destination unreachable quoting the probe's headers.
+func matchReply(msg []byte, peer netip.Addr, p probe) (reply, bool) {
+	if len(msg) < 8 {
+		return reply{}, false
+	}
+	switch msg[0] {
+	case icmpEchoReply:
+		if p.proto != protoICMP || peer != p.dst {
+			return reply{}, false
+		}
+		if int(binary.BigEndian.Uint16(msg[4:6])) != p.id || int(binary.BigEndian.Uint16(msg[6:8])) != p.seq {
+			return reply{}, false
+		}
+		return reply{addr: peer, reached: true}, true
+	case icmpTimeExceeded, icmpDestUnreachable:
+		if !quotesProbe(msg[8:], p) {
+			return reply{}, false
+		}
+		r := reply{addr: peer}
+		if msg[0] == icmpDestUnreachable {
+			r.final = true
+			r.reached = peer == p.dst
+		}
+		return r, true
+	default:
+		return reply{}, false
+	}
+}
+
+// quotesProbe checks the original datagram quoted in an ICMP error (IPv4 header + first 8 bytes).
+func quotesProbe(inner []byte, p probe) bool {
+	if len(inner) < 20 || inner[0]>>4 != 4 {
+		return false
+	}
+	ihl := int(inner[0]&0x0f) * 4
+	if ihl < 20 || len(inner) < ihl+8 {
+		return false
+	}
+	if int(inner[9]) != p.proto {
+		return false
+	}
+	if dst, ok := netip.AddrFromSlice(inner[16:20]); !ok || dst != p.dst {
+		return false
+	}
+	transport := inner[ihl:]
+	switch p.proto {
+	case protoICMP:
+		return transport[0] == icmpEchoRequest &&
+			int(binary.BigEndian.Uint16(transport[4:6])) == p.id &&
+			int(binary.BigEndian.Uint16(transport[6:8])) == p.seq
+	case protoUDP:
+		return (p.srcPort == 0 || int(binary.BigEndian.Uint16(transport[0:2])) == p.srcPort) &&
+			int(binary.BigEndian.Uint16(transport[2:4])) == p.dstPort
+	default:
+		return false
+	}
+}
+
+// echoRequest builds an ICMP echo request.
+func echoRequest(id, seq int, data []byte) []byte {
+	b := make([]byte, 8+len(data))
+	b[0] = icmpEchoRequest
+	binary.BigEndian.PutUint16(b[4:6], uint16(id))
+	binary.BigEndian.PutUint16(b[6:8], uint16(seq))
+	copy(b[8:], data)
+	binary.BigEndian.PutUint16(b[2:4], checksum(b))
+	return b
+}
+
+// checksum is the Internet checksum (RFC 1071).
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// setTTL sets the unicast IPv4 TTL on a socket.
+func setTTL(conn net.PacketConn, ttl int) error {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return errors.New("socket does not expose a file descriptor")
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var setErr error
+	if err := raw.Control(func(fd uintptr) {
+		setErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TTL, ttl)
+	}); err != nil {
+		return err
+	}
+	return setErr
+}
@@ -0,0 +1,145 @@
+package traceroute
+
+import (
+	"context"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// icmpError builds a time exceeded / destination unreachable message quoting an IPv4 header and the first
+// 8 bytes of the original datagram.
+func icmpError(typ, code byte, proto byte, dst netip.Addr, transport []byte) []byte {
+	inner := make([]byte, 20)
+	inner[0] = 0x45
+	inner[8] = 1
+	inner[9] = proto
+	copy(inner[12:16], netip.MustParseAddr("192.0.2.10").AsSlice())
+	copy(inner[16:20], dst.AsSlice())
+	msg := []byte{typ, code, 0, 0, 0, 0, 0, 0}
+	return append(append(msg, inner...), transport[:8]...)
+}
+
+func udpHeader(src, dst int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint16(b[0:2], uint16(src))
+	binary.BigEndian.PutUint16(b[2:4], uint16(dst))
+	return b
+}
+
+func TestMatchReply(t *testing.T) {
+	target := netip.MustParseAddr("10.20.0.1")
+	router := netip.MustParseAddr("10.0.0.1")
+	icmpProbe := probe{proto: protoICMP, dst: target, id: 77, seq: 3}
+	udpProbe := probe{proto: protoUDP, dst: target, srcPort: 40000, dstPort: udpBasePort + 2}
+
+	echoReply := echoRequest(77, 3, []byte("x"))
+	echoReply[0] = icmpEchoReply
+
+	cases := []struct {
+		name    string
+		msg     []byte
+		peer    netip.Addr
+		probe   probe
+		ok      bool
+		reached bool
+		final   bool
+	}{
+		{name: "echo reply from target", msg: echoReply, peer: target, probe: icmpProbe, ok: true, reached: true},
+		{name: "echo reply for another sequence", msg: echoRequestWithType(icmpEchoReply, 77, 4), peer: target, probe: icmpProbe},
+		{name: "time exceeded quoting echo", msg: icmpError(icmpTimeExceeded, 0, protoICMP, target, echoRequest(77, 3, nil)), peer: router, probe: icmpProbe, ok: true},
+		{name: "time exceeded for another identifier", msg: icmpError(icmpTimeExceeded, 0, protoICMP, target, echoRequest(78, 3, nil)), peer: router, probe: icmpProbe},
+		{name: "time exceeded quoting udp", msg: icmpError(icmpTimeExceeded, 0, protoUDP, target, udpHeader(40000, udpBasePort+2)), peer: router, probe: udpProbe, ok: true},
+		{name: "port unreachable from target", msg: icmpError(icmpDestUnreachable, 3, protoUDP, target, udpHeader(40000, udpBasePort+2)), peer: target, probe: udpProbe, ok: true, reached: true, final: true},
+		{name: "host unreachable from router", msg: icmpError(icmpDestUnreachable, 1, protoUDP, target, udpHeader(40000, udpBasePort+2)), peer: router, probe: udpProbe, ok: true, final: true},
+		{name: "udp error for another port", msg: icmpError(icmpTimeExceeded, 0, protoUDP, target, udpHeader(40000, udpBasePort+5)), peer: router, probe: udpProbe},
+		{name: "truncated", msg: []byte{icmpTimeExceeded, 0, 0}, peer: router, probe: icmpProbe},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, ok := matchReply(tc.msg, tc.peer, tc.probe)
+			if ok != tc.ok {
+				t.Fatalf("expected ok=%v, got %v", tc.ok, ok)
+			}
+			if !ok {
+				return
+			}
+			if r.addr != tc.peer || r.reached != tc.reached || r.final != tc.final {
+				t.Fatalf("unexpected reply %+v", r)
+			}
+		})
+	}
+}
+
+func echoRequestWithType(typ byte, id, seq int) []byte {
+	b := echoRequest(id, seq, nil)
+	b[0] = typ
+	return b
+}
+
+func TestTracePath(t *testing.T) {
+	target := netip.MustParseAddr("10.20.0.1")
+	hop := func(s string) reply { return reply{addr: netip.MustParseAddr(s), rtt: time.Millisecond} }
+
+	cases := []struct {
+		name    string
+		replies []reply
+		maxGap  int
+		want    []string // "" = silent hop
+		reached bool
+	}{
+		{
+			name:    "reaches target through a silent hop",
+			replies: []reply{hop("10.0.0.1"), {}, hop("10.10.0.1"), {addr: target, reached: true}},
+			maxGap:  3,
+			want:    []string{"10.0.0.1", "", "10.10.0.1", "10.20.0.1"},
+			reached: true,
+		},
+		{
+			name:    "gives up after consecutive silent hops and trims them",
+			replies: []reply{hop("10.0.0.1"), {}, {}, {}, hop("10.99.0.1")},
+			maxGap:  2,
+			want:    []string{"10.0.0.1"},
+		},
+		{
+			name:    "stops at unreachable",
+			replies: []reply{hop("10.0.0.1"), {addr: netip.MustParseAddr("10.0.0.2"), final: true}, hop("10.0.0.3")},
+			maxGap:  3,
+			want:    []string{"10.0.0.1", "10.0.0.2"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			send := func(ctx context.Context, ttl int) (reply, error) {
+				if ttl > len(tc.replies) {
+					return reply{}, nil
+				}
+				return tc.replies[ttl-1], nil
+			}
+			path, err := tracePath(context.Background(), target, Config{MaxHops: 10, MaxGap: tc.maxGap}, send)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if path.Reached != tc.reached || len(path.Hops) != len(tc.want) {
+				t.Fatalf("unexpected path %+v", path)
+			}
+			for i, want := range tc.want {
+				h := path.Hops[i]
+				if h.TTL != i+1 {
+					t.Fatalf("hop %d has ttl %d", i, h.TTL)
+				}
+				if (want == "" && h.Addr.IsValid()) || (want != "" && h.Addr.String() != want) {
+					t.Fatalf("hop %d: expected %q, got %v", i, want, h.Addr)
+				}
+			}
+		})
+	}
+}
+
+func TestEchoRequestChecksum(t *testing.T) {
+	msg := echoRequest(0x1234, 7, []byte("roller_hoops"))
+	if checksum(msg) != 0 {
+		t.Fatalf("checksum over a complete message must be zero, got %#x", checksum(msg))
+	}
+}
@@ -0,0 +1,508 @@
+package udpprobe
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/miekg/dns"
+
+	"roller_hoops/core-go/internal/enrichment/mdns"
+)
+
+// Config controls how UDP probes are sent.
+type Config struct {
+	Timeout       time.Duration
+	Retries       int
+	SNMPCommunity string
+}
+
+// Result is the outcome of probing one UDP port.
+//
+// State is "open" when the service replied in its protocol, "closed" when the host returned ICMP port
+// unreachable, and "filtered" when nothing that parses came back (which proves nothing either way).
+type Result struct {
+	Port    int
+	Name    string
+	State   string
+	Summary string
+}
+
+// probe pairs a payload builder with a parser for the matching reply.
+//
+// build returns the request bytes plus any state parse needs to validate the reply (e.g. a transaction ID).
+// parse returns a short human-readable summary; ok=false means the reply did not look like the protocol.
+type probe struct {
+	name  string
+	port  int
+	build func(cfg Config) ([]byte, any, error)
+	parse func(resp []byte, state any) (summary string, ok bool)
+}
+
+var probes = []probe{
+	{name: "dns", port: 53, build: buildDNSProbe, parse: parseDNSReply},
+	{name: "tftp", port: 69, build: buildTFTPProbe, parse: parseTFTPReply},
+	{name: "ntp", port: 123, build: buildNTPProbe, parse: parseNTPReply},
+	{name: "netbios-ns", port: 137, build: buildNetBIOSProbe, parse: parseNetBIOSReply},
+	{name: "snmp", port: 161, build: buildSNMPProbe, parse: parseSNMPReply},
+	{name: "syslog", port: 514, build: buildSyslogProbe, parse: parseSyslogReply},
+	{name: "ipmi", port: 623, build: buildRMCPProbe, parse: parseRMCPReply},
+	{name: "ssdp", port: 1900, build: buildSSDPProbe, parse: parseSSDPReply},
+	{name: "mdns", port: 5353, build: buildMDNSProbe, parse: parseMDNSReply},
+}
+
+// DefaultPorts returns every UDP port that has a protocol-specific probe, sorted ascending.
+func DefaultPorts() []int {
+	out := make([]int, 0, len(probes))
+	for _, p := range probes {
+		out = append(out, p.port)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// Prober sends protocol-aware UDP payloads and only treats ports that reply as open.
+//
+// A bare UDP scan cannot tell "open" from "filtered" on hosts that suppress ICMP unreachables,
+// so a port is only reported open when the service answers with a payload.
+type Prober struct {
+	cfg Config
+}
+
+// NewProber constructs a Prober with sane defaults.
+func NewProber(cfg Config) *Prober {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Second
+	}
+	if cfg.Retries < 0 {
+		cfg.Retries = 0
+	}
+	if strings.TrimSpace(cfg.SNMPCommunity) == "" {
+		cfg.SNMPCommunity = "public"
+	}
+	return &Prober{cfg: cfg}
+}
+
+// ProbeHost probes the given ports on address. Ports without a known probe, or that could not be probed
+// at all (e.g. local dial errors), are skipped.
+//
+// The ports are probed concurrently, each on its own socket, so a silent host costs one probe's
+// Timeout × (Retries+1) rather than that per port.
+func (p *Prober) ProbeHost(ctx context.Context, address string, ports []int) []Result {
+	if p == nil || net.ParseIP(address) == nil {
+		return nil
+	}
+
+	wanted := make(map[int]struct{}, len(ports))
+	for _, port := range ports {
+		wanted[port] = struct{}{}
+	}
+
+	var selected []probe
+	for _, pr := range probes {
+		if _, ok := wanted[pr.port]; ok {
+			selected = append(selected, pr)
+		}
+	}
+
+	results := make([]*Result, len(selected))
+	var wg sync.WaitGroup
+	for i, pr := range selected {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if res, err := p.probeOne(ctx, address, pr); err == nil {
+				results[i] = &res
+			}
+		}()
+	}
+	wg.Wait()
+
+	var out []Result
+	for _, res := range results {
+		if res != nil {
+			out = append(out, *res)
+		}
+	}
+	return out
+}
+
+func (p *Prober) probeOne(ctx context.Context, address string, pr probe) (Result, error) {
+	res := Result{Port: pr.port, Name: pr.name, State: "filtered"}
+
+	req, state, err := pr.build(p.cfg)
+	if err != nil {
+		return res, fmt.Errorf("udpprobe %s: build: %w", pr.name, err)
+	}
+
+	dialer := &net.Dialer{Timeout: p.cfg.Timeout}
+	conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(address, strconv.Itoa(pr.port)))
+	if err != nil {
+		return res, fmt.Errorf("udpprobe %s: dial: %w", pr.name, err)
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 4096)
+attempts:
+	for attempt := 0; attempt <= p.cfg.Retries; attempt++ {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		deadline := time.Now().Add(p.cfg.Timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		_ = conn.SetDeadline(deadline)
+
+		if _, err := conn.Write(req); err != nil {
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				res.State = "closed"
+				return res, nil
+			}
+			return res, fmt.Errorf("udpprobe %s: write: %w", pr.name, err)
+		}
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				var ne net.Error
+				if errors.As(err, &ne) && ne.Timeout() {
+					continue attempts
+				}
+				// ICMP port unreachable surfaces as ECONNREFUSED on a connected socket.
+				if errors.Is(err, syscall.ECONNREFUSED) {
+					res.State = "closed"
+					return res, nil
+				}
+				return res, fmt.Errorf("udpprobe %s: read: %w", pr.name, err)
+			}
+			if n == 0 {
+				continue
+			}
+			// A reply that does not parse, or answers another transaction, is not this service answering
+			// the probe (a late reply, a reflector, noise); keep listening until the deadline.
+			summary, ok := pr.parse(buf[:n], state)
+			if !ok {
+				continue
+			}
+			res.State = "open"
+			res.Summary = summary
+			return res, nil
+		}
+	}
+	return res, nil
+}
+
+func randomUint16() (uint16, error) {
+	var buf [2]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(buf[:]), nil
+}
+
+func printable(b []byte, max int) string {
+	var sb strings.Builder
+	for _, c := range b {
+		if sb.Len() >= max {
+			break
+		}
+		if c >= 0x20 && c < 0x7f {
+			sb.WriteByte(c)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// DNS: CHAOS TXT version.bind. Servers that refuse still answer with an rcode.
+
+func buildDNSProbe(_ Config) ([]byte, any, error) {
+	id, err := randomUint16()
+	if err != nil {
+		return nil, nil, err
+	}
+	msg := &dns.Msg{}
+	msg.Id = id
+	msg.RecursionDesired = false
+	msg.Question = []dns.Question{{Name: "version.bind.", Qtype: dns.TypeTXT, Qclass: dns.ClassCHAOS}}
+	b, err := msg.Pack()
+	return b, id, err
+}
+
+func parseDNSReply(resp []byte, state any) (string, bool) {
+	msg := &dns.Msg{}
+	if err := msg.Unpack(resp); err != nil {
+		return "", false
+	}
+	if id, ok := state.(uint16); ok && msg.Id != id {
+		return "", false
+	}
+	if !msg.Response {
+		return "", false
+	}
+	parts := []string{"rcode=" + dns.RcodeToString[msg.Rcode]}
+	for _, rr := range msg.Answer {
+		if txt, ok := rr.(*dns.TXT); ok && len(txt.Txt) > 0 {
+			parts = append(parts, "version="+strings.Join(txt.Txt, " "))
+			break
+		}
+	}
+	return "dns " + strings.Join(parts, " "), true
+}
+
+// TFTP: read request for a file that should not exist; any DATA or ERROR reply proves a server.
+
+func buildTFTPProbe(_ Config) ([]byte, any, error) {
+	req := []byte{0x00, 0x01}
+	req = append(req, []byte("roller-hoops-probe")...)
+	req = append(req, 0x00)
+	req = append(req, []byte("octet")...)
+	req = append(req, 0x00)
+	return req, nil, nil
+}
+
+func parseTFTPReply(resp []byte, _ any) (string, bool) {
+	if len(resp) < 4 {
+		return "", false
+	}
+	switch binary.BigEndian.Uint16(resp[0:2]) {
+	case 3:
+		return "tftp data", true
+	case 5:
+		code := binary.BigEndian.Uint16(resp[2:4])
+		msg := printable(resp[4:], 64)
+		if msg == "" {
+			return fmt.Sprintf("tftp error code=%d", code), true
+		}
+		return fmt.Sprintf("tftp error code=%d msg=%q", code, msg), true
+	default:
+		return "", false
+	}
+}
+
+// NTP: v3 client request.
+
+func buildNTPProbe(_ Config) ([]byte, any, error) {
+	req := make([]byte, 48)
+	req[0] = 0x1b // LI=0, VN=3, Mode=3 (client)
+	return req, nil, nil
+}
+
+func parseNTPReply(resp []byte, _ any) (string, bool) {
+	if len(resp) < 48 {
+		return "", false
+	}
+	mode := resp[0] & 0x07
+	if mode != 4 && mode != 5 {
+		return "", false
+	}
+	version := (resp[0] >> 3) & 0x07
+	stratum := resp[1]
+	refID := resp[12:16]
+	ref := ""
+	if stratum <= 1 {
+		ref = printable(refID, 4)
+	} else {
+		ref = net.IP(refID).String()
+	}
+	if ref == "" {
+		return fmt.Sprintf("ntp v%d stratum=%d", version, stratum), true
+	}
+	return fmt.Sprintf("ntp v%d stratum=%d refid=%s", version, stratum, ref), true
+}
+
+// NetBIOS name service: wildcard node status query.
+
+func buildNetBIOSProbe(_ Config) ([]byte, any, error) {
+	req, txID, err := mdns.NetBIOSNodeStatusRequest()
+	return req, txID, err
+}
+
+func parseNetBIOSReply(resp []byte, state any) (string, bool) {
+	txID, _ := state.(uint16)
+	names, err := mdns.ParseNetBIOSNodeStatus(resp, txID)
+	if err != nil {
+		return "", false
+	}
+	if len(names) == 0 {
+		return "netbios-ns", true
+	}
+	return "netbios-ns names=" + strings.Join(names, ","), true
+}
+
+// SNMP: v2c get sysDescr.0 with the configured community. A wrong community is silently dropped by agents.
+
+const sysDescrOID = ".1.3.6.1.2.1.1.1.0"
+
+func buildSNMPProbe(cfg Config) ([]byte, any, error) {
+	id, err := randomUint16()
+	if err != nil {
+		return nil, nil, err
+	}
+	pkt := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: cfg.SNMPCommunity,
+		PDUType:   gosnmp.GetRequest,
+		RequestID: uint32(id),
+		Variables: []gosnmp.SnmpPDU{{Name: sysDescrOID, Type: gosnmp.Null}},
+	}
+	b, err := pkt.MarshalMsg()
+	return b, uint32(id), err
+}
+
+func parseSNMPReply(resp []byte, state any) (string, bool) {
+	decoder := &gosnmp.GoSNMP{Version: gosnmp.Version2c}
+	pkt, err := decoder.SnmpDecodePacket(resp)
+	if err != nil || pkt == nil {
+		return "", false
+	}
+	if id, ok := state.(uint32); ok && pkt.RequestID != id {
+		return "", false
+	}
+	for _, v := range pkt.Variables {
+		if v.Name != sysDescrOID {
+			continue
+		}
+		if b, ok := v.Value.([]byte); ok {
+			if descr := printable(b, 96); descr != "" {
+				return "snmp sysDescr=" + descr, true
+			}
+		}
+	}
+	return "snmp", true
+}
+
+// syslog: receivers do not answer, so this only reports relays/collectors that echo or acknowledge.
+
+func buildSyslogProbe(_ Config) ([]byte, any, error) {
+	return []byte("<14>1 - - roller-hoops - - - udp service probe"), nil, nil
+}
+
+func parseSyslogReply(resp []byte, _ any) (string, bool) {
+	if msg := printable(resp, 64); msg != "" {
+		return "syslog reply=" + msg, true
+	}
+	return "syslog", true
+}
+
+// IPMI: RMCP ASF presence ping; BMCs answer with a presence pong.
+
+func buildRMCPProbe(_ Config) ([]byte, any, error) {
+	return []byte{
+		0x06, 0x00, 0xff, 0x06, // RMCP v1, seq 0xff (no ack), class ASF
+		0x00, 0x00, 0x11, 0xbe, // ASF IANA enterprise number (4542)
+		0x80, 0x00, 0x00, 0x00, // presence ping, tag 0, reserved, data length 0
+	}, nil, nil
+}
+
+func parseRMCPReply(resp []byte, _ any) (string, bool) {
+	if len(resp) < 12 || resp[0] != 0x06 || resp[3]&0x0f != 0x06 {
+		return "", false
+	}
+	if resp[8] != 0x40 {
+		return "", false
+	}
+	if len(resp) >= 21 && resp[20]&0x80 != 0 {
+		return "rmcp presence pong ipmi=supported", true
+	}
+	return "rmcp presence pong", true
+}
+
+// SSDP: unicast M-SEARCH; UPnP devices answer with an HTTP-style response.
+
+func buildSSDPProbe(_ Config) ([]byte, any, error) {
+	req := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: 239.255.255.250:1900\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 1\r\n" +
+		"ST: ssdp:all\r\n\r\n"
+	return []byte(req), nil, nil
+}
+
+func parseSSDPReply(resp []byte, _ any) (string, bool) {
+	text := string(resp)
+	if !strings.HasPrefix(text, "HTTP/1.1") && !strings.HasPrefix(text, "NOTIFY") {
+		return "", false
+	}
+	parts := []string{"ssdp"}
+	for _, line := range strings.Split(text, "\r\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "server":
+			parts = append(parts, "server="+printable([]byte(value), 96))
+		case "st":
+			parts = append(parts, "st="+printable([]byte(value), 96))
+		}
+	}
+	return strings.Join(parts, " "), true
+}
+
+// mDNS: legacy unicast DNS-SD service enumeration sent directly to the host.
+
+func buildMDNSProbe(_ Config) ([]byte, any, error) {
+	id, err := randomUint16()
+	if err != nil {
+		return nil, nil, err
+	}
+	msg := &dns.Msg{}
+	msg.SetQuestion("_services._dns-sd._udp.local.", dns.TypePTR)
+	msg.Id = id
+	msg.RecursionDesired = false
+	b, err := msg.Pack()
+	return b, id, err
+}
+
+func parseMDNSReply(resp []byte, state any) (string, bool) {
+	msg := &dns.Msg{}
+	if err := msg.Unpack(resp); err != nil {
+		return "", false
+	}
+	// Legacy unicast responses echo the query ID (RFC 6762 section 6.7).
+	if id, ok := state.(uint16); ok && msg.Id != id {
+		return "", false
+	}
+	if !msg.Response {
+		return "", false
+	}
+	services := make([]string, 0, len(msg.Answer))
+	seen := map[string]struct{}{}
+	for _, rr := range msg.Answer {
+		ptr, ok := rr.(*dns.PTR)
+		if !ok {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimSuffix(ptr.Ptr, "."), ".local")
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		services = append(services, name)
+	}
+	if len(services) == 0 {
+		return "mdns", true
+	}
+	sort.Strings(services)
+	if len(services) > 8 {
+		services = services[:8]
+	}
+	return "mdns services=" + strings.Join(services, ","), true
+}
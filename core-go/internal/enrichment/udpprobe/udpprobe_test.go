@@ -0,0 +1,180 @@
+package udpprobe
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/miekg/dns"
+)
+
+func TestParseReplies(t *testing.T) {
+	dnsReply := func(id uint16) []byte {
+		msg := &dns.Msg{}
+		msg.Id = id
+		msg.Response = true
+		msg.Question = []dns.Question{{Name: "version.bind.", Qtype: dns.TypeTXT, Qclass: dns.ClassCHAOS}}
+		msg.Answer = []dns.RR{&dns.TXT{
+			Hdr: dns.RR_Header{Name: "version.bind.", Rrtype: dns.TypeTXT, Class: dns.ClassCHAOS},
+			Txt: []string{"9.18.1"},
+		}}
+		b, err := msg.Pack()
+		if err != nil {
+			t.Fatalf("pack dns: %v", err)
+		}
+		return b
+	}
+
+	mdnsReply := func(id uint16) []byte {
+		msg := &dns.Msg{}
+		msg.Id = id
+		msg.Response = true
+		msg.Answer = []dns.RR{&dns.PTR{
+			Hdr: dns.RR_Header{Name: "_services._dns-sd._udp.local.", Rrtype: dns.TypePTR, Class: dns.ClassINET},
+			Ptr: "_http._tcp.local.",
+		}}
+		b, err := msg.Pack()
+		if err != nil {
+			t.Fatalf("pack mdns: %v", err)
+		}
+		return b
+	}
+
+	ntpReply := make([]byte, 48)
+	ntpReply[0] = 0x24 // VN=4, Mode=4 (server)
+	ntpReply[1] = 2
+	copy(ntpReply[12:16], []byte{192, 0, 2, 1})
+
+	tftpErr := []byte{0x00, 0x05, 0x00, 0x01}
+	tftpErr = append(tftpErr, []byte("File not found")...)
+	tftpErr = append(tftpErr, 0x00)
+
+	rmcpPong := make([]byte, 28)
+	copy(rmcpPong, []byte{0x06, 0x00, 0xff, 0x06, 0x00, 0x00, 0x11, 0xbe, 0x40, 0x00, 0x00, 0x10})
+	rmcpPong[20] = 0x81
+
+	snmpReply := func(id uint32) []byte {
+		pkt := &gosnmp.SnmpPacket{
+			Version:   gosnmp.Version2c,
+			Community: "public",
+			PDUType:   gosnmp.GetResponse,
+			RequestID: id,
+			Variables: []gosnmp.SnmpPDU{{Name: sysDescrOID, Type: gosnmp.OctetString, Value: "Linux nas01 5.10"}},
+		}
+		b, err := pkt.MarshalMsg()
+		if err != nil {
+			t.Fatalf("marshal snmp: %v", err)
+		}
+		return b
+	}
+
+	cases := []struct {
+		name  string
+		parse func([]byte, any) (string, bool)
+		resp  []byte
+		state any
+		want  string
+		ok    bool
+	}{
+		{name: "dns version", parse: parseDNSReply, resp: dnsReply(7), state: uint16(7), want: "dns rcode=NOERROR version=9.18.1", ok: true},
+		{name: "dns id mismatch", parse: parseDNSReply, resp: dnsReply(7), state: uint16(8), ok: false},
+		{name: "ntp server", parse: parseNTPReply, resp: ntpReply, want: "ntp v4 stratum=2 refid=192.0.2.1", ok: true},
+		{name: "ntp short", parse: parseNTPReply, resp: ntpReply[:10], ok: false},
+		{name: "tftp error", parse: parseTFTPReply, resp: tftpErr, want: `tftp error code=1 msg="File not found"`, ok: true},
+		{name: "rmcp pong", parse: parseRMCPReply, resp: rmcpPong, want: "rmcp presence pong ipmi=supported", ok: true},
+		{name: "ssdp", parse: parseSSDPReply, resp: []byte("HTTP/1.1 200 OK\r\nST: upnp:rootdevice\r\nSERVER: Linux/3.x UPnP/1.0 miniupnpd/2.0\r\n\r\n"), want: "ssdp st=upnp:rootdevice server=Linux/3.x UPnP/1.0 miniupnpd/2.0", ok: true},
+		{name: "ssdp garbage", parse: parseSSDPReply, resp: []byte("hello"), ok: false},
+		{name: "mdns services", parse: parseMDNSReply, resp: mdnsReply(9), state: uint16(9), want: "mdns services=_http._tcp", ok: true},
+		{name: "mdns id mismatch", parse: parseMDNSReply, resp: mdnsReply(9), state: uint16(10), ok: false},
+		{name: "snmp sysdescr", parse: parseSNMPReply, resp: snmpReply(42), state: uint32(42), want: "snmp sysDescr=Linux nas01 5.10", ok: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := tc.parse(tc.resp, tc.state)
+			if ok != tc.ok {
+				t.Fatalf("expected ok=%v, got %v (%q)", tc.ok, ok, got)
+			}
+			if tc.ok && got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildProbes(t *testing.T) {
+	cfg := Config{SNMPCommunity: "public"}
+	for _, pr := range probes {
+		req, _, err := pr.build(cfg)
+		if err != nil {
+			t.Fatalf("%s: build: %v", pr.name, err)
+		}
+		if len(req) == 0 {
+			t.Fatalf("%s: empty payload", pr.name)
+		}
+	}
+
+	req, _, _ := buildTFTPProbe(cfg)
+	if binary.BigEndian.Uint16(req[0:2]) != 1 || !strings.Contains(string(req), "octet") {
+		t.Fatalf("unexpected tftp request %q", req)
+	}
+
+	ports := DefaultPorts()
+	if len(ports) != len(probes) || ports[0] != 53 || ports[len(ports)-1] != 5353 {
+		t.Fatalf("unexpected default ports %v", ports)
+	}
+}
+
+func TestProbeOne_IgnoresRepliesThatDoNotParse(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot bind: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	// Answer every query twice: first with garbage and a wrong transaction ID, then (when matching) correctly.
+	var matching atomic.Bool
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			query := &dns.Msg{}
+			if err := query.Unpack(buf[:n]); err != nil {
+				continue
+			}
+			reply := &dns.Msg{}
+			reply.SetReply(query)
+			reply.Id = query.Id + 1
+			wrongID, _ := reply.Pack()
+			_, _ = conn.WriteTo([]byte("not dns"), from)
+			_, _ = conn.WriteTo(wrongID, from)
+			if matching.Load() {
+				reply.Id = query.Id
+				b, _ := reply.Pack()
+				_, _ = conn.WriteTo(b, from)
+			}
+		}
+	}()
+
+	pr := probe{name: "dns", port: conn.LocalAddr().(*net.UDPAddr).Port, build: buildDNSProbe, parse: parseDNSReply}
+	p := NewProber(Config{Timeout: 200 * time.Millisecond})
+
+	res, err := p.probeOne(context.Background(), "127.0.0.1", pr)
+	if err != nil || res.State != "filtered" || res.Summary != "" {
+		t.Fatalf("expected replies that do not parse to leave the port filtered, got %+v (%v)", res, err)
+	}
+
+	matching.Store(true)
+	res, err = p.probeOne(context.Background(), "127.0.0.1", pr)
+	if err != nil || res.State != "open" || res.Summary != "dns rcode=NOERROR" {
+		t.Fatalf("expected the matching reply to be read past the others, got %+v (%v)", res, err)
+	}
+}
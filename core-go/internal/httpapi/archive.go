@@ -0,0 +1,163 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// defaultPurgeActor is recorded in the audit log when a purge does not name an actor.
+const defaultPurgeActor = "api"
+
+type deviceArchiveRequest struct {
+	Actor  *string `json:"actor,omitempty"`
+	Reason *string `json:"reason,omitempty"`
+}
+
+type deviceArchiveQueries interface {
+	ArchiveDevice(ctx context.Context, arg sqlcgen.DeviceArchiveParams) (int64, error)
+	RestoreDevice(ctx context.Context, arg sqlcgen.DeviceArchiveParams) (int64, error)
+	PurgeDevice(ctx context.Context, arg sqlcgen.PurgeDeviceParams) error
+}
+
+func (h *Handler) deviceArchiveQueries(w http.ResponseWriter) (deviceArchiveQueries, bool) {
+	if !h.ensureDeviceQueries(w) {
+		return nil, false
+	}
+	q, ok := h.devices.(deviceArchiveQueries)
+	if !ok {
+		h.log.Error().Msg("device archive queries missing")
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "device archive not supported", nil)
+		return nil, false
+	}
+	return q, true
+}
+
+// getDeviceForAction loads a device before acting on it, writing the not_found / invalid_id errors.
+func (h *Handler) getDeviceForAction(w http.ResponseWriter, r *http.Request, id, action string) (sqlcgen.Device, bool) {
+	row, err := h.devices.GetDevice(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			h.writeError(w, http.StatusNotFound, "not_found", "device not found", map[string]any{"id": id})
+		case isInvalidUUID(err):
+			h.writeError(w, http.StatusBadRequest, "invalid_id", "device id is not a valid uuid", map[string]any{"id": id})
+		default:
+			h.log.Error().Err(err).Str("id", id).Msgf("fetch device before %s failed", action)
+			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to "+action+" device", nil)
+		}
+		return sqlcgen.Device{}, false
+	}
+	return row, true
+}
+
+// handleArchiveDevice hides a device from lists, maps and IP/name matching. Archiving an archived
+// device is a no-op; discovery unarchives it when its MAC or DHCP client ID is seen again.
+func (h *Handler) handleArchiveDevice(w http.ResponseWriter, r *http.Request) {
+	h.setDeviceArchived(w, r, true)
+}
+
+// handleRestoreDevice makes an archived device active again; restoring an active device is a no-op.
+func (h *Handler) handleRestoreDevice(w http.ResponseWriter, r *http.Request) {
+	h.setDeviceArchived(w, r, false)
+}
+
+func (h *Handler) setDeviceArchived(w http.ResponseWriter, r *http.Request, archive bool) {
+	action := "restore"
+	if archive {
+		action = "archive"
+	}
+	id := chi.URLParam(r, "id")
+	var req deviceArchiveRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSONStrict(r, &req); err != nil {
+			h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid json body", map[string]any{"error": err.Error()})
+			return
+		}
+	}
+	q, ok := h.deviceArchiveQueries(w)
+	if !ok {
+		return
+	}
+	if _, ok := h.getDeviceForAction(w, r, id, action); !ok {
+		return
+	}
+
+	ctx := r.Context()
+	arg := sqlcgen.DeviceArchiveParams{
+		ID:     id,
+		Actor:  normalizeStringPtr(req.Actor),
+		Reason: normalizeStringPtr(req.Reason),
+	}
+	var err error
+	if archive {
+		_, err = q.ArchiveDevice(ctx, arg)
+	} else {
+		_, err = q.RestoreDevice(ctx, arg)
+	}
+	if err != nil {
+		h.log.Error().Err(err).Str("id", id).Msgf("%s device failed", action)
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to "+action+" device", nil)
+		return
+	}
+
+	row, err := h.devices.GetDevice(ctx, id)
+	if err != nil {
+		h.log.Error().Err(err).Str("id", id).Msgf("get device after %s failed", action)
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to fetch device", nil)
+		return
+	}
+	out := toDevice(row)
+	if tags, err := h.devices.ListDeviceEffectiveTags(ctx, row.ID); err == nil && len(tags) > 0 {
+		out.Tags = tags
+	}
+	h.writeJSON(w, http.StatusOK, out)
+}
+
+// handlePurgeDevice permanently deletes an archived device with all of its facts and history. Only
+// archived devices can be purged; the purge is recorded as a `device.purge` audit event.
+func (h *Handler) handlePurgeDevice(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	query := r.URL.Query()
+	actor := defaultPurgeActor
+	if v := strings.TrimSpace(query.Get("actor")); v != "" {
+		actor = v
+	}
+	actorRole, reason := query.Get("actor_role"), query.Get("reason")
+
+	q, ok := h.deviceArchiveQueries(w)
+	if !ok {
+		return
+	}
+	if _, ok := h.getDeviceForAction(w, r, id, "purge"); !ok {
+		return
+	}
+
+	err := q.PurgeDevice(r.Context(), sqlcgen.PurgeDeviceParams{
+		ID:        id,
+		Actor:     actor,
+		ActorRole: normalizeStringPtr(&actorRole),
+		Reason:    normalizeStringPtr(&reason),
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, sqlcgen.ErrDeviceNotArchived):
+		h.writeError(w, http.StatusConflict, "conflict", "device must be archived before it can be purged", map[string]any{"id": id})
+		return
+	case errors.Is(err, pgx.ErrNoRows):
+		h.writeError(w, http.StatusNotFound, "not_found", "device not found", map[string]any{"id": id})
+		return
+	default:
+		h.log.Error().Err(err).Str("id", id).Msg("purge device failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to purge device", nil)
+		return
+	}
+	h.log.Info().Str("id", id).Str("actor", actor).Msg("device purged")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,211 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+type fakeDeviceQueriesWithArchive struct {
+	fakeDeviceQueries
+	archiveFn func(ctx context.Context, arg sqlcgen.DeviceArchiveParams) (int64, error)
+	restoreFn func(ctx context.Context, arg sqlcgen.DeviceArchiveParams) (int64, error)
+	purgeFn   func(ctx context.Context, arg sqlcgen.PurgeDeviceParams) error
+}
+
+func (f fakeDeviceQueriesWithArchive) ArchiveDevice(ctx context.Context, arg sqlcgen.DeviceArchiveParams) (int64, error) {
+	return f.archiveFn(ctx, arg)
+}
+
+func (f fakeDeviceQueriesWithArchive) RestoreDevice(ctx context.Context, arg sqlcgen.DeviceArchiveParams) (int64, error) {
+	return f.restoreFn(ctx, arg)
+}
+
+func (f fakeDeviceQueriesWithArchive) PurgeDevice(ctx context.Context, arg sqlcgen.PurgeDeviceParams) error {
+	return f.purgeFn(ctx, arg)
+}
+
+// newArchiveTestHandler serves one known device whose archived_at follows archive/restore calls.
+func newArchiveTestHandler(deviceID string, archivedAt *time.Time, calls *[]string) *Handler {
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueriesWithArchive{
+		fakeDeviceQueries: fakeDeviceQueries{
+			getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+				if id != deviceID {
+					return sqlcgen.Device{}, pgx.ErrNoRows
+				}
+				return sqlcgen.Device{ID: id, ArchivedAt: archivedAt}, nil
+			},
+		},
+		archiveFn: func(ctx context.Context, arg sqlcgen.DeviceArchiveParams) (int64, error) {
+			*calls = append(*calls, "archive:"+derefString(arg.Actor)+":"+derefString(arg.Reason))
+			now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+			archivedAt = &now
+			return 1, nil
+		},
+		restoreFn: func(ctx context.Context, arg sqlcgen.DeviceArchiveParams) (int64, error) {
+			*calls = append(*calls, "restore:"+derefString(arg.Actor)+":"+derefString(arg.Reason))
+			archivedAt = nil
+			return 1, nil
+		},
+		purgeFn: func(ctx context.Context, arg sqlcgen.PurgeDeviceParams) error {
+			*calls = append(*calls, "purge:"+arg.Actor+":"+derefString(arg.Reason))
+			if archivedAt == nil {
+				return sqlcgen.ErrDeviceNotArchived
+			}
+			return nil
+		},
+	}
+	return h
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func TestDevices_ArchiveRestore(t *testing.T) {
+	deviceID := "00000000-0000-0000-0000-000000000001"
+	archivedAt := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name         string
+		path         string
+		body         string
+		archivedAt   *time.Time
+		wantCode     int
+		wantErr      string
+		wantCall     string
+		wantArchived bool
+	}{
+		{name: "archive", path: "/archive", body: `{"actor":"alice","reason":" retired "}`, wantCode: http.StatusOK, wantCall: "archive:alice:retired", wantArchived: true},
+		{name: "archive without body", path: "/archive", wantCode: http.StatusOK, wantCall: "archive::", wantArchived: true},
+		{name: "restore", path: "/restore", archivedAt: &archivedAt, wantCode: http.StatusOK, wantCall: "restore::"},
+		{name: "unknown field", path: "/archive", body: `{"who":"alice"}`, wantCode: http.StatusBadRequest, wantErr: "validation_failed"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			h := newArchiveTestHandler(deviceID, tc.archivedAt, &calls)
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/devices/"+deviceID+tc.path, strings.NewReader(tc.body))
+			h.Router().ServeHTTP(rr, req)
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			body := decodeBody(t, rr)
+			if tc.wantErr != "" {
+				if code := body["error"].(map[string]any)["code"]; code != tc.wantErr {
+					t.Fatalf("expected error code %q, got %v", tc.wantErr, code)
+				}
+				if len(calls) != 0 {
+					t.Fatalf("expected no query calls, got %v", calls)
+				}
+				return
+			}
+			if len(calls) != 1 || calls[0] != tc.wantCall {
+				t.Fatalf("expected call %q, got %v", tc.wantCall, calls)
+			}
+			if _, ok := body["archived_at"]; ok != tc.wantArchived {
+				t.Fatalf("expected archived_at present=%v, got %v", tc.wantArchived, body)
+			}
+		})
+	}
+}
+
+func TestDevices_ArchiveUnknownDevice(t *testing.T) {
+	var calls []string
+	h := newArchiveTestHandler("00000000-0000-0000-0000-000000000001", nil, &calls)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/devices/00000000-0000-0000-0000-000000000009/archive", nil)
+	h.Router().ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound || len(calls) != 0 {
+		t.Fatalf("expected 404 without archiving, got %d (%v)", rr.Code, calls)
+	}
+}
+
+func TestDevices_Purge(t *testing.T) {
+	deviceID := "00000000-0000-0000-0000-000000000001"
+	archivedAt := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name       string
+		id         string
+		query      string
+		archivedAt *time.Time
+		wantCode   int
+		wantCall   string
+	}{
+		{name: "archived device", id: deviceID, query: "?actor=alice&reason=sold", archivedAt: &archivedAt, wantCode: http.StatusNoContent, wantCall: "purge:alice:sold"},
+		{name: "default actor", id: deviceID, archivedAt: &archivedAt, wantCode: http.StatusNoContent, wantCall: "purge:api:"},
+		{name: "active device", id: deviceID, wantCode: http.StatusConflict, wantCall: "purge:api:"},
+		{name: "unknown device", id: "00000000-0000-0000-0000-000000000009", wantCode: http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			h := newArchiveTestHandler(deviceID, tc.archivedAt, &calls)
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/devices/"+tc.id+tc.query, nil)
+			h.Router().ServeHTTP(rr, req)
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if tc.wantCall == "" {
+				if len(calls) != 0 {
+					t.Fatalf("expected no purge, got %v", calls)
+				}
+				return
+			}
+			if len(calls) != 1 || calls[0] != tc.wantCall {
+				t.Fatalf("expected call %q, got %v", tc.wantCall, calls)
+			}
+		})
+	}
+}
+
+func TestDevices_ListArchivedFilter(t *testing.T) {
+	cases := []struct {
+		query    string
+		wantCode int
+		want     string
+	}{
+		{query: "", wantCode: http.StatusOK, want: "exclude"},
+		{query: "?archived=only", wantCode: http.StatusOK, want: "only"},
+		{query: "?archived=include", wantCode: http.StatusOK, want: "include"},
+		{query: "?archived=yes", wantCode: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.query, func(t *testing.T) {
+			var got string
+			h := NewHandler(NewLogger("debug"), nil)
+			h.devices = fakeDeviceQueries{
+				listPageFn: func(ctx context.Context, arg sqlcgen.ListDevicesPageParams) ([]sqlcgen.DeviceListItem, error) {
+					got = arg.Archived
+					return nil, nil
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices"+tc.query, nil))
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if got != tc.want {
+				t.Fatalf("expected archived=%q, got %q", tc.want, got)
+			}
+		})
+	}
+}
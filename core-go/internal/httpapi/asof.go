@@ -0,0 +1,222 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/retention"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// asOfQueries reconstructs device state at an earlier instant from observations and the device event history.
+type asOfQueries interface {
+	GetDeviceAsOf(ctx context.Context, id string, asOf time.Time) (sqlcgen.Device, error)
+	ListDevicesPageAsOf(ctx context.Context, arg sqlcgen.ListDevicesPageParams, asOf time.Time) ([]sqlcgen.DeviceListItem, error)
+	ListDeviceEffectiveTagsAsOf(ctx context.Context, deviceID string, asOf time.Time) ([]string, error)
+	ListDeviceIPsAsOf(ctx context.Context, deviceID string, asOf time.Time) ([]sqlcgen.DeviceIP, error)
+	ListDeviceMACsAsOf(ctx context.Context, deviceID string, asOf time.Time) ([]sqlcgen.DeviceMAC, error)
+	ListDeviceInterfacesAsOf(ctx context.Context, deviceID string, asOf time.Time) ([]sqlcgen.DeviceInterface, error)
+	ListDeviceServicesAsOf(ctx context.Context, deviceID string, asOf time.Time) ([]sqlcgen.DeviceService, error)
+	GetDeviceSNMPAsOf(ctx context.Context, deviceID string, asOf time.Time) (sqlcgen.DeviceSNMP, error)
+	ListDeviceLinksAsOf(ctx context.Context, deviceID string, asOf time.Time) ([]sqlcgen.DeviceLink, error)
+	ListDevicesInCIDRAsOf(ctx context.Context, cidr string, excludeDeviceID *string, limit int32, asOf time.Time) ([]sqlcgen.MapDevicePeer, error)
+	ListDevicePVIDsAsOf(ctx context.Context, deviceID string, asOf time.Time) ([]int32, error)
+	ListDevicesInVLANAsOf(ctx context.Context, vlanID int32, excludeDeviceID *string, limit int32, asOf time.Time) ([]sqlcgen.MapDevicePeer, error)
+	ListDeviceLinkPeersAsOf(ctx context.Context, deviceID string, limit int32, asOf time.Time) ([]sqlcgen.MapDeviceLinkPeer, error)
+	ListServicesForDeviceAsOf(ctx context.Context, deviceID string, limit int32, asOf time.Time) ([]sqlcgen.MapService, error)
+	GetServiceByIDAsOf(ctx context.Context, serviceID string, asOf time.Time) (sqlcgen.MapService, error)
+}
+
+// asOfDevices answers the device read queries as they would have been answered at `at`. Queries it does not
+// override (OS guesses, name candidates) stay live; optional map overlays (STP roots, PoE, routing adjacencies,
+// traceroute paths) are not implemented here, so the map handler skips them for historical projections.
+type asOfDevices struct {
+	deviceQueries
+	q  asOfQueries
+	at time.Time
+}
+
+func (d asOfDevices) GetDevice(ctx context.Context, id string) (sqlcgen.Device, error) {
+	return d.q.GetDeviceAsOf(ctx, id, d.at)
+}
+
+func (d asOfDevices) ListDevicesPage(ctx context.Context, arg sqlcgen.ListDevicesPageParams) ([]sqlcgen.DeviceListItem, error) {
+	return d.q.ListDevicesPageAsOf(ctx, arg, d.at)
+}
+
+func (d asOfDevices) ListDeviceEffectiveTags(ctx context.Context, deviceID string) ([]string, error) {
+	return d.q.ListDeviceEffectiveTagsAsOf(ctx, deviceID, d.at)
+}
+
+func (d asOfDevices) ListDeviceIPs(ctx context.Context, deviceID string) ([]sqlcgen.DeviceIP, error) {
+	return d.q.ListDeviceIPsAsOf(ctx, deviceID, d.at)
+}
+
+func (d asOfDevices) ListDeviceMACs(ctx context.Context, deviceID string) ([]sqlcgen.DeviceMAC, error) {
+	return d.q.ListDeviceMACsAsOf(ctx, deviceID, d.at)
+}
+
+func (d asOfDevices) ListDeviceInterfaces(ctx context.Context, deviceID string) ([]sqlcgen.DeviceInterface, error) {
+	return d.q.ListDeviceInterfacesAsOf(ctx, deviceID, d.at)
+}
+
+func (d asOfDevices) ListDeviceServices(ctx context.Context, deviceID string) ([]sqlcgen.DeviceService, error) {
+	return d.q.ListDeviceServicesAsOf(ctx, deviceID, d.at)
+}
+
+func (d asOfDevices) GetDeviceSNMP(ctx context.Context, deviceID string) (sqlcgen.DeviceSNMP, error) {
+	return d.q.GetDeviceSNMPAsOf(ctx, deviceID, d.at)
+}
+
+func (d asOfDevices) ListDeviceLinks(ctx context.Context, deviceID string) ([]sqlcgen.DeviceLink, error) {
+	return d.q.ListDeviceLinksAsOf(ctx, deviceID, d.at)
+}
+
+// ListDeviceSSHHostKeys drops keys first seen after `at`; key material does not change once recorded.
+func (d asOfDevices) ListDeviceSSHHostKeys(ctx context.Context, deviceID string) ([]sqlcgen.SSHHostKey, error) {
+	keys, err := d.deviceQueries.ListDeviceSSHHostKeys(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	out := keys[:0]
+	for _, key := range keys {
+		if !key.FirstSeenAt.After(d.at) {
+			out = append(out, key)
+		}
+	}
+	return out, nil
+}
+
+func (d asOfDevices) ListDevicesInCIDR(ctx context.Context, cidr string, limit int32) ([]sqlcgen.MapDevicePeer, error) {
+	return d.q.ListDevicesInCIDRAsOf(ctx, cidr, nil, limit, d.at)
+}
+
+func (d asOfDevices) ListDevicePeersInCIDR(ctx context.Context, cidr string, excludeDeviceID string, limit int32) ([]sqlcgen.MapDevicePeer, error) {
+	return d.q.ListDevicesInCIDRAsOf(ctx, cidr, &excludeDeviceID, limit, d.at)
+}
+
+func (d asOfDevices) ListDevicePVIDs(ctx context.Context, deviceID string) ([]int32, error) {
+	return d.q.ListDevicePVIDsAsOf(ctx, deviceID, d.at)
+}
+
+func (d asOfDevices) ListDevicesInVLAN(ctx context.Context, vlanID int32, limit int32) ([]sqlcgen.MapDevicePeer, error) {
+	return d.q.ListDevicesInVLANAsOf(ctx, vlanID, nil, limit, d.at)
+}
+
+func (d asOfDevices) ListDevicePeersInVLAN(ctx context.Context, vlanID int32, excludeDeviceID string, limit int32) ([]sqlcgen.MapDevicePeer, error) {
+	return d.q.ListDevicesInVLANAsOf(ctx, vlanID, &excludeDeviceID, limit, d.at)
+}
+
+func (d asOfDevices) ListDeviceLinkPeers(ctx context.Context, deviceID string, limit int32) ([]sqlcgen.MapDeviceLinkPeer, error) {
+	return d.q.ListDeviceLinkPeersAsOf(ctx, deviceID, limit, d.at)
+}
+
+func (d asOfDevices) ListServicesForDevice(ctx context.Context, deviceID string, limit int32) ([]sqlcgen.MapService, error) {
+	return d.q.ListServicesForDeviceAsOf(ctx, deviceID, limit, d.at)
+}
+
+func (d asOfDevices) GetServiceByID(ctx context.Context, serviceID string) (sqlcgen.MapService, error) {
+	return d.q.GetServiceByIDAsOf(ctx, serviceID, d.at)
+}
+
+// GetDeviceAlias keeps merged-away IDs resolvable; aliases are never removed, so the live mapping applies.
+func (d asOfDevices) GetDeviceAlias(ctx context.Context, aliasID string) (string, error) {
+	aliases, ok := d.deviceQueries.(deviceAliasQueries)
+	if !ok {
+		return "", pgx.ErrNoRows
+	}
+	return aliases.GetDeviceAlias(ctx, aliasID)
+}
+
+// parseAsOfParam parses the optional `as_of` timestamp (RFC3339). Instants in the future are rejected.
+func parseAsOfParam(value string, now time.Time) (*time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	ts, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid as_of timestamp: %w", err)
+	}
+	if ts.After(now) {
+		return nil, errors.New("as_of must not be in the future")
+	}
+	ts = ts.UTC()
+	return &ts, nil
+}
+
+// withAsOf serves next against device state reconstructed at the `as_of` query parameter, or unchanged when the
+// parameter is absent.
+func (h *Handler) withAsOf(next func(*Handler, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		asOf, err := parseAsOfParam(r.URL.Query().Get("as_of"), time.Now())
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid as_of", map[string]any{"error": err.Error()})
+			return
+		}
+		if asOf == nil {
+			next(h, w, r)
+			return
+		}
+		if !h.ensureDeviceQueries(w) {
+			return
+		}
+		horizon, pruned, err := h.asOfHorizon(r.Context(), time.Now().UTC())
+		if err != nil {
+			h.log.Error().Err(err).Msg("list retention policies failed")
+			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to list retention policies", nil)
+			return
+		}
+		if pruned && asOf.Before(horizon) {
+			h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid as_of", map[string]any{
+				"error":          "as_of is older than the retained observation history",
+				"earliest_as_of": horizon,
+			})
+			return
+		}
+		q, ok := h.devices.(asOfQueries)
+		if !ok {
+			h.log.Error().Msg("point-in-time device queries missing")
+			h.writeError(w, http.StatusInternalServerError, "internal_error", "as_of not supported", nil)
+			return
+		}
+		historical := *h
+		historical.devices = asOfDevices{deviceQueries: h.devices, q: q, at: *asOf}
+		next(&historical, w, r)
+	}
+}
+
+// asOfHorizon is the earliest as_of the IP and MAC observations still answer while the retention job prunes them.
+// Past it they are gone and held addresses would silently go missing; between its drop and thin cutoffs only the first
+// and last sighting per device, address and day are left, so answers are accurate to the day. pruned is false when the job is
+// off or keeps them forever.
+func (h *Handler) asOfHorizon(ctx context.Context, now time.Time) (horizon time.Time, pruned bool, err error) {
+	if !h.retentionEnabled {
+		return time.Time{}, false, nil
+	}
+	store, ok := h.devices.(interface {
+		ListRetentionPolicies(ctx context.Context) ([]sqlcgen.RetentionPolicy, error)
+	})
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	overrides, err := store.ListRetentionPolicies(ctx)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	horizon, pruned = retention.Horizon(retention.Effective(h.retentionDefaults, overrides), retention.AddressHistoryTables, now)
+	return horizon, pruned, nil
+}
+
+// asOfTime reports the instant device reads are pinned to, if any.
+func (h *Handler) asOfTime() (time.Time, bool) {
+	if d, ok := h.devices.(asOfDevices); ok {
+		return d.at, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,348 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/retention"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// fakeDeviceQueriesWithAsOf answers the point-in-time queries from fixed rows and records the instants it was asked for.
+type fakeDeviceQueriesWithAsOf struct {
+	fakeDeviceQueries
+	asOfCalls   *[]time.Time
+	name        string
+	tags        []string
+	ips         []sqlcgen.DeviceIP
+	cidrPeers   []sqlcgen.MapDevicePeer
+	listPageArg *sqlcgen.ListDevicesPageParams
+}
+
+func (f fakeDeviceQueriesWithAsOf) record(asOf time.Time) {
+	if f.asOfCalls != nil {
+		*f.asOfCalls = append(*f.asOfCalls, asOf)
+	}
+}
+
+func (f fakeDeviceQueriesWithAsOf) GetDeviceAsOf(ctx context.Context, id string, asOf time.Time) (sqlcgen.Device, error) {
+	f.record(asOf)
+	name := f.name
+	return sqlcgen.Device{ID: id, DisplayName: &name}, nil
+}
+
+func (f fakeDeviceQueriesWithAsOf) ListDevicesPageAsOf(ctx context.Context, arg sqlcgen.ListDevicesPageParams, asOf time.Time) ([]sqlcgen.DeviceListItem, error) {
+	f.record(asOf)
+	if f.listPageArg != nil {
+		*f.listPageArg = arg
+	}
+	name := f.name
+	return []sqlcgen.DeviceListItem{{ID: "00000000-0000-0000-0000-000000000001", DisplayName: &name, SortTs: asOf}}, nil
+}
+
+func (f fakeDeviceQueriesWithAsOf) ListDeviceEffectiveTagsAsOf(ctx context.Context, deviceID string, asOf time.Time) ([]string, error) {
+	f.record(asOf)
+	return f.tags, nil
+}
+
+func (f fakeDeviceQueriesWithAsOf) ListDeviceIPsAsOf(ctx context.Context, deviceID string, asOf time.Time) ([]sqlcgen.DeviceIP, error) {
+	f.record(asOf)
+	return f.ips, nil
+}
+
+func (f fakeDeviceQueriesWithAsOf) ListDeviceMACsAsOf(ctx context.Context, deviceID string, asOf time.Time) ([]sqlcgen.DeviceMAC, error) {
+	return nil, nil
+}
+
+func (f fakeDeviceQueriesWithAsOf) ListDeviceInterfacesAsOf(ctx context.Context, deviceID string, asOf time.Time) ([]sqlcgen.DeviceInterface, error) {
+	return nil, nil
+}
+
+func (f fakeDeviceQueriesWithAsOf) ListDeviceServicesAsOf(ctx context.Context, deviceID string, asOf time.Time) ([]sqlcgen.DeviceService, error) {
+	return nil, nil
+}
+
+func (f fakeDeviceQueriesWithAsOf) GetDeviceSNMPAsOf(ctx context.Context, deviceID string, asOf time.Time) (sqlcgen.DeviceSNMP, error) {
+	return sqlcgen.DeviceSNMP{}, pgx.ErrNoRows
+}
+
+func (f fakeDeviceQueriesWithAsOf) ListDeviceLinksAsOf(ctx context.Context, deviceID string, asOf time.Time) ([]sqlcgen.DeviceLink, error) {
+	return nil, nil
+}
+
+func (f fakeDeviceQueriesWithAsOf) ListDevicesInCIDRAsOf(ctx context.Context, cidr string, excludeDeviceID *string, limit int32, asOf time.Time) ([]sqlcgen.MapDevicePeer, error) {
+	f.record(asOf)
+	return f.cidrPeers, nil
+}
+
+func (f fakeDeviceQueriesWithAsOf) ListDevicePVIDsAsOf(ctx context.Context, deviceID string, asOf time.Time) ([]int32, error) {
+	return nil, nil
+}
+
+func (f fakeDeviceQueriesWithAsOf) ListDevicesInVLANAsOf(ctx context.Context, vlanID int32, excludeDeviceID *string, limit int32, asOf time.Time) ([]sqlcgen.MapDevicePeer, error) {
+	return nil, nil
+}
+
+func (f fakeDeviceQueriesWithAsOf) ListDeviceLinkPeersAsOf(ctx context.Context, deviceID string, limit int32, asOf time.Time) ([]sqlcgen.MapDeviceLinkPeer, error) {
+	return nil, nil
+}
+
+func (f fakeDeviceQueriesWithAsOf) ListServicesForDeviceAsOf(ctx context.Context, deviceID string, limit int32, asOf time.Time) ([]sqlcgen.MapService, error) {
+	return nil, nil
+}
+
+func (f fakeDeviceQueriesWithAsOf) GetServiceByIDAsOf(ctx context.Context, serviceID string, asOf time.Time) (sqlcgen.MapService, error) {
+	return sqlcgen.MapService{}, pgx.ErrNoRows
+}
+
+func TestAsOf_InvalidValue_Returns400(t *testing.T) {
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	for _, path := range []string{
+		"/api/v1/devices?as_of=yesterday",
+		"/api/v1/devices/00000000-0000-0000-0000-000000000001?as_of=2026-13-01",
+		"/api/v1/devices/00000000-0000-0000-0000-000000000001/facts?as_of=" + future,
+		"/api/v1/map/l3?focusType=subnet&focusId=10.0.1.0/24&as_of=nope",
+	} {
+		t.Run(path, func(t *testing.T) {
+			h := NewHandler(NewLogger("debug"), nil)
+			h.devices = fakeDeviceQueriesWithAsOf{}
+
+			rr := httptest.NewRecorder()
+			h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
+			}
+			if msg := decodeBody(t, rr)["error"].(map[string]any)["message"]; msg != "invalid as_of" {
+				t.Fatalf("expected invalid as_of, got %v", msg)
+			}
+		})
+	}
+}
+
+func TestAsOf_Unsupported_Returns500(t *testing.T) {
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueries{}
+
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/00000000-0000-0000-0000-000000000001?as_of=2026-01-01T00:00:00Z", nil))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+type fakeDeviceQueriesWithAsOfRetention struct {
+	fakeDeviceQueriesWithAsOf
+	overrides []sqlcgen.RetentionPolicy
+}
+
+func (f fakeDeviceQueriesWithAsOfRetention) ListRetentionPolicies(ctx context.Context) ([]sqlcgen.RetentionPolicy, error) {
+	return f.overrides, nil
+}
+
+func TestAsOf_OlderThanRetainedObservations_Returns400(t *testing.T) {
+	h := NewHandlerWithOptions(NewLogger("debug"), nil, nil, Options{
+		RetentionEnabled: true,
+		RetentionDefaults: []retention.Policy{
+			{Table: sqlcgen.RetentionTableIPObservations, KeepRawDays: 30, KeepDailyDays: 365},
+			{Table: sqlcgen.RetentionTableDiscoveryRunLogs, KeepRawDays: 7},
+		},
+	})
+	h.devices = fakeDeviceQueriesWithAsOfRetention{
+		fakeDeviceQueriesWithAsOf: fakeDeviceQueriesWithAsOf{name: "old-name"},
+		overrides:                 []sqlcgen.RetentionPolicy{{TableName: sqlcgen.RetentionTableMACObservations, KeepRawDays: 90}},
+	}
+	get := func(asOf time.Time) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		path := "/api/v1/devices/00000000-0000-0000-0000-000000000001?as_of=" + asOf.UTC().Format(time.RFC3339)
+		h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	// Run logs are not address history; the MAC override drops sightings after 90 days, before the IP daily tier ends.
+	if rr := get(time.Now().AddDate(0, 0, -60)); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 inside the retained window, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr := get(time.Now().AddDate(0, 0, -120))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 past the retained window, got %d: %s", rr.Code, rr.Body.String())
+	}
+	details := decodeBody(t, rr)["error"].(map[string]any)["details"].(map[string]any)
+	earliest, err := time.Parse(time.RFC3339Nano, details["earliest_as_of"].(string))
+	if err != nil {
+		t.Fatalf("expected earliest_as_of timestamp, got %v", details["earliest_as_of"])
+	}
+	if want := time.Now().AddDate(0, 0, -90); earliest.Sub(want).Abs() > time.Minute {
+		t.Fatalf("expected earliest_as_of near %s, got %s", want, earliest)
+	}
+
+	h.retentionEnabled = false
+	if rr := get(time.Now().AddDate(0, 0, -120)); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 while the retention job is off, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestAsOf_GetDevice_ReadsHistoricalState(t *testing.T) {
+	var calls []time.Time
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueriesWithAsOf{
+		fakeDeviceQueries: fakeDeviceQueries{
+			getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+				t.Fatalf("live GetDevice must not be used for as_of reads")
+				return sqlcgen.Device{}, nil
+			},
+		},
+		asOfCalls: &calls,
+		name:      "old-name",
+		tags:      []string{"printer"},
+	}
+
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/00000000-0000-0000-0000-000000000001?as_of=2026-01-02T03:04:05%2B02:00", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	body := decodeBody(t, rr)
+	if body["display_name"] != "old-name" {
+		t.Fatalf("expected historical name, got %v", body["display_name"])
+	}
+	if tags, _ := body["tags"].([]any); len(tags) != 1 || tags[0] != "printer" {
+		t.Fatalf("expected historical tags, got %v", body["tags"])
+	}
+	want := time.Date(2026, 1, 2, 1, 4, 5, 0, time.UTC)
+	if len(calls) == 0 {
+		t.Fatalf("expected as_of queries to be used")
+	}
+	for _, at := range calls {
+		if !at.Equal(want) {
+			t.Fatalf("expected as_of %s, got %s", want, at)
+		}
+	}
+}
+
+func TestAsOf_ListDevices_WindowsRelativeToAsOf(t *testing.T) {
+	var arg sqlcgen.ListDevicesPageParams
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueriesWithAsOf{name: "old-name", listPageArg: &arg}
+
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices?as_of=2026-01-02T00:00:00Z&seen_within_seconds=60", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if want := time.Date(2026, 1, 1, 23, 59, 0, 0, time.UTC); !arg.SeenAfter.Equal(want) {
+		t.Fatalf("expected seen window to end at as_of, got seen_after=%s", arg.SeenAfter)
+	}
+	if !strings.Contains(rr.Body.String(), "old-name") {
+		t.Fatalf("expected historical listing, got %s", rr.Body.String())
+	}
+}
+
+func TestAsOf_Facts_FiltersLaterSSHKeys(t *testing.T) {
+	asOf := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueriesWithAsOf{
+		fakeDeviceQueries: fakeDeviceQueries{
+			listSSHHostKeysFn: func(ctx context.Context, deviceID string) ([]sqlcgen.SSHHostKey, error) {
+				return []sqlcgen.SSHHostKey{
+					{ID: "old", KeyType: "ssh-ed25519", FirstSeenAt: asOf.Add(-time.Hour)},
+					{ID: "new", KeyType: "ssh-rsa", FirstSeenAt: asOf.Add(time.Hour)},
+				}, nil
+			},
+			listOSGuessesFn: func(ctx context.Context, deviceID string) ([]sqlcgen.DeviceOSGuess, error) { return nil, nil },
+		},
+		ips: []sqlcgen.DeviceIP{{IP: "10.0.0.5/32", CreatedAt: asOf.Add(-time.Hour), LastSeenAt: asOf.Add(-time.Minute)}},
+	}
+
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/00000000-0000-0000-0000-000000000001/facts?as_of=2026-01-02T00:00:00Z", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	body := decodeBody(t, rr)
+	if ips, _ := body["ips"].([]any); len(ips) != 1 {
+		t.Fatalf("expected historical ips, got %v", body["ips"])
+	}
+	keys, _ := body["ssh_host_keys"].([]any)
+	if len(keys) != 1 || keys[0].(map[string]any)["key_type"] != "ssh-ed25519" {
+		t.Fatalf("expected only the key seen before as_of, got %v", body["ssh_host_keys"])
+	}
+}
+
+func TestAsOf_MapProjection_ReportsAsOf(t *testing.T) {
+	var calls []time.Time
+	peer := "peer-1"
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueriesWithAsOf{
+		asOfCalls: &calls,
+		cidrPeers: []sqlcgen.MapDevicePeer{{ID: "00000000-0000-0000-0000-000000000001", DisplayName: &peer}},
+	}
+
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/map/l3?focusType=subnet&focusId=10.0.1.0/24&as_of=2026-01-02T00:00:00Z", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(calls) == 0 {
+		t.Fatalf("expected the subnet members to be read as of the requested instant")
+	}
+	meta, _ := decodeBody(t, rr)["meta"].(map[string]any)
+	if meta["as_of"] != "2026-01-02T00:00:00Z" {
+		t.Fatalf("expected meta.as_of, got %v", meta)
+	}
+}
+
+func TestPutDeviceTags_ReplacesTagsWithSnapshot(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{name: "written", wantCode: http.StatusOK},
+		{name: "snapshot fails", err: errors.New("boom"), wantCode: http.StatusInternalServerError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []sqlcgen.ReplaceManualDeviceTagsParams
+			h := NewHandler(NewLogger("debug"), nil)
+			h.devices = fakeDeviceQueries{
+				getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) { return sqlcgen.Device{ID: id}, nil },
+				replaceManualTagsFn: func(ctx context.Context, arg sqlcgen.ReplaceManualDeviceTagsParams) error {
+					calls = append(calls, arg)
+					return tc.err
+				},
+				listTagsFn: func(ctx context.Context, deviceID string) ([]sqlcgen.DeviceTag, error) { return nil, nil },
+			}
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/devices/00000000-0000-0000-0000-000000000001/tags", strings.NewReader(`{"tags":["printer"],"actor":"alice","reason":"relabel"}`))
+			req.Header.Set("Content-Type", "application/json")
+			h.Router().ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if len(calls) != 1 {
+				t.Fatalf("expected one tag replacement, got %d", len(calls))
+			}
+			got := calls[0]
+			if got.DeviceID != "00000000-0000-0000-0000-000000000001" || len(got.Tags) != 1 || got.Tags[0] != "printer" {
+				t.Fatalf("unexpected replacement %+v", got)
+			}
+			if got.Actor.Type != sqlcgen.DeviceEventActorUser || derefString(got.Actor.Name) != "alice" || derefString(got.Actor.Reason) != "relabel" {
+				t.Fatalf("expected user actor, got %+v", got.Actor)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+const (
+	defaultAvailabilityWindow = 7 * 24 * time.Hour
+	maxAvailabilityWindow     = 366 * 24 * time.Hour
+	// availabilityUnknown covers the part of a window before the first recorded transition.
+	availabilityUnknown = "unknown"
+)
+
+type availabilityQueries interface {
+	ListDeviceAvailability(ctx context.Context, arg sqlcgen.ListDeviceAvailabilityParams) ([]sqlcgen.DeviceAvailabilityTransition, error)
+}
+
+type availabilityInterval struct {
+	State string    `json:"state"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+}
+
+type availabilityTransition struct {
+	State         string         `json:"state"`
+	PreviousState *string        `json:"previous_state,omitempty"`
+	ChangedAt     time.Time      `json:"changed_at"`
+	RunID         *string        `json:"run_id,omitempty"`
+	Evidence      map[string]any `json:"evidence"`
+}
+
+type deviceAvailabilityResponse struct {
+	DeviceID string    `json:"device_id"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	// State is the state at To.
+	State string `json:"state"`
+	// UptimePercent is up time over the part of the window with a known state; null when none of it is known.
+	UptimePercent  *float64                 `json:"uptime_percent"`
+	UpSeconds      int64                    `json:"up_seconds"`
+	DownSeconds    int64                    `json:"down_seconds"`
+	UnknownSeconds int64                    `json:"unknown_seconds"`
+	Intervals      []availabilityInterval   `json:"intervals"`
+	Transitions    []availabilityTransition `json:"transitions"`
+}
+
+// availabilityIntervals splits [from, to] into consecutive intervals of one state. transitions must be ordered
+// by changed_at and may start with the last transition before from, which sets the initial state.
+func availabilityIntervals(transitions []sqlcgen.DeviceAvailabilityTransition, from, to time.Time) []availabilityInterval {
+	out := []availabilityInterval{}
+	state, cursor := availabilityUnknown, from
+	add := func(end time.Time) {
+		if !end.After(cursor) {
+			return
+		}
+		if n := len(out); n > 0 && out[n-1].State == state {
+			out[n-1].To = end
+		} else {
+			out = append(out, availabilityInterval{State: state, From: cursor, To: end})
+		}
+		cursor = end
+	}
+	for _, t := range transitions {
+		if t.ChangedAt.After(to) {
+			break
+		}
+		if t.ChangedAt.After(from) {
+			add(t.ChangedAt)
+		}
+		state = t.State
+	}
+	add(to)
+	return out
+}
+
+func toDeviceAvailabilityResponse(deviceID string, transitions []sqlcgen.DeviceAvailabilityTransition, from, to time.Time) deviceAvailabilityResponse {
+	resp := deviceAvailabilityResponse{
+		DeviceID:    deviceID,
+		From:        from,
+		To:          to,
+		State:       availabilityUnknown,
+		Intervals:   availabilityIntervals(transitions, from, to),
+		Transitions: []availabilityTransition{},
+	}
+
+	var up, down, unknown time.Duration
+	for _, iv := range resp.Intervals {
+		d := iv.To.Sub(iv.From)
+		switch iv.State {
+		case sqlcgen.AvailabilityUp:
+			up += d
+		case sqlcgen.AvailabilityDown:
+			down += d
+		default:
+			unknown += d
+		}
+		resp.State = iv.State
+	}
+	resp.UpSeconds = int64(up.Seconds())
+	resp.DownSeconds = int64(down.Seconds())
+	resp.UnknownSeconds = int64(unknown.Seconds())
+	if known := up + down; known > 0 {
+		pct := math.Round(float64(up)/float64(known)*10000) / 100
+		resp.UptimePercent = &pct
+	}
+
+	for _, t := range transitions {
+		if !t.ChangedAt.After(from) || t.ChangedAt.After(to) {
+			continue
+		}
+		evidence := t.Evidence
+		if evidence == nil {
+			evidence = map[string]any{}
+		}
+		resp.Transitions = append(resp.Transitions, availabilityTransition{
+			State:         t.State,
+			PreviousState: t.PreviousState,
+			ChangedAt:     t.ChangedAt,
+			RunID:         t.RunID,
+			Evidence:      evidence,
+		})
+	}
+	return resp
+}
+
+// handleGetDeviceAvailability returns the up/down intervals of a device between from and to (RFC3339; default
+// the last 7 days) with its uptime over that window.
+func (h *Handler) handleGetDeviceAvailability(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !h.ensureDeviceQueries(w) {
+		return
+	}
+
+	to := time.Now().UTC()
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		ts, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid to timestamp", map[string]any{"error": err.Error()})
+			return
+		}
+		to = ts
+	}
+	from := to.Add(-defaultAvailabilityWindow)
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		ts, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid from timestamp", map[string]any{"error": err.Error()})
+			return
+		}
+		from = ts
+	}
+	if !to.After(from) || to.Sub(from) > maxAvailabilityWindow {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "from must be before to and at most 366 days earlier", map[string]any{"from": from, "to": to})
+		return
+	}
+
+	store, ok := h.devices.(availabilityQueries)
+	if !ok {
+		h.log.Error().Msg("availability queries missing")
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "device availability not supported", nil)
+		return
+	}
+	if _, err := h.devices.GetDevice(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			h.writeError(w, http.StatusNotFound, "not_found", "device not found", map[string]any{"id": id})
+		case isInvalidUUID(err):
+			h.writeError(w, http.StatusBadRequest, "invalid_id", "device id is not a valid uuid", map[string]any{"id": id})
+		default:
+			h.log.Error().Err(err).Str("id", id).Msg("fetch device before availability failed")
+			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to fetch device availability", nil)
+		}
+		return
+	}
+
+	rows, err := store.ListDeviceAvailability(r.Context(), sqlcgen.ListDeviceAvailabilityParams{DeviceID: id, From: from, To: to})
+	if err != nil {
+		h.log.Error().Err(err).Str("id", id).Msg("list device availability failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to fetch device availability", nil)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, toDeviceAvailabilityResponse(id, rows, from, to))
+}
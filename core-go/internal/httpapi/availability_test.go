@@ -0,0 +1,149 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+type fakeDeviceQueriesWithAvailability struct {
+	fakeDeviceQueries
+	listAvailabilityFn func(ctx context.Context, arg sqlcgen.ListDeviceAvailabilityParams) ([]sqlcgen.DeviceAvailabilityTransition, error)
+}
+
+func (f fakeDeviceQueriesWithAvailability) ListDeviceAvailability(ctx context.Context, arg sqlcgen.ListDeviceAvailabilityParams) ([]sqlcgen.DeviceAvailabilityTransition, error) {
+	return f.listAvailabilityFn(ctx, arg)
+}
+
+func TestAvailabilityIntervals(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(10 * time.Hour)
+	at := func(h int) time.Time { return from.Add(time.Duration(h) * time.Hour) }
+	tr := func(h int, state string) sqlcgen.DeviceAvailabilityTransition {
+		return sqlcgen.DeviceAvailabilityTransition{State: state, ChangedAt: at(h)}
+	}
+
+	cases := []struct {
+		name        string
+		transitions []sqlcgen.DeviceAvailabilityTransition
+		want        []availabilityInterval
+		wantUptime  *float64
+	}{
+		{
+			name: "no transitions",
+			want: []availabilityInterval{{State: "unknown", From: from, To: to}},
+		},
+		{
+			name:        "state carried in from before the window",
+			transitions: []sqlcgen.DeviceAvailabilityTransition{tr(-5, "up"), tr(6, "down"), tr(8, "up")},
+			want: []availabilityInterval{
+				{State: "up", From: from, To: at(6)},
+				{State: "down", From: at(6), To: at(8)},
+				{State: "up", From: at(8), To: to},
+			},
+			wantUptime: ptrFloat(80),
+		},
+		{
+			name:        "first seen inside the window",
+			transitions: []sqlcgen.DeviceAvailabilityTransition{tr(2, "up"), tr(7, "down"), tr(12, "up")},
+			want: []availabilityInterval{
+				{State: "unknown", From: from, To: at(2)},
+				{State: "up", From: at(2), To: at(7)},
+				{State: "down", From: at(7), To: to},
+			},
+			wantUptime: ptrFloat(62.5),
+		},
+		{
+			name:        "transition exactly at from",
+			transitions: []sqlcgen.DeviceAvailabilityTransition{tr(0, "down")},
+			want:        []availabilityInterval{{State: "down", From: from, To: to}},
+			wantUptime:  ptrFloat(0),
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := toDeviceAvailabilityResponse("dev", tc.transitions, from, to)
+			if len(resp.Intervals) != len(tc.want) {
+				t.Fatalf("expected %+v, got %+v", tc.want, resp.Intervals)
+			}
+			for i := range tc.want {
+				if resp.Intervals[i] != tc.want[i] {
+					t.Fatalf("interval %d: expected %+v, got %+v", i, tc.want[i], resp.Intervals[i])
+				}
+			}
+			switch {
+			case tc.wantUptime == nil && resp.UptimePercent != nil:
+				t.Fatalf("expected no uptime, got %v", *resp.UptimePercent)
+			case tc.wantUptime != nil && (resp.UptimePercent == nil || *resp.UptimePercent != *tc.wantUptime):
+				t.Fatalf("expected uptime %v, got %v", *tc.wantUptime, resp.UptimePercent)
+			}
+			if got := resp.UpSeconds + resp.DownSeconds + resp.UnknownSeconds; got != int64(to.Sub(from).Seconds()) {
+				t.Fatalf("expected intervals to cover the window, got %d seconds", got)
+			}
+		})
+	}
+}
+
+func ptrFloat(v float64) *float64 { return &v }
+
+func TestDevices_Availability(t *testing.T) {
+	deviceID := "00000000-0000-0000-0000-000000000001"
+	cases := []struct {
+		name     string
+		id       string
+		query    string
+		wantCode int
+		wantFrom string
+	}{
+		{name: "explicit window", id: deviceID, query: "?from=2026-03-01T00:00:00Z&to=2026-03-02T00:00:00Z", wantCode: http.StatusOK, wantFrom: "2026-03-01T00:00:00Z"},
+		{name: "default window", id: deviceID, query: "?to=2026-03-08T00:00:00Z", wantCode: http.StatusOK, wantFrom: "2026-03-01T00:00:00Z"},
+		{name: "inverted window", id: deviceID, query: "?from=2026-03-02T00:00:00Z&to=2026-03-01T00:00:00Z", wantCode: http.StatusBadRequest},
+		{name: "bad timestamp", id: deviceID, query: "?from=yesterday", wantCode: http.StatusBadRequest},
+		{name: "unknown device", id: "00000000-0000-0000-0000-000000000009", wantCode: http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var listed []sqlcgen.ListDeviceAvailabilityParams
+			h := NewHandler(NewLogger("debug"), nil)
+			h.devices = fakeDeviceQueriesWithAvailability{
+				fakeDeviceQueries: fakeDeviceQueries{
+					getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+						if id != deviceID {
+							return sqlcgen.Device{}, pgx.ErrNoRows
+						}
+						return sqlcgen.Device{ID: id}, nil
+					},
+				},
+				listAvailabilityFn: func(ctx context.Context, arg sqlcgen.ListDeviceAvailabilityParams) ([]sqlcgen.DeviceAvailabilityTransition, error) {
+					listed = append(listed, arg)
+					return []sqlcgen.DeviceAvailabilityTransition{{State: "up", ChangedAt: arg.From.Add(-time.Hour)}}, nil
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/"+tc.id+"/availability"+tc.query, nil))
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if tc.wantCode != http.StatusOK {
+				if len(listed) != 0 {
+					t.Fatalf("expected no availability query, got %v", listed)
+				}
+				return
+			}
+			body := decodeBody(t, rr)
+			if body["from"] != tc.wantFrom || body["state"] != "up" || body["uptime_percent"] != float64(100) {
+				t.Fatalf("unexpected body %v", body)
+			}
+			if transitions := body["transitions"].([]any); len(transitions) != 0 {
+				t.Fatalf("expected the seed transition to be left out, got %v", transitions)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+type certificate struct {
+	ID                string    `json:"id"`
+	DeviceID          string    `json:"device_id"`
+	DeviceDisplayName *string   `json:"device_display_name,omitempty"`
+	ServiceID         string    `json:"service_id"`
+	Protocol          *string   `json:"protocol,omitempty"`
+	Port              *int32    `json:"port,omitempty"`
+	ServiceState      *string   `json:"service_state,omitempty"`
+	FingerprintSHA256 string    `json:"fingerprint_sha256"`
+	Subject           string    `json:"subject"`
+	CommonName        *string   `json:"common_name,omitempty"`
+	SANs              []string  `json:"sans"`
+	Issuer            string    `json:"issuer"`
+	SerialNumber      string    `json:"serial_number"`
+	NotBefore         time.Time `json:"not_before"`
+	NotAfter          time.Time `json:"not_after"`
+	KeyType           string    `json:"key_type"`
+	SelfSigned        bool      `json:"self_signed"`
+	Expired           bool      `json:"expired"`
+	FirstSeenAt       time.Time `json:"first_seen_at"`
+	LastSeenAt        time.Time `json:"last_seen_at"`
+}
+
+type certificatesResponse struct {
+	Certificates []certificate `json:"certificates"`
+	Cursor       *string       `json:"cursor,omitempty"`
+}
+
+// parseExpiresWithin accepts a day count suffix (`30d`) or any Go duration (`12h`).
+func parseExpiresWithin(value string) (time.Duration, error) {
+	v := strings.TrimSpace(strings.ToLower(value))
+	if v == "" {
+		return 0, errors.New("empty duration")
+	}
+	var d time.Duration
+	if strings.HasSuffix(v, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(v, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid day count %q", value)
+		}
+		d = time.Duration(days) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, err
+		}
+		d = parsed
+	}
+	if d <= 0 {
+		return 0, errors.New("duration must be positive")
+	}
+	if d > 3650*24*time.Hour {
+		return 0, errors.New("duration must be at most 3650d")
+	}
+	return d, nil
+}
+
+func (h *Handler) handleListCertificates(w http.ResponseWriter, r *http.Request) {
+	if !h.ensureDeviceQueries(w) {
+		return
+	}
+	lister, ok := h.devices.(interface {
+		ListCertificates(ctx context.Context, arg sqlcgen.ListCertificatesParams) ([]sqlcgen.Certificate, error)
+	})
+	if !ok {
+		h.log.Error().Msg("certificate query missing")
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "certificate inventory not supported", nil)
+		return
+	}
+
+	limit, err := parseLimitParam(r.URL.Query().Get("limit"), 100, 500)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid limit", map[string]any{"error": err.Error()})
+		return
+	}
+
+	now := time.Now().UTC()
+	var expiresBefore *time.Time
+	if raw := r.URL.Query().Get("expires_within"); raw != "" {
+		d, err := parseExpiresWithin(raw)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid expires_within", map[string]any{"error": err.Error()})
+			return
+		}
+		cutoff := now.Add(d)
+		expiresBefore = &cutoff
+	}
+
+	var afterNotAfter *time.Time
+	var afterID *string
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		ts, id, err := decodeCursor(cursor)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid cursor", map[string]any{"error": err.Error()})
+			return
+		}
+		afterNotAfter = &ts
+		afterID = &id
+	}
+
+	rows, err := lister.ListCertificates(r.Context(), sqlcgen.ListCertificatesParams{
+		ExpiresBefore: expiresBefore,
+		AfterNotAfter: afterNotAfter,
+		AfterID:       afterID,
+		Limit:         int32(limit + 1),
+	})
+	if err != nil {
+		if isInvalidUUID(err) {
+			h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid cursor", nil)
+			return
+		}
+		h.log.Error().Err(err).Msg("list certificates failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to list certificates", nil)
+		return
+	}
+
+	var cursorOut *string
+	if len(rows) > limit {
+		last := rows[limit-1]
+		next := encodeCursor(last.NotAfter, last.ID)
+		cursorOut = &next
+		rows = rows[:limit]
+	}
+
+	out := make([]certificate, 0, len(rows))
+	for _, row := range rows {
+		sans := row.SANs
+		if sans == nil {
+			sans = []string{}
+		}
+		out = append(out, certificate{
+			ID:                row.ID,
+			DeviceID:          row.DeviceID,
+			DeviceDisplayName: row.DeviceDisplayName,
+			ServiceID:         row.ServiceID,
+			Protocol:          row.Protocol,
+			Port:              row.Port,
+			ServiceState:      row.ServiceState,
+			FingerprintSHA256: row.FingerprintSHA256,
+			Subject:           row.Subject,
+			CommonName:        row.CommonName,
+			SANs:              sans,
+			Issuer:            row.Issuer,
+			SerialNumber:      row.SerialNumber,
+			NotBefore:         row.NotBefore,
+			NotAfter:          row.NotAfter,
+			KeyType:           row.KeyType,
+			SelfSigned:        row.SelfSigned,
+			Expired:           row.NotAfter.Before(now),
+			FirstSeenAt:       row.FirstSeenAt,
+			LastSeenAt:        row.LastSeenAt,
+		})
+	}
+
+	h.writeJSON(w, http.StatusOK, certificatesResponse{Certificates: out, Cursor: cursorOut})
+}
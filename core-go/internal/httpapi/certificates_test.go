@@ -0,0 +1,109 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+type fakeDeviceQueriesWithCertificates struct {
+	fakeDeviceQueries
+	listCertificatesFn func(ctx context.Context, arg sqlcgen.ListCertificatesParams) ([]sqlcgen.Certificate, error)
+}
+
+func (f fakeDeviceQueriesWithCertificates) ListCertificates(ctx context.Context, arg sqlcgen.ListCertificatesParams) ([]sqlcgen.Certificate, error) {
+	if f.listCertificatesFn == nil {
+		return nil, nil
+	}
+	return f.listCertificatesFn(ctx, arg)
+}
+
+func TestParseExpiresWithin(t *testing.T) {
+	cases := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{in: "30d", want: 30 * 24 * time.Hour, ok: true},
+		{in: " 12h ", want: 12 * time.Hour, ok: true},
+		{in: "90m", want: 90 * time.Minute, ok: true},
+		{in: "0d", ok: false},
+		{in: "-5d", ok: false},
+		{in: "soon", ok: false},
+		{in: "99999d", ok: false},
+	}
+	for _, tc := range cases {
+		got, err := parseExpiresWithin(tc.in)
+		if (err == nil) != tc.ok {
+			t.Fatalf("%q: expected ok=%v, got err=%v", tc.in, tc.ok, err)
+		}
+		if tc.ok && got != tc.want {
+			t.Fatalf("%q: expected %v, got %v", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestCertificates_List_ExpiresWithin(t *testing.T) {
+	now := time.Now().UTC()
+	port := int32(443)
+	cn := "nas01.lan"
+
+	var gotArg sqlcgen.ListCertificatesParams
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueriesWithCertificates{
+		listCertificatesFn: func(ctx context.Context, arg sqlcgen.ListCertificatesParams) ([]sqlcgen.Certificate, error) {
+			gotArg = arg
+			return []sqlcgen.Certificate{
+				{ID: "00000000-0000-0000-0000-000000000001", DeviceID: "d1", ServiceID: "s1", Port: &port, CommonName: &cn, NotAfter: now.Add(-time.Hour)},
+				{ID: "00000000-0000-0000-0000-000000000002", DeviceID: "d2", ServiceID: "s2", Port: &port, NotAfter: now.Add(24 * time.Hour)},
+			}, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/certificates?expires_within=30d&limit=1", nil)
+	h.Router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if gotArg.ExpiresBefore == nil || gotArg.ExpiresBefore.Sub(now) < 29*24*time.Hour {
+		t.Fatalf("expected ~30d cutoff, got %v", gotArg.ExpiresBefore)
+	}
+	if gotArg.Limit != 2 {
+		t.Fatalf("expected limit+1 to be requested, got %d", gotArg.Limit)
+	}
+
+	body := decodeBody(t, rr)
+	certs, ok := body["certificates"].([]any)
+	if !ok || len(certs) != 1 {
+		t.Fatalf("expected 1 certificate, got %v", body["certificates"])
+	}
+	first := certs[0].(map[string]any)
+	if first["expired"] != true || first["common_name"] != cn {
+		t.Fatalf("unexpected certificate %v", first)
+	}
+	if sans, ok := first["sans"].([]any); !ok || len(sans) != 0 {
+		t.Fatalf("expected empty sans array, got %v", first["sans"])
+	}
+	if _, ok := body["cursor"].(string); !ok {
+		t.Fatalf("expected cursor, got %v", body["cursor"])
+	}
+}
+
+func TestCertificates_List_RejectsInvalidWindow(t *testing.T) {
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueriesWithCertificates{}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/certificates?expires_within=soon", nil)
+	h.Router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,185 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/duplicates"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+const maxDuplicateReasonLength = 500
+
+type deviceDuplicateQueries interface {
+	ListDeviceDuplicateCandidates(ctx context.Context, minScore int32, limit int32) ([]sqlcgen.DeviceDuplicateCandidate, error)
+	DismissDeviceDuplicate(ctx context.Context, arg sqlcgen.DismissDeviceDuplicateParams) (sqlcgen.DeviceDuplicateDismissal, error)
+}
+
+type deviceRef struct {
+	ID          string  `json:"id"`
+	DisplayName *string `json:"display_name,omitempty"`
+}
+
+type deviceDuplicate struct {
+	DeviceA         deviceRef                         `json:"device_a"`
+	DeviceB         deviceRef                         `json:"device_b"`
+	Score           int32                             `json:"score"`
+	Evidence        []sqlcgen.DeviceDuplicateEvidence `json:"evidence"`
+	FirstDetectedAt time.Time                         `json:"first_detected_at"`
+	DetectedAt      time.Time                         `json:"detected_at"`
+}
+
+type deviceDuplicatesResponse struct {
+	Duplicates []deviceDuplicate `json:"duplicates"`
+}
+
+type deviceDuplicateDismissRequest struct {
+	DeviceIDs []string `json:"device_ids"`
+	Actor     *string  `json:"actor,omitempty"`
+	Reason    *string  `json:"reason,omitempty"`
+}
+
+type deviceDuplicateDismissal struct {
+	DeviceIDs   []string  `json:"device_ids"`
+	Actor       *string   `json:"actor,omitempty"`
+	Reason      *string   `json:"reason,omitempty"`
+	DismissedAt time.Time `json:"dismissed_at"`
+}
+
+// parseMinScoreParam reads the min_score filter (0-100), falling back to the analyzer threshold.
+func parseMinScoreParam(value string) (int, error) {
+	if value == "" {
+		return duplicates.MinScore, nil
+	}
+	score, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid min_score")
+	}
+	if score < 0 || score > 100 {
+		return 0, fmt.Errorf("min_score must be between 0 and 100")
+	}
+	return score, nil
+}
+
+func (h *Handler) duplicateQueries(w http.ResponseWriter) (deviceDuplicateQueries, bool) {
+	if !h.ensureDeviceQueries(w) {
+		return nil, false
+	}
+	q, ok := h.devices.(deviceDuplicateQueries)
+	if !ok {
+		h.log.Error().Msg("device duplicate queries missing")
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "duplicate detection not supported", nil)
+		return nil, false
+	}
+	return q, true
+}
+
+// handleListDeviceDuplicates returns the pairs the background analyzer thinks are the same device,
+// strongest first. Dismissed pairs are never listed.
+func (h *Handler) handleListDeviceDuplicates(w http.ResponseWriter, r *http.Request) {
+	minScore, err := parseMinScoreParam(r.URL.Query().Get("min_score"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid min_score", map[string]any{"error": err.Error()})
+		return
+	}
+	limit, err := parseLimitParam(r.URL.Query().Get("limit"), 50, 200)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid limit", map[string]any{"error": err.Error()})
+		return
+	}
+	q, ok := h.duplicateQueries(w)
+	if !ok {
+		return
+	}
+
+	rows, err := q.ListDeviceDuplicateCandidates(r.Context(), int32(minScore), int32(limit))
+	if err != nil {
+		h.log.Error().Err(err).Msg("list device duplicates failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to list duplicate devices", nil)
+		return
+	}
+	out := make([]deviceDuplicate, 0, len(rows))
+	for _, row := range rows {
+		evidence := row.Evidence
+		if evidence == nil {
+			evidence = []sqlcgen.DeviceDuplicateEvidence{}
+		}
+		out = append(out, deviceDuplicate{
+			DeviceA:         deviceRef{ID: row.DeviceAID, DisplayName: row.DeviceADisplayName},
+			DeviceB:         deviceRef{ID: row.DeviceBID, DisplayName: row.DeviceBDisplayName},
+			Score:           row.Score,
+			Evidence:        evidence,
+			FirstDetectedAt: row.FirstDetectedAt,
+			DetectedAt:      row.DetectedAt,
+		})
+	}
+	h.writeJSON(w, http.StatusOK, deviceDuplicatesResponse{Duplicates: out})
+}
+
+// handleDismissDeviceDuplicate records that two devices are distinct so the analyzer stops suggesting them.
+func (h *Handler) handleDismissDeviceDuplicate(w http.ResponseWriter, r *http.Request) {
+	var req deviceDuplicateDismissRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid json body", map[string]any{"error": err.Error()})
+		return
+	}
+	if len(req.DeviceIDs) != 2 {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "device_ids must name exactly two devices", nil)
+		return
+	}
+	a, b := duplicates.PairKey(req.DeviceIDs[0], req.DeviceIDs[1])
+	if a == "" || a == b {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "device_ids must name two different devices", nil)
+		return
+	}
+	actor := normalizeStringPtr(req.Actor)
+	reason := normalizeStringPtr(req.Reason)
+	if reason != nil && len(*reason) > maxDuplicateReasonLength {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "reason is too long", map[string]any{"max_length": maxDuplicateReasonLength})
+		return
+	}
+
+	q, ok := h.duplicateQueries(w)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	for _, deviceID := range []string{a, b} {
+		if _, err := h.devices.GetDevice(ctx, deviceID); err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				h.writeError(w, http.StatusNotFound, "not_found", "device not found", map[string]any{"id": deviceID})
+			case isInvalidUUID(err):
+				h.writeError(w, http.StatusBadRequest, "invalid_id", "device id is not a valid uuid", map[string]any{"id": deviceID})
+			default:
+				h.log.Error().Err(err).Str("id", deviceID).Msg("fetch device before duplicate dismissal failed")
+				h.writeError(w, http.StatusInternalServerError, "db_error", "failed to dismiss duplicate", nil)
+			}
+			return
+		}
+	}
+
+	row, err := q.DismissDeviceDuplicate(ctx, sqlcgen.DismissDeviceDuplicateParams{
+		DeviceAID: a,
+		DeviceBID: b,
+		Actor:     actor,
+		Reason:    reason,
+	})
+	if err != nil {
+		h.log.Error().Err(err).Str("device_a", a).Str("device_b", b).Msg("dismiss device duplicate failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to dismiss duplicate", nil)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, deviceDuplicateDismissal{
+		DeviceIDs:   []string{row.DeviceAID, row.DeviceBID},
+		Actor:       row.Actor,
+		Reason:      row.Reason,
+		DismissedAt: row.DismissedAt,
+	})
+}
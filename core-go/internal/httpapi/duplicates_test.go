@@ -0,0 +1,147 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+type fakeDeviceQueriesWithDuplicates struct {
+	fakeDeviceQueries
+	listFn    func(ctx context.Context, minScore int32, limit int32) ([]sqlcgen.DeviceDuplicateCandidate, error)
+	dismissFn func(ctx context.Context, arg sqlcgen.DismissDeviceDuplicateParams) (sqlcgen.DeviceDuplicateDismissal, error)
+}
+
+func (f fakeDeviceQueriesWithDuplicates) ListDeviceDuplicateCandidates(ctx context.Context, minScore int32, limit int32) ([]sqlcgen.DeviceDuplicateCandidate, error) {
+	return f.listFn(ctx, minScore, limit)
+}
+
+func (f fakeDeviceQueriesWithDuplicates) DismissDeviceDuplicate(ctx context.Context, arg sqlcgen.DismissDeviceDuplicateParams) (sqlcgen.DeviceDuplicateDismissal, error) {
+	return f.dismissFn(ctx, arg)
+}
+
+func TestDevices_ListDuplicates(t *testing.T) {
+	cases := []struct {
+		name         string
+		query        string
+		wantCode     int
+		wantMinScore int32
+		wantLimit    int32
+	}{
+		{name: "defaults", wantCode: http.StatusOK, wantMinScore: 40, wantLimit: 50},
+		{name: "filters", query: "?min_score=80&limit=500", wantCode: http.StatusOK, wantMinScore: 80, wantLimit: 200},
+		{name: "bad min_score", query: "?min_score=101", wantCode: http.StatusBadRequest},
+		{name: "bad limit", query: "?limit=x", wantCode: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotMin, gotLimit int32
+			name := "core-sw"
+			h := NewHandler(NewLogger("debug"), nil)
+			h.devices = fakeDeviceQueriesWithDuplicates{
+				listFn: func(ctx context.Context, minScore int32, limit int32) ([]sqlcgen.DeviceDuplicateCandidate, error) {
+					gotMin, gotLimit = minScore, limit
+					return []sqlcgen.DeviceDuplicateCandidate{{
+						DeviceAID:          "00000000-0000-0000-0000-000000000001",
+						DeviceADisplayName: &name,
+						DeviceBID:          "00000000-0000-0000-0000-000000000002",
+						Score:              90,
+						Evidence:           []sqlcgen.DeviceDuplicateEvidence{{Signal: "mac", Weight: 50, Values: []string{"aa:bb:cc:dd:ee:ff"}}},
+						FirstDetectedAt:    time.Now(),
+						DetectedAt:         time.Now(),
+					}}, nil
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/duplicates"+tc.query, nil))
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+			if gotMin != tc.wantMinScore || gotLimit != tc.wantLimit {
+				t.Fatalf("expected min_score=%d limit=%d, got %d/%d", tc.wantMinScore, tc.wantLimit, gotMin, gotLimit)
+			}
+			body := decodeBody(t, rr)
+			items := body["duplicates"].([]any)
+			first := items[0].(map[string]any)
+			if first["device_a"].(map[string]any)["display_name"] != "core-sw" || first["score"] != float64(90) {
+				t.Fatalf("unexpected duplicate %v", first)
+			}
+			if ev := first["evidence"].([]any)[0].(map[string]any); ev["signal"] != "mac" {
+				t.Fatalf("unexpected evidence %v", ev)
+			}
+		})
+	}
+}
+
+func TestDevices_DismissDuplicate(t *testing.T) {
+	a := "00000000-0000-0000-0000-000000000001"
+	b := "00000000-0000-0000-0000-000000000002"
+	missing := "00000000-0000-0000-0000-000000000009"
+
+	cases := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "dismisses in canonical order", body: `{"device_ids":["` + b + `","` + a + `"],"actor":"alice","reason":" two NICs "}`, wantCode: http.StatusCreated},
+		{name: "same device twice", body: `{"device_ids":["` + a + `","` + strings.ToUpper(a) + `"]}`, wantCode: http.StatusBadRequest, wantErr: "validation_failed"},
+		{name: "one device", body: `{"device_ids":["` + a + `"]}`, wantCode: http.StatusBadRequest, wantErr: "validation_failed"},
+		{name: "unknown device", body: `{"device_ids":["` + a + `","` + missing + `"]}`, wantCode: http.StatusNotFound, wantErr: "not_found"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got sqlcgen.DismissDeviceDuplicateParams
+			h := NewHandler(NewLogger("debug"), nil)
+			h.devices = fakeDeviceQueriesWithDuplicates{
+				fakeDeviceQueries: fakeDeviceQueries{
+					getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+						if id == missing {
+							return sqlcgen.Device{}, pgx.ErrNoRows
+						}
+						return sqlcgen.Device{ID: id}, nil
+					},
+				},
+				dismissFn: func(ctx context.Context, arg sqlcgen.DismissDeviceDuplicateParams) (sqlcgen.DeviceDuplicateDismissal, error) {
+					got = arg
+					return sqlcgen.DeviceDuplicateDismissal{DeviceAID: arg.DeviceAID, DeviceBID: arg.DeviceBID, Actor: arg.Actor, Reason: arg.Reason, DismissedAt: time.Now()}, nil
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/devices/duplicates/dismissals", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			h.Router().ServeHTTP(rr, req)
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			body := decodeBody(t, rr)
+			if tc.wantErr != "" {
+				if code := body["error"].(map[string]any)["code"]; code != tc.wantErr {
+					t.Fatalf("expected error code %q, got %v", tc.wantErr, code)
+				}
+				if got.DeviceAID != "" {
+					t.Fatalf("dismissal should not be stored on %s", tc.name)
+				}
+				return
+			}
+			if got.DeviceAID != a || got.DeviceBID != b || got.Actor == nil || *got.Actor != "alice" || got.Reason == nil || *got.Reason != "two NICs" {
+				t.Fatalf("unexpected dismissal params %+v", got)
+			}
+			if ids := body["device_ids"].([]any); ids[0] != a || ids[1] != b {
+				t.Fatalf("unexpected dismissal response %v", body)
+			}
+		})
+	}
+}
@@ -22,8 +22,10 @@ import (
 	"github.com/rs/zerolog"
 
 	"roller_hoops/core-go/internal/db"
+	"roller_hoops/core-go/internal/identity"
 	"roller_hoops/core-go/internal/metrics"
 	"roller_hoops/core-go/internal/naming"
+	"roller_hoops/core-go/internal/retention"
 	"roller_hoops/core-go/internal/sqlcgen"
 	"roller_hoops/core-go/internal/tagging"
 )
@@ -37,10 +39,22 @@ type Handler struct {
 	audit                 auditQueries
 	metrics               *metrics.Metrics
 	discoveryDefaultScope *string
+	pcapImportMaxBytes    int64
+	macIdentity           identity.Config
+	retentionDefaults     []retention.Policy
+	retentionEnabled      bool
 }
 
 type Options struct {
 	DiscoveryDefaultScope *string
+	// PcapImportMaxBytes caps uploaded captures; zero uses defaultPcapImportMaxBytes.
+	PcapImportMaxBytes int64
+	// MACIdentity is the randomized-MAC matching policy applied to imported hosts.
+	MACIdentity identity.Config
+	// RetentionDefaults are the env retention policies; API overrides take precedence.
+	RetentionDefaults []retention.Policy
+	// RetentionEnabled is set when the retention job runs, so as_of reads older than what it keeps are rejected.
+	RetentionEnabled bool
 }
 
 type deviceQueries interface {
@@ -52,8 +66,7 @@ type deviceQueries interface {
 	UpsertDeviceMetadata(ctx context.Context, arg sqlcgen.UpsertDeviceMetadataParams) (sqlcgen.DeviceMetadata, error)
 	ListDeviceTags(ctx context.Context, deviceID string) ([]sqlcgen.DeviceTag, error)
 	ListDeviceEffectiveTags(ctx context.Context, deviceID string) ([]string, error)
-	DeleteDeviceTagsBySource(ctx context.Context, arg sqlcgen.DeleteDeviceTagsBySourceParams) error
-	UpsertDeviceTag(ctx context.Context, arg sqlcgen.UpsertDeviceTagParams) error
+	ReplaceManualDeviceTags(ctx context.Context, arg sqlcgen.ReplaceManualDeviceTagsParams) error
 	ListDeviceNameCandidates(ctx context.Context, deviceID string) ([]sqlcgen.DeviceNameCandidate, error)
 	ListDeviceIPs(ctx context.Context, deviceID string) ([]sqlcgen.DeviceIP, error)
 	ListDeviceMACs(ctx context.Context, deviceID string) ([]sqlcgen.DeviceMAC, error)
@@ -61,6 +74,8 @@ type deviceQueries interface {
 	ListDeviceServices(ctx context.Context, deviceID string) ([]sqlcgen.DeviceService, error)
 	GetDeviceSNMP(ctx context.Context, deviceID string) (sqlcgen.DeviceSNMP, error)
 	ListDeviceLinks(ctx context.Context, deviceID string) ([]sqlcgen.DeviceLink, error)
+	ListDeviceSSHHostKeys(ctx context.Context, deviceID string) ([]sqlcgen.SSHHostKey, error)
+	ListDeviceOSGuesses(ctx context.Context, deviceID string) ([]sqlcgen.DeviceOSGuess, error)
 	ListDeviceChangeEvents(ctx context.Context, arg sqlcgen.ListDeviceChangeEventsParams) ([]sqlcgen.DeviceChangeEvent, error)
 	ListDeviceChangeEventsForDevice(ctx context.Context, arg sqlcgen.ListDeviceChangeEventsForDeviceParams) ([]sqlcgen.DeviceChangeEvent, error)
 }
@@ -79,7 +94,7 @@ type inventoryQueries interface {
 	FindDeviceIDByIP(ctx context.Context, ip string) (string, error)
 	CreateDevice(ctx context.Context, displayName *string) (sqlcgen.Device, error)
 	SetDeviceDisplayNameIfUnset(ctx context.Context, arg sqlcgen.SetDeviceDisplayNameIfUnsetParams) (int64, error)
-	UpsertDeviceIP(ctx context.Context, arg sqlcgen.UpsertDeviceIPParams) error
+	UpsertImportedDeviceIP(ctx context.Context, arg sqlcgen.UpsertDeviceIPParams) error
 	UpsertDeviceMetadataFillBlank(ctx context.Context, arg sqlcgen.UpsertDeviceMetadataParams) (sqlcgen.DeviceMetadata, error)
 }
 
@@ -120,6 +135,10 @@ func newHandler(log zerolog.Logger, pool *db.Pool, m *metrics.Metrics, opts Opti
 		audit:                 aq,
 		metrics:               m,
 		discoveryDefaultScope: normalizeScope(opts.DiscoveryDefaultScope),
+		pcapImportMaxBytes:    opts.PcapImportMaxBytes,
+		macIdentity:           opts.MACIdentity,
+		retentionDefaults:     opts.RetentionDefaults,
+		retentionEnabled:      opts.RetentionEnabled,
 	}
 }
 
@@ -145,19 +164,31 @@ func (h *Handler) Router() http.Handler {
 	r.Route("/api", func(r chi.Router) {
 		r.Route("/v1", func(r chi.Router) {
 			r.Route("/devices", func(r chi.Router) {
-				r.Get("/", h.handleListDevices)
+				r.Get("/", h.withAsOf((*Handler).handleListDevices))
 				r.Get("/changes", h.handleListDeviceChangeEvents)
 				r.Get("/export", h.handleExportDevices)
 				r.Post("/", h.handleCreateDevice)
 				r.Post("/import", h.handleImportDevices)
+				r.Get("/duplicates", h.handleListDeviceDuplicates)
+				r.Post("/duplicates/dismissals", h.handleDismissDeviceDuplicate)
 				r.Route("/{id}", func(r chi.Router) {
-					r.Get("/", h.handleGetDevice)
-					r.Get("/facts", h.handleGetDeviceFacts)
+					r.Get("/", h.withAsOf((*Handler).handleGetDevice))
+					r.Get("/facts", h.withAsOf((*Handler).handleGetDeviceFacts))
 					r.Get("/name-candidates", h.handleListDeviceNameCandidates)
 					r.Get("/tags", h.handleListDeviceTags)
 					r.Put("/tags", h.handlePutDeviceTags)
 					r.Get("/history", h.handleDeviceHistory)
+					r.Get("/availability", h.handleGetDeviceAvailability)
+					r.Get("/monitor", h.handleGetDeviceMonitor)
+					r.Put("/monitor", h.handlePutDeviceMonitor)
+					r.Delete("/monitor", h.handleDeleteDeviceMonitor)
+					r.Get("/reachability", h.handleGetDeviceReachability)
+					r.Get("/powered-devices", h.handleListPoweredDevices)
+					r.Post("/merge", h.handleMergeDevices)
+					r.Post("/archive", h.handleArchiveDevice)
+					r.Post("/restore", h.handleRestoreDevice)
 					r.Put("/", h.handleUpdateDevice)
+					r.Delete("/", h.handlePurgeDevice)
 				})
 			})
 
@@ -177,14 +208,33 @@ func (h *Handler) Router() http.Handler {
 			r.Route("/inventory", func(r chi.Router) {
 				r.Post("/netbox/import", h.handleImportNetBox)
 				r.Post("/nautobot/import", h.handleImportNautobot)
+				r.Post("/scan-import", h.handleImportScan)
+				r.Post("/pcap-import", h.handleImportPcap)
 			})
 
 			r.Route("/audit", func(r chi.Router) {
 				r.Post("/events", h.handleCreateAuditEvent)
 			})
 
+			r.Get("/certificates", h.handleListCertificates)
+
+			r.Route("/snmp-profiles", func(r chi.Router) {
+				r.Get("/", h.handleListSNMPProfiles)
+				r.Post("/", h.handleCreateSNMPProfile)
+				r.Get("/{id}", h.handleGetSNMPProfile)
+				r.Put("/{id}", h.handleUpdateSNMPProfile)
+				r.Delete("/{id}", h.handleDeleteSNMPProfile)
+			})
+
+			r.Route("/retention", func(r chi.Router) {
+				r.Get("/policies", h.handleListRetentionPolicies)
+				r.Put("/policies/{table}", h.handlePutRetentionPolicy)
+				r.Delete("/policies/{table}", h.handleDeleteRetentionPolicy)
+				r.Get("/report", h.handleGetRetentionReport)
+			})
+
 			r.Route("/map", func(r chi.Router) {
-				r.Get("/{layer}", h.handleGetMapProjection)
+				r.Get("/{layer}", h.withAsOf((*Handler).handleGetMapProjection))
 			})
 		})
 	})
@@ -317,6 +367,7 @@ type device struct {
 	Metadata     *deviceMetadata `json:"metadata,omitempty"`
 	LastSeenAt   *time.Time      `json:"last_seen_at,omitempty"`
 	LastChangeAt *time.Time      `json:"last_change_at,omitempty"`
+	ArchivedAt   *time.Time      `json:"archived_at,omitempty"`
 }
 
 type deviceNameCandidate struct {
@@ -383,19 +434,23 @@ type deviceMetadataBody struct {
 }
 
 type deviceIPFact struct {
-	IP            string    `json:"ip"`
-	InterfaceID   *string   `json:"interface_id,omitempty"`
-	InterfaceName *string   `json:"interface_name,omitempty"`
-	UpdatedAt     time.Time `json:"updated_at"`
-	CreatedAt     time.Time `json:"created_at"`
+	IP            string     `json:"ip"`
+	InterfaceID   *string    `json:"interface_id,omitempty"`
+	InterfaceName *string    `json:"interface_name,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastSeenAt    time.Time  `json:"last_seen_at"`
+	StaleAt       *time.Time `json:"stale_at,omitempty"`
 }
 
 type deviceMACFact struct {
-	MAC           string    `json:"mac"`
-	InterfaceID   *string   `json:"interface_id,omitempty"`
-	InterfaceName *string   `json:"interface_name,omitempty"`
-	UpdatedAt     time.Time `json:"updated_at"`
-	CreatedAt     time.Time `json:"created_at"`
+	MAC           string     `json:"mac"`
+	InterfaceID   *string    `json:"interface_id,omitempty"`
+	InterfaceName *string    `json:"interface_name,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastSeenAt    time.Time  `json:"last_seen_at"`
+	StaleAt       *time.Time `json:"stale_at,omitempty"`
 }
 
 type deviceInterfaceFact struct {
@@ -416,14 +471,33 @@ type deviceInterfaceFact struct {
 }
 
 type deviceServiceFact struct {
-	Protocol   *string   `json:"protocol,omitempty"`
-	Port       *int32    `json:"port,omitempty"`
-	Name       *string   `json:"name,omitempty"`
-	State      *string   `json:"state,omitempty"`
-	Source     *string   `json:"source,omitempty"`
-	ObservedAt time.Time `json:"observed_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
-	CreatedAt  time.Time `json:"created_at"`
+	Protocol    *string                `json:"protocol,omitempty"`
+	Port        *int32                 `json:"port,omitempty"`
+	Name        *string                `json:"name,omitempty"`
+	State       *string                `json:"state,omitempty"`
+	Source      *string                `json:"source,omitempty"`
+	Summary     *string                `json:"summary,omitempty"`
+	ObservedAt  time.Time              `json:"observed_at"`
+	FirstSeenAt *time.Time             `json:"first_seen_at,omitempty"`
+	LastSeenAt  *time.Time             `json:"last_seen_at,omitempty"`
+	ClosedAt    *time.Time             `json:"closed_at,omitempty"`
+	Product     *string                `json:"product,omitempty"`
+	Version     *string                `json:"version,omitempty"`
+	ExtraInfo   *string                `json:"extra_info,omitempty"`
+	CPE         []string               `json:"cpe,omitempty"`
+	HTTP        *deviceServiceHTTPFact `json:"http,omitempty"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
+
+type deviceServiceHTTPFact struct {
+	Status      *int32    `json:"status,omitempty"`
+	Title       *string   `json:"title,omitempty"`
+	Server      *string   `json:"server,omitempty"`
+	PoweredBy   *string   `json:"powered_by,omitempty"`
+	FaviconHash *int32    `json:"favicon_hash,omitempty"`
+	FinalURL    *string   `json:"final_url,omitempty"`
+	ObservedAt  time.Time `json:"observed_at"`
 }
 
 type deviceSNMPFact struct {
@@ -446,28 +520,65 @@ type deviceLinkFact struct {
 	PeerInterfaceID  *string    `json:"peer_interface_id,omitempty"`
 	LinkType         *string    `json:"link_type,omitempty"`
 	Source           string     `json:"source"`
+	Confidence       *int32     `json:"confidence,omitempty"`
+	State            *string    `json:"state,omitempty"`
+	LocalAS          *int64     `json:"local_as,omitempty"`
+	PeerAS           *int64     `json:"peer_as,omitempty"`
+	Area             *string    `json:"area,omitempty"`
+	LastChangeAt     *time.Time `json:"last_change_at,omitempty"`
 	ObservedAt       *time.Time `json:"observed_at,omitempty"`
 	UpdatedAt        time.Time  `json:"updated_at"`
 }
 
+type deviceSSHHostKeyFact struct {
+	KeyType             string    `json:"key_type"`
+	FingerprintSHA256   string    `json:"fingerprint_sha256"`
+	PublicKey           string    `json:"public_key"`
+	Port                *int32    `json:"port,omitempty"`
+	Banner              *string   `json:"banner,omitempty"`
+	SharedWithDeviceIDs []string  `json:"shared_with_device_ids"`
+	FirstSeenAt         time.Time `json:"first_seen_at"`
+	LastSeenAt          time.Time `json:"last_seen_at"`
+}
+
+type deviceOSGuessFact struct {
+	Source       string    `json:"source"`
+	Rank         int32     `json:"rank"`
+	Name         string    `json:"name"`
+	Accuracy     int32     `json:"accuracy"`
+	Vendor       *string   `json:"vendor,omitempty"`
+	OSFamily     *string   `json:"os_family,omitempty"`
+	OSGeneration *string   `json:"os_generation,omitempty"`
+	DeviceType   *string   `json:"device_type,omitempty"`
+	CPE          []string  `json:"cpe"`
+	ObservedAt   time.Time `json:"observed_at"`
+}
+
 type deviceFacts struct {
-	DeviceID   string                `json:"device_id"`
-	IPs        []deviceIPFact        `json:"ips"`
-	MACs       []deviceMACFact       `json:"macs"`
-	Interfaces []deviceInterfaceFact `json:"interfaces"`
-	Services   []deviceServiceFact   `json:"services"`
-	SNMP       *deviceSNMPFact       `json:"snmp,omitempty"`
-	Links      []deviceLinkFact      `json:"links"`
+	DeviceID    string                 `json:"device_id"`
+	IPs         []deviceIPFact         `json:"ips"`
+	MACs        []deviceMACFact        `json:"macs"`
+	Interfaces  []deviceInterfaceFact  `json:"interfaces"`
+	Services    []deviceServiceFact    `json:"services"`
+	SNMP        *deviceSNMPFact        `json:"snmp,omitempty"`
+	Links       []deviceLinkFact       `json:"links"`
+	SSHHostKeys []deviceSSHHostKeyFact `json:"ssh_host_keys"`
+	OSGuesses   []deviceOSGuessFact    `json:"os_guesses"`
+	CustomFacts []deviceCustomFact     `json:"custom_facts"`
 }
 
 type deviceCreate struct {
 	DisplayName *string             `json:"display_name,omitempty"`
 	Metadata    *deviceMetadataBody `json:"metadata,omitempty"`
+	Actor       *string             `json:"actor,omitempty"`
+	Reason      *string             `json:"reason,omitempty"`
 }
 
 type deviceUpdate struct {
 	DisplayName *string             `json:"display_name,omitempty"`
 	Metadata    *deviceMetadataBody `json:"metadata,omitempty"`
+	Actor       *string             `json:"actor,omitempty"`
+	Reason      *string             `json:"reason,omitempty"`
 }
 
 type importDevice struct {
@@ -478,6 +589,8 @@ type importDevice struct {
 
 type importDevicesRequest struct {
 	Devices []importDevice `json:"devices"`
+	Actor   *string        `json:"actor,omitempty"`
+	Reason  *string        `json:"reason,omitempty"`
 }
 
 type importDevicesResult struct {
@@ -544,6 +657,7 @@ func toDevice(d sqlcgen.Device) device {
 		ID:          d.ID,
 		DisplayName: d.DisplayName,
 		Metadata:    meta,
+		ArchivedAt:  d.ArchivedAt,
 	}
 }
 
@@ -564,6 +678,7 @@ func toDeviceListItem(d sqlcgen.DeviceListItem) device {
 		Metadata:     meta,
 		LastSeenAt:   d.LastSeenAt,
 		LastChangeAt: &lastChangeAt,
+		ArchivedAt:   d.ArchivedAt,
 	}
 }
 
@@ -660,7 +775,7 @@ func (h *Handler) listDevicesPayload(ctx context.Context) ([]device, error) {
 	return resp, nil
 }
 
-func (h *Handler) persistImportMetadata(ctx context.Context, deviceID string, meta *deviceMetadataBody) error {
+func (h *Handler) persistImportMetadata(ctx context.Context, deviceID string, meta *deviceMetadataBody, actor sqlcgen.DeviceEventActor) error {
 	if meta == nil {
 		return nil
 	}
@@ -669,6 +784,7 @@ func (h *Handler) persistImportMetadata(ctx context.Context, deviceID string, me
 		Owner:    meta.Owner,
 		Location: meta.Location,
 		Notes:    meta.Notes,
+		Actor:    actor,
 	})
 	if err != nil {
 		h.log.Error().Err(err).Str("device_id", deviceID).Msg("failed to import metadata")
@@ -828,6 +944,14 @@ func isInvalidUUID(err error) bool {
 	return false
 }
 
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+	return false
+}
+
 func encodeCursor(ts time.Time, id string) string {
 	payload := fmt.Sprintf("%s|%s", ts.UTC().Format(time.RFC3339Nano), id)
 	return base64.RawURLEncoding.EncodeToString([]byte(payload))
@@ -939,6 +1063,16 @@ func (h *Handler) handleListDevices(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	archived := strings.TrimSpace(r.URL.Query().Get("archived"))
+	if archived == "" {
+		archived = "exclude"
+	}
+	switch archived {
+	case "exclude", "include", "only":
+	default:
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid archived value", map[string]any{"archived": archived})
+		return
+	}
 
 	limit, err := parseLimitParam(r.URL.Query().Get("limit"), 50, 200)
 	if err != nil {
@@ -957,6 +1091,9 @@ func (h *Handler) handleListDevices(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	now := time.Now().UTC()
+	if at, ok := h.asOfTime(); ok {
+		now = at
+	}
 	seenAfter := now.Add(-time.Duration(seenWithinSeconds) * time.Second)
 	changedAfter := now.Add(-time.Duration(changedWithinSeconds) * time.Second)
 
@@ -991,6 +1128,7 @@ func (h *Handler) handleListDevices(w http.ResponseWriter, r *http.Request) {
 		BeforeSortTs: beforeSortTs,
 		BeforeID:     beforeID,
 		Limit:        int32(limit + 1),
+		Archived:     archived,
 	})
 	if err != nil {
 		h.log.Error().Err(err).Msg("list devices failed")
@@ -1059,6 +1197,7 @@ func (h *Handler) handleCreateDevice(w http.ResponseWriter, r *http.Request) {
 			Owner:    req.Metadata.Owner,
 			Location: req.Metadata.Location,
 			Notes:    req.Metadata.Notes,
+			Actor:    sqlcgen.UserActor(normalizeStringPtr(req.Actor), normalizeStringPtr(req.Reason)),
 		})
 		if err != nil {
 			h.log.Error().Err(err).Str("device_id", row.ID).Msg("failed to upsert metadata")
@@ -1092,6 +1231,7 @@ func (h *Handler) handleImportDevices(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 	result := importDevicesResult{}
+	actor := sqlcgen.UserActor(normalizeStringPtr(req.Actor), normalizeStringPtr(req.Reason))
 
 	for _, entry := range req.Devices {
 		meta := normalizeMetadataBody(entry.Metadata)
@@ -1099,6 +1239,7 @@ func (h *Handler) handleImportDevices(w http.ResponseWriter, r *http.Request) {
 			row, err := h.devices.UpdateDevice(ctx, sqlcgen.UpdateDeviceParams{
 				ID:          *id,
 				DisplayName: entry.DisplayName,
+				Actor:       actor,
 			})
 			if err != nil {
 				switch {
@@ -1112,7 +1253,7 @@ func (h *Handler) handleImportDevices(w http.ResponseWriter, r *http.Request) {
 				}
 				return
 			}
-			if err := h.persistImportMetadata(ctx, row.ID, meta); err != nil {
+			if err := h.persistImportMetadata(ctx, row.ID, meta, actor); err != nil {
 				h.writeError(w, http.StatusInternalServerError, "db_error", "failed to import device metadata", nil)
 				return
 			}
@@ -1126,7 +1267,7 @@ func (h *Handler) handleImportDevices(w http.ResponseWriter, r *http.Request) {
 			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to create device", nil)
 			return
 		}
-		if err := h.persistImportMetadata(ctx, row.ID, meta); err != nil {
+		if err := h.persistImportMetadata(ctx, row.ID, meta, actor); err != nil {
 			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to import device metadata", nil)
 			return
 		}
@@ -1236,6 +1377,7 @@ func (h *Handler) handleImportInventoryPayload(w http.ResponseWriter, r *http.Re
 			_, _ = h.inventory.SetDeviceDisplayNameIfUnset(ctx, sqlcgen.SetDeviceDisplayNameIfUnsetParams{
 				ID:          deviceID,
 				DisplayName: name,
+				Actor:       sqlcgen.IntegrationActor(source),
 			})
 		}
 
@@ -1245,7 +1387,7 @@ func (h *Handler) handleImportInventoryPayload(w http.ResponseWriter, r *http.Re
 		}
 
 		if ip != nil {
-			if err := h.inventory.UpsertDeviceIP(ctx, sqlcgen.UpsertDeviceIPParams{
+			if err := h.inventory.UpsertImportedDeviceIP(ctx, sqlcgen.UpsertDeviceIPParams{
 				DeviceID: deviceID,
 				IP:       *ip,
 			}); err == nil {
@@ -1263,6 +1405,7 @@ func (h *Handler) handleImportInventoryPayload(w http.ResponseWriter, r *http.Re
 				Owner:    owner,
 				Location: location,
 				Notes:    notes,
+				Actor:    sqlcgen.IntegrationActor(source),
 			}); err == nil {
 				res.MetadataWritten++
 			}
@@ -1392,6 +1535,16 @@ func (h *Handler) handleGetDevice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	row, err := h.devices.GetDevice(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Merged devices keep resolving to the device they were merged into.
+		survivorID, aliased, aliasErr := h.resolveDeviceAlias(r.Context(), id)
+		switch {
+		case aliasErr != nil:
+			err = aliasErr
+		case aliased:
+			row, err = h.devices.GetDevice(r.Context(), survivorID)
+		}
+	}
 	if err != nil {
 		switch {
 		case errors.Is(err, pgx.ErrNoRows):
@@ -1487,6 +1640,28 @@ func (h *Handler) handleGetDeviceFacts(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	sshKeys, err := h.devices.ListDeviceSSHHostKeys(ctx, id)
+	if err != nil {
+		switch {
+		case isInvalidUUID(err):
+			h.writeError(w, http.StatusBadRequest, "invalid_id", "device id is not a valid uuid", map[string]any{"id": id})
+		default:
+			h.log.Error().Err(err).Str("id", id).Msg("list device ssh host keys failed")
+			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to list device ssh host keys", nil)
+		}
+		return
+	}
+	osGuesses, err := h.devices.ListDeviceOSGuesses(ctx, id)
+	if err != nil {
+		switch {
+		case isInvalidUUID(err):
+			h.writeError(w, http.StatusBadRequest, "invalid_id", "device id is not a valid uuid", map[string]any{"id": id})
+		default:
+			h.log.Error().Err(err).Str("id", id).Msg("list device os guesses failed")
+			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to list device os guesses", nil)
+		}
+		return
+	}
 
 	var snmpOut *deviceSNMPFact
 	if snmpRow, err := h.devices.GetDeviceSNMP(ctx, id); err == nil {
@@ -1520,6 +1695,8 @@ func (h *Handler) handleGetDeviceFacts(w http.ResponseWriter, r *http.Request) {
 			InterfaceName: row.InterfaceName,
 			CreatedAt:     row.CreatedAt,
 			UpdatedAt:     row.UpdatedAt,
+			LastSeenAt:    row.LastSeenAt,
+			StaleAt:       row.StaleAt,
 		})
 	}
 	macFacts := make([]deviceMACFact, 0, len(macs))
@@ -1530,6 +1707,8 @@ func (h *Handler) handleGetDeviceFacts(w http.ResponseWriter, r *http.Request) {
 			InterfaceName: row.InterfaceName,
 			CreatedAt:     row.CreatedAt,
 			UpdatedAt:     row.UpdatedAt,
+			LastSeenAt:    row.LastSeenAt,
+			StaleAt:       row.StaleAt,
 		})
 	}
 	ifaceFacts := make([]deviceInterfaceFact, 0, len(ifaces))
@@ -1553,15 +1732,36 @@ func (h *Handler) handleGetDeviceFacts(w http.ResponseWriter, r *http.Request) {
 	}
 	serviceFacts := make([]deviceServiceFact, 0, len(services))
 	for _, row := range services {
+		var httpFact *deviceServiceHTTPFact
+		if row.HTTPObservedAt != nil {
+			httpFact = &deviceServiceHTTPFact{
+				Status:      row.HTTPStatus,
+				Title:       row.HTTPTitle,
+				Server:      row.HTTPServer,
+				PoweredBy:   row.HTTPPoweredBy,
+				FaviconHash: row.HTTPFaviconHash,
+				FinalURL:    row.HTTPFinalURL,
+				ObservedAt:  *row.HTTPObservedAt,
+			}
+		}
 		serviceFacts = append(serviceFacts, deviceServiceFact{
-			Protocol:   row.Protocol,
-			Port:       row.Port,
-			Name:       row.Name,
-			State:      row.State,
-			Source:     row.Source,
-			ObservedAt: row.ObservedAt,
-			CreatedAt:  row.CreatedAt,
-			UpdatedAt:  row.UpdatedAt,
+			Protocol:    row.Protocol,
+			Port:        row.Port,
+			Name:        row.Name,
+			State:       row.State,
+			Source:      row.Source,
+			Summary:     row.Summary,
+			ObservedAt:  row.ObservedAt,
+			FirstSeenAt: row.FirstSeenAt,
+			LastSeenAt:  row.LastSeenAt,
+			ClosedAt:    row.ClosedAt,
+			Product:     row.Product,
+			Version:     row.Version,
+			ExtraInfo:   row.ExtraInfo,
+			CPE:         row.CPE,
+			HTTP:        httpFact,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
 		})
 	}
 	linkFacts := make([]deviceLinkFact, 0, len(links))
@@ -1574,19 +1774,70 @@ func (h *Handler) handleGetDeviceFacts(w http.ResponseWriter, r *http.Request) {
 			PeerInterfaceID:  row.PeerInterfaceID,
 			LinkType:         row.LinkType,
 			Source:           row.Source,
+			Confidence:       row.Confidence,
+			State:            row.State,
+			LocalAS:          row.LocalAS,
+			PeerAS:           row.PeerAS,
+			Area:             row.Area,
+			LastChangeAt:     row.LastChangeAt,
 			ObservedAt:       row.ObservedAt,
 			UpdatedAt:        row.UpdatedAt,
 		})
 	}
+	sshKeyFacts := make([]deviceSSHHostKeyFact, 0, len(sshKeys))
+	for _, row := range sshKeys {
+		shared := row.SharedWithDeviceIDs
+		if shared == nil {
+			shared = []string{}
+		}
+		sshKeyFacts = append(sshKeyFacts, deviceSSHHostKeyFact{
+			KeyType:             row.KeyType,
+			FingerprintSHA256:   row.FingerprintSHA256,
+			PublicKey:           row.PublicKey,
+			Port:                row.Port,
+			Banner:              row.Banner,
+			SharedWithDeviceIDs: shared,
+			FirstSeenAt:         row.FirstSeenAt,
+			LastSeenAt:          row.LastSeenAt,
+		})
+	}
+	osGuessFacts := make([]deviceOSGuessFact, 0, len(osGuesses))
+	for _, row := range osGuesses {
+		cpe := row.CPE
+		if cpe == nil {
+			cpe = []string{}
+		}
+		osGuessFacts = append(osGuessFacts, deviceOSGuessFact{
+			Source:       row.Source,
+			Rank:         row.Rank,
+			Name:         row.Name,
+			Accuracy:     row.Accuracy,
+			Vendor:       row.Vendor,
+			OSFamily:     row.OSFamily,
+			OSGeneration: row.OSGeneration,
+			DeviceType:   row.DeviceType,
+			CPE:          cpe,
+			ObservedAt:   row.ObservedAt,
+		})
+	}
+	customFacts, err := h.listDeviceCustomFacts(r.Context(), id)
+	if err != nil {
+		h.log.Error().Err(err).Str("id", id).Msg("list device custom facts failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to fetch device facts", nil)
+		return
+	}
 
 	h.writeJSON(w, http.StatusOK, deviceFacts{
-		DeviceID:   id,
-		IPs:        ipFacts,
-		MACs:       macFacts,
-		Interfaces: ifaceFacts,
-		Services:   serviceFacts,
-		SNMP:       snmpOut,
-		Links:      linkFacts,
+		DeviceID:    id,
+		IPs:         ipFacts,
+		MACs:        macFacts,
+		Interfaces:  ifaceFacts,
+		Services:    serviceFacts,
+		SNMP:        snmpOut,
+		Links:       linkFacts,
+		SSHHostKeys: sshKeyFacts,
+		OSGuesses:   osGuessFacts,
+		CustomFacts: customFacts,
 	})
 }
 
@@ -1652,7 +1903,9 @@ func (h *Handler) handleListDeviceNameCandidates(w http.ResponseWriter, r *http.
 }
 
 type deviceTagsUpdate struct {
-	Tags []string `json:"tags"`
+	Tags   []string `json:"tags"`
+	Actor  *string  `json:"actor,omitempty"`
+	Reason *string  `json:"reason,omitempty"`
 }
 
 func validateDeviceTagList(tags []string) ([]string, error) {
@@ -1748,26 +2001,20 @@ func (h *Handler) handlePutDeviceTags(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.devices.DeleteDeviceTagsBySource(ctx, sqlcgen.DeleteDeviceTagsBySourceParams{DeviceID: id, Source: "manual"}); err != nil {
+	if err := h.devices.ReplaceManualDeviceTags(ctx, sqlcgen.ReplaceManualDeviceTagsParams{
+		DeviceID: id,
+		Tags:     normalized,
+		Actor:    sqlcgen.UserActor(normalizeStringPtr(req.Actor), normalizeStringPtr(req.Reason)),
+	}); err != nil {
 		if isInvalidUUID(err) {
 			h.writeError(w, http.StatusBadRequest, "invalid_id", "device id is not a valid uuid", map[string]any{"id": id})
 		} else {
-			h.log.Error().Err(err).Str("id", id).Msg("delete manual tags failed")
+			h.log.Error().Err(err).Str("id", id).Msg("replace manual tags failed")
 			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to update device tags", nil)
 		}
 		return
 	}
 
-	for _, tag := range normalized {
-		_ = h.devices.UpsertDeviceTag(ctx, sqlcgen.UpsertDeviceTagParams{
-			DeviceID:   id,
-			Tag:        tag,
-			Source:     "manual",
-			Confidence: 100,
-			Evidence:   map[string]any{"signal": "manual"},
-		})
-	}
-
 	h.handleListDeviceTags(w, r)
 }
 
@@ -1902,9 +2149,11 @@ func (h *Handler) handleUpdateDevice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
+	actor := sqlcgen.UserActor(normalizeStringPtr(req.Actor), normalizeStringPtr(req.Reason))
 	row, err := h.devices.UpdateDevice(ctx, sqlcgen.UpdateDeviceParams{
 		ID:          id,
 		DisplayName: req.DisplayName,
+		Actor:       actor,
 	})
 	if err != nil {
 		switch {
@@ -1925,6 +2174,7 @@ func (h *Handler) handleUpdateDevice(w http.ResponseWriter, r *http.Request) {
 			Owner:    req.Metadata.Owner,
 			Location: req.Metadata.Location,
 			Notes:    req.Metadata.Notes,
+			Actor:    actor,
 		})
 		if err != nil {
 			h.log.Error().Err(err).Str("id", id).Msg("update device metadata failed")
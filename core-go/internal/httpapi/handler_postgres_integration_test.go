@@ -3,6 +3,7 @@ package httpapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -18,6 +19,9 @@ import (
 	"github.com/jackc/pgx/v5"
 
 	"roller_hoops/core-go/internal/db"
+	"roller_hoops/core-go/internal/duplicates"
+	"roller_hoops/core-go/internal/retention"
+	"roller_hoops/core-go/internal/sqlcgen"
 )
 
 func requireTestDatabaseURL(t *testing.T) string {
@@ -770,3 +774,1170 @@ func TestHandler_Postgres_MapL3_SubnetFocus_EmptySubnet_Returns200(t *testing.T)
 		t.Fatalf("expected 0 edges, got %d", len(proj.Edges))
 	}
 }
+
+func TestHandler_Postgres_DeviceMerge(t *testing.T) {
+	adminURL := requireTestDatabaseURL(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	dbName := newTestDatabaseName()
+	testDBURL := mustDeriveDatabaseURL(t, adminURL, dbName)
+
+	if err := createDatabase(ctx, adminURL, dbName); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = dropDatabase(context.Background(), adminURL, dbName)
+	})
+
+	conn, err := pgx.Connect(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close(context.Background()) })
+	if err := applyMigrations(ctx, conn, migrationsDir(t)); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+
+	var survivorID, sourceID string
+	if err := conn.QueryRow(ctx, `INSERT INTO devices (display_name) VALUES (NULL) RETURNING id::text`).Scan(&survivorID); err != nil {
+		t.Fatalf("insert survivor: %v", err)
+	}
+	if err := conn.QueryRow(ctx, `INSERT INTO devices (display_name) VALUES ('core-sw-1') RETURNING id::text`).Scan(&sourceID); err != nil {
+		t.Fatalf("insert source: %v", err)
+	}
+	seed := []string{
+		`INSERT INTO ip_addresses (device_id, ip, created_at, last_seen_at) VALUES ($1::uuid, '192.0.2.10', now() - interval '2 hours', now() - interval '2 hours'), ($2::uuid, '192.0.2.10', now() - interval '2 hours', now() - interval '2 hours'), ($2::uuid, '192.0.2.11', now() - interval '2 hours', now() - interval '2 hours')`,
+		`INSERT INTO interfaces (device_id, ifindex, name) VALUES ($1::uuid, 1, 'ge-0/0/1'), ($2::uuid, 1, 'ge-0/0/1'), ($2::uuid, 2, 'ge-0/0/2')`,
+		`INSERT INTO services (device_id, protocol, port, state) VALUES ($1::uuid, 'tcp', 22, 'open'), ($2::uuid, 'tcp', 22, 'open'), ($2::uuid, 'tcp', 443, 'open')`,
+		`INSERT INTO device_tags (device_id, tag, source, confidence) VALUES ($1::uuid, 'switch', 'auto', 40), ($2::uuid, 'switch', 'auto', 80)`,
+		`INSERT INTO device_metadata (device_id, owner, location) VALUES ($2::uuid, 'netops', 'rack 4')`,
+		`INSERT INTO links (link_key, a_device_id, b_device_id, source) VALUES ('manual:pair', $1::uuid, $2::uuid, 'manual')`,
+		`INSERT INTO device_archive_events (device_id, action, actor) VALUES ($2::uuid, 'archived', 'bob')`,
+		`INSERT INTO fact_detachments (device_id, kind, value, last_seen_at) VALUES ($2::uuid, 'ip', '192.0.2.99', now() - interval '30 days')`,
+		`INSERT INTO device_availability (device_id, state, previous_state, changed_at) VALUES ($1::uuid, 'up', NULL, now() - interval '1 hour'), ($2::uuid, 'up', NULL, now() - interval '3 hours'), ($2::uuid, 'down', 'up', now() - interval '10 minutes')`,
+		`INSERT INTO device_monitors (device_id, source, method) VALUES ($1::uuid, 'manual', 'icmp'), ($2::uuid, 'manual', 'tcp')`,
+		`INSERT INTO device_events (device_id, kind, actor_type, after, summary) VALUES ($2::uuid, 'snmp', 'system', '{"sys_name": "core-sw-1"}'::jsonb, 'snmp identity core-sw-1')`,
+		`INSERT INTO reachability_samples (device_id, probed_at, method, address, success, rtt_ms) VALUES ($2::uuid, now(), 'tcp', '192.0.2.11', true, 3)`,
+		`INSERT INTO reachability_rollups (device_id, bucket_start, probes, failures, rtt_sum_ms, rtt_min_ms, rtt_max_ms) VALUES ($1::uuid, '2026-01-01T10:00:00Z', 10, 1, 18, 1, 4), ($2::uuid, '2026-01-01T10:00:00Z', 5, 0, 15, 2, 6), ($2::uuid, '2026-01-01T11:00:00Z', 5, 5, 0, NULL, NULL)`,
+	}
+	for _, stmt := range seed {
+		if _, err := conn.Exec(ctx, stmt, survivorID, sourceID); err != nil {
+			t.Fatalf("seed %q: %v", stmt, err)
+		}
+	}
+
+	pool, err := db.Open(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("open db pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	router := NewHandler(NewLogger("error"), pool).Router()
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/devices/"+survivorID+"/merge", strings.NewReader(`{"source_ids":["`+sourceID+`"],"actor":"alice"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("merge expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var merged deviceMergeResult
+	if err := json.NewDecoder(rr.Body).Decode(&merged); err != nil {
+		t.Fatalf("decode merge response: %v", err)
+	}
+	if merged.Device.DisplayName == nil || *merged.Device.DisplayName != "core-sw-1" {
+		t.Fatalf("expected display_name to be filled from the source, got %v", merged.Device.DisplayName)
+	}
+	if merged.Moved["ips"] != 1 || merged.Moved["services"] != 1 || merged.Moved["interfaces"] != 1 || merged.Moved["interfaces_deduplicated"] != 1 {
+		t.Fatalf("unexpected moved counts %v", merged.Moved)
+	}
+
+	counts := []struct {
+		query string
+		want  int
+	}{
+		{`SELECT count(*) FROM ip_addresses WHERE device_id = $1::uuid`, 2},
+		{`SELECT count(*) FROM ip_addresses WHERE device_id = $1::uuid AND merged_from IS NOT NULL AND ip = '192.0.2.11'`, 1},
+		{`SELECT count(*) FROM interfaces WHERE device_id = $1::uuid`, 2},
+		{`SELECT count(*) FROM services WHERE device_id = $1::uuid`, 2},
+		{`SELECT count(*) FROM device_tags WHERE device_id = $1::uuid AND confidence = 80`, 1},
+		{`SELECT count(*) FROM device_metadata WHERE device_id = $1::uuid AND owner = 'netops'`, 1},
+		{`SELECT count(*) FROM links WHERE a_device_id = $1::uuid OR b_device_id = $1::uuid`, 0},
+		{`SELECT count(*) FROM device_archive_events WHERE device_id = $1::uuid AND actor = 'bob'`, 1},
+		{`SELECT count(*) FROM fact_detachments WHERE device_id = $1::uuid AND value = '192.0.2.99'`, 1},
+		{`SELECT count(*) FROM device_availability WHERE device_id = $1::uuid`, 2},
+		{`SELECT count(*) FROM device_monitors WHERE device_id = $1::uuid AND method = 'icmp'`, 1},
+		{`SELECT count(*) FROM reachability_samples WHERE device_id = $1::uuid`, 1},
+		{`SELECT count(*) FROM reachability_rollups WHERE device_id = $1::uuid AND bucket_start = '2026-01-01T10:00:00Z' AND probes = 15 AND failures = 1 AND rtt_sum_ms = 33 AND rtt_min_ms = 1 AND rtt_max_ms = 6`, 1},
+		{`SELECT count(*) FROM reachability_rollups WHERE device_id = $1::uuid`, 2},
+		{`SELECT count(*) FROM device_events WHERE device_id = $1::uuid AND kind = 'snmp'`, 0},
+		{`SELECT count(*) FROM device_events e JOIN device_aliases a ON a.alias_id = e.device_id WHERE a.device_id = $1::uuid AND e.kind = 'snmp'`, 1},
+		{`SELECT count(*) FROM device_events WHERE device_id = $1::uuid AND kind = 'tags' AND actor = 'alice' AND after = '{"tags": ["switch"]}'::jsonb`, 1},
+		{`SELECT count(*) FROM device_events WHERE device_id = $1::uuid AND kind = 'display_name' AND actor = 'alice' AND summary = 'named core-sw-1' AND reason LIKE 'merged from %'`, 1},
+		{`SELECT count(*) FROM device_events WHERE device_id = $1::uuid AND kind = 'metadata' AND actor = 'alice' AND after->>'owner' = 'netops' AND before IS NULL`, 1},
+		{`SELECT count(*) FROM (SELECT state FROM device_availability WHERE device_id = $1::uuid ORDER BY changed_at DESC, id DESC LIMIT 1) latest WHERE state = 'up'`, 1},
+		{`SELECT count(*) FROM devices WHERE id <> $1::uuid`, 0},
+		{`SELECT count(*) FROM audit_events WHERE action = 'device.merge' AND actor = 'alice' AND target_id = $1::uuid`, 1},
+	}
+	for _, c := range counts {
+		var got int
+		if err := conn.QueryRow(ctx, c.query, survivorID).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", c.query, err)
+		}
+		if got != c.want {
+			t.Fatalf("%s: expected %d, got %d", c.query, c.want, got)
+		}
+	}
+
+	// The source's IP belongs to the survivor only from the merge on.
+	for _, tc := range []struct {
+		at   time.Time
+		want string
+	}{
+		{time.Now().Add(-time.Hour), "192.0.2.10"},
+		{time.Now(), "192.0.2.10,192.0.2.11"},
+	} {
+		ips, err := pool.Queries().ListDeviceIPsAsOf(ctx, survivorID, tc.at)
+		if err != nil {
+			t.Fatalf("list ips as of: %v", err)
+		}
+		got := make([]string, 0, len(ips))
+		for _, ip := range ips {
+			got = append(got, ip.IP)
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != tc.want {
+			t.Fatalf("ips as of %s: expected %s, got %v", tc.at, tc.want, got)
+		}
+	}
+
+	rrHistory := httptest.NewRecorder()
+	router.ServeHTTP(rrHistory, httptest.NewRequest(http.MethodGet, "/api/v1/devices/"+survivorID+"/history", nil))
+	if rrHistory.Code != http.StatusOK || !strings.Contains(rrHistory.Body.String(), "snmp identity core-sw-1") {
+		t.Fatalf("expected the survivor's history to include the merged device's events, got %d: %s", rrHistory.Code, rrHistory.Body.String())
+	}
+
+	rrAlias := httptest.NewRecorder()
+	router.ServeHTTP(rrAlias, httptest.NewRequest(http.MethodGet, "/api/v1/devices/"+sourceID, nil))
+	if rrAlias.Code != http.StatusOK {
+		t.Fatalf("alias get expected 200, got %d: %s", rrAlias.Code, rrAlias.Body.String())
+	}
+	var resolved device
+	if err := json.NewDecoder(rrAlias.Body).Decode(&resolved); err != nil {
+		t.Fatalf("decode alias response: %v", err)
+	}
+	if resolved.ID != survivorID {
+		t.Fatalf("expected merged id to resolve to %s, got %s", survivorID, resolved.ID)
+	}
+
+	var purgedEvents int
+	if _, err := conn.Exec(ctx, `DELETE FROM devices WHERE id = $1::uuid`, survivorID); err != nil {
+		t.Fatalf("purge survivor: %v", err)
+	}
+	if err := conn.QueryRow(ctx, `SELECT count(*) FROM device_events WHERE device_id IN ($1::uuid, $2::uuid)`, survivorID, sourceID).Scan(&purgedEvents); err != nil || purgedEvents != 0 {
+		t.Fatalf("expected purging the survivor to remove its and its aliases' events, got %d (%v)", purgedEvents, err)
+	}
+}
+
+func TestHandler_Postgres_DeviceDuplicates(t *testing.T) {
+	adminURL := requireTestDatabaseURL(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	dbName := newTestDatabaseName()
+	testDBURL := mustDeriveDatabaseURL(t, adminURL, dbName)
+
+	if err := createDatabase(ctx, adminURL, dbName); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = dropDatabase(context.Background(), adminURL, dbName)
+	})
+
+	conn, err := pgx.Connect(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close(context.Background()) })
+	if err := applyMigrations(ctx, conn, migrationsDir(t)); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+
+	var aID, bID string
+	if err := conn.QueryRow(ctx, `INSERT INTO devices (display_name) VALUES ('nas-old') RETURNING id::text`).Scan(&aID); err != nil {
+		t.Fatalf("insert device: %v", err)
+	}
+	if err := conn.QueryRow(ctx, `INSERT INTO devices (display_name) VALUES ('nas-new') RETURNING id::text`).Scan(&bID); err != nil {
+		t.Fatalf("insert device: %v", err)
+	}
+	seed := []string{
+		`INSERT INTO mac_addresses (device_id, mac) VALUES ($1::uuid, '02:11:22:33:44:55'), ($2::uuid, '02:11:22:33:44:55')`,
+		`INSERT INTO device_snmp (device_id, sys_name) VALUES ($1::uuid, 'NAS01'), ($2::uuid, 'nas01')`,
+	}
+	for _, stmt := range seed {
+		if _, err := conn.Exec(ctx, stmt, aID, bID); err != nil {
+			t.Fatalf("seed %q: %v", stmt, err)
+		}
+	}
+
+	pool, err := db.Open(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("open db pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	q := pool.Queries()
+
+	analyze := func() int64 {
+		matches, err := q.ListDuplicateMatches(ctx, 1000)
+		if err != nil {
+			t.Fatalf("list matches: %v", err)
+		}
+		in := make([]duplicates.Match, 0, len(matches))
+		for _, m := range matches {
+			in = append(in, duplicates.Match{DeviceA: m.DeviceAID, DeviceB: m.DeviceBID, Signal: m.Signal, Value: m.Value})
+		}
+		params := sqlcgen.ReplaceDeviceDuplicateCandidatesParams{DetectedAt: time.Now()}
+		for _, c := range duplicates.Score(in) {
+			evidence, _ := json.Marshal(c.Evidence)
+			params.DeviceAIDs = append(params.DeviceAIDs, c.DeviceA)
+			params.DeviceBIDs = append(params.DeviceBIDs, c.DeviceB)
+			params.Scores = append(params.Scores, int32(c.Score))
+			params.Evidence = append(params.Evidence, string(evidence))
+		}
+		n, err := q.ReplaceDeviceDuplicateCandidates(ctx, params)
+		if err != nil {
+			t.Fatalf("replace candidates: %v", err)
+		}
+		return n
+	}
+	if n := analyze(); n != 1 {
+		t.Fatalf("expected 1 stored candidate, got %d", n)
+	}
+
+	router := NewHandler(NewLogger("error"), pool).Router()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/duplicates", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list duplicates expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var listed deviceDuplicatesResponse
+	if err := json.NewDecoder(rr.Body).Decode(&listed); err != nil {
+		t.Fatalf("decode duplicates: %v", err)
+	}
+	if len(listed.Duplicates) != 1 || listed.Duplicates[0].Score != 90 || len(listed.Duplicates[0].Evidence) != 2 {
+		t.Fatalf("unexpected duplicates %+v", listed.Duplicates)
+	}
+
+	rr = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/devices/duplicates/dismissals", strings.NewReader(`{"device_ids":["`+aID+`","`+bID+`"],"reason":"two NAS heads"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("dismiss expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if n := analyze(); n != 0 {
+		t.Fatalf("dismissed pair should not be stored again, got %d", n)
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/duplicates", nil))
+	if err := json.NewDecoder(rr.Body).Decode(&listed); err != nil || len(listed.Duplicates) != 0 {
+		t.Fatalf("expected no duplicates after dismissal, got %+v (%v)", listed.Duplicates, err)
+	}
+}
+
+func TestHandler_Postgres_DeviceArchive(t *testing.T) {
+	adminURL := requireTestDatabaseURL(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	dbName := newTestDatabaseName()
+	testDBURL := mustDeriveDatabaseURL(t, adminURL, dbName)
+
+	if err := createDatabase(ctx, adminURL, dbName); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = dropDatabase(context.Background(), adminURL, dbName)
+	})
+
+	conn, err := pgx.Connect(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close(context.Background()) })
+	if err := applyMigrations(ctx, conn, migrationsDir(t)); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+
+	var deviceID string
+	if err := conn.QueryRow(ctx, `INSERT INTO devices (display_name) VALUES ('old-printer') RETURNING id::text`).Scan(&deviceID); err != nil {
+		t.Fatalf("insert device: %v", err)
+	}
+	if _, err := conn.Exec(ctx, `INSERT INTO ip_addresses (device_id, ip) VALUES ($1::uuid, '192.0.2.20')`, deviceID); err != nil {
+		t.Fatalf("seed ip: %v", err)
+	}
+
+	pool, err := db.Open(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("open db pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	q := pool.Queries()
+	router := NewHandler(NewLogger("error"), pool).Router()
+
+	listIDs := func(query string) []string {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices"+query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("list %s expected 200, got %d: %s", query, rr.Code, rr.Body.String())
+		}
+		var page devicePage
+		if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+			t.Fatalf("decode list: %v", err)
+		}
+		ids := make([]string, 0, len(page.Devices))
+		for _, d := range page.Devices {
+			ids = append(ids, d.ID)
+		}
+		return ids
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/devices/"+deviceID+"/archive", strings.NewReader(`{"actor":"alice","reason":"retired"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("archive expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ids := listIDs(""); len(ids) != 0 {
+		t.Fatalf("archived device should be hidden by default, got %v", ids)
+	}
+	if ids := listIDs("?archived=only"); len(ids) != 1 || ids[0] != deviceID {
+		t.Fatalf("expected archived device with archived=only, got %v", ids)
+	}
+	if _, err := q.FindDeviceIDByIP(ctx, "192.0.2.20"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("archived device should not match by ip, got %v", err)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/api/v1/devices/"+deviceID+"?actor=alice", nil))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("purge expected 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	counts := []struct {
+		query string
+		want  int
+	}{
+		{`SELECT count(*) FROM devices WHERE id = $1::uuid`, 0},
+		{`SELECT count(*) FROM ip_addresses WHERE device_id = $1::uuid`, 0},
+		{`SELECT count(*) FROM audit_events WHERE action = 'device.purge' AND actor = 'alice' AND target_id = $1::uuid`, 1},
+	}
+	for _, c := range counts {
+		var got int
+		if err := conn.QueryRow(ctx, c.query, deviceID).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", c.query, err)
+		}
+		if got != c.want {
+			t.Fatalf("%s: expected %d, got %d", c.query, c.want, got)
+		}
+	}
+}
+
+func TestHandler_Postgres_FactAging(t *testing.T) {
+	adminURL := requireTestDatabaseURL(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	dbName := newTestDatabaseName()
+	testDBURL := mustDeriveDatabaseURL(t, adminURL, dbName)
+
+	if err := createDatabase(ctx, adminURL, dbName); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = dropDatabase(context.Background(), adminURL, dbName)
+	})
+
+	conn, err := pgx.Connect(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close(context.Background()) })
+	if err := applyMigrations(ctx, conn, migrationsDir(t)); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+
+	var deviceID string
+	if err := conn.QueryRow(ctx, `INSERT INTO devices (display_name) VALUES ('laptop') RETURNING id::text`).Scan(&deviceID); err != nil {
+		t.Fatalf("insert device: %v", err)
+	}
+	seed := []string{
+		`INSERT INTO ip_addresses (device_id, ip, last_seen_at) VALUES ($1::uuid, '192.0.2.30', now() - interval '10 days'), ($1::uuid, '192.0.2.31', now())`,
+		`INSERT INTO mac_addresses (device_id, mac, last_seen_at) VALUES ($1::uuid, '00:11:22:33:44:66', now() - interval '40 days')`,
+		// Imported from inventory long ago and never seen by discovery: aging leaves it alone.
+		`INSERT INTO ip_addresses (device_id, ip, last_seen_at, source) VALUES ($1::uuid, '192.0.2.32', now() - interval '40 days', 'import')`,
+	}
+	for _, stmt := range seed {
+		if _, err := conn.Exec(ctx, stmt, deviceID); err != nil {
+			t.Fatalf("seed %q: %v", stmt, err)
+		}
+	}
+
+	pool, err := db.Open(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("open db pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	q := pool.Queries()
+
+	now := time.Now()
+	if n, err := q.MarkStaleIPAddresses(ctx, now.Add(-7*24*time.Hour)); err != nil || n != 1 {
+		t.Fatalf("mark stale ips: %d (%v)", n, err)
+	}
+	if n, err := q.DetachStaleMACAddresses(ctx, sqlcgen.DetachStaleFactsParams{Before: now.Add(-30 * 24 * time.Hour)}); err != nil || n != 1 {
+		t.Fatalf("detach macs: %d (%v)", n, err)
+	}
+	if n, err := q.DetachStaleIPAddresses(ctx, sqlcgen.DetachStaleFactsParams{Before: now.Add(-30 * 24 * time.Hour)}); err != nil || n != 0 {
+		t.Fatalf("detach ips: expected the imported ip to stay, got %d (%v)", n, err)
+	}
+	if id, err := q.FindDeviceIDByIP(ctx, "192.0.2.32"); err != nil || id != deviceID {
+		t.Fatalf("imported ip should not be stale, got %s (%v)", id, err)
+	}
+
+	if _, err := q.FindDeviceIDByIP(ctx, "192.0.2.30"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("stale ip should not match, got %v", err)
+	}
+	if id, err := q.FindDeviceIDByIP(ctx, "192.0.2.31"); err != nil || id != deviceID {
+		t.Fatalf("current ip should match %s, got %s (%v)", deviceID, id, err)
+	}
+	if err := q.UpsertDeviceIP(ctx, sqlcgen.UpsertDeviceIPParams{DeviceID: deviceID, IP: "192.0.2.30"}); err != nil {
+		t.Fatalf("re-observe ip: %v", err)
+	}
+	if id, err := q.FindDeviceIDByIP(ctx, "192.0.2.30"); err != nil || id != deviceID {
+		t.Fatalf("re-observed ip should match again, got %s (%v)", id, err)
+	}
+
+	// A DHCP lease moved: the older record is not stale yet, but the IP now answers from another device.
+	var newHolderID string
+	if err := conn.QueryRow(ctx, `INSERT INTO devices (display_name) VALUES ('phone') RETURNING id::text`).Scan(&newHolderID); err != nil {
+		t.Fatalf("insert device: %v", err)
+	}
+	if _, err := conn.Exec(ctx, `UPDATE ip_addresses SET last_seen_at = now() - interval '2 days' WHERE device_id = $1::uuid AND ip = '192.0.2.31'`, deviceID); err != nil {
+		t.Fatalf("age ip: %v", err)
+	}
+	if err := q.UpsertDeviceIP(ctx, sqlcgen.UpsertDeviceIPParams{DeviceID: newHolderID, IP: "192.0.2.31"}); err != nil {
+		t.Fatalf("observe moved ip: %v", err)
+	}
+	if id, err := q.FindDeviceIDByIP(ctx, "192.0.2.31"); err != nil || id != newHolderID {
+		t.Fatalf("shared ip should match the most recent holder %s, got %s (%v)", newHolderID, id, err)
+	}
+
+	router := NewHandler(NewLogger("error"), pool).Router()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/"+deviceID+"/history", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("history expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var feed deviceChangeEventsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&feed); err != nil {
+		t.Fatalf("decode history: %v", err)
+	}
+	detached := 0
+	for _, ev := range feed.Events {
+		if ev.Kind == "detached" && ev.Summary == "mac 00:11:22:33:44:66 detached" {
+			detached++
+		}
+	}
+	if detached != 1 {
+		t.Fatalf("expected one detachment event, got %+v", feed.Events)
+	}
+}
+
+func TestHandler_Postgres_Retention(t *testing.T) {
+	adminURL := requireTestDatabaseURL(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	dbName := newTestDatabaseName()
+	testDBURL := mustDeriveDatabaseURL(t, adminURL, dbName)
+
+	if err := createDatabase(ctx, adminURL, dbName); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = dropDatabase(context.Background(), adminURL, dbName)
+	})
+
+	conn, err := pgx.Connect(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close(context.Background()) })
+	if err := applyMigrations(ctx, conn, migrationsDir(t)); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+
+	var deviceID string
+	if err := conn.QueryRow(ctx, `INSERT INTO devices (display_name) VALUES ('nas') RETURNING id::text`).Scan(&deviceID); err != nil {
+		t.Fatalf("insert device: %v", err)
+	}
+	// One raw observation, three on the same UTC day inside the daily tier, and one past it; each needs its own run.
+	// Thinning keeps the first and last of the day.
+	seed := []string{
+		`WITH obs(n, observed_at) AS (
+		   VALUES (1, now() - interval '1 day'),
+		          (2, (date_trunc('day', (now() - interval '100 days') AT TIME ZONE 'UTC') + interval '1 hour') AT TIME ZONE 'UTC'),
+		          (3, (date_trunc('day', (now() - interval '100 days') AT TIME ZONE 'UTC') + interval '2 hours') AT TIME ZONE 'UTC'),
+		          (4, (date_trunc('day', (now() - interval '100 days') AT TIME ZONE 'UTC') + interval '3 hours') AT TIME ZONE 'UTC'),
+		          (5, now() - interval '400 days')
+		 ),
+		 runs AS (
+		   INSERT INTO discovery_runs (status) SELECT 'succeeded' FROM obs RETURNING id
+		 )
+		 INSERT INTO ip_observations (run_id, device_id, ip, observed_at)
+		 SELECT r.id, $1::uuid, '192.0.2.40', o.observed_at
+		 FROM (SELECT id, row_number() OVER () AS n FROM runs) r
+		 JOIN obs o USING (n)`,
+		`INSERT INTO discovery_run_logs (run_id, message, created_at)
+		 SELECT run_id, 'old', now() - interval '100 days' FROM ip_observations WHERE device_id = $1::uuid LIMIT 1`,
+		`INSERT INTO discovery_run_logs (run_id, message)
+		 SELECT run_id, 'new' FROM ip_observations WHERE device_id = $1::uuid LIMIT 1`,
+	}
+	for _, stmt := range seed {
+		if _, err := conn.Exec(ctx, stmt, deviceID); err != nil {
+			t.Fatalf("seed %q: %v", stmt, err)
+		}
+	}
+
+	pool, err := db.Open(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("open db pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	q := pool.Queries()
+
+	defaults := []retention.Policy{
+		{Table: sqlcgen.RetentionTableIPObservations, KeepRawDays: 30, KeepDailyDays: 365},
+		{Table: sqlcgen.RetentionTableDiscoveryRunLogs, KeepRawDays: 90},
+	}
+	now := time.Now().UTC()
+	reports, err := retention.Report(ctx, q, defaults, now)
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	want := map[string]sqlcgen.RetentionCounts{
+		sqlcgen.RetentionTableIPObservations:   {Total: 5, ToDrop: 1, ToThin: 1},
+		sqlcgen.RetentionTableMACObservations:  {},
+		sqlcgen.RetentionTableDiscoveryRunLogs: {Total: 2, ToDrop: 1},
+	}
+	for _, rep := range reports {
+		if rep.Counts != want[rep.Policy.Table] {
+			t.Fatalf("%s: expected %+v, got %+v", rep.Policy.Table, want[rep.Policy.Table], rep.Counts)
+		}
+	}
+
+	job := retention.New(NewLogger("error"), q, retention.Options{BatchSize: 1, Defaults: defaults}, nil)
+	if _, err := job.RunOnce(ctx, now); err != nil {
+		t.Fatalf("run retention: %v", err)
+	}
+
+	var ipRows, logRows int
+	if err := conn.QueryRow(ctx, `SELECT count(*) FROM ip_observations`).Scan(&ipRows); err != nil {
+		t.Fatalf("count ip observations: %v", err)
+	}
+	if err := conn.QueryRow(ctx, `SELECT count(*) FROM discovery_run_logs`).Scan(&logRows); err != nil {
+		t.Fatalf("count run logs: %v", err)
+	}
+	if ipRows != 3 || logRows != 1 {
+		t.Fatalf("expected 3 observations and 1 log left, got %d and %d", ipRows, logRows)
+	}
+	var middle int
+	if err := conn.QueryRow(ctx,
+		`SELECT count(*) FROM ip_observations
+		 WHERE observed_at = (date_trunc('day', (now() - interval '100 days') AT TIME ZONE 'UTC') + interval '2 hours') AT TIME ZONE 'UTC'`,
+	).Scan(&middle); err != nil {
+		t.Fatalf("count thinned observation: %v", err)
+	}
+	if middle != 0 {
+		t.Fatalf("expected the middle observation of the day to be thinned")
+	}
+}
+
+func TestHandler_Postgres_DeviceAvailability(t *testing.T) {
+	adminURL := requireTestDatabaseURL(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	dbName := newTestDatabaseName()
+	testDBURL := mustDeriveDatabaseURL(t, adminURL, dbName)
+
+	if err := createDatabase(ctx, adminURL, dbName); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = dropDatabase(context.Background(), adminURL, dbName)
+	})
+
+	conn, err := pgx.Connect(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close(context.Background()) })
+	if err := applyMigrations(ctx, conn, migrationsDir(t)); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+
+	var deviceID string
+	if err := conn.QueryRow(ctx, `INSERT INTO devices (display_name) VALUES ('printer') RETURNING id::text`).Scan(&deviceID); err != nil {
+		t.Fatalf("insert device: %v", err)
+	}
+	if _, err := conn.Exec(ctx, `INSERT INTO devices (display_name) VALUES ('never seen')`); err != nil {
+		t.Fatalf("insert unseen device: %v", err)
+	}
+	if _, err := conn.Exec(ctx, `INSERT INTO ip_addresses (device_id, ip, last_seen_at) VALUES ($1::uuid, '192.0.2.50', now() - interval '3 hours')`, deviceID); err != nil {
+		t.Fatalf("seed ip: %v", err)
+	}
+
+	pool, err := db.Open(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("open db pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	q := pool.Queries()
+
+	record := func() []sqlcgen.DeviceAvailabilityChange {
+		t.Helper()
+		now := time.Now()
+		changes, err := q.RecordDeviceAvailability(ctx, sqlcgen.RecordDeviceAvailabilityParams{SeenAfter: now.Add(-time.Hour), Now: now, Source: "discovery"})
+		if err != nil {
+			t.Fatalf("record availability: %v", err)
+		}
+		return changes
+	}
+	if changes := record(); len(changes) != 1 || changes[0].State != sqlcgen.AvailabilityDown || changes[0].PreviousState != nil {
+		t.Fatalf("expected the seen device to start down, got %+v", changes)
+	}
+	if changes := record(); len(changes) != 0 {
+		t.Fatalf("expected no change without new observations, got %+v", changes)
+	}
+	if err := q.UpsertDeviceIP(ctx, sqlcgen.UpsertDeviceIPParams{DeviceID: deviceID, IP: "192.0.2.50"}); err != nil {
+		t.Fatalf("re-observe ip: %v", err)
+	}
+	if changes := record(); len(changes) != 1 || changes[0].State != sqlcgen.AvailabilityUp || changes[0].PreviousState == nil {
+		t.Fatalf("expected the device to come back up, got %+v", changes)
+	}
+
+	router := NewHandler(NewLogger("error"), pool).Router()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/"+deviceID+"/availability", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("availability expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var availability deviceAvailabilityResponse
+	if err := json.NewDecoder(rr.Body).Decode(&availability); err != nil {
+		t.Fatalf("decode availability: %v", err)
+	}
+	if availability.State != sqlcgen.AvailabilityUp || len(availability.Transitions) != 2 || availability.UptimePercent == nil {
+		t.Fatalf("unexpected availability %+v", availability)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/"+deviceID+"/history", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("history expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var feed deviceChangeEventsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&feed); err != nil {
+		t.Fatalf("decode history: %v", err)
+	}
+	var availabilityEvents []string
+	for _, ev := range feed.Events {
+		if ev.Kind == "availability" {
+			availabilityEvents = append(availabilityEvents, ev.Summary)
+		}
+	}
+	if len(availabilityEvents) != 1 || availabilityEvents[0] != "device up (was down)" {
+		t.Fatalf("expected one availability event, got %v", availabilityEvents)
+	}
+
+	// Two scopes, both last seen hours ago: a run over one of them says nothing about the other.
+	var officeID, labID string
+	if err := conn.QueryRow(ctx, `INSERT INTO devices (display_name) VALUES ('office') RETURNING id::text`).Scan(&officeID); err != nil {
+		t.Fatalf("insert device: %v", err)
+	}
+	if err := conn.QueryRow(ctx, `INSERT INTO devices (display_name) VALUES ('lab') RETURNING id::text`).Scan(&labID); err != nil {
+		t.Fatalf("insert device: %v", err)
+	}
+	if _, err := conn.Exec(ctx, `INSERT INTO ip_addresses (device_id, ip, last_seen_at) VALUES ($1::uuid, '10.0.1.5', now() - interval '3 hours'), ($2::uuid, '10.0.2.5', now() - interval '3 hours')`, officeID, labID); err != nil {
+		t.Fatalf("seed ips: %v", err)
+	}
+	recordScope := func(scope string) map[string]string {
+		t.Helper()
+		now := time.Now()
+		changes, err := q.RecordDeviceAvailability(ctx, sqlcgen.RecordDeviceAvailabilityParams{SeenAfter: now.Add(-time.Hour), Now: now, Source: "discovery", Scope: &scope})
+		if err != nil {
+			t.Fatalf("record availability: %v", err)
+		}
+		out := map[string]string{}
+		for _, c := range changes {
+			out[c.DeviceID] = c.State
+		}
+		return out
+	}
+	if got := recordScope("10.0.1.0/24"); len(got) != 1 || got[officeID] != sqlcgen.AvailabilityDown {
+		t.Fatalf("expected only the office device to be evaluated, got %v", got)
+	}
+	if got := recordScope("10.0.2.0/24"); len(got) != 1 || got[labID] != sqlcgen.AvailabilityDown {
+		t.Fatalf("expected only the lab device to be evaluated, got %v", got)
+	}
+	if got := recordScope("10.0.1.0/24"); len(got) != 0 {
+		t.Fatalf("expected no change on a second run over the same scope, got %v", got)
+	}
+}
+
+func TestHandler_Postgres_ReachabilityMonitor(t *testing.T) {
+	adminURL := requireTestDatabaseURL(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	dbName := newTestDatabaseName()
+	testDBURL := mustDeriveDatabaseURL(t, adminURL, dbName)
+
+	if err := createDatabase(ctx, adminURL, dbName); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = dropDatabase(context.Background(), adminURL, dbName)
+	})
+
+	conn, err := pgx.Connect(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close(context.Background()) })
+	if err := applyMigrations(ctx, conn, migrationsDir(t)); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+
+	var routerID, serverID string
+	if err := conn.QueryRow(ctx, `INSERT INTO devices (display_name) VALUES ('core router') RETURNING id::text`).Scan(&routerID); err != nil {
+		t.Fatalf("insert router: %v", err)
+	}
+	if err := conn.QueryRow(ctx, `INSERT INTO devices (display_name) VALUES ('web') RETURNING id::text`).Scan(&serverID); err != nil {
+		t.Fatalf("insert server: %v", err)
+	}
+	if _, err := conn.Exec(ctx, `INSERT INTO device_tags (device_id, tag, source) VALUES ($1::uuid, 'router', 'manual')`, routerID); err != nil {
+		t.Fatalf("tag router: %v", err)
+	}
+	if _, err := conn.Exec(ctx, `INSERT INTO ip_addresses (device_id, ip, last_seen_at) VALUES ($1::uuid, '192.0.2.1', now()), ($2::uuid, '192.0.2.80', now())`, routerID, serverID); err != nil {
+		t.Fatalf("seed ips: %v", err)
+	}
+
+	pool, err := db.Open(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("open db pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	q := pool.Queries()
+	router := NewHandler(NewLogger("error"), pool).Router()
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/api/v1/devices/"+serverID+"/monitor", strings.NewReader(`{"method":"tcp","port":443}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("put monitor expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if changed, err := q.SyncTaggedDeviceMonitors(ctx, sqlcgen.SyncTaggedDeviceMonitorsParams{Tags: []string{"router"}, Method: "icmp"}); err != nil || changed != 1 {
+		t.Fatalf("expected the router to be picked up by tag, got %d (%v)", changed, err)
+	}
+
+	targets, err := q.ListMonitorTargets(ctx)
+	if err != nil || len(targets) != 2 {
+		t.Fatalf("expected two targets, got %+v (%v)", targets, err)
+	}
+	for _, target := range targets {
+		if target.Address == nil {
+			t.Fatalf("expected an address for %+v", target)
+		}
+		if target.DeviceID == serverID && (target.Method != "tcp" || target.Port == nil || *target.Port != 443) {
+			t.Fatalf("unexpected server target %+v", target)
+		}
+	}
+
+	probedAt := time.Now().UTC().Truncate(time.Second)
+	rtt := 2.5
+	for i, ok := range []bool{true, false, false} {
+		arg := sqlcgen.RecordReachabilitySampleParams{DeviceID: routerID, ProbedAt: probedAt.Add(time.Duration(i) * time.Second), Method: "icmp", Address: "192.0.2.1", Success: ok}
+		if ok {
+			arg.RTTMs = &rtt
+		}
+		status, err := q.RecordReachabilitySample(ctx, arg)
+		if err != nil {
+			t.Fatalf("record sample %d: %v", i, err)
+		}
+		if want := int32(i); status.ConsecutiveFailures != want {
+			t.Fatalf("sample %d: expected %d consecutive failures, got %d", i, want, status.ConsecutiveFailures)
+		}
+	}
+	for _, state := range []string{sqlcgen.AvailabilityDown, sqlcgen.AvailabilityDown} {
+		if _, err := q.RecordMonitorAvailability(ctx, sqlcgen.RecordMonitorAvailabilityParams{DeviceID: routerID, State: state, ChangedAt: probedAt, Evidence: map[string]any{"source": "monitor"}}); err != nil {
+			t.Fatalf("record monitor availability: %v", err)
+		}
+	}
+	var transitions int
+	if err := conn.QueryRow(ctx, `SELECT count(*) FROM device_availability WHERE device_id = $1::uuid`, routerID).Scan(&transitions); err != nil || transitions != 1 {
+		t.Fatalf("expected one monitor transition, got %d (%v)", transitions, err)
+	}
+	// The monitor owns the router's state, so a discovery evaluation leaves it alone.
+	changes, err := q.RecordDeviceAvailability(ctx, sqlcgen.RecordDeviceAvailabilityParams{SeenAfter: time.Now().Add(-time.Hour), Now: time.Now(), Source: "discovery"})
+	if err != nil {
+		t.Fatalf("record discovery availability: %v", err)
+	}
+	for _, c := range changes {
+		if c.DeviceID == routerID {
+			t.Fatalf("expected discovery to skip the monitored router, got %+v", changes)
+		}
+	}
+
+	for _, resolution := range []string{"raw", "hour"} {
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/"+routerID+"/reachability?resolution="+resolution, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("reachability expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var series deviceReachabilityResponse
+		if err := json.NewDecoder(rr.Body).Decode(&series); err != nil {
+			t.Fatalf("decode reachability: %v", err)
+		}
+		var probes, failures int32
+		for _, p := range series.Points {
+			probes += p.Probes
+			failures += p.Failures
+		}
+		if probes != 3 || failures != 2 {
+			t.Fatalf("%s: expected 3 probes with 2 failures, got %+v", resolution, series.Points)
+		}
+	}
+
+	if _, err := conn.Exec(ctx, `DELETE FROM device_tags WHERE device_id = $1::uuid`, routerID); err != nil {
+		t.Fatalf("untag router: %v", err)
+	}
+	if changed, err := q.SyncTaggedDeviceMonitors(ctx, sqlcgen.SyncTaggedDeviceMonitorsParams{Tags: []string{"router"}, Method: "icmp"}); err != nil || changed != 1 {
+		t.Fatalf("expected the untagged router monitor to be removed, got %d (%v)", changed, err)
+	}
+	if n, err := q.DeleteReachabilitySamplesBefore(ctx, probedAt.Add(time.Hour), 100); err != nil || n != 3 {
+		t.Fatalf("expected the three samples to be pruned, got %d (%v)", n, err)
+	}
+}
+
+func TestHandler_Postgres_DeviceEvents(t *testing.T) {
+	adminURL := requireTestDatabaseURL(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	dbName := newTestDatabaseName()
+	testDBURL := mustDeriveDatabaseURL(t, adminURL, dbName)
+
+	if err := createDatabase(ctx, adminURL, dbName); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = dropDatabase(context.Background(), adminURL, dbName)
+	})
+
+	conn, err := pgx.Connect(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close(context.Background()) })
+	if err := applyMigrations(ctx, conn, migrationsDir(t)); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+
+	pool, err := db.Open(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("open db pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	q := pool.Queries()
+
+	device, err := q.CreateDevice(ctx, nil)
+	if err != nil {
+		t.Fatalf("create device: %v", err)
+	}
+	runID := "00000000-0000-0000-0000-0000000000aa"
+	if n, err := q.SetDeviceDisplayNameIfUnset(ctx, sqlcgen.SetDeviceDisplayNameIfUnsetParams{ID: device.ID, DisplayName: "sw-1", Actor: sqlcgen.RunActor(runID)}); err != nil || n != 1 {
+		t.Fatalf("name unnamed device: n=%d err=%v", n, err)
+	}
+	if n, err := q.SetDeviceDisplayNameIfUnset(ctx, sqlcgen.SetDeviceDisplayNameIfUnsetParams{ID: device.ID, DisplayName: "sw-2", Actor: sqlcgen.RunActor(runID)}); err != nil || n != 0 {
+		t.Fatalf("expected a named device to be left alone: n=%d err=%v", n, err)
+	}
+
+	sysName := "sw-1.lab"
+	now := time.Now()
+	for i, arg := range []sqlcgen.UpsertDeviceSNMPParams{
+		{DeviceID: device.ID, SysName: &sysName, LastSuccessAt: &now},
+		{DeviceID: device.ID, SysName: &sysName, LastSuccessAt: &now},
+		{DeviceID: device.ID, LastError: &sysName},
+		{DeviceID: device.ID, SysName: &sysName, LastSuccessAt: &now},
+	} {
+		arg.Actor = sqlcgen.RunActor(runID)
+		if err := q.UpsertDeviceSNMP(ctx, arg); err != nil {
+			t.Fatalf("snmp poll %d: %v", i, err)
+		}
+	}
+
+	router := NewHandler(NewLogger("error"), pool).Router()
+	for _, body := range []string{
+		`{"display_name":"core-switch","metadata":{"owner":"alice"},"actor":"bob","reason":"ticket 42"}`,
+		`{"display_name":"core-switch","metadata":{"owner":"carol"},"actor":"bob"}`,
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/api/v1/devices/"+device.ID, strings.NewReader(body)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("update expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/"+device.ID+"/history", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("history expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var feed deviceChangeEventsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&feed); err != nil {
+		t.Fatalf("decode history: %v", err)
+	}
+	var summaries []string
+	var rename, ownerChange map[string]any
+	for _, ev := range feed.Events {
+		if !strings.HasPrefix(ev.EventID, "device_event:") {
+			continue
+		}
+		summaries = append(summaries, ev.Kind+": "+ev.Summary)
+		switch {
+		case ev.Kind == "display_name" && rename == nil:
+			rename = ev.Details
+		case ev.Kind == "metadata" && ownerChange == nil:
+			ownerChange = ev.Details
+		}
+	}
+	want := []string{
+		"metadata: metadata updated: owner",
+		"metadata: metadata updated: owner",
+		"display_name: renamed from sw-1 to core-switch",
+		"snmp: snmp identity observed",
+		"display_name: named sw-1",
+	}
+	if strings.Join(summaries, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected device events:\n got %v\nwant %v", summaries, want)
+	}
+	if rename["actor_type"] != sqlcgen.DeviceEventActorUser || rename["actor"] != "bob" || rename["reason"] != "ticket 42" {
+		t.Fatalf("unexpected rename attribution %v", rename)
+	}
+	if before := rename["before"].(map[string]any); before["display_name"] != "sw-1" {
+		t.Fatalf("expected the previous name in before, got %v", rename["before"])
+	}
+	if before := ownerChange["before"].(map[string]any); before["owner"] != "alice" || ownerChange["after"].(map[string]any)["owner"] != "carol" {
+		t.Fatalf("expected owner alice -> carol, got %v", ownerChange)
+	}
+}
+
+func TestHandler_Postgres_DeviceAsOf(t *testing.T) {
+	adminURL := requireTestDatabaseURL(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	dbName := newTestDatabaseName()
+	testDBURL := mustDeriveDatabaseURL(t, adminURL, dbName)
+
+	if err := createDatabase(ctx, adminURL, dbName); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = dropDatabase(context.Background(), adminURL, dbName)
+	})
+
+	conn, err := pgx.Connect(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close(context.Background()) })
+	if err := applyMigrations(ctx, conn, migrationsDir(t)); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+
+	pool, err := db.Open(ctx, testDBURL)
+	if err != nil {
+		t.Fatalf("open db pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	q := pool.Queries()
+
+	preCreation := url.QueryEscape(time.Now().Add(-time.Minute).UTC().Format(time.RFC3339Nano))
+	device, err := q.CreateDevice(ctx, nil)
+	if err != nil {
+		t.Fatalf("create device: %v", err)
+	}
+	router := NewHandler(NewLogger("error"), pool).Router()
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	for _, step := range []struct{ method, path, body string }{
+		{http.MethodPut, "/api/v1/devices/" + device.ID, `{"display_name":"printer-1","metadata":{"location":"lab"}}`},
+		{http.MethodPut, "/api/v1/devices/" + device.ID + "/tags", `{"tags":["printer"],"actor":"alice"}`},
+	} {
+		if rr := serve(step.method, step.path, step.body); rr.Code != http.StatusOK {
+			t.Fatalf("%s %s expected 200, got %d: %s", step.method, step.path, rr.Code, rr.Body.String())
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	before := time.Now().UTC()
+	time.Sleep(20 * time.Millisecond)
+	for _, step := range []struct{ method, path, body string }{
+		{http.MethodPut, "/api/v1/devices/" + device.ID, `{"display_name":"scanner-1","metadata":{"location":"office"}}`},
+		{http.MethodPut, "/api/v1/devices/" + device.ID + "/tags", `{"tags":["scanner"]}`},
+	} {
+		if rr := serve(step.method, step.path, step.body); rr.Code != http.StatusOK {
+			t.Fatalf("%s %s expected 200, got %d: %s", step.method, step.path, rr.Code, rr.Body.String())
+		}
+	}
+
+	asOf := url.QueryEscape(before.Format(time.RFC3339Nano))
+	rr := serve(http.MethodGet, "/api/v1/devices/"+device.ID+"?as_of="+asOf, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("as_of get expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var past map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&past); err != nil {
+		t.Fatalf("decode device: %v", err)
+	}
+	if past["display_name"] != "printer-1" || past["metadata"].(map[string]any)["location"] != "lab" {
+		t.Fatalf("expected the earlier name and location, got %v", past)
+	}
+	if tags, _ := past["tags"].([]any); len(tags) != 1 || tags[0] != "printer" {
+		t.Fatalf("expected the earlier tags, got %v", past["tags"])
+	}
+
+	rr = serve(http.MethodGet, "/api/v1/devices?q=printer&as_of="+asOf, "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), device.ID) {
+		t.Fatalf("expected the device to match its earlier name, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = serve(http.MethodGet, "/api/v1/devices?q=printer", "")
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), device.ID) {
+		t.Fatalf("expected the live listing to use the new name, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := serve(http.MethodGet, "/api/v1/devices/"+device.ID+"?as_of="+preCreation, ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 before the device existed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodGet, "/api/v1/devices/"+device.ID+"/facts?as_of="+asOf, ""); rr.Code != http.StatusOK {
+		t.Fatalf("as_of facts expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// 192.0.2.50 moves from the laptop to the phone an hour ago; the laptop's 192.0.2.51 went stale 90 minutes ago.
+	var laptopID, phoneID string
+	if err := conn.QueryRow(ctx, `INSERT INTO devices (display_name, created_at) VALUES ('laptop', now() - interval '4 hours') RETURNING id::text`).Scan(&laptopID); err != nil {
+		t.Fatalf("insert device: %v", err)
+	}
+	if err := conn.QueryRow(ctx, `INSERT INTO devices (display_name, created_at) VALUES ('phone', now() - interval '4 hours') RETURNING id::text`).Scan(&phoneID); err != nil {
+		t.Fatalf("insert device: %v", err)
+	}
+	if _, err := conn.Exec(ctx, `INSERT INTO ip_addresses (device_id, ip, created_at, last_seen_at, stale_at) VALUES
+		($1::uuid, '192.0.2.50', now() - interval '3 hours', now() - interval '2 hours', NULL),
+		($1::uuid, '192.0.2.51', now() - interval '3 hours', now() - interval '3 hours', now() - interval '90 minutes'),
+		($2::uuid, '192.0.2.50', now() - interval '1 hour', now(), NULL)`, laptopID, phoneID); err != nil {
+		t.Fatalf("seed ips: %v", err)
+	}
+	heldIPs := func(deviceID string, ago time.Duration) []string {
+		t.Helper()
+		ips, err := q.ListDeviceIPsAsOf(ctx, deviceID, time.Now().Add(-ago))
+		if err != nil {
+			t.Fatalf("list ips as of: %v", err)
+		}
+		out := make([]string, 0, len(ips))
+		for _, ip := range ips {
+			out = append(out, ip.IP)
+		}
+		sort.Strings(out)
+		return out
+	}
+	if got := heldIPs(laptopID, 150*time.Minute); strings.Join(got, ",") != "192.0.2.50,192.0.2.51" {
+		t.Fatalf("expected the laptop to hold both IPs before the move, got %v", got)
+	}
+	if got := heldIPs(phoneID, 150*time.Minute); len(got) != 0 {
+		t.Fatalf("expected the phone to hold nothing before the move, got %v", got)
+	}
+	if got := heldIPs(laptopID, 30*time.Minute); len(got) != 0 {
+		t.Fatalf("expected the laptop to hold nothing after the move and the stale mark, got %v", got)
+	}
+	if got := heldIPs(phoneID, 30*time.Minute); strings.Join(got, ",") != "192.0.2.50" {
+		t.Fatalf("expected the phone to hold the moved IP, got %v", got)
+	}
+
+	// Re-inferring a link with a new confidence, or recreating it under a new id, is not a topology change.
+	var linkID string
+	if err := conn.QueryRow(ctx, `INSERT INTO links (link_key, a_device_id, b_device_id, source, confidence) VALUES ('inferred:laptop-phone', $1::uuid, $2::uuid, 'inferred', 40) RETURNING id::text`, laptopID, phoneID).Scan(&linkID); err != nil {
+		t.Fatalf("insert link: %v", err)
+	}
+	snapshot := func() int64 {
+		t.Helper()
+		n, err := q.RecordDeviceStateSnapshots(ctx, sqlcgen.RecordDeviceStateSnapshotsParams{DeviceIDs: []string{laptopID, phoneID}})
+		if err != nil {
+			t.Fatalf("record snapshots: %v", err)
+		}
+		return n
+	}
+	if n := snapshot(); n != 2 {
+		t.Fatalf("expected one links snapshot per device, got %d", n)
+	}
+	if _, err := conn.Exec(ctx, `UPDATE links SET confidence = 75 WHERE link_key = 'inferred:laptop-phone'`); err != nil {
+		t.Fatalf("update confidence: %v", err)
+	}
+	if n := snapshot(); n != 0 {
+		t.Fatalf("expected a confidence change to record nothing, got %d", n)
+	}
+	if err := conn.QueryRow(ctx, `WITH gone AS (DELETE FROM links WHERE link_key = 'inferred:laptop-phone' RETURNING a_device_id, b_device_id)
+		INSERT INTO links (link_key, a_device_id, b_device_id, source, confidence) SELECT 'inferred:laptop-phone', a_device_id, b_device_id, 'inferred', 60 FROM gone RETURNING id::text`).Scan(&linkID); err != nil {
+		t.Fatalf("recreate link: %v", err)
+	}
+	if n := snapshot(); n != 0 {
+		t.Fatalf("expected a recreated link to record nothing, got %d", n)
+	}
+	links, err := q.ListDeviceLinksAsOf(ctx, laptopID, time.Now())
+	if err != nil {
+		t.Fatalf("list links as of: %v", err)
+	}
+	if len(links) != 1 || links[0].ID != linkID || links[0].Confidence == nil || *links[0].Confidence != 60 {
+		t.Fatalf("expected the live link id and confidence, got %+v", links)
+	}
+
+	// The feed reports the link once, from the snapshot, and again when it goes away.
+	if _, err := conn.Exec(ctx, `DELETE FROM links WHERE link_key = 'inferred:laptop-phone'`); err != nil {
+		t.Fatalf("delete link: %v", err)
+	}
+	if n := snapshot(); n != 2 {
+		t.Fatalf("expected the removed link to be recorded for both devices, got %d", n)
+	}
+	rr = serve(http.MethodGet, "/api/v1/devices/"+laptopID+"/history", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("history expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var feed deviceChangeEventsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&feed); err != nil {
+		t.Fatalf("decode history: %v", err)
+	}
+	var linkEvents []string
+	for _, ev := range feed.Events {
+		if ev.Kind == "link" {
+			linkEvents = append(linkEvents, ev.Summary)
+		}
+	}
+	if want := []string{"links: -link to " + phoneID, "links: +link to " + phoneID}; strings.Join(linkEvents, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected %v, got %v", want, linkEvents)
+	}
+}
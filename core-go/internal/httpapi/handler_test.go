@@ -25,8 +25,7 @@ type fakeDeviceQueries struct {
 	upsertFn             func(ctx context.Context, arg sqlcgen.UpsertDeviceMetadataParams) (sqlcgen.DeviceMetadata, error)
 	listTagsFn           func(ctx context.Context, deviceID string) ([]sqlcgen.DeviceTag, error)
 	listEffectiveTagsFn  func(ctx context.Context, deviceID string) ([]string, error)
-	deleteTagsBySourceFn func(ctx context.Context, arg sqlcgen.DeleteDeviceTagsBySourceParams) error
-	upsertTagFn          func(ctx context.Context, arg sqlcgen.UpsertDeviceTagParams) error
+	replaceManualTagsFn  func(ctx context.Context, arg sqlcgen.ReplaceManualDeviceTagsParams) error
 	listNameCandidatesFn func(ctx context.Context, deviceID string) ([]sqlcgen.DeviceNameCandidate, error)
 	listIPsFn            func(ctx context.Context, deviceID string) ([]sqlcgen.DeviceIP, error)
 	listMACsFn           func(ctx context.Context, deviceID string) ([]sqlcgen.DeviceMAC, error)
@@ -34,6 +33,8 @@ type fakeDeviceQueries struct {
 	listServicesFn       func(ctx context.Context, deviceID string) ([]sqlcgen.DeviceService, error)
 	getSNMPFn            func(ctx context.Context, deviceID string) (sqlcgen.DeviceSNMP, error)
 	listLinksFn          func(ctx context.Context, deviceID string) ([]sqlcgen.DeviceLink, error)
+	listSSHHostKeysFn    func(ctx context.Context, deviceID string) ([]sqlcgen.SSHHostKey, error)
+	listOSGuessesFn      func(ctx context.Context, deviceID string) ([]sqlcgen.DeviceOSGuess, error)
 	listChangeEventsFn   func(ctx context.Context, arg sqlcgen.ListDeviceChangeEventsParams) ([]sqlcgen.DeviceChangeEvent, error)
 	listHistoryFn        func(ctx context.Context, arg sqlcgen.ListDeviceChangeEventsForDeviceParams) ([]sqlcgen.DeviceChangeEvent, error)
 }
@@ -105,18 +106,11 @@ func (f fakeDeviceQueries) ListDeviceEffectiveTags(ctx context.Context, deviceID
 	return f.listEffectiveTagsFn(ctx, deviceID)
 }
 
-func (f fakeDeviceQueries) DeleteDeviceTagsBySource(ctx context.Context, arg sqlcgen.DeleteDeviceTagsBySourceParams) error {
-	if f.deleteTagsBySourceFn == nil {
+func (f fakeDeviceQueries) ReplaceManualDeviceTags(ctx context.Context, arg sqlcgen.ReplaceManualDeviceTagsParams) error {
+	if f.replaceManualTagsFn == nil {
 		return nil
 	}
-	return f.deleteTagsBySourceFn(ctx, arg)
-}
-
-func (f fakeDeviceQueries) UpsertDeviceTag(ctx context.Context, arg sqlcgen.UpsertDeviceTagParams) error {
-	if f.upsertTagFn == nil {
-		return nil
-	}
-	return f.upsertTagFn(ctx, arg)
+	return f.replaceManualTagsFn(ctx, arg)
 }
 
 func (f fakeDeviceQueries) ListDeviceNameCandidates(ctx context.Context, deviceID string) ([]sqlcgen.DeviceNameCandidate, error) {
@@ -168,6 +162,20 @@ func (f fakeDeviceQueries) ListDeviceLinks(ctx context.Context, deviceID string)
 	return f.listLinksFn(ctx, deviceID)
 }
 
+func (f fakeDeviceQueries) ListDeviceSSHHostKeys(ctx context.Context, deviceID string) ([]sqlcgen.SSHHostKey, error) {
+	if f.listSSHHostKeysFn == nil {
+		return nil, nil
+	}
+	return f.listSSHHostKeysFn(ctx, deviceID)
+}
+
+func (f fakeDeviceQueries) ListDeviceOSGuesses(ctx context.Context, deviceID string) ([]sqlcgen.DeviceOSGuess, error) {
+	if f.listOSGuessesFn == nil {
+		return nil, nil
+	}
+	return f.listOSGuessesFn(ctx, deviceID)
+}
+
 func (f fakeDeviceQueries) ListDeviceChangeEvents(ctx context.Context, arg sqlcgen.ListDeviceChangeEventsParams) ([]sqlcgen.DeviceChangeEvent, error) {
 	if f.listChangeEventsFn == nil {
 		return nil, nil
@@ -351,6 +359,91 @@ func TestDevices_Get_NotFound(t *testing.T) {
 	}
 }
 
+func TestDevices_Facts_IncludesSSHHostKeys(t *testing.T) {
+	port := int32(22)
+	banner := "SSH-2.0-OpenSSH_9.6"
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueries{
+		getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+			return sqlcgen.Device{ID: id}, nil
+		},
+		listSSHHostKeysFn: func(ctx context.Context, deviceID string) ([]sqlcgen.SSHHostKey, error) {
+			return []sqlcgen.SSHHostKey{
+				{KeyType: "ssh-ed25519", FingerprintSHA256: "SHA256:abc", PublicKey: "AAAA", Port: &port, Banner: &banner, SharedWithDeviceIDs: []string{"00000000-0000-0000-0000-000000000009"}},
+				{KeyType: "ssh-rsa", FingerprintSHA256: "SHA256:def", PublicKey: "BBBB"},
+			}, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/devices/00000000-0000-0000-0000-000000000002/facts", nil)
+	h.Router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	body := decodeBody(t, rr)
+	keys, ok := body["ssh_host_keys"].([]any)
+	if !ok || len(keys) != 2 {
+		t.Fatalf("expected 2 ssh host keys, got %v", body["ssh_host_keys"])
+	}
+	first := keys[0].(map[string]any)
+	if first["key_type"] != "ssh-ed25519" || first["banner"] != banner {
+		t.Fatalf("unexpected key %v", first)
+	}
+	if shared, ok := first["shared_with_device_ids"].([]any); !ok || len(shared) != 1 {
+		t.Fatalf("expected shared device id, got %v", first["shared_with_device_ids"])
+	}
+	if shared, ok := keys[1].(map[string]any)["shared_with_device_ids"].([]any); !ok || len(shared) != 0 {
+		t.Fatalf("expected empty shared list, got %v", shared)
+	}
+}
+
+func TestDevices_Facts_IncludesServiceVersionsAndOSGuesses(t *testing.T) {
+	proto := "tcp"
+	port := int32(22)
+	product := "OpenSSH"
+	version := "9.6p1"
+	family := "Linux"
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueries{
+		getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+			return sqlcgen.Device{ID: id}, nil
+		},
+		listServicesFn: func(ctx context.Context, deviceID string) ([]sqlcgen.DeviceService, error) {
+			return []sqlcgen.DeviceService{{Protocol: &proto, Port: &port, Product: &product, Version: &version, CPE: []string{"cpe:/a:openbsd:openssh:9.6p1"}}}, nil
+		},
+		listOSGuessesFn: func(ctx context.Context, deviceID string) ([]sqlcgen.DeviceOSGuess, error) {
+			return []sqlcgen.DeviceOSGuess{{Source: "nmap", Rank: 1, Name: "Linux 5.0 - 5.14", Accuracy: 98, OSFamily: &family}}, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/devices/00000000-0000-0000-0000-000000000002/facts", nil)
+	h.Router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	body := decodeBody(t, rr)
+	services := body["services"].([]any)
+	svc := services[0].(map[string]any)
+	if svc["product"] != product || svc["version"] != version || len(svc["cpe"].([]any)) != 1 {
+		t.Fatalf("unexpected service %v", svc)
+	}
+	guesses, ok := body["os_guesses"].([]any)
+	if !ok || len(guesses) != 1 {
+		t.Fatalf("expected 1 os guess, got %v", body["os_guesses"])
+	}
+	guess := guesses[0].(map[string]any)
+	if guess["os_family"] != family || guess["accuracy"] != float64(98) {
+		t.Fatalf("unexpected os guess %v", guess)
+	}
+	if cpe, ok := guess["cpe"].([]any); !ok || len(cpe) != 0 {
+		t.Fatalf("expected empty cpe array, got %v", guess["cpe"])
+	}
+}
+
 func TestDevices_Get_InvalidID(t *testing.T) {
 	invalidUUIDErr := &pgconn.PgError{Code: "22P02", Message: "invalid input syntax for type uuid"}
 
@@ -460,6 +553,39 @@ func TestDevices_Create_WithMetadata(t *testing.T) {
 	}
 }
 
+func TestDevices_Update_RecordsActor(t *testing.T) {
+	var updates []sqlcgen.UpdateDeviceParams
+	var metadata []sqlcgen.UpsertDeviceMetadataParams
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueries{
+		updateFn: func(ctx context.Context, arg sqlcgen.UpdateDeviceParams) (sqlcgen.Device, error) {
+			updates = append(updates, arg)
+			return sqlcgen.Device{ID: arg.ID, DisplayName: arg.DisplayName}, nil
+		},
+		upsertFn: func(ctx context.Context, arg sqlcgen.UpsertDeviceMetadataParams) (sqlcgen.DeviceMetadata, error) {
+			metadata = append(metadata, arg)
+			return sqlcgen.DeviceMetadata{DeviceID: arg.DeviceID, Owner: arg.Owner}, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/devices/00000000-0000-0000-0000-000000000006", strings.NewReader(`{"display_name":"core","metadata":{"owner":"ops"},"actor":" alice ","reason":"  "}`))
+	req.Header.Set("Content-Type", "application/json")
+	h.Router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(updates) != 1 || len(metadata) != 1 {
+		t.Fatalf("expected one update and one metadata upsert, got %d and %d", len(updates), len(metadata))
+	}
+	for _, actor := range []sqlcgen.DeviceEventActor{updates[0].Actor, metadata[0].Actor} {
+		if actor.Type != sqlcgen.DeviceEventActorUser || actor.Name == nil || *actor.Name != "alice" || actor.Reason != nil || actor.RunID != nil {
+			t.Fatalf("expected the change attributed to user alice without a reason, got %+v", actor)
+		}
+	}
+}
+
 func TestDevices_Create_UsesUpstreamRequestID(t *testing.T) {
 	h := NewHandler(NewLogger("debug"), nil)
 	h.devices = fakeDeviceQueries{
@@ -23,14 +23,15 @@ const (
 )
 
 type mapProjection struct {
-	Layer      string        `json:"layer"`
-	Focus      *mapFocus     `json:"focus,omitempty"`
-	Guidance   *string       `json:"guidance,omitempty"`
-	Regions    []mapRegion   `json:"regions"`
-	Nodes      []mapNode     `json:"nodes"`
-	Edges      []mapEdge     `json:"edges"`
-	Inspector  *mapInspector `json:"inspector,omitempty"`
-	Truncation mapTruncation `json:"truncation"`
+	Layer      string         `json:"layer"`
+	Focus      *mapFocus      `json:"focus,omitempty"`
+	Guidance   *string        `json:"guidance,omitempty"`
+	Regions    []mapRegion    `json:"regions"`
+	Nodes      []mapNode      `json:"nodes"`
+	Edges      []mapEdge      `json:"edges"`
+	Inspector  *mapInspector  `json:"inspector,omitempty"`
+	Truncation mapTruncation  `json:"truncation"`
+	Meta       map[string]any `json:"meta,omitempty"`
 }
 
 type mapFocus struct {
@@ -232,6 +233,7 @@ func (h *Handler) handleGetMapProjection(w http.ResponseWriter, r *http.Request)
 				h.writeError(w, http.StatusInternalServerError, "db_error", "failed to build l3 projection", nil)
 				return
 			}
+			deviceIPs = currentDeviceIPs(deviceIPs)
 
 			if len(deviceIPs) > 0 {
 				ips := append([]sqlcgen.DeviceIP(nil), deviceIPs...)
@@ -761,6 +763,12 @@ func (h *Handler) handleGetMapProjection(w http.ResponseWriter, r *http.Request)
 		resp.Truncation.Edges.Returned = len(resp.Edges)
 		resp.Truncation.Edges.Truncated = edgesTruncated
 
+		if err := h.attachRoutingAdjacencies(r.Context(), &resp, focusID); err != nil {
+			h.log.Error().Err(err).Str("device_id", focusID).Msg("list routing adjacencies for map projection failed")
+			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to build l3 projection", nil)
+			return
+		}
+
 		if resp.Inspector != nil {
 			peerCount := len(resp.Nodes)
 			if peerCount > 0 {
@@ -856,6 +864,12 @@ func (h *Handler) handleGetMapProjection(w http.ResponseWriter, r *http.Request)
 				resp.Truncation.Nodes.Total = &totalNodes
 			}
 
+			if err := h.attachRoutingAdjacencies(ctx, &resp, ""); err != nil {
+				h.log.Error().Err(err).Str("subnet", focusID).Msg("list routing adjacencies for map projection failed")
+				h.writeError(w, http.StatusInternalServerError, "db_error", "failed to build l3 projection", nil)
+				return
+			}
+
 			if resp.Inspector != nil {
 				resp.Inspector.Status = append(resp.Inspector.Status, mapInspectorField{Label: "Devices", Value: strconv.Itoa(len(resp.Nodes))})
 
@@ -876,6 +890,12 @@ func (h *Handler) handleGetMapProjection(w http.ResponseWriter, r *http.Request)
 				}
 			}
 
+			if err := h.attachTraceroutePaths(ctx, &resp, focusID); err != nil {
+				h.log.Error().Err(err).Str("subnet", focusID).Msg("list traceroute paths for map projection failed")
+				h.writeError(w, http.StatusInternalServerError, "db_error", "failed to build l3 projection", nil)
+				return
+			}
+
 			if len(resp.Nodes) == 0 {
 				guidance := "No devices observed in this subnet yet. Run discovery or add IP facts to populate membership."
 				resp.Guidance = &guidance
@@ -968,6 +988,12 @@ func (h *Handler) handleGetMapProjection(w http.ResponseWriter, r *http.Request)
 			resp.Truncation.Nodes.Total = &totalNodes
 		}
 
+		if err := h.attachSTPRoots(r.Context(), &resp); err != nil {
+			h.log.Error().Err(err).Str("device_id", focusID).Msg("list stp roots for map projection failed")
+			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to build l2 projection", nil)
+			return
+		}
+
 		if resp.Inspector != nil {
 			peerCount := len(resp.Nodes)
 			if peerCount > 0 {
@@ -1069,6 +1095,12 @@ func (h *Handler) handleGetMapProjection(w http.ResponseWriter, r *http.Request)
 				resp.Truncation.Nodes.Total = &totalNodes
 			}
 
+			if err := h.attachSTPRoots(r.Context(), &resp); err != nil {
+				h.log.Error().Err(err).Str("vlan", focusID).Msg("list stp roots for map projection failed")
+				h.writeError(w, http.StatusInternalServerError, "db_error", "failed to build l2 projection", nil)
+				return
+			}
+
 			if resp.Inspector != nil {
 				resp.Inspector.Status = append(resp.Inspector.Status, mapInspectorField{Label: "Devices", Value: strconv.Itoa(len(resp.Nodes))})
 
@@ -1116,8 +1148,8 @@ func (h *Handler) handleGetMapProjection(w http.ResponseWriter, r *http.Request)
 		peerIDs := make([]string, 0, len(linksIncluded))
 		peerLabels := make(map[string]*string, len(linksIncluded))
 		for _, link := range linksIncluded {
-			peerIDs = append(peerIDs, link.PeerDeviceID)
 			if _, exists := peerLabels[link.PeerDeviceID]; !exists {
+				peerIDs = append(peerIDs, link.PeerDeviceID)
 				peerLabels[link.PeerDeviceID] = link.PeerDisplayName
 			}
 		}
@@ -1151,27 +1183,14 @@ func (h *Handler) handleGetMapProjection(w http.ResponseWriter, r *http.Request)
 			resp.Truncation.Nodes.Total = &totalNodes
 		}
 
-		resp.Edges = make([]mapEdge, 0, len(linksIncluded))
-		for _, link := range linksIncluded {
-			linkType := ""
-			if link.LinkType != nil {
-				linkType = strings.TrimSpace(*link.LinkType)
-			}
-			meta := map[string]any{
-				"link_key": link.LinkKey,
-				"source":   link.Source,
-			}
-			if linkType != "" {
-				meta["link_type"] = linkType
-			}
-			resp.Edges = append(resp.Edges, mapEdge{
-				ID:   "link:" + link.LinkID,
-				Kind: "link",
-				From: focusID,
-				To:   link.PeerDeviceID,
-				Meta: meta,
-			})
+		if err := h.attachSTPRoots(r.Context(), &resp); err != nil {
+			h.log.Error().Err(err).Str("device_id", focusID).Msg("list stp roots for map projection failed")
+			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to build physical projection", nil)
+			return
 		}
+
+		// Aggregate members collapse into one logical edge; STP state rides along in edge meta.
+		resp.Edges = buildPhysicalLinkEdges(focusID, linksIncluded)
 		resp.Truncation.Edges.Returned = len(resp.Edges)
 		resp.Truncation.Edges.Truncated = linksTruncated
 		if linksTruncated {
@@ -1206,6 +1225,12 @@ func (h *Handler) handleGetMapProjection(w http.ResponseWriter, r *http.Request)
 			}
 		}
 
+		if err := h.attachPoE(r.Context(), &resp, focusID); err != nil {
+			h.log.Error().Err(err).Str("device_id", focusID).Msg("list poe power paths for map projection failed")
+			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to build physical projection", nil)
+			return
+		}
+
 		if len(linksIncluded) == 0 {
 			guidance := "No physical links known yet. Add manual links or enable LLDP/CDP enrichment to render adjacency."
 			resp.Guidance = &guidance
@@ -1366,6 +1391,12 @@ func (h *Handler) handleGetMapProjection(w http.ResponseWriter, r *http.Request)
 		resp.Nodes = []mapNode{*focusNode}
 		resp.Truncation.Nodes.Returned = len(resp.Nodes)
 	}
+	if at, ok := h.asOfTime(); ok {
+		if resp.Meta == nil {
+			resp.Meta = map[string]any{}
+		}
+		resp.Meta["as_of"] = at
+	}
 
 	sortMapProjection(&resp)
 	h.writeJSON(w, http.StatusOK, resp)
@@ -1507,3 +1538,14 @@ func deriveL3SubnetID(ip string) (string, bool) {
 	prefix := netip.PrefixFrom(addr, bits).Masked()
 	return prefix.String(), true
 }
+
+// currentDeviceIPs drops stale addresses so a device is only placed in subnets it still uses.
+func currentDeviceIPs(rows []sqlcgen.DeviceIP) []sqlcgen.DeviceIP {
+	out := rows[:0:0]
+	for _, row := range rows {
+		if row.StaleAt == nil {
+			out = append(out, row)
+		}
+	}
+	return out
+}
@@ -0,0 +1,202 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// linkSTPState picks the spanning tree state to show for a link: a blocked end wins, otherwise the local
+// end's state (falling back to the peer's).
+func linkSTPState(local, peer *string) string {
+	l, p := "", ""
+	if local != nil {
+		l = strings.TrimSpace(*local)
+	}
+	if peer != nil {
+		p = strings.TrimSpace(*peer)
+	}
+	switch {
+	case isSTPBlocked(l):
+		return l
+	case isSTPBlocked(p):
+		return p
+	case l != "":
+		return l
+	default:
+		return p
+	}
+}
+
+// isSTPBlocked reports whether a port in this state discards traffic for loop prevention.
+func isSTPBlocked(state string) bool {
+	return state == "blocking" || state == "broken"
+}
+
+// buildPhysicalLinkEdges turns link rows into physical edges. Links whose local (or peer) port is a member
+// of the same link aggregate collapse into one logical edge that lists the members; spanning tree state
+// is attached as stp_state / stp_blocked.
+func buildPhysicalLinkEdges(focusID string, links []sqlcgen.MapDeviceLinkPeer) []mapEdge {
+	type group struct {
+		key     string
+		members []sqlcgen.MapDeviceLinkPeer
+	}
+	var groups []*group
+	byKey := map[string]*group{}
+	for _, link := range links {
+		key := "link:" + link.LinkID
+		switch {
+		case link.LocalAggregateID != nil && *link.LocalAggregateID != "":
+			key = "lag:" + *link.LocalAggregateID
+		case link.PeerAggregateID != nil && *link.PeerAggregateID != "":
+			key = "lag:" + *link.PeerAggregateID
+		}
+		g := byKey[key]
+		if g == nil {
+			g = &group{key: key}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.members = append(g.members, link)
+	}
+
+	edges := make([]mapEdge, 0, len(groups))
+	for _, g := range groups {
+		first := g.members[0]
+		meta := map[string]any{
+			"link_key": first.LinkKey,
+			"source":   first.Source,
+		}
+		if first.LinkType != nil && strings.TrimSpace(*first.LinkType) != "" {
+			meta["link_type"] = strings.TrimSpace(*first.LinkType)
+		}
+		edge := mapEdge{
+			ID:   "link:" + first.LinkID,
+			Kind: "link",
+			From: focusID,
+			To:   first.PeerDeviceID,
+			Meta: meta,
+		}
+
+		blocked := false
+		state := ""
+		if strings.HasPrefix(g.key, "lag:") {
+			edge.ID = g.key
+			aggregate := map[string]any{"member_count": len(g.members)}
+			if first.LocalAggregateID != nil {
+				aggregate["interface_id"] = *first.LocalAggregateID
+			}
+			if first.LocalAggregateName != nil && strings.TrimSpace(*first.LocalAggregateName) != "" {
+				name := strings.TrimSpace(*first.LocalAggregateName)
+				aggregate["name"] = name
+				edge.Label = &name
+			}
+			if first.PeerAggregateID != nil {
+				aggregate["peer_interface_id"] = *first.PeerAggregateID
+			}
+			members := make([]map[string]any, 0, len(g.members))
+			for _, m := range g.members {
+				member := map[string]any{
+					"link_id":  m.LinkID,
+					"link_key": m.LinkKey,
+					"source":   m.Source,
+				}
+				if m.LocalInterfaceID != nil {
+					member["local_interface_id"] = *m.LocalInterfaceID
+				}
+				if m.LocalInterfaceName != nil {
+					member["local_interface_name"] = *m.LocalInterfaceName
+				}
+				if m.PeerInterfaceID != nil {
+					member["peer_interface_id"] = *m.PeerInterfaceID
+				}
+				if s := linkSTPState(m.LocalSTPState, m.PeerSTPState); s != "" {
+					member["stp_state"] = s
+					if isSTPBlocked(s) {
+						blocked = true
+					}
+					if state == "" || isSTPBlocked(s) {
+						state = s
+					}
+				}
+				members = append(members, member)
+			}
+			meta["aggregate"] = aggregate
+			meta["members"] = members
+		} else {
+			state = linkSTPState(first.LocalSTPState, first.PeerSTPState)
+			blocked = isSTPBlocked(state)
+		}
+		if state != "" {
+			meta["stp_state"] = state
+			meta["stp_blocked"] = blocked
+		}
+		edges = append(edges, edge)
+	}
+	return edges
+}
+
+// attachSTPRoots adds the spanning tree root bridge(s) reported by the projected devices to the projection
+// meta (`stp_roots`) and flags root nodes with meta.stp_root. It is a no-op when the query is unavailable.
+func (h *Handler) attachSTPRoots(ctx context.Context, resp *mapProjection) error {
+	lister, ok := h.devices.(interface {
+		ListSTPRoots(ctx context.Context, deviceIDs []string) ([]sqlcgen.MapSTPRoot, error)
+	})
+	if !ok || len(resp.Nodes) == 0 {
+		return nil
+	}
+	deviceIDs := make([]string, 0, len(resp.Nodes))
+	for _, n := range resp.Nodes {
+		if n.Kind == "device" {
+			deviceIDs = append(deviceIDs, n.ID)
+		}
+	}
+	rows, err := lister.ListSTPRoots(ctx, deviceIDs)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	rootInstances := map[string][]int{}
+	roots := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		root := map[string]any{
+			"instance":     row.Instance,
+			"root_address": row.RootAddress,
+			"reported_by":  row.ReportedBy,
+		}
+		if row.RootPriority != nil {
+			root["root_priority"] = *row.RootPriority
+			root["root_bridge_id"] = fmt.Sprintf("%d.%s", *row.RootPriority, row.RootAddress)
+		}
+		if row.RootDeviceID != nil {
+			root["root_device_id"] = *row.RootDeviceID
+			rootInstances[*row.RootDeviceID] = append(rootInstances[*row.RootDeviceID], int(row.Instance))
+		}
+		if row.RootDisplayName != nil && strings.TrimSpace(*row.RootDisplayName) != "" {
+			root["root_label"] = strings.TrimSpace(*row.RootDisplayName)
+		}
+		roots = append(roots, root)
+	}
+	if resp.Meta == nil {
+		resp.Meta = map[string]any{}
+	}
+	resp.Meta["stp_roots"] = roots
+
+	for i := range resp.Nodes {
+		instances, ok := rootInstances[resp.Nodes[i].ID]
+		if !ok {
+			continue
+		}
+		if resp.Nodes[i].Meta == nil {
+			resp.Nodes[i].Meta = map[string]any{}
+		}
+		resp.Nodes[i].Meta["stp_root"] = true
+		resp.Nodes[i].Meta["stp_root_instances"] = instances
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// attachPoE adds PoE power delivery to a physical device-focus projection: powered peers get meta.poe
+// (the switch port powering them), and the inspector lists what the focus device powers and what powers
+// it. It is a no-op when the queries are unavailable.
+func (h *Handler) attachPoE(ctx context.Context, resp *mapProjection, focusDeviceID string) error {
+	lister, ok := h.devices.(interface {
+		ListPoweredDevices(ctx context.Context, pseDeviceID string) ([]sqlcgen.PoEPowerPath, error)
+		ListPowerSources(ctx context.Context, poweredDeviceID string) ([]sqlcgen.PoEPowerPath, error)
+	})
+	if !ok || focusDeviceID == "" {
+		return nil
+	}
+	powered, err := lister.ListPoweredDevices(ctx, focusDeviceID)
+	if err != nil {
+		return err
+	}
+	sources, err := lister.ListPowerSources(ctx, focusDeviceID)
+	if err != nil {
+		return err
+	}
+	if len(powered) == 0 && len(sources) == 0 {
+		return nil
+	}
+
+	nodeIndex := make(map[string]int, len(resp.Nodes))
+	for i, n := range resp.Nodes {
+		nodeIndex[n.ID] = i
+	}
+	setPoEMeta := func(nodeID string, row sqlcgen.PoEPowerPath) {
+		i, ok := nodeIndex[nodeID]
+		if !ok {
+			return
+		}
+		if resp.Nodes[i].Meta == nil {
+			resp.Nodes[i].Meta = map[string]any{}
+		}
+		resp.Nodes[i].Meta["poe"] = poePathMeta(row)
+	}
+
+	totalMW, metered := 0, false
+	for _, row := range powered {
+		setPoEMeta(row.PoweredDeviceID, row)
+		if row.PowerMW != nil {
+			totalMW += int(*row.PowerMW)
+			metered = true
+		}
+	}
+	for _, row := range sources {
+		setPoEMeta(focusDeviceID, row)
+	}
+
+	if resp.Inspector == nil {
+		return nil
+	}
+	if len(powered) > 0 {
+		resp.Inspector.Status = append(resp.Inspector.Status, mapInspectorField{Label: "PoE powered devices", Value: strconv.Itoa(len(powered))})
+		if metered {
+			resp.Inspector.Status = append(resp.Inspector.Status, mapInspectorField{Label: "PoE power drawn", Value: formatWatts(totalMW)})
+		}
+	}
+	for _, row := range sources {
+		value := poeDeviceLabel(row.PSEDeviceID, row.PSEDisplayName)
+		if row.InterfaceName != nil && strings.TrimSpace(*row.InterfaceName) != "" {
+			value += " (" + strings.TrimSpace(*row.InterfaceName) + ")"
+		}
+		resp.Inspector.Status = append(resp.Inspector.Status, mapInspectorField{Label: "Powered by", Value: value})
+		resp.Inspector.Relationships = append(resp.Inspector.Relationships, mapInspectorRelation{
+			Label:     "Open power source " + poeDeviceLabel(row.PSEDeviceID, row.PSEDisplayName),
+			Layer:     "physical",
+			FocusType: "device",
+			FocusID:   row.PSEDeviceID,
+		})
+	}
+	for _, row := range powered {
+		resp.Inspector.Relationships = append(resp.Inspector.Relationships, mapInspectorRelation{
+			Label:     "Open powered device " + poeDeviceLabel(row.PoweredDeviceID, row.PoweredDisplayName),
+			Layer:     "physical",
+			FocusType: "device",
+			FocusID:   row.PoweredDeviceID,
+		})
+	}
+	return nil
+}
+
+func poePathMeta(row sqlcgen.PoEPowerPath) map[string]any {
+	meta := map[string]any{
+		"pse_device_id":    row.PSEDeviceID,
+		"interface_id":     row.InterfaceID,
+		"detection_status": row.DetectionStatus,
+	}
+	if row.InterfaceName != nil {
+		meta["interface_name"] = *row.InterfaceName
+	}
+	if row.AdminEnabled != nil {
+		meta["admin_enabled"] = *row.AdminEnabled
+	}
+	if row.PowerClass != nil {
+		meta["power_class"] = *row.PowerClass
+	}
+	if row.PowerMW != nil {
+		meta["power_mw"] = *row.PowerMW
+	}
+	return meta
+}
+
+func poeDeviceLabel(id string, displayName *string) string {
+	if displayName != nil {
+		if trimmed := strings.TrimSpace(*displayName); trimmed != "" {
+			return trimmed
+		}
+	}
+	return id
+}
+
+// formatWatts renders milliwatts as watts with one decimal (`15.4 W`).
+func formatWatts(mw int) string {
+	return fmt.Sprintf("%.1f W", float64(mw)/1000)
+}
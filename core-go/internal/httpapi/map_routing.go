@@ -0,0 +1,160 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// attachRoutingAdjacencies adds OSPF/BGP adjacencies between projected devices to an L3 projection as
+// `ospf` / `bgp` edges. With a device focus, routing peers that share no subnet with the focus are added
+// as nodes (within the node cap) so its adjacencies are always visible. It is a no-op when the query is
+// unavailable.
+func (h *Handler) attachRoutingAdjacencies(ctx context.Context, resp *mapProjection, focusDeviceID string) error {
+	lister, ok := h.devices.(interface {
+		ListRoutingAdjacencies(ctx context.Context, deviceIDs []string, limit int32) ([]sqlcgen.MapRoutingAdjacency, error)
+	})
+	if !ok || len(resp.Nodes) == 0 {
+		return nil
+	}
+
+	nodeIndex := make(map[string]int, len(resp.Nodes))
+	deviceIDs := make([]string, 0, len(resp.Nodes))
+	for i, n := range resp.Nodes {
+		nodeIndex[n.ID] = i
+		if n.Kind == "device" {
+			deviceIDs = append(deviceIDs, n.ID)
+		}
+	}
+	rows, err := lister.ListRoutingAdjacencies(ctx, deviceIDs, int32(resp.Truncation.Edges.Limit+1))
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	if focusDeviceID != "" {
+		nodesCapped := false
+		for _, row := range rows {
+			peerID, peerLabel := row.BDeviceID, row.BDisplayName
+			switch focusDeviceID {
+			case row.ADeviceID:
+			case row.BDeviceID:
+				peerID, peerLabel = row.ADeviceID, row.ADisplayName
+			default:
+				continue
+			}
+			if _, exists := nodeIndex[peerID]; exists {
+				continue
+			}
+			if len(resp.Nodes) >= resp.Truncation.Nodes.Limit {
+				nodesCapped = true
+				continue
+			}
+			nodeIndex[peerID] = len(resp.Nodes)
+			resp.Nodes = append(resp.Nodes, mapNode{ID: peerID, Kind: "device", Label: peerLabel, RegionIDs: []string{}})
+		}
+		resp.Truncation.Nodes.Returned = len(resp.Nodes)
+		if nodesCapped {
+			resp.Truncation.Nodes.Truncated = true
+			resp.Truncation.Nodes.Total = nil
+			if resp.Truncation.Nodes.Warning == nil {
+				warning := fmt.Sprintf("Node cap hit: showing %d devices; some routing peers were omitted.", len(resp.Nodes))
+				resp.Truncation.Nodes.Warning = &warning
+			}
+		} else if resp.Truncation.Nodes.Total != nil {
+			total := len(resp.Nodes)
+			resp.Truncation.Nodes.Total = &total
+		}
+	}
+
+	candidates := 0
+	edgesCapped := false
+	protocols := map[string]map[string]bool{}
+	for _, row := range rows {
+		if _, ok := nodeIndex[row.ADeviceID]; !ok {
+			continue
+		}
+		if _, ok := nodeIndex[row.BDeviceID]; !ok {
+			continue
+		}
+		candidates++
+		for _, id := range []string{row.ADeviceID, row.BDeviceID} {
+			if protocols[id] == nil {
+				protocols[id] = map[string]bool{}
+			}
+			protocols[id][row.LinkType] = true
+		}
+		if len(resp.Edges) >= resp.Truncation.Edges.Limit {
+			edgesCapped = true
+			continue
+		}
+		resp.Edges = append(resp.Edges, routingAdjacencyEdge(row))
+	}
+	if candidates == 0 {
+		return nil
+	}
+
+	for id, set := range protocols {
+		i := nodeIndex[id]
+		if resp.Nodes[i].Meta == nil {
+			resp.Nodes[i].Meta = map[string]any{}
+		}
+		list := make([]string, 0, len(set))
+		for _, p := range []string{"ospf", "bgp"} {
+			if set[p] {
+				list = append(list, p)
+			}
+		}
+		resp.Nodes[i].Meta["routing_protocols"] = list
+	}
+
+	resp.Truncation.Edges.Returned = len(resp.Edges)
+	if edgesCapped || len(rows) > resp.Truncation.Edges.Limit {
+		resp.Truncation.Edges.Truncated = true
+		resp.Truncation.Edges.Total = nil
+		if resp.Truncation.Edges.Warning == nil {
+			warning := fmt.Sprintf("Edge cap hit: showing %d edges; some routing adjacencies were omitted.", len(resp.Edges))
+			resp.Truncation.Edges.Warning = &warning
+		}
+	} else if !resp.Truncation.Edges.Truncated {
+		total := len(resp.Edges)
+		resp.Truncation.Edges.Total = &total
+	}
+	return nil
+}
+
+// routingAdjacencyEdge renders one adjacency; the label is the protocol state (e.g. `full`, `established`).
+func routingAdjacencyEdge(row sqlcgen.MapRoutingAdjacency) mapEdge {
+	meta := map[string]any{
+		"link_key": row.LinkKey,
+		"protocol": row.LinkType,
+	}
+	if row.State != nil {
+		meta["state"] = *row.State
+		meta["up"] = *row.State == "full" || *row.State == "established"
+	}
+	if row.AAS != nil {
+		meta["a_as"] = *row.AAS
+	}
+	if row.BAS != nil {
+		meta["b_as"] = *row.BAS
+	}
+	if row.Area != nil {
+		meta["area"] = *row.Area
+	}
+	if row.LastChangeAt != nil {
+		meta["last_change_at"] = row.LastChangeAt.UTC().Format(time.RFC3339)
+	}
+	return mapEdge{
+		ID:    "link:" + row.LinkID,
+		Kind:  row.LinkType,
+		From:  row.ADeviceID,
+		To:    row.BDeviceID,
+		Label: row.State,
+		Meta:  meta,
+	}
+}
@@ -342,6 +342,43 @@ func TestMapProjection_DeviceFocus_L3InspectorIncludesSubnetRelationships(t *tes
 	}
 }
 
+func TestMapProjection_DeviceFocus_L3IgnoresStaleIPs(t *testing.T) {
+	staleAt := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueries{
+		getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+			return sqlcgen.Device{ID: id}, nil
+		},
+		listIPsFn: func(ctx context.Context, deviceID string) ([]sqlcgen.DeviceIP, error) {
+			return []sqlcgen.DeviceIP{
+				{IP: "10.0.1.10"},
+				{IP: "10.0.9.10", StaleAt: &staleAt},
+			}, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/map/l3?focusType=device&focusId=00000000-0000-0000-0000-000000000011", nil)
+	h.Router().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	body := decodeBody(t, rr)
+	regions, _ := body["regions"].([]any)
+	found := map[string]bool{}
+	for _, regionAny := range regions {
+		if region, ok := regionAny.(map[string]any); ok {
+			if id, ok := region["id"].(string); ok {
+				found[id] = true
+			}
+		}
+	}
+	if !found["10.0.1.0/24"] || found["10.0.9.0/24"] {
+		t.Fatalf("expected only the current subnet region, got %v", found)
+	}
+}
+
 type fakeDeviceQueriesWithVLAN struct {
 	fakeDeviceQueries
 	listPVIDsFn         func(ctx context.Context, deviceID string) ([]int32, error)
@@ -626,6 +663,98 @@ func TestMapProjection_DeviceFocus_PhysicalIncludesEdges(t *testing.T) {
 	}
 }
 
+type fakeDeviceQueriesWithSTP struct {
+	fakeDeviceQueriesWithPhysical
+	listSTPRootsFn func(ctx context.Context, deviceIDs []string) ([]sqlcgen.MapSTPRoot, error)
+}
+
+func (f fakeDeviceQueriesWithSTP) ListSTPRoots(ctx context.Context, deviceIDs []string) ([]sqlcgen.MapSTPRoot, error) {
+	if f.listSTPRootsFn == nil {
+		return nil, nil
+	}
+	return f.listSTPRootsFn(ctx, deviceIDs)
+}
+
+func TestMapProjection_DeviceFocus_PhysicalGroupsAggregatesAndMarksSTP(t *testing.T) {
+	focus := "00000000-0000-0000-0000-000000000011"
+	peerA := "00000000-0000-0000-0000-000000000101"
+	peerB := "00000000-0000-0000-0000-000000000102"
+	str := func(s string) *string { return &s }
+	prio := int32(4096)
+
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueriesWithSTP{
+		fakeDeviceQueriesWithPhysical: fakeDeviceQueriesWithPhysical{
+			fakeDeviceQueries: fakeDeviceQueries{
+				getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+					return sqlcgen.Device{ID: focus}, nil
+				},
+			},
+			listLinkPeersFn: func(ctx context.Context, deviceID string, limit int32) ([]sqlcgen.MapDeviceLinkPeer, error) {
+				return []sqlcgen.MapDeviceLinkPeer{
+					{LinkID: "link-1", LinkKey: "lldp:1", PeerDeviceID: peerA, Source: "lldp", LocalInterfaceID: str("if-1"), LocalInterfaceName: str("Gi1/0/1"), LocalAggregateID: str("po-1"), LocalAggregateName: str("Po1"), LocalSTPState: str("forwarding")},
+					{LinkID: "link-2", LinkKey: "lldp:2", PeerDeviceID: peerA, Source: "lldp", LocalInterfaceID: str("if-2"), LocalInterfaceName: str("Gi1/0/2"), LocalAggregateID: str("po-1"), LocalAggregateName: str("Po1"), LocalSTPState: str("forwarding")},
+					{LinkID: "link-3", LinkKey: "lldp:3", PeerDeviceID: peerB, Source: "lldp", LocalInterfaceID: str("if-3"), PeerSTPState: str("blocking")},
+				}, nil
+			},
+		},
+		listSTPRootsFn: func(ctx context.Context, deviceIDs []string) ([]sqlcgen.MapSTPRoot, error) {
+			if len(deviceIDs) != 3 {
+				t.Fatalf("expected focus + 2 peers, got %v", deviceIDs)
+			}
+			return []sqlcgen.MapSTPRoot{{Instance: 0, RootAddress: "00:11:22:33:44:55", RootPriority: &prio, RootDeviceID: &peerA, ReportedBy: 3}}, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/map/physical?focusType=device&focusId="+focus, nil)
+	h.Router().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	body := decodeBody(t, rr)
+	if nodes := body["nodes"].([]any); len(nodes) != 3 {
+		t.Fatalf("expected 3 nodes, got %d", len(nodes))
+	}
+	edges := body["edges"].([]any)
+	if len(edges) != 2 {
+		t.Fatalf("expected aggregate + single edge, got %v", edges)
+	}
+	byID := map[string]map[string]any{}
+	for _, e := range edges {
+		edge := e.(map[string]any)
+		byID[edge["id"].(string)] = edge
+	}
+
+	lag := byID["lag:po-1"]
+	if lag == nil || lag["to"] != peerA || lag["label"] != "Po1" {
+		t.Fatalf("expected aggregate edge to peer A, got %v", edges)
+	}
+	lagMeta := lag["meta"].(map[string]any)
+	if lagMeta["aggregate"].(map[string]any)["member_count"] != float64(2) || len(lagMeta["members"].([]any)) != 2 || lagMeta["stp_blocked"] != false {
+		t.Fatalf("unexpected aggregate meta %v", lagMeta)
+	}
+
+	single := byID["link:link-3"]
+	if single == nil || single["meta"].(map[string]any)["stp_state"] != "blocking" || single["meta"].(map[string]any)["stp_blocked"] != true {
+		t.Fatalf("expected blocked single link, got %v", single)
+	}
+
+	roots := body["meta"].(map[string]any)["stp_roots"].([]any)
+	root := roots[0].(map[string]any)
+	if len(roots) != 1 || root["root_bridge_id"] != "4096.00:11:22:33:44:55" || root["root_device_id"] != peerA {
+		t.Fatalf("unexpected stp roots %v", roots)
+	}
+	for _, n := range body["nodes"].([]any) {
+		node := n.(map[string]any)
+		meta, _ := node["meta"].(map[string]any)
+		if isRoot := meta["stp_root"] == true; isRoot != (node["id"] == peerA) {
+			t.Fatalf("unexpected stp_root flag on %v", node)
+		}
+	}
+}
+
 type fakeDeviceQueriesWithServices struct {
 	fakeDeviceQueries
 	getServiceFn            func(ctx context.Context, serviceID string) (sqlcgen.MapService, error)
@@ -849,3 +978,188 @@ func TestMapProjection_ServiceFocus_ServicesIncludesHostRegion(t *testing.T) {
 		t.Fatalf("expected host region kind device, got %v", region["kind"])
 	}
 }
+
+type fakeDeviceQueriesWithRouting struct {
+	fakeDeviceQueries
+	listRoutingFn func(ctx context.Context, deviceIDs []string, limit int32) ([]sqlcgen.MapRoutingAdjacency, error)
+}
+
+func (f fakeDeviceQueriesWithRouting) ListRoutingAdjacencies(ctx context.Context, deviceIDs []string, limit int32) ([]sqlcgen.MapRoutingAdjacency, error) {
+	return f.listRoutingFn(ctx, deviceIDs, limit)
+}
+
+func TestMapProjection_DeviceFocus_L3RendersRoutingAdjacencies(t *testing.T) {
+	focusID := "00000000-0000-0000-0000-000000000011"
+	peerID := "00000000-0000-0000-0000-000000000022"
+	otherID := "00000000-0000-0000-0000-000000000033"
+	name, peerName := "router-1", "router-2"
+	full, established := "full", "established"
+	area := "0.0.0.0"
+	aAS, bAS := int64(65001), int64(65002)
+
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueriesWithRouting{
+		fakeDeviceQueries: fakeDeviceQueries{
+			getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+				return sqlcgen.Device{ID: focusID, DisplayName: &name}, nil
+			},
+			listIPsFn: func(ctx context.Context, deviceID string) ([]sqlcgen.DeviceIP, error) {
+				return []sqlcgen.DeviceIP{{IP: "10.0.1.1"}}, nil
+			},
+		},
+		listRoutingFn: func(ctx context.Context, deviceIDs []string, limit int32) ([]sqlcgen.MapRoutingAdjacency, error) {
+			if len(deviceIDs) != 1 || deviceIDs[0] != focusID {
+				t.Fatalf("expected focus device ids, got %v", deviceIDs)
+			}
+			return []sqlcgen.MapRoutingAdjacency{
+				{LinkID: "l-ospf", LinkKey: "ospf:a:-:b:-", ADeviceID: focusID, BDeviceID: peerID, BDisplayName: &peerName, LinkType: "ospf", State: &full, Area: &area},
+				{LinkID: "l-bgp", LinkKey: "bgp:a:-:b:-", ADeviceID: focusID, BDeviceID: peerID, BDisplayName: &peerName, LinkType: "bgp", State: &established, AAS: &aAS, BAS: &bAS},
+				{LinkID: "l-far", LinkKey: "bgp:b:-:c:-", ADeviceID: peerID, BDeviceID: otherID, LinkType: "bgp", State: &established},
+			}, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/map/l3?focusType=device&focusId="+focusID, nil)
+	h.Router().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	body := decodeBody(t, rr)
+	nodes := body["nodes"].([]any)
+	if len(nodes) != 2 {
+		t.Fatalf("expected focus + routing peer nodes, got %v", nodes)
+	}
+	peer := nodes[1].(map[string]any)
+	if peer["id"] != peerID || peer["label"] != peerName {
+		t.Fatalf("unexpected peer node: %v", peer)
+	}
+	if protocols := peer["meta"].(map[string]any)["routing_protocols"].([]any); len(protocols) != 2 || protocols[0] != "ospf" {
+		t.Fatalf("unexpected routing_protocols: %v", protocols)
+	}
+
+	edges := body["edges"].([]any)
+	if len(edges) != 2 {
+		t.Fatalf("expected 2 adjacency edges, got %v", edges)
+	}
+	byID := map[string]map[string]any{}
+	for _, e := range edges {
+		edge := e.(map[string]any)
+		byID[edge["id"].(string)] = edge
+	}
+	ospf := byID["link:l-ospf"]
+	if ospf == nil || ospf["kind"] != "ospf" || ospf["label"] != "full" {
+		t.Fatalf("unexpected ospf edge: %v", ospf)
+	}
+	if meta := ospf["meta"].(map[string]any); meta["area"] != "0.0.0.0" || meta["up"] != true {
+		t.Fatalf("unexpected ospf edge meta: %v", meta)
+	}
+	bgp := byID["link:l-bgp"]
+	if meta := bgp["meta"].(map[string]any); bgp["kind"] != "bgp" || meta["a_as"] != float64(65001) || meta["b_as"] != float64(65002) {
+		t.Fatalf("unexpected bgp edge: %v", bgp)
+	}
+}
+
+type fakeDeviceQueriesWithTraceroute struct {
+	fakeDeviceQueriesWithCIDR
+	listTracerouteFn func(ctx context.Context, cidr string) ([]sqlcgen.MapTracerouteHop, error)
+}
+
+func (f fakeDeviceQueriesWithTraceroute) ListTraceroutePaths(ctx context.Context, cidr string) ([]sqlcgen.MapTracerouteHop, error) {
+	return f.listTracerouteFn(ctx, cidr)
+}
+
+func TestMapProjection_SubnetFocus_L3RendersTraceroutePath(t *testing.T) {
+	memberID := "00000000-0000-0000-0000-000000000001"
+	gatewayID := "00000000-0000-0000-0000-000000000010"
+	coreID := "00000000-0000-0000-0000-000000000020"
+	memberName, gatewayName := "nas", "edge-gw"
+	observed := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ip := func(s string) *string { return &s }
+	id := func(s string) *string { return &s }
+	rtt := func(v float32) *float32 { return &v }
+
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueriesWithTraceroute{
+		fakeDeviceQueriesWithCIDR: fakeDeviceQueriesWithCIDR{
+			fakeDeviceQueries: fakeDeviceQueries{
+				getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) { return sqlcgen.Device{}, nil },
+			},
+			listCIDRFn: func(ctx context.Context, cidr string, limit int32) ([]sqlcgen.MapDevicePeer, error) {
+				return []sqlcgen.MapDevicePeer{{ID: memberID, DisplayName: &memberName}}, nil
+			},
+		},
+		listTracerouteFn: func(ctx context.Context, cidr string) ([]sqlcgen.MapTracerouteHop, error) {
+			if cidr != "192.168.50.0/24" {
+				t.Fatalf("unexpected cidr %q", cidr)
+			}
+			row := sqlcgen.MapTracerouteHop{PathID: "p1", Scope: "192.168.50.0/24", Target: "192.168.50.1", Reached: true, ObservedAt: observed}
+			hop := func(ttl int32, addr, device *string, name *string, ms *float32) sqlcgen.MapTracerouteHop {
+				r := row
+				r.TTL, r.IP, r.DeviceID, r.DisplayName, r.RTTMs = ttl, addr, device, name, ms
+				return r
+			}
+			return []sqlcgen.MapTracerouteHop{
+				hop(1, ip("10.0.0.1"), id(gatewayID), &gatewayName, rtt(1.5)),
+				hop(2, nil, nil, nil, nil),
+				hop(3, ip("172.16.0.9"), id(coreID), nil, rtt(8)),
+				hop(4, ip("192.168.50.1"), id(memberID), &memberName, rtt(9)),
+			}, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/map/l3?focusType=subnet&focusId=192.168.50.0/24", nil)
+	h.Router().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	body := decodeBody(t, rr)
+	nodes := map[string]map[string]any{}
+	for _, n := range body["nodes"].([]any) {
+		node := n.(map[string]any)
+		nodes[node["id"].(string)] = node
+	}
+	if len(nodes) != 4 {
+		t.Fatalf("expected member, vantage and two router hops, got %v", nodes)
+	}
+	if vantage := nodes["vantage:core-go"]; vantage == nil || vantage["kind"] != "vantage" {
+		t.Fatalf("expected vantage node, got %v", nodes)
+	}
+	if regions := nodes[gatewayID]["region_ids"].([]any); len(regions) != 0 {
+		t.Fatalf("expected hop outside the subnet to have no region, got %v", regions)
+	}
+	if regions := nodes[memberID]["region_ids"].([]any); len(regions) != 1 {
+		t.Fatalf("expected target to stay in the subnet region, got %v", regions)
+	}
+
+	edges := map[string]map[string]any{}
+	for _, e := range body["edges"].([]any) {
+		edge := e.(map[string]any)
+		edges[edge["id"].(string)] = edge
+	}
+	want := map[string][2]string{
+		"hop:p1:1": {"vantage:core-go", gatewayID},
+		"hop:p1:3": {gatewayID, coreID},
+		"hop:p1:4": {coreID, memberID},
+	}
+	if len(edges) != len(want) {
+		t.Fatalf("expected %d hop edges, got %v", len(want), edges)
+	}
+	for edgeID, ends := range want {
+		edge := edges[edgeID]
+		if edge == nil || edge["kind"] != "hop" || edge["from"] != ends[0] || edge["to"] != ends[1] {
+			t.Fatalf("unexpected edge %s: %v", edgeID, edge)
+		}
+	}
+	if meta := edges["hop:p1:3"]["meta"].(map[string]any); meta["skipped_hops"] != float64(1) || meta["rtt_ms"] != float64(8) {
+		t.Fatalf("unexpected hop meta: %v", meta)
+	}
+
+	paths := body["meta"].(map[string]any)["traceroute_paths"].([]any)
+	if len(paths) != 1 || paths[0].(map[string]any)["hop_count"] != float64(4) || paths[0].(map[string]any)["reached"] != true {
+		t.Fatalf("unexpected traceroute_paths meta: %v", paths)
+	}
+}
@@ -0,0 +1,183 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// tracerouteVantageNodeID is the synthetic node for core-go itself: traced paths start here.
+const tracerouteVantageNodeID = "vantage:core-go"
+
+// attachTraceroutePaths draws the latest traced path toward each scope overlapping a subnet-focus L3
+// projection: a `vantage` node for core-go, each answering hop as a device node and `hop` edges chaining
+// them in TTL order. Silent hops are bridged and counted in the edge meta (`skipped_hops`). It is a no-op
+// when the query is unavailable or nothing was traced.
+func (h *Handler) attachTraceroutePaths(ctx context.Context, resp *mapProjection, subnet string) error {
+	lister, ok := h.devices.(interface {
+		ListTraceroutePaths(ctx context.Context, cidr string) ([]sqlcgen.MapTracerouteHop, error)
+	})
+	if !ok {
+		return nil
+	}
+	rows, err := lister.ListTraceroutePaths(ctx, subnet)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	subnetPrefix, _ := netip.ParsePrefix(subnet)
+
+	nodeIndex := make(map[string]int, len(resp.Nodes))
+	for i, n := range resp.Nodes {
+		nodeIndex[n.ID] = i
+	}
+	nodesCapped, edgesCapped := false, false
+	addNode := func(n mapNode) bool {
+		if _, ok := nodeIndex[n.ID]; ok {
+			return true
+		}
+		if len(resp.Nodes) >= resp.Truncation.Nodes.Limit {
+			nodesCapped = true
+			return false
+		}
+		nodeIndex[n.ID] = len(resp.Nodes)
+		resp.Nodes = append(resp.Nodes, n)
+		return true
+	}
+
+	vantageLabel := "core-go"
+	if !addNode(mapNode{ID: tracerouteVantageNodeID, Kind: "vantage", Label: &vantageLabel, RegionIDs: []string{}}) {
+		return nil
+	}
+
+	paths := make([]map[string]any, 0)
+	var lastPath *sqlcgen.MapTracerouteHop
+	prev, skipped, stopped := tracerouteVantageNodeID, 0, false
+	for i := range rows {
+		row := rows[i]
+		if lastPath == nil || lastPath.PathID != row.PathID {
+			lastPath = &rows[i]
+			prev, skipped, stopped = tracerouteVantageNodeID, 0, false
+			paths = append(paths, map[string]any{
+				"path_id":     row.PathID,
+				"scope":       row.Scope,
+				"target":      row.Target,
+				"reached":     row.Reached,
+				"observed_at": row.ObservedAt.UTC().Format(time.RFC3339),
+				"hop_count":   0,
+			})
+		}
+		current := paths[len(paths)-1]
+		current["hop_count"] = current["hop_count"].(int) + 1
+		if stopped {
+			continue
+		}
+		if row.DeviceID == nil || row.IP == nil {
+			skipped++
+			continue
+		}
+
+		node := mapNode{ID: *row.DeviceID, Kind: "device", Label: row.DisplayName, RegionIDs: []string{}}
+		if addr, err := netip.ParseAddr(*row.IP); err == nil && subnetPrefix.IsValid() && subnetPrefix.Contains(addr) {
+			primary := subnet
+			node.PrimaryRegionID = &primary
+			node.RegionIDs = []string{subnet}
+		}
+		if !addNode(node) {
+			// Without the hop node the rest of the chain cannot be drawn faithfully.
+			stopped = true
+			continue
+		}
+		hopNode := &resp.Nodes[nodeIndex[*row.DeviceID]]
+		if hopNode.Meta == nil {
+			hopNode.Meta = map[string]any{}
+		}
+		if _, ok := hopNode.Meta["traceroute_hop"]; !ok {
+			hopNode.Meta["traceroute_hop"] = map[string]any{"ttl": row.TTL, "ip": *row.IP}
+		}
+
+		if prev != *row.DeviceID {
+			if len(resp.Edges) >= resp.Truncation.Edges.Limit {
+				edgesCapped = true
+			} else {
+				resp.Edges = append(resp.Edges, tracerouteHopEdge(row, prev, skipped))
+			}
+		}
+		prev, skipped = *row.DeviceID, 0
+	}
+
+	if resp.Meta == nil {
+		resp.Meta = map[string]any{}
+	}
+	resp.Meta["traceroute_paths"] = paths
+
+	resp.Truncation.Nodes.Returned = len(resp.Nodes)
+	if nodesCapped {
+		resp.Truncation.Nodes.Truncated = true
+		resp.Truncation.Nodes.Total = nil
+		if resp.Truncation.Nodes.Warning == nil {
+			warning := fmt.Sprintf("Node cap hit: showing %d devices; some traceroute hops were omitted.", len(resp.Nodes))
+			resp.Truncation.Nodes.Warning = &warning
+		}
+	} else if resp.Truncation.Nodes.Total != nil {
+		total := len(resp.Nodes)
+		resp.Truncation.Nodes.Total = &total
+	}
+	resp.Truncation.Edges.Returned = len(resp.Edges)
+	if edgesCapped {
+		resp.Truncation.Edges.Truncated = true
+		resp.Truncation.Edges.Total = nil
+		if resp.Truncation.Edges.Warning == nil {
+			warning := fmt.Sprintf("Edge cap hit: showing %d edges; some traceroute hops were omitted.", len(resp.Edges))
+			resp.Truncation.Edges.Warning = &warning
+		}
+	} else if !resp.Truncation.Edges.Truncated {
+		total := len(resp.Edges)
+		resp.Truncation.Edges.Total = &total
+	}
+
+	if resp.Inspector != nil {
+		for _, p := range paths {
+			state := "target not reached"
+			if p["reached"] == true {
+				state = "reached"
+			}
+			resp.Inspector.Status = append(resp.Inspector.Status, mapInspectorField{
+				Label: "Traced path",
+				Value: fmt.Sprintf("%s: %d hops (%s)", p["scope"], p["hop_count"], state),
+			})
+		}
+	}
+	return nil
+}
+
+// tracerouteHopEdge links the previous answering hop (or the vantage node) to this one.
+func tracerouteHopEdge(row sqlcgen.MapTracerouteHop, from string, skipped int) mapEdge {
+	meta := map[string]any{
+		"ttl":    row.TTL,
+		"ip":     *row.IP,
+		"scope":  row.Scope,
+		"target": row.Target,
+	}
+	if row.RTTMs != nil {
+		meta["rtt_ms"] = *row.RTTMs
+	}
+	if skipped > 0 {
+		meta["skipped_hops"] = skipped
+	}
+	label := "ttl " + strconv.Itoa(int(row.TTL))
+	return mapEdge{
+		ID:    fmt.Sprintf("hop:%s:%d", row.PathID, row.TTL),
+		Kind:  "hop",
+		From:  from,
+		To:    *row.DeviceID,
+		Label: &label,
+		Meta:  meta,
+	}
+}
@@ -0,0 +1,166 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+const (
+	maxMergeSourceDevices = 50
+	// defaultMergeActor is recorded in the audit log when the caller does not name one.
+	defaultMergeActor = "api"
+)
+
+type deviceMergeRequest struct {
+	SourceIDs []string `json:"source_ids"`
+	Actor     *string  `json:"actor,omitempty"`
+	ActorRole *string  `json:"actor_role,omitempty"`
+}
+
+type deviceMergeResult struct {
+	Device    device           `json:"device"`
+	MergedIDs []string         `json:"merged_ids"`
+	Moved     map[string]int64 `json:"moved"`
+}
+
+type deviceAliasQueries interface {
+	GetDeviceAlias(ctx context.Context, aliasID string) (string, error)
+}
+
+// validateMergeSources trims and de-duplicates the source IDs; the survivor may not be one of them.
+func validateMergeSources(survivorID string, sourceIDs []string) ([]string, error) {
+	out := make([]string, 0, len(sourceIDs))
+	seen := make(map[string]struct{}, len(sourceIDs))
+	for i, raw := range sourceIDs {
+		id := strings.ToLower(strings.TrimSpace(raw))
+		if id == "" {
+			return nil, fmt.Errorf("source_ids[%d] is empty", i)
+		}
+		if id == strings.ToLower(survivorID) {
+			return nil, errors.New("a device cannot be merged into itself")
+		}
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("source_ids is required")
+	}
+	if len(out) > maxMergeSourceDevices {
+		return nil, fmt.Errorf("too many source_ids (max %d)", maxMergeSourceDevices)
+	}
+	return out, nil
+}
+
+// handleMergeDevices folds duplicate devices into the device in the path. Everything happens in one
+// transaction; the merged IDs keep resolving on GET /devices/{id} through device_aliases.
+func (h *Handler) handleMergeDevices(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req deviceMergeRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid json body", map[string]any{"error": err.Error()})
+		return
+	}
+	sources, err := validateMergeSources(id, req.SourceIDs)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid merge request", map[string]any{"error": err.Error()})
+		return
+	}
+	actor := defaultMergeActor
+	if req.Actor != nil && strings.TrimSpace(*req.Actor) != "" {
+		actor = strings.TrimSpace(*req.Actor)
+	}
+	var actorRole *string
+	if req.ActorRole != nil && strings.TrimSpace(*req.ActorRole) != "" {
+		role := strings.TrimSpace(*req.ActorRole)
+		actorRole = &role
+	}
+
+	if !h.ensureDeviceQueries(w) {
+		return
+	}
+	merger, ok := h.devices.(interface {
+		MergeDevices(ctx context.Context, arg sqlcgen.MergeDevicesParams) (sqlcgen.MergeDevicesResult, error)
+	})
+	if !ok {
+		h.log.Error().Msg("device merge query missing")
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "device merge not supported", nil)
+		return
+	}
+
+	ctx := r.Context()
+	for _, deviceID := range append([]string{id}, sources...) {
+		if _, err := h.devices.GetDevice(ctx, deviceID); err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				h.writeError(w, http.StatusNotFound, "not_found", "device not found", map[string]any{"id": deviceID})
+			case isInvalidUUID(err):
+				h.writeError(w, http.StatusBadRequest, "invalid_id", "device id is not a valid uuid", map[string]any{"id": deviceID})
+			default:
+				h.log.Error().Err(err).Str("id", deviceID).Msg("fetch device before merge failed")
+				h.writeError(w, http.StatusInternalServerError, "db_error", "failed to merge devices", nil)
+			}
+			return
+		}
+	}
+
+	res, err := merger.MergeDevices(ctx, sqlcgen.MergeDevicesParams{
+		SurvivorID: id,
+		SourceIDs:  sources,
+		Actor:      actor,
+		ActorRole:  actorRole,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// A device was deleted or merged by someone else between the check above and the transaction.
+			h.writeError(w, http.StatusNotFound, "not_found", "device not found", map[string]any{"ids": append([]string{id}, sources...)})
+			return
+		}
+		h.log.Error().Err(err).Str("id", id).Strs("source_ids", sources).Msg("merge devices failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to merge devices", nil)
+		return
+	}
+	h.log.Info().Str("id", id).Strs("source_ids", sources).Str("actor", actor).Msg("devices merged")
+
+	row, err := h.devices.GetDevice(ctx, id)
+	if err != nil {
+		h.log.Error().Err(err).Str("id", id).Msg("get device after merge failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to fetch merged device", nil)
+		return
+	}
+	out := toDevice(row)
+	if tags, err := h.devices.ListDeviceEffectiveTags(ctx, row.ID); err == nil && len(tags) > 0 {
+		out.Tags = tags
+	}
+	moved := res.Moved
+	if moved == nil {
+		moved = map[string]int64{}
+	}
+	h.writeJSON(w, http.StatusOK, deviceMergeResult{Device: out, MergedIDs: sources, Moved: moved})
+}
+
+// resolveDeviceAlias maps a merged device ID to its survivor; ok is false when id is not an alias.
+func (h *Handler) resolveDeviceAlias(ctx context.Context, id string) (string, bool, error) {
+	aliases, ok := h.devices.(deviceAliasQueries)
+	if !ok {
+		return "", false, nil
+	}
+	survivorID, err := aliases.GetDeviceAlias(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return survivorID, true, nil
+}
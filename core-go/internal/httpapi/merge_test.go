@@ -0,0 +1,151 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+type fakeDeviceQueriesWithMerge struct {
+	fakeDeviceQueries
+	mergeFn func(ctx context.Context, arg sqlcgen.MergeDevicesParams) (sqlcgen.MergeDevicesResult, error)
+	aliases map[string]string
+}
+
+func (f fakeDeviceQueriesWithMerge) MergeDevices(ctx context.Context, arg sqlcgen.MergeDevicesParams) (sqlcgen.MergeDevicesResult, error) {
+	return f.mergeFn(ctx, arg)
+}
+
+func (f fakeDeviceQueriesWithMerge) GetDeviceAlias(ctx context.Context, aliasID string) (string, error) {
+	if survivorID, ok := f.aliases[aliasID]; ok {
+		return survivorID, nil
+	}
+	return "", pgx.ErrNoRows
+}
+
+func TestValidateMergeSources(t *testing.T) {
+	survivor := "00000000-0000-0000-0000-000000000001"
+	cases := []struct {
+		name    string
+		sources []string
+		want    int
+		wantErr string
+	}{
+		{name: "dedupes", sources: []string{"00000000-0000-0000-0000-000000000002", " 00000000-0000-0000-0000-000000000002 "}, want: 1},
+		{name: "empty list", sources: nil, wantErr: "source_ids is required"},
+		{name: "blank id", sources: []string{" "}, wantErr: "source_ids[0] is empty"},
+		{name: "self merge", sources: []string{survivor}, wantErr: "into itself"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := validateMergeSources(survivor, tc.sources)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil || len(got) != tc.want {
+				t.Fatalf("expected %d sources, got %v (%v)", tc.want, got, err)
+			}
+		})
+	}
+}
+
+func TestDevices_Merge(t *testing.T) {
+	survivor := "00000000-0000-0000-0000-000000000001"
+	source := "00000000-0000-0000-0000-000000000002"
+	missing := "00000000-0000-0000-0000-000000000009"
+
+	cases := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "merges", body: `{"source_ids":["` + source + `"],"actor":"alice"}`, wantCode: http.StatusOK},
+		{name: "unknown source", body: `{"source_ids":["` + missing + `"]}`, wantCode: http.StatusNotFound, wantErr: "not_found"},
+		{name: "self merge", body: `{"source_ids":["` + survivor + `"]}`, wantCode: http.StatusBadRequest, wantErr: "validation_failed"},
+		{name: "unknown field", body: `{"source":"x"}`, wantCode: http.StatusBadRequest, wantErr: "validation_failed"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got sqlcgen.MergeDevicesParams
+			h := NewHandler(NewLogger("debug"), nil)
+			h.devices = fakeDeviceQueriesWithMerge{
+				fakeDeviceQueries: fakeDeviceQueries{
+					getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+						if id == missing {
+							return sqlcgen.Device{}, pgx.ErrNoRows
+						}
+						return sqlcgen.Device{ID: id}, nil
+					},
+				},
+				mergeFn: func(ctx context.Context, arg sqlcgen.MergeDevicesParams) (sqlcgen.MergeDevicesResult, error) {
+					got = arg
+					return sqlcgen.MergeDevicesResult{DeviceID: arg.SurvivorID, MergedIDs: arg.SourceIDs, Moved: map[string]int64{"ips": 2}}, nil
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/devices/"+survivor+"/merge", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			h.Router().ServeHTTP(rr, req)
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			body := decodeBody(t, rr)
+			if tc.wantErr != "" {
+				if code := body["error"].(map[string]any)["code"]; code != tc.wantErr {
+					t.Fatalf("expected error code %q, got %v", tc.wantErr, code)
+				}
+				if got.SurvivorID != "" {
+					t.Fatalf("merge should not run on %s", tc.name)
+				}
+				return
+			}
+			if got.SurvivorID != survivor || len(got.SourceIDs) != 1 || got.SourceIDs[0] != source || got.Actor != "alice" {
+				t.Fatalf("unexpected merge params %+v", got)
+			}
+			if body["device"].(map[string]any)["id"] != survivor || body["moved"].(map[string]any)["ips"] != float64(2) {
+				t.Fatalf("unexpected merge result %v", body)
+			}
+		})
+	}
+}
+
+func TestDevices_Get_ResolvesMergedAlias(t *testing.T) {
+	survivor := "00000000-0000-0000-0000-000000000001"
+	merged := "00000000-0000-0000-0000-000000000002"
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueriesWithMerge{
+		fakeDeviceQueries: fakeDeviceQueries{
+			getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+				if id != survivor {
+					return sqlcgen.Device{}, pgx.ErrNoRows
+				}
+				return sqlcgen.Device{ID: id}, nil
+			},
+		},
+		aliases: map[string]string{merged: survivor},
+	}
+
+	for id, wantCode := range map[string]int{merged: http.StatusOK, "00000000-0000-0000-0000-000000000003": http.StatusNotFound} {
+		rr := httptest.NewRecorder()
+		h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/"+id, nil))
+		if rr.Code != wantCode {
+			t.Fatalf("%s: expected %d, got %d: %s", id, wantCode, rr.Code, rr.Body.String())
+		}
+		if wantCode == http.StatusOK {
+			if got := decodeBody(t, rr)["id"]; got != survivor {
+				t.Fatalf("expected alias to resolve to %s, got %v", survivor, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+const (
+	defaultReachabilityWindow = 24 * time.Hour
+	maxReachabilityWindow     = 366 * 24 * time.Hour
+	// maxRawReachabilityWindow bounds raw requests; at a 30s interval a week is about 20k samples.
+	maxRawReachabilityWindow = 7 * 24 * time.Hour
+
+	reachabilityResolutionRaw  = "raw"
+	reachabilityResolutionHour = "hour"
+)
+
+type monitorQueries interface {
+	GetDeviceMonitor(ctx context.Context, deviceID string) (sqlcgen.DeviceMonitor, error)
+	UpsertDeviceMonitor(ctx context.Context, arg sqlcgen.UpsertDeviceMonitorParams) (sqlcgen.DeviceMonitor, error)
+	DeleteDeviceMonitor(ctx context.Context, deviceID string) (int64, error)
+	ListReachabilitySamples(ctx context.Context, arg sqlcgen.ListReachabilityParams) ([]sqlcgen.ReachabilitySample, error)
+	ListReachabilityRollups(ctx context.Context, arg sqlcgen.ListReachabilityParams) ([]sqlcgen.ReachabilityRollup, error)
+}
+
+type deviceMonitor struct {
+	DeviceID            string     `json:"device_id"`
+	Source              string     `json:"source"`
+	Enabled             bool       `json:"enabled"`
+	Method              string     `json:"method"`
+	Port                *int32     `json:"port"`
+	LastProbeAt         *time.Time `json:"last_probe_at"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	LastRTTMs           *float64   `json:"last_rtt_ms"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+type deviceMonitorBody struct {
+	Method  string `json:"method"`
+	Port    *int32 `json:"port"`
+	Enabled *bool  `json:"enabled"`
+}
+
+type reachabilityPoint struct {
+	At          time.Time `json:"at"`
+	Probes      int32     `json:"probes"`
+	Failures    int32     `json:"failures"`
+	LossPercent float64   `json:"loss_percent"`
+	RTTAvgMs    *float64  `json:"rtt_avg_ms"`
+	RTTMinMs    *float64  `json:"rtt_min_ms"`
+	RTTMaxMs    *float64  `json:"rtt_max_ms"`
+}
+
+type deviceReachabilityResponse struct {
+	DeviceID   string              `json:"device_id"`
+	From       time.Time           `json:"from"`
+	To         time.Time           `json:"to"`
+	Resolution string              `json:"resolution"`
+	Points     []reachabilityPoint `json:"points"`
+}
+
+func toDeviceMonitor(m sqlcgen.DeviceMonitor) deviceMonitor {
+	return deviceMonitor{
+		DeviceID:            m.DeviceID,
+		Source:              m.Source,
+		Enabled:             m.Enabled,
+		Method:              m.Method,
+		Port:                m.Port,
+		LastProbeAt:         m.LastProbeAt,
+		LastSuccessAt:       m.LastSuccessAt,
+		LastRTTMs:           m.LastRTTMs,
+		ConsecutiveFailures: m.ConsecutiveFailures,
+		CreatedAt:           m.CreatedAt,
+		UpdatedAt:           m.UpdatedAt,
+	}
+}
+
+func lossPercent(probes, failures int32) float64 {
+	if probes <= 0 {
+		return 0
+	}
+	return math.Round(float64(failures)/float64(probes)*10000) / 100
+}
+
+func rawReachabilityPoints(samples []sqlcgen.ReachabilitySample) []reachabilityPoint {
+	out := make([]reachabilityPoint, 0, len(samples))
+	for _, s := range samples {
+		p := reachabilityPoint{At: s.ProbedAt, Probes: 1, RTTAvgMs: s.RTTMs, RTTMinMs: s.RTTMs, RTTMaxMs: s.RTTMs}
+		if !s.Success {
+			p.Failures = 1
+		}
+		p.LossPercent = lossPercent(p.Probes, p.Failures)
+		out = append(out, p)
+	}
+	return out
+}
+
+func hourlyReachabilityPoints(rollups []sqlcgen.ReachabilityRollup) []reachabilityPoint {
+	out := make([]reachabilityPoint, 0, len(rollups))
+	for _, r := range rollups {
+		out = append(out, reachabilityPoint{
+			At:          r.BucketStart,
+			Probes:      r.Probes,
+			Failures:    r.Failures,
+			LossPercent: lossPercent(r.Probes, r.Failures),
+			RTTAvgMs:    r.RTTAvgMs,
+			RTTMinMs:    r.RTTMinMs,
+			RTTMaxMs:    r.RTTMaxMs,
+		})
+	}
+	return out
+}
+
+// monitorStore checks the device exists and returns the monitor queries, writing an error otherwise.
+func (h *Handler) monitorStore(w http.ResponseWriter, r *http.Request, id string) (monitorQueries, bool) {
+	if !h.ensureDeviceQueries(w) {
+		return nil, false
+	}
+	store, ok := h.devices.(monitorQueries)
+	if !ok {
+		h.log.Error().Msg("monitor queries missing")
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "reachability monitor not supported", nil)
+		return nil, false
+	}
+	if _, err := h.devices.GetDevice(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			h.writeError(w, http.StatusNotFound, "not_found", "device not found", map[string]any{"id": id})
+		case isInvalidUUID(err):
+			h.writeError(w, http.StatusBadRequest, "invalid_id", "device id is not a valid uuid", map[string]any{"id": id})
+		default:
+			h.log.Error().Err(err).Str("id", id).Msg("fetch device before monitor failed")
+			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to fetch device", nil)
+		}
+		return nil, false
+	}
+	return store, true
+}
+
+func (h *Handler) handleGetDeviceMonitor(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	store, ok := h.monitorStore(w, r, id)
+	if !ok {
+		return
+	}
+	row, err := store.GetDeviceMonitor(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			h.writeError(w, http.StatusNotFound, "not_found", "device is not monitored", map[string]any{"id": id})
+			return
+		}
+		h.log.Error().Err(err).Str("id", id).Msg("get device monitor failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to fetch device monitor", nil)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, toDeviceMonitor(row))
+}
+
+// handlePutDeviceMonitor flags a device for the reachability monitor. It always stores a manual monitor, which
+// also overrides a tag-selected one (e.g. to disable it).
+func (h *Handler) handlePutDeviceMonitor(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req deviceMonitorBody
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid json body", map[string]any{"error": err.Error()})
+		return
+	}
+	if req.Method == "" {
+		req.Method = sqlcgen.MonitorMethodICMP
+	}
+	switch {
+	case req.Method != sqlcgen.MonitorMethodICMP && req.Method != sqlcgen.MonitorMethodTCP:
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "method must be icmp or tcp", map[string]any{"method": req.Method})
+		return
+	case req.Port != nil && req.Method != sqlcgen.MonitorMethodTCP:
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "port is only used with the tcp method", map[string]any{"port": *req.Port})
+		return
+	case req.Port != nil && (*req.Port < 1 || *req.Port > 65535):
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "port must be between 1 and 65535", map[string]any{"port": *req.Port})
+		return
+	}
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	store, ok := h.monitorStore(w, r, id)
+	if !ok {
+		return
+	}
+	row, err := store.UpsertDeviceMonitor(r.Context(), sqlcgen.UpsertDeviceMonitorParams{
+		DeviceID: id,
+		Enabled:  enabled,
+		Method:   req.Method,
+		Port:     req.Port,
+	})
+	if err != nil {
+		h.log.Error().Err(err).Str("id", id).Msg("upsert device monitor failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to save device monitor", nil)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, toDeviceMonitor(row))
+}
+
+// handleDeleteDeviceMonitor stops monitoring a device. A device carrying a MONITOR_TAGS tag is picked up again
+// by the next monitor cycle; disable its monitor instead to keep it unprobed.
+func (h *Handler) handleDeleteDeviceMonitor(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	store, ok := h.monitorStore(w, r, id)
+	if !ok {
+		return
+	}
+	n, err := store.DeleteDeviceMonitor(r.Context(), id)
+	if err != nil {
+		h.log.Error().Err(err).Str("id", id).Msg("delete device monitor failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to delete device monitor", nil)
+		return
+	}
+	if n == 0 {
+		h.writeError(w, http.StatusNotFound, "not_found", "device is not monitored", map[string]any{"id": id})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetDeviceReachability returns the RTT/loss series of a monitored device between from and to (RFC3339;
+// default the last 24 hours). resolution is raw (one point per probe) or hour (rollups); it defaults to raw for
+// windows up to 24 hours.
+func (h *Handler) handleGetDeviceReachability(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	q := r.URL.Query()
+
+	to := time.Now().UTC()
+	if raw := q.Get("to"); raw != "" {
+		ts, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid to timestamp", map[string]any{"error": err.Error()})
+			return
+		}
+		to = ts
+	}
+	from := to.Add(-defaultReachabilityWindow)
+	if raw := q.Get("from"); raw != "" {
+		ts, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid from timestamp", map[string]any{"error": err.Error()})
+			return
+		}
+		from = ts
+	}
+	if !to.After(from) || to.Sub(from) > maxReachabilityWindow {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "from must be before to and at most 366 days earlier", map[string]any{"from": from, "to": to})
+		return
+	}
+	resolution := q.Get("resolution")
+	switch resolution {
+	case "":
+		resolution = reachabilityResolutionHour
+		if to.Sub(from) <= defaultReachabilityWindow {
+			resolution = reachabilityResolutionRaw
+		}
+	case reachabilityResolutionRaw:
+		if to.Sub(from) > maxRawReachabilityWindow {
+			h.writeError(w, http.StatusBadRequest, "validation_failed", "raw resolution is limited to 7 days", map[string]any{"from": from, "to": to})
+			return
+		}
+	case reachabilityResolutionHour:
+	default:
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "resolution must be raw or hour", map[string]any{"resolution": resolution})
+		return
+	}
+
+	store, ok := h.monitorStore(w, r, id)
+	if !ok {
+		return
+	}
+	arg := sqlcgen.ListReachabilityParams{DeviceID: id, From: from, To: to}
+	resp := deviceReachabilityResponse{DeviceID: id, From: from, To: to, Resolution: resolution}
+	if resolution == reachabilityResolutionRaw {
+		samples, err := store.ListReachabilitySamples(r.Context(), arg)
+		if err != nil {
+			h.log.Error().Err(err).Str("id", id).Msg("list reachability samples failed")
+			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to fetch reachability", nil)
+			return
+		}
+		resp.Points = rawReachabilityPoints(samples)
+	} else {
+		// Include the rollup of the hour from falls in.
+		arg.From = from.Truncate(time.Hour)
+		rollups, err := store.ListReachabilityRollups(r.Context(), arg)
+		if err != nil {
+			h.log.Error().Err(err).Str("id", id).Msg("list reachability rollups failed")
+			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to fetch reachability", nil)
+			return
+		}
+		resp.Points = hourlyReachabilityPoints(rollups)
+	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
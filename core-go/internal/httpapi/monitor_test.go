@@ -0,0 +1,180 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+type fakeDeviceQueriesWithMonitor struct {
+	fakeDeviceQueries
+	monitors map[string]sqlcgen.DeviceMonitor
+	samples  []sqlcgen.ListReachabilityParams
+	rollups  []sqlcgen.ListReachabilityParams
+}
+
+func (f *fakeDeviceQueriesWithMonitor) GetDeviceMonitor(ctx context.Context, deviceID string) (sqlcgen.DeviceMonitor, error) {
+	m, ok := f.monitors[deviceID]
+	if !ok {
+		return sqlcgen.DeviceMonitor{}, pgx.ErrNoRows
+	}
+	return m, nil
+}
+
+func (f *fakeDeviceQueriesWithMonitor) UpsertDeviceMonitor(ctx context.Context, arg sqlcgen.UpsertDeviceMonitorParams) (sqlcgen.DeviceMonitor, error) {
+	m := sqlcgen.DeviceMonitor{
+		DeviceID: arg.DeviceID,
+		Source:   sqlcgen.MonitorSourceManual,
+		Enabled:  arg.Enabled,
+		Method:   arg.Method,
+		Port:     arg.Port,
+	}
+	f.monitors[arg.DeviceID] = m
+	return m, nil
+}
+
+func (f *fakeDeviceQueriesWithMonitor) DeleteDeviceMonitor(ctx context.Context, deviceID string) (int64, error) {
+	if _, ok := f.monitors[deviceID]; !ok {
+		return 0, nil
+	}
+	delete(f.monitors, deviceID)
+	return 1, nil
+}
+
+func (f *fakeDeviceQueriesWithMonitor) ListReachabilitySamples(ctx context.Context, arg sqlcgen.ListReachabilityParams) ([]sqlcgen.ReachabilitySample, error) {
+	f.samples = append(f.samples, arg)
+	rtt := 1.5
+	return []sqlcgen.ReachabilitySample{
+		{ProbedAt: arg.From.Add(time.Minute), Method: "icmp", Address: "10.0.0.1", Success: true, RTTMs: &rtt},
+		{ProbedAt: arg.From.Add(2 * time.Minute), Method: "icmp", Address: "10.0.0.1"},
+	}, nil
+}
+
+func (f *fakeDeviceQueriesWithMonitor) ListReachabilityRollups(ctx context.Context, arg sqlcgen.ListReachabilityParams) ([]sqlcgen.ReachabilityRollup, error) {
+	f.rollups = append(f.rollups, arg)
+	return []sqlcgen.ReachabilityRollup{{BucketStart: arg.From, Probes: 120, Failures: 3}}, nil
+}
+
+func newMonitorTestHandler(deviceID string) (*Handler, *fakeDeviceQueriesWithMonitor) {
+	store := &fakeDeviceQueriesWithMonitor{
+		fakeDeviceQueries: fakeDeviceQueries{
+			getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+				if id != deviceID {
+					return sqlcgen.Device{}, pgx.ErrNoRows
+				}
+				return sqlcgen.Device{ID: id}, nil
+			},
+		},
+		monitors: map[string]sqlcgen.DeviceMonitor{},
+	}
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = store
+	return h, store
+}
+
+func TestDevices_Monitor(t *testing.T) {
+	deviceID := "00000000-0000-0000-0000-000000000001"
+	h, store := newMonitorTestHandler(deviceID)
+	path := "/api/v1/devices/" + deviceID + "/monitor"
+
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 before the device is monitored, got %d", rr.Code)
+	}
+
+	cases := []struct {
+		name     string
+		path     string
+		body     string
+		wantCode int
+	}{
+		{name: "unknown method", path: path, body: `{"method":"udp"}`, wantCode: http.StatusBadRequest},
+		{name: "port with icmp", path: path, body: `{"method":"icmp","port":22}`, wantCode: http.StatusBadRequest},
+		{name: "port out of range", path: path, body: `{"method":"tcp","port":70000}`, wantCode: http.StatusBadRequest},
+		{name: "unknown device", path: "/api/v1/devices/00000000-0000-0000-0000-000000000009/monitor", body: `{}`, wantCode: http.StatusNotFound},
+		{name: "tcp", path: path, body: `{"method":"tcp","port":443}`, wantCode: http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodPut, tc.path, strings.NewReader(tc.body)))
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+		})
+	}
+	m, ok := store.monitors[deviceID]
+	if !ok || m.Method != "tcp" || m.Port == nil || *m.Port != 443 || !m.Enabled {
+		t.Fatalf("expected an enabled tcp/443 monitor, got %+v", store.monitors)
+	}
+
+	rr = httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+	if body := decodeBody(t, rr); rr.Code != http.StatusOK || body["source"] != "manual" || body["port"] != float64(443) {
+		t.Fatalf("unexpected monitor %d %v", rr.Code, body)
+	}
+
+	for _, want := range []int{http.StatusNoContent, http.StatusNotFound} {
+		rr = httptest.NewRecorder()
+		h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, path, nil))
+		if rr.Code != want {
+			t.Fatalf("expected %d, got %d: %s", want, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestDevices_Reachability(t *testing.T) {
+	deviceID := "00000000-0000-0000-0000-000000000001"
+	cases := []struct {
+		name           string
+		query          string
+		wantCode       int
+		wantResolution string
+	}{
+		{name: "default window is raw", query: "?to=2026-03-02T00:00:00Z", wantCode: http.StatusOK, wantResolution: "raw"},
+		{name: "long window is hourly", query: "?from=2026-02-01T00:30:00Z&to=2026-03-01T00:00:00Z", wantCode: http.StatusOK, wantResolution: "hour"},
+		{name: "explicit raw", query: "?from=2026-02-25T00:00:00Z&to=2026-03-01T00:00:00Z&resolution=raw", wantCode: http.StatusOK, wantResolution: "raw"},
+		{name: "raw too long", query: "?from=2026-02-01T00:00:00Z&to=2026-03-01T00:00:00Z&resolution=raw", wantCode: http.StatusBadRequest},
+		{name: "unknown resolution", query: "?resolution=minute", wantCode: http.StatusBadRequest},
+		{name: "inverted window", query: "?from=2026-03-02T00:00:00Z&to=2026-03-01T00:00:00Z", wantCode: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, store := newMonitorTestHandler(deviceID)
+			rr := httptest.NewRecorder()
+			h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/"+deviceID+"/reachability"+tc.query, nil))
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+			body := decodeBody(t, rr)
+			if body["resolution"] != tc.wantResolution {
+				t.Fatalf("expected %s resolution, got %v", tc.wantResolution, body["resolution"])
+			}
+			points := body["points"].([]any)
+			switch tc.wantResolution {
+			case "raw":
+				if len(store.samples) != 1 || len(points) != 2 || points[1].(map[string]any)["loss_percent"] != float64(100) {
+					t.Fatalf("unexpected raw points %v", points)
+				}
+			case "hour":
+				if len(store.rollups) != 1 || !store.rollups[0].From.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)) {
+					t.Fatalf("expected the rollup query to start on the hour, got %+v", store.rollups)
+				}
+				if p := points[0].(map[string]any); p["loss_percent"] != float64(2.5) || p["rtt_avg_ms"] != nil {
+					t.Fatalf("unexpected hourly point %v", p)
+				}
+			}
+		})
+	}
+}
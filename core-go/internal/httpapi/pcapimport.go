@@ -0,0 +1,135 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/discoveryworker"
+	"roller_hoops/core-go/internal/enrichment/pcap"
+)
+
+const (
+	// defaultPcapImportMaxBytes applies when Options.PcapImportMaxBytes is unset.
+	defaultPcapImportMaxBytes = 256 << 20
+	// pcapImportTimeout bounds the background decode of one accepted capture.
+	pcapImportTimeout = 30 * time.Minute
+)
+
+// handleImportPcap accepts a raw pcap/pcapng body, spools it to a temporary file and returns the
+// discovery run right away; the capture is decoded and applied in the background.
+func (h *Handler) handleImportPcap(w http.ResponseWriter, r *http.Request) {
+	if !h.ensureDiscoveryQueries(w) {
+		return
+	}
+	importer, ok := h.discovery.(discoveryworker.PcapImportQueries)
+	if !ok {
+		h.log.Error().Msg("pcap import queries missing")
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "pcap import not supported", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	in := discoveryworker.PcapImport{
+		Origin:           strings.TrimSpace(query.Get("origin")),
+		CaptureDeviceID:  strings.TrimSpace(query.Get("capture_device_id")),
+		CaptureInterface: strings.TrimSpace(query.Get("capture_interface")),
+		MACIdentity:      h.macIdentity,
+	}
+	if in.CaptureInterface != "" && in.CaptureDeviceID == "" {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "capture_interface requires capture_device_id", nil)
+		return
+	}
+	if in.CaptureDeviceID != "" {
+		if !h.ensureDeviceQueries(w) {
+			return
+		}
+		if _, err := h.devices.GetDevice(r.Context(), in.CaptureDeviceID); err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				h.writeError(w, http.StatusBadRequest, "validation_failed", "capture device not found", map[string]any{"capture_device_id": in.CaptureDeviceID})
+			case isInvalidUUID(err):
+				h.writeError(w, http.StatusBadRequest, "invalid_id", "capture_device_id is not a valid uuid", map[string]any{"capture_device_id": in.CaptureDeviceID})
+			default:
+				h.log.Error().Err(err).Str("id", in.CaptureDeviceID).Msg("fetch capture device failed")
+				h.writeError(w, http.StatusInternalServerError, "db_error", "failed to fetch capture device", nil)
+			}
+			return
+		}
+	}
+
+	limit := h.pcapImportMaxBytes
+	if limit <= 0 {
+		limit = defaultPcapImportMaxBytes
+	}
+	f, err := os.CreateTemp("", "roller-hoops-pcap-*")
+	if err != nil {
+		h.log.Error().Err(err).Msg("create pcap spool file failed")
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "failed to buffer capture", nil)
+		return
+	}
+	handedOff := false
+	defer func() {
+		if !handedOff {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	n, err := io.Copy(f, http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.writeError(w, http.StatusRequestEntityTooLarge, "validation_failed", "capture exceeds size limit", map[string]any{"max_bytes": limit})
+			return
+		}
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "failed to read capture", map[string]any{"error": err.Error()})
+		return
+	}
+	var magic [4]byte
+	if _, err := f.ReadAt(magic[:], 0); err != nil && !errors.Is(err, io.EOF) {
+		h.log.Error().Err(err).Msg("read pcap spool file failed")
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "failed to buffer capture", nil)
+		return
+	}
+	format, ok := pcap.Detect(magic[:])
+	if !ok || n < 4 {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "body is not a pcap or pcapng capture", nil)
+		return
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		h.log.Error().Err(err).Msg("rewind pcap spool file failed")
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "failed to buffer capture", nil)
+		return
+	}
+	in.Format = format
+	in.Bytes = n
+
+	run, err := discoveryworker.StartPcapImport(r.Context(), importer, in)
+	if err != nil {
+		h.log.Error().Err(err).Msg("failed to create pcap import run")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to start pcap import", nil)
+		return
+	}
+
+	handedOff = true
+	go func() {
+		defer func() {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), pcapImportTimeout)
+		defer cancel()
+		if _, err := discoveryworker.ImportPcap(ctx, importer, run.ID, f, in); err != nil {
+			h.log.Error().Err(err).Str("run_id", run.ID).Msg("pcap import failed")
+		}
+	}()
+
+	h.writeJSON(w, http.StatusAccepted, toDiscoveryRun(run))
+}
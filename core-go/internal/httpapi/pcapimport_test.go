@@ -0,0 +1,142 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// fakePcapImportDiscovery adds the link/interface writes a pcap import performs.
+type fakePcapImportDiscovery struct {
+	fakeScanImportDiscovery
+}
+
+func (f *fakePcapImportDiscovery) SetDeviceDisplayNameIfUnset(ctx context.Context, arg sqlcgen.SetDeviceDisplayNameIfUnsetParams) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakePcapImportDiscovery) UpsertInterfaceByName(ctx context.Context, arg sqlcgen.UpsertInterfaceByNameParams) (string, error) {
+	return "iface-" + arg.Name, nil
+}
+
+func (f *fakePcapImportDiscovery) UpsertLink(ctx context.Context, arg sqlcgen.UpsertLinkParams) error {
+	return nil
+}
+
+// arpReplyPcap is a one-packet capture: 00:11:22:33:44:55 is at 10.1.0.20.
+func arpReplyPcap() []byte {
+	frame := []byte{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x08, 0x06,
+		0x00, 0x01, 0x08, 0x00, 0x06, 0x04, 0x00, 0x02,
+		0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 10, 1, 0, 20,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	out := make([]byte, 40)
+	binary.LittleEndian.PutUint32(out[0:4], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(out[4:6], 2)
+	binary.LittleEndian.PutUint16(out[6:8], 4)
+	binary.LittleEndian.PutUint32(out[16:20], 65535)
+	binary.LittleEndian.PutUint32(out[20:24], 1)
+	binary.LittleEndian.PutUint32(out[24:28], 1772366400)
+	binary.LittleEndian.PutUint32(out[32:36], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(out[36:40], uint32(len(frame)))
+	return append(out, frame...)
+}
+
+func TestPcapImport_AcceptsCaptureAndCompletesInBackground(t *testing.T) {
+	now := time.Now()
+	done := make(chan sqlcgen.UpdateDiscoveryRunParams, 1)
+	fake := &fakePcapImportDiscovery{fakeScanImportDiscovery{
+		fakeDiscoveryQueries: fakeDiscoveryQueries{
+			insertFn: func(ctx context.Context, arg sqlcgen.InsertDiscoveryRunParams) (sqlcgen.DiscoveryRun, error) {
+				return sqlcgen.DiscoveryRun{ID: "run-pcap", Status: arg.Status, Stats: arg.Stats, StartedAt: now}, nil
+			},
+			updateFn: func(ctx context.Context, arg sqlcgen.UpdateDiscoveryRunParams) (sqlcgen.DiscoveryRun, error) {
+				done <- arg
+				return sqlcgen.DiscoveryRun{ID: arg.ID, Status: arg.Status, Stats: arg.Stats, StartedAt: now, CompletedAt: arg.CompletedAt}, nil
+			},
+		},
+	}}
+	h := NewHandler(NewLogger("debug"), nil)
+	h.discovery = fake
+	h.devices = fakeDeviceQueries{getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+		return sqlcgen.Device{ID: id}, nil
+	}}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/inventory/pcap-import?origin=site-7&capture_device_id=dev-sw&capture_interface=Gi1/0/48", bytes.NewReader(arpReplyPcap()))
+	req.Header.Set("Content-Type", "application/vnd.tcpdump.pcap")
+	h.Router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+	got := decodeBody(t, rr)
+	stats, _ := got["stats"].(map[string]any)
+	imported, _ := stats["import"].(map[string]any)
+	if got["id"] != "run-pcap" || got["status"] != "running" || stats["method"] != "pcap" || imported["format"] != "pcap" || imported["capture_device_id"] != "dev-sw" {
+		t.Fatalf("unexpected run %v", got)
+	}
+
+	select {
+	case final := <-done:
+		if final.Status != "succeeded" {
+			t.Fatalf("unexpected final update %+v", final)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("pcap import did not complete")
+	}
+	if len(fake.ipObservations) != 1 || fake.ipObservations[0].RunID != "run-pcap" || fake.ipObservations[0].IP != "10.1.0.20" {
+		t.Fatalf("unexpected observations %+v", fake.ipObservations)
+	}
+}
+
+func TestPcapImport_Validation(t *testing.T) {
+	cases := []struct {
+		name     string
+		query    string
+		body     []byte
+		maxBytes int64
+		wantCode int
+	}{
+		{name: "not a capture", body: []byte("<?xml version=\"1.0\"?><nmaprun/>"), wantCode: http.StatusBadRequest},
+		{name: "empty body", body: nil, wantCode: http.StatusBadRequest},
+		{name: "too large", body: arpReplyPcap(), maxBytes: 32, wantCode: http.StatusRequestEntityTooLarge},
+		{name: "interface without device", query: "?capture_interface=eth0", body: arpReplyPcap(), wantCode: http.StatusBadRequest},
+		{name: "unknown capture device", query: "?capture_device_id=00000000-0000-0000-0000-000000000000", body: arpReplyPcap(), wantCode: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandlerWithOptions(NewLogger("debug"), nil, nil, Options{PcapImportMaxBytes: tc.maxBytes})
+			h.discovery = &fakePcapImportDiscovery{fakeScanImportDiscovery{
+				fakeDiscoveryQueries: fakeDiscoveryQueries{
+					insertFn: func(ctx context.Context, arg sqlcgen.InsertDiscoveryRunParams) (sqlcgen.DiscoveryRun, error) {
+						t.Fatalf("no run should be created")
+						return sqlcgen.DiscoveryRun{}, nil
+					},
+				},
+			}}
+			h.devices = fakeDeviceQueries{getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+				return sqlcgen.Device{}, pgx.ErrNoRows
+			}}
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/inventory/pcap-import"+tc.query, bytes.NewReader(tc.body))
+			h.Router().ServeHTTP(rr, req)
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if got := decodeBody(t, rr); got["error"].(map[string]any)["code"] != "validation_failed" {
+				t.Fatalf("unexpected error body %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+type poweredDevice struct {
+	DeviceID        string    `json:"device_id"`
+	DisplayName     *string   `json:"display_name,omitempty"`
+	InterfaceID     string    `json:"interface_id"`
+	InterfaceName   *string   `json:"interface_name,omitempty"`
+	AdminEnabled    *bool     `json:"admin_enabled,omitempty"`
+	DetectionStatus string    `json:"detection_status"`
+	PowerClass      *string   `json:"power_class,omitempty"`
+	PowerMW         *int32    `json:"power_mw,omitempty"`
+	ObservedAt      time.Time `json:"observed_at"`
+}
+
+// handleListPoweredDevices answers "which devices are powered by switch X": one row per PoE port of the
+// device that is delivering power to a linked device.
+func (h *Handler) handleListPoweredDevices(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !h.ensureDeviceQueries(w) {
+		return
+	}
+	lister, ok := h.devices.(interface {
+		ListPoweredDevices(ctx context.Context, pseDeviceID string) ([]sqlcgen.PoEPowerPath, error)
+	})
+	if !ok {
+		h.log.Error().Msg("poe query missing")
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "poe power mapping not supported", nil)
+		return
+	}
+	if _, err := h.devices.GetDevice(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			h.writeError(w, http.StatusNotFound, "not_found", "device not found", map[string]any{"id": id})
+		case isInvalidUUID(err):
+			h.writeError(w, http.StatusBadRequest, "invalid_id", "device id is not a valid uuid", map[string]any{"id": id})
+		default:
+			h.log.Error().Err(err).Str("id", id).Msg("fetch device before powered devices failed")
+			h.writeError(w, http.StatusInternalServerError, "db_error", "failed to list powered devices", nil)
+		}
+		return
+	}
+
+	rows, err := lister.ListPoweredDevices(r.Context(), id)
+	if err != nil {
+		h.log.Error().Err(err).Str("device_id", id).Msg("list powered devices failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to list powered devices", nil)
+		return
+	}
+	out := make([]poweredDevice, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, poweredDevice{
+			DeviceID:        row.PoweredDeviceID,
+			DisplayName:     row.PoweredDisplayName,
+			InterfaceID:     row.InterfaceID,
+			InterfaceName:   row.InterfaceName,
+			AdminEnabled:    row.AdminEnabled,
+			DetectionStatus: row.DetectionStatus,
+			PowerClass:      row.PowerClass,
+			PowerMW:         row.PowerMW,
+			ObservedAt:      row.ObservedAt,
+		})
+	}
+	h.writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,175 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+type fakeDeviceQueriesWithPoE struct {
+	fakeDeviceQueriesWithPhysical
+	listPoweredFn func(ctx context.Context, pseDeviceID string) ([]sqlcgen.PoEPowerPath, error)
+	listSourcesFn func(ctx context.Context, poweredDeviceID string) ([]sqlcgen.PoEPowerPath, error)
+}
+
+func (f fakeDeviceQueriesWithPoE) ListPoweredDevices(ctx context.Context, pseDeviceID string) ([]sqlcgen.PoEPowerPath, error) {
+	if f.listPoweredFn == nil {
+		return nil, nil
+	}
+	return f.listPoweredFn(ctx, pseDeviceID)
+}
+
+func (f fakeDeviceQueriesWithPoE) ListPowerSources(ctx context.Context, poweredDeviceID string) ([]sqlcgen.PoEPowerPath, error) {
+	if f.listSourcesFn == nil {
+		return nil, nil
+	}
+	return f.listSourcesFn(ctx, poweredDeviceID)
+}
+
+func TestPoweredDevices_List(t *testing.T) {
+	switchID := "00000000-0000-0000-0000-000000000011"
+	cameraID := "00000000-0000-0000-0000-000000000101"
+	str := func(s string) *string { return &s }
+	mw := int32(6400)
+
+	cases := []struct {
+		name     string
+		getErr   error
+		wantCode int
+		wantRows int
+	}{
+		{name: "lists powered devices", wantCode: http.StatusOK, wantRows: 1},
+		{name: "unknown device", getErr: pgx.ErrNoRows, wantCode: http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(NewLogger("debug"), nil)
+			h.devices = fakeDeviceQueriesWithPoE{
+				fakeDeviceQueriesWithPhysical: fakeDeviceQueriesWithPhysical{
+					fakeDeviceQueries: fakeDeviceQueries{
+						getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+							return sqlcgen.Device{ID: id}, tc.getErr
+						},
+					},
+				},
+				listPoweredFn: func(ctx context.Context, pseDeviceID string) ([]sqlcgen.PoEPowerPath, error) {
+					if pseDeviceID != switchID {
+						t.Fatalf("unexpected switch id %q", pseDeviceID)
+					}
+					return []sqlcgen.PoEPowerPath{{
+						PSEDeviceID:        switchID,
+						PoweredDeviceID:    cameraID,
+						PoweredDisplayName: str("camera-lobby"),
+						InterfaceID:        "if-1",
+						InterfaceName:      str("Gi1/0/1"),
+						DetectionStatus:    "delivering_power",
+						PowerClass:         str("class2"),
+						PowerMW:            &mw,
+						ObservedAt:         time.Now().UTC(),
+					}}, nil
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/devices/"+switchID+"/powered-devices", nil)
+			h.Router().ServeHTTP(rr, req)
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+			var rows []any
+			if err := json.Unmarshal(rr.Body.Bytes(), &rows); err != nil {
+				t.Fatalf("failed to decode powered devices: %v", err)
+			}
+			if len(rows) != tc.wantRows {
+				t.Fatalf("expected %d rows, got %v", tc.wantRows, rows)
+			}
+			row := rows[0].(map[string]any)
+			if row["device_id"] != cameraID || row["interface_name"] != "Gi1/0/1" || row["power_mw"] != float64(6400) {
+				t.Fatalf("unexpected row %v", row)
+			}
+		})
+	}
+}
+
+func TestMapProjection_DeviceFocus_PhysicalShowsPoE(t *testing.T) {
+	switchID := "00000000-0000-0000-0000-000000000011"
+	cameraID := "00000000-0000-0000-0000-000000000101"
+	apID := "00000000-0000-0000-0000-000000000102"
+	str := func(s string) *string { return &s }
+	cameraMW, apMW := int32(6400), int32(12600)
+
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueriesWithPoE{
+		fakeDeviceQueriesWithPhysical: fakeDeviceQueriesWithPhysical{
+			fakeDeviceQueries: fakeDeviceQueries{
+				getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+					return sqlcgen.Device{ID: switchID}, nil
+				},
+			},
+			listLinkPeersFn: func(ctx context.Context, deviceID string, limit int32) ([]sqlcgen.MapDeviceLinkPeer, error) {
+				return []sqlcgen.MapDeviceLinkPeer{
+					{LinkID: "link-1", LinkKey: "lldp:1", PeerDeviceID: cameraID, PeerDisplayName: str("camera-lobby"), Source: "lldp"},
+					{LinkID: "link-2", LinkKey: "lldp:2", PeerDeviceID: apID, PeerDisplayName: str("ap-2f"), Source: "lldp"},
+				}, nil
+			},
+		},
+		listPoweredFn: func(ctx context.Context, pseDeviceID string) ([]sqlcgen.PoEPowerPath, error) {
+			return []sqlcgen.PoEPowerPath{
+				{PSEDeviceID: switchID, PoweredDeviceID: cameraID, PoweredDisplayName: str("camera-lobby"), InterfaceID: "if-1", InterfaceName: str("Gi1/0/1"), DetectionStatus: "delivering_power", PowerMW: &cameraMW},
+				{PSEDeviceID: switchID, PoweredDeviceID: apID, PoweredDisplayName: str("ap-2f"), InterfaceID: "if-2", InterfaceName: str("Gi1/0/2"), DetectionStatus: "delivering_power", PowerMW: &apMW},
+			}, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/map/physical?focusType=device&focusId="+switchID, nil)
+	h.Router().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	body := decodeBody(t, rr)
+	for _, n := range body["nodes"].([]any) {
+		node := n.(map[string]any)
+		meta, _ := node["meta"].(map[string]any)
+		poe, hasPoE := meta["poe"].(map[string]any)
+		if node["id"] == switchID {
+			if hasPoE {
+				t.Fatalf("did not expect poe meta on the switch: %v", node)
+			}
+			continue
+		}
+		if !hasPoE || poe["pse_device_id"] != switchID || poe["detection_status"] != "delivering_power" {
+			t.Fatalf("expected poe meta on %v", node)
+		}
+	}
+
+	inspector := body["inspector"].(map[string]any)
+	status := map[string]string{}
+	for _, f := range inspector["status"].([]any) {
+		field := f.(map[string]any)
+		status[field["label"].(string)] = field["value"].(string)
+	}
+	if status["PoE powered devices"] != "2" || status["PoE power drawn"] != "19.0 W" {
+		t.Fatalf("unexpected inspector status %v", status)
+	}
+	found := false
+	for _, r := range inspector["relationships"].([]any) {
+		if rel := r.(map[string]any); rel["label"] == "Open powered device camera-lobby" && rel["focus_id"] == cameraID {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected powered device relationship, got %v", inspector["relationships"])
+	}
+}
@@ -0,0 +1,217 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"roller_hoops/core-go/internal/retention"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+type retentionQueries interface {
+	ListRetentionPolicies(ctx context.Context) ([]sqlcgen.RetentionPolicy, error)
+	UpsertRetentionPolicy(ctx context.Context, arg sqlcgen.UpsertRetentionPolicyParams) (sqlcgen.RetentionPolicy, error)
+	DeleteRetentionPolicy(ctx context.Context, tableName string) (int64, error)
+	CountRetentionRows(ctx context.Context, arg sqlcgen.CountRetentionRowsParams) (sqlcgen.RetentionCounts, error)
+}
+
+type retentionPolicy struct {
+	Table         string     `json:"table"`
+	KeepRawDays   int        `json:"keep_raw_days"`
+	KeepDailyDays int        `json:"keep_daily_days"`
+	Source        string     `json:"source"`
+	Enabled       bool       `json:"enabled"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+}
+
+type retentionPoliciesResponse struct {
+	Policies []retentionPolicy `json:"policies"`
+}
+
+type retentionPolicyBody struct {
+	KeepRawDays   *int `json:"keep_raw_days"`
+	KeepDailyDays int  `json:"keep_daily_days"`
+}
+
+type retentionReportTable struct {
+	retentionPolicy
+	DropBefore *time.Time `json:"drop_before,omitempty"`
+	ThinBefore *time.Time `json:"thin_before,omitempty"`
+	TotalRows  int64      `json:"total_rows"`
+	RowsToDrop int64      `json:"rows_to_drop"`
+	RowsToThin int64      `json:"rows_to_thin"`
+}
+
+type retentionReportResponse struct {
+	GeneratedAt time.Time              `json:"generated_at"`
+	Tables      []retentionReportTable `json:"tables"`
+}
+
+func toRetentionPolicy(p retention.Policy) retentionPolicy {
+	out := retentionPolicy{
+		Table:         p.Table,
+		KeepRawDays:   p.KeepRawDays,
+		KeepDailyDays: p.KeepDailyDays,
+		Source:        p.Source,
+		Enabled:       p.Enabled(),
+	}
+	if !p.UpdatedAt.IsZero() {
+		updatedAt := p.UpdatedAt
+		out.UpdatedAt = &updatedAt
+	}
+	return out
+}
+
+// retentionStore returns the retention queries, writing an error when they are unavailable.
+func (h *Handler) retentionStore(w http.ResponseWriter) (retentionQueries, bool) {
+	if !h.ensureDeviceQueries(w) {
+		return nil, false
+	}
+	store, ok := h.devices.(retentionQueries)
+	if !ok {
+		h.log.Error().Msg("retention queries missing")
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "retention not supported", nil)
+		return nil, false
+	}
+	return store, true
+}
+
+// effectiveRetentionPolicies merges the stored overrides over the env defaults, writing an error on failure.
+func (h *Handler) effectiveRetentionPolicies(w http.ResponseWriter, r *http.Request, store retentionQueries) ([]retention.Policy, bool) {
+	overrides, err := store.ListRetentionPolicies(r.Context())
+	if err != nil {
+		h.log.Error().Err(err).Msg("list retention policies failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to list retention policies", nil)
+		return nil, false
+	}
+	return retention.Effective(h.retentionDefaults, overrides), true
+}
+
+func (h *Handler) writeRetentionPolicy(w http.ResponseWriter, r *http.Request, store retentionQueries, table string) {
+	policies, ok := h.effectiveRetentionPolicies(w, r, store)
+	if !ok {
+		return
+	}
+	for _, p := range policies {
+		if p.Table == table {
+			h.writeJSON(w, http.StatusOK, toRetentionPolicy(p))
+			return
+		}
+	}
+	h.writeError(w, http.StatusNotFound, "not_found", "retention table not found", map[string]any{"table": table})
+}
+
+func (h *Handler) handleListRetentionPolicies(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.retentionStore(w)
+	if !ok {
+		return
+	}
+	policies, ok := h.effectiveRetentionPolicies(w, r, store)
+	if !ok {
+		return
+	}
+	out := make([]retentionPolicy, 0, len(policies))
+	for _, p := range policies {
+		out = append(out, toRetentionPolicy(p))
+	}
+	h.writeJSON(w, http.StatusOK, retentionPoliciesResponse{Policies: out})
+}
+
+// handlePutRetentionPolicy stores an API override for one table; it takes precedence over the env default.
+func (h *Handler) handlePutRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	table := chi.URLParam(r, "table")
+	if !sqlcgen.RetentionTableKnown(table) {
+		h.writeError(w, http.StatusNotFound, "not_found", "retention table not found", map[string]any{"table": table})
+		return
+	}
+	var req retentionPolicyBody
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid json body", map[string]any{"error": err.Error()})
+		return
+	}
+	if req.KeepRawDays == nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "keep_raw_days is required", nil)
+		return
+	}
+	policy := retention.Policy{Table: table, KeepRawDays: *req.KeepRawDays, KeepDailyDays: req.KeepDailyDays}
+	if err := policy.Validate(); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid retention policy", map[string]any{"error": err.Error()})
+		return
+	}
+	store, ok := h.retentionStore(w)
+	if !ok {
+		return
+	}
+	row, err := store.UpsertRetentionPolicy(r.Context(), sqlcgen.UpsertRetentionPolicyParams{
+		TableName:     table,
+		KeepRawDays:   int32(policy.KeepRawDays),
+		KeepDailyDays: int32(policy.KeepDailyDays),
+	})
+	if err != nil {
+		h.log.Error().Err(err).Str("table", table).Msg("upsert retention policy failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to save retention policy", nil)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, toRetentionPolicy(retention.Policy{
+		Table:         row.TableName,
+		KeepRawDays:   int(row.KeepRawDays),
+		KeepDailyDays: int(row.KeepDailyDays),
+		Source:        retention.SourceAPI,
+		UpdatedAt:     row.UpdatedAt,
+	}))
+}
+
+// handleDeleteRetentionPolicy drops the API override so the table reverts to its env default.
+func (h *Handler) handleDeleteRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	table := chi.URLParam(r, "table")
+	if !sqlcgen.RetentionTableKnown(table) {
+		h.writeError(w, http.StatusNotFound, "not_found", "retention table not found", map[string]any{"table": table})
+		return
+	}
+	store, ok := h.retentionStore(w)
+	if !ok {
+		return
+	}
+	if _, err := store.DeleteRetentionPolicy(r.Context(), table); err != nil {
+		h.log.Error().Err(err).Str("table", table).Msg("delete retention policy failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to delete retention policy", nil)
+		return
+	}
+	h.writeRetentionPolicy(w, r, store, table)
+}
+
+// handleGetRetentionReport is a dry run: it counts what the retention job would delete right now.
+func (h *Handler) handleGetRetentionReport(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.retentionStore(w)
+	if !ok {
+		return
+	}
+	now := time.Now().UTC()
+	reports, err := retention.Report(r.Context(), store, h.retentionDefaults, now)
+	if err != nil {
+		h.log.Error().Err(err).Msg("retention report failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to build retention report", nil)
+		return
+	}
+	out := retentionReportResponse{GeneratedAt: now, Tables: make([]retentionReportTable, 0, len(reports))}
+	for _, rep := range reports {
+		t := retentionReportTable{
+			retentionPolicy: toRetentionPolicy(rep.Policy),
+			TotalRows:       rep.Counts.Total,
+			RowsToDrop:      rep.Counts.ToDrop,
+			RowsToThin:      rep.Counts.ToThin,
+		}
+		if rep.Cutoffs != nil {
+			drop, thin := rep.Cutoffs.Drop, rep.Cutoffs.Thin
+			t.DropBefore = &drop
+			if thin.After(drop) {
+				t.ThinBefore = &thin
+			}
+		}
+		out.Tables = append(out.Tables, t)
+	}
+	h.writeJSON(w, http.StatusOK, out)
+}
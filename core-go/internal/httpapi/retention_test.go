@@ -0,0 +1,156 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"roller_hoops/core-go/internal/retention"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+type fakeDeviceQueriesWithRetention struct {
+	fakeDeviceQueries
+	overrides map[string]sqlcgen.RetentionPolicy
+	counts    []sqlcgen.CountRetentionRowsParams
+}
+
+func (f *fakeDeviceQueriesWithRetention) ListRetentionPolicies(ctx context.Context) ([]sqlcgen.RetentionPolicy, error) {
+	var out []sqlcgen.RetentionPolicy
+	for _, t := range retention.Tables {
+		if p, ok := f.overrides[t]; ok {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeDeviceQueriesWithRetention) UpsertRetentionPolicy(ctx context.Context, arg sqlcgen.UpsertRetentionPolicyParams) (sqlcgen.RetentionPolicy, error) {
+	p := sqlcgen.RetentionPolicy{
+		TableName:     arg.TableName,
+		KeepRawDays:   arg.KeepRawDays,
+		KeepDailyDays: arg.KeepDailyDays,
+		UpdatedAt:     time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	f.overrides[arg.TableName] = p
+	return p, nil
+}
+
+func (f *fakeDeviceQueriesWithRetention) DeleteRetentionPolicy(ctx context.Context, tableName string) (int64, error) {
+	if _, ok := f.overrides[tableName]; !ok {
+		return 0, nil
+	}
+	delete(f.overrides, tableName)
+	return 1, nil
+}
+
+func (f *fakeDeviceQueriesWithRetention) CountRetentionRows(ctx context.Context, arg sqlcgen.CountRetentionRowsParams) (sqlcgen.RetentionCounts, error) {
+	f.counts = append(f.counts, arg)
+	if arg.DropBefore.IsZero() {
+		return sqlcgen.RetentionCounts{Total: 10}, nil
+	}
+	return sqlcgen.RetentionCounts{Total: 100, ToDrop: 40, ToThin: 25}, nil
+}
+
+func newRetentionTestHandler() (*Handler, *fakeDeviceQueriesWithRetention) {
+	store := &fakeDeviceQueriesWithRetention{overrides: map[string]sqlcgen.RetentionPolicy{}}
+	h := NewHandlerWithOptions(NewLogger("debug"), nil, nil, Options{RetentionDefaults: []retention.Policy{
+		{Table: sqlcgen.RetentionTableIPObservations, KeepRawDays: 30, KeepDailyDays: 365},
+		{Table: sqlcgen.RetentionTableDiscoveryRunLogs, KeepRawDays: 90},
+	}})
+	h.devices = store
+	return h, store
+}
+
+func TestRetention_PutAndDeletePolicy(t *testing.T) {
+	h, store := newRetentionTestHandler()
+
+	cases := []struct {
+		name     string
+		method   string
+		table    string
+		body     string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "daily tier on run logs", method: http.MethodPut, table: "discovery_run_logs", body: `{"keep_raw_days":7,"keep_daily_days":30}`, wantCode: http.StatusBadRequest, wantErr: "validation_failed"},
+		{name: "daily below raw", method: http.MethodPut, table: "ip_observations", body: `{"keep_raw_days":30,"keep_daily_days":10}`, wantCode: http.StatusBadRequest, wantErr: "validation_failed"},
+		{name: "missing raw days", method: http.MethodPut, table: "ip_observations", body: `{"keep_daily_days":10}`, wantCode: http.StatusBadRequest, wantErr: "validation_failed"},
+		{name: "unknown table", method: http.MethodPut, table: "devices", body: `{"keep_raw_days":1}`, wantCode: http.StatusNotFound, wantErr: "not_found"},
+		{name: "override", method: http.MethodPut, table: "discovery_run_logs", body: `{"keep_raw_days":14}`, wantCode: http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, "/api/v1/retention/policies/"+tc.table, strings.NewReader(tc.body))
+			h.Router().ServeHTTP(rr, req)
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if tc.wantErr != "" {
+				if code := decodeBody(t, rr)["error"].(map[string]any)["code"]; code != tc.wantErr {
+					t.Fatalf("expected error code %q, got %v", tc.wantErr, code)
+				}
+			}
+		})
+	}
+	if len(store.overrides) != 1 || store.overrides["discovery_run_logs"].KeepRawDays != 14 {
+		t.Fatalf("expected only the run log override to be stored, got %+v", store.overrides)
+	}
+
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/retention/policies", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	policies := decodeBody(t, rr)["policies"].([]any)
+	if len(policies) != 3 {
+		t.Fatalf("expected 3 policies, got %v", policies)
+	}
+	logs := policies[2].(map[string]any)
+	if logs["source"] != "api" || logs["keep_raw_days"] != float64(14) {
+		t.Fatalf("expected run log override, got %v", logs)
+	}
+	if mac := policies[1].(map[string]any); mac["enabled"] != false || mac["source"] != "env" {
+		t.Fatalf("expected mac observations to be kept forever, got %v", mac)
+	}
+
+	rr = httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/api/v1/retention/policies/discovery_run_logs", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if body := decodeBody(t, rr); body["source"] != "env" || body["keep_raw_days"] != float64(90) {
+		t.Fatalf("expected revert to env default, got %v", body)
+	}
+}
+
+func TestRetention_Report(t *testing.T) {
+	h, store := newRetentionTestHandler()
+
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/retention/report", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	tables := decodeBody(t, rr)["tables"].([]any)
+	if len(tables) != 3 || len(store.counts) != 3 {
+		t.Fatalf("expected 3 tables counted, got %v (%d counts)", tables, len(store.counts))
+	}
+
+	ip := tables[0].(map[string]any)
+	if ip["rows_to_drop"] != float64(40) || ip["rows_to_thin"] != float64(25) || ip["thin_before"] == nil || ip["drop_before"] == nil {
+		t.Fatalf("unexpected ip observations report: %v", ip)
+	}
+	mac := tables[1].(map[string]any)
+	if mac["enabled"] != false || mac["total_rows"] != float64(10) || mac["rows_to_drop"] != float64(0) || mac["drop_before"] != nil {
+		t.Fatalf("expected disabled mac observations to only report size, got %v", mac)
+	}
+	logs := tables[2].(map[string]any)
+	if logs["drop_before"] == nil || logs["thin_before"] != nil {
+		t.Fatalf("expected run logs to have a drop cutoff only, got %v", logs)
+	}
+}
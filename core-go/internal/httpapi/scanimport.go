@@ -0,0 +1,84 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"roller_hoops/core-go/internal/discoveryworker"
+)
+
+// scanImportMaxBodyBytes caps uploaded scanner output; a /16 nmap -sV run stays well below this.
+const scanImportMaxBodyBytes = 32 << 20
+
+type scanImportRequest struct {
+	Format  string  `json:"format"`
+	Content string  `json:"content"`
+	Scope   *string `json:"scope,omitempty"`
+	Origin  *string `json:"origin,omitempty"`
+}
+
+func (h *Handler) handleImportScan(w http.ResponseWriter, r *http.Request) {
+	if !h.ensureDiscoveryQueries(w) {
+		return
+	}
+	importer, ok := h.discovery.(discoveryworker.ScanImportQueries)
+	if !ok {
+		h.log.Error().Msg("scan import queries missing")
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "scan import not supported", nil)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, scanImportMaxBodyBytes)
+	var req scanImportRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid json body", map[string]any{"error": err.Error()})
+		return
+	}
+
+	format := strings.ToLower(strings.TrimSpace(req.Format))
+	switch format {
+	case discoveryworker.ScanImportFormatNmapXML, discoveryworker.ScanImportFormatMasscanJSON, discoveryworker.ScanImportFormatARPScan:
+	default:
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "format must be one of: nmap_xml, masscan_json, arp_scan", nil)
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "missing content", nil)
+		return
+	}
+
+	scope, err := validateAndCanonicalizeScope(req.Scope)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid scope", map[string]any{"error": err.Error()})
+		return
+	}
+
+	origin := ""
+	if req.Origin != nil {
+		origin = strings.TrimSpace(*req.Origin)
+	}
+
+	run, err := discoveryworker.ImportScan(r.Context(), importer, discoveryworker.ScanImport{
+		Format:      format,
+		Content:     []byte(req.Content),
+		Scope:       scope,
+		Origin:      origin,
+		MACIdentity: h.macIdentity,
+	})
+	if err != nil {
+		if errors.Is(err, discoveryworker.ErrInvalidScanImport) {
+			h.writeError(w, http.StatusBadRequest, "validation_failed", err.Error(), map[string]any{"format": format})
+			return
+		}
+		h.log.Error().Err(err).Str("format", format).Msg("scan import failed")
+		details := map[string]any{}
+		if run.ID != "" {
+			details["run_id"] = run.ID
+		}
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to import scan results", details)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, toDiscoveryRun(run))
+}
@@ -0,0 +1,170 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// fakeScanImportDiscovery extends the discovery fake with the device writes a scan import performs.
+type fakeScanImportDiscovery struct {
+	fakeDiscoveryQueries
+	ipObservations []sqlcgen.InsertIPObservationParams
+}
+
+func (f *fakeScanImportDiscovery) CreateDevice(ctx context.Context, displayName *string) (sqlcgen.Device, error) {
+	return sqlcgen.Device{ID: "dev-imported"}, nil
+}
+
+func (f *fakeScanImportDiscovery) FindDeviceIDByMAC(ctx context.Context, mac string) (string, error) {
+	return "", pgx.ErrNoRows
+}
+
+func (f *fakeScanImportDiscovery) FindDeviceIDByIP(ctx context.Context, ip string) (string, error) {
+	return "", pgx.ErrNoRows
+}
+
+func (f *fakeScanImportDiscovery) FindDeviceIDByClientID(ctx context.Context, clientID string) (string, error) {
+	return "", pgx.ErrNoRows
+}
+
+func (f *fakeScanImportDiscovery) FindDeviceIDByHostName(ctx context.Context, name string) (string, error) {
+	return "", pgx.ErrNoRows
+}
+
+func (f *fakeScanImportDiscovery) FindRotatingDeviceIDByIP(ctx context.Context, arg sqlcgen.FindRotatingDeviceIDByIPParams) (string, error) {
+	return "", pgx.ErrNoRows
+}
+
+func (f *fakeScanImportDiscovery) UpsertDeviceClientID(ctx context.Context, arg sqlcgen.UpsertDeviceClientIDParams) error {
+	return nil
+}
+
+func (f *fakeScanImportDiscovery) UnarchiveReseenDevice(ctx context.Context, arg sqlcgen.UnarchiveReseenDeviceParams) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeScanImportDiscovery) UpsertImportedDeviceIP(ctx context.Context, arg sqlcgen.UpsertDeviceIPParams) error {
+	return nil
+}
+
+func (f *fakeScanImportDiscovery) UpsertImportedDeviceMAC(ctx context.Context, arg sqlcgen.UpsertDeviceMACParams) error {
+	return nil
+}
+
+func (f *fakeScanImportDiscovery) InsertIPObservation(ctx context.Context, arg sqlcgen.InsertIPObservationParams) error {
+	f.ipObservations = append(f.ipObservations, arg)
+	return nil
+}
+
+func (f *fakeScanImportDiscovery) InsertMACObservation(ctx context.Context, arg sqlcgen.InsertMACObservationParams) error {
+	return nil
+}
+
+func (f *fakeScanImportDiscovery) InsertDeviceNameCandidate(ctx context.Context, arg sqlcgen.InsertDeviceNameCandidateParams) error {
+	return nil
+}
+
+func (f *fakeScanImportDiscovery) UpsertServiceFromScan(ctx context.Context, arg sqlcgen.UpsertServiceFromScanParams) error {
+	return nil
+}
+
+func (f *fakeScanImportDiscovery) UpsertDeviceOSGuess(ctx context.Context, arg sqlcgen.UpsertDeviceOSGuessParams) error {
+	return nil
+}
+
+func (f *fakeScanImportDiscovery) DeleteDeviceOSGuessesAboveRank(ctx context.Context, arg sqlcgen.DeleteDeviceOSGuessesAboveRankParams) error {
+	return nil
+}
+
+func (f *fakeScanImportDiscovery) UpsertDeviceTag(ctx context.Context, arg sqlcgen.UpsertDeviceTagParams) error {
+	return nil
+}
+
+func (f *fakeScanImportDiscovery) RecordDeviceStateSnapshots(ctx context.Context, arg sqlcgen.RecordDeviceStateSnapshotsParams) (int64, error) {
+	return 0, nil
+}
+
+func TestScanImport_CreatesCompletedRun(t *testing.T) {
+	now := time.Now()
+	fake := &fakeScanImportDiscovery{
+		fakeDiscoveryQueries: fakeDiscoveryQueries{
+			insertFn: func(ctx context.Context, arg sqlcgen.InsertDiscoveryRunParams) (sqlcgen.DiscoveryRun, error) {
+				return sqlcgen.DiscoveryRun{ID: "run-import", Status: arg.Status, Scope: arg.Scope, Stats: arg.Stats, StartedAt: now}, nil
+			},
+			updateFn: func(ctx context.Context, arg sqlcgen.UpdateDiscoveryRunParams) (sqlcgen.DiscoveryRun, error) {
+				return sqlcgen.DiscoveryRun{ID: arg.ID, Status: arg.Status, Stats: arg.Stats, StartedAt: now, CompletedAt: arg.CompletedAt}, nil
+			},
+		},
+	}
+	h := NewHandler(NewLogger("debug"), nil)
+	h.discovery = fake
+
+	body, _ := json.Marshal(map[string]any{
+		"format":  "arp_scan",
+		"content": "10.60.0.1\t00:11:22:33:44:55\tAcme\n10.60.0.2\t00:11:22:33:44:66\tAcme\n",
+		"origin":  "jump-host-b",
+	})
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/inventory/scan-import", strings.NewReader(string(body)))
+	h.Router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	got := decodeBody(t, rr)
+	if got["id"] != "run-import" || got["status"] != "succeeded" {
+		t.Fatalf("unexpected run %v", got)
+	}
+	stats, _ := got["stats"].(map[string]any)
+	imported, _ := stats["import"].(map[string]any)
+	if stats["method"] != "import" || imported["format"] != "arp_scan" || imported["origin"] != "jump-host-b" {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+	if len(fake.ipObservations) != 2 || fake.ipObservations[0].RunID != "run-import" {
+		t.Fatalf("unexpected observations %+v", fake.ipObservations)
+	}
+}
+
+func TestScanImport_Validation(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+	}{
+		{name: "unknown format", body: `{"format":"zmap","content":"10.0.0.1"}`},
+		{name: "missing content", body: `{"format":"nmap_xml","content":"  "}`},
+		{name: "invalid scope", body: `{"format":"arp_scan","content":"10.0.0.1\t00:11:22:33:44:55","scope":"lan"}`},
+		{name: "unparseable content", body: `{"format":"masscan_json","content":"not json"}`},
+		{name: "unknown field", body: `{"format":"arp_scan","content":"x","extra":true}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(NewLogger("debug"), nil)
+			h.discovery = &fakeScanImportDiscovery{
+				fakeDiscoveryQueries: fakeDiscoveryQueries{
+					insertFn: func(ctx context.Context, arg sqlcgen.InsertDiscoveryRunParams) (sqlcgen.DiscoveryRun, error) {
+						t.Fatalf("no run should be created")
+						return sqlcgen.DiscoveryRun{}, nil
+					},
+				},
+			}
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/inventory/scan-import", strings.NewReader(tc.body))
+			h.Router().ServeHTTP(rr, req)
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
+			}
+			if got := decodeBody(t, rr); got["error"].(map[string]any)["code"] != "validation_failed" {
+				t.Fatalf("unexpected error body %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"roller_hoops/core-go/internal/enrichment/snmp"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+const (
+	maxSNMPProfileItems    = 64
+	maxSNMPProfileNameLen  = 100
+	maxSNMPProfileLabelLen = 100
+	// customFactHistoryLimit is how many recent value changes the facts endpoint returns per custom fact.
+	customFactHistoryLimit = 10
+)
+
+var snmpProfileKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+type snmpProfileQueries interface {
+	ListSNMPProfiles(ctx context.Context) ([]sqlcgen.SNMPProfile, error)
+	GetSNMPProfile(ctx context.Context, id string) (sqlcgen.SNMPProfile, error)
+	CreateSNMPProfile(ctx context.Context, arg sqlcgen.CreateSNMPProfileParams) (sqlcgen.SNMPProfile, error)
+	UpdateSNMPProfile(ctx context.Context, arg sqlcgen.UpdateSNMPProfileParams) (sqlcgen.SNMPProfile, error)
+	DeleteSNMPProfile(ctx context.Context, id string) (int64, error)
+}
+
+type snmpProfileItem struct {
+	Key   string  `json:"key"`
+	Label *string `json:"label,omitempty"`
+	OID   string  `json:"oid"`
+	Kind  string  `json:"kind"`
+	Type  string  `json:"type"`
+}
+
+type snmpProfile struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description *string           `json:"description,omitempty"`
+	Tags        []string          `json:"tags"`
+	Items       []snmpProfileItem `json:"items"`
+	Enabled     bool              `json:"enabled"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+type snmpProfileBody struct {
+	Name        string            `json:"name"`
+	Description *string           `json:"description,omitempty"`
+	Tags        []string          `json:"tags"`
+	Items       []snmpProfileItem `json:"items"`
+	Enabled     *bool             `json:"enabled,omitempty"`
+}
+
+type snmpProfilesResponse struct {
+	Profiles []snmpProfile `json:"profiles"`
+}
+
+type deviceCustomFactChange struct {
+	Value        string    `json:"value"`
+	NumericValue *float64  `json:"numeric_value,omitempty"`
+	ObservedAt   time.Time `json:"observed_at"`
+}
+
+type deviceCustomFact struct {
+	ProfileID    string                   `json:"profile_id"`
+	ProfileName  string                   `json:"profile_name"`
+	Key          string                   `json:"key"`
+	Instance     string                   `json:"instance"`
+	Label        *string                  `json:"label,omitempty"`
+	OID          string                   `json:"oid"`
+	ValueType    string                   `json:"value_type"`
+	Value        string                   `json:"value"`
+	NumericValue *float64                 `json:"numeric_value,omitempty"`
+	FirstSeenAt  time.Time                `json:"first_seen_at"`
+	ObservedAt   time.Time                `json:"observed_at"`
+	ChangedAt    time.Time                `json:"changed_at"`
+	History      []deviceCustomFactChange `json:"history"`
+}
+
+// validatedSNMPProfile is a profile body after normalisation.
+type validatedSNMPProfile struct {
+	name        string
+	description *string
+	tags        []string
+	items       []sqlcgen.SNMPProfileItem
+	enabled     bool
+}
+
+func validateSNMPProfileBody(body snmpProfileBody) (validatedSNMPProfile, error) {
+	out := validatedSNMPProfile{enabled: true}
+	out.name = strings.TrimSpace(body.Name)
+	if out.name == "" {
+		return out, errors.New("name is required")
+	}
+	if len(out.name) > maxSNMPProfileNameLen {
+		return out, fmt.Errorf("name is too long (max %d)", maxSNMPProfileNameLen)
+	}
+	if body.Description != nil {
+		if d := strings.TrimSpace(*body.Description); d != "" {
+			out.description = &d
+		}
+	}
+	if body.Enabled != nil {
+		out.enabled = *body.Enabled
+	}
+
+	tags, err := validateDeviceTagList(body.Tags)
+	if err != nil {
+		return out, err
+	}
+	if len(tags) == 0 {
+		return out, errors.New("at least one tag is required")
+	}
+	out.tags = tags
+
+	if len(body.Items) == 0 {
+		return out, errors.New("at least one item is required")
+	}
+	if len(body.Items) > maxSNMPProfileItems {
+		return out, fmt.Errorf("too many items (max %d)", maxSNMPProfileItems)
+	}
+	seen := make(map[string]struct{}, len(body.Items))
+	out.items = make([]sqlcgen.SNMPProfileItem, 0, len(body.Items))
+	for i, raw := range body.Items {
+		key := strings.ToLower(strings.TrimSpace(raw.Key))
+		if !snmpProfileKeyPattern.MatchString(key) {
+			return out, fmt.Errorf("items[%d].key must be lowercase letters, digits and underscores", i)
+		}
+		if _, dup := seen[key]; dup {
+			return out, fmt.Errorf("items[%d].key %q is duplicated", i, key)
+		}
+		seen[key] = struct{}{}
+
+		oid, ok := snmp.NormalizeOID(raw.OID)
+		if !ok {
+			return out, fmt.Errorf("items[%d].oid must be a numeric dotted OID", i)
+		}
+		kind := strings.ToLower(strings.TrimSpace(raw.Kind))
+		if kind == "" {
+			kind = snmp.CustomOIDScalar
+		}
+		if kind != snmp.CustomOIDScalar && kind != snmp.CustomOIDColumn {
+			return out, fmt.Errorf("items[%d].kind must be scalar or column", i)
+		}
+		typ := strings.ToLower(strings.TrimSpace(raw.Type))
+		if typ == "" {
+			typ = snmp.CustomTypeString
+		}
+		if !snmp.IsCustomType(typ) {
+			return out, fmt.Errorf("items[%d].type must be one of %s", i, strings.Join(snmp.CustomTypes(), ", "))
+		}
+		label := ""
+		if raw.Label != nil {
+			label = strings.TrimSpace(*raw.Label)
+		}
+		if len(label) > maxSNMPProfileLabelLen {
+			return out, fmt.Errorf("items[%d].label is too long (max %d)", i, maxSNMPProfileLabelLen)
+		}
+		out.items = append(out.items, sqlcgen.SNMPProfileItem{Key: key, Label: label, OID: oid, Kind: kind, Type: typ})
+	}
+	return out, nil
+}
+
+func toSNMPProfile(row sqlcgen.SNMPProfile) snmpProfile {
+	tags := row.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	items := make([]snmpProfileItem, 0, len(row.Items))
+	for _, item := range row.Items {
+		out := snmpProfileItem{Key: item.Key, OID: item.OID, Kind: item.Kind, Type: item.Type}
+		if item.Label != "" {
+			label := item.Label
+			out.Label = &label
+		}
+		items = append(items, out)
+	}
+	return snmpProfile{
+		ID:          row.ID,
+		Name:        row.Name,
+		Description: row.Description,
+		Tags:        tags,
+		Items:       items,
+		Enabled:     row.Enabled,
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+	}
+}
+
+// snmpProfileStore returns the profile queries, writing an error when they are unavailable.
+func (h *Handler) snmpProfileStore(w http.ResponseWriter) (snmpProfileQueries, bool) {
+	if !h.ensureDeviceQueries(w) {
+		return nil, false
+	}
+	store, ok := h.devices.(snmpProfileQueries)
+	if !ok {
+		h.log.Error().Msg("snmp profile queries missing")
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "snmp profiles not supported", nil)
+		return nil, false
+	}
+	return store, true
+}
+
+func (h *Handler) handleListSNMPProfiles(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.snmpProfileStore(w)
+	if !ok {
+		return
+	}
+	rows, err := store.ListSNMPProfiles(r.Context())
+	if err != nil {
+		h.log.Error().Err(err).Msg("list snmp profiles failed")
+		h.writeError(w, http.StatusInternalServerError, "db_error", "failed to list snmp profiles", nil)
+		return
+	}
+	out := make([]snmpProfile, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, toSNMPProfile(row))
+	}
+	h.writeJSON(w, http.StatusOK, snmpProfilesResponse{Profiles: out})
+}
+
+func (h *Handler) handleGetSNMPProfile(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	store, ok := h.snmpProfileStore(w)
+	if !ok {
+		return
+	}
+	row, err := store.GetSNMPProfile(r.Context(), id)
+	if err != nil {
+		h.writeSNMPProfileError(w, err, id, "failed to fetch snmp profile")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, toSNMPProfile(row))
+}
+
+func (h *Handler) handleCreateSNMPProfile(w http.ResponseWriter, r *http.Request) {
+	var req snmpProfileBody
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid json body", map[string]any{"error": err.Error()})
+		return
+	}
+	profile, err := validateSNMPProfileBody(req)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid snmp profile", map[string]any{"error": err.Error()})
+		return
+	}
+	store, ok := h.snmpProfileStore(w)
+	if !ok {
+		return
+	}
+	row, err := store.CreateSNMPProfile(r.Context(), sqlcgen.CreateSNMPProfileParams{
+		Name:        profile.name,
+		Description: profile.description,
+		Tags:        profile.tags,
+		Items:       profile.items,
+		Enabled:     profile.enabled,
+	})
+	if err != nil {
+		h.writeSNMPProfileError(w, err, "", "failed to create snmp profile")
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, toSNMPProfile(row))
+}
+
+func (h *Handler) handleUpdateSNMPProfile(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req snmpProfileBody
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid json body", map[string]any{"error": err.Error()})
+		return
+	}
+	profile, err := validateSNMPProfileBody(req)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_failed", "invalid snmp profile", map[string]any{"error": err.Error()})
+		return
+	}
+	store, ok := h.snmpProfileStore(w)
+	if !ok {
+		return
+	}
+	row, err := store.UpdateSNMPProfile(r.Context(), sqlcgen.UpdateSNMPProfileParams{
+		ID:          id,
+		Name:        profile.name,
+		Description: profile.description,
+		Tags:        profile.tags,
+		Items:       profile.items,
+		Enabled:     profile.enabled,
+	})
+	if err != nil {
+		h.writeSNMPProfileError(w, err, id, "failed to update snmp profile")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, toSNMPProfile(row))
+}
+
+// handleDeleteSNMPProfile removes a profile together with the custom facts (and their history) it produced.
+func (h *Handler) handleDeleteSNMPProfile(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	store, ok := h.snmpProfileStore(w)
+	if !ok {
+		return
+	}
+	n, err := store.DeleteSNMPProfile(r.Context(), id)
+	if err == nil && n == 0 {
+		err = pgx.ErrNoRows
+	}
+	if err != nil {
+		h.writeSNMPProfileError(w, err, id, "failed to delete snmp profile")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) writeSNMPProfileError(w http.ResponseWriter, err error, id string, msg string) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		h.writeError(w, http.StatusNotFound, "not_found", "snmp profile not found", map[string]any{"id": id})
+	case isInvalidUUID(err):
+		h.writeError(w, http.StatusBadRequest, "invalid_id", "snmp profile id is not a valid uuid", map[string]any{"id": id})
+	case isUniqueViolation(err):
+		h.writeError(w, http.StatusConflict, "conflict", "an snmp profile with this name already exists", nil)
+	default:
+		h.log.Error().Err(err).Str("id", id).Msg(msg)
+		h.writeError(w, http.StatusInternalServerError, "db_error", msg, nil)
+	}
+}
+
+// listDeviceCustomFacts returns the device's custom facts with their recent value changes; it is empty when
+// the queries are unavailable.
+func (h *Handler) listDeviceCustomFacts(ctx context.Context, deviceID string) ([]deviceCustomFact, error) {
+	lister, ok := h.devices.(interface {
+		ListDeviceCustomFacts(ctx context.Context, deviceID string) ([]sqlcgen.DeviceCustomFact, error)
+		ListDeviceCustomFactHistory(ctx context.Context, deviceID string, perFactLimit int32) ([]sqlcgen.DeviceCustomFactChange, error)
+	})
+	out := []deviceCustomFact{}
+	if !ok {
+		return out, nil
+	}
+	rows, err := lister.ListDeviceCustomFacts(ctx, deviceID)
+	if err != nil || len(rows) == 0 {
+		return out, err
+	}
+	changes, err := lister.ListDeviceCustomFactHistory(ctx, deviceID, customFactHistoryLimit)
+	if err != nil {
+		return out, err
+	}
+	history := make(map[[3]string][]deviceCustomFactChange)
+	for _, c := range changes {
+		key := [3]string{c.ProfileID, c.FactKey, c.Instance}
+		history[key] = append(history[key], deviceCustomFactChange{Value: c.Value, NumericValue: c.NumericValue, ObservedAt: c.ObservedAt})
+	}
+	for _, row := range rows {
+		h := history[[3]string{row.ProfileID, row.FactKey, row.Instance}]
+		if h == nil {
+			h = []deviceCustomFactChange{}
+		}
+		out = append(out, deviceCustomFact{
+			ProfileID:    row.ProfileID,
+			ProfileName:  row.ProfileName,
+			Key:          row.FactKey,
+			Instance:     row.Instance,
+			Label:        row.Label,
+			OID:          row.OID,
+			ValueType:    row.ValueType,
+			Value:        row.Value,
+			NumericValue: row.NumericValue,
+			FirstSeenAt:  row.FirstSeenAt,
+			ObservedAt:   row.ObservedAt,
+			ChangedAt:    row.ChangedAt,
+			History:      h,
+		})
+	}
+	return out, nil
+}
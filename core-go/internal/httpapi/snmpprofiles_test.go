@@ -0,0 +1,253 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+type fakeDeviceQueriesWithSNMPProfiles struct {
+	fakeDeviceQueries
+	createFn      func(ctx context.Context, arg sqlcgen.CreateSNMPProfileParams) (sqlcgen.SNMPProfile, error)
+	getProfileFn  func(ctx context.Context, id string) (sqlcgen.SNMPProfile, error)
+	deleteFn      func(ctx context.Context, id string) (int64, error)
+	listFactsFn   func(ctx context.Context, deviceID string) ([]sqlcgen.DeviceCustomFact, error)
+	listHistoryFn func(ctx context.Context, deviceID string, perFactLimit int32) ([]sqlcgen.DeviceCustomFactChange, error)
+}
+
+func (f fakeDeviceQueriesWithSNMPProfiles) ListSNMPProfiles(ctx context.Context) ([]sqlcgen.SNMPProfile, error) {
+	return nil, nil
+}
+
+func (f fakeDeviceQueriesWithSNMPProfiles) GetSNMPProfile(ctx context.Context, id string) (sqlcgen.SNMPProfile, error) {
+	if f.getProfileFn == nil {
+		return sqlcgen.SNMPProfile{}, pgx.ErrNoRows
+	}
+	return f.getProfileFn(ctx, id)
+}
+
+func (f fakeDeviceQueriesWithSNMPProfiles) CreateSNMPProfile(ctx context.Context, arg sqlcgen.CreateSNMPProfileParams) (sqlcgen.SNMPProfile, error) {
+	return f.createFn(ctx, arg)
+}
+
+func (f fakeDeviceQueriesWithSNMPProfiles) UpdateSNMPProfile(ctx context.Context, arg sqlcgen.UpdateSNMPProfileParams) (sqlcgen.SNMPProfile, error) {
+	return sqlcgen.SNMPProfile{}, pgx.ErrNoRows
+}
+
+func (f fakeDeviceQueriesWithSNMPProfiles) DeleteSNMPProfile(ctx context.Context, id string) (int64, error) {
+	if f.deleteFn == nil {
+		return 0, nil
+	}
+	return f.deleteFn(ctx, id)
+}
+
+func (f fakeDeviceQueriesWithSNMPProfiles) ListDeviceCustomFacts(ctx context.Context, deviceID string) ([]sqlcgen.DeviceCustomFact, error) {
+	if f.listFactsFn == nil {
+		return nil, nil
+	}
+	return f.listFactsFn(ctx, deviceID)
+}
+
+func (f fakeDeviceQueriesWithSNMPProfiles) ListDeviceCustomFactHistory(ctx context.Context, deviceID string, perFactLimit int32) ([]sqlcgen.DeviceCustomFactChange, error) {
+	if f.listHistoryFn == nil {
+		return nil, nil
+	}
+	return f.listHistoryFn(ctx, deviceID, perFactLimit)
+}
+
+func TestValidateSNMPProfileBody(t *testing.T) {
+	label := " Toner level "
+	cases := []struct {
+		name    string
+		body    snmpProfileBody
+		wantErr string
+	}{
+		{
+			name: "defaults kind and type",
+			body: snmpProfileBody{Name: " Printers ", Tags: []string{"printer"}, Items: []snmpProfileItem{{Key: "toner", Label: &label, OID: ".1.3.6.1.2.1.43.11.1.1.9"}}},
+		},
+		{name: "missing name", body: snmpProfileBody{Tags: []string{"printer"}, Items: []snmpProfileItem{{Key: "a", OID: "1.3.6"}}}, wantErr: "name is required"},
+		{name: "missing tags", body: snmpProfileBody{Name: "p", Items: []snmpProfileItem{{Key: "a", OID: "1.3.6"}}}, wantErr: "at least one tag"},
+		{name: "unknown tag", body: snmpProfileBody{Name: "p", Tags: []string{"toaster"}, Items: []snmpProfileItem{{Key: "a", OID: "1.3.6"}}}, wantErr: "toaster"},
+		{name: "missing items", body: snmpProfileBody{Name: "p", Tags: []string{"printer"}}, wantErr: "at least one item"},
+		{name: "bad key", body: snmpProfileBody{Name: "p", Tags: []string{"printer"}, Items: []snmpProfileItem{{Key: "9lives", OID: "1.3.6"}}}, wantErr: "items[0].key"},
+		{name: "duplicate key", body: snmpProfileBody{Name: "p", Tags: []string{"printer"}, Items: []snmpProfileItem{{Key: "a", OID: "1.3.6"}, {Key: "a", OID: "1.3.7"}}}, wantErr: "duplicated"},
+		{name: "bad oid", body: snmpProfileBody{Name: "p", Tags: []string{"printer"}, Items: []snmpProfileItem{{Key: "a", OID: "sysDescr"}}}, wantErr: "items[0].oid"},
+		{name: "bad kind", body: snmpProfileBody{Name: "p", Tags: []string{"printer"}, Items: []snmpProfileItem{{Key: "a", OID: "1.3.6", Kind: "walk"}}}, wantErr: "items[0].kind"},
+		{name: "bad type", body: snmpProfileBody{Name: "p", Tags: []string{"printer"}, Items: []snmpProfileItem{{Key: "a", OID: "1.3.6", Type: "float"}}}, wantErr: "items[0].type"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := validateSNMPProfileBody(tc.body)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.name != "Printers" || !got.enabled {
+				t.Fatalf("unexpected profile %+v", got)
+			}
+			item := got.items[0]
+			if item.OID != "1.3.6.1.2.1.43.11.1.1.9" || item.Kind != "scalar" || item.Type != "string" || item.Label != "Toner level" {
+				t.Fatalf("unexpected item %+v", item)
+			}
+		})
+	}
+}
+
+func TestSNMPProfiles_CreateGetDelete(t *testing.T) {
+	profileID := "00000000-0000-0000-0000-0000000000a1"
+	cases := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		fake     fakeDeviceQueriesWithSNMPProfiles
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name:   "create",
+			method: http.MethodPost,
+			path:   "/api/v1/snmp-profiles",
+			body:   `{"name":"NAS","tags":["nas"],"items":[{"key":"battery_pct","oid":"1.3.6.1.2.1.33.1.2.4.0","type":"gauge"}]}`,
+			fake: fakeDeviceQueriesWithSNMPProfiles{createFn: func(ctx context.Context, arg sqlcgen.CreateSNMPProfileParams) (sqlcgen.SNMPProfile, error) {
+				return sqlcgen.SNMPProfile{ID: profileID, Name: arg.Name, Tags: arg.Tags, Items: arg.Items, Enabled: arg.Enabled}, nil
+			}},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:   "create duplicate name",
+			method: http.MethodPost,
+			path:   "/api/v1/snmp-profiles",
+			body:   `{"name":"NAS","tags":["nas"],"items":[{"key":"battery_pct","oid":"1.3.6.1.2.1.33.1.2.4.0"}]}`,
+			fake: fakeDeviceQueriesWithSNMPProfiles{createFn: func(ctx context.Context, arg sqlcgen.CreateSNMPProfileParams) (sqlcgen.SNMPProfile, error) {
+				return sqlcgen.SNMPProfile{}, &pgconn.PgError{Code: "23505"}
+			}},
+			wantCode: http.StatusConflict,
+			wantErr:  "conflict",
+		},
+		{
+			name:     "create invalid",
+			method:   http.MethodPost,
+			path:     "/api/v1/snmp-profiles",
+			body:     `{"name":"NAS","tags":["nas"],"items":[]}`,
+			wantCode: http.StatusBadRequest,
+			wantErr:  "validation_failed",
+		},
+		{
+			name:     "get unknown",
+			method:   http.MethodGet,
+			path:     "/api/v1/snmp-profiles/" + profileID,
+			wantCode: http.StatusNotFound,
+			wantErr:  "not_found",
+		},
+		{
+			name:   "delete",
+			method: http.MethodDelete,
+			path:   "/api/v1/snmp-profiles/" + profileID,
+			fake: fakeDeviceQueriesWithSNMPProfiles{deleteFn: func(ctx context.Context, id string) (int64, error) {
+				return 1, nil
+			}},
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "delete unknown",
+			method:   http.MethodDelete,
+			path:     "/api/v1/snmp-profiles/" + profileID,
+			wantCode: http.StatusNotFound,
+			wantErr:  "not_found",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(NewLogger("debug"), nil)
+			h.devices = tc.fake
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			h.Router().ServeHTTP(rr, req)
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if tc.wantErr != "" {
+				body := decodeBody(t, rr)
+				if code := body["error"].(map[string]any)["code"]; code != tc.wantErr {
+					t.Fatalf("expected error code %q, got %v", tc.wantErr, code)
+				}
+				return
+			}
+			if tc.wantCode != http.StatusCreated {
+				return
+			}
+			body := decodeBody(t, rr)
+			items := body["items"].([]any)
+			if body["id"] != profileID || len(items) != 1 || items[0].(map[string]any)["kind"] != "scalar" {
+				t.Fatalf("unexpected profile %v", body)
+			}
+		})
+	}
+}
+
+func TestDevices_Facts_IncludesCustomFacts(t *testing.T) {
+	profileID := "00000000-0000-0000-0000-0000000000a1"
+	label := "Battery"
+	pct := float64(87)
+	older := float64(91)
+	now := time.Now().UTC()
+	h := NewHandler(NewLogger("debug"), nil)
+	h.devices = fakeDeviceQueriesWithSNMPProfiles{
+		fakeDeviceQueries: fakeDeviceQueries{
+			getFn: func(ctx context.Context, id string) (sqlcgen.Device, error) {
+				return sqlcgen.Device{ID: id}, nil
+			},
+		},
+		listFactsFn: func(ctx context.Context, deviceID string) ([]sqlcgen.DeviceCustomFact, error) {
+			return []sqlcgen.DeviceCustomFact{{
+				ProfileID: profileID, ProfileName: "UPS", FactKey: "battery_pct", Label: &label,
+				OID: "1.3.6.1.2.1.33.1.2.4.0", ValueType: "gauge", Value: "87", NumericValue: &pct,
+				FirstSeenAt: now.Add(-time.Hour), ObservedAt: now, ChangedAt: now,
+			}}, nil
+		},
+		listHistoryFn: func(ctx context.Context, deviceID string, perFactLimit int32) ([]sqlcgen.DeviceCustomFactChange, error) {
+			return []sqlcgen.DeviceCustomFactChange{
+				{ProfileID: profileID, FactKey: "battery_pct", Value: "87", NumericValue: &pct, ObservedAt: now},
+				{ProfileID: profileID, FactKey: "battery_pct", Value: "91", NumericValue: &older, ObservedAt: now.Add(-time.Hour)},
+			}, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/devices/00000000-0000-0000-0000-000000000002/facts", nil)
+	h.Router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	body := decodeBody(t, rr)
+	facts, ok := body["custom_facts"].([]any)
+	if !ok || len(facts) != 1 {
+		t.Fatalf("expected 1 custom fact, got %v", body["custom_facts"])
+	}
+	fact := facts[0].(map[string]any)
+	if fact["key"] != "battery_pct" || fact["profile_name"] != "UPS" || fact["numeric_value"] != float64(87) || fact["instance"] != "" {
+		t.Fatalf("unexpected custom fact %v", fact)
+	}
+	history := fact["history"].([]any)
+	if len(history) != 2 || history[1].(map[string]any)["value"] != "91" {
+		t.Fatalf("unexpected history %v", history)
+	}
+}
@@ -0,0 +1,125 @@
+package identity
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// MAC matching policies.
+const (
+	// PolicyStrict treats every MAC as a stable identifier (match by MAC, then by any device that ever
+	// held the IP).
+	PolicyStrict = "strict"
+	// PolicyRotationAware treats locally administered MACs as weak: when such a MAC is unknown, the
+	// device is matched by DHCP client ID, host-claimed name or recent IP reuse before a new one is made.
+	PolicyRotationAware = "rotation_aware"
+)
+
+// DefaultReuseWindow is how recently a rotating device must have held an IP for its reuse to count.
+const DefaultReuseWindow = 2 * time.Hour
+
+// HostNameSources are the name candidate sources a host claims for itself (reverse DNS follows the
+// address, not the host, so it is not used for identity).
+var HostNameSources = []string{"dhcp", "mdns", "netbios"}
+
+// Rule applies a policy to every address inside Prefix.
+type Rule struct {
+	Prefix netip.Prefix
+	Policy string
+}
+
+// Config selects the MAC matching policy per address. The zero value is strict everywhere.
+type Config struct {
+	Default     string
+	Rules       []Rule
+	ReuseWindow time.Duration
+}
+
+// ValidPolicy reports whether p names a known policy.
+func ValidPolicy(p string) bool {
+	return p == PolicyStrict || p == PolicyRotationAware
+}
+
+// ParseRules reads a comma-separated list of `prefix=policy` pairs (e.g. `10.20.0.0/16=strict`), as set in
+// DISCOVERY_MAC_ROTATION_CIDRS. Prefixes are matched against observed IPs, not run scopes.
+func ParseRules(raw string) ([]Rule, error) {
+	var out []Rule
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		prefix, policy, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q: expected prefix=policy", part)
+		}
+		p, err := netip.ParsePrefix(strings.TrimSpace(prefix))
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", part, err)
+		}
+		policy = strings.ToLower(strings.TrimSpace(policy))
+		if !ValidPolicy(policy) {
+			return nil, fmt.Errorf("%q: unknown policy %q", part, policy)
+		}
+		out = append(out, Rule{Prefix: p.Masked(), Policy: policy})
+	}
+	return out, nil
+}
+
+// PolicyFor returns the policy of the most specific rule containing ip, else the default.
+func (c Config) PolicyFor(ip netip.Addr) string {
+	policy := c.Default
+	bits := -1
+	if ip.IsValid() {
+		ip = ip.Unmap()
+		for _, r := range c.Rules {
+			if r.Prefix.Bits() > bits && r.Prefix.Contains(ip) {
+				policy = r.Policy
+				bits = r.Prefix.Bits()
+			}
+		}
+	}
+	if !ValidPolicy(policy) {
+		return PolicyStrict
+	}
+	return policy
+}
+
+// Window returns the IP reuse window, falling back to DefaultReuseWindow.
+func (c Config) Window() time.Duration {
+	if c.ReuseWindow > 0 {
+		return c.ReuseWindow
+	}
+	return DefaultReuseWindow
+}
+
+// IsLocallyAdministered reports whether mac has the U/L bit set, as randomized/private Wi-Fi MACs do.
+// Group (multicast) addresses are never reported as locally administered unicast MACs.
+func IsLocallyAdministered(mac string) bool {
+	hw, err := net.ParseMAC(strings.TrimSpace(mac))
+	if err != nil || len(hw) == 0 {
+		return false
+	}
+	return hw[0]&0x02 != 0 && hw[0]&0x01 == 0
+}
+
+// HostNameKey is the form host-claimed names are compared in: the first label, lower-cased.
+func HostNameKey(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+	label, _, _ := strings.Cut(name, ".")
+	return label
+}
+
+// IsHostNameSource reports whether names from source are claimed by the host itself.
+func IsHostNameSource(source string) bool {
+	source = strings.ToLower(strings.TrimSpace(source))
+	for _, s := range HostNameSources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package identity
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsLocallyAdministered(t *testing.T) {
+	cases := map[string]bool{
+		"3c:22:fb:01:02:03": false, // vendor OUI
+		"da:a1:19:6e:2b:7c": true,  // iOS/Android private address
+		"02:00:00:00:00:01": true,
+		"DA:A1:19:6E:2B:7C": true,
+		"03:00:00:00:00:01": false, // group bit set
+		"not-a-mac":         false,
+		"":                  false,
+	}
+	for mac, want := range cases {
+		if got := IsLocallyAdministered(mac); got != want {
+			t.Errorf("IsLocallyAdministered(%q) = %v, want %v", mac, got, want)
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	cases := []struct {
+		raw     string
+		want    int
+		wantErr bool
+	}{
+		{raw: "", want: 0},
+		{raw: "10.20.0.0/16=strict, 192.168.1.0/24=ROTATION_AWARE,", want: 2},
+		{raw: "10.20.0.1/16=strict", want: 1},
+		{raw: "10.20.0.0/16", wantErr: true},
+		{raw: "10.20.0.0/16=loose", wantErr: true},
+		{raw: "nope=strict", wantErr: true},
+	}
+	for _, tc := range cases {
+		rules, err := ParseRules(tc.raw)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("ParseRules(%q) err=%v wantErr=%v", tc.raw, err, tc.wantErr)
+		}
+		if err == nil && len(rules) != tc.want {
+			t.Fatalf("ParseRules(%q) = %d rules, want %d", tc.raw, len(rules), tc.want)
+		}
+	}
+	rules, _ := ParseRules("10.20.0.1/16=strict")
+	if rules[0].Prefix.String() != "10.20.0.0/16" {
+		t.Fatalf("expected masked prefix, got %s", rules[0].Prefix)
+	}
+}
+
+func TestConfigPolicyFor(t *testing.T) {
+	rules, err := ParseRules("10.0.0.0/8=strict,10.1.0.0/16=rotation_aware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Default: PolicyRotationAware, Rules: rules}
+	cases := []struct {
+		ip   string
+		want string
+	}{
+		{ip: "10.2.3.4", want: PolicyStrict},
+		{ip: "10.1.3.4", want: PolicyRotationAware},
+		{ip: "192.168.1.10", want: PolicyRotationAware},
+		{ip: "", want: PolicyRotationAware},
+	}
+	for _, tc := range cases {
+		var ip netip.Addr
+		if tc.ip != "" {
+			ip = netip.MustParseAddr(tc.ip)
+		}
+		if got := cfg.PolicyFor(ip); got != tc.want {
+			t.Errorf("PolicyFor(%q) = %q, want %q", tc.ip, got, tc.want)
+		}
+	}
+	if got := (Config{}).PolicyFor(netip.MustParseAddr("10.1.3.4")); got != PolicyStrict {
+		t.Fatalf("zero config should be strict, got %q", got)
+	}
+	if (Config{}).Window() != DefaultReuseWindow || (Config{ReuseWindow: time.Minute}).Window() != time.Minute {
+		t.Fatal("unexpected reuse window")
+	}
+}
+
+func TestHostNameKey(t *testing.T) {
+	cases := map[string]string{
+		"Johns-iPhone":             "johns-iphone",
+		"johns-iphone.local.":      "johns-iphone",
+		" LAPTOP-7Q2.corp.example": "laptop-7q2",
+		"":                         "",
+	}
+	for in, want := range cases {
+		if got := HostNameKey(in); got != want {
+			t.Errorf("HostNameKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
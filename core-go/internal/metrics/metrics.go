@@ -16,6 +16,12 @@ type Metrics struct {
 	httpRequestDuration  *prometheus.HistogramVec
 	discoveryRunsTotal   prometheus.Counter
 	discoveryRunDuration prometheus.Histogram
+	retentionRowsDeleted *prometheus.CounterVec
+	reachabilityTargets  prometheus.Gauge
+	reachabilityUp       *prometheus.GaugeVec
+	reachabilityProbeOK  *prometheus.GaugeVec
+	reachabilityRTT      *prometheus.GaugeVec
+	reachabilityLoss     *prometheus.GaugeVec
 }
 
 // New creates a fresh Metrics registry with HTTP and discovery metrics registered.
@@ -48,11 +54,53 @@ func New() *Metrics {
 		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200},
 	})
 
+	retentionRowsDeleted := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "roller",
+		Name:      "retention_rows_deleted_total",
+		Help:      "Rows deleted by the retention job, by table and tier (drop or thin)",
+	}, []string{"table", "tier"})
+
+	reachabilityTargets := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "roller",
+		Name:      "reachability_targets",
+		Help:      "Devices probed by the reachability monitor in its last cycle",
+	})
+
+	reachabilityUp := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "roller",
+		Name:      "reachability_up",
+		Help:      "Availability state set by the reachability monitor (1 up, 0 down)",
+	}, []string{"device_id"})
+
+	reachabilityProbeOK := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "roller",
+		Name:      "reachability_probe_success",
+		Help:      "Whether the last reachability probe of a device succeeded (1) or failed (0)",
+	}, []string{"device_id"})
+
+	reachabilityRTT := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "roller",
+		Name:      "reachability_rtt_seconds",
+		Help:      "Round-trip time of the last successful reachability probe of a device",
+	}, []string{"device_id"})
+
+	reachabilityLoss := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "roller",
+		Name:      "reachability_loss_ratio",
+		Help:      "Share of failed reachability probes of a device over its recent probes",
+	}, []string{"device_id"})
+
 	registry.MustRegister(
 		httpRequests,
 		httpRequestDuration,
 		discoveryRunsTotal,
 		discoveryRunDuration,
+		retentionRowsDeleted,
+		reachabilityTargets,
+		reachabilityUp,
+		reachabilityProbeOK,
+		reachabilityRTT,
+		reachabilityLoss,
 	)
 
 	return &Metrics{
@@ -61,6 +109,12 @@ func New() *Metrics {
 		httpRequestDuration:  httpRequestDuration,
 		discoveryRunsTotal:   discoveryRunsTotal,
 		discoveryRunDuration: discoveryRunDuration,
+		retentionRowsDeleted: retentionRowsDeleted,
+		reachabilityTargets:  reachabilityTargets,
+		reachabilityUp:       reachabilityUp,
+		reachabilityProbeOK:  reachabilityProbeOK,
+		reachabilityRTT:      reachabilityRTT,
+		reachabilityLoss:     reachabilityLoss,
 	}
 }
 
@@ -94,6 +148,60 @@ func (m *Metrics) ObserveDiscoveryRunDuration(duration time.Duration) {
 	m.discoveryRunDuration.Observe(duration.Seconds())
 }
 
+// AddRetentionRowsDeleted counts rows the retention job deleted from table in one tier.
+func (m *Metrics) AddRetentionRowsDeleted(table, tier string, n int64) {
+	if m == nil || n <= 0 {
+		return
+	}
+	m.retentionRowsDeleted.WithLabelValues(table, tier).Add(float64(n))
+}
+
+// SetReachabilityTargets records how many devices the reachability monitor probed.
+func (m *Metrics) SetReachabilityTargets(n int) {
+	if m == nil {
+		return
+	}
+	m.reachabilityTargets.Set(float64(n))
+}
+
+// ObserveReachabilityProbe records the outcome of a device's latest probe and its loss over recent probes.
+// The RTT gauge keeps the last successful value when a probe fails.
+func (m *Metrics) ObserveReachabilityProbe(deviceID string, ok bool, rtt time.Duration, loss float64) {
+	if m == nil {
+		return
+	}
+	if ok {
+		m.reachabilityProbeOK.WithLabelValues(deviceID).Set(1)
+		m.reachabilityRTT.WithLabelValues(deviceID).Set(rtt.Seconds())
+	} else {
+		m.reachabilityProbeOK.WithLabelValues(deviceID).Set(0)
+	}
+	m.reachabilityLoss.WithLabelValues(deviceID).Set(loss)
+}
+
+// SetReachabilityUp records the availability state the monitor derived for a device.
+func (m *Metrics) SetReachabilityUp(deviceID string, up bool) {
+	if m == nil {
+		return
+	}
+	v := 0.0
+	if up {
+		v = 1
+	}
+	m.reachabilityUp.WithLabelValues(deviceID).Set(v)
+}
+
+// DeleteReachability drops the series of a device that is no longer monitored.
+func (m *Metrics) DeleteReachability(deviceID string) {
+	if m == nil {
+		return
+	}
+	m.reachabilityUp.DeleteLabelValues(deviceID)
+	m.reachabilityProbeOK.DeleteLabelValues(deviceID)
+	m.reachabilityRTT.DeleteLabelValues(deviceID)
+	m.reachabilityLoss.DeleteLabelValues(deviceID)
+}
+
 // Handler exposes the Prometheus registry over HTTP.
 func (m *Metrics) Handler() http.Handler {
 	if m == nil {
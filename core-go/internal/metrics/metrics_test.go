@@ -28,6 +28,12 @@ func TestHandler_exposesRegisteredMetrics(t *testing.T) {
 	m.ObserveHTTPRequest(http.MethodGet, "/readyz", http.StatusOK, 12*time.Millisecond)
 	m.IncDiscoveryRun()
 	m.ObserveDiscoveryRunDuration(3 * time.Second)
+	m.AddRetentionRowsDeleted("ip_observations", "drop", 42)
+	m.SetReachabilityTargets(2)
+	m.ObserveReachabilityProbe("dev-1", true, 15*time.Millisecond, 0.25)
+	m.SetReachabilityUp("dev-1", true)
+	m.ObserveReachabilityProbe("dev-2", false, 0, 1)
+	m.DeleteReachability("dev-2")
 
 	rr := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
@@ -51,4 +57,21 @@ func TestHandler_exposesRegisteredMetrics(t *testing.T) {
 	if !strings.Contains(body, "roller_discovery_run_duration_seconds_count 1") {
 		t.Fatalf("expected discovery run duration histogram to have one observation; body=%s", body)
 	}
+	if !strings.Contains(body, `roller_retention_rows_deleted_total{table="ip_observations",tier="drop"} 42`) {
+		t.Fatalf("expected retention rows counter to be incremented; body=%s", body)
+	}
+	for _, want := range []string{
+		"roller_reachability_targets 2",
+		`roller_reachability_up{device_id="dev-1"} 1`,
+		`roller_reachability_probe_success{device_id="dev-1"} 1`,
+		`roller_reachability_rtt_seconds{device_id="dev-1"} 0.015`,
+		`roller_reachability_loss_ratio{device_id="dev-1"} 0.25`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q; body=%s", want, body)
+		}
+	}
+	if strings.Contains(body, `device_id="dev-2"`) {
+		t.Fatalf("expected deleted device series to be gone; body=%s", body)
+	}
 }
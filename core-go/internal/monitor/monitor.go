@@ -0,0 +1,432 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/metrics"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// Source is recorded in the evidence of the availability transitions the monitor writes.
+const Source = "monitor"
+
+// lossWindow is how many recent probes per device the loss gauge covers.
+const lossWindow = 10
+
+// pruneEvery is how often a running monitor deletes expired samples and rollups.
+const pruneEvery = time.Hour
+
+const pruneBatchSize = 5000
+
+// Queries is the DB interface the monitor needs. *sqlcgen.Queries satisfies it.
+type Queries interface {
+	SyncTaggedDeviceMonitors(ctx context.Context, arg sqlcgen.SyncTaggedDeviceMonitorsParams) (int64, error)
+	ListMonitorTargets(ctx context.Context) ([]sqlcgen.MonitorTarget, error)
+	RecordReachabilitySample(ctx context.Context, arg sqlcgen.RecordReachabilitySampleParams) (sqlcgen.MonitorStatus, error)
+	RecordMonitorAvailability(ctx context.Context, arg sqlcgen.RecordMonitorAvailabilityParams) (int64, error)
+	DeleteReachabilitySamplesBefore(ctx context.Context, before time.Time, limit int32) (int64, error)
+	DeleteReachabilityRollupsBefore(ctx context.Context, before time.Time, limit int32) (int64, error)
+}
+
+// Options configures the reachability monitor.
+type Options struct {
+	Interval time.Duration
+	Timeout  time.Duration
+	Workers  int
+	// Tags selects devices to monitor in addition to the ones flagged through the API.
+	Tags []string
+	// DefaultMethod is the probe used for tag-selected devices.
+	DefaultMethod string
+	// FailuresBeforeDown is how many consecutive failed probes mark a device down.
+	FailuresBeforeDown int
+	// RawRetention is how long individual probes are kept; hourly rollups outlive them.
+	RawRetention time.Duration
+	// RollupRetention is how long hourly rollups are kept; 0 keeps them forever.
+	RollupRetention time.Duration
+}
+
+// probeFunc returns the round-trip time of one probe, or an error when the target did not answer.
+type probeFunc func(ctx context.Context, method, address string, port int, timeout time.Duration) (time.Duration, error)
+
+// Job probes monitored devices on a short interval, independent of discovery runs.
+type Job struct {
+	log                zerolog.Logger
+	q                  Queries
+	interval           time.Duration
+	timeout            time.Duration
+	workers            int
+	tags               []string
+	defaultMethod      string
+	failuresBeforeDown int32
+	rawRetention       time.Duration
+	rollupRetention    time.Duration
+	metrics            *metrics.Metrics
+	probe              probeFunc
+
+	// states is the availability state last written or confirmed per device; history holds recent outcomes.
+	states    map[string]string
+	history   map[string][]bool
+	lastPrune time.Time
+}
+
+func New(log zerolog.Logger, q Queries, opts Options, m *metrics.Metrics) *Job {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = 16
+	}
+	method := opts.DefaultMethod
+	if method != sqlcgen.MonitorMethodTCP {
+		method = sqlcgen.MonitorMethodICMP
+	}
+	failures := opts.FailuresBeforeDown
+	if failures <= 0 {
+		failures = 3
+	}
+	rawRetention := opts.RawRetention
+	if rawRetention <= 0 {
+		rawRetention = 48 * time.Hour
+	}
+	rollupRetention := opts.RollupRetention
+	if rollupRetention < 0 {
+		rollupRetention = 0
+	}
+	return &Job{
+		log:                log,
+		q:                  q,
+		interval:           interval,
+		timeout:            timeout,
+		workers:            workers,
+		tags:               opts.Tags,
+		defaultMethod:      method,
+		failuresBeforeDown: int32(failures),
+		rawRetention:       rawRetention,
+		rollupRetention:    rollupRetention,
+		metrics:            m,
+		probe:              probe,
+		states:             map[string]string{},
+		history:            map[string][]bool{},
+	}
+}
+
+// Run probes once at start and then every interval until ctx is done.
+func (j *Job) Run(ctx context.Context) {
+	if j == nil || j.q == nil {
+		return
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		res, err := j.RunOnce(ctx, time.Now().UTC())
+		if err != nil && ctx.Err() == nil {
+			j.log.Error().Err(err).Msg("reachability monitor cycle failed")
+		} else {
+			j.log.Debug().
+				Int("targets", res.Targets).
+				Int("failed", res.Failed).
+				Int("skipped", res.Skipped).
+				Msg("reachability monitor cycle")
+		}
+		timer.Reset(j.interval)
+	}
+}
+
+// Result summarises one monitor cycle.
+type Result struct {
+	Targets  int
+	Failed   int
+	Skipped  int
+	WentUp   int
+	WentDown int
+	Pruned   int64
+}
+
+type outcome struct {
+	target  sqlcgen.MonitorTarget
+	method  string
+	address string
+	port    *int32
+	rtt     time.Duration
+	err     error
+}
+
+// RunOnce syncs the tag selection, probes every enabled monitor, records the samples and any availability
+// change, and prunes expired samples at most once an hour. Probes of one cycle are stamped with now.
+func (j *Job) RunOnce(ctx context.Context, now time.Time) (Result, error) {
+	var res Result
+	if _, err := j.q.SyncTaggedDeviceMonitors(ctx, sqlcgen.SyncTaggedDeviceMonitorsParams{Tags: j.tags, Method: j.defaultMethod}); err != nil {
+		return res, fmt.Errorf("sync tagged monitors: %w", err)
+	}
+	targets, err := j.q.ListMonitorTargets(ctx)
+	if err != nil {
+		return res, fmt.Errorf("list monitor targets: %w", err)
+	}
+
+	var probes []outcome
+	current := make(map[string]struct{}, len(targets))
+	for _, t := range targets {
+		current[t.DeviceID] = struct{}{}
+		if t.Address == nil || *t.Address == "" {
+			res.Skipped++
+			continue
+		}
+		o := outcome{target: t, method: t.Method, address: *t.Address, port: t.Port}
+		// A tcp monitor without a known port falls back to ICMP rather than not probing at all.
+		if o.method != sqlcgen.MonitorMethodTCP || o.port == nil {
+			o.method, o.port = sqlcgen.MonitorMethodICMP, nil
+		}
+		probes = append(probes, o)
+	}
+	for id := range j.history {
+		if _, ok := current[id]; !ok {
+			delete(j.history, id)
+			delete(j.states, id)
+			j.metrics.DeleteReachability(id)
+		}
+	}
+	res.Targets = len(probes)
+	j.metrics.SetReachabilityTargets(len(probes))
+
+	j.probeAll(ctx, probes)
+	for _, o := range probes {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		if err := j.record(ctx, now, o, &res); err != nil {
+			return res, fmt.Errorf("record probe of %s: %w", o.target.DeviceID, err)
+		}
+	}
+
+	if now.Sub(j.lastPrune) >= pruneEvery {
+		res.Pruned, err = j.prune(ctx, now)
+		if err != nil {
+			return res, fmt.Errorf("prune reachability samples: %w", err)
+		}
+		j.lastPrune = now
+	}
+	return res, nil
+}
+
+// probeAll runs the probes with at most workers in flight, filling in rtt and err.
+func (j *Job) probeAll(ctx context.Context, probes []outcome) {
+	sem := make(chan struct{}, j.workers)
+	var wg sync.WaitGroup
+	for i := range probes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(o *outcome) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			port := 0
+			if o.port != nil {
+				port = int(*o.port)
+			}
+			o.rtt, o.err = j.probe(ctx, o.method, o.address, port, j.timeout)
+		}(&probes[i])
+	}
+	wg.Wait()
+}
+
+func (j *Job) record(ctx context.Context, now time.Time, o outcome, res *Result) error {
+	id := o.target.DeviceID
+	ok := o.err == nil
+	arg := sqlcgen.RecordReachabilitySampleParams{
+		DeviceID: id,
+		ProbedAt: now,
+		Method:   o.method,
+		Address:  o.address,
+		Port:     o.port,
+		Success:  ok,
+	}
+	if ok {
+		rttMs := float64(o.rtt.Microseconds()) / 1000
+		arg.RTTMs = &rttMs
+	} else {
+		res.Failed++
+	}
+	status, err := j.q.RecordReachabilitySample(ctx, arg)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The monitor was removed while the probe was in flight.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	h := append(j.history[id], ok)
+	if len(h) > lossWindow {
+		h = h[len(h)-lossWindow:]
+	}
+	j.history[id] = h
+	j.metrics.ObserveReachabilityProbe(id, ok, o.rtt, lossRatio(h))
+
+	state := ""
+	switch {
+	case ok:
+		state = sqlcgen.AvailabilityUp
+	case status.ConsecutiveFailures >= j.failuresBeforeDown:
+		state = sqlcgen.AvailabilityDown
+	}
+	if state == "" || j.states[id] == state {
+		return nil
+	}
+
+	evidence := map[string]any{
+		"source":               Source,
+		"method":               o.method,
+		"address":              o.address,
+		"consecutive_failures": status.ConsecutiveFailures,
+		"last_success_at":      status.LastSuccessAt,
+	}
+	if o.port != nil {
+		evidence["port"] = *o.port
+	}
+	if arg.RTTMs != nil {
+		evidence["rtt_ms"] = *arg.RTTMs
+	}
+	n, err := j.q.RecordMonitorAvailability(ctx, sqlcgen.RecordMonitorAvailabilityParams{
+		DeviceID:  id,
+		State:     state,
+		ChangedAt: now,
+		Evidence:  evidence,
+	})
+	if err != nil {
+		return err
+	}
+	j.states[id] = state
+	j.metrics.SetReachabilityUp(id, state == sqlcgen.AvailabilityUp)
+	if n > 0 {
+		if state == sqlcgen.AvailabilityUp {
+			res.WentUp++
+		} else {
+			res.WentDown++
+		}
+		j.log.Info().Str("device_id", id).Str("state", state).Str("address", o.address).Msg("reachability changed")
+	}
+	return nil
+}
+
+func lossRatio(history []bool) float64 {
+	if len(history) == 0 {
+		return 0
+	}
+	failed := 0
+	for _, ok := range history {
+		if !ok {
+			failed++
+		}
+	}
+	return float64(failed) / float64(len(history))
+}
+
+// prune deletes raw samples past the raw retention and rollups past the rollup retention, in batches.
+func (j *Job) prune(ctx context.Context, now time.Time) (int64, error) {
+	total, err := batch(ctx, func() (int64, error) {
+		return j.q.DeleteReachabilitySamplesBefore(ctx, now.Add(-j.rawRetention), pruneBatchSize)
+	})
+	if err != nil || j.rollupRetention == 0 {
+		return total, err
+	}
+	n, err := batch(ctx, func() (int64, error) {
+		return j.q.DeleteReachabilityRollupsBefore(ctx, now.Add(-j.rollupRetention), pruneBatchSize)
+	})
+	return total + n, err
+}
+
+// batch repeats del until it deletes less than a full batch, returning the total.
+func batch(ctx context.Context, del func() (int64, error)) (int64, error) {
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		n, err := del()
+		total += n
+		if err != nil || n < pruneBatchSize {
+			return total, err
+		}
+	}
+}
+
+func probe(ctx context.Context, method, address string, port int, timeout time.Duration) (time.Duration, error) {
+	if method == sqlcgen.MonitorMethodTCP {
+		return probeTCP(ctx, address, port, timeout)
+	}
+	return probeICMP(ctx, address, timeout)
+}
+
+// probeTCP measures a full TCP connect; a refused connection counts as a failure.
+func probeTCP(ctx context.Context, address string, port int, timeout time.Duration) (time.Duration, error) {
+	d := net.Dialer{Timeout: timeout}
+	start := time.Now()
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(address, strconv.Itoa(port)))
+	if err != nil {
+		return 0, err
+	}
+	rtt := time.Since(start)
+	_ = conn.Close()
+	return rtt, nil
+}
+
+var pingTimeRe = regexp.MustCompile(`time[=<]([0-9.]+) ?ms`)
+
+// probeICMP sends one echo request with the system ping, like the discovery sweep, and reads the RTT it reports.
+func probeICMP(ctx context.Context, address string, timeout time.Duration) (time.Duration, error) {
+	pingPath, err := exec.LookPath("ping")
+	if err != nil {
+		return 0, err
+	}
+	wait := strconv.Itoa(int(math.Max(1, math.Ceil(timeout.Seconds()))))
+	pingCtx, cancel := context.WithTimeout(ctx, timeout+time.Second)
+	defer cancel()
+
+	start := time.Now()
+	out, err := exec.CommandContext(pingCtx, pingPath, "-c", "1", "-W", wait, address).Output()
+	elapsed := time.Since(start)
+	if err != nil {
+		return 0, err
+	}
+	if rtt, ok := parsePingRTT(string(out)); ok {
+		return rtt, nil
+	}
+	return elapsed, nil
+}
+
+// parsePingRTT extracts the round-trip time from ping output ("time=0.431 ms" or "time<1ms").
+func parsePingRTT(out string) (time.Duration, bool) {
+	m := pingTimeRe.FindStringSubmatch(out)
+	if m == nil {
+		return 0, false
+	}
+	ms, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(ms * float64(time.Millisecond)), true
+}
@@ -0,0 +1,196 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+type fakeQueries struct {
+	targets      []sqlcgen.MonitorTarget
+	synced       []sqlcgen.SyncTaggedDeviceMonitorsParams
+	samples      []sqlcgen.RecordReachabilitySampleParams
+	failures     map[string]int32
+	removed      map[string]bool
+	availability []sqlcgen.RecordMonitorAvailabilityParams
+	latest       map[string]string
+	sampleCuts   []time.Time
+	rollupCuts   []time.Time
+}
+
+func newFakeQueries(targets ...sqlcgen.MonitorTarget) *fakeQueries {
+	return &fakeQueries{
+		targets:  targets,
+		failures: map[string]int32{},
+		removed:  map[string]bool{},
+		latest:   map[string]string{},
+	}
+}
+
+func (f *fakeQueries) SyncTaggedDeviceMonitors(ctx context.Context, arg sqlcgen.SyncTaggedDeviceMonitorsParams) (int64, error) {
+	f.synced = append(f.synced, arg)
+	return 0, nil
+}
+
+func (f *fakeQueries) ListMonitorTargets(ctx context.Context) ([]sqlcgen.MonitorTarget, error) {
+	return f.targets, nil
+}
+
+func (f *fakeQueries) RecordReachabilitySample(ctx context.Context, arg sqlcgen.RecordReachabilitySampleParams) (sqlcgen.MonitorStatus, error) {
+	if f.removed[arg.DeviceID] {
+		return sqlcgen.MonitorStatus{}, pgx.ErrNoRows
+	}
+	f.samples = append(f.samples, arg)
+	if arg.Success {
+		f.failures[arg.DeviceID] = 0
+	} else {
+		f.failures[arg.DeviceID]++
+	}
+	return sqlcgen.MonitorStatus{ConsecutiveFailures: f.failures[arg.DeviceID]}, nil
+}
+
+func (f *fakeQueries) RecordMonitorAvailability(ctx context.Context, arg sqlcgen.RecordMonitorAvailabilityParams) (int64, error) {
+	if f.latest[arg.DeviceID] == arg.State {
+		return 0, nil
+	}
+	f.latest[arg.DeviceID] = arg.State
+	f.availability = append(f.availability, arg)
+	return 1, nil
+}
+
+func (f *fakeQueries) DeleteReachabilitySamplesBefore(ctx context.Context, before time.Time, limit int32) (int64, error) {
+	f.sampleCuts = append(f.sampleCuts, before)
+	return 0, nil
+}
+
+func (f *fakeQueries) DeleteReachabilityRollupsBefore(ctx context.Context, before time.Time, limit int32) (int64, error) {
+	f.rollupCuts = append(f.rollupCuts, before)
+	return 0, nil
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestJobRunOnce_FeedsAvailability(t *testing.T) {
+	q := newFakeQueries(
+		sqlcgen.MonitorTarget{DeviceID: "a", Method: sqlcgen.MonitorMethodTCP, Port: ptr(int32(22)), Address: ptr("10.0.0.1")},
+		sqlcgen.MonitorTarget{DeviceID: "b", Method: sqlcgen.MonitorMethodICMP, Address: ptr("10.0.0.2")},
+		sqlcgen.MonitorTarget{DeviceID: "c", Method: sqlcgen.MonitorMethodICMP},
+		sqlcgen.MonitorTarget{DeviceID: "d", Method: sqlcgen.MonitorMethodTCP, Address: ptr("10.0.0.4")},
+	)
+	job := New(zerolog.Nop(), q, Options{Tags: []string{"router"}, FailuresBeforeDown: 3}, nil)
+	probed := map[string]string{}
+	job.probe = func(ctx context.Context, method, address string, port int, timeout time.Duration) (time.Duration, error) {
+		probed[address] = method
+		if address == "10.0.0.2" {
+			return 0, errors.New("timeout")
+		}
+		return 2 * time.Millisecond, nil
+	}
+
+	start := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	var res Result
+	for i := range 3 {
+		var err error
+		res, err = job.RunOnce(context.Background(), start.Add(time.Duration(i)*30*time.Second))
+		if err != nil {
+			t.Fatalf("cycle %d: unexpected error: %v", i, err)
+		}
+		if i == 0 && len(q.availability) != 2 {
+			t.Fatalf("expected a and d to come up on the first cycle, got %+v", q.availability)
+		}
+	}
+
+	if res.Targets != 3 || res.Skipped != 1 || res.Failed != 1 || res.WentDown != 1 || res.WentUp != 0 {
+		t.Fatalf("unexpected last cycle result %+v", res)
+	}
+	if probed["10.0.0.1"] != sqlcgen.MonitorMethodTCP || probed["10.0.0.4"] != sqlcgen.MonitorMethodICMP {
+		t.Fatalf("expected tcp for a and an icmp fallback for portless d, got %v", probed)
+	}
+	if len(q.samples) != 9 {
+		t.Fatalf("expected 9 samples over 3 cycles, got %d", len(q.samples))
+	}
+	if len(q.availability) != 3 {
+		t.Fatalf("expected up(a), up(d) and down(b), got %+v", q.availability)
+	}
+	down := q.availability[2]
+	if down.DeviceID != "b" || down.State != sqlcgen.AvailabilityDown || !down.ChangedAt.Equal(start.Add(time.Minute)) {
+		t.Fatalf("expected b down on the third failure, got %+v", down)
+	}
+	if down.Evidence["source"] != Source || down.Evidence["consecutive_failures"] != int32(3) {
+		t.Fatalf("unexpected evidence %v", down.Evidence)
+	}
+	if len(q.synced) != 3 || q.synced[0].Method != sqlcgen.MonitorMethodICMP || q.synced[0].Tags[0] != "router" {
+		t.Fatalf("expected the tag selection synced every cycle, got %+v", q.synced)
+	}
+	if got := lossRatio(job.history["b"]); got != 1 {
+		t.Fatalf("expected full loss for b, got %v", got)
+	}
+}
+
+func TestJobRunOnce_ForgetsRemovedMonitors(t *testing.T) {
+	q := newFakeQueries(sqlcgen.MonitorTarget{DeviceID: "a", Method: sqlcgen.MonitorMethodICMP, Address: ptr("10.0.0.1")})
+	job := New(zerolog.Nop(), q, Options{}, nil)
+	job.probe = func(ctx context.Context, method, address string, port int, timeout time.Duration) (time.Duration, error) {
+		return time.Millisecond, nil
+	}
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, err := job.RunOnce(context.Background(), now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Removed between listing and recording: the sample is dropped without failing the cycle.
+	q.removed["a"] = true
+	if _, err := job.RunOnce(context.Background(), now.Add(time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q.targets = nil
+	if _, err := job.RunOnce(context.Background(), now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := job.states["a"]; ok {
+		t.Fatalf("expected state of removed monitor to be forgotten")
+	}
+}
+
+func TestJobRunOnce_Prunes(t *testing.T) {
+	q := newFakeQueries()
+	job := New(zerolog.Nop(), q, Options{RawRetention: 24 * time.Hour, RollupRetention: 90 * 24 * time.Hour}, nil)
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, at := range []time.Time{now, now.Add(30 * time.Minute), now.Add(time.Hour)} {
+		if _, err := job.RunOnce(context.Background(), at); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(q.sampleCuts) != 2 || len(q.rollupCuts) != 2 {
+		t.Fatalf("expected pruning at most hourly, got %d sample and %d rollup passes", len(q.sampleCuts), len(q.rollupCuts))
+	}
+	if !q.sampleCuts[0].Equal(now.Add(-24*time.Hour)) || !q.rollupCuts[0].Equal(now.Add(-90*24*time.Hour)) {
+		t.Fatalf("unexpected cutoffs %s / %s", q.sampleCuts[0], q.rollupCuts[0])
+	}
+}
+
+func TestParsePingRTT(t *testing.T) {
+	cases := []struct {
+		out  string
+		want time.Duration
+		ok   bool
+	}{
+		{out: "64 bytes from 10.0.0.1: icmp_seq=1 ttl=64 time=0.431 ms", want: 431 * time.Microsecond, ok: true},
+		{out: "64 bytes from 10.0.0.1: seq=0 ttl=64 time=12.5 ms", want: 12500 * time.Microsecond, ok: true},
+		{out: "Reply from 10.0.0.1: bytes=32 time<1ms TTL=64", want: time.Millisecond, ok: true},
+		{out: "1 packets transmitted, 1 received"},
+	}
+	for _, tc := range cases {
+		got, ok := parsePingRTT(tc.out)
+		if ok != tc.ok || got != tc.want {
+			t.Fatalf("parsePingRTT(%q) = %s, %v; want %s, %v", tc.out, got, ok, tc.want, tc.ok)
+		}
+	}
+}
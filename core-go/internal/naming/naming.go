@@ -160,6 +160,13 @@ func scoreCandidate(source, stored, display string) int {
 		base = 78
 	case "manual":
 		base = 70
+	case "http_title":
+		// Page titles are descriptive ("Synology DiskStation - nas01") rather than hostnames; keep them
+		// as candidates but below the auto display-name bar unless nothing better exists.
+		if looksGenericHTTPTitle(normalized) {
+			return -1
+		}
+		base = 60
 	}
 
 	// Penalize very short labels.
@@ -201,6 +208,54 @@ func looksHostnameLabel(value string) bool {
 	return true
 }
 
+var genericHTTPTitles = map[string]struct{}{
+	"login":                     {},
+	"log in":                    {},
+	"sign in":                   {},
+	"it works":                  {},
+	"test page":                 {},
+	"redirect":                  {},
+	"redirecting":               {},
+	"loading":                   {},
+	"document moved":            {},
+	"object moved":              {},
+	"302 found":                 {},
+	"301 moved permanently":     {},
+	"400 bad request":           {},
+	"401 unauthorized":          {},
+	"403 forbidden":             {},
+	"404 not found":             {},
+	"error":                     {},
+	"home":                      {},
+	"web ui":                    {},
+	"iis windows server":        {},
+	"untitled":                  {},
+	"untitled document":         {},
+	"default web site page":     {},
+	"web server's default page": {},
+}
+
+var genericHTTPTitlePrefixes = []string{
+	"index of /",
+	"welcome to nginx",
+	"apache2 ubuntu default page",
+	"apache2 debian default page",
+	"apache http server test page",
+}
+
+func looksGenericHTTPTitle(normalized string) bool {
+	normalized = strings.TrimSpace(strings.Trim(normalized, "!.-:| "))
+	if _, ok := genericHTTPTitles[normalized]; ok {
+		return true
+	}
+	for _, p := range genericHTTPTitlePrefixes {
+		if strings.HasPrefix(normalized, p) {
+			return true
+		}
+	}
+	return false
+}
+
 func looksGarbage(normalized string) bool {
 	if normalized == "" {
 		return true
@@ -40,3 +40,25 @@ func TestChooseBestDisplayName_RejectsGarbage(t *testing.T) {
 		t.Fatalf("expected ok=false, got name=%q", name)
 	}
 }
+
+func TestNormalizeCandidate_HTTPTitle(t *testing.T) {
+	cases := []struct {
+		title string
+		ok    bool
+	}{
+		{title: "Synology DiskStation - nas01", ok: true},
+		{title: "Login", ok: false},
+		{title: "Welcome to nginx!", ok: false},
+		{title: "Index of /backups", ok: false},
+		{title: "404 Not Found", ok: false},
+	}
+	for _, tc := range cases {
+		_, _, score, ok := NormalizeCandidate("http_title", tc.title)
+		if ok != tc.ok {
+			t.Fatalf("%q: expected ok=%v, got %v", tc.title, tc.ok, ok)
+		}
+		if ok && score >= 70 {
+			t.Fatalf("%q: expected title candidates to stay below the auto display-name bar, got %d", tc.title, score)
+		}
+	}
+}
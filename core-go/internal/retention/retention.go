@@ -0,0 +1,329 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/metrics"
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+// Where an effective policy comes from.
+const (
+	SourceEnv = "env"
+	SourceAPI = "api"
+)
+
+// Tables lists the tables the job prunes, in the order it prunes them.
+var Tables = []string{
+	sqlcgen.RetentionTableIPObservations,
+	sqlcgen.RetentionTableMACObservations,
+	sqlcgen.RetentionTableDiscoveryRunLogs,
+}
+
+// Errors returned by Policy.Validate.
+var (
+	ErrUnknownTable  = errors.New("unknown retention table")
+	ErrNegativeDays  = errors.New("retention days must not be negative")
+	ErrNoDailyTier   = errors.New("table has no daily tier")
+	ErrDailyNotAbove = errors.New("keep_daily_days must be greater than keep_raw_days")
+)
+
+// Policy keeps every row of Table for KeepRawDays, then the first and last row per device, address and UTC day
+// until KeepDailyDays, then drops it. KeepRawDays 0 keeps the table forever; KeepDailyDays 0 skips the daily tier.
+type Policy struct {
+	Table         string
+	KeepRawDays   int
+	KeepDailyDays int
+	Source        string
+	// UpdatedAt is when an API override was last set; zero for env defaults.
+	UpdatedAt time.Time
+}
+
+// Validate rejects unknown tables, negative windows and a daily tier that is unsupported or never reached.
+func (p Policy) Validate() error {
+	if !sqlcgen.RetentionTableKnown(p.Table) {
+		return ErrUnknownTable
+	}
+	if p.KeepRawDays < 0 || p.KeepDailyDays < 0 {
+		return ErrNegativeDays
+	}
+	if p.KeepDailyDays == 0 {
+		return nil
+	}
+	if !sqlcgen.RetentionTableThins(p.Table) {
+		return ErrNoDailyTier
+	}
+	if p.KeepDailyDays <= p.KeepRawDays {
+		return ErrDailyNotAbove
+	}
+	return nil
+}
+
+// Enabled reports whether the policy deletes anything.
+func (p Policy) Enabled() bool {
+	return p.KeepRawDays > 0
+}
+
+// Cutoffs are the boundaries of a policy at one instant: rows before Drop are deleted and rows in
+// [Drop, Thin) are reduced to the first and last per device, address and day. Thin equals Drop without a daily tier.
+type Cutoffs struct {
+	Thin time.Time
+	Drop time.Time
+}
+
+// Cutoffs returns the tier boundaries at now. Call only for enabled policies.
+func (p Policy) Cutoffs(now time.Time) Cutoffs {
+	thin := now.Add(-days(p.KeepRawDays))
+	if p.KeepDailyDays <= p.KeepRawDays {
+		return Cutoffs{Thin: thin, Drop: thin}
+	}
+	return Cutoffs{Thin: thin, Drop: now.Add(-days(p.KeepDailyDays))}
+}
+
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// Effective merges API overrides over the env defaults, returning one policy per managed table.
+func Effective(defaults []Policy, overrides []sqlcgen.RetentionPolicy) []Policy {
+	byTable := make(map[string]Policy, len(Tables))
+	for _, t := range Tables {
+		byTable[t] = Policy{Table: t, Source: SourceEnv}
+	}
+	for _, p := range defaults {
+		if _, ok := byTable[p.Table]; ok {
+			p.Source = SourceEnv
+			byTable[p.Table] = p
+		}
+	}
+	for _, o := range overrides {
+		if _, ok := byTable[o.TableName]; ok {
+			byTable[o.TableName] = Policy{
+				Table:         o.TableName,
+				KeepRawDays:   int(o.KeepRawDays),
+				KeepDailyDays: int(o.KeepDailyDays),
+				Source:        SourceAPI,
+				UpdatedAt:     o.UpdatedAt,
+			}
+		}
+	}
+
+	out := make([]Policy, 0, len(byTable))
+	for _, t := range Tables {
+		out = append(out, byTable[t])
+	}
+	return out
+}
+
+// AddressHistoryTables are the tables point-in-time device reads rebuild IPs and MACs from.
+var AddressHistoryTables = []string{
+	sqlcgen.RetentionTableIPObservations,
+	sqlcgen.RetentionTableMACObservations,
+}
+
+// Horizon is the earliest instant that tables still cover at now: the latest drop cutoff among their enabled
+// policies. Older rows are gone; rows between the drop and thin cutoffs are down to the first and last per device,
+// address and day. ok is false when no policy for tables deletes anything.
+func Horizon(policies []Policy, tables []string, now time.Time) (horizon time.Time, ok bool) {
+	for _, p := range policies {
+		if !p.Enabled() || p.Validate() != nil || !slices.Contains(tables, p.Table) {
+			continue
+		}
+		if drop := p.Cutoffs(now).Drop; !ok || drop.After(horizon) {
+			horizon, ok = drop, true
+		}
+	}
+	return horizon, ok
+}
+
+// Queries is the DB interface the retention job needs. *sqlcgen.Queries satisfies it.
+type Queries interface {
+	ListRetentionPolicies(ctx context.Context) ([]sqlcgen.RetentionPolicy, error)
+	DeleteRetentionRows(ctx context.Context, arg sqlcgen.DeleteRetentionRowsParams) (int64, error)
+	ThinRetentionRows(ctx context.Context, arg sqlcgen.ThinRetentionRowsParams) (sqlcgen.ThinRetentionPage, error)
+}
+
+// ReportQueries is the DB interface the dry-run report needs.
+type ReportQueries interface {
+	ListRetentionPolicies(ctx context.Context) ([]sqlcgen.RetentionPolicy, error)
+	CountRetentionRows(ctx context.Context, arg sqlcgen.CountRetentionRowsParams) (sqlcgen.RetentionCounts, error)
+}
+
+// TableReport is what one policy would delete if the job ran at the report time.
+type TableReport struct {
+	Policy  Policy
+	Cutoffs *Cutoffs
+	Counts  sqlcgen.RetentionCounts
+}
+
+// Report counts, without deleting, the rows each effective policy would drop or thin at now.
+func Report(ctx context.Context, q ReportQueries, defaults []Policy, now time.Time) ([]TableReport, error) {
+	overrides, err := q.ListRetentionPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []TableReport
+	for _, p := range Effective(defaults, overrides) {
+		r := TableReport{Policy: p}
+		arg := sqlcgen.CountRetentionRowsParams{Table: p.Table}
+		if p.Enabled() {
+			c := p.Cutoffs(now)
+			r.Cutoffs = &c
+			arg.DropBefore, arg.ThinBefore = c.Drop, c.Thin
+		}
+		// Disabled policies still report the table size; zero cutoffs match no rows.
+		r.Counts, err = q.CountRetentionRows(ctx, arg)
+		if err != nil {
+			return nil, fmt.Errorf("count %s: %w", p.Table, err)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// Options configures the retention job.
+type Options struct {
+	Interval  time.Duration
+	BatchSize int
+	Defaults  []Policy
+}
+
+// Job periodically prunes historical tables according to the effective policies.
+type Job struct {
+	log       zerolog.Logger
+	q         Queries
+	interval  time.Duration
+	batchSize int32
+	defaults  []Policy
+	metrics   *metrics.Metrics
+}
+
+func New(log zerolog.Logger, q Queries, opts Options, m *metrics.Metrics) *Job {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 5000
+	}
+	return &Job{
+		log:       log,
+		q:         q,
+		interval:  interval,
+		batchSize: int32(batchSize),
+		defaults:  opts.Defaults,
+		metrics:   m,
+	}
+}
+
+// Run prunes once at start and then every interval until ctx is done.
+func (j *Job) Run(ctx context.Context) {
+	if j == nil || j.q == nil {
+		return
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		results, err := j.RunOnce(ctx, time.Now().UTC())
+		for _, r := range results {
+			if r.Dropped > 0 || r.Thinned > 0 {
+				j.log.Info().Str("table", r.Table).Int64("dropped", r.Dropped).Int64("thinned", r.Thinned).Msg("retention pruned rows")
+			}
+		}
+		if err != nil && ctx.Err() == nil {
+			j.log.Error().Err(err).Msg("retention run failed")
+		}
+		timer.Reset(j.interval)
+	}
+}
+
+// Result is what one run deleted from one table.
+type Result struct {
+	Table   string
+	Dropped int64
+	Thinned int64
+}
+
+// RunOnce applies every enabled policy at now, deleting in batches so no statement holds locks for long.
+// It stops at the first error, returning what was deleted so far.
+func (j *Job) RunOnce(ctx context.Context, now time.Time) ([]Result, error) {
+	overrides, err := j.q.ListRetentionPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, p := range Effective(j.defaults, overrides) {
+		if !p.Enabled() {
+			continue
+		}
+		if err := p.Validate(); err != nil {
+			j.log.Warn().Err(err).Str("table", p.Table).Msg("skipping invalid retention policy")
+			continue
+		}
+		c := p.Cutoffs(now)
+		r := Result{Table: p.Table}
+
+		r.Dropped, err = j.batch(ctx, func() (int64, error) {
+			return j.q.DeleteRetentionRows(ctx, sqlcgen.DeleteRetentionRowsParams{Table: p.Table, Before: c.Drop, Limit: j.batchSize})
+		})
+		j.metrics.AddRetentionRowsDeleted(p.Table, "drop", r.Dropped)
+		if err == nil && c.Thin.After(c.Drop) {
+			r.Thinned, err = j.thin(ctx, p.Table, c)
+			j.metrics.AddRetentionRowsDeleted(p.Table, "thin", r.Thinned)
+		}
+		results = append(results, r)
+		if err != nil {
+			return results, fmt.Errorf("prune %s: %w", p.Table, err)
+		}
+	}
+	return results, nil
+}
+
+// thin pages through [c.Drop, c.Thin) by (observed_at, id) so each batch scans only its own rows, returning the
+// total deleted.
+func (j *Job) thin(ctx context.Context, table string, c Cutoffs) (int64, error) {
+	arg := sqlcgen.ThinRetentionRowsParams{Table: table, From: c.Drop, Before: c.Thin, AfterObservedAt: c.Drop, Limit: j.batchSize}
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		page, err := j.q.ThinRetentionRows(ctx, arg)
+		total += page.Thinned
+		if err != nil || page.Scanned < int64(j.batchSize) {
+			return total, err
+		}
+		arg.AfterObservedAt, arg.AfterID = page.LastObservedAt, page.LastID
+	}
+}
+
+// batch repeats del until it deletes less than a full batch, returning the total.
+func (j *Job) batch(ctx context.Context, del func() (int64, error)) (int64, error) {
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		n, err := del()
+		total += n
+		if err != nil || n < int64(j.batchSize) {
+			return total, err
+		}
+	}
+}
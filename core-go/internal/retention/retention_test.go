@@ -0,0 +1,194 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"roller_hoops/core-go/internal/sqlcgen"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		policy Policy
+		want   error
+	}{
+		{name: "raw and daily", policy: Policy{Table: sqlcgen.RetentionTableIPObservations, KeepRawDays: 30, KeepDailyDays: 365}},
+		{name: "disabled", policy: Policy{Table: sqlcgen.RetentionTableMACObservations}},
+		{name: "run logs raw only", policy: Policy{Table: sqlcgen.RetentionTableDiscoveryRunLogs, KeepRawDays: 90}},
+		{name: "unknown table", policy: Policy{Table: "devices", KeepRawDays: 1}, want: ErrUnknownTable},
+		{name: "negative", policy: Policy{Table: sqlcgen.RetentionTableIPObservations, KeepRawDays: -1}, want: ErrNegativeDays},
+		{name: "run logs daily", policy: Policy{Table: sqlcgen.RetentionTableDiscoveryRunLogs, KeepRawDays: 7, KeepDailyDays: 30}, want: ErrNoDailyTier},
+		{name: "daily not above raw", policy: Policy{Table: sqlcgen.RetentionTableIPObservations, KeepRawDays: 30, KeepDailyDays: 30}, want: ErrDailyNotAbove},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.policy.Validate(); !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestEffective(t *testing.T) {
+	defaults := []Policy{
+		{Table: sqlcgen.RetentionTableIPObservations, KeepRawDays: 30, KeepDailyDays: 365},
+		{Table: sqlcgen.RetentionTableDiscoveryRunLogs, KeepRawDays: 90},
+	}
+	overrides := []sqlcgen.RetentionPolicy{
+		{TableName: sqlcgen.RetentionTableDiscoveryRunLogs, KeepRawDays: 14},
+		{TableName: "retired_table", KeepRawDays: 1},
+	}
+
+	got := Effective(defaults, overrides)
+	want := []Policy{
+		{Table: sqlcgen.RetentionTableIPObservations, KeepRawDays: 30, KeepDailyDays: 365, Source: SourceEnv},
+		{Table: sqlcgen.RetentionTableMACObservations, Source: SourceEnv},
+		{Table: sqlcgen.RetentionTableDiscoveryRunLogs, KeepRawDays: 14, Source: SourceAPI},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d policies, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("policy %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestHorizon(t *testing.T) {
+	now := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	policies := []Policy{
+		{Table: sqlcgen.RetentionTableIPObservations, KeepRawDays: 30, KeepDailyDays: 365},
+		{Table: sqlcgen.RetentionTableMACObservations, KeepRawDays: 60},
+		{Table: sqlcgen.RetentionTableDiscoveryRunLogs, KeepRawDays: 7},
+	}
+
+	got, ok := Horizon(policies, AddressHistoryTables, now)
+	if want := now.AddDate(0, 0, -60); !ok || !got.Equal(want) {
+		t.Fatalf("expected horizon %s, got %s (ok=%v)", want, got, ok)
+	}
+
+	policies[1].KeepRawDays = 0
+	got, ok = Horizon(policies, AddressHistoryTables, now)
+	if want := now.AddDate(0, 0, -365); !ok || !got.Equal(want) {
+		t.Fatalf("expected horizon %s once MACs are kept forever, got %s (ok=%v)", want, got, ok)
+	}
+
+	if _, ok := Horizon(policies[1:], AddressHistoryTables, now); ok {
+		t.Fatalf("expected no horizon when no address history is pruned")
+	}
+}
+
+type fakeQueries struct {
+	overrides []sqlcgen.RetentionPolicy
+	pending   map[string]int64
+	deletes   []sqlcgen.DeleteRetentionRowsParams
+	thins     []sqlcgen.ThinRetentionRowsParams
+	deleteErr error
+}
+
+func (f *fakeQueries) ListRetentionPolicies(ctx context.Context) ([]sqlcgen.RetentionPolicy, error) {
+	return f.overrides, nil
+}
+
+// take removes up to limit of the rows pending for key.
+func (f *fakeQueries) take(key string, limit int32) int64 {
+	n := min(f.pending[key], int64(limit))
+	f.pending[key] -= n
+	return n
+}
+
+func (f *fakeQueries) DeleteRetentionRows(ctx context.Context, arg sqlcgen.DeleteRetentionRowsParams) (int64, error) {
+	f.deletes = append(f.deletes, arg)
+	if f.deleteErr != nil {
+		return 0, f.deleteErr
+	}
+	return f.take(arg.Table+":drop", arg.Limit), nil
+}
+
+// ThinRetentionRows scans up to Limit pending rows and thins all of them, numbering rows from 1.
+func (f *fakeQueries) ThinRetentionRows(ctx context.Context, arg sqlcgen.ThinRetentionRowsParams) (sqlcgen.ThinRetentionPage, error) {
+	f.thins = append(f.thins, arg)
+	n := f.take(arg.Table+":thin", arg.Limit)
+	return sqlcgen.ThinRetentionPage{Scanned: n, Thinned: n, LastObservedAt: arg.Before, LastID: arg.AfterID + n}, nil
+}
+
+func TestJobRunOnce(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	q := &fakeQueries{
+		overrides: []sqlcgen.RetentionPolicy{{TableName: sqlcgen.RetentionTableDiscoveryRunLogs, KeepRawDays: 7}},
+		pending: map[string]int64{
+			"ip_observations:drop":    250,
+			"ip_observations:thin":    140,
+			"discovery_run_logs:drop": 100,
+		},
+	}
+	job := New(zerolog.Nop(), q, Options{
+		BatchSize: 100,
+		Defaults: []Policy{
+			{Table: sqlcgen.RetentionTableIPObservations, KeepRawDays: 30, KeepDailyDays: 365},
+			{Table: sqlcgen.RetentionTableDiscoveryRunLogs, KeepRawDays: 90},
+		},
+	}, nil)
+
+	results, err := job.RunOnce(context.Background(), now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Result{
+		{Table: sqlcgen.RetentionTableIPObservations, Dropped: 250, Thinned: 140},
+		{Table: sqlcgen.RetentionTableDiscoveryRunLogs, Dropped: 100},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("expected %+v, got %+v", want, results)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Fatalf("result %d: expected %+v, got %+v", i, want[i], results[i])
+		}
+	}
+
+	// 250 rows in batches of 100 take three IP deletes; 100 run logs take a full batch plus an empty one.
+	if len(q.deletes) != 5 {
+		t.Fatalf("expected 5 delete batches, got %d", len(q.deletes))
+	}
+	if got, want := q.deletes[0].Before, now.Add(-365*24*time.Hour); !got.Equal(want) {
+		t.Fatalf("expected ip drop cutoff %s, got %s", want, got)
+	}
+	if got, want := q.deletes[3].Before, now.Add(-7*24*time.Hour); !got.Equal(want) {
+		t.Fatalf("expected run log override cutoff %s, got %s", want, got)
+	}
+	// 140 rows to thin take two pages; the second resumes after the last row the first scanned.
+	if len(q.thins) != 2 {
+		t.Fatalf("expected two thin pages, got %d", len(q.thins))
+	}
+	if th := q.thins[0]; !th.From.Equal(now.Add(-365*24*time.Hour)) || !th.Before.Equal(now.Add(-30*24*time.Hour)) {
+		t.Fatalf("unexpected thin window %s..%s", th.From, th.Before)
+	}
+	if th := q.thins[0]; !th.AfterObservedAt.Equal(th.From) || th.AfterID != 0 {
+		t.Fatalf("expected the first page to start at the window, got %s/%d", th.AfterObservedAt, th.AfterID)
+	}
+	if th := q.thins[1]; !th.AfterObservedAt.Equal(th.Before) || th.AfterID != 100 {
+		t.Fatalf("expected the second page to resume after id 100, got %s/%d", th.AfterObservedAt, th.AfterID)
+	}
+}
+
+func TestJobRunOnce_StopsOnError(t *testing.T) {
+	q := &fakeQueries{pending: map[string]int64{}, deleteErr: errors.New("boom")}
+	job := New(zerolog.Nop(), q, Options{Defaults: []Policy{
+		{Table: sqlcgen.RetentionTableIPObservations, KeepRawDays: 30, KeepDailyDays: 365},
+		{Table: sqlcgen.RetentionTableMACObservations, KeepRawDays: 30},
+	}}, nil)
+
+	if _, err := job.RunOnce(context.Background(), time.Now()); err == nil {
+		t.Fatalf("expected error")
+	}
+	if len(q.deletes) != 1 || len(q.thins) != 0 {
+		t.Fatalf("expected the run to stop after the failed delete, got %d deletes and %d thins", len(q.deletes), len(q.thins))
+	}
+}
@@ -0,0 +1,186 @@
+package sqlcgen
+
+import (
+	"context"
+	"time"
+)
+
+const recordDeviceAvailability = `-- name: RecordDeviceAvailability :many
+WITH seen AS (
+  SELECT d.id AS device_id,
+         GREATEST(
+           (
+             SELECT MAX(ia.last_seen_at)
+             FROM ip_addresses ia
+             LEFT JOIN interfaces i ON i.id = ia.interface_id
+             WHERE ia.device_id = d.id OR i.device_id = d.id
+           ),
+           (
+             SELECT MAX(ma.last_seen_at)
+             FROM mac_addresses ma
+             LEFT JOIN interfaces i ON i.id = ma.interface_id
+             WHERE ma.device_id = d.id OR i.device_id = d.id
+           ),
+           (SELECT MAX(s.observed_at) FROM services s WHERE s.device_id = d.id),
+           (SELECT MAX(ds.last_success_at) FROM device_snmp ds WHERE ds.device_id = d.id)
+         ) AS last_seen_at
+  FROM devices d
+  WHERE d.archived_at IS NULL
+    AND NOT EXISTS (
+      SELECT 1
+      FROM device_monitors m
+      WHERE m.device_id = d.id
+        AND m.enabled
+        AND m.last_probe_at >= $1
+    )
+    AND (
+      $5::cidr IS NULL
+      OR EXISTS (
+        SELECT 1
+        FROM ip_addresses ia
+        LEFT JOIN interfaces i ON i.id = ia.interface_id
+        WHERE (ia.device_id = d.id OR i.device_id = d.id)
+          AND ia.ip <<= $5::cidr
+      )
+    )
+), latest AS (
+  SELECT DISTINCT ON (device_id) device_id, state, changed_at
+  FROM device_availability
+  ORDER BY device_id, changed_at DESC, id DESC
+), next AS (
+  SELECT s.device_id,
+         s.last_seen_at,
+         l.state AS previous_state,
+         l.changed_at AS previous_changed_at,
+         CASE WHEN s.last_seen_at >= $1 THEN 'up' ELSE 'down' END AS state
+  FROM seen s
+  LEFT JOIN latest l ON l.device_id = s.device_id
+  WHERE s.last_seen_at IS NOT NULL OR l.state IS NOT NULL
+)
+INSERT INTO device_availability (device_id, state, previous_state, changed_at, run_id, evidence)
+SELECT n.device_id,
+       n.state,
+       n.previous_state,
+       GREATEST(
+         CASE
+           WHEN n.state = 'up' THEN n.last_seen_at
+           ELSE COALESCE(n.last_seen_at + ($2::timestamptz - $1::timestamptz), $2::timestamptz)
+         END,
+         n.previous_changed_at
+       ),
+       $3::uuid,
+       jsonb_build_object(
+         'source', $4::text,
+         'last_seen_at', n.last_seen_at,
+         'down_after_seconds', EXTRACT(EPOCH FROM ($2::timestamptz - $1::timestamptz))::bigint
+       )
+FROM next n
+WHERE n.previous_state IS DISTINCT FROM n.state
+RETURNING device_id, state, previous_state, changed_at
+`
+
+const listDeviceAvailability = `-- name: ListDeviceAvailability :many
+SELECT id, device_id, state, previous_state, changed_at, run_id, evidence
+FROM (
+  (
+    SELECT id, device_id, state, previous_state, changed_at, run_id, evidence
+    FROM device_availability
+    WHERE device_id = $1
+      AND changed_at <= $2
+    ORDER BY changed_at DESC, id DESC
+    LIMIT 1
+  )
+  UNION ALL
+  (
+    SELECT id, device_id, state, previous_state, changed_at, run_id, evidence
+    FROM device_availability
+    WHERE device_id = $1
+      AND changed_at > $2
+      AND changed_at <= $3
+  )
+) t
+ORDER BY changed_at ASC, id ASC
+`
+
+// Availability states.
+const (
+	AvailabilityUp   = "up"
+	AvailabilityDown = "down"
+)
+
+type RecordDeviceAvailabilityParams struct {
+	// SeenAfter is Now minus the down window: devices observed since then are up.
+	SeenAfter time.Time
+	Now       time.Time
+	RunID     *string
+	// Source names what triggered the evaluation, e.g. "discovery".
+	Source string
+	// Scope limits the evaluation to devices with an IP inside this CIDR; nil evaluates every device.
+	Scope *string
+}
+
+type DeviceAvailabilityChange struct {
+	DeviceID      string
+	State         string
+	PreviousState *string
+	ChangedAt     time.Time
+}
+
+// RecordDeviceAvailability writes a transition for every device whose up/down state changed and returns them.
+func (q *Queries) RecordDeviceAvailability(ctx context.Context, arg RecordDeviceAvailabilityParams) ([]DeviceAvailabilityChange, error) {
+	rows, err := q.db.Query(ctx, recordDeviceAvailability, arg.SeenAfter, arg.Now, arg.RunID, arg.Source, arg.Scope)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []DeviceAvailabilityChange
+	for rows.Next() {
+		var i DeviceAvailabilityChange
+		if err := rows.Scan(&i.DeviceID, &i.State, &i.PreviousState, &i.ChangedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type ListDeviceAvailabilityParams struct {
+	DeviceID string
+	From     time.Time
+	To       time.Time
+}
+
+type DeviceAvailabilityTransition struct {
+	ID            int64
+	DeviceID      string
+	State         string
+	PreviousState *string
+	ChangedAt     time.Time
+	RunID         *string
+	Evidence      map[string]any
+}
+
+func (q *Queries) ListDeviceAvailability(ctx context.Context, arg ListDeviceAvailabilityParams) ([]DeviceAvailabilityTransition, error) {
+	rows, err := q.db.Query(ctx, listDeviceAvailability, arg.DeviceID, arg.From, arg.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []DeviceAvailabilityTransition
+	for rows.Next() {
+		var i DeviceAvailabilityTransition
+		if err := rows.Scan(&i.ID, &i.DeviceID, &i.State, &i.PreviousState, &i.ChangedAt, &i.RunID, &i.Evidence); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
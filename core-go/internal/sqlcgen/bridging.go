@@ -0,0 +1,130 @@
+package sqlcgen
+
+import (
+	"context"
+	"time"
+)
+
+const upsertSTPBridge = `-- name: UpsertSTPBridge :exec
+INSERT INTO stp_bridges (
+  device_id,
+  instance,
+  bridge_address,
+  priority,
+  root_address,
+  root_priority,
+  root_cost,
+  root_interface_id,
+  observed_at
+)
+VALUES ($1::uuid, $2, $3::macaddr, $4, $5::macaddr, $6, $7, $8::uuid, $9)
+ON CONFLICT (device_id, instance) DO UPDATE
+SET bridge_address = EXCLUDED.bridge_address,
+    priority = EXCLUDED.priority,
+    root_address = EXCLUDED.root_address,
+    root_priority = EXCLUDED.root_priority,
+    root_cost = EXCLUDED.root_cost,
+    root_interface_id = EXCLUDED.root_interface_id,
+    observed_at = EXCLUDED.observed_at,
+    updated_at = now()
+`
+
+type UpsertSTPBridgeParams struct {
+	DeviceID        string
+	Instance        int32
+	BridgeAddress   *string
+	Priority        *int32
+	RootAddress     *string
+	RootPriority    *int32
+	RootCost        *int32
+	RootInterfaceID *string
+	ObservedAt      time.Time
+}
+
+func (q *Queries) UpsertSTPBridge(ctx context.Context, arg UpsertSTPBridgeParams) error {
+	_, err := q.db.Exec(ctx, upsertSTPBridge,
+		arg.DeviceID,
+		arg.Instance,
+		arg.BridgeAddress,
+		arg.Priority,
+		arg.RootAddress,
+		arg.RootPriority,
+		arg.RootCost,
+		arg.RootInterfaceID,
+		arg.ObservedAt,
+	)
+	return err
+}
+
+const upsertSTPPort = `-- name: UpsertSTPPort :exec
+INSERT INTO stp_ports (interface_id, instance, device_id, state, path_cost, observed_at)
+VALUES ($1::uuid, $2, $3::uuid, $4, $5, $6)
+ON CONFLICT (interface_id, instance) DO UPDATE
+SET state = EXCLUDED.state,
+    path_cost = EXCLUDED.path_cost,
+    observed_at = EXCLUDED.observed_at,
+    updated_at = now()
+`
+
+type UpsertSTPPortParams struct {
+	InterfaceID string
+	Instance    int32
+	DeviceID    string
+	State       string
+	PathCost    *int32
+	ObservedAt  time.Time
+}
+
+func (q *Queries) UpsertSTPPort(ctx context.Context, arg UpsertSTPPortParams) error {
+	_, err := q.db.Exec(ctx, upsertSTPPort, arg.InterfaceID, arg.Instance, arg.DeviceID, arg.State, arg.PathCost, arg.ObservedAt)
+	return err
+}
+
+const deleteStaleSTPPorts = `-- name: DeleteStaleSTPPorts :execrows
+DELETE FROM stp_ports
+WHERE device_id = $1::uuid
+  AND observed_at < $2
+`
+
+type DeleteStaleSTPPortsParams struct {
+	DeviceID string
+	Before   time.Time
+}
+
+func (q *Queries) DeleteStaleSTPPorts(ctx context.Context, arg DeleteStaleSTPPortsParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, deleteStaleSTPPorts, arg.DeviceID, arg.Before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+const syncInterfaceAggregates = `-- name: SyncInterfaceAggregates :execrows
+-- Sets aggregate_interface_id for the given member interfaces of a device and clears it on every other
+-- interface of that device.
+UPDATE interfaces i
+SET aggregate_interface_id = m.aggregate_id,
+    updated_at = now()
+FROM (
+  SELECT d.id, a.aggregate_id
+  FROM interfaces d
+  LEFT JOIN unnest($2::uuid[], $3::uuid[]) AS a(member_id, aggregate_id) ON a.member_id = d.id
+  WHERE d.device_id = $1::uuid
+) m
+WHERE i.id = m.id
+  AND i.aggregate_interface_id IS DISTINCT FROM m.aggregate_id
+`
+
+type SyncInterfaceAggregatesParams struct {
+	DeviceID              string
+	MemberInterfaceIDs    []string
+	AggregateInterfaceIDs []string
+}
+
+func (q *Queries) SyncInterfaceAggregates(ctx context.Context, arg SyncInterfaceAggregatesParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, syncInterfaceAggregates, arg.DeviceID, arg.MemberInterfaceIDs, arg.AggregateInterfaceIDs)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,230 @@
+package sqlcgen
+
+import (
+	"context"
+	"time"
+)
+
+type OpenTCPService struct {
+	ID      string
+	Port    int32
+	Name    *string
+	Summary *string
+}
+
+type Certificate struct {
+	ID                string
+	DeviceID          string
+	DeviceDisplayName *string
+	ServiceID         string
+	Protocol          *string
+	Port              *int32
+	ServiceState      *string
+	FingerprintSHA256 string
+	Subject           string
+	CommonName        *string
+	SANs              []string
+	Issuer            string
+	SerialNumber      string
+	NotBefore         time.Time
+	NotAfter          time.Time
+	KeyType           string
+	SelfSigned        bool
+	FirstSeenAt       time.Time
+	LastSeenAt        time.Time
+}
+
+const listOpenTCPServicesForDevice = `-- name: ListOpenTCPServicesForDevice :many
+SELECT id,
+       port,
+       name,
+       summary
+FROM services
+WHERE device_id = $1::uuid
+  AND protocol = 'tcp'
+  AND state = 'open'
+  AND port IS NOT NULL
+ORDER BY port ASC
+LIMIT $2
+`
+
+func (q *Queries) ListOpenTCPServicesForDevice(ctx context.Context, deviceID string, limit int32) ([]OpenTCPService, error) {
+	rows, err := q.db.Query(ctx, listOpenTCPServicesForDevice, deviceID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []OpenTCPService
+	for rows.Next() {
+		var i OpenTCPService
+		if err := rows.Scan(&i.ID, &i.Port, &i.Name, &i.Summary); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertServiceCertificate = `-- name: UpsertServiceCertificate :exec
+INSERT INTO service_certificates (
+  service_id,
+  device_id,
+  fingerprint_sha256,
+  subject,
+  common_name,
+  sans,
+  issuer,
+  serial_number,
+  not_before,
+  not_after,
+  key_type,
+  self_signed,
+  first_seen_at,
+  last_seen_at
+)
+VALUES (
+  $1::uuid,
+  $2::uuid,
+  $3,
+  $4,
+  $5,
+  $6::text[],
+  $7,
+  $8,
+  $9::timestamptz,
+  $10::timestamptz,
+  $11,
+  $12,
+  $13::timestamptz,
+  $13::timestamptz
+)
+ON CONFLICT (service_id, fingerprint_sha256)
+DO UPDATE
+SET last_seen_at = EXCLUDED.last_seen_at,
+    updated_at = now()
+`
+
+type UpsertServiceCertificateParams struct {
+	ServiceID         string
+	DeviceID          string
+	FingerprintSHA256 string
+	Subject           string
+	CommonName        *string
+	SANs              []string
+	Issuer            string
+	SerialNumber      string
+	NotBefore         time.Time
+	NotAfter          time.Time
+	KeyType           string
+	SelfSigned        bool
+	ObservedAt        time.Time
+}
+
+func (q *Queries) UpsertServiceCertificate(ctx context.Context, arg UpsertServiceCertificateParams) error {
+	sans := arg.SANs
+	if sans == nil {
+		sans = []string{}
+	}
+	_, err := q.db.Exec(ctx, upsertServiceCertificate,
+		arg.ServiceID,
+		arg.DeviceID,
+		arg.FingerprintSHA256,
+		arg.Subject,
+		arg.CommonName,
+		sans,
+		arg.Issuer,
+		arg.SerialNumber,
+		arg.NotBefore,
+		arg.NotAfter,
+		arg.KeyType,
+		arg.SelfSigned,
+		arg.ObservedAt,
+	)
+	return err
+}
+
+const listCertificates = `-- name: ListCertificates :many
+WITH latest AS (
+  SELECT DISTINCT ON (service_id) *
+  FROM service_certificates
+  ORDER BY service_id, last_seen_at DESC, id
+)
+SELECT c.id,
+       c.device_id,
+       d.display_name,
+       c.service_id,
+       s.protocol,
+       s.port,
+       s.state,
+       c.fingerprint_sha256,
+       c.subject,
+       c.common_name,
+       c.sans,
+       c.issuer,
+       c.serial_number,
+       c.not_before,
+       c.not_after,
+       c.key_type,
+       c.self_signed,
+       c.first_seen_at,
+       c.last_seen_at
+FROM latest c
+JOIN services s ON s.id = c.service_id
+JOIN devices d ON d.id = c.device_id
+WHERE ($1::timestamptz IS NULL OR c.not_after <= $1::timestamptz)
+  AND ($2::timestamptz IS NULL OR (c.not_after, c.id) > ($2::timestamptz, $3::uuid))
+ORDER BY c.not_after ASC, c.id ASC
+LIMIT $4
+`
+
+type ListCertificatesParams struct {
+	ExpiresBefore *time.Time
+	AfterNotAfter *time.Time
+	AfterID       *string
+	Limit         int32
+}
+
+func (q *Queries) ListCertificates(ctx context.Context, arg ListCertificatesParams) ([]Certificate, error) {
+	rows, err := q.db.Query(ctx, listCertificates, arg.ExpiresBefore, arg.AfterNotAfter, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []Certificate
+	for rows.Next() {
+		var i Certificate
+		if err := rows.Scan(
+			&i.ID,
+			&i.DeviceID,
+			&i.DeviceDisplayName,
+			&i.ServiceID,
+			&i.Protocol,
+			&i.Port,
+			&i.ServiceState,
+			&i.FingerprintSHA256,
+			&i.Subject,
+			&i.CommonName,
+			&i.SANs,
+			&i.Issuer,
+			&i.SerialNumber,
+			&i.NotBefore,
+			&i.NotAfter,
+			&i.KeyType,
+			&i.SelfSigned,
+			&i.FirstSeenAt,
+			&i.LastSeenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,173 @@
+package sqlcgen
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const archiveDevice = `-- name: ArchiveDevice :execrows
+WITH archived AS (
+  UPDATE devices
+  SET archived_at = now()
+  WHERE id = $1
+    AND archived_at IS NULL
+  RETURNING id, archived_at
+)
+INSERT INTO device_archive_events (device_id, action, actor, reason, changed_at)
+SELECT id, 'archived', $2, $3, archived_at
+FROM archived
+`
+
+const restoreDevice = `-- name: RestoreDevice :execrows
+WITH restored AS (
+  UPDATE devices
+  SET archived_at = NULL
+  WHERE id = $1
+    AND archived_at IS NOT NULL
+  RETURNING id
+)
+INSERT INTO device_archive_events (device_id, action, actor, reason)
+SELECT id, 'restored', $2, $3
+FROM restored
+`
+
+const unarchiveReseenDevice = `-- name: UnarchiveReseenDevice :execrows
+WITH reappeared AS (
+  UPDATE devices
+  SET archived_at = NULL
+  WHERE id = $1
+    AND archived_at IS NOT NULL
+  RETURNING id
+)
+INSERT INTO device_archive_events (device_id, action, run_id)
+SELECT id, 'reappeared', $2::uuid
+FROM reappeared
+`
+
+const lockDeviceForPurge = `-- name: LockDeviceForPurge :one
+SELECT id,
+       display_name,
+       archived_at
+FROM devices
+WHERE id = $1
+FOR UPDATE
+`
+
+const purgeDevice = `-- name: PurgeDevice :exec
+DELETE FROM devices
+WHERE id = $1
+`
+
+// Device archive actions, as stored in device_archive_events and shown in the change feed.
+const (
+	DeviceArchiveActionArchived   = "archived"
+	DeviceArchiveActionRestored   = "restored"
+	DeviceArchiveActionReappeared = "reappeared"
+)
+
+// DevicePurgeAction is the audit action recorded when a device is purged.
+const DevicePurgeAction = "device.purge"
+
+// ErrDeviceNotArchived is returned by PurgeDevice for a device that is still active.
+var ErrDeviceNotArchived = errors.New("device is not archived")
+
+type DeviceArchiveParams struct {
+	ID     string
+	Actor  *string
+	Reason *string
+}
+
+// ArchiveDevice hides an active device and records who archived it. It affects 0 rows when the
+// device does not exist or is already archived.
+func (q *Queries) ArchiveDevice(ctx context.Context, arg DeviceArchiveParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, archiveDevice, arg.ID, arg.Actor, arg.Reason)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// RestoreDevice makes an archived device active again. It affects 0 rows when the device does not
+// exist or is not archived.
+func (q *Queries) RestoreDevice(ctx context.Context, arg DeviceArchiveParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, restoreDevice, arg.ID, arg.Actor, arg.Reason)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+type UnarchiveReseenDeviceParams struct {
+	DeviceID string
+	RunID    *string
+}
+
+// UnarchiveReseenDevice reactivates a device that discovery observed again; 0 rows when it was active.
+func (q *Queries) UnarchiveReseenDevice(ctx context.Context, arg UnarchiveReseenDeviceParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, unarchiveReseenDevice, arg.DeviceID, arg.RunID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+type PurgeDeviceParams struct {
+	ID        string
+	Actor     string
+	ActorRole *string
+	Reason    *string
+}
+
+var errPurgeNeedsTx = errors.New("device purge needs a connection that can begin a transaction")
+
+// PurgeDevice permanently deletes an archived device and everything that cascades from it, and writes
+// an audit event in the same transaction. It returns pgx.ErrNoRows when the device does not exist and
+// ErrDeviceNotArchived when it is still active.
+func (q *Queries) PurgeDevice(ctx context.Context, arg PurgeDeviceParams) error {
+	beginner, ok := q.db.(interface {
+		Begin(ctx context.Context) (pgx.Tx, error)
+	})
+	if !ok {
+		return errPurgeNeedsTx
+	}
+	tx, err := beginner.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var (
+		id          string
+		displayName *string
+		archivedAt  *time.Time
+	)
+	if err := tx.QueryRow(ctx, lockDeviceForPurge, arg.ID).Scan(&id, &displayName, &archivedAt); err != nil {
+		return err
+	}
+	if archivedAt == nil {
+		return ErrDeviceNotArchived
+	}
+	if _, err := tx.Exec(ctx, purgeDevice, id); err != nil {
+		return err
+	}
+
+	targetType := "device"
+	details := map[string]any{"display_name": displayName, "archived_at": archivedAt}
+	if arg.Reason != nil {
+		details["reason"] = *arg.Reason
+	}
+	if err := q.WithTx(tx).InsertAuditEvent(ctx, InsertAuditEventParams{
+		Actor:      arg.Actor,
+		ActorRole:  arg.ActorRole,
+		Action:     DevicePurgeAction,
+		TargetType: &targetType,
+		TargetID:   &id,
+		Details:    details,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
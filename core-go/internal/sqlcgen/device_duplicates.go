@@ -0,0 +1,272 @@
+package sqlcgen
+
+import (
+	"context"
+	"time"
+)
+
+const listDuplicateMatches = `-- name: ListDuplicateMatches :many
+WITH facts AS (
+  SELECT device_id, 'mac' AS signal, 'mac' AS kind, mac::text AS value
+  FROM mac_addresses
+  WHERE device_id IS NOT NULL
+  UNION
+  SELECT i.device_id, 'mac', 'interface', i.mac::text
+  FROM interfaces i
+  WHERE i.mac IS NOT NULL
+    AND NOT EXISTS (SELECT 1 FROM mac_addresses m WHERE m.device_id = i.device_id AND m.mac = i.mac)
+  UNION
+  SELECT device_id, 'sys_name', '', lower(btrim(sys_name))
+  FROM device_snmp
+  WHERE btrim(COALESCE(sys_name, '')) <> ''
+  UNION
+  SELECT device_id, 'serial', '', upper(btrim(value))
+  FROM device_custom_facts
+  WHERE fact_key IN ('serial', 'serial_number')
+    AND btrim(value) <> ''
+  UNION
+  SELECT device_id, 'ssh_host_key', '', fingerprint_sha256
+  FROM ssh_host_keys
+  UNION
+  SELECT device_id, 'name_candidate', '', lower(btrim(name))
+  FROM device_name_candidates
+  WHERE btrim(name) <> ''
+),
+pair_values AS (
+  SELECT signal, value
+  FROM facts
+  WHERE value NOT IN ('00:00:00:00:00:00', 'ff:ff:ff:ff:ff:ff')
+  GROUP BY signal, value
+  HAVING count(DISTINCT device_id) = 2
+),
+usable AS (
+  SELECT f.device_id, f.signal, f.kind, f.value
+  FROM facts f
+  JOIN pair_values p ON p.signal = f.signal AND p.value = f.value
+),
+shared AS (
+  SELECT a.device_id AS device_a_id,
+         b.device_id AS device_b_id,
+         CASE WHEN a.signal = 'mac' AND (a.kind = 'interface' OR b.kind = 'interface') THEN 'interface_mac' ELSE a.signal END AS signal,
+         a.value
+  FROM usable a
+  JOIN usable b ON b.signal = a.signal AND b.value = a.value AND a.device_id < b.device_id
+),
+ip_spans AS (
+  SELECT device_id, ip, min(observed_at) AS first_seen
+  FROM ip_observations
+  GROUP BY device_id, ip
+),
+pair_ips AS (
+  SELECT ip
+  FROM ip_spans
+  GROUP BY ip
+  HAVING count(*) = 2
+),
+device_last AS (
+  SELECT device_id, max(observed_at) AS last_seen
+  FROM ip_observations
+  GROUP BY device_id
+),
+handoffs AS (
+  SELECT LEAST(o.device_id, n.device_id) AS device_a_id,
+         GREATEST(o.device_id, n.device_id) AS device_b_id,
+         'ip_handoff' AS signal,
+         host(o.ip) AS value
+  FROM ip_spans o
+  JOIN pair_ips p ON p.ip = o.ip
+  JOIN ip_spans n ON n.ip = o.ip AND n.device_id <> o.device_id
+  JOIN device_last l ON l.device_id = o.device_id
+  WHERE l.last_seen <= n.first_seen
+)
+SELECT DISTINCT m.device_a_id::text, m.device_b_id::text, m.signal, m.value
+FROM (
+  SELECT device_a_id, device_b_id, signal, value FROM shared
+  UNION ALL
+  SELECT device_a_id, device_b_id, signal, value FROM handoffs
+) m
+ORDER BY 1, 2, 3, 4
+LIMIT $1
+`
+
+type DuplicateMatch struct {
+	DeviceAID string
+	DeviceBID string
+	Signal    string
+	Value     string
+}
+
+func (q *Queries) ListDuplicateMatches(ctx context.Context, limit int32) ([]DuplicateMatch, error) {
+	rows, err := q.db.Query(ctx, listDuplicateMatches, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []DuplicateMatch
+	for rows.Next() {
+		var i DuplicateMatch
+		if err := rows.Scan(&i.DeviceAID, &i.DeviceBID, &i.Signal, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
+
+const replaceDeviceDuplicateCandidates = `-- name: ReplaceDeviceDuplicateCandidates :execrows
+WITH incoming AS (
+  SELECT c.device_a_id::uuid AS device_a_id,
+         c.device_b_id::uuid AS device_b_id,
+         c.score,
+         c.evidence::jsonb AS evidence
+  FROM unnest($1::text[], $2::text[], $3::int[], $4::text[]) AS c(device_a_id, device_b_id, score, evidence)
+  WHERE EXISTS (SELECT 1 FROM devices d WHERE d.id = c.device_a_id::uuid)
+    AND EXISTS (SELECT 1 FROM devices d WHERE d.id = c.device_b_id::uuid)
+    AND NOT EXISTS (
+      SELECT 1 FROM device_duplicate_dismissals x
+      WHERE x.device_a_id = c.device_a_id::uuid AND x.device_b_id = c.device_b_id::uuid
+    )
+),
+removed AS (
+  DELETE FROM device_duplicate_candidates dc
+  WHERE NOT EXISTS (SELECT 1 FROM incoming i WHERE i.device_a_id = dc.device_a_id AND i.device_b_id = dc.device_b_id)
+  RETURNING 1
+)
+INSERT INTO device_duplicate_candidates (device_a_id, device_b_id, score, evidence, first_detected_at, detected_at)
+SELECT device_a_id, device_b_id, score, evidence, $5, $5
+FROM incoming
+ON CONFLICT (device_a_id, device_b_id) DO UPDATE
+SET score = EXCLUDED.score,
+    evidence = EXCLUDED.evidence,
+    detected_at = EXCLUDED.detected_at
+`
+
+// ReplaceDeviceDuplicateCandidatesParams carries the full suggestion set; DeviceAIDs, DeviceBIDs, Scores
+// and Evidence (JSON text) are parallel arrays.
+type ReplaceDeviceDuplicateCandidatesParams struct {
+	DeviceAIDs []string
+	DeviceBIDs []string
+	Scores     []int32
+	Evidence   []string
+	DetectedAt time.Time
+}
+
+func (q *Queries) ReplaceDeviceDuplicateCandidates(ctx context.Context, arg ReplaceDeviceDuplicateCandidatesParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, replaceDeviceDuplicateCandidates,
+		arg.DeviceAIDs,
+		arg.DeviceBIDs,
+		arg.Scores,
+		arg.Evidence,
+		arg.DetectedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+const listDeviceDuplicateCandidates = `-- name: ListDeviceDuplicateCandidates :many
+SELECT c.device_a_id::text,
+       a.display_name,
+       c.device_b_id::text,
+       b.display_name,
+       c.score,
+       c.evidence,
+       c.first_detected_at,
+       c.detected_at
+FROM device_duplicate_candidates c
+JOIN devices a ON a.id = c.device_a_id
+JOIN devices b ON b.id = c.device_b_id
+WHERE c.score >= $1
+  AND NOT EXISTS (
+    SELECT 1 FROM device_duplicate_dismissals x
+    WHERE x.device_a_id = c.device_a_id AND x.device_b_id = c.device_b_id
+  )
+ORDER BY c.score DESC, c.detected_at DESC, c.device_a_id, c.device_b_id
+LIMIT $2
+`
+
+type DeviceDuplicateEvidence struct {
+	Signal string   `json:"signal"`
+	Weight int32    `json:"weight"`
+	Values []string `json:"values"`
+}
+
+type DeviceDuplicateCandidate struct {
+	DeviceAID          string
+	DeviceADisplayName *string
+	DeviceBID          string
+	DeviceBDisplayName *string
+	Score              int32
+	Evidence           []DeviceDuplicateEvidence
+	FirstDetectedAt    time.Time
+	DetectedAt         time.Time
+}
+
+func (q *Queries) ListDeviceDuplicateCandidates(ctx context.Context, minScore int32, limit int32) ([]DeviceDuplicateCandidate, error) {
+	rows, err := q.db.Query(ctx, listDeviceDuplicateCandidates, minScore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []DeviceDuplicateCandidate
+	for rows.Next() {
+		var i DeviceDuplicateCandidate
+		if err := rows.Scan(
+			&i.DeviceAID,
+			&i.DeviceADisplayName,
+			&i.DeviceBID,
+			&i.DeviceBDisplayName,
+			&i.Score,
+			&i.Evidence,
+			&i.FirstDetectedAt,
+			&i.DetectedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
+
+const dismissDeviceDuplicate = `-- name: DismissDeviceDuplicate :one
+WITH dismissed AS (
+  INSERT INTO device_duplicate_dismissals (device_a_id, device_b_id, actor, reason)
+  VALUES ($1, $2, $3, $4)
+  ON CONFLICT (device_a_id, device_b_id) DO UPDATE
+  SET actor = EXCLUDED.actor,
+      reason = EXCLUDED.reason,
+      dismissed_at = now()
+  RETURNING device_a_id, device_b_id, actor, reason, dismissed_at
+),
+cleared AS (
+  DELETE FROM device_duplicate_candidates
+  WHERE device_a_id = $1 AND device_b_id = $2
+)
+SELECT device_a_id::text, device_b_id::text, actor, reason, dismissed_at
+FROM dismissed
+`
+
+type DismissDeviceDuplicateParams struct {
+	DeviceAID string
+	DeviceBID string
+	Actor     *string
+	Reason    *string
+}
+
+type DeviceDuplicateDismissal struct {
+	DeviceAID   string
+	DeviceBID   string
+	Actor       *string
+	Reason      *string
+	DismissedAt time.Time
+}
+
+func (q *Queries) DismissDeviceDuplicate(ctx context.Context, arg DismissDeviceDuplicateParams) (DeviceDuplicateDismissal, error) {
+	row := q.db.QueryRow(ctx, dismissDeviceDuplicate, arg.DeviceAID, arg.DeviceBID, arg.Actor, arg.Reason)
+	var i DeviceDuplicateDismissal
+	err := row.Scan(&i.DeviceAID, &i.DeviceBID, &i.Actor, &i.Reason, &i.DismissedAt)
+	return i, err
+}
@@ -0,0 +1,40 @@
+package sqlcgen
+
+// Device event kinds, as stored in device_events and shown in the change feed.
+const (
+	DeviceEventKindDisplayName = "display_name"
+	DeviceEventKindMetadata    = "metadata"
+	DeviceEventKindSNMP        = "snmp"
+)
+
+// Device event actor types.
+const (
+	DeviceEventActorUser        = "user"
+	DeviceEventActorRun         = "run"
+	DeviceEventActorIntegration = "integration"
+	DeviceEventActorSystem      = "system"
+)
+
+// DeviceEventActor says who made a change that is recorded in device_events. An empty Type is
+// stored as "system".
+type DeviceEventActor struct {
+	Type   string
+	Name   *string
+	RunID  *string
+	Reason *string
+}
+
+// UserActor attributes a change to an API caller. name may be nil when the caller did not say who they are.
+func UserActor(name, reason *string) DeviceEventActor {
+	return DeviceEventActor{Type: DeviceEventActorUser, Name: name, Reason: reason}
+}
+
+// RunActor attributes a change to a discovery run.
+func RunActor(runID string) DeviceEventActor {
+	return DeviceEventActor{Type: DeviceEventActorRun, RunID: &runID}
+}
+
+// IntegrationActor attributes a change to an inventory integration or import, such as "netbox".
+func IntegrationActor(name string) DeviceEventActor {
+	return DeviceEventActor{Type: DeviceEventActorIntegration, Name: &name}
+}
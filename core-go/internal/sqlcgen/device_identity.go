@@ -0,0 +1,85 @@
+package sqlcgen
+
+import (
+	"context"
+	"time"
+)
+
+const upsertDeviceClientID = `-- name: UpsertDeviceClientID :exec
+INSERT INTO device_client_ids (device_id, client_id, first_seen_at, last_seen_at)
+VALUES ($1, $2, $3, $3)
+ON CONFLICT (device_id, client_id) DO UPDATE
+SET last_seen_at = GREATEST(device_client_ids.last_seen_at, EXCLUDED.last_seen_at)
+`
+
+type UpsertDeviceClientIDParams struct {
+	DeviceID   string
+	ClientID   string
+	ObservedAt time.Time
+}
+
+func (q *Queries) UpsertDeviceClientID(ctx context.Context, arg UpsertDeviceClientIDParams) error {
+	_, err := q.db.Exec(ctx, upsertDeviceClientID, arg.DeviceID, arg.ClientID, arg.ObservedAt)
+	return err
+}
+
+const findDeviceIDByClientID = `-- name: FindDeviceIDByClientID :one
+SELECT device_id
+FROM device_client_ids
+WHERE client_id = $1
+ORDER BY last_seen_at DESC
+LIMIT 1
+`
+
+func (q *Queries) FindDeviceIDByClientID(ctx context.Context, clientID string) (string, error) {
+	row := q.db.QueryRow(ctx, findDeviceIDByClientID, clientID)
+	var deviceID string
+	err := row.Scan(&deviceID)
+	return deviceID, err
+}
+
+const findDeviceIDByHostName = `-- name: FindDeviceIDByHostName :one
+SELECT min(c.device_id::text)
+FROM device_name_candidates c
+JOIN devices d ON d.id = c.device_id
+WHERE c.source IN ('dhcp', 'mdns', 'netbios')
+  AND lower(split_part(c.name, '.', 1)) = $1
+  AND d.archived_at IS NULL
+HAVING count(DISTINCT c.device_id) = 1
+`
+
+// FindDeviceIDByHostName expects the name in identity.HostNameKey form.
+func (q *Queries) FindDeviceIDByHostName(ctx context.Context, name string) (string, error) {
+	row := q.db.QueryRow(ctx, findDeviceIDByHostName, name)
+	var deviceID string
+	err := row.Scan(&deviceID)
+	return deviceID, err
+}
+
+const findRotatingDeviceIDByIP = `-- name: FindRotatingDeviceIDByIP :one
+SELECT o.device_id
+FROM ip_observations o
+JOIN devices d ON d.id = o.device_id
+WHERE o.ip = $1::inet
+  AND o.observed_at >= $2
+  AND d.archived_at IS NULL
+  AND EXISTS (
+    SELECT 1 FROM mac_addresses m
+    WHERE m.device_id = o.device_id
+      AND substr(m.mac::text, 2, 1) IN ('2', '6', 'a', 'e')
+  )
+ORDER BY o.observed_at DESC
+LIMIT 1
+`
+
+type FindRotatingDeviceIDByIPParams struct {
+	IP    string
+	Since time.Time
+}
+
+func (q *Queries) FindRotatingDeviceIDByIP(ctx context.Context, arg FindRotatingDeviceIDByIPParams) (string, error) {
+	row := q.db.QueryRow(ctx, findRotatingDeviceIDByIP, arg.IP, arg.Since)
+	var deviceID string
+	err := row.Scan(&deviceID)
+	return deviceID, err
+}
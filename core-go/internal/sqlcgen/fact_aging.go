@@ -0,0 +1,113 @@
+package sqlcgen
+
+import (
+	"context"
+	"time"
+)
+
+const markStaleIPAddresses = `-- name: MarkStaleIPAddresses :execrows
+UPDATE ip_addresses
+SET stale_at = now()
+WHERE stale_at IS NULL
+  AND source = 'discovery'
+  AND last_seen_at < $1
+`
+
+const markStaleMACAddresses = `-- name: MarkStaleMACAddresses :execrows
+UPDATE mac_addresses
+SET stale_at = now()
+WHERE stale_at IS NULL
+  AND source = 'discovery'
+  AND last_seen_at < $1
+`
+
+const detachStaleIPAddresses = `-- name: DetachStaleIPAddresses :execrows
+WITH expired AS (
+  SELECT a.id,
+         d.id AS device_id,
+         host(a.ip) AS value,
+         a.interface_id,
+         a.last_seen_at
+  FROM ip_addresses a
+  LEFT JOIN interfaces i ON i.id = a.interface_id
+  JOIN devices d ON d.id = COALESCE(a.device_id, i.device_id)
+  WHERE a.last_seen_at < $1
+    AND a.source = 'discovery'
+    AND d.archived_at IS NULL
+), detached AS (
+  DELETE FROM ip_addresses a
+  USING expired x
+  WHERE a.id = x.id
+  RETURNING x.device_id, x.value, x.interface_id, x.last_seen_at
+)
+INSERT INTO fact_detachments (device_id, kind, value, interface_id, last_seen_at, run_id)
+SELECT device_id, 'ip', value, interface_id, last_seen_at, $2::uuid
+FROM detached
+`
+
+const detachStaleMACAddresses = `-- name: DetachStaleMACAddresses :execrows
+WITH expired AS (
+  SELECT m.id,
+         d.id AS device_id,
+         m.mac::text AS value,
+         m.interface_id,
+         m.last_seen_at
+  FROM mac_addresses m
+  LEFT JOIN interfaces i ON i.id = m.interface_id
+  JOIN devices d ON d.id = COALESCE(m.device_id, i.device_id)
+  WHERE m.last_seen_at < $1
+    AND m.source = 'discovery'
+    AND d.archived_at IS NULL
+), detached AS (
+  DELETE FROM mac_addresses m
+  USING expired x
+  WHERE m.id = x.id
+  RETURNING x.device_id, x.value, x.interface_id, x.last_seen_at
+)
+INSERT INTO fact_detachments (device_id, kind, value, interface_id, last_seen_at, run_id)
+SELECT device_id, 'mac', value, interface_id, last_seen_at, $2::uuid
+FROM detached
+`
+
+// MarkStaleIPAddresses flags IP addresses last seen before the cutoff; matching and maps skip them.
+func (q *Queries) MarkStaleIPAddresses(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := q.db.Exec(ctx, markStaleIPAddresses, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// MarkStaleMACAddresses flags MAC addresses last seen before the cutoff.
+func (q *Queries) MarkStaleMACAddresses(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := q.db.Exec(ctx, markStaleMACAddresses, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+type DetachStaleFactsParams struct {
+	Before time.Time
+	RunID  *string
+}
+
+// DetachStaleIPAddresses removes IP addresses last seen before the cutoff from active devices and
+// records a fact_detachments row for each.
+func (q *Queries) DetachStaleIPAddresses(ctx context.Context, arg DetachStaleFactsParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, detachStaleIPAddresses, arg.Before, arg.RunID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DetachStaleMACAddresses removes MAC addresses last seen before the cutoff from active devices and
+// records a fact_detachments row for each.
+func (q *Queries) DetachStaleMACAddresses(ctx context.Context, arg DetachStaleFactsParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, detachStaleMACAddresses, arg.Before, arg.RunID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
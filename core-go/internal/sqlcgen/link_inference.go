@@ -0,0 +1,83 @@
+package sqlcgen
+
+import (
+	"context"
+	"time"
+)
+
+const upsertInferredLink = `-- name: UpsertInferredLink :execrows
+-- Skipped (0 rows) when an observed physical link already covers the device pair or either interface
+-- (routing adjacencies do not count).
+INSERT INTO links (
+  link_key,
+  a_device_id,
+  a_interface_id,
+  b_device_id,
+  b_interface_id,
+  link_type,
+  source,
+  confidence,
+  observed_at
+)
+SELECT $1, $2::uuid, $3::uuid, $4::uuid, $5::uuid, $6, 'inferred', $7, $8
+WHERE NOT EXISTS (
+  SELECT 1
+  FROM links l
+  WHERE l.source <> 'inferred'
+    AND COALESCE(l.link_type, '') NOT IN ('ospf', 'bgp')
+    AND (
+      (l.a_device_id = $2::uuid AND l.b_device_id = $4::uuid)
+      OR (l.a_device_id = $4::uuid AND l.b_device_id = $2::uuid)
+      OR ($3::uuid IS NOT NULL AND $3::uuid IN (l.a_interface_id, l.b_interface_id))
+      OR ($5::uuid IS NOT NULL AND $5::uuid IN (l.a_interface_id, l.b_interface_id))
+    )
+)
+ON CONFLICT (link_key) DO UPDATE
+SET a_interface_id = EXCLUDED.a_interface_id,
+    b_interface_id = EXCLUDED.b_interface_id,
+    link_type = EXCLUDED.link_type,
+    confidence = EXCLUDED.confidence,
+    observed_at = EXCLUDED.observed_at,
+    updated_at = now()
+WHERE links.source = 'inferred'
+`
+
+type UpsertInferredLinkParams struct {
+	LinkKey      string
+	ADeviceID    string
+	AInterfaceID *string
+	BDeviceID    string
+	BInterfaceID *string
+	LinkType     *string
+	Confidence   int32
+	ObservedAt   time.Time
+}
+
+func (q *Queries) UpsertInferredLink(ctx context.Context, arg UpsertInferredLinkParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, upsertInferredLink, arg.LinkKey, arg.ADeviceID, arg.AInterfaceID, arg.BDeviceID, arg.BInterfaceID, arg.LinkType, arg.Confidence, arg.ObservedAt)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+const deleteStaleInferredLinks = `-- name: DeleteStaleInferredLinks :execrows
+-- Removes inferred links touching the given (re-polled) devices that were not re-inferred since $2.
+DELETE FROM links
+WHERE source = 'inferred'
+  AND (a_device_id = ANY($1::uuid[]) OR b_device_id = ANY($1::uuid[]))
+  AND COALESCE(observed_at, updated_at) < $2
+`
+
+type DeleteStaleInferredLinksParams struct {
+	DeviceIDs []string
+	Before    time.Time
+}
+
+func (q *Queries) DeleteStaleInferredLinks(ctx context.Context, arg DeleteStaleInferredLinksParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, deleteStaleInferredLinks, arg.DeviceIDs, arg.Before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -41,6 +41,7 @@ JOIN interfaces i ON i.id = iv.interface_id
 JOIN devices d ON d.id = i.device_id
 WHERE iv.role = 'pvid'
   AND iv.vlan_id = $1
+  AND d.archived_at IS NULL
 ORDER BY d.id ASC
 LIMIT $2;
 `
@@ -53,6 +54,7 @@ JOIN interfaces i ON i.id = iv.interface_id
 JOIN devices d ON d.id = i.device_id
 WHERE iv.role = 'pvid'
   AND iv.vlan_id = $1
+  AND d.archived_at IS NULL
   AND d.id <> $2::uuid
 ORDER BY d.id ASC
 LIMIT $3;
@@ -99,3 +101,71 @@ func (q *Queries) ListDevicePeersInVLAN(ctx context.Context, vlanID int32, exclu
 	}
 	return items, nil
 }
+
+type MapSTPRoot struct {
+	Instance        int32
+	RootAddress     string
+	RootPriority    *int32
+	RootDeviceID    *string
+	RootDisplayName *string
+	ReportedBy      int64
+}
+
+const listSTPRoots = `-- name: ListSTPRoots :many
+-- Root bridges reported by the given devices, resolved to a device by bridge address (then any known MAC,
+-- current before stale).
+SELECT b.instance,
+       b.root_address::text AS root_address,
+       b.root_priority,
+       r.device_id::text AS root_device_id,
+       d.display_name AS root_display_name,
+       count(*) AS reported_by
+FROM stp_bridges b
+LEFT JOIN LATERAL (
+  SELECT x.device_id
+  FROM (
+    SELECT rb.device_id, 0 AS pref
+    FROM stp_bridges rb
+    WHERE rb.bridge_address = b.root_address
+    UNION ALL
+    SELECT m.device_id, CASE WHEN m.stale_at IS NULL THEN 1 ELSE 2 END AS pref
+    FROM mac_addresses m
+    WHERE m.mac = b.root_address
+  ) x
+  ORDER BY x.pref ASC, x.device_id ASC
+  LIMIT 1
+) r ON true
+LEFT JOIN devices d ON d.id = r.device_id
+WHERE b.device_id = ANY($1::uuid[])
+  AND b.root_address IS NOT NULL
+GROUP BY b.instance, b.root_address, b.root_priority, r.device_id, d.display_name
+ORDER BY b.instance ASC, reported_by DESC, root_address ASC;
+`
+
+func (q *Queries) ListSTPRoots(ctx context.Context, deviceIDs []string) ([]MapSTPRoot, error) {
+	rows, err := q.db.Query(ctx, listSTPRoots, deviceIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []MapSTPRoot
+	for rows.Next() {
+		var i MapSTPRoot
+		if err := rows.Scan(
+			&i.Instance,
+			&i.RootAddress,
+			&i.RootPriority,
+			&i.RootDeviceID,
+			&i.RootDisplayName,
+			&i.ReportedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
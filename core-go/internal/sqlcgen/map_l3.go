@@ -1,6 +1,9 @@
 package sqlcgen
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type MapDevicePeer struct {
 	ID          string
@@ -14,6 +17,8 @@ FROM ip_addresses ia
 LEFT JOIN interfaces i ON i.id = ia.interface_id
 JOIN devices d ON d.id = COALESCE(ia.device_id, i.device_id)
 WHERE ia.ip << $1::cidr
+  AND ia.stale_at IS NULL
+  AND d.archived_at IS NULL
 ORDER BY d.id ASC
 LIMIT $2;
 `
@@ -25,6 +30,8 @@ FROM ip_addresses ia
 LEFT JOIN interfaces i ON i.id = ia.interface_id
 JOIN devices d ON d.id = COALESCE(ia.device_id, i.device_id)
 WHERE ia.ip << $1::cidr
+  AND ia.stale_at IS NULL
+  AND d.archived_at IS NULL
   AND d.id <> $2::uuid
 ORDER BY d.id ASC
 LIMIT $3;
@@ -71,3 +78,145 @@ func (q *Queries) ListDevicePeersInCIDR(ctx context.Context, cidr string, exclud
 	}
 	return items, nil
 }
+
+type MapRoutingAdjacency struct {
+	LinkID       string
+	LinkKey      string
+	ADeviceID    string
+	ADisplayName *string
+	BDeviceID    string
+	BDisplayName *string
+	LinkType     string
+	State        *string
+	AAS          *int64
+	BAS          *int64
+	Area         *string
+	LastChangeAt *time.Time
+}
+
+const listRoutingAdjacencies = `-- name: ListRoutingAdjacencies :many
+SELECT l.id::text,
+       l.link_key,
+       l.a_device_id::text,
+       da.display_name,
+       l.b_device_id::text,
+       db.display_name,
+       l.link_type,
+       l.state,
+       l.a_as,
+       l.b_as,
+       l.area,
+       l.last_change_at
+FROM links l
+JOIN devices da ON da.id = l.a_device_id
+JOIN devices db ON db.id = l.b_device_id
+WHERE l.link_type IN ('ospf', 'bgp')
+  AND da.archived_at IS NULL
+  AND db.archived_at IS NULL
+  AND (l.a_device_id = ANY($1::uuid[]) OR l.b_device_id = ANY($1::uuid[]))
+ORDER BY l.link_type ASC, l.a_device_id ASC, l.b_device_id ASC
+LIMIT $2;
+`
+
+func (q *Queries) ListRoutingAdjacencies(ctx context.Context, deviceIDs []string, limit int32) ([]MapRoutingAdjacency, error) {
+	rows, err := q.db.Query(ctx, listRoutingAdjacencies, deviceIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []MapRoutingAdjacency
+	for rows.Next() {
+		var i MapRoutingAdjacency
+		if err := rows.Scan(
+			&i.LinkID,
+			&i.LinkKey,
+			&i.ADeviceID,
+			&i.ADisplayName,
+			&i.BDeviceID,
+			&i.BDisplayName,
+			&i.LinkType,
+			&i.State,
+			&i.AAS,
+			&i.BAS,
+			&i.Area,
+			&i.LastChangeAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// MapTracerouteHop is one hop of the latest traced path toward a scope; rows of a path share PathID.
+type MapTracerouteHop struct {
+	PathID      string
+	Scope       string
+	Target      string
+	Reached     bool
+	ObservedAt  time.Time
+	TTL         int32
+	IP          *string
+	DeviceID    *string
+	DisplayName *string
+	RTTMs       *float32
+}
+
+const listTraceroutePaths = `-- name: ListTraceroutePaths :many
+WITH latest AS (
+  SELECT DISTINCT ON (p.scope) p.id, p.scope, p.target, p.reached, p.observed_at
+  FROM traceroute_paths p
+  WHERE p.scope && $1::cidr
+  ORDER BY p.scope, p.observed_at DESC
+)
+SELECT latest.id::text,
+       latest.scope::text,
+       host(latest.target),
+       latest.reached,
+       latest.observed_at,
+       h.ttl,
+       host(h.ip),
+       d.id::text,
+       d.display_name,
+       h.rtt_ms
+FROM latest
+JOIN traceroute_hops h ON h.path_id = latest.id
+LEFT JOIN devices d ON d.id = h.device_id AND d.archived_at IS NULL
+ORDER BY latest.scope ASC, h.ttl ASC
+`
+
+func (q *Queries) ListTraceroutePaths(ctx context.Context, cidr string) ([]MapTracerouteHop, error) {
+	rows, err := q.db.Query(ctx, listTraceroutePaths, cidr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []MapTracerouteHop
+	for rows.Next() {
+		var i MapTracerouteHop
+		if err := rows.Scan(
+			&i.PathID,
+			&i.Scope,
+			&i.Target,
+			&i.Reached,
+			&i.ObservedAt,
+			&i.TTL,
+			&i.IP,
+			&i.DeviceID,
+			&i.DisplayName,
+			&i.RTTMs,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
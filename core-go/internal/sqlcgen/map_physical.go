@@ -6,16 +6,27 @@ import (
 )
 
 type MapDeviceLinkPeer struct {
-	LinkID          string
-	LinkKey         string
-	PeerDeviceID    string
-	PeerDisplayName *string
-	LinkType        *string
-	Source          string
-	LastSeenAt      time.Time
+	LinkID             string
+	LinkKey            string
+	PeerDeviceID       string
+	PeerDisplayName    *string
+	LinkType           *string
+	Source             string
+	LastSeenAt         time.Time
+	LocalInterfaceID   *string
+	LocalInterfaceName *string
+	LocalAggregateID   *string
+	LocalAggregateName *string
+	PeerInterfaceID    *string
+	PeerAggregateID    *string
+	LocalSTPState      *string
+	PeerSTPState       *string
 }
 
 const listDeviceLinkPeers = `-- name: ListDeviceLinkPeers :many
+-- One row per link (parallel links to the same peer are kept so aggregates can be grouped). STP state
+-- prefers the aggregate port's state, since spanning tree runs on the logical port. Routing adjacencies
+-- (OSPF/BGP) belong to the L3 projection and are left out.
 WITH links_with_peers AS (
   SELECT l.id::text AS link_id,
          l.link_key,
@@ -24,26 +35,45 @@ WITH links_with_peers AS (
            ELSE l.a_device_id
          END AS peer_device_uuid,
          CASE
-           WHEN l.a_device_id = $1::uuid THEN l.b_device_id::text
-           ELSE l.a_device_id::text
-         END AS peer_device_id,
+           WHEN l.a_device_id = $1::uuid THEN l.a_interface_id
+           ELSE l.b_interface_id
+         END AS local_interface_uuid,
+         CASE
+           WHEN l.a_device_id = $1::uuid THEN l.b_interface_id
+           ELSE l.a_interface_id
+         END AS peer_interface_uuid,
          l.link_type,
          l.source,
          COALESCE(l.observed_at, l.updated_at) AS last_seen_at
   FROM links l
-  WHERE l.a_device_id = $1::uuid OR l.b_device_id = $1::uuid
+  WHERE (l.a_device_id = $1::uuid OR l.b_device_id = $1::uuid)
+    AND COALESCE(l.link_type, '') NOT IN ('ospf', 'bgp')
 )
-SELECT DISTINCT ON (l.peer_device_id)
-       l.link_id,
+SELECT l.link_id,
        l.link_key,
-       l.peer_device_id,
+       l.peer_device_uuid::text AS peer_device_id,
        d.display_name AS peer_display_name,
        l.link_type,
        l.source,
-       l.last_seen_at
+       l.last_seen_at,
+       l.local_interface_uuid::text AS local_interface_id,
+       li.name AS local_interface_name,
+       li.aggregate_interface_id::text AS local_aggregate_id,
+       la.name AS local_aggregate_name,
+       l.peer_interface_uuid::text AS peer_interface_id,
+       pi.aggregate_interface_id::text AS peer_aggregate_id,
+       COALESCE(las.state, ls.state) AS local_stp_state,
+       COALESCE(pas.state, ps.state) AS peer_stp_state
 FROM links_with_peers l
-JOIN devices d ON d.id = l.peer_device_uuid
-ORDER BY l.peer_device_id ASC, l.link_id ASC
+JOIN devices d ON d.id = l.peer_device_uuid AND d.archived_at IS NULL
+LEFT JOIN interfaces li ON li.id = l.local_interface_uuid
+LEFT JOIN interfaces la ON la.id = li.aggregate_interface_id
+LEFT JOIN interfaces pi ON pi.id = l.peer_interface_uuid
+LEFT JOIN stp_ports ls ON ls.interface_id = l.local_interface_uuid AND ls.instance = 0
+LEFT JOIN stp_ports las ON las.interface_id = li.aggregate_interface_id AND las.instance = 0
+LEFT JOIN stp_ports ps ON ps.interface_id = l.peer_interface_uuid AND ps.instance = 0
+LEFT JOIN stp_ports pas ON pas.interface_id = pi.aggregate_interface_id AND pas.instance = 0
+ORDER BY peer_device_id ASC, l.link_id ASC
 LIMIT $2;
 `
 
@@ -65,6 +95,14 @@ func (q *Queries) ListDeviceLinkPeers(ctx context.Context, deviceID string, limi
 			&i.LinkType,
 			&i.Source,
 			&i.LastSeenAt,
+			&i.LocalInterfaceID,
+			&i.LocalInterfaceName,
+			&i.LocalAggregateID,
+			&i.LocalAggregateName,
+			&i.PeerInterfaceID,
+			&i.PeerAggregateID,
+			&i.LocalSTPState,
+			&i.PeerSTPState,
 		); err != nil {
 			return nil, err
 		}
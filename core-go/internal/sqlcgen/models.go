@@ -8,6 +8,7 @@ type Device struct {
 	Owner       *string
 	Location    *string
 	Notes       *string
+	ArchivedAt  *time.Time
 }
 
 type DeviceListItem struct {
@@ -21,6 +22,7 @@ type DeviceListItem struct {
 	UpdatedAt    time.Time
 	LastSeenAt   *time.Time
 	LastChangeAt time.Time
+	ArchivedAt   *time.Time
 	SortTs       time.Time
 }
 
@@ -55,6 +57,8 @@ type DeviceIP struct {
 	InterfaceName *string
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
+	LastSeenAt    time.Time
+	StaleAt       *time.Time
 }
 
 type DeviceMAC struct {
@@ -63,6 +67,8 @@ type DeviceMAC struct {
 	InterfaceName *string
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
+	LastSeenAt    time.Time
+	StaleAt       *time.Time
 }
 
 type DeviceInterface struct {
@@ -83,14 +89,29 @@ type DeviceInterface struct {
 }
 
 type DeviceService struct {
-	Protocol   *string
-	Port       *int32
-	Name       *string
-	State      *string
-	Source     *string
-	ObservedAt time.Time
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
+	Protocol        *string
+	Port            *int32
+	Name            *string
+	State           *string
+	Source          *string
+	Summary         *string
+	ObservedAt      time.Time
+	FirstSeenAt     *time.Time
+	LastSeenAt      *time.Time
+	ClosedAt        *time.Time
+	HTTPStatus      *int32
+	HTTPTitle       *string
+	HTTPServer      *string
+	HTTPPoweredBy   *string
+	HTTPFaviconHash *int32
+	HTTPFinalURL    *string
+	HTTPObservedAt  *time.Time
+	Product         *string
+	Version         *string
+	ExtraInfo       *string
+	CPE             []string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
 type DeviceSNMP struct {
@@ -114,6 +135,12 @@ type DeviceLink struct {
 	PeerInterfaceID  *string
 	LinkType         *string
 	Source           string
+	Confidence       *int32
+	State            *string
+	LocalAS          *int64
+	PeerAS           *int64
+	Area             *string
+	LastChangeAt     *time.Time
 	ObservedAt       *time.Time
 	UpdatedAt        time.Time
 }
@@ -0,0 +1,447 @@
+package sqlcgen
+
+import (
+	"context"
+	"time"
+)
+
+const syncTaggedDeviceMonitors = `-- name: SyncTaggedDeviceMonitors :one
+WITH tagged AS (
+  SELECT DISTINCT dt.device_id
+  FROM device_tags dt
+  JOIN devices d ON d.id = dt.device_id
+  WHERE dt.tag = ANY($1::text[])
+    AND d.archived_at IS NULL
+), removed AS (
+  DELETE FROM device_monitors m
+  WHERE m.source = 'tag'
+    AND m.device_id NOT IN (SELECT device_id FROM tagged)
+  RETURNING m.device_id
+), upserted AS (
+  INSERT INTO device_monitors (device_id, source, method)
+  SELECT device_id, 'tag', $2
+  FROM tagged
+  ON CONFLICT (device_id) DO UPDATE
+  SET method = EXCLUDED.method,
+      port = NULL,
+      updated_at = now()
+  WHERE device_monitors.source = 'tag'
+    AND device_monitors.method <> EXCLUDED.method
+  RETURNING device_id
+)
+SELECT (SELECT count(*) FROM removed) + (SELECT count(*) FROM upserted)
+`
+
+const listMonitorTargets = `-- name: ListMonitorTargets :many
+SELECT m.device_id,
+       m.method,
+       COALESCE(
+         m.port,
+         (
+           SELECT MIN(s.port)
+           FROM services s
+           WHERE s.device_id = m.device_id
+             AND s.protocol = 'tcp'
+             AND s.state = 'open'
+         )
+       ) AS port,
+       (
+         SELECT host(ia.ip)
+         FROM ip_addresses ia
+         LEFT JOIN interfaces i ON i.id = ia.interface_id
+         WHERE ia.device_id = m.device_id OR i.device_id = m.device_id
+         ORDER BY ia.stale_at IS NOT NULL, ia.last_seen_at DESC, family(ia.ip) ASC, ia.ip ASC
+         LIMIT 1
+       ) AS address,
+       m.consecutive_failures
+FROM device_monitors m
+JOIN devices d ON d.id = m.device_id
+WHERE m.enabled
+  AND d.archived_at IS NULL
+ORDER BY m.device_id ASC
+`
+
+const recordReachabilitySample = `-- name: RecordReachabilitySample :one
+WITH sample AS (
+  INSERT INTO reachability_samples (device_id, probed_at, method, address, port, success, rtt_ms)
+  VALUES ($1::uuid, $2::timestamptz, $3, $4, $5, $6::boolean, $7::double precision)
+  RETURNING device_id
+), rollup AS (
+  INSERT INTO reachability_rollups (device_id, bucket_start, probes, failures, rtt_sum_ms, rtt_min_ms, rtt_max_ms)
+  VALUES (
+    $1::uuid,
+    date_trunc('hour', $2::timestamptz, 'UTC'),
+    1,
+    CASE WHEN $6::boolean THEN 0 ELSE 1 END,
+    COALESCE($7::double precision, 0),
+    $7::double precision,
+    $7::double precision
+  )
+  ON CONFLICT (device_id, bucket_start) DO UPDATE
+  SET probes = reachability_rollups.probes + 1,
+      failures = reachability_rollups.failures + EXCLUDED.failures,
+      rtt_sum_ms = reachability_rollups.rtt_sum_ms + EXCLUDED.rtt_sum_ms,
+      rtt_min_ms = LEAST(reachability_rollups.rtt_min_ms, EXCLUDED.rtt_min_ms),
+      rtt_max_ms = GREATEST(reachability_rollups.rtt_max_ms, EXCLUDED.rtt_max_ms)
+  RETURNING device_id
+)
+UPDATE device_monitors
+SET last_probe_at = $2::timestamptz,
+    last_success_at = CASE WHEN $6::boolean THEN $2::timestamptz ELSE last_success_at END,
+    last_rtt_ms = CASE WHEN $6::boolean THEN $7::double precision ELSE last_rtt_ms END,
+    consecutive_failures = CASE WHEN $6::boolean THEN 0 ELSE consecutive_failures + 1 END
+WHERE device_id = $1::uuid
+RETURNING consecutive_failures, last_success_at
+`
+
+const recordMonitorAvailability = `-- name: RecordMonitorAvailability :execrows
+INSERT INTO device_availability (device_id, state, previous_state, changed_at, evidence)
+SELECT $1::uuid, $2::text, l.state, GREATEST($3::timestamptz, l.changed_at), $4::jsonb
+FROM (SELECT 1) one
+LEFT JOIN LATERAL (
+  SELECT state, changed_at
+  FROM device_availability
+  WHERE device_id = $1::uuid
+  ORDER BY changed_at DESC, id DESC
+  LIMIT 1
+) l ON true
+WHERE l.state IS DISTINCT FROM $2::text
+`
+
+const deleteReachabilitySamplesBefore = `-- name: DeleteReachabilitySamplesBefore :execrows
+DELETE FROM reachability_samples
+WHERE id IN (
+  SELECT id
+  FROM reachability_samples
+  WHERE probed_at < $1
+  ORDER BY probed_at ASC
+  LIMIT $2
+)
+`
+
+const deleteReachabilityRollupsBefore = `-- name: DeleteReachabilityRollupsBefore :execrows
+DELETE FROM reachability_rollups
+WHERE (device_id, bucket_start) IN (
+  SELECT device_id, bucket_start
+  FROM reachability_rollups
+  WHERE bucket_start < $1
+  ORDER BY bucket_start ASC
+  LIMIT $2
+)
+`
+
+const getDeviceMonitor = `-- name: GetDeviceMonitor :one
+SELECT device_id,
+       source,
+       enabled,
+       method,
+       port,
+       last_probe_at,
+       last_success_at,
+       last_rtt_ms,
+       consecutive_failures,
+       created_at,
+       updated_at
+FROM device_monitors
+WHERE device_id = $1
+`
+
+const upsertDeviceMonitor = `-- name: UpsertDeviceMonitor :one
+INSERT INTO device_monitors (device_id, source, enabled, method, port)
+VALUES ($1, 'manual', $2, $3, $4)
+ON CONFLICT (device_id) DO UPDATE
+SET source = 'manual',
+    enabled = EXCLUDED.enabled,
+    method = EXCLUDED.method,
+    port = EXCLUDED.port,
+    consecutive_failures = CASE
+      WHEN device_monitors.method = EXCLUDED.method AND device_monitors.port IS NOT DISTINCT FROM EXCLUDED.port
+        THEN device_monitors.consecutive_failures
+      ELSE 0
+    END,
+    updated_at = now()
+RETURNING device_id,
+          source,
+          enabled,
+          method,
+          port,
+          last_probe_at,
+          last_success_at,
+          last_rtt_ms,
+          consecutive_failures,
+          created_at,
+          updated_at
+`
+
+const deleteDeviceMonitor = `-- name: DeleteDeviceMonitor :execrows
+DELETE FROM device_monitors
+WHERE device_id = $1
+`
+
+const listReachabilitySamples = `-- name: ListReachabilitySamples :many
+SELECT probed_at, method, address, port, success, rtt_ms
+FROM reachability_samples
+WHERE device_id = $1
+  AND probed_at >= $2
+  AND probed_at < $3
+ORDER BY probed_at ASC, id ASC
+`
+
+const listReachabilityRollups = `-- name: ListReachabilityRollups :many
+SELECT bucket_start,
+       probes,
+       failures,
+       CASE WHEN probes > failures THEN rtt_sum_ms / (probes - failures) END AS rtt_avg_ms,
+       rtt_min_ms,
+       rtt_max_ms
+FROM reachability_rollups
+WHERE device_id = $1
+  AND bucket_start >= $2
+  AND bucket_start < $3
+ORDER BY bucket_start ASC
+`
+
+// Monitor probe methods and sources.
+const (
+	MonitorMethodICMP   = "icmp"
+	MonitorMethodTCP    = "tcp"
+	MonitorSourceManual = "manual"
+	MonitorSourceTag    = "tag"
+)
+
+type SyncTaggedDeviceMonitorsParams struct {
+	Tags   []string
+	Method string
+}
+
+// SyncTaggedDeviceMonitors adds and removes tag monitors to match the tagged devices, returning how many changed.
+func (q *Queries) SyncTaggedDeviceMonitors(ctx context.Context, arg SyncTaggedDeviceMonitorsParams) (int64, error) {
+	var changed int64
+	err := q.db.QueryRow(ctx, syncTaggedDeviceMonitors, arg.Tags, arg.Method).Scan(&changed)
+	return changed, err
+}
+
+type MonitorTarget struct {
+	DeviceID string
+	Method   string
+	// Port is nil for icmp and for tcp monitors of devices without an open TCP service.
+	Port *int32
+	// Address is nil when the device has no IP.
+	Address             *string
+	ConsecutiveFailures int32
+}
+
+func (q *Queries) ListMonitorTargets(ctx context.Context) ([]MonitorTarget, error) {
+	rows, err := q.db.Query(ctx, listMonitorTargets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []MonitorTarget
+	for rows.Next() {
+		var i MonitorTarget
+		if err := rows.Scan(&i.DeviceID, &i.Method, &i.Port, &i.Address, &i.ConsecutiveFailures); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type RecordReachabilitySampleParams struct {
+	DeviceID string
+	ProbedAt time.Time
+	Method   string
+	Address  string
+	Port     *int32
+	Success  bool
+	// RTTMs is nil for failed probes.
+	RTTMs *float64
+}
+
+type MonitorStatus struct {
+	ConsecutiveFailures int32
+	LastSuccessAt       *time.Time
+}
+
+// RecordReachabilitySample returns pgx.ErrNoRows when the monitor was removed while the probe ran.
+func (q *Queries) RecordReachabilitySample(ctx context.Context, arg RecordReachabilitySampleParams) (MonitorStatus, error) {
+	var i MonitorStatus
+	err := q.db.QueryRow(ctx, recordReachabilitySample,
+		arg.DeviceID,
+		arg.ProbedAt,
+		arg.Method,
+		arg.Address,
+		arg.Port,
+		arg.Success,
+		arg.RTTMs,
+	).Scan(&i.ConsecutiveFailures, &i.LastSuccessAt)
+	return i, err
+}
+
+type RecordMonitorAvailabilityParams struct {
+	DeviceID  string
+	State     string
+	ChangedAt time.Time
+	Evidence  map[string]any
+}
+
+func (q *Queries) RecordMonitorAvailability(ctx context.Context, arg RecordMonitorAvailabilityParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, recordMonitorAvailability, arg.DeviceID, arg.State, arg.ChangedAt, arg.Evidence)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (q *Queries) DeleteReachabilitySamplesBefore(ctx context.Context, before time.Time, limit int32) (int64, error) {
+	tag, err := q.db.Exec(ctx, deleteReachabilitySamplesBefore, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (q *Queries) DeleteReachabilityRollupsBefore(ctx context.Context, before time.Time, limit int32) (int64, error) {
+	tag, err := q.db.Exec(ctx, deleteReachabilityRollupsBefore, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+type DeviceMonitor struct {
+	DeviceID            string
+	Source              string
+	Enabled             bool
+	Method              string
+	Port                *int32
+	LastProbeAt         *time.Time
+	LastSuccessAt       *time.Time
+	LastRTTMs           *float64
+	ConsecutiveFailures int32
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+func (q *Queries) GetDeviceMonitor(ctx context.Context, deviceID string) (DeviceMonitor, error) {
+	var i DeviceMonitor
+	err := q.db.QueryRow(ctx, getDeviceMonitor, deviceID).Scan(
+		&i.DeviceID,
+		&i.Source,
+		&i.Enabled,
+		&i.Method,
+		&i.Port,
+		&i.LastProbeAt,
+		&i.LastSuccessAt,
+		&i.LastRTTMs,
+		&i.ConsecutiveFailures,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+type UpsertDeviceMonitorParams struct {
+	DeviceID string
+	Enabled  bool
+	Method   string
+	Port     *int32
+}
+
+func (q *Queries) UpsertDeviceMonitor(ctx context.Context, arg UpsertDeviceMonitorParams) (DeviceMonitor, error) {
+	var i DeviceMonitor
+	err := q.db.QueryRow(ctx, upsertDeviceMonitor, arg.DeviceID, arg.Enabled, arg.Method, arg.Port).Scan(
+		&i.DeviceID,
+		&i.Source,
+		&i.Enabled,
+		&i.Method,
+		&i.Port,
+		&i.LastProbeAt,
+		&i.LastSuccessAt,
+		&i.LastRTTMs,
+		&i.ConsecutiveFailures,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+func (q *Queries) DeleteDeviceMonitor(ctx context.Context, deviceID string) (int64, error) {
+	tag, err := q.db.Exec(ctx, deleteDeviceMonitor, deviceID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+type ListReachabilityParams struct {
+	DeviceID string
+	From     time.Time
+	To       time.Time
+}
+
+type ReachabilitySample struct {
+	ProbedAt time.Time
+	Method   string
+	Address  string
+	Port     *int32
+	Success  bool
+	RTTMs    *float64
+}
+
+func (q *Queries) ListReachabilitySamples(ctx context.Context, arg ListReachabilityParams) ([]ReachabilitySample, error) {
+	rows, err := q.db.Query(ctx, listReachabilitySamples, arg.DeviceID, arg.From, arg.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ReachabilitySample
+	for rows.Next() {
+		var i ReachabilitySample
+		if err := rows.Scan(&i.ProbedAt, &i.Method, &i.Address, &i.Port, &i.Success, &i.RTTMs); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type ReachabilityRollup struct {
+	BucketStart time.Time
+	Probes      int32
+	Failures    int32
+	// RTT aggregates cover successful probes only and are nil for an hour without one.
+	RTTAvgMs *float64
+	RTTMinMs *float64
+	RTTMaxMs *float64
+}
+
+func (q *Queries) ListReachabilityRollups(ctx context.Context, arg ListReachabilityParams) ([]ReachabilityRollup, error) {
+	rows, err := q.db.Query(ctx, listReachabilityRollups, arg.DeviceID, arg.From, arg.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ReachabilityRollup
+	for rows.Next() {
+		var i ReachabilityRollup
+		if err := rows.Scan(&i.BucketStart, &i.Probes, &i.Failures, &i.RTTAvgMs, &i.RTTMinMs, &i.RTTMaxMs); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,160 @@
+package sqlcgen
+
+import (
+	"context"
+	"time"
+)
+
+type DeviceOSGuess struct {
+	Source       string
+	Rank         int32
+	Name         string
+	Accuracy     int32
+	Vendor       *string
+	OSFamily     *string
+	OSGeneration *string
+	DeviceType   *string
+	CPE          []string
+	ObservedAt   time.Time
+}
+
+const upsertDeviceOSGuess = `-- name: UpsertDeviceOSGuess :exec
+INSERT INTO device_os_guesses (
+  device_id,
+  source,
+  rank,
+  name,
+  accuracy,
+  vendor,
+  os_family,
+  os_generation,
+  device_type,
+  cpe,
+  observed_at
+)
+VALUES (
+  $1::uuid,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7,
+  $8,
+  $9,
+  $10::text[],
+  $11::timestamptz
+)
+ON CONFLICT (device_id, source, rank)
+DO UPDATE
+SET name = EXCLUDED.name,
+    accuracy = EXCLUDED.accuracy,
+    vendor = EXCLUDED.vendor,
+    os_family = EXCLUDED.os_family,
+    os_generation = EXCLUDED.os_generation,
+    device_type = EXCLUDED.device_type,
+    cpe = EXCLUDED.cpe,
+    observed_at = EXCLUDED.observed_at,
+    updated_at = now()
+`
+
+type UpsertDeviceOSGuessParams struct {
+	DeviceID     string
+	Source       string
+	Rank         int32
+	Name         string
+	Accuracy     int32
+	Vendor       *string
+	OSFamily     *string
+	OSGeneration *string
+	DeviceType   *string
+	CPE          []string
+	ObservedAt   time.Time
+}
+
+func (q *Queries) UpsertDeviceOSGuess(ctx context.Context, arg UpsertDeviceOSGuessParams) error {
+	cpe := arg.CPE
+	if cpe == nil {
+		cpe = []string{}
+	}
+	_, err := q.db.Exec(ctx, upsertDeviceOSGuess,
+		arg.DeviceID,
+		arg.Source,
+		arg.Rank,
+		arg.Name,
+		arg.Accuracy,
+		arg.Vendor,
+		arg.OSFamily,
+		arg.OSGeneration,
+		arg.DeviceType,
+		cpe,
+		arg.ObservedAt,
+	)
+	return err
+}
+
+const deleteDeviceOSGuessesAboveRank = `-- name: DeleteDeviceOSGuessesAboveRank :exec
+DELETE FROM device_os_guesses
+WHERE device_id = $1::uuid
+  AND source = $2
+  AND rank > $3
+`
+
+type DeleteDeviceOSGuessesAboveRankParams struct {
+	DeviceID string
+	Source   string
+	Rank     int32
+}
+
+func (q *Queries) DeleteDeviceOSGuessesAboveRank(ctx context.Context, arg DeleteDeviceOSGuessesAboveRankParams) error {
+	_, err := q.db.Exec(ctx, deleteDeviceOSGuessesAboveRank, arg.DeviceID, arg.Source, arg.Rank)
+	return err
+}
+
+const listDeviceOSGuesses = `-- name: ListDeviceOSGuesses :many
+SELECT source,
+       rank,
+       name,
+       accuracy,
+       vendor,
+       os_family,
+       os_generation,
+       device_type,
+       cpe,
+       observed_at
+FROM device_os_guesses
+WHERE device_id = $1::uuid
+ORDER BY source ASC, rank ASC
+`
+
+func (q *Queries) ListDeviceOSGuesses(ctx context.Context, deviceID string) ([]DeviceOSGuess, error) {
+	rows, err := q.db.Query(ctx, listDeviceOSGuesses, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []DeviceOSGuess
+	for rows.Next() {
+		var i DeviceOSGuess
+		if err := rows.Scan(
+			&i.Source,
+			&i.Rank,
+			&i.Name,
+			&i.Accuracy,
+			&i.Vendor,
+			&i.OSFamily,
+			&i.OSGeneration,
+			&i.DeviceType,
+			&i.CPE,
+			&i.ObservedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
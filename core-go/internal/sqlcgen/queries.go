@@ -57,13 +57,14 @@ const createDevice = `-- name: CreateDevice :one
 WITH inserted AS (
   INSERT INTO devices (display_name)
   VALUES ($1)
-  RETURNING id, display_name
+  RETURNING id, display_name, archived_at
 )
 SELECT i.id,
        i.display_name,
        m.owner,
        m.location,
-       m.notes
+       m.notes,
+       i.archived_at
 FROM inserted i
 LEFT JOIN device_metadata m ON m.device_id = i.id
 `
@@ -71,7 +72,7 @@ LEFT JOIN device_metadata m ON m.device_id = i.id
 func (q *Queries) CreateDevice(ctx context.Context, displayName *string) (Device, error) {
 	row := q.db.QueryRow(ctx, createDevice, displayName)
 	var i Device
-	err := row.Scan(&i.ID, &i.DisplayName, &i.Owner, &i.Location, &i.Notes)
+	err := row.Scan(&i.ID, &i.DisplayName, &i.Owner, &i.Location, &i.Notes, &i.ArchivedAt)
 	return i, err
 }
 
@@ -80,7 +81,8 @@ SELECT d.id,
        d.display_name,
        m.owner,
        m.location,
-       m.notes
+       m.notes,
+       d.archived_at
 FROM devices d
 LEFT JOIN device_metadata m ON m.device_id = d.id
 WHERE d.id = $1
@@ -89,7 +91,7 @@ WHERE d.id = $1
 func (q *Queries) GetDevice(ctx context.Context, id string) (Device, error) {
 	row := q.db.QueryRow(ctx, getDevice, id)
 	var i Device
-	err := row.Scan(&i.ID, &i.DisplayName, &i.Owner, &i.Location, &i.Notes)
+	err := row.Scan(&i.ID, &i.DisplayName, &i.Owner, &i.Location, &i.Notes, &i.ArchivedAt)
 	return i, err
 }
 
@@ -98,9 +100,11 @@ SELECT d.id,
        d.display_name,
        m.owner,
        m.location,
-       m.notes
+       m.notes,
+       d.archived_at
 FROM devices d
 LEFT JOIN device_metadata m ON m.device_id = d.id
+WHERE d.archived_at IS NULL
 ORDER BY d.created_at DESC
 `
 
@@ -113,7 +117,7 @@ func (q *Queries) ListDevices(ctx context.Context) ([]Device, error) {
 	var items []Device
 	for rows.Next() {
 		var i Device
-		if err := rows.Scan(&i.ID, &i.DisplayName, &i.Owner, &i.Location, &i.Notes); err != nil {
+		if err := rows.Scan(&i.ID, &i.DisplayName, &i.Owner, &i.Location, &i.Notes, &i.ArchivedAt); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -134,7 +138,7 @@ WITH computed AS (
 			FROM ip_addresses ia
 			LEFT JOIN interfaces i2 ON i2.id = ia.interface_id
 			WHERE ia.device_id = d.id OR i2.device_id = d.id
-			ORDER BY ia.updated_at DESC, ia.ip::text ASC
+			ORDER BY (ia.stale_at IS NOT NULL) ASC, ia.updated_at DESC, ia.ip::text ASC
 			LIMIT 1
 		) AS primary_ip,
 		m.owner,
@@ -142,6 +146,7 @@ WITH computed AS (
 		m.notes,
 		d.created_at,
 		d.updated_at,
+		d.archived_at,
 		(
 			SELECT MAX(ts)
 			FROM (
@@ -221,6 +226,7 @@ SELECT
 	q.updated_at,
 	q.last_seen_at,
 	q.last_change_at,
+	q.archived_at,
 	q.sort_ts
 FROM (
 	SELECT
@@ -274,6 +280,11 @@ FROM (
 			OR ($2::text = 'offline' AND (c.last_seen_at IS NULL OR c.last_seen_at < $4))
 			OR ($2::text = 'changed' AND c.last_change_at >= $5)
 		)
+		AND (
+			($9::text = 'include')
+			OR ($9::text = 'only' AND c.archived_at IS NOT NULL)
+			OR ($9::text <> 'only' AND c.archived_at IS NULL)
+		)
 ) q
 WHERE
 	($6::timestamptz IS NULL OR (q.sort_ts < $6::timestamptz OR (q.sort_ts = $6::timestamptz AND q.id < $7::uuid)))
@@ -290,6 +301,8 @@ type ListDevicesPageParams struct {
 	BeforeSortTs *time.Time
 	BeforeID     *string
 	Limit        int32
+	// Archived is "exclude" (default), "include" or "only".
+	Archived string
 }
 
 func (q *Queries) ListDevicesPage(ctx context.Context, arg ListDevicesPageParams) ([]DeviceListItem, error) {
@@ -304,6 +317,7 @@ func (q *Queries) ListDevicesPage(ctx context.Context, arg ListDevicesPageParams
 		arg.BeforeSortTs,
 		arg.BeforeID,
 		arg.Limit,
+		arg.Archived,
 	)
 	if err != nil {
 		return nil, err
@@ -324,6 +338,7 @@ func (q *Queries) ListDevicesPage(ctx context.Context, arg ListDevicesPageParams
 			&i.UpdatedAt,
 			&i.LastSeenAt,
 			&i.LastChangeAt,
+			&i.ArchivedAt,
 			&i.SortTs,
 		); err != nil {
 			return nil, err
@@ -337,18 +352,42 @@ func (q *Queries) ListDevicesPage(ctx context.Context, arg ListDevicesPageParams
 }
 
 const updateDevice = `-- name: UpdateDevice :one
-WITH updated AS (
-  UPDATE devices
+WITH prev AS (
+  SELECT id, display_name
+  FROM devices
+  WHERE id = $1
+  FOR UPDATE
+), updated AS (
+  UPDATE devices d
   SET display_name = $2,
       updated_at = now()
-  WHERE id = $1
-  RETURNING id, display_name
+  FROM prev p
+  WHERE d.id = p.id
+  RETURNING d.id, d.display_name, d.archived_at, p.display_name AS previous_name
+), event AS (
+  INSERT INTO device_events (device_id, kind, actor_type, actor, run_id, reason, before, after, summary)
+  SELECT u.id,
+         'display_name',
+         COALESCE(NULLIF($3::text, ''), 'system'),
+         $4::text,
+         $5::uuid,
+         $6::text,
+         CASE WHEN u.previous_name IS NOT NULL THEN jsonb_build_object('display_name', u.previous_name) END,
+         jsonb_build_object('display_name', u.display_name),
+         CASE
+           WHEN u.display_name IS NULL THEN 'name cleared'
+           WHEN u.previous_name IS NULL THEN 'named ' || u.display_name
+           ELSE 'renamed from ' || u.previous_name || ' to ' || u.display_name
+         END
+  FROM updated u
+  WHERE u.display_name IS DISTINCT FROM u.previous_name
 )
 SELECT u.id,
        u.display_name,
        m.owner,
        m.location,
-       m.notes
+       m.notes,
+       u.archived_at
 FROM updated u
 LEFT JOIN device_metadata m ON m.device_id = u.id
 `
@@ -356,24 +395,63 @@ LEFT JOIN device_metadata m ON m.device_id = u.id
 type UpdateDeviceParams struct {
 	ID          string
 	DisplayName *string
+	Actor       DeviceEventActor
 }
 
 func (q *Queries) UpdateDevice(ctx context.Context, arg UpdateDeviceParams) (Device, error) {
-	row := q.db.QueryRow(ctx, updateDevice, arg.ID, arg.DisplayName)
+	row := q.db.QueryRow(
+		ctx,
+		updateDevice,
+		arg.ID,
+		arg.DisplayName,
+		arg.Actor.Type,
+		arg.Actor.Name,
+		arg.Actor.RunID,
+		arg.Actor.Reason,
+	)
 	var i Device
-	err := row.Scan(&i.ID, &i.DisplayName, &i.Owner, &i.Location, &i.Notes)
+	err := row.Scan(&i.ID, &i.DisplayName, &i.Owner, &i.Location, &i.Notes, &i.ArchivedAt)
 	return i, err
 }
 
 const upsertDeviceMetadata = `-- name: UpsertDeviceMetadata :one
-INSERT INTO device_metadata (device_id, owner, location, notes)
-VALUES ($1, $2, $3, $4)
-ON CONFLICT (device_id) DO UPDATE
-SET owner = EXCLUDED.owner,
-    location = EXCLUDED.location,
-    notes = EXCLUDED.notes,
-    updated_at = now()
-RETURNING device_id, owner, location, notes
+WITH prev AS (
+  SELECT device_id, owner, location, notes
+  FROM device_metadata
+  WHERE device_id = $1::uuid
+  FOR UPDATE
+), upserted AS (
+  INSERT INTO device_metadata (device_id, owner, location, notes)
+  SELECT $1::uuid, $2::text, $3::text, $4::text
+  FROM (SELECT count(*) FROM prev) locked
+  ON CONFLICT (device_id) DO UPDATE
+  SET owner = EXCLUDED.owner,
+      location = EXCLUDED.location,
+      notes = EXCLUDED.notes,
+      updated_at = now()
+  RETURNING device_id, owner, location, notes
+), event AS (
+  INSERT INTO device_events (device_id, kind, actor_type, actor, run_id, reason, before, after, summary)
+  SELECT u.device_id,
+         'metadata',
+         COALESCE(NULLIF($5::text, ''), 'system'),
+         $6::text,
+         $7::uuid,
+         $8::text,
+         CASE WHEN p.device_id IS NOT NULL THEN jsonb_build_object('owner', p.owner, 'location', p.location, 'notes', p.notes) END,
+         jsonb_build_object('owner', u.owner, 'location', u.location, 'notes', u.notes),
+         'metadata updated: ' || concat_ws(
+           ', ',
+           CASE WHEN p.owner IS DISTINCT FROM u.owner THEN 'owner' END,
+           CASE WHEN p.location IS DISTINCT FROM u.location THEN 'location' END,
+           CASE WHEN p.notes IS DISTINCT FROM u.notes THEN 'notes' END
+         )
+  FROM upserted u
+  LEFT JOIN prev p ON true
+  WHERE (p.owner, p.location, p.notes) IS DISTINCT FROM (u.owner, u.location, u.notes)
+)
+SELECT device_id, owner, location, notes
+FROM upserted
 `
 
 type UpsertDeviceMetadataParams struct {
@@ -381,37 +459,89 @@ type UpsertDeviceMetadataParams struct {
 	Owner    *string
 	Location *string
 	Notes    *string
+	Actor    DeviceEventActor
 }
 
 func (q *Queries) UpsertDeviceMetadata(ctx context.Context, arg UpsertDeviceMetadataParams) (DeviceMetadata, error) {
-	row := q.db.QueryRow(ctx, upsertDeviceMetadata, arg.DeviceID, arg.Owner, arg.Location, arg.Notes)
+	row := q.db.QueryRow(
+		ctx,
+		upsertDeviceMetadata,
+		arg.DeviceID,
+		arg.Owner,
+		arg.Location,
+		arg.Notes,
+		arg.Actor.Type,
+		arg.Actor.Name,
+		arg.Actor.RunID,
+		arg.Actor.Reason,
+	)
 	var i DeviceMetadata
 	err := row.Scan(&i.DeviceID, &i.Owner, &i.Location, &i.Notes)
 	return i, err
 }
 
 const upsertDeviceMetadataFillBlank = `-- name: UpsertDeviceMetadataFillBlank :one
-INSERT INTO device_metadata (device_id, owner, location, notes)
-VALUES ($1, $2, $3, $4)
-ON CONFLICT (device_id) DO UPDATE
-SET owner = CASE
-              WHEN device_metadata.owner IS NULL OR btrim(device_metadata.owner) = '' THEN EXCLUDED.owner
-              ELSE device_metadata.owner
-            END,
-    location = CASE
-                 WHEN device_metadata.location IS NULL OR btrim(device_metadata.location) = '' THEN EXCLUDED.location
-                 ELSE device_metadata.location
-               END,
-    notes = CASE
-              WHEN device_metadata.notes IS NULL OR btrim(device_metadata.notes) = '' THEN EXCLUDED.notes
-              ELSE device_metadata.notes
-            END,
-    updated_at = now()
-RETURNING device_id, owner, location, notes
+WITH prev AS (
+  SELECT device_id, owner, location, notes
+  FROM device_metadata
+  WHERE device_id = $1::uuid
+  FOR UPDATE
+), upserted AS (
+  INSERT INTO device_metadata (device_id, owner, location, notes)
+  SELECT $1::uuid, $2::text, $3::text, $4::text
+  FROM (SELECT count(*) FROM prev) locked
+  ON CONFLICT (device_id) DO UPDATE
+  SET owner = CASE
+                WHEN device_metadata.owner IS NULL OR btrim(device_metadata.owner) = '' THEN EXCLUDED.owner
+                ELSE device_metadata.owner
+              END,
+      location = CASE
+                   WHEN device_metadata.location IS NULL OR btrim(device_metadata.location) = '' THEN EXCLUDED.location
+                   ELSE device_metadata.location
+                 END,
+      notes = CASE
+                WHEN device_metadata.notes IS NULL OR btrim(device_metadata.notes) = '' THEN EXCLUDED.notes
+                ELSE device_metadata.notes
+              END,
+      updated_at = now()
+  RETURNING device_id, owner, location, notes
+), event AS (
+  INSERT INTO device_events (device_id, kind, actor_type, actor, run_id, reason, before, after, summary)
+  SELECT u.device_id,
+         'metadata',
+         COALESCE(NULLIF($5::text, ''), 'system'),
+         $6::text,
+         $7::uuid,
+         $8::text,
+         CASE WHEN p.device_id IS NOT NULL THEN jsonb_build_object('owner', p.owner, 'location', p.location, 'notes', p.notes) END,
+         jsonb_build_object('owner', u.owner, 'location', u.location, 'notes', u.notes),
+         'metadata updated: ' || concat_ws(
+           ', ',
+           CASE WHEN p.owner IS DISTINCT FROM u.owner THEN 'owner' END,
+           CASE WHEN p.location IS DISTINCT FROM u.location THEN 'location' END,
+           CASE WHEN p.notes IS DISTINCT FROM u.notes THEN 'notes' END
+         )
+  FROM upserted u
+  LEFT JOIN prev p ON true
+  WHERE (p.owner, p.location, p.notes) IS DISTINCT FROM (u.owner, u.location, u.notes)
+)
+SELECT device_id, owner, location, notes
+FROM upserted
 `
 
 func (q *Queries) UpsertDeviceMetadataFillBlank(ctx context.Context, arg UpsertDeviceMetadataParams) (DeviceMetadata, error) {
-	row := q.db.QueryRow(ctx, upsertDeviceMetadataFillBlank, arg.DeviceID, arg.Owner, arg.Location, arg.Notes)
+	row := q.db.QueryRow(
+		ctx,
+		upsertDeviceMetadataFillBlank,
+		arg.DeviceID,
+		arg.Owner,
+		arg.Location,
+		arg.Notes,
+		arg.Actor.Type,
+		arg.Actor.Name,
+		arg.Actor.RunID,
+		arg.Actor.Reason,
+	)
 	var i DeviceMetadata
 	err := row.Scan(&i.DeviceID, &i.Owner, &i.Location, &i.Notes)
 	return i, err
@@ -577,16 +707,33 @@ func (q *Queries) ListDeviceEffectiveTags(ctx context.Context, deviceID string)
 	return items, nil
 }
 
+const deviceHasTag = `-- name: DeviceHasTag :one
+SELECT EXISTS (
+  SELECT 1
+  FROM device_tags
+  WHERE device_id = $1::uuid
+    AND tag = $2
+)
+`
+
+func (q *Queries) DeviceHasTag(ctx context.Context, deviceID string, tag string) (bool, error) {
+	var ok bool
+	err := q.db.QueryRow(ctx, deviceHasTag, deviceID, tag).Scan(&ok)
+	return ok, err
+}
+
 const listDeviceIPs = `-- name: ListDeviceIPs :many
 SELECT ia.ip::text,
        ia.interface_id::text,
        i.name AS interface_name,
        ia.created_at,
-       ia.updated_at
+       ia.updated_at,
+       ia.last_seen_at,
+       ia.stale_at
 FROM ip_addresses ia
 LEFT JOIN interfaces i ON i.id = ia.interface_id
 WHERE ia.device_id = $1::uuid OR i.device_id = $1::uuid
-ORDER BY ia.updated_at DESC, ia.ip::text ASC
+ORDER BY (ia.stale_at IS NOT NULL) ASC, ia.updated_at DESC, ia.ip::text ASC
 `
 
 func (q *Queries) ListDeviceIPs(ctx context.Context, deviceID string) ([]DeviceIP, error) {
@@ -599,7 +746,7 @@ func (q *Queries) ListDeviceIPs(ctx context.Context, deviceID string) ([]DeviceI
 	var items []DeviceIP
 	for rows.Next() {
 		var i DeviceIP
-		if err := rows.Scan(&i.IP, &i.InterfaceID, &i.InterfaceName, &i.CreatedAt, &i.UpdatedAt); err != nil {
+		if err := rows.Scan(&i.IP, &i.InterfaceID, &i.InterfaceName, &i.CreatedAt, &i.UpdatedAt, &i.LastSeenAt, &i.StaleAt); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -615,11 +762,13 @@ SELECT ma.mac::text,
        ma.interface_id::text,
        i.name AS interface_name,
        ma.created_at,
-       ma.updated_at
+       ma.updated_at,
+       ma.last_seen_at,
+       ma.stale_at
 FROM mac_addresses ma
 LEFT JOIN interfaces i ON i.id = ma.interface_id
 WHERE ma.device_id = $1::uuid OR i.device_id = $1::uuid
-ORDER BY ma.updated_at DESC, ma.mac::text ASC
+ORDER BY (ma.stale_at IS NOT NULL) ASC, ma.updated_at DESC, ma.mac::text ASC
 `
 
 func (q *Queries) ListDeviceMACs(ctx context.Context, deviceID string) ([]DeviceMAC, error) {
@@ -632,7 +781,7 @@ func (q *Queries) ListDeviceMACs(ctx context.Context, deviceID string) ([]Device
 	var items []DeviceMAC
 	for rows.Next() {
 		var i DeviceMAC
-		if err := rows.Scan(&i.MAC, &i.InterfaceID, &i.InterfaceName, &i.CreatedAt, &i.UpdatedAt); err != nil {
+		if err := rows.Scan(&i.MAC, &i.InterfaceID, &i.InterfaceName, &i.CreatedAt, &i.UpdatedAt, &i.LastSeenAt, &i.StaleAt); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -706,7 +855,22 @@ SELECT protocol,
        name,
        state,
        source,
+       summary,
        observed_at,
+       first_seen_at,
+       last_seen_at,
+       closed_at,
+       http_status,
+       http_title,
+       http_server,
+       http_powered_by,
+       http_favicon_hash,
+       http_final_url,
+       http_observed_at,
+       product,
+       version,
+       extra_info,
+       cpe,
        created_at,
        updated_at
 FROM services
@@ -724,7 +888,7 @@ func (q *Queries) ListDeviceServices(ctx context.Context, deviceID string) ([]De
 	var items []DeviceService
 	for rows.Next() {
 		var i DeviceService
-		if err := rows.Scan(&i.Protocol, &i.Port, &i.Name, &i.State, &i.Source, &i.ObservedAt, &i.CreatedAt, &i.UpdatedAt); err != nil {
+		if err := rows.Scan(&i.Protocol, &i.Port, &i.Name, &i.State, &i.Source, &i.Summary, &i.ObservedAt, &i.FirstSeenAt, &i.LastSeenAt, &i.ClosedAt, &i.HTTPStatus, &i.HTTPTitle, &i.HTTPServer, &i.HTTPPoweredBy, &i.HTTPFaviconHash, &i.HTTPFinalURL, &i.HTTPObservedAt, &i.Product, &i.Version, &i.ExtraInfo, &i.CPE, &i.CreatedAt, &i.UpdatedAt); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -776,6 +940,12 @@ SELECT l.id,
        CASE WHEN l.a_device_id = $1::uuid THEN l.b_interface_id::text ELSE l.a_interface_id::text END AS peer_interface_id,
        l.link_type,
        l.source,
+       l.confidence,
+       l.state,
+       CASE WHEN l.a_device_id = $1::uuid THEN l.a_as ELSE l.b_as END AS local_as,
+       CASE WHEN l.a_device_id = $1::uuid THEN l.b_as ELSE l.a_as END AS peer_as,
+       l.area,
+       l.last_change_at,
        l.observed_at,
        l.updated_at
 FROM links l
@@ -801,6 +971,12 @@ func (q *Queries) ListDeviceLinks(ctx context.Context, deviceID string) ([]Devic
 			&i.PeerInterfaceID,
 			&i.LinkType,
 			&i.Source,
+			&i.Confidence,
+			&i.State,
+			&i.LocalAS,
+			&i.PeerAS,
+			&i.Area,
+			&i.LastChangeAt,
 			&i.ObservedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -815,20 +991,50 @@ func (q *Queries) ListDeviceLinks(ctx context.Context, deviceID string) ([]Devic
 }
 
 const setDeviceDisplayNameIfUnset = `-- name: SetDeviceDisplayNameIfUnset :execrows
-UPDATE devices
-SET display_name = $2,
-    updated_at = now()
-WHERE id = $1::uuid
-  AND (display_name IS NULL OR btrim(display_name) = '')
+WITH prev AS (
+  SELECT id, display_name
+  FROM devices
+  WHERE id = $1::uuid
+    AND (display_name IS NULL OR btrim(display_name) = '')
+  FOR UPDATE
+), updated AS (
+  UPDATE devices d
+  SET display_name = $2,
+      updated_at = now()
+  FROM prev p
+  WHERE d.id = p.id
+  RETURNING d.id, d.display_name, p.display_name AS previous_name
+)
+INSERT INTO device_events (device_id, kind, actor_type, actor, run_id, reason, before, after, summary)
+SELECT u.id,
+       'display_name',
+       COALESCE(NULLIF($3::text, ''), 'system'),
+       $4::text,
+       $5::uuid,
+       $6::text,
+       CASE WHEN u.previous_name IS NOT NULL THEN jsonb_build_object('display_name', u.previous_name) END,
+       jsonb_build_object('display_name', u.display_name),
+       'named ' || u.display_name
+FROM updated u
 `
 
 type SetDeviceDisplayNameIfUnsetParams struct {
 	ID          string
 	DisplayName string
+	Actor       DeviceEventActor
 }
 
 func (q *Queries) SetDeviceDisplayNameIfUnset(ctx context.Context, arg SetDeviceDisplayNameIfUnsetParams) (int64, error) {
-	tag, err := q.db.Exec(ctx, setDeviceDisplayNameIfUnset, arg.ID, arg.DisplayName)
+	tag, err := q.db.Exec(
+		ctx,
+		setDeviceDisplayNameIfUnset,
+		arg.ID,
+		arg.DisplayName,
+		arg.Actor.Type,
+		arg.Actor.Name,
+		arg.Actor.RunID,
+		arg.Actor.Reason,
+	)
 	if err != nil {
 		return 0, err
 	}
@@ -836,29 +1042,73 @@ func (q *Queries) SetDeviceDisplayNameIfUnset(ctx context.Context, arg SetDevice
 }
 
 const upsertDeviceSNMP = `-- name: UpsertDeviceSNMP :exec
-INSERT INTO device_snmp (
-  device_id,
-  address,
-  sys_name,
-  sys_descr,
-  sys_object_id,
-  sys_contact,
-  sys_location,
-  last_success_at,
-  last_error,
-  updated_at
+WITH upserted AS (
+  INSERT INTO device_snmp (
+    device_id,
+    address,
+    sys_name,
+    sys_descr,
+    sys_object_id,
+    sys_contact,
+    sys_location,
+    last_success_at,
+    last_error,
+    updated_at
+  )
+  VALUES ($1::uuid, $2::inet, $3, $4, $5, $6, $7, $8, $9, now())
+  ON CONFLICT (device_id) DO UPDATE
+  SET address = EXCLUDED.address,
+      sys_name = EXCLUDED.sys_name,
+      sys_descr = EXCLUDED.sys_descr,
+      sys_object_id = EXCLUDED.sys_object_id,
+      sys_contact = EXCLUDED.sys_contact,
+      sys_location = EXCLUDED.sys_location,
+      last_success_at = EXCLUDED.last_success_at,
+      last_error = EXCLUDED.last_error,
+      updated_at = now()
+  RETURNING device_id,
+            last_success_at,
+            jsonb_build_object(
+              'address', host(address),
+              'sys_name', sys_name,
+              'sys_descr', sys_descr,
+              'sys_object_id', sys_object_id,
+              'sys_contact', sys_contact,
+              'sys_location', sys_location
+            ) AS identity
+), previous AS (
+  SELECT e.after
+  FROM device_events e
+  WHERE e.device_id = $1::uuid
+    AND e.kind = 'snmp'
+  ORDER BY e.occurred_at DESC, e.id DESC
+  LIMIT 1
 )
-VALUES ($1::uuid, $2::inet, $3, $4, $5, $6, $7, $8, $9, now())
-ON CONFLICT (device_id) DO UPDATE
-SET address = EXCLUDED.address,
-    sys_name = EXCLUDED.sys_name,
-    sys_descr = EXCLUDED.sys_descr,
-    sys_object_id = EXCLUDED.sys_object_id,
-    sys_contact = EXCLUDED.sys_contact,
-    sys_location = EXCLUDED.sys_location,
-    last_success_at = EXCLUDED.last_success_at,
-    last_error = EXCLUDED.last_error,
-    updated_at = now()
+INSERT INTO device_events (device_id, kind, actor_type, actor, run_id, reason, before, after, summary)
+SELECT u.device_id,
+       'snmp',
+       COALESCE(NULLIF($10::text, ''), 'system'),
+       $11::text,
+       $12::uuid,
+       $13::text,
+       p.after,
+       u.identity,
+       CASE
+         WHEN p.after IS NULL THEN 'snmp identity observed'
+         ELSE 'snmp identity changed: ' || concat_ws(
+           ', ',
+           CASE WHEN p.after->'address' IS DISTINCT FROM u.identity->'address' THEN 'address' END,
+           CASE WHEN p.after->'sys_name' IS DISTINCT FROM u.identity->'sys_name' THEN 'sys_name' END,
+           CASE WHEN p.after->'sys_descr' IS DISTINCT FROM u.identity->'sys_descr' THEN 'sys_descr' END,
+           CASE WHEN p.after->'sys_object_id' IS DISTINCT FROM u.identity->'sys_object_id' THEN 'sys_object_id' END,
+           CASE WHEN p.after->'sys_contact' IS DISTINCT FROM u.identity->'sys_contact' THEN 'sys_contact' END,
+           CASE WHEN p.after->'sys_location' IS DISTINCT FROM u.identity->'sys_location' THEN 'sys_location' END
+         )
+       END
+FROM upserted u
+LEFT JOIN previous p ON true
+WHERE u.last_success_at IS NOT NULL
+  AND p.after IS DISTINCT FROM u.identity
 `
 
 type UpsertDeviceSNMPParams struct {
@@ -871,6 +1121,7 @@ type UpsertDeviceSNMPParams struct {
 	SysLocation   *string
 	LastSuccessAt *time.Time
 	LastError     *string
+	Actor         DeviceEventActor
 }
 
 func (q *Queries) UpsertDeviceSNMP(ctx context.Context, arg UpsertDeviceSNMPParams) error {
@@ -886,6 +1137,10 @@ func (q *Queries) UpsertDeviceSNMP(ctx context.Context, arg UpsertDeviceSNMPPara
 		arg.SysLocation,
 		arg.LastSuccessAt,
 		arg.LastError,
+		arg.Actor.Type,
+		arg.Actor.Name,
+		arg.Actor.RunID,
+		arg.Actor.Reason,
 	)
 	return err
 }
@@ -955,7 +1210,9 @@ INSERT INTO mac_addresses (device_id, interface_id, mac)
 VALUES ($1::uuid, $2::uuid, $3::macaddr)
 ON CONFLICT (interface_id, mac) WHERE interface_id IS NOT NULL
 DO UPDATE SET device_id = EXCLUDED.device_id,
-              updated_at = now()
+              updated_at = now(),
+              last_seen_at = now(),
+              stale_at = NULL
 `
 
 type UpsertInterfaceMACParams struct {
@@ -1171,7 +1428,7 @@ SELECT device_id
 FROM mac_addresses
 WHERE mac = $1::macaddr
   AND device_id IS NOT NULL
-ORDER BY created_at ASC
+ORDER BY (stale_at IS NOT NULL) ASC, created_at ASC
 LIMIT 1
 `
 
@@ -1183,11 +1440,13 @@ func (q *Queries) FindDeviceIDByMAC(ctx context.Context, mac string) (string, er
 }
 
 const findDeviceIDByIP = `-- name: FindDeviceIDByIP :one
-SELECT device_id
-FROM ip_addresses
-WHERE ip = $1::inet
-  AND device_id IS NOT NULL
-ORDER BY created_at ASC
+SELECT a.device_id
+FROM ip_addresses a
+JOIN devices d ON d.id = a.device_id
+WHERE a.ip = $1::inet
+  AND a.stale_at IS NULL
+  AND d.archived_at IS NULL
+ORDER BY a.last_seen_at DESC, a.created_at ASC
 LIMIT 1
 `
 
@@ -1202,7 +1461,10 @@ const upsertDeviceIP = `-- name: UpsertDeviceIP :exec
 INSERT INTO ip_addresses (device_id, ip)
 VALUES ($1::uuid, $2::inet)
 ON CONFLICT (device_id, ip) WHERE device_id IS NOT NULL
-DO UPDATE SET updated_at = now()
+DO UPDATE SET updated_at = now(),
+              last_seen_at = now(),
+              stale_at = NULL,
+              source = 'discovery'
 `
 
 type UpsertDeviceIPParams struct {
@@ -1219,7 +1481,10 @@ const upsertDeviceMAC = `-- name: UpsertDeviceMAC :exec
 INSERT INTO mac_addresses (device_id, mac)
 VALUES ($1::uuid, $2::macaddr)
 ON CONFLICT (device_id, mac) WHERE device_id IS NOT NULL
-DO UPDATE SET updated_at = now()
+DO UPDATE SET updated_at = now(),
+              last_seen_at = now(),
+              stale_at = NULL,
+              source = 'discovery'
 `
 
 type UpsertDeviceMACParams struct {
@@ -1232,6 +1497,36 @@ func (q *Queries) UpsertDeviceMAC(ctx context.Context, arg UpsertDeviceMACParams
 	return err
 }
 
+const upsertImportedDeviceIP = `-- name: UpsertImportedDeviceIP :exec
+INSERT INTO ip_addresses (device_id, ip, source)
+VALUES ($1::uuid, $2::inet, 'import')
+ON CONFLICT (device_id, ip) WHERE device_id IS NOT NULL
+DO UPDATE SET updated_at = now(),
+              last_seen_at = now(),
+              stale_at = NULL,
+              source = 'import'
+`
+
+func (q *Queries) UpsertImportedDeviceIP(ctx context.Context, arg UpsertDeviceIPParams) error {
+	_, err := q.db.Exec(ctx, upsertImportedDeviceIP, arg.DeviceID, arg.IP)
+	return err
+}
+
+const upsertImportedDeviceMAC = `-- name: UpsertImportedDeviceMAC :exec
+INSERT INTO mac_addresses (device_id, mac, source)
+VALUES ($1::uuid, $2::macaddr, 'import')
+ON CONFLICT (device_id, mac) WHERE device_id IS NOT NULL
+DO UPDATE SET updated_at = now(),
+              last_seen_at = now(),
+              stale_at = NULL,
+              source = 'import'
+`
+
+func (q *Queries) UpsertImportedDeviceMAC(ctx context.Context, arg UpsertDeviceMACParams) error {
+	_, err := q.db.Exec(ctx, upsertImportedDeviceMAC, arg.DeviceID, arg.MAC)
+	return err
+}
+
 const insertIPObservation = `-- name: InsertIPObservation :exec
 INSERT INTO ip_observations (run_id, device_id, ip)
 VALUES ($1::uuid, $2::uuid, $3::inet)
@@ -1267,6 +1562,20 @@ func (q *Queries) InsertMACObservation(ctx context.Context, arg InsertMACObserva
 }
 
 const upsertLink = `-- name: UpsertLink :exec
+-- Observed (LLDP/CDP/manual) links always win: inferred links that touch the same device pair or either
+-- interface are removed when an observed link is written.
+WITH superseded AS (
+  DELETE FROM links l
+  WHERE l.source = 'inferred'
+    AND $7 <> 'inferred'
+    AND l.link_key <> $1
+    AND (
+      (l.a_device_id = $2::uuid AND l.b_device_id = $4::uuid)
+      OR (l.a_device_id = $4::uuid AND l.b_device_id = $2::uuid)
+      OR ($3::uuid IS NOT NULL AND $3::uuid IN (l.a_interface_id, l.b_interface_id))
+      OR ($5::uuid IS NOT NULL AND $5::uuid IN (l.a_interface_id, l.b_interface_id))
+    )
+)
 INSERT INTO links (
   link_key,
   a_device_id,
@@ -1326,23 +1635,93 @@ func (q *Queries) UpsertInterfaceByName(ctx context.Context, arg UpsertInterface
 }
 
 const upsertServiceFromScan = `-- name: UpsertServiceFromScan :exec
-INSERT INTO services (
+WITH prev AS (
+  SELECT state
+  FROM services
+  WHERE device_id = $1::uuid
+    AND protocol = $2
+    AND port = $3
+),
+upserted AS (
+  INSERT INTO services (
+    device_id,
+    protocol,
+    port,
+    name,
+    state,
+    source,
+    summary,
+    observed_at,
+    first_seen_at,
+    last_seen_at,
+    closed_at,
+    product,
+    version,
+    extra_info,
+    cpe
+  )
+  VALUES (
+    $1::uuid,
+    $2,
+    $3,
+    $4,
+    $5::text,
+    $6,
+    $7,
+    $8::timestamptz,
+    $8::timestamptz,
+    CASE WHEN $5::text = 'open' THEN $8::timestamptz END,
+    CASE WHEN $5::text IN ('closed', 'filtered') THEN $8::timestamptz END,
+    $9,
+    $10,
+    $11,
+    $12::text[]
+  )
+  ON CONFLICT (device_id, protocol, port) WHERE protocol IS NOT NULL AND port IS NOT NULL
+  DO UPDATE
+  SET name = COALESCE(EXCLUDED.name, services.name),
+      state = EXCLUDED.state,
+      source = EXCLUDED.source,
+      summary = COALESCE(EXCLUDED.summary, services.summary),
+      product = COALESCE(EXCLUDED.product, services.product),
+      version = COALESCE(EXCLUDED.version, services.version),
+      extra_info = COALESCE(EXCLUDED.extra_info, services.extra_info),
+      cpe = COALESCE(EXCLUDED.cpe, services.cpe),
+      observed_at = EXCLUDED.observed_at,
+      first_seen_at = COALESCE(services.first_seen_at, EXCLUDED.observed_at),
+      last_seen_at = CASE
+        WHEN EXCLUDED.state = 'open' THEN EXCLUDED.observed_at
+        ELSE services.last_seen_at
+      END,
+      closed_at = CASE
+        WHEN EXCLUDED.state = 'open' THEN NULL
+        WHEN services.state IS DISTINCT FROM EXCLUDED.state THEN EXCLUDED.observed_at
+        ELSE services.closed_at
+      END,
+      updated_at = now()
+  RETURNING id, device_id, protocol, port, state, source, observed_at
+)
+INSERT INTO service_transitions (
+  service_id,
   device_id,
   protocol,
   port,
-  name,
-  state,
+  from_state,
+  to_state,
   source,
-  observed_at
+  changed_at
 )
-VALUES ($1::uuid, $2, $3, $4, $5, $6, $7)
-ON CONFLICT (device_id, protocol, port) WHERE protocol IS NOT NULL AND port IS NOT NULL
-DO UPDATE
-SET name = EXCLUDED.name,
-    state = EXCLUDED.state,
-    source = EXCLUDED.source,
-    observed_at = EXCLUDED.observed_at,
-    updated_at = now()
+SELECT u.id,
+       u.device_id,
+       u.protocol,
+       u.port,
+       (SELECT state FROM prev),
+       u.state,
+       u.source,
+       u.observed_at
+FROM upserted u
+WHERE u.state IS NOT NULL
+  AND u.state IS DISTINCT FROM (SELECT state FROM prev);
 `
 
 type UpsertServiceFromScanParams struct {
@@ -1352,143 +1731,303 @@ type UpsertServiceFromScanParams struct {
 	Name       *string
 	State      *string
 	Source     *string
+	Summary    *string
 	ObservedAt time.Time
+	Product    *string
+	Version    *string
+	ExtraInfo  *string
+	CPE        []string
 }
 
 func (q *Queries) UpsertServiceFromScan(ctx context.Context, arg UpsertServiceFromScanParams) error {
-	_, err := q.db.Exec(ctx, upsertServiceFromScan, arg.DeviceID, arg.Protocol, arg.Port, arg.Name, arg.State, arg.Source, arg.ObservedAt)
+	_, err := q.db.Exec(ctx, upsertServiceFromScan, arg.DeviceID, arg.Protocol, arg.Port, arg.Name, arg.State, arg.Source, arg.Summary, arg.ObservedAt, arg.Product, arg.Version, arg.ExtraInfo, arg.CPE)
+	return err
+}
+
+const markServiceNotOpen = `-- name: MarkServiceNotOpen :execrows
+WITH updated AS (
+  UPDATE services
+  SET state = $4::text,
+      source = COALESCE($5, source),
+      observed_at = $6::timestamptz,
+      closed_at = $6::timestamptz,
+      updated_at = now()
+  WHERE device_id = $1::uuid
+    AND protocol = $2
+    AND port = $3
+    AND state = 'open'
+  RETURNING id, device_id, protocol, port, state, source, observed_at
+)
+INSERT INTO service_transitions (
+  service_id,
+  device_id,
+  protocol,
+  port,
+  from_state,
+  to_state,
+  source,
+  changed_at
+)
+SELECT id, device_id, protocol, port, 'open', state, source, observed_at
+FROM updated;
+`
+
+type MarkServiceNotOpenParams struct {
+	DeviceID   string
+	Protocol   string
+	Port       int32
+	State      string
+	Source     *string
+	ObservedAt time.Time
+}
+
+func (q *Queries) MarkServiceNotOpen(ctx context.Context, arg MarkServiceNotOpenParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, markServiceNotOpen, arg.DeviceID, arg.Protocol, arg.Port, arg.State, arg.Source, arg.ObservedAt)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+const updateServiceHTTPFingerprint = `-- name: UpdateServiceHTTPFingerprint :exec
+UPDATE services
+SET http_status = $2,
+    http_title = $3,
+    http_server = $4,
+    http_powered_by = $5,
+    http_favicon_hash = $6,
+    http_final_url = $7,
+    http_observed_at = $8::timestamptz,
+    updated_at = now()
+WHERE id = $1::uuid;
+`
+
+type UpdateServiceHTTPFingerprintParams struct {
+	ID          string
+	Status      *int32
+	Title       *string
+	Server      *string
+	PoweredBy   *string
+	FaviconHash *int32
+	FinalURL    *string
+	ObservedAt  time.Time
+}
+
+func (q *Queries) UpdateServiceHTTPFingerprint(ctx context.Context, arg UpdateServiceHTTPFingerprintParams) error {
+	_, err := q.db.Exec(ctx, updateServiceHTTPFingerprint, arg.ID, arg.Status, arg.Title, arg.Server, arg.PoweredBy, arg.FaviconHash, arg.FinalURL, arg.ObservedAt)
 	return err
 }
 
 const listDeviceChangeEvents = `-- name: ListDeviceChangeEvents :many
 WITH events AS (
-	SELECT
-		'ip_observation:' || id::text AS event_id,
-		device_id,
-		observed_at AS event_at,
-		'ip_observation' AS kind,
-		ip::text AS summary,
-		jsonb_build_object('run_id', run_id, 'ip', ip::text) AS details
-	FROM ip_observations
-	UNION ALL
-	SELECT
-		'mac_observation:' || id::text AS event_id,
-		device_id,
-		observed_at AS event_at,
-		'mac_observation' AS kind,
-		mac::text AS summary,
-		jsonb_build_object('run_id', run_id, 'mac', mac::text) AS details
-	FROM mac_observations
-	UNION ALL
-	SELECT
-		'interface_vlan:' || iv.id::text AS event_id,
-		i.device_id,
-		iv.observed_at AS event_at,
-		'vlan' AS kind,
-		'vlan ' || iv.vlan_id::text AS summary,
-		jsonb_build_object(
-			'interface_id', iv.interface_id,
-			'vlan_id', iv.vlan_id,
-			'role', iv.role,
-			'source', iv.source
-		) AS details
-	FROM interface_vlans iv
-	JOIN interfaces i ON i.id = iv.interface_id
-	UNION ALL
-	SELECT
-		'link:' || l.id::text AS event_id,
-		l.a_device_id AS device_id,
-		COALESCE(l.observed_at, l.updated_at) AS event_at,
-		'link' AS kind,
-		'link to ' || l.b_device_id::text AS summary,
-		jsonb_build_object(
-			'a_device_id', l.a_device_id,
-			'a_interface_id', l.a_interface_id,
-			'b_device_id', l.b_device_id,
-			'b_interface_id', l.b_interface_id,
-			'link_type', l.link_type,
-			'source', l.source
-		) AS details
-	FROM links l
-	UNION ALL
-	SELECT
-		'link:' || l.id::text AS event_id,
-		l.b_device_id AS device_id,
-		COALESCE(l.observed_at, l.updated_at) AS event_at,
-		'link' AS kind,
-		'link to ' || l.a_device_id::text AS summary,
-		jsonb_build_object(
-			'a_device_id', l.a_device_id,
-			'a_interface_id', l.a_interface_id,
-			'b_device_id', l.b_device_id,
-			'b_interface_id', l.b_interface_id,
-			'link_type', l.link_type,
-			'source', l.source
-		) AS details
-	FROM links l
-	UNION ALL
-	SELECT
-		'metadata:' || id::text AS event_id,
-		device_id,
-		updated_at AS event_at,
-		'metadata' AS kind,
-		COALESCE(owner, location, notes, 'metadata updated') AS summary,
-		jsonb_build_object('owner', owner, 'location', location, 'notes', notes) AS details
-	FROM device_metadata
-	UNION ALL
-	SELECT
-		'device_display_name:' || id::text AS event_id,
-		id AS device_id,
-		updated_at AS event_at,
-		'display_name' AS kind,
-		COALESCE(display_name, 'device updated') AS summary,
-		jsonb_build_object('display_name', display_name) AS details
-	FROM devices
-	UNION ALL
-	SELECT
-		'snmp:' || device_id::text AS event_id,
-		device_id,
-		updated_at AS event_at,
-		'snmp' AS kind,
-		COALESCE(sys_name, 'snmp updated') AS summary,
-		jsonb_build_object(
-			'address', address::text,
-			'sys_name', sys_name,
-			'sys_descr', sys_descr,
-			'sys_object_id', sys_object_id,
-			'sys_contact', sys_contact,
-			'sys_location', sys_location,
-			'last_success_at', last_success_at,
-			'last_error', last_error
-		) AS details
-	FROM device_snmp
-	UNION ALL
-	SELECT
-		'service:' || id::text AS event_id,
-		device_id,
-		observed_at AS event_at,
-		'service' AS kind,
-		COALESCE(name, CONCAT(COALESCE(protocol, 'unknown'), '/', COALESCE(port::text, '0'))) AS summary,
-		jsonb_build_object(
-			'port', port,
-			'protocol', protocol,
-			'state', state,
-			'source', source,
-			'name', name
-		) AS details
-	FROM services
+  SELECT
+    'ip_observation:' || id::text AS event_id,
+    device_id,
+    observed_at AS event_at,
+    'ip_observation' AS kind,
+    ip::text AS summary,
+    jsonb_build_object('run_id', run_id, 'ip', ip::text) AS details
+  FROM ip_observations
+  UNION ALL
+  SELECT
+    'mac_observation:' || id::text AS event_id,
+    device_id,
+    observed_at AS event_at,
+    'mac_observation' AS kind,
+    mac::text AS summary,
+    jsonb_build_object('run_id', run_id, 'mac', mac::text) AS details
+  FROM mac_observations
+  UNION ALL
+  SELECT
+    'device_event:' || e.id::text AS event_id,
+    COALESCE(da.device_id, e.device_id) AS device_id,
+    e.occurred_at AS event_at,
+    CASE e.kind WHEN 'vlans' THEN 'vlan' WHEN 'links' THEN 'link' ELSE e.kind END AS kind,
+    e.summary,
+    jsonb_build_object(
+      'actor_type', e.actor_type,
+      'actor', e.actor,
+      'run_id', e.run_id,
+      'reason', e.reason,
+      'before', e.before,
+      'after', e.after
+    ) AS details
+  FROM device_events e
+  LEFT JOIN device_aliases da ON da.alias_id = e.device_id
+  UNION ALL
+  SELECT
+    'service_transition:' || t.id::text AS event_id,
+    t.device_id,
+    t.changed_at AS event_at,
+    'service' AS kind,
+    CONCAT(
+      COALESCE(s.name, CONCAT(COALESCE(t.protocol, 'unknown'), '/', COALESCE(t.port::text, '0'))),
+      ' ',
+      CASE t.to_state WHEN 'open' THEN 'opened' ELSE t.to_state END
+    ) AS summary,
+    jsonb_build_object(
+      'service_id', t.service_id,
+      'port', t.port,
+      'protocol', t.protocol,
+      'state', t.to_state,
+      'from_state', t.from_state,
+      'source', t.source,
+      'name', s.name
+    ) AS details
+  FROM service_transitions t
+  JOIN services s ON s.id = t.service_id
+  UNION ALL
+  SELECT
+    'certificate:' || c.id::text AS event_id,
+    c.device_id,
+    c.first_seen_at AS event_at,
+    'certificate' AS kind,
+    CONCAT(
+      COALESCE(s.protocol, 'tcp'),
+      '/',
+      COALESCE(s.port::text, '0'),
+      CASE WHEN prev.fingerprint_sha256 IS NULL THEN ' certificate observed' ELSE ' certificate changed' END,
+      COALESCE(': ' || c.common_name, '')
+    ) AS summary,
+    jsonb_build_object(
+      'certificate_id', c.id,
+      'service_id', c.service_id,
+      'port', s.port,
+      'fingerprint_sha256', c.fingerprint_sha256,
+      'previous_fingerprint_sha256', prev.fingerprint_sha256,
+      'subject', c.subject,
+      'issuer', c.issuer,
+      'not_after', c.not_after
+    ) AS details
+  FROM service_certificates c
+  JOIN services s ON s.id = c.service_id
+  LEFT JOIN LATERAL (
+    SELECT p.fingerprint_sha256
+    FROM service_certificates p
+    WHERE p.service_id = c.service_id
+      AND p.first_seen_at < c.first_seen_at
+    ORDER BY p.first_seen_at DESC
+    LIMIT 1
+  ) prev ON true
+  UNION ALL
+  SELECT
+    'ssh_host_key:' || k.id::text AS event_id,
+    k.device_id,
+    k.first_seen_at AS event_at,
+    'ssh_host_key' AS kind,
+    CONCAT(
+      k.key_type,
+      CASE WHEN prev.fingerprint_sha256 IS NULL THEN ' host key observed' ELSE ' host key changed' END,
+      CASE WHEN shared.device_ids IS NULL THEN '' ELSE ' (also seen on another device: possible duplicate or moved host)' END
+    ) AS summary,
+    jsonb_build_object(
+      'ssh_host_key_id', k.id,
+      'service_id', k.service_id,
+      'key_type', k.key_type,
+      'fingerprint_sha256', k.fingerprint_sha256,
+      'previous_fingerprint_sha256', prev.fingerprint_sha256,
+      'shared_with_device_ids', COALESCE(shared.device_ids, '[]'::jsonb)
+    ) AS details
+  FROM ssh_host_keys k
+  LEFT JOIN LATERAL (
+    SELECT p.fingerprint_sha256
+    FROM ssh_host_keys p
+    WHERE p.device_id = k.device_id
+      AND p.key_type = k.key_type
+      AND p.first_seen_at < k.first_seen_at
+    ORDER BY p.first_seen_at DESC
+    LIMIT 1
+  ) prev ON true
+  LEFT JOIN LATERAL (
+    SELECT jsonb_agg(DISTINCT o.device_id) AS device_ids
+    FROM ssh_host_keys o
+    WHERE o.fingerprint_sha256 = k.fingerprint_sha256
+      AND o.device_id <> k.device_id
+      AND o.first_seen_at <= k.first_seen_at
+  ) shared ON true
+  UNION ALL
+  SELECT
+    'link_state:' || t.id::text || ':' || e.device_id::text AS event_id,
+    e.device_id,
+    t.changed_at AS event_at,
+    'adjacency' AS kind,
+    CONCAT(
+      UPPER(COALESCE(t.link_type, 'routing')),
+      ' adjacency ',
+      t.to_state,
+      CASE WHEN t.from_state IS NULL THEN '' ELSE ' (was ' || t.from_state || ')' END
+    ) AS summary,
+    jsonb_build_object(
+      'link_id', t.link_id,
+      'link_type', t.link_type,
+      'peer_device_id', e.peer_device_id,
+      'state', t.to_state,
+      'from_state', t.from_state
+    ) AS details
+  FROM link_state_transitions t
+  CROSS JOIN LATERAL (
+    VALUES (t.a_device_id, t.b_device_id), (t.b_device_id, t.a_device_id)
+  ) AS e(device_id, peer_device_id)
+  UNION ALL
+  SELECT
+    'device_archive:' || a.id::text AS event_id,
+    a.device_id,
+    a.changed_at AS event_at,
+    'archive' AS kind,
+    CASE a.action
+      WHEN 'archived' THEN 'device archived'
+      WHEN 'restored' THEN 'device restored'
+      ELSE 'archived device seen again (unarchived)'
+    END AS summary,
+    jsonb_build_object(
+      'action', a.action,
+      'actor', a.actor,
+      'reason', a.reason,
+      'run_id', a.run_id
+    ) AS details
+  FROM device_archive_events a
+  UNION ALL
+  SELECT
+    'fact_detachment:' || f.id::text AS event_id,
+    f.device_id,
+    f.detached_at AS event_at,
+    'detached' AS kind,
+    f.kind || ' ' || f.value || ' detached' AS summary,
+    jsonb_build_object(
+      'kind', f.kind,
+      'value', f.value,
+      'interface_id', f.interface_id,
+      'last_seen_at', f.last_seen_at,
+      'run_id', f.run_id
+    ) AS details
+  FROM fact_detachments f
+  UNION ALL
+  SELECT
+    'availability:' || v.id::text AS event_id,
+    v.device_id,
+    v.changed_at AS event_at,
+    'availability' AS kind,
+    'device ' || v.state || ' (was ' || v.previous_state || ')' AS summary,
+    jsonb_build_object(
+      'state', v.state,
+      'previous_state', v.previous_state,
+      'run_id', v.run_id,
+      'evidence', v.evidence
+    ) AS details
+  FROM device_availability v
+  WHERE v.previous_state IS NOT NULL
 )
 SELECT
-	event_id,
-	device_id,
-	event_at,
-	kind,
-	summary,
-	details
+  event_id,
+  device_id,
+  event_at,
+  kind,
+  summary,
+  details
 FROM events
 WHERE
-	($1::timestamptz IS NULL OR (event_at < $1::timestamptz OR (event_at = $1::timestamptz AND event_id < $2::text)))
-	AND ($3::timestamptz IS NULL OR event_at >= $3::timestamptz)
+  ($1::timestamptz IS NULL OR (event_at < $1::timestamptz OR (event_at = $1::timestamptz AND event_id < $2::text)))
+  AND ($3::timestamptz IS NULL OR event_at >= $3::timestamptz)
 ORDER BY event_at DESC, event_id DESC
 LIMIT $4
 `
@@ -1515,133 +2054,214 @@ func (q *Queries) ListDeviceChangeEvents(ctx context.Context, arg ListDeviceChan
 
 const listDeviceChangeEventsForDevice = `-- name: ListDeviceChangeEventsForDevice :many
 WITH events AS (
-	SELECT
-		'ip_observation:' || id::text AS event_id,
-		device_id,
-		observed_at AS event_at,
-		'ip_observation' AS kind,
-		ip::text AS summary,
-		jsonb_build_object('run_id', run_id, 'ip', ip::text) AS details
-	FROM ip_observations
-	UNION ALL
-	SELECT
-		'mac_observation:' || id::text AS event_id,
-		device_id,
-		observed_at AS event_at,
-		'mac_observation' AS kind,
-		mac::text AS summary,
-		jsonb_build_object('run_id', run_id, 'mac', mac::text) AS details
-	FROM mac_observations
-	UNION ALL
-	SELECT
-		'interface_vlan:' || iv.id::text AS event_id,
-		i.device_id,
-		iv.observed_at AS event_at,
-		'vlan' AS kind,
-		'vlan ' || iv.vlan_id::text AS summary,
-		jsonb_build_object(
-			'interface_id', iv.interface_id,
-			'vlan_id', iv.vlan_id,
-			'role', iv.role,
-			'source', iv.source
-		) AS details
-	FROM interface_vlans iv
-	JOIN interfaces i ON i.id = iv.interface_id
-	UNION ALL
-	SELECT
-		'link:' || l.id::text AS event_id,
-		l.a_device_id AS device_id,
-		COALESCE(l.observed_at, l.updated_at) AS event_at,
-		'link' AS kind,
-		'link to ' || l.b_device_id::text AS summary,
-		jsonb_build_object(
-			'a_device_id', l.a_device_id,
-			'a_interface_id', l.a_interface_id,
-			'b_device_id', l.b_device_id,
-			'b_interface_id', l.b_interface_id,
-			'link_type', l.link_type,
-			'source', l.source
-		) AS details
-	FROM links l
-	UNION ALL
-	SELECT
-		'link:' || l.id::text AS event_id,
-		l.b_device_id AS device_id,
-		COALESCE(l.observed_at, l.updated_at) AS event_at,
-		'link' AS kind,
-		'link to ' || l.a_device_id::text AS summary,
-		jsonb_build_object(
-			'a_device_id', l.a_device_id,
-			'a_interface_id', l.a_interface_id,
-			'b_device_id', l.b_device_id,
-			'b_interface_id', l.b_interface_id,
-			'link_type', l.link_type,
-			'source', l.source
-		) AS details
-	FROM links l
-	UNION ALL
-	SELECT
-		'metadata:' || id::text AS event_id,
-		device_id,
-		updated_at AS event_at,
-		'metadata' AS kind,
-		COALESCE(owner, location, notes, 'metadata updated') AS summary,
-		jsonb_build_object('owner', owner, 'location', location, 'notes', notes) AS details
-	FROM device_metadata
-	UNION ALL
-	SELECT
-		'device_display_name:' || id::text AS event_id,
-		id AS device_id,
-		updated_at AS event_at,
-		'display_name' AS kind,
-		COALESCE(display_name, 'device updated') AS summary,
-		jsonb_build_object('display_name', display_name) AS details
-	FROM devices
-	UNION ALL
-	SELECT
-		'snmp:' || device_id::text AS event_id,
-		device_id,
-		updated_at AS event_at,
-		'snmp' AS kind,
-		COALESCE(sys_name, 'snmp updated') AS summary,
-		jsonb_build_object(
-			'address', address::text,
-			'sys_name', sys_name,
-			'sys_descr', sys_descr,
-			'sys_object_id', sys_object_id,
-			'sys_contact', sys_contact,
-			'sys_location', sys_location,
-			'last_success_at', last_success_at,
-			'last_error', last_error
-		) AS details
-	FROM device_snmp
-	UNION ALL
-	SELECT
-		'service:' || id::text AS event_id,
-		device_id,
-		observed_at AS event_at,
-		'service' AS kind,
-		COALESCE(name, CONCAT(COALESCE(protocol, 'unknown'), '/', COALESCE(port::text, '0'))) AS summary,
-		jsonb_build_object(
-			'port', port,
-			'protocol', protocol,
-			'state', state,
-			'source', source,
-			'name', name
-		) AS details
-	FROM services
+  SELECT
+    'ip_observation:' || id::text AS event_id,
+    device_id,
+    observed_at AS event_at,
+    'ip_observation' AS kind,
+    ip::text AS summary,
+    jsonb_build_object('run_id', run_id, 'ip', ip::text) AS details
+  FROM ip_observations
+  UNION ALL
+  SELECT
+    'mac_observation:' || id::text AS event_id,
+    device_id,
+    observed_at AS event_at,
+    'mac_observation' AS kind,
+    mac::text AS summary,
+    jsonb_build_object('run_id', run_id, 'mac', mac::text) AS details
+  FROM mac_observations
+  UNION ALL
+  SELECT
+    'device_event:' || e.id::text AS event_id,
+    COALESCE(da.device_id, e.device_id) AS device_id,
+    e.occurred_at AS event_at,
+    CASE e.kind WHEN 'vlans' THEN 'vlan' WHEN 'links' THEN 'link' ELSE e.kind END AS kind,
+    e.summary,
+    jsonb_build_object(
+      'actor_type', e.actor_type,
+      'actor', e.actor,
+      'run_id', e.run_id,
+      'reason', e.reason,
+      'before', e.before,
+      'after', e.after
+    ) AS details
+  FROM device_events e
+  LEFT JOIN device_aliases da ON da.alias_id = e.device_id
+  UNION ALL
+  SELECT
+    'service_transition:' || t.id::text AS event_id,
+    t.device_id,
+    t.changed_at AS event_at,
+    'service' AS kind,
+    CONCAT(
+      COALESCE(s.name, CONCAT(COALESCE(t.protocol, 'unknown'), '/', COALESCE(t.port::text, '0'))),
+      ' ',
+      CASE t.to_state WHEN 'open' THEN 'opened' ELSE t.to_state END
+    ) AS summary,
+    jsonb_build_object(
+      'service_id', t.service_id,
+      'port', t.port,
+      'protocol', t.protocol,
+      'state', t.to_state,
+      'from_state', t.from_state,
+      'source', t.source,
+      'name', s.name
+    ) AS details
+  FROM service_transitions t
+  JOIN services s ON s.id = t.service_id
+  UNION ALL
+  SELECT
+    'certificate:' || c.id::text AS event_id,
+    c.device_id,
+    c.first_seen_at AS event_at,
+    'certificate' AS kind,
+    CONCAT(
+      COALESCE(s.protocol, 'tcp'),
+      '/',
+      COALESCE(s.port::text, '0'),
+      CASE WHEN prev.fingerprint_sha256 IS NULL THEN ' certificate observed' ELSE ' certificate changed' END,
+      COALESCE(': ' || c.common_name, '')
+    ) AS summary,
+    jsonb_build_object(
+      'certificate_id', c.id,
+      'service_id', c.service_id,
+      'port', s.port,
+      'fingerprint_sha256', c.fingerprint_sha256,
+      'previous_fingerprint_sha256', prev.fingerprint_sha256,
+      'subject', c.subject,
+      'issuer', c.issuer,
+      'not_after', c.not_after
+    ) AS details
+  FROM service_certificates c
+  JOIN services s ON s.id = c.service_id
+  LEFT JOIN LATERAL (
+    SELECT p.fingerprint_sha256
+    FROM service_certificates p
+    WHERE p.service_id = c.service_id
+      AND p.first_seen_at < c.first_seen_at
+    ORDER BY p.first_seen_at DESC
+    LIMIT 1
+  ) prev ON true
+  UNION ALL
+  SELECT
+    'ssh_host_key:' || k.id::text AS event_id,
+    k.device_id,
+    k.first_seen_at AS event_at,
+    'ssh_host_key' AS kind,
+    CONCAT(
+      k.key_type,
+      CASE WHEN prev.fingerprint_sha256 IS NULL THEN ' host key observed' ELSE ' host key changed' END,
+      CASE WHEN shared.device_ids IS NULL THEN '' ELSE ' (also seen on another device: possible duplicate or moved host)' END
+    ) AS summary,
+    jsonb_build_object(
+      'ssh_host_key_id', k.id,
+      'service_id', k.service_id,
+      'key_type', k.key_type,
+      'fingerprint_sha256', k.fingerprint_sha256,
+      'previous_fingerprint_sha256', prev.fingerprint_sha256,
+      'shared_with_device_ids', COALESCE(shared.device_ids, '[]'::jsonb)
+    ) AS details
+  FROM ssh_host_keys k
+  LEFT JOIN LATERAL (
+    SELECT p.fingerprint_sha256
+    FROM ssh_host_keys p
+    WHERE p.device_id = k.device_id
+      AND p.key_type = k.key_type
+      AND p.first_seen_at < k.first_seen_at
+    ORDER BY p.first_seen_at DESC
+    LIMIT 1
+  ) prev ON true
+  LEFT JOIN LATERAL (
+    SELECT jsonb_agg(DISTINCT o.device_id) AS device_ids
+    FROM ssh_host_keys o
+    WHERE o.fingerprint_sha256 = k.fingerprint_sha256
+      AND o.device_id <> k.device_id
+      AND o.first_seen_at <= k.first_seen_at
+  ) shared ON true
+  UNION ALL
+  SELECT
+    'link_state:' || t.id::text || ':' || e.device_id::text AS event_id,
+    e.device_id,
+    t.changed_at AS event_at,
+    'adjacency' AS kind,
+    CONCAT(
+      UPPER(COALESCE(t.link_type, 'routing')),
+      ' adjacency ',
+      t.to_state,
+      CASE WHEN t.from_state IS NULL THEN '' ELSE ' (was ' || t.from_state || ')' END
+    ) AS summary,
+    jsonb_build_object(
+      'link_id', t.link_id,
+      'link_type', t.link_type,
+      'peer_device_id', e.peer_device_id,
+      'state', t.to_state,
+      'from_state', t.from_state
+    ) AS details
+  FROM link_state_transitions t
+  CROSS JOIN LATERAL (
+    VALUES (t.a_device_id, t.b_device_id), (t.b_device_id, t.a_device_id)
+  ) AS e(device_id, peer_device_id)
+  UNION ALL
+  SELECT
+    'device_archive:' || a.id::text AS event_id,
+    a.device_id,
+    a.changed_at AS event_at,
+    'archive' AS kind,
+    CASE a.action
+      WHEN 'archived' THEN 'device archived'
+      WHEN 'restored' THEN 'device restored'
+      ELSE 'archived device seen again (unarchived)'
+    END AS summary,
+    jsonb_build_object(
+      'action', a.action,
+      'actor', a.actor,
+      'reason', a.reason,
+      'run_id', a.run_id
+    ) AS details
+  FROM device_archive_events a
+  UNION ALL
+  SELECT
+    'fact_detachment:' || f.id::text AS event_id,
+    f.device_id,
+    f.detached_at AS event_at,
+    'detached' AS kind,
+    f.kind || ' ' || f.value || ' detached' AS summary,
+    jsonb_build_object(
+      'kind', f.kind,
+      'value', f.value,
+      'interface_id', f.interface_id,
+      'last_seen_at', f.last_seen_at,
+      'run_id', f.run_id
+    ) AS details
+  FROM fact_detachments f
+  UNION ALL
+  SELECT
+    'availability:' || v.id::text AS event_id,
+    v.device_id,
+    v.changed_at AS event_at,
+    'availability' AS kind,
+    'device ' || v.state || ' (was ' || v.previous_state || ')' AS summary,
+    jsonb_build_object(
+      'state', v.state,
+      'previous_state', v.previous_state,
+      'run_id', v.run_id,
+      'evidence', v.evidence
+    ) AS details
+  FROM device_availability v
+  WHERE v.previous_state IS NOT NULL
 )
 SELECT
-	event_id,
-	device_id,
-	event_at,
-	kind,
-	summary,
-	details
+  event_id,
+  device_id,
+  event_at,
+  kind,
+  summary,
+  details
 FROM events
 WHERE
-	device_id = $1
-	AND ($2::timestamptz IS NULL OR (event_at < $2::timestamptz OR (event_at = $2::timestamptz AND event_id < $3::text)))
+  device_id = $1
+  AND ($2::timestamptz IS NULL OR (event_at < $2::timestamptz OR (event_at = $2::timestamptz AND event_id < $3::text)))
 ORDER BY event_at DESC, event_id DESC
 LIMIT $4
 `
@@ -0,0 +1,374 @@
+package sqlcgen
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const listRetentionPolicies = `-- name: ListRetentionPolicies :many
+SELECT table_name,
+       keep_raw_days,
+       keep_daily_days,
+       updated_at
+FROM retention_policies
+ORDER BY table_name ASC
+`
+
+const upsertRetentionPolicy = `-- name: UpsertRetentionPolicy :one
+INSERT INTO retention_policies (table_name, keep_raw_days, keep_daily_days)
+VALUES ($1, $2, $3)
+ON CONFLICT (table_name) DO UPDATE
+SET keep_raw_days = EXCLUDED.keep_raw_days,
+    keep_daily_days = EXCLUDED.keep_daily_days,
+    updated_at = now()
+RETURNING table_name, keep_raw_days, keep_daily_days, updated_at
+`
+
+const deleteRetentionPolicy = `-- name: DeleteRetentionPolicy :execrows
+DELETE FROM retention_policies
+WHERE table_name = $1
+`
+
+const deleteIPObservationsBefore = `-- name: DeleteIPObservationsBefore :execrows
+DELETE FROM ip_observations
+WHERE id IN (
+  SELECT id
+  FROM ip_observations
+  WHERE observed_at < $1
+  ORDER BY observed_at ASC
+  LIMIT $2
+)
+`
+
+const thinIPObservations = `-- name: ThinIPObservations :one
+WITH page AS (
+  SELECT id,
+         device_id,
+         ip,
+         observed_at,
+         date_trunc('day', observed_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS day_start
+  FROM ip_observations
+  WHERE observed_at >= $1
+    AND observed_at < $2
+    AND (observed_at, id) > ($3::timestamptz, $4::bigint)
+  ORDER BY observed_at ASC, id ASC
+  LIMIT $5
+),
+thinned AS (
+  DELETE FROM ip_observations o
+  USING page p
+  WHERE o.id = p.id
+    AND EXISTS (
+      SELECT 1
+      FROM ip_observations e
+      WHERE e.device_id = p.device_id
+        AND e.ip = p.ip
+        AND e.observed_at >= p.day_start
+        AND (e.observed_at, e.id) < (p.observed_at, p.id)
+    )
+    AND EXISTS (
+      SELECT 1
+      FROM ip_observations e
+      WHERE e.device_id = p.device_id
+        AND e.ip = p.ip
+        AND e.observed_at < p.day_start + interval '1 day'
+        AND (e.observed_at, e.id) > (p.observed_at, p.id)
+    )
+  RETURNING o.id
+),
+last AS (
+  SELECT observed_at, id
+  FROM page
+  ORDER BY observed_at DESC, id DESC
+  LIMIT 1
+)
+SELECT (SELECT count(*) FROM page) AS scanned,
+       (SELECT count(*) FROM thinned) AS thinned,
+       COALESCE((SELECT observed_at FROM last), $3::timestamptz) AS last_observed_at,
+       COALESCE((SELECT id FROM last), $4::bigint) AS last_id
+`
+
+const countIPObservationsRetention = `-- name: CountIPObservationsRetention :one
+SELECT count(*) AS total,
+       count(*) FILTER (WHERE observed_at < $1) AS to_drop,
+       (
+         SELECT count(*)
+         FROM (
+           SELECT observed_at,
+                  row_number() OVER (day_rows ORDER BY observed_at ASC, id ASC) AS from_first,
+                  row_number() OVER (day_rows ORDER BY observed_at DESC, id DESC) AS from_last
+           FROM ip_observations
+           WHERE observed_at >= $1
+             AND observed_at < $2 + interval '1 day'
+           WINDOW day_rows AS (PARTITION BY device_id, ip, (observed_at AT TIME ZONE 'UTC')::date)
+         ) ranked
+         WHERE observed_at < $2
+           AND from_first > 1
+           AND from_last > 1
+       ) AS to_thin
+FROM ip_observations
+`
+
+const deleteMACObservationsBefore = `-- name: DeleteMACObservationsBefore :execrows
+DELETE FROM mac_observations
+WHERE id IN (
+  SELECT id
+  FROM mac_observations
+  WHERE observed_at < $1
+  ORDER BY observed_at ASC
+  LIMIT $2
+)
+`
+
+const thinMACObservations = `-- name: ThinMACObservations :one
+WITH page AS (
+  SELECT id,
+         device_id,
+         mac,
+         observed_at,
+         date_trunc('day', observed_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS day_start
+  FROM mac_observations
+  WHERE observed_at >= $1
+    AND observed_at < $2
+    AND (observed_at, id) > ($3::timestamptz, $4::bigint)
+  ORDER BY observed_at ASC, id ASC
+  LIMIT $5
+),
+thinned AS (
+  DELETE FROM mac_observations o
+  USING page p
+  WHERE o.id = p.id
+    AND EXISTS (
+      SELECT 1
+      FROM mac_observations e
+      WHERE e.device_id = p.device_id
+        AND e.mac = p.mac
+        AND e.observed_at >= p.day_start
+        AND (e.observed_at, e.id) < (p.observed_at, p.id)
+    )
+    AND EXISTS (
+      SELECT 1
+      FROM mac_observations e
+      WHERE e.device_id = p.device_id
+        AND e.mac = p.mac
+        AND e.observed_at < p.day_start + interval '1 day'
+        AND (e.observed_at, e.id) > (p.observed_at, p.id)
+    )
+  RETURNING o.id
+),
+last AS (
+  SELECT observed_at, id
+  FROM page
+  ORDER BY observed_at DESC, id DESC
+  LIMIT 1
+)
+SELECT (SELECT count(*) FROM page) AS scanned,
+       (SELECT count(*) FROM thinned) AS thinned,
+       COALESCE((SELECT observed_at FROM last), $3::timestamptz) AS last_observed_at,
+       COALESCE((SELECT id FROM last), $4::bigint) AS last_id
+`
+
+const countMACObservationsRetention = `-- name: CountMACObservationsRetention :one
+SELECT count(*) AS total,
+       count(*) FILTER (WHERE observed_at < $1) AS to_drop,
+       (
+         SELECT count(*)
+         FROM (
+           SELECT observed_at,
+                  row_number() OVER (day_rows ORDER BY observed_at ASC, id ASC) AS from_first,
+                  row_number() OVER (day_rows ORDER BY observed_at DESC, id DESC) AS from_last
+           FROM mac_observations
+           WHERE observed_at >= $1
+             AND observed_at < $2 + interval '1 day'
+           WINDOW day_rows AS (PARTITION BY device_id, mac, (observed_at AT TIME ZONE 'UTC')::date)
+         ) ranked
+         WHERE observed_at < $2
+           AND from_first > 1
+           AND from_last > 1
+       ) AS to_thin
+FROM mac_observations
+`
+
+const deleteDiscoveryRunLogsBefore = `-- name: DeleteDiscoveryRunLogsBefore :execrows
+DELETE FROM discovery_run_logs
+WHERE id IN (
+  SELECT id
+  FROM discovery_run_logs
+  WHERE created_at < $1
+  ORDER BY created_at ASC
+  LIMIT $2
+)
+`
+
+const countDiscoveryRunLogsRetention = `-- name: CountDiscoveryRunLogsRetention :one
+SELECT count(*) AS total,
+       count(*) FILTER (WHERE created_at < $1) AS to_drop,
+       0::bigint AS to_thin
+FROM discovery_run_logs
+`
+
+// Tables the retention job prunes.
+const (
+	RetentionTableIPObservations   = "ip_observations"
+	RetentionTableMACObservations  = "mac_observations"
+	RetentionTableDiscoveryRunLogs = "discovery_run_logs"
+)
+
+// ErrUnknownRetentionTable is returned for a table the retention job does not manage.
+var ErrUnknownRetentionTable = errors.New("unknown retention table")
+
+// retentionTableQueries are the prune and dry-run queries of one table. thin is empty for tables
+// without a daily tier.
+type retentionTableQueries struct {
+	deleteBefore string
+	thin         string
+	count        string
+}
+
+var retentionTables = map[string]retentionTableQueries{
+	RetentionTableIPObservations:   {deleteBefore: deleteIPObservationsBefore, thin: thinIPObservations, count: countIPObservationsRetention},
+	RetentionTableMACObservations:  {deleteBefore: deleteMACObservationsBefore, thin: thinMACObservations, count: countMACObservationsRetention},
+	RetentionTableDiscoveryRunLogs: {deleteBefore: deleteDiscoveryRunLogsBefore, count: countDiscoveryRunLogsRetention},
+}
+
+// RetentionTableThins reports whether table supports the daily tier.
+func RetentionTableThins(table string) bool {
+	return retentionTables[table].thin != ""
+}
+
+// RetentionTableKnown reports whether the retention job manages table.
+func RetentionTableKnown(table string) bool {
+	_, ok := retentionTables[table]
+	return ok
+}
+
+type RetentionPolicy struct {
+	TableName     string
+	KeepRawDays   int32
+	KeepDailyDays int32
+	UpdatedAt     time.Time
+}
+
+func (q *Queries) ListRetentionPolicies(ctx context.Context) ([]RetentionPolicy, error) {
+	rows, err := q.db.Query(ctx, listRetentionPolicies)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []RetentionPolicy
+	for rows.Next() {
+		var i RetentionPolicy
+		if err := rows.Scan(&i.TableName, &i.KeepRawDays, &i.KeepDailyDays, &i.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type UpsertRetentionPolicyParams struct {
+	TableName     string
+	KeepRawDays   int32
+	KeepDailyDays int32
+}
+
+func (q *Queries) UpsertRetentionPolicy(ctx context.Context, arg UpsertRetentionPolicyParams) (RetentionPolicy, error) {
+	var i RetentionPolicy
+	err := q.db.QueryRow(ctx, upsertRetentionPolicy, arg.TableName, arg.KeepRawDays, arg.KeepDailyDays).
+		Scan(&i.TableName, &i.KeepRawDays, &i.KeepDailyDays, &i.UpdatedAt)
+	return i, err
+}
+
+func (q *Queries) DeleteRetentionPolicy(ctx context.Context, tableName string) (int64, error) {
+	tag, err := q.db.Exec(ctx, deleteRetentionPolicy, tableName)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+type DeleteRetentionRowsParams struct {
+	Table  string
+	Before time.Time
+	Limit  int32
+}
+
+// DeleteRetentionRows deletes up to Limit of the oldest rows of Table written before Before.
+func (q *Queries) DeleteRetentionRows(ctx context.Context, arg DeleteRetentionRowsParams) (int64, error) {
+	t, ok := retentionTables[arg.Table]
+	if !ok {
+		return 0, ErrUnknownRetentionTable
+	}
+	tag, err := q.db.Exec(ctx, t.deleteBefore, arg.Before, arg.Limit)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+type ThinRetentionRowsParams struct {
+	Table  string
+	From   time.Time
+	Before time.Time
+	// AfterObservedAt and AfterID are the keyset to resume from: the Last* of the previous page, or From and 0.
+	AfterObservedAt time.Time
+	AfterID         int64
+	Limit           int32
+}
+
+// ThinRetentionPage is one page of a thinning pass.
+type ThinRetentionPage struct {
+	Scanned        int64
+	Thinned        int64
+	LastObservedAt time.Time
+	LastID         int64
+}
+
+// ThinRetentionRows scans up to Limit rows of Table in [From, Before) after the keyset, oldest first, and deletes
+// those that are neither the first nor the last of their device, address and UTC day. Tables without a daily tier
+// scan no rows.
+func (q *Queries) ThinRetentionRows(ctx context.Context, arg ThinRetentionRowsParams) (ThinRetentionPage, error) {
+	t, ok := retentionTables[arg.Table]
+	if !ok {
+		return ThinRetentionPage{}, ErrUnknownRetentionTable
+	}
+	if t.thin == "" {
+		return ThinRetentionPage{LastObservedAt: arg.AfterObservedAt, LastID: arg.AfterID}, nil
+	}
+	var i ThinRetentionPage
+	err := q.db.QueryRow(ctx, t.thin, arg.From, arg.Before, arg.AfterObservedAt, arg.AfterID, arg.Limit).
+		Scan(&i.Scanned, &i.Thinned, &i.LastObservedAt, &i.LastID)
+	return i, err
+}
+
+type CountRetentionRowsParams struct {
+	Table      string
+	DropBefore time.Time
+	ThinBefore time.Time
+}
+
+// RetentionCounts is a dry run of one table's policy.
+type RetentionCounts struct {
+	Total  int64
+	ToDrop int64
+	ToThin int64
+}
+
+func (q *Queries) CountRetentionRows(ctx context.Context, arg CountRetentionRowsParams) (RetentionCounts, error) {
+	t, ok := retentionTables[arg.Table]
+	if !ok {
+		return RetentionCounts{}, ErrUnknownRetentionTable
+	}
+	args := []any{arg.DropBefore}
+	if t.thin != "" {
+		args = append(args, arg.ThinBefore)
+	}
+	var i RetentionCounts
+	err := q.db.QueryRow(ctx, t.count, args...).Scan(&i.Total, &i.ToDrop, &i.ToThin)
+	return i, err
+}
@@ -0,0 +1,399 @@
+package sqlcgen
+
+import (
+	"context"
+	"time"
+)
+
+// SNMPProfileItem is one polled OID of a profile: a scalar (read with GET) or a table column (walked,
+// one value per row index).
+type SNMPProfileItem struct {
+	Key   string `json:"key"`
+	Label string `json:"label,omitempty"`
+	OID   string `json:"oid"`
+	Kind  string `json:"kind"`
+	Type  string `json:"type"`
+}
+
+type SNMPProfile struct {
+	ID          string
+	Name        string
+	Description *string
+	Tags        []string
+	Items       []SNMPProfileItem
+	Enabled     bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+const listSNMPProfiles = `-- name: ListSNMPProfiles :many
+SELECT id::text, name, description, tags, items, enabled, created_at, updated_at
+FROM snmp_profiles
+ORDER BY name ASC
+`
+
+func (q *Queries) ListSNMPProfiles(ctx context.Context) ([]SNMPProfile, error) {
+	rows, err := q.db.Query(ctx, listSNMPProfiles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []SNMPProfile
+	for rows.Next() {
+		var i SNMPProfile
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Tags,
+			&i.Items,
+			&i.Enabled,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSNMPProfile = `-- name: GetSNMPProfile :one
+SELECT id::text, name, description, tags, items, enabled, created_at, updated_at
+FROM snmp_profiles
+WHERE id = $1::uuid
+`
+
+func (q *Queries) GetSNMPProfile(ctx context.Context, id string) (SNMPProfile, error) {
+	row := q.db.QueryRow(ctx, getSNMPProfile, id)
+	var i SNMPProfile
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Tags,
+		&i.Items,
+		&i.Enabled,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createSNMPProfile = `-- name: CreateSNMPProfile :one
+INSERT INTO snmp_profiles (name, description, tags, items, enabled)
+VALUES ($1, $2, $3::text[], $4::jsonb, $5)
+RETURNING id::text, name, description, tags, items, enabled, created_at, updated_at
+`
+
+type CreateSNMPProfileParams struct {
+	Name        string
+	Description *string
+	Tags        []string
+	Items       []SNMPProfileItem
+	Enabled     bool
+}
+
+func (q *Queries) CreateSNMPProfile(ctx context.Context, arg CreateSNMPProfileParams) (SNMPProfile, error) {
+	row := q.db.QueryRow(ctx, createSNMPProfile, arg.Name, arg.Description, arg.Tags, arg.Items, arg.Enabled)
+	var i SNMPProfile
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Tags,
+		&i.Items,
+		&i.Enabled,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateSNMPProfile = `-- name: UpdateSNMPProfile :one
+UPDATE snmp_profiles
+SET name = $2,
+    description = $3,
+    tags = $4::text[],
+    items = $5::jsonb,
+    enabled = $6,
+    updated_at = now()
+WHERE id = $1::uuid
+RETURNING id::text, name, description, tags, items, enabled, created_at, updated_at
+`
+
+type UpdateSNMPProfileParams struct {
+	ID          string
+	Name        string
+	Description *string
+	Tags        []string
+	Items       []SNMPProfileItem
+	Enabled     bool
+}
+
+func (q *Queries) UpdateSNMPProfile(ctx context.Context, arg UpdateSNMPProfileParams) (SNMPProfile, error) {
+	row := q.db.QueryRow(ctx, updateSNMPProfile, arg.ID, arg.Name, arg.Description, arg.Tags, arg.Items, arg.Enabled)
+	var i SNMPProfile
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Tags,
+		&i.Items,
+		&i.Enabled,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteSNMPProfile = `-- name: DeleteSNMPProfile :execrows
+DELETE FROM snmp_profiles
+WHERE id = $1::uuid
+`
+
+func (q *Queries) DeleteSNMPProfile(ctx context.Context, id string) (int64, error) {
+	tag, err := q.db.Exec(ctx, deleteSNMPProfile, id)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+const listSNMPProfilesForDevice = `-- name: ListSNMPProfilesForDevice :many
+-- Enabled profiles bound to any tag the device currently carries (manual or auto).
+SELECT p.id::text, p.name, p.description, p.tags, p.items, p.enabled, p.created_at, p.updated_at
+FROM snmp_profiles p
+WHERE p.enabled
+  AND p.tags && ARRAY(SELECT t.tag FROM device_tags t WHERE t.device_id = $1::uuid)
+ORDER BY p.name ASC
+`
+
+func (q *Queries) ListSNMPProfilesForDevice(ctx context.Context, deviceID string) ([]SNMPProfile, error) {
+	rows, err := q.db.Query(ctx, listSNMPProfilesForDevice, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []SNMPProfile
+	for rows.Next() {
+		var i SNMPProfile
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Tags,
+			&i.Items,
+			&i.Enabled,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertDeviceCustomFact = `-- name: UpsertDeviceCustomFact :execrows
+-- Stores the current value and appends a history row when the value is new or changed; the affected row
+-- count is 1 only when history was written.
+WITH prev AS (
+  SELECT value
+  FROM device_custom_facts
+  WHERE device_id = $1::uuid AND profile_id = $2::uuid AND fact_key = $3 AND instance = $4
+), upserted AS (
+  INSERT INTO device_custom_facts (
+    device_id, profile_id, fact_key, instance, label, oid, value_type, value, numeric_value,
+    first_seen_at, observed_at, changed_at
+  )
+  VALUES ($1::uuid, $2::uuid, $3, $4, $5, $6, $7, $8, $9, $10, $10, $10)
+  ON CONFLICT (device_id, profile_id, fact_key, instance) DO UPDATE
+  SET label = EXCLUDED.label,
+      oid = EXCLUDED.oid,
+      value_type = EXCLUDED.value_type,
+      value = EXCLUDED.value,
+      numeric_value = EXCLUDED.numeric_value,
+      observed_at = EXCLUDED.observed_at,
+      changed_at = CASE
+        WHEN device_custom_facts.value IS DISTINCT FROM EXCLUDED.value THEN EXCLUDED.observed_at
+        ELSE device_custom_facts.changed_at
+      END
+  RETURNING 1
+)
+INSERT INTO device_custom_fact_history (device_id, profile_id, fact_key, instance, value, numeric_value, observed_at)
+SELECT $1::uuid, $2::uuid, $3, $4, $8, $9, $10
+FROM upserted
+WHERE NOT EXISTS (SELECT 1 FROM prev WHERE prev.value = $8)
+`
+
+type UpsertDeviceCustomFactParams struct {
+	DeviceID     string
+	ProfileID    string
+	FactKey      string
+	Instance     string
+	Label        *string
+	OID          string
+	ValueType    string
+	Value        string
+	NumericValue *float64
+	ObservedAt   time.Time
+}
+
+// UpsertDeviceCustomFact returns 1 when the value was new or changed (a history row was appended).
+func (q *Queries) UpsertDeviceCustomFact(ctx context.Context, arg UpsertDeviceCustomFactParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, upsertDeviceCustomFact,
+		arg.DeviceID,
+		arg.ProfileID,
+		arg.FactKey,
+		arg.Instance,
+		arg.Label,
+		arg.OID,
+		arg.ValueType,
+		arg.Value,
+		arg.NumericValue,
+		arg.ObservedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+const deleteStaleDeviceCustomFacts = `-- name: DeleteStaleDeviceCustomFacts :execrows
+-- Drops current values a successful poll of the profile no longer reported (history is kept).
+DELETE FROM device_custom_facts
+WHERE device_id = $1::uuid
+  AND profile_id = $2::uuid
+  AND observed_at < $3
+`
+
+type DeleteStaleDeviceCustomFactsParams struct {
+	DeviceID  string
+	ProfileID string
+	Before    time.Time
+}
+
+func (q *Queries) DeleteStaleDeviceCustomFacts(ctx context.Context, arg DeleteStaleDeviceCustomFactsParams) (int64, error) {
+	tag, err := q.db.Exec(ctx, deleteStaleDeviceCustomFacts, arg.DeviceID, arg.ProfileID, arg.Before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+const listDeviceCustomFacts = `-- name: ListDeviceCustomFacts :many
+SELECT f.profile_id::text, p.name, f.fact_key, f.instance, f.label, f.oid, f.value_type, f.value,
+       f.numeric_value, f.first_seen_at, f.observed_at, f.changed_at
+FROM device_custom_facts f
+JOIN snmp_profiles p ON p.id = f.profile_id
+WHERE f.device_id = $1::uuid
+ORDER BY p.name ASC, f.fact_key ASC, f.instance ASC
+`
+
+type DeviceCustomFact struct {
+	ProfileID    string
+	ProfileName  string
+	FactKey      string
+	Instance     string
+	Label        *string
+	OID          string
+	ValueType    string
+	Value        string
+	NumericValue *float64
+	FirstSeenAt  time.Time
+	ObservedAt   time.Time
+	ChangedAt    time.Time
+}
+
+func (q *Queries) ListDeviceCustomFacts(ctx context.Context, deviceID string) ([]DeviceCustomFact, error) {
+	rows, err := q.db.Query(ctx, listDeviceCustomFacts, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []DeviceCustomFact
+	for rows.Next() {
+		var i DeviceCustomFact
+		if err := rows.Scan(
+			&i.ProfileID,
+			&i.ProfileName,
+			&i.FactKey,
+			&i.Instance,
+			&i.Label,
+			&i.OID,
+			&i.ValueType,
+			&i.Value,
+			&i.NumericValue,
+			&i.FirstSeenAt,
+			&i.ObservedAt,
+			&i.ChangedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDeviceCustomFactHistory = `-- name: ListDeviceCustomFactHistory :many
+-- The most recent value changes per fact, newest first.
+SELECT profile_id, fact_key, instance, value, numeric_value, observed_at
+FROM (
+  SELECT h.profile_id::text AS profile_id, h.fact_key, h.instance, h.value, h.numeric_value, h.observed_at,
+         row_number() OVER (PARTITION BY h.profile_id, h.fact_key, h.instance ORDER BY h.observed_at DESC, h.id DESC) AS rn
+  FROM device_custom_fact_history h
+  WHERE h.device_id = $1::uuid
+) ranked
+WHERE rn <= $2
+ORDER BY profile_id ASC, fact_key ASC, instance ASC, observed_at DESC
+`
+
+type DeviceCustomFactChange struct {
+	ProfileID    string
+	FactKey      string
+	Instance     string
+	Value        string
+	NumericValue *float64
+	ObservedAt   time.Time
+}
+
+func (q *Queries) ListDeviceCustomFactHistory(ctx context.Context, deviceID string, perFactLimit int32) ([]DeviceCustomFactChange, error) {
+	rows, err := q.db.Query(ctx, listDeviceCustomFactHistory, deviceID, perFactLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []DeviceCustomFactChange
+	for rows.Next() {
+		var i DeviceCustomFactChange
+		if err := rows.Scan(
+			&i.ProfileID,
+			&i.FactKey,
+			&i.Instance,
+			&i.Value,
+			&i.NumericValue,
+			&i.ObservedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,147 @@
+package sqlcgen
+
+import (
+	"context"
+	"time"
+)
+
+type SSHHostKey struct {
+	ID                  string
+	ServiceID           *string
+	Port                *int32
+	KeyType             string
+	FingerprintSHA256   string
+	PublicKey           string
+	Banner              *string
+	FirstSeenAt         time.Time
+	LastSeenAt          time.Time
+	SharedWithDeviceIDs []string
+}
+
+const upsertSSHHostKey = `-- name: UpsertSSHHostKey :one
+WITH upserted AS (
+  INSERT INTO ssh_host_keys (
+    device_id,
+    service_id,
+    key_type,
+    fingerprint_sha256,
+    public_key,
+    banner,
+    first_seen_at,
+    last_seen_at
+  )
+  VALUES (
+    $1::uuid,
+    $2::uuid,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7::timestamptz,
+    $7::timestamptz
+  )
+  ON CONFLICT (device_id, key_type, fingerprint_sha256)
+  DO UPDATE
+  SET service_id = EXCLUDED.service_id,
+      banner = COALESCE(EXCLUDED.banner, ssh_host_keys.banner),
+      last_seen_at = EXCLUDED.last_seen_at,
+      updated_at = now()
+  RETURNING id
+)
+SELECT upserted.id,
+       (
+         SELECT count(DISTINCT o.device_id)
+         FROM ssh_host_keys o
+         WHERE o.fingerprint_sha256 = $4
+           AND o.device_id <> $1::uuid
+       )::int AS shared_device_count
+FROM upserted
+`
+
+type UpsertSSHHostKeyParams struct {
+	DeviceID          string
+	ServiceID         *string
+	KeyType           string
+	FingerprintSHA256 string
+	PublicKey         string
+	Banner            *string
+	ObservedAt        time.Time
+}
+
+type UpsertSSHHostKeyRow struct {
+	ID                string
+	SharedDeviceCount int32
+}
+
+func (q *Queries) UpsertSSHHostKey(ctx context.Context, arg UpsertSSHHostKeyParams) (UpsertSSHHostKeyRow, error) {
+	row := q.db.QueryRow(ctx, upsertSSHHostKey,
+		arg.DeviceID,
+		arg.ServiceID,
+		arg.KeyType,
+		arg.FingerprintSHA256,
+		arg.PublicKey,
+		arg.Banner,
+		arg.ObservedAt,
+	)
+	var i UpsertSSHHostKeyRow
+	err := row.Scan(&i.ID, &i.SharedDeviceCount)
+	return i, err
+}
+
+const listDeviceSSHHostKeys = `-- name: ListDeviceSSHHostKeys :many
+SELECT DISTINCT ON (k.key_type)
+       k.id,
+       k.service_id,
+       s.port,
+       k.key_type,
+       k.fingerprint_sha256,
+       k.public_key,
+       k.banner,
+       k.first_seen_at,
+       k.last_seen_at,
+       COALESCE(
+         (
+           SELECT array_agg(DISTINCT o.device_id::text)
+           FROM ssh_host_keys o
+           WHERE o.fingerprint_sha256 = k.fingerprint_sha256
+             AND o.device_id <> k.device_id
+         ),
+         '{}'
+       )::text[] AS shared_with_device_ids
+FROM ssh_host_keys k
+LEFT JOIN services s ON s.id = k.service_id
+WHERE k.device_id = $1::uuid
+ORDER BY k.key_type ASC, k.last_seen_at DESC, k.id ASC
+`
+
+func (q *Queries) ListDeviceSSHHostKeys(ctx context.Context, deviceID string) ([]SSHHostKey, error) {
+	rows, err := q.db.Query(ctx, listDeviceSSHHostKeys, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []SSHHostKey
+	for rows.Next() {
+		var i SSHHostKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceID,
+			&i.Port,
+			&i.KeyType,
+			&i.FingerprintSHA256,
+			&i.PublicKey,
+			&i.Banner,
+			&i.FirstSeenAt,
+			&i.LastSeenAt,
+			&i.SharedWithDeviceIDs,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
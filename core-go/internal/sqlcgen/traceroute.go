@@ -0,0 +1,47 @@
+package sqlcgen
+
+import (
+	"context"
+	"time"
+)
+
+const insertTraceroutePath = `-- name: InsertTraceroutePath :exec
+WITH path AS (
+  INSERT INTO traceroute_paths (run_id, scope, target, mode, reached, hop_count, observed_at)
+  VALUES ($1::uuid, $2::cidr, $3::inet, $4, $5, cardinality($6::int[]), $10)
+  RETURNING id
+)
+INSERT INTO traceroute_hops (path_id, ttl, ip, device_id, rtt_ms)
+SELECT path.id, h.ttl, h.ip::inet, h.device_id::uuid, h.rtt_ms
+FROM path, unnest($6::int[], $7::text[], $8::text[], $9::real[]) AS h(ttl, ip, device_id, rtt_ms)
+`
+
+// InsertTraceroutePathParams carries one path; TTLs, IPs, DeviceIDs and RTTMs are parallel hop arrays.
+type InsertTraceroutePathParams struct {
+	RunID      *string
+	Scope      string
+	Target     string
+	Mode       string
+	Reached    bool
+	TTLs       []int32
+	IPs        []*string
+	DeviceIDs  []*string
+	RTTMs      []*float32
+	ObservedAt time.Time
+}
+
+func (q *Queries) InsertTraceroutePath(ctx context.Context, arg InsertTraceroutePathParams) error {
+	_, err := q.db.Exec(ctx, insertTraceroutePath,
+		arg.RunID,
+		arg.Scope,
+		arg.Target,
+		arg.Mode,
+		arg.Reached,
+		arg.TTLs,
+		arg.IPs,
+		arg.DeviceIDs,
+		arg.RTTMs,
+		arg.ObservedAt,
+	)
+	return err
+}
@@ -200,6 +200,322 @@ func SuggestFromOpenPorts(openPorts []int32) []Suggestion {
 	return out
 }
 
+// HTTPFingerprint is the subset of an HTTP service fingerprint used for classification.
+type HTTPFingerprint struct {
+	Port        int32
+	Title       string
+	Server      string
+	PoweredBy   string
+	FaviconHash *int32
+}
+
+// SuggestFromHTTP matches well-known appliance web UIs (NVRs, printer EWS pages, NAS, firewalls) by
+// page title and Server/X-Powered-By headers.
+func SuggestFromHTTP(fp HTTPFingerprint) []Suggestion {
+	title := strings.ToLower(strings.TrimSpace(fp.Title))
+	server := strings.ToLower(strings.TrimSpace(fp.Server))
+	poweredBy := strings.ToLower(strings.TrimSpace(fp.PoweredBy))
+	if title == "" && server == "" && poweredBy == "" {
+		return nil
+	}
+
+	add := func(tag string, match string, confidence int) Suggestion {
+		evidence := map[string]any{
+			"signal": "http",
+			"match":  match,
+			"port":   fp.Port,
+		}
+		if fp.Title != "" {
+			evidence["title"] = truncate(fp.Title, 120)
+		}
+		if fp.Server != "" {
+			evidence["server"] = truncate(fp.Server, 120)
+		}
+		if fp.FaviconHash != nil {
+			evidence["favicon_hash"] = *fp.FaviconHash
+		}
+		return Suggestion{Tag: tag, Confidence: confidence, Evidence: evidence}
+	}
+	contains := func(value string, needles ...string) bool {
+		for _, n := range needles {
+			if value != "" && strings.Contains(value, n) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var out []Suggestion
+	switch {
+	case contains(server, "hikvision", "dnvrs-webs", "app-webs"), contains(title, "hikvision"):
+		out = append(out, add(TagCamera, "hikvision", 88))
+	case contains(title, "dahua"), contains(server, "dahua"):
+		out = append(out, add(TagCamera, "dahua", 86))
+	case contains(title, "network video recorder", "nvr", "dvr", "ip camera", "axis ", "reolink", "amcrest", "unifi protect", "blue iris", "frigate"):
+		out = append(out, add(TagCamera, "nvr_title", 84))
+	}
+
+	switch {
+	case contains(server, "hp http server", "hp-chaisoe", "cups/", "kyocera", "xerox", "lexmark", "epson_linux", "canon http server"):
+		out = append(out, add(TagPrinter, "printer_server", 86))
+	case contains(title, "laserjet", "officejet", "deskjet", "embedded web server", "web image monitor", "brother ", "epson ", "lexmark", "xerox", "kyocera", "command center"):
+		out = append(out, add(TagPrinter, "printer_ews", 84))
+	}
+
+	if contains(title, "synology", "diskstation", "qnap", "truenas", "freenas", "unraid", "openmediavault") {
+		out = append(out, add(TagNAS, "nas_ui", 84))
+	}
+
+	switch {
+	case contains(title, "pfsense", "opnsense", "fortigate", "sophos", "sonicwall", "watchguard"):
+		out = append(out, add(TagFirewall, "firewall_ui", 84))
+	case contains(title, "routeros", "mikrotik", "openwrt", "luci", "edgeos", "edgerouter", "dd-wrt", "asuswrt"):
+		out = append(out, add(TagRouter, "router_ui", 80))
+	}
+
+	if contains(title, "proxmox virtual environment", "vmware esxi", "xen orchestra") {
+		out = append(out, add(TagVMHost, "hypervisor_ui", 86))
+	}
+
+	if contains(title, "home assistant", "shelly", "tasmota", "esphome", "philips hue") || contains(poweredBy, "esphome") {
+		out = append(out, add(TagIoT, "iot_ui", 78))
+	}
+
+	return out
+}
+
+// OSGuess is a single OS fingerprint match (e.g. from nmap -O).
+type OSGuess struct {
+	Name       string
+	Vendor     string
+	Family     string
+	Generation string
+	DeviceType string
+	Accuracy   int
+	CPE        []string
+}
+
+// osGuessMinAccuracy is the lowest OS match accuracy that is allowed to drive a tag.
+const osGuessMinAccuracy = 85
+
+// SuggestFromOS classifies a device from its best OS match: the nmap device type (router, WAP, printer,
+// webcam, ...) first, then the OS family (Windows client vs server, ESXi). Confidence scales with the
+// match accuracy and low-accuracy guesses are ignored.
+func SuggestFromOS(guess OSGuess) []Suggestion {
+	if guess.Accuracy < osGuessMinAccuracy {
+		return nil
+	}
+	deviceType := strings.ToLower(strings.TrimSpace(guess.DeviceType))
+	family := strings.ToLower(strings.TrimSpace(guess.Family))
+	name := strings.ToLower(strings.TrimSpace(guess.Name))
+	gen := strings.ToLower(strings.TrimSpace(guess.Generation))
+
+	add := func(tag string, match string, base int) Suggestion {
+		return Suggestion{
+			Tag:        tag,
+			Confidence: base * guess.Accuracy / 100,
+			Evidence: map[string]any{
+				"signal":   "os",
+				"match":    match,
+				"os":       truncate(guess.Name, 120),
+				"accuracy": guess.Accuracy,
+			},
+		}
+	}
+
+	var out []Suggestion
+	switch deviceType {
+	case "router", "broadband router":
+		out = append(out, add(TagRouter, "device_type", 85))
+	case "switch":
+		out = append(out, add(TagSwitch, "device_type", 85))
+	case "wap":
+		out = append(out, add(TagAccessPoint, "device_type", 85))
+	case "firewall":
+		out = append(out, add(TagFirewall, "device_type", 85))
+	case "printer":
+		out = append(out, add(TagPrinter, "device_type", 85))
+	case "webcam":
+		out = append(out, add(TagCamera, "device_type", 85))
+	case "storage-misc":
+		out = append(out, add(TagNAS, "device_type", 80))
+	case "media device", "power-device", "specialized":
+		out = append(out, add(TagIoT, "device_type", 70))
+	}
+
+	switch {
+	case strings.Contains(name, "esxi") || family == "esx":
+		out = append(out, add(TagVMHost, "os_family", 85))
+	case family == "windows" && (strings.Contains(name, "server") || strings.Contains(gen, "server") || strings.HasPrefix(gen, "20")):
+		out = append(out, add(TagServer, "os_family", 75))
+	case family == "windows" && deviceType == "general purpose":
+		out = append(out, add(TagWorkstation, "os_family", 70))
+	case family == "mac os x" || family == "macos":
+		out = append(out, add(TagWorkstation, "os_family", 65))
+	}
+
+	return out
+}
+
+type cpeRule struct {
+	prefix     string
+	tag        string
+	confidence int
+}
+
+// cpeRules match normalized CPE 2.2 URIs (`cpe:/<part>:<vendor>:<product>`) by prefix. More specific
+// prefixes come first; the first match per tag wins.
+var cpeRules = []cpeRule{
+	{prefix: "cpe:/o:vmware:esxi", tag: TagVMHost, confidence: 88},
+	{prefix: "cpe:/a:proxmox:virtual_environment", tag: TagVMHost, confidence: 86},
+	{prefix: "cpe:/o:fortinet:fortios", tag: TagFirewall, confidence: 88},
+	{prefix: "cpe:/o:paloaltonetworks:pan-os", tag: TagFirewall, confidence: 88},
+	{prefix: "cpe:/a:pfsense:pfsense", tag: TagFirewall, confidence: 86},
+	{prefix: "cpe:/o:cisco:asa", tag: TagFirewall, confidence: 86},
+	{prefix: "cpe:/o:mikrotik:routeros", tag: TagRouter, confidence: 84},
+	{prefix: "cpe:/o:juniper:junos", tag: TagRouter, confidence: 80},
+	{prefix: "cpe:/o:cisco:ios", tag: TagRouter, confidence: 72},
+	{prefix: "cpe:/o:synology:diskstation_manager", tag: TagNAS, confidence: 88},
+	{prefix: "cpe:/o:qnap:qts", tag: TagNAS, confidence: 88},
+	{prefix: "cpe:/h:synology:", tag: TagNAS, confidence: 86},
+	{prefix: "cpe:/h:qnap:", tag: TagNAS, confidence: 86},
+	{prefix: "cpe:/h:hp:laserjet", tag: TagPrinter, confidence: 88},
+	{prefix: "cpe:/h:hp:officejet", tag: TagPrinter, confidence: 88},
+	{prefix: "cpe:/h:brother:", tag: TagPrinter, confidence: 84},
+	{prefix: "cpe:/h:lexmark:", tag: TagPrinter, confidence: 84},
+	{prefix: "cpe:/h:xerox:", tag: TagPrinter, confidence: 84},
+	{prefix: "cpe:/h:hikvision:", tag: TagCamera, confidence: 88},
+	{prefix: "cpe:/h:dahua:", tag: TagCamera, confidence: 86},
+	{prefix: "cpe:/h:axis:", tag: TagCamera, confidence: 84},
+	{prefix: "cpe:/h:ubiquiti:unifi_ap", tag: TagAccessPoint, confidence: 84},
+	{prefix: "cpe:/o:microsoft:windows_server", tag: TagServer, confidence: 78},
+	{prefix: "cpe:/o:microsoft:windows_10", tag: TagWorkstation, confidence: 72},
+	{prefix: "cpe:/o:microsoft:windows_11", tag: TagWorkstation, confidence: 72},
+	{prefix: "cpe:/o:microsoft:windows_7", tag: TagWorkstation, confidence: 70},
+	{prefix: "cpe:/o:apple:mac_os_x", tag: TagWorkstation, confidence: 65},
+}
+
+// SuggestFromCPE classifies a device from CPE identifiers reported by service/OS detection
+// (e.g. `cpe:/o:vmware:esxi:7.0.3`, `cpe:/h:hp:laserjet_pro_m404`).
+func SuggestFromCPE(cpes []string) []Suggestion {
+	if len(cpes) == 0 {
+		return nil
+	}
+	var out []Suggestion
+	seenTag := map[string]bool{}
+	for _, rule := range cpeRules {
+		if seenTag[rule.tag] {
+			continue
+		}
+		for _, raw := range cpes {
+			cpe := strings.ToLower(strings.TrimSpace(raw))
+			if !strings.HasPrefix(cpe, rule.prefix) {
+				continue
+			}
+			seenTag[rule.tag] = true
+			out = append(out, Suggestion{
+				Tag:        rule.tag,
+				Confidence: rule.confidence,
+				Evidence: map[string]any{
+					"signal": "cpe",
+					"match":  rule.prefix,
+					"cpe":    truncate(raw, 120),
+				},
+			})
+			break
+		}
+	}
+	return out
+}
+
+// NeighborInfo is what a device advertises about itself over LLDP or CDP.
+type NeighborInfo struct {
+	Source       string   // "lldp" | "cdp"
+	Capabilities []string // normalized: bridge, router, wlan_ap, telephone, docsis, station, repeater, other
+	Platform     string   // CDP platform or LLDP-MED model
+	SysDescr     string   // LLDP system description or CDP software version
+}
+
+type platformRule struct {
+	match      string
+	tag        string
+	confidence int
+}
+
+// platformRules match lowercased CDP platforms / LLDP-MED model names by substring.
+var platformRules = []platformRule{
+	{match: "air-ap", tag: TagAccessPoint, confidence: 88},
+	{match: "air-cap", tag: TagAccessPoint, confidence: 88},
+	{match: "access point", tag: TagAccessPoint, confidence: 86},
+	{match: "ip phone", tag: TagIoT, confidence: 82},
+	{match: "cp-", tag: TagIoT, confidence: 80},
+	{match: "ws-c", tag: TagSwitch, confidence: 86},
+	{match: "catalyst", tag: TagSwitch, confidence: 84},
+	{match: "isr", tag: TagRouter, confidence: 82},
+	{match: "asr", tag: TagRouter, confidence: 82},
+}
+
+// SuggestFromNeighbor classifies a device from its own LLDP/CDP advertisement (as seen by a neighbor):
+// enabled capabilities first, then platform/model hints, then the same system description keywords as
+// SNMP. Used for devices that are only known as someone's neighbor.
+func SuggestFromNeighbor(info NeighborInfo) []Suggestion {
+	source := strings.TrimSpace(info.Source)
+	if source == "" {
+		source = "lldp"
+	}
+	caps := map[string]bool{}
+	for _, c := range info.Capabilities {
+		caps[strings.ToLower(strings.TrimSpace(c))] = true
+	}
+
+	add := func(tag string, match string, confidence int) Suggestion {
+		evidence := map[string]any{
+			"signal": source,
+			"match":  match,
+		}
+		if len(info.Capabilities) > 0 {
+			evidence["capabilities"] = info.Capabilities
+		}
+		if info.Platform != "" {
+			evidence["platform"] = truncate(info.Platform, 120)
+		}
+		return Suggestion{Tag: tag, Confidence: confidence, Evidence: evidence}
+	}
+
+	var out []Suggestion
+	switch {
+	case caps["wlan_ap"]:
+		out = append(out, add(TagAccessPoint, "capability:wlan_ap", 88))
+	case caps["telephone"]:
+		out = append(out, add(TagIoT, "capability:telephone", 78))
+	case caps["bridge"] && caps["router"]:
+		// Layer 3 switches advertise both.
+		out = append(out, add(TagSwitch, "capability:bridge", 85), add(TagRouter, "capability:router", 60))
+	case caps["bridge"]:
+		out = append(out, add(TagSwitch, "capability:bridge", 85))
+	case caps["router"]:
+		out = append(out, add(TagRouter, "capability:router", 85))
+	case caps["docsis"]:
+		out = append(out, add(TagRouter, "capability:docsis", 65))
+	}
+
+	platform := strings.ToLower(strings.TrimSpace(info.Platform))
+	if platform != "" {
+		for _, rule := range platformRules {
+			if strings.Contains(platform, rule.match) {
+				out = append(out, add(rule.tag, "platform", rule.confidence))
+				break
+			}
+		}
+	}
+
+	for _, s := range SuggestFromSNMP(info.SysDescr) {
+		s.Evidence["signal"] = source
+		out = append(out, s)
+	}
+	return MergeSuggestions(out)
+}
+
 func tokenize(value string) []string {
 	var out []string
 	var buf strings.Builder
@@ -0,0 +1,127 @@
+package tagging
+
+import "testing"
+
+func TestSuggestFromHTTP(t *testing.T) {
+	cases := []struct {
+		name string
+		fp   HTTPFingerprint
+		want []string
+	}{
+		{name: "hikvision nvr", fp: HTTPFingerprint{Port: 80, Server: "DNVRS-Webs"}, want: []string{TagCamera}},
+		{name: "generic nvr title", fp: HTTPFingerprint{Port: 443, Title: "NVR Login"}, want: []string{TagCamera}},
+		{name: "hp ews", fp: HTTPFingerprint{Port: 80, Title: "HP Color LaserJet MFP M479fdw", Server: "HP HTTP Server; HP Color LaserJet"}, want: []string{TagPrinter}},
+		{name: "synology", fp: HTTPFingerprint{Port: 5001, Title: "Synology DiskStation - nas01", Server: "nginx"}, want: []string{TagNAS}},
+		{name: "proxmox", fp: HTTPFingerprint{Port: 8006, Title: "pve01 - Proxmox Virtual Environment"}, want: []string{TagVMHost}},
+		{name: "opnsense", fp: HTTPFingerprint{Port: 443, Title: "Login | OPNsense"}, want: []string{TagFirewall}},
+		{name: "plain nginx", fp: HTTPFingerprint{Port: 80, Title: "Welcome to nginx!", Server: "nginx/1.25"}, want: nil},
+		{name: "empty", fp: HTTPFingerprint{Port: 80}, want: nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := SuggestFromHTTP(tc.fp)
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %+v", tc.want, got)
+			}
+			for i, tag := range tc.want {
+				if got[i].Tag != tag {
+					t.Fatalf("expected %v, got %+v", tc.want, got)
+				}
+				if got[i].Evidence["signal"] != "http" {
+					t.Fatalf("expected http evidence, got %v", got[i].Evidence)
+				}
+			}
+		})
+	}
+}
+
+func TestSuggestFromOS(t *testing.T) {
+	cases := []struct {
+		name  string
+		guess OSGuess
+		want  []string
+	}{
+		{name: "router", guess: OSGuess{Name: "MikroTik RouterOS 6.45 - 6.49", DeviceType: "router", Family: "RouterOS", Accuracy: 96}, want: []string{TagRouter}},
+		{name: "printer", guess: OSGuess{Name: "HP LaserJet M402dn printer", DeviceType: "printer", Accuracy: 100}, want: []string{TagPrinter}},
+		{name: "windows server", guess: OSGuess{Name: "Microsoft Windows Server 2019", DeviceType: "general purpose", Family: "Windows", Generation: "2019", Accuracy: 97}, want: []string{TagServer}},
+		{name: "windows client", guess: OSGuess{Name: "Microsoft Windows 10 1909 - 2004", DeviceType: "general purpose", Family: "Windows", Generation: "10", Accuracy: 95}, want: []string{TagWorkstation}},
+		{name: "esxi", guess: OSGuess{Name: "VMware ESXi 7.0", DeviceType: "general purpose", Family: "ESX Server", Accuracy: 92}, want: []string{TagVMHost}},
+		{name: "plain linux", guess: OSGuess{Name: "Linux 5.0 - 5.14", DeviceType: "general purpose", Family: "Linux", Accuracy: 98}, want: nil},
+		{name: "low accuracy", guess: OSGuess{Name: "Cisco IOS 15", DeviceType: "router", Accuracy: 80}, want: nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := SuggestFromOS(tc.guess)
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %+v", tc.want, got)
+			}
+			for i, tag := range tc.want {
+				if got[i].Tag != tag || got[i].Evidence["signal"] != "os" {
+					t.Fatalf("expected %v, got %+v", tc.want, got)
+				}
+				if got[i].Confidence > 85 {
+					t.Fatalf("confidence should scale below the base, got %d", got[i].Confidence)
+				}
+			}
+		})
+	}
+}
+
+func TestSuggestFromCPE(t *testing.T) {
+	cases := []struct {
+		name string
+		cpes []string
+		want []string
+	}{
+		{name: "esxi", cpes: []string{"cpe:/o:vmware:esxi:7.0.3"}, want: []string{TagVMHost}},
+		{name: "printer hardware", cpes: []string{"cpe:/a:hp:http_server", "cpe:/h:hp:laserjet_pro_m404"}, want: []string{TagPrinter}},
+		{name: "nas os and hardware dedupe", cpes: []string{"cpe:/o:synology:diskstation_manager:7", "cpe:/h:synology:ds920+"}, want: []string{TagNAS}},
+		{name: "mixed case", cpes: []string{"CPE:/o:Microsoft:Windows_Server_2016"}, want: []string{TagServer}},
+		{name: "unknown", cpes: []string{"cpe:/a:openbsd:openssh:9.6"}, want: nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := SuggestFromCPE(tc.cpes)
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %+v", tc.want, got)
+			}
+			for i, tag := range tc.want {
+				if got[i].Tag != tag || got[i].Evidence["signal"] != "cpe" {
+					t.Fatalf("expected %v, got %+v", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSuggestFromNeighbor(t *testing.T) {
+	cases := []struct {
+		name string
+		info NeighborInfo
+		want []string
+	}{
+		{name: "access switch", info: NeighborInfo{Source: "lldp", Capabilities: []string{"bridge"}}, want: []string{TagSwitch}},
+		{name: "layer 3 switch", info: NeighborInfo{Source: "cdp", Capabilities: []string{"router", "bridge"}, Platform: "cisco WS-C3850-24T"}, want: []string{TagSwitch, TagRouter}},
+		{name: "router", info: NeighborInfo{Source: "cdp", Capabilities: []string{"router"}, Platform: "cisco ISR4331/K9"}, want: []string{TagRouter}},
+		{name: "access point", info: NeighborInfo{Source: "lldp", Capabilities: []string{"bridge", "wlan_ap"}}, want: []string{TagAccessPoint}},
+		{name: "phone", info: NeighborInfo{Source: "lldp", Capabilities: []string{"bridge", "telephone"}, Platform: "CP-8845"}, want: []string{TagIoT}},
+		{name: "description only", info: NeighborInfo{Source: "lldp", SysDescr: "FortiGate-60F v7.2.5"}, want: []string{TagFirewall}},
+		{name: "nothing advertised", info: NeighborInfo{Source: "lldp", Capabilities: []string{"station"}}, want: nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := SuggestFromNeighbor(tc.info)
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %+v", tc.want, got)
+			}
+			for i, tag := range tc.want {
+				if got[i].Tag != tag || got[i].Evidence["signal"] != tc.info.Source {
+					t.Fatalf("expected %v, got %+v", tc.want, got)
+				}
+			}
+		})
+	}
+}
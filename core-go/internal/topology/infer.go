@@ -0,0 +1,233 @@
+package topology
+
+import (
+	"sort"
+	"strings"
+)
+
+// Confidence scores attached to inferred links.
+const (
+	// ConfidenceHost: a single device is the only thing learned on a non-uplink switch port.
+	ConfidenceHost = 90
+	// ConfidenceSwitchMutual: two switches learn each other on ports with no polled switch in between.
+	ConfidenceSwitchMutual = 85
+	// ConfidenceSwitchOneSided: only one switch learns the other; the peer port is assumed to be its uplink.
+	ConfidenceSwitchOneSided = 60
+	// ConfidenceSwitchNoPeerPort: as above, but the peer has no identifiable uplink port.
+	ConfidenceSwitchNoPeerPort = 50
+)
+
+// Link kinds.
+const (
+	KindUplink = "uplink"
+	KindHost   = "host"
+)
+
+// Port is one switch interface and the MAC addresses its bridge has learned there.
+type Port struct {
+	InterfaceID string
+	MACs        []string
+}
+
+// Switch is a polled bridge: its device ID, its own interface MACs and its forwarding database by port.
+type Switch struct {
+	DeviceID string
+	MACs     []string
+	Ports    []Port
+}
+
+// Link is an inferred adjacency. BInterfaceID is empty when the far-end port is unknown (hosts, one-sided
+// switch links without an uplink).
+type Link struct {
+	ADeviceID    string
+	AInterfaceID string
+	BDeviceID    string
+	BInterfaceID string
+	Kind         string
+	Confidence   int
+}
+
+type portView struct {
+	interfaceID string
+	macCount    int
+	devices     map[string]bool // known owners of learned MACs (other than the switch itself)
+	unknown     int             // learned MACs with no known owner
+}
+
+type switchView struct {
+	deviceID string
+	ports    []portView
+	uplink   int // index into ports, -1 when none
+}
+
+// portSeeing returns the index of the first port where device id is learned, or -1.
+func (s *switchView) portSeeing(id string) int {
+	for i := range s.ports {
+		if s.ports[i].devices[id] {
+			return i
+		}
+	}
+	return -1
+}
+
+// Infer derives switch-to-switch and host-to-port links from bridge forwarding tables. owners maps a MAC
+// address to the device that owns it (from known interface MACs and ARP); switches' own MACs are added
+// automatically.
+//
+// The rules are deliberately conservative:
+//   - a switch's uplink is the port that has learned the most MACs (at least two);
+//   - switch A's port links to polled switch B when B is learned there and no other polled switch sits
+//     between them (i.e. sees A and B on different ports); confidence is higher when B also learns A;
+//   - a device is attached to a port when it is the only device learned there, the port is neither the
+//     uplink nor facing another switch, and no other switch claims the same device that way.
+func Infer(switches []Switch, owners map[string]string) []Link {
+	macOwner := make(map[string]string, len(owners))
+	for mac, id := range owners {
+		macOwner[normalizeMAC(mac)] = id
+	}
+	isSwitch := make(map[string]bool, len(switches))
+	for _, sw := range switches {
+		isSwitch[sw.DeviceID] = true
+		for _, mac := range sw.MACs {
+			macOwner[normalizeMAC(mac)] = sw.DeviceID
+		}
+	}
+
+	views := make([]*switchView, 0, len(switches))
+	byID := make(map[string]*switchView, len(switches))
+	for _, sw := range switches {
+		if sw.DeviceID == "" || byID[sw.DeviceID] != nil {
+			continue
+		}
+		v := &switchView{deviceID: sw.DeviceID, uplink: -1}
+		best := 1
+		for _, p := range sw.Ports {
+			if p.InterfaceID == "" {
+				continue
+			}
+			pv := portView{interfaceID: p.InterfaceID, devices: map[string]bool{}}
+			seen := map[string]bool{}
+			for _, mac := range p.MACs {
+				mac = normalizeMAC(mac)
+				if mac == "" || seen[mac] {
+					continue
+				}
+				seen[mac] = true
+				pv.macCount++
+				switch owner := macOwner[mac]; owner {
+				case sw.DeviceID:
+				case "":
+					pv.unknown++
+				default:
+					pv.devices[owner] = true
+				}
+			}
+			if pv.macCount > best {
+				best = pv.macCount
+				v.uplink = len(v.ports)
+			}
+			v.ports = append(v.ports, pv)
+		}
+		views = append(views, v)
+		byID[v.deviceID] = v
+	}
+
+	var out []Link
+
+	// Switch-to-switch links.
+	linked := map[[2]string]bool{}
+	for _, a := range views {
+		for pi := range a.ports {
+			for bID := range a.ports[pi].devices {
+				b := byID[bID]
+				if b == nil || bID == a.deviceID {
+					continue
+				}
+				pair := [2]string{a.deviceID, bID}
+				if pair[0] > pair[1] {
+					pair[0], pair[1] = pair[1], pair[0]
+				}
+				if linked[pair] || switchBetween(views, a, b, pi) {
+					continue
+				}
+				link := Link{ADeviceID: a.deviceID, AInterfaceID: a.ports[pi].interfaceID, BDeviceID: bID, Kind: KindUplink}
+				if bi := b.portSeeing(a.deviceID); bi >= 0 {
+					link.BInterfaceID = b.ports[bi].interfaceID
+					link.Confidence = ConfidenceSwitchMutual
+				} else if b.uplink >= 0 {
+					link.BInterfaceID = b.ports[b.uplink].interfaceID
+					link.Confidence = ConfidenceSwitchOneSided
+				} else {
+					link.Confidence = ConfidenceSwitchNoPeerPort
+				}
+				linked[pair] = true
+				out = append(out, link)
+			}
+		}
+	}
+
+	// Host-to-port attachments.
+	type attachment struct {
+		switchID    string
+		interfaceID string
+	}
+	claims := map[string][]attachment{}
+	for _, sw := range views {
+		for pi, p := range sw.ports {
+			if pi == sw.uplink || p.unknown > 0 || len(p.devices) != 1 {
+				continue
+			}
+			for id := range p.devices {
+				if isSwitch[id] {
+					continue
+				}
+				claims[id] = append(claims[id], attachment{switchID: sw.deviceID, interfaceID: p.interfaceID})
+			}
+		}
+	}
+	for id, at := range claims {
+		if len(at) != 1 {
+			continue
+		}
+		out = append(out, Link{
+			ADeviceID:    at[0].switchID,
+			AInterfaceID: at[0].interfaceID,
+			BDeviceID:    id,
+			Kind:         KindHost,
+			Confidence:   ConfidenceHost,
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ADeviceID != out[j].ADeviceID {
+			return out[i].ADeviceID < out[j].ADeviceID
+		}
+		if out[i].AInterfaceID != out[j].AInterfaceID {
+			return out[i].AInterfaceID < out[j].AInterfaceID
+		}
+		return out[i].BDeviceID < out[j].BDeviceID
+	})
+	return out
+}
+
+// switchBetween reports whether another polled switch learned on a's port sits between a and b: it sees
+// them on different ports. A switch that has not learned a is assumed to reach it over its uplink.
+func switchBetween(views []*switchView, a, b *switchView, port int) bool {
+	for _, c := range views {
+		if c == a || c == b || !a.ports[port].devices[c.deviceID] {
+			continue
+		}
+		ca, cb := c.portSeeing(a.deviceID), c.portSeeing(b.deviceID)
+		if ca < 0 {
+			ca = c.uplink
+		}
+		if ca >= 0 && cb >= 0 && ca != cb {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeMAC(mac string) string {
+	return strings.ToLower(strings.TrimSpace(mac))
+}
@@ -0,0 +1,97 @@
+package topology
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInfer(t *testing.T) {
+	owners := map[string]string{
+		"00:00:00:00:01:01": "h1",
+		"00:00:00:00:01:02": "h2",
+		"00:00:00:00:01:03": "h3",
+	}
+	cases := []struct {
+		name     string
+		switches []Switch
+		want     []Link
+	}{
+		{
+			name: "core and access switch learn each other",
+			switches: []Switch{
+				{DeviceID: "core", MACs: []string{"00:00:00:00:00:0c"}, Ports: []Port{
+					{InterfaceID: "core-p1", MACs: []string{"00:00:00:00:00:0a", "00:00:00:00:01:01", "00:00:00:00:01:02"}},
+					{InterfaceID: "core-p2", MACs: []string{"00:00:00:00:01:03"}},
+				}},
+				{DeviceID: "acc", MACs: []string{"00:00:00:00:00:0A"}, Ports: []Port{
+					{InterfaceID: "acc-up", MACs: []string{"00:00:00:00:00:0c", "00:00:00:00:01:03"}},
+					{InterfaceID: "acc-1", MACs: []string{"00:00:00:00:01:01"}},
+					{InterfaceID: "acc-2", MACs: []string{"00:00:00:00:01:02"}},
+				}},
+			},
+			want: []Link{
+				{ADeviceID: "acc", AInterfaceID: "acc-1", BDeviceID: "h1", Kind: KindHost, Confidence: ConfidenceHost},
+				{ADeviceID: "acc", AInterfaceID: "acc-2", BDeviceID: "h2", Kind: KindHost, Confidence: ConfidenceHost},
+				{ADeviceID: "core", AInterfaceID: "core-p1", BDeviceID: "acc", BInterfaceID: "acc-up", Kind: KindUplink, Confidence: ConfidenceSwitchMutual},
+				{ADeviceID: "core", AInterfaceID: "core-p2", BDeviceID: "h3", Kind: KindHost, Confidence: ConfidenceHost},
+			},
+		},
+		{
+			name: "switch in between suppresses the transitive link",
+			switches: []Switch{
+				{DeviceID: "core", MACs: []string{"00:00:00:00:00:0c"}, Ports: []Port{
+					{InterfaceID: "core-p1", MACs: []string{"00:00:00:00:00:0d", "00:00:00:00:00:0a", "00:00:00:00:01:01"}},
+				}},
+				{DeviceID: "dist", MACs: []string{"00:00:00:00:00:0d"}, Ports: []Port{
+					{InterfaceID: "dist-up", MACs: []string{"00:00:00:00:00:0c"}},
+					{InterfaceID: "dist-down", MACs: []string{"00:00:00:00:00:0a", "00:00:00:00:01:01"}},
+				}},
+				{DeviceID: "acc", MACs: []string{"00:00:00:00:00:0a"}, Ports: []Port{
+					{InterfaceID: "acc-up", MACs: []string{"00:00:00:00:00:0c", "00:00:00:00:00:0d"}},
+					{InterfaceID: "acc-1", MACs: []string{"00:00:00:00:01:01"}},
+				}},
+			},
+			want: []Link{
+				{ADeviceID: "acc", AInterfaceID: "acc-1", BDeviceID: "h1", Kind: KindHost, Confidence: ConfidenceHost},
+				{ADeviceID: "core", AInterfaceID: "core-p1", BDeviceID: "dist", BInterfaceID: "dist-up", Kind: KindUplink, Confidence: ConfidenceSwitchMutual},
+				{ADeviceID: "dist", AInterfaceID: "dist-down", BDeviceID: "acc", BInterfaceID: "acc-up", Kind: KindUplink, Confidence: ConfidenceSwitchMutual},
+			},
+		},
+		{
+			name: "one-sided switch link uses the peer's uplink",
+			switches: []Switch{
+				{DeviceID: "core", MACs: []string{"00:00:00:00:00:0c"}, Ports: []Port{
+					{InterfaceID: "core-p1", MACs: []string{"00:00:00:00:00:0a", "00:00:00:00:01:01"}},
+				}},
+				{DeviceID: "acc", MACs: []string{"00:00:00:00:00:0a"}, Ports: []Port{
+					{InterfaceID: "acc-up", MACs: []string{"00:00:00:00:ff:01", "00:00:00:00:ff:02"}},
+					{InterfaceID: "acc-1", MACs: []string{"00:00:00:00:01:01"}},
+				}},
+			},
+			want: []Link{
+				{ADeviceID: "acc", AInterfaceID: "acc-1", BDeviceID: "h1", Kind: KindHost, Confidence: ConfidenceHost},
+				{ADeviceID: "core", AInterfaceID: "core-p1", BDeviceID: "acc", BInterfaceID: "acc-up", Kind: KindUplink, Confidence: ConfidenceSwitchOneSided},
+			},
+		},
+		{
+			name: "shared or ambiguous ports are not attached",
+			switches: []Switch{
+				{DeviceID: "sw1", Ports: []Port{
+					{InterfaceID: "sw1-p1", MACs: []string{"00:00:00:00:01:01", "00:00:00:00:ff:01"}},
+					{InterfaceID: "sw1-p2", MACs: []string{"00:00:00:00:01:02"}},
+				}},
+				{DeviceID: "sw2", Ports: []Port{
+					{InterfaceID: "sw2-p1", MACs: []string{"00:00:00:00:01:02"}},
+				}},
+			},
+			want: nil,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Infer(tc.switches, owners); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
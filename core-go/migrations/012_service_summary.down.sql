@@ -0,0 +1,4 @@
+-- +migrate Down
+
+ALTER TABLE services
+  DROP COLUMN IF EXISTS summary;
@@ -0,0 +1,6 @@
+-- +migrate Up
+
+-- Phase 17: UDP service probing stores a parsed response summary per service.
+
+ALTER TABLE services
+  ADD COLUMN IF NOT EXISTS summary text NULL;
@@ -0,0 +1,28 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS service_transitions_changed_at_idx;
+DROP INDEX IF EXISTS service_transitions_device_changed_at_idx;
+DROP TABLE IF EXISTS service_transitions;
+
+UPDATE services SET state = 'closed' WHERE state = 'filtered';
+
+DO $$
+BEGIN
+  IF EXISTS (
+    SELECT 1
+    FROM pg_constraint
+    WHERE conname = 'services_state_chk'
+  ) THEN
+    ALTER TABLE services
+      DROP CONSTRAINT services_state_chk;
+  END IF;
+
+  ALTER TABLE services
+    ADD CONSTRAINT services_state_chk
+    CHECK (state IS NULL OR state IN ('open', 'closed'));
+END $$;
+
+ALTER TABLE services
+  DROP COLUMN IF EXISTS closed_at,
+  DROP COLUMN IF EXISTS last_seen_at,
+  DROP COLUMN IF EXISTS first_seen_at;
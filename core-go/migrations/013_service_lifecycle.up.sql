@@ -0,0 +1,53 @@
+-- +migrate Up
+
+-- Phase 17: service lifecycle (first/last seen, closures) + open/close transition log.
+
+ALTER TABLE services
+  ADD COLUMN IF NOT EXISTS first_seen_at timestamptz NULL,
+  ADD COLUMN IF NOT EXISTS last_seen_at timestamptz NULL,
+  ADD COLUMN IF NOT EXISTS closed_at timestamptz NULL;
+
+UPDATE services
+SET first_seen_at = COALESCE(first_seen_at, created_at),
+    last_seen_at = COALESCE(last_seen_at, observed_at)
+WHERE first_seen_at IS NULL OR last_seen_at IS NULL;
+
+DO $$
+BEGIN
+  IF EXISTS (
+    SELECT 1
+    FROM pg_constraint
+    WHERE conname = 'services_state_chk'
+  ) THEN
+    ALTER TABLE services
+      DROP CONSTRAINT services_state_chk;
+  END IF;
+
+  ALTER TABLE services
+    ADD CONSTRAINT services_state_chk
+    CHECK (state IS NULL OR state IN ('open', 'closed', 'filtered'));
+END $$;
+
+CREATE TABLE IF NOT EXISTS service_transitions (
+  id bigserial PRIMARY KEY,
+  service_id uuid NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  protocol text NULL,
+  port integer NULL,
+  from_state text NULL,
+  to_state text NOT NULL,
+  source text NULL,
+  changed_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS service_transitions_device_changed_at_idx
+  ON service_transitions (device_id, changed_at DESC);
+
+CREATE INDEX IF NOT EXISTS service_transitions_changed_at_idx
+  ON service_transitions (changed_at DESC);
+
+-- Backfill: one transition per existing service so the change feed keeps showing known services.
+INSERT INTO service_transitions (service_id, device_id, protocol, port, from_state, to_state, source, changed_at)
+SELECT s.id, s.device_id, s.protocol, s.port, NULL, COALESCE(s.state, 'open'), s.source, COALESCE(s.first_seen_at, s.observed_at)
+FROM services s
+WHERE NOT EXISTS (SELECT 1 FROM service_transitions t WHERE t.service_id = s.id);
@@ -0,0 +1,7 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS service_certificates_not_after_idx;
+DROP INDEX IF EXISTS service_certificates_device_first_seen_idx;
+DROP INDEX IF EXISTS service_certificates_service_last_seen_idx;
+DROP INDEX IF EXISTS service_certificates_service_fingerprint_uniq;
+DROP TABLE IF EXISTS service_certificates;
@@ -0,0 +1,35 @@
+-- +migrate Up
+
+-- Phase 17: TLS certificate inventory (leaf certificate per TLS-speaking service).
+
+CREATE TABLE IF NOT EXISTS service_certificates (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  service_id uuid NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  fingerprint_sha256 text NOT NULL,
+  subject text NOT NULL,
+  common_name text NULL,
+  sans text[] NOT NULL DEFAULT '{}',
+  issuer text NOT NULL,
+  serial_number text NOT NULL,
+  not_before timestamptz NOT NULL,
+  not_after timestamptz NOT NULL,
+  key_type text NOT NULL, -- e.g. "rsa-2048", "ecdsa-p256", "ed25519"
+  self_signed boolean NOT NULL DEFAULT false,
+  first_seen_at timestamptz NOT NULL DEFAULT now(),
+  last_seen_at timestamptz NOT NULL DEFAULT now(),
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS service_certificates_service_fingerprint_uniq
+  ON service_certificates (service_id, fingerprint_sha256);
+
+CREATE INDEX IF NOT EXISTS service_certificates_service_last_seen_idx
+  ON service_certificates (service_id, last_seen_at DESC);
+
+CREATE INDEX IF NOT EXISTS service_certificates_device_first_seen_idx
+  ON service_certificates (device_id, first_seen_at DESC);
+
+CREATE INDEX IF NOT EXISTS service_certificates_not_after_idx
+  ON service_certificates (not_after);
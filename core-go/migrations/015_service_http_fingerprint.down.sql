@@ -0,0 +1,12 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS services_http_favicon_hash_idx;
+
+ALTER TABLE services
+  DROP COLUMN IF EXISTS http_observed_at,
+  DROP COLUMN IF EXISTS http_final_url,
+  DROP COLUMN IF EXISTS http_favicon_hash,
+  DROP COLUMN IF EXISTS http_powered_by,
+  DROP COLUMN IF EXISTS http_server,
+  DROP COLUMN IF EXISTS http_title,
+  DROP COLUMN IF EXISTS http_status;
@@ -0,0 +1,16 @@
+-- +migrate Up
+
+-- Phase 17: HTTP fingerprint of web services (status, title, headers, favicon hash).
+
+ALTER TABLE services
+  ADD COLUMN IF NOT EXISTS http_status integer NULL,
+  ADD COLUMN IF NOT EXISTS http_title text NULL,
+  ADD COLUMN IF NOT EXISTS http_server text NULL,
+  ADD COLUMN IF NOT EXISTS http_powered_by text NULL,
+  ADD COLUMN IF NOT EXISTS http_favicon_hash integer NULL,
+  ADD COLUMN IF NOT EXISTS http_final_url text NULL,
+  ADD COLUMN IF NOT EXISTS http_observed_at timestamptz NULL;
+
+CREATE INDEX IF NOT EXISTS services_http_favicon_hash_idx
+  ON services (http_favicon_hash)
+  WHERE http_favicon_hash IS NOT NULL;
@@ -0,0 +1,6 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS ssh_host_keys_device_first_seen_idx;
+DROP INDEX IF EXISTS ssh_host_keys_fingerprint_idx;
+DROP INDEX IF EXISTS ssh_host_keys_device_type_fingerprint_uniq;
+DROP TABLE IF EXISTS ssh_host_keys;
@@ -0,0 +1,26 @@
+-- +migrate Up
+
+-- Phase 17: SSH host keys collected via key exchange only (no authentication).
+
+CREATE TABLE IF NOT EXISTS ssh_host_keys (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  service_id uuid NULL REFERENCES services(id) ON DELETE SET NULL,
+  key_type text NOT NULL, -- key blob format, e.g. "ssh-ed25519", "ecdsa-sha2-nistp256", "ssh-rsa"
+  fingerprint_sha256 text NOT NULL, -- OpenSSH format: "SHA256:<unpadded base64>"
+  public_key text NOT NULL, -- base64 key blob (authorized_keys form)
+  banner text NULL, -- server identification string, e.g. "SSH-2.0-OpenSSH_9.6"
+  first_seen_at timestamptz NOT NULL DEFAULT now(),
+  last_seen_at timestamptz NOT NULL DEFAULT now(),
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ssh_host_keys_device_type_fingerprint_uniq
+  ON ssh_host_keys (device_id, key_type, fingerprint_sha256);
+
+CREATE INDEX IF NOT EXISTS ssh_host_keys_fingerprint_idx
+  ON ssh_host_keys (fingerprint_sha256);
+
+CREATE INDEX IF NOT EXISTS ssh_host_keys_device_first_seen_idx
+  ON ssh_host_keys (device_id, first_seen_at DESC);
@@ -0,0 +1,10 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS device_os_guesses_device_source_rank_uniq;
+DROP TABLE IF EXISTS device_os_guesses;
+
+ALTER TABLE services
+  DROP COLUMN IF EXISTS cpe,
+  DROP COLUMN IF EXISTS extra_info,
+  DROP COLUMN IF EXISTS version,
+  DROP COLUMN IF EXISTS product;
@@ -0,0 +1,29 @@
+-- +migrate Up
+
+-- Phase 17: nmap version detection (-sV) on services and OS guesses (-O) per device.
+
+ALTER TABLE services
+  ADD COLUMN IF NOT EXISTS product text NULL,
+  ADD COLUMN IF NOT EXISTS version text NULL,
+  ADD COLUMN IF NOT EXISTS extra_info text NULL,
+  ADD COLUMN IF NOT EXISTS cpe text[] NULL;
+
+CREATE TABLE IF NOT EXISTS device_os_guesses (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  source text NOT NULL, -- e.g. "nmap"
+  rank integer NOT NULL, -- 1 = best match
+  name text NOT NULL, -- e.g. "Linux 5.0 - 5.14"
+  accuracy integer NOT NULL CHECK (accuracy BETWEEN 0 AND 100),
+  vendor text NULL,
+  os_family text NULL,
+  os_generation text NULL,
+  device_type text NULL, -- nmap class type, e.g. "general purpose", "router", "printer"
+  cpe text[] NOT NULL DEFAULT '{}',
+  observed_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS device_os_guesses_device_source_rank_uniq
+  ON device_os_guesses (device_id, source, rank);
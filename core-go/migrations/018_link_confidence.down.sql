@@ -0,0 +1,7 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS links_b_interface_id_idx;
+DROP INDEX IF EXISTS links_a_interface_id_idx;
+
+ALTER TABLE links
+  DROP COLUMN IF EXISTS confidence;
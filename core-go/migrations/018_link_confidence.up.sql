@@ -0,0 +1,9 @@
+-- +migrate Up
+
+-- Phase 17: inferred topology (bridge FDB + ARP) stores links with source "inferred" and a confidence score.
+
+ALTER TABLE links
+  ADD COLUMN IF NOT EXISTS confidence integer NULL CHECK (confidence BETWEEN 0 AND 100);
+
+CREATE INDEX IF NOT EXISTS links_a_interface_id_idx ON links (a_interface_id);
+CREATE INDEX IF NOT EXISTS links_b_interface_id_idx ON links (b_interface_id);
@@ -0,0 +1,12 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS stp_ports_device_id_idx;
+DROP TABLE IF EXISTS stp_ports;
+
+DROP INDEX IF EXISTS stp_bridges_bridge_address_idx;
+DROP TABLE IF EXISTS stp_bridges;
+
+DROP INDEX IF EXISTS interfaces_aggregate_interface_id_idx;
+
+ALTER TABLE interfaces
+  DROP COLUMN IF EXISTS aggregate_interface_id;
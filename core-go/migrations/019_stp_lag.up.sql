@@ -0,0 +1,37 @@
+-- +migrate Up
+
+-- Phase 17: spanning tree state (BRIDGE-MIB) and link aggregation membership (IEEE8023-LAG-MIB).
+
+ALTER TABLE interfaces
+  ADD COLUMN IF NOT EXISTS aggregate_interface_id uuid NULL REFERENCES interfaces(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS interfaces_aggregate_interface_id_idx ON interfaces (aggregate_interface_id);
+
+CREATE TABLE IF NOT EXISTS stp_bridges (
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  instance integer NOT NULL DEFAULT 0, -- 0 = common spanning tree (BRIDGE-MIB)
+  bridge_address macaddr NULL,
+  priority integer NULL,
+  root_address macaddr NULL,
+  root_priority integer NULL,
+  root_cost integer NULL,
+  root_interface_id uuid NULL REFERENCES interfaces(id) ON DELETE SET NULL,
+  observed_at timestamptz NOT NULL,
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (device_id, instance)
+);
+
+CREATE INDEX IF NOT EXISTS stp_bridges_bridge_address_idx ON stp_bridges (bridge_address);
+
+CREATE TABLE IF NOT EXISTS stp_ports (
+  interface_id uuid NOT NULL REFERENCES interfaces(id) ON DELETE CASCADE,
+  instance integer NOT NULL DEFAULT 0,
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  state text NOT NULL, -- disabled | blocking | listening | learning | forwarding | broken
+  path_cost integer NULL,
+  observed_at timestamptz NOT NULL,
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (interface_id, instance)
+);
+
+CREATE INDEX IF NOT EXISTS stp_ports_device_id_idx ON stp_ports (device_id);
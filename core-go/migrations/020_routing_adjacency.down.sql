@@ -0,0 +1,16 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS link_state_transitions_changed_at_idx;
+DROP INDEX IF EXISTS link_state_transitions_b_device_changed_at_idx;
+DROP INDEX IF EXISTS link_state_transitions_a_device_changed_at_idx;
+DROP INDEX IF EXISTS link_state_transitions_link_id_idx;
+DROP TABLE IF EXISTS link_state_transitions;
+
+DROP INDEX IF EXISTS links_link_type_idx;
+
+ALTER TABLE links
+  DROP COLUMN IF EXISTS last_change_at,
+  DROP COLUMN IF EXISTS area,
+  DROP COLUMN IF EXISTS b_as,
+  DROP COLUMN IF EXISTS a_as,
+  DROP COLUMN IF EXISTS state;
@@ -0,0 +1,35 @@
+-- +migrate Up
+
+-- Phase 17: routing-protocol adjacencies (OSPF neighbors, BGP peers) stored as links + state transitions.
+
+ALTER TABLE links
+  ADD COLUMN IF NOT EXISTS state text NULL,
+  ADD COLUMN IF NOT EXISTS a_as bigint NULL,
+  ADD COLUMN IF NOT EXISTS b_as bigint NULL,
+  ADD COLUMN IF NOT EXISTS area text NULL,
+  ADD COLUMN IF NOT EXISTS last_change_at timestamptz NULL;
+
+CREATE INDEX IF NOT EXISTS links_link_type_idx ON links (link_type);
+
+CREATE TABLE IF NOT EXISTS link_state_transitions (
+  id bigserial PRIMARY KEY,
+  link_id uuid NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+  a_device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  b_device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  link_type text NULL,
+  from_state text NULL,
+  to_state text NOT NULL,
+  changed_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS link_state_transitions_link_id_idx
+  ON link_state_transitions (link_id);
+
+CREATE INDEX IF NOT EXISTS link_state_transitions_a_device_changed_at_idx
+  ON link_state_transitions (a_device_id, changed_at DESC);
+
+CREATE INDEX IF NOT EXISTS link_state_transitions_b_device_changed_at_idx
+  ON link_state_transitions (b_device_id, changed_at DESC);
+
+CREATE INDEX IF NOT EXISTS link_state_transitions_changed_at_idx
+  ON link_state_transitions (changed_at DESC);
@@ -0,0 +1,5 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS interface_poe_powered_device_id_idx;
+DROP INDEX IF EXISTS interface_poe_device_id_idx;
+DROP TABLE IF EXISTS interface_poe;
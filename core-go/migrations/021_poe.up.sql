@@ -0,0 +1,20 @@
+-- +migrate Up
+
+-- Phase 17: PoE power delivery per switch port (POWER-ETHERNET-MIB) and the device each port powers.
+
+CREATE TABLE IF NOT EXISTS interface_poe (
+  interface_id uuid PRIMARY KEY REFERENCES interfaces(id) ON DELETE CASCADE,
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  pse_group integer NOT NULL,
+  pse_port integer NOT NULL,
+  admin_enabled boolean NULL,
+  detection_status text NOT NULL, -- disabled | searching | delivering_power | fault | test | other_fault
+  power_class text NULL, -- class0 .. class4
+  power_mw integer NULL,
+  powered_device_id uuid NULL REFERENCES devices(id) ON DELETE SET NULL,
+  observed_at timestamptz NOT NULL,
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS interface_poe_device_id_idx ON interface_poe (device_id);
+CREATE INDEX IF NOT EXISTS interface_poe_powered_device_id_idx ON interface_poe (powered_device_id);
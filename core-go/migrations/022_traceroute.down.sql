@@ -0,0 +1,7 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS traceroute_hops_device_id_idx;
+DROP TABLE IF EXISTS traceroute_hops;
+
+DROP INDEX IF EXISTS traceroute_paths_scope_observed_at_idx;
+DROP TABLE IF EXISTS traceroute_paths;
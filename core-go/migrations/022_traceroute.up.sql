@@ -0,0 +1,28 @@
+-- +migrate Up
+
+-- Phase 17: traceroute paths from the core-go vantage point toward remote (not directly connected) scopes.
+
+CREATE TABLE IF NOT EXISTS traceroute_paths (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  run_id uuid NULL REFERENCES discovery_runs(id) ON DELETE SET NULL,
+  scope cidr NOT NULL,
+  target inet NOT NULL,
+  mode text NOT NULL, -- icmp | udp
+  reached boolean NOT NULL,
+  hop_count integer NOT NULL,
+  observed_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS traceroute_paths_scope_observed_at_idx ON traceroute_paths (scope, observed_at DESC);
+
+CREATE TABLE IF NOT EXISTS traceroute_hops (
+  path_id uuid NOT NULL REFERENCES traceroute_paths(id) ON DELETE CASCADE,
+  ttl integer NOT NULL,
+  ip inet NULL, -- NULL = hop did not answer
+  device_id uuid NULL REFERENCES devices(id) ON DELETE SET NULL,
+  rtt_ms real NULL,
+  PRIMARY KEY (path_id, ttl)
+);
+
+CREATE INDEX IF NOT EXISTS traceroute_hops_device_id_idx ON traceroute_hops (device_id);
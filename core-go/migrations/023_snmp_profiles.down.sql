@@ -0,0 +1,9 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS device_custom_fact_history_fact_idx;
+DROP TABLE IF EXISTS device_custom_fact_history;
+
+DROP TABLE IF EXISTS device_custom_facts;
+
+DROP INDEX IF EXISTS snmp_profiles_tags_idx;
+DROP TABLE IF EXISTS snmp_profiles;
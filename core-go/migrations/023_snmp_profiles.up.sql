@@ -0,0 +1,46 @@
+-- +migrate Up
+
+-- Phase 17: admin-defined SNMP polling profiles bound to device tags, and the custom facts they produce.
+
+CREATE TABLE IF NOT EXISTS snmp_profiles (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  name text NOT NULL UNIQUE,
+  description text NULL,
+  tags text[] NOT NULL DEFAULT '{}', -- taxonomy tags; a device with any of them is polled
+  items jsonb NOT NULL DEFAULT '[]'::jsonb, -- [{key, label, oid, kind: scalar|column, type}]
+  enabled boolean NOT NULL DEFAULT true,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS snmp_profiles_tags_idx ON snmp_profiles USING gin (tags);
+
+CREATE TABLE IF NOT EXISTS device_custom_facts (
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  profile_id uuid NOT NULL REFERENCES snmp_profiles(id) ON DELETE CASCADE,
+  fact_key text NOT NULL,
+  instance text NOT NULL DEFAULT '', -- table row index for column items, '' for scalars
+  label text NULL,
+  oid text NOT NULL,
+  value_type text NOT NULL,
+  value text NOT NULL,
+  numeric_value double precision NULL,
+  first_seen_at timestamptz NOT NULL,
+  observed_at timestamptz NOT NULL,
+  changed_at timestamptz NOT NULL,
+  PRIMARY KEY (device_id, profile_id, fact_key, instance)
+);
+
+CREATE TABLE IF NOT EXISTS device_custom_fact_history (
+  id bigserial PRIMARY KEY,
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  profile_id uuid NOT NULL REFERENCES snmp_profiles(id) ON DELETE CASCADE,
+  fact_key text NOT NULL,
+  instance text NOT NULL DEFAULT '',
+  value text NOT NULL,
+  numeric_value double precision NULL,
+  observed_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS device_custom_fact_history_fact_idx
+  ON device_custom_fact_history (device_id, profile_id, fact_key, instance, observed_at DESC);
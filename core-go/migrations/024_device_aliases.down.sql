@@ -0,0 +1,4 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS device_aliases_device_id_idx;
+DROP TABLE IF EXISTS device_aliases;
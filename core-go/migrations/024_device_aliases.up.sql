@@ -0,0 +1,11 @@
+-- +migrate Up
+
+-- Phase 17: device merge leaves the merged device IDs behind as aliases of the surviving device.
+
+CREATE TABLE IF NOT EXISTS device_aliases (
+  alias_id uuid PRIMARY KEY, -- former devices.id (the row itself is deleted by the merge)
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  merged_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS device_aliases_device_id_idx ON device_aliases (device_id);
@@ -0,0 +1,8 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS device_duplicate_dismissals_device_b_id_idx;
+DROP TABLE IF EXISTS device_duplicate_dismissals;
+
+DROP INDEX IF EXISTS device_duplicate_candidates_score_idx;
+DROP INDEX IF EXISTS device_duplicate_candidates_device_b_id_idx;
+DROP TABLE IF EXISTS device_duplicate_candidates;
@@ -0,0 +1,32 @@
+-- +migrate Up
+
+-- Phase 17: duplicate device suggestions (scored pairs with evidence) and the pairs an operator dismissed.
+
+CREATE TABLE IF NOT EXISTS device_duplicate_candidates (
+  device_a_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  device_b_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  score integer NOT NULL CHECK (score BETWEEN 0 AND 100),
+  evidence jsonb NOT NULL DEFAULT '[]'::jsonb, -- [{signal, weight, values[]}]
+  first_detected_at timestamptz NOT NULL DEFAULT now(),
+  detected_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (device_a_id, device_b_id),
+  CHECK (device_a_id < device_b_id)
+);
+
+CREATE INDEX IF NOT EXISTS device_duplicate_candidates_device_b_id_idx
+  ON device_duplicate_candidates (device_b_id);
+CREATE INDEX IF NOT EXISTS device_duplicate_candidates_score_idx
+  ON device_duplicate_candidates (score DESC);
+
+CREATE TABLE IF NOT EXISTS device_duplicate_dismissals (
+  device_a_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  device_b_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  actor text NULL,
+  reason text NULL,
+  dismissed_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (device_a_id, device_b_id),
+  CHECK (device_a_id < device_b_id)
+);
+
+CREATE INDEX IF NOT EXISTS device_duplicate_dismissals_device_b_id_idx
+  ON device_duplicate_dismissals (device_b_id);
@@ -0,0 +1,5 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS device_name_candidates_host_name_idx;
+DROP INDEX IF EXISTS device_client_ids_client_id_idx;
+DROP TABLE IF EXISTS device_client_ids;
@@ -0,0 +1,18 @@
+-- +migrate Up
+
+-- Phase 17: DHCP client identifiers per device, used to follow devices whose Wi-Fi MAC rotates.
+
+CREATE TABLE IF NOT EXISTS device_client_ids (
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  client_id text NOT NULL, -- DHCP option 61, colon-separated hex including the type byte
+  first_seen_at timestamptz NOT NULL DEFAULT now(),
+  last_seen_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (device_id, client_id)
+);
+
+CREATE INDEX IF NOT EXISTS device_client_ids_client_id_idx ON device_client_ids (client_id);
+
+-- Host-claimed names are matched on their first label when a rotated MAC is resolved.
+CREATE INDEX IF NOT EXISTS device_name_candidates_host_name_idx
+  ON device_name_candidates (lower(split_part(name, '.', 1)))
+  WHERE source IN ('dhcp', 'mdns', 'netbios');
@@ -0,0 +1,7 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS device_archive_events_changed_at_idx;
+DROP INDEX IF EXISTS device_archive_events_device_changed_at_idx;
+DROP TABLE IF EXISTS device_archive_events;
+DROP INDEX IF EXISTS devices_archived_at_idx;
+ALTER TABLE devices DROP COLUMN IF EXISTS archived_at;
@@ -0,0 +1,24 @@
+-- +migrate Up
+
+-- Phase 17: soft delete. Archived devices are hidden from lists and maps and only match again by MAC.
+
+ALTER TABLE devices
+  ADD COLUMN IF NOT EXISTS archived_at timestamptz NULL;
+
+CREATE INDEX IF NOT EXISTS devices_archived_at_idx ON devices (archived_at) WHERE archived_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS device_archive_events (
+  id bigserial PRIMARY KEY,
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  action text NOT NULL CHECK (action IN ('archived', 'restored', 'reappeared')),
+  actor text NULL,
+  reason text NULL,
+  run_id uuid NULL, -- discovery run that saw an archived device again
+  changed_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS device_archive_events_device_changed_at_idx
+  ON device_archive_events (device_id, changed_at DESC);
+
+CREATE INDEX IF NOT EXISTS device_archive_events_changed_at_idx
+  ON device_archive_events (changed_at DESC);
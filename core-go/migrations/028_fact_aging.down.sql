@@ -0,0 +1,13 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS fact_detachments_detached_at_idx;
+DROP INDEX IF EXISTS fact_detachments_device_detached_at_idx;
+DROP TABLE IF EXISTS fact_detachments;
+DROP INDEX IF EXISTS mac_addresses_last_seen_at_idx;
+DROP INDEX IF EXISTS ip_addresses_last_seen_at_idx;
+ALTER TABLE mac_addresses
+  DROP COLUMN IF EXISTS stale_at,
+  DROP COLUMN IF EXISTS last_seen_at;
+ALTER TABLE ip_addresses
+  DROP COLUMN IF EXISTS stale_at,
+  DROP COLUMN IF EXISTS last_seen_at;
@@ -0,0 +1,35 @@
+-- +migrate Up
+
+-- Phase 17: fact aging. IPs and MACs that discovery stops seeing go stale, then are detached from the device.
+
+ALTER TABLE ip_addresses
+  ADD COLUMN IF NOT EXISTS last_seen_at timestamptz NOT NULL DEFAULT now(),
+  ADD COLUMN IF NOT EXISTS stale_at timestamptz NULL;
+
+ALTER TABLE mac_addresses
+  ADD COLUMN IF NOT EXISTS last_seen_at timestamptz NOT NULL DEFAULT now(),
+  ADD COLUMN IF NOT EXISTS stale_at timestamptz NULL;
+
+-- Existing rows were last touched by an upsert, which is the best record of when they were seen.
+UPDATE ip_addresses SET last_seen_at = updated_at;
+UPDATE mac_addresses SET last_seen_at = updated_at;
+
+CREATE INDEX IF NOT EXISTS ip_addresses_last_seen_at_idx ON ip_addresses (last_seen_at);
+CREATE INDEX IF NOT EXISTS mac_addresses_last_seen_at_idx ON mac_addresses (last_seen_at);
+
+CREATE TABLE IF NOT EXISTS fact_detachments (
+  id bigserial PRIMARY KEY,
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  kind text NOT NULL CHECK (kind IN ('ip', 'mac')),
+  value text NOT NULL,
+  interface_id uuid NULL, -- not a foreign key: the interface may be gone by the time the event is read
+  last_seen_at timestamptz NOT NULL,
+  run_id uuid NULL,
+  detached_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS fact_detachments_device_detached_at_idx
+  ON fact_detachments (device_id, detached_at DESC);
+
+CREATE INDEX IF NOT EXISTS fact_detachments_detached_at_idx
+  ON fact_detachments (detached_at DESC);
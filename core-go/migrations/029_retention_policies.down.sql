@@ -0,0 +1,4 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS discovery_run_logs_created_at_idx;
+DROP TABLE IF EXISTS retention_policies;
@@ -0,0 +1,13 @@
+-- +migrate Up
+
+-- Phase 17: retention policy overrides set through the API; tables without a row use the env defaults.
+
+CREATE TABLE IF NOT EXISTS retention_policies (
+  table_name text PRIMARY KEY CHECK (table_name IN ('ip_observations', 'mac_observations', 'discovery_run_logs')),
+  keep_raw_days integer NOT NULL CHECK (keep_raw_days >= 0),
+  keep_daily_days integer NOT NULL DEFAULT 0 CHECK (keep_daily_days >= 0),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Pruning scans run logs by age (observations already have observed_at indexes).
+CREATE INDEX IF NOT EXISTS discovery_run_logs_created_at_idx ON discovery_run_logs (created_at);
@@ -0,0 +1,17 @@
+-- +migrate Up
+
+-- Phase 17: device availability. Each row is a change of a device's up/down state, written after discovery runs.
+
+CREATE TABLE IF NOT EXISTS device_availability (
+  id bigserial PRIMARY KEY,
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  state text NOT NULL CHECK (state IN ('up', 'down')),
+  previous_state text NULL CHECK (previous_state IN ('up', 'down')), -- NULL for the first known state
+  changed_at timestamptz NOT NULL,
+  run_id uuid NULL,
+  evidence jsonb NOT NULL DEFAULT '{}'::jsonb,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS device_availability_device_changed_at_idx
+  ON device_availability (device_id, changed_at DESC, id DESC);
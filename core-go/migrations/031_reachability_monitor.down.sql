@@ -0,0 +1,5 @@
+-- +migrate Down
+
+DROP TABLE IF EXISTS reachability_rollups;
+DROP TABLE IF EXISTS reachability_samples;
+DROP TABLE IF EXISTS device_monitors;
@@ -0,0 +1,49 @@
+-- +migrate Up
+
+-- Phase 17: reachability monitor. Flagged (or tag-selected) devices are probed on a short interval, independent of
+-- discovery runs. Raw probes are kept briefly; hourly rollups keep the long-term RTT/loss series.
+
+CREATE TABLE IF NOT EXISTS device_monitors (
+  device_id uuid PRIMARY KEY REFERENCES devices(id) ON DELETE CASCADE,
+  source text NOT NULL CHECK (source IN ('manual', 'tag')), -- "tag" rows are synced from MONITOR_TAGS
+  enabled boolean NOT NULL DEFAULT true,
+  method text NOT NULL CHECK (method IN ('icmp', 'tcp')),
+  port integer NULL CHECK (port BETWEEN 1 AND 65535), -- tcp only; NULL uses the lowest open TCP service
+  last_probe_at timestamptz NULL,
+  last_success_at timestamptz NULL,
+  last_rtt_ms double precision NULL,
+  consecutive_failures integer NOT NULL DEFAULT 0,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS reachability_samples (
+  id bigserial PRIMARY KEY,
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  probed_at timestamptz NOT NULL,
+  method text NOT NULL CHECK (method IN ('icmp', 'tcp')),
+  address text NOT NULL,
+  port integer NULL,
+  success boolean NOT NULL,
+  rtt_ms double precision NULL
+);
+
+CREATE INDEX IF NOT EXISTS reachability_samples_device_probed_at_idx
+  ON reachability_samples (device_id, probed_at DESC);
+
+CREATE INDEX IF NOT EXISTS reachability_samples_probed_at_idx
+  ON reachability_samples (probed_at);
+
+CREATE TABLE IF NOT EXISTS reachability_rollups (
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  bucket_start timestamptz NOT NULL, -- UTC hour
+  probes integer NOT NULL,
+  failures integer NOT NULL,
+  rtt_sum_ms double precision NOT NULL DEFAULT 0, -- over successful probes only
+  rtt_min_ms double precision NULL,
+  rtt_max_ms double precision NULL,
+  PRIMARY KEY (device_id, bucket_start)
+);
+
+CREATE INDEX IF NOT EXISTS reachability_rollups_bucket_start_idx
+  ON reachability_rollups (bucket_start);
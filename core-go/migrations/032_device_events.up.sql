@@ -0,0 +1,74 @@
+-- +migrate Up
+
+-- Phase 17: append-only device events. Name, metadata and SNMP identity changes are written here in the same
+-- statement as the change, with who made it and the values before and after.
+
+CREATE TABLE IF NOT EXISTS device_events (
+  id bigserial PRIMARY KEY,
+  device_id uuid NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+  kind text NOT NULL CHECK (kind IN ('display_name', 'metadata', 'snmp')),
+  actor_type text NOT NULL CHECK (actor_type IN ('user', 'run', 'integration', 'system')),
+  actor text NULL, -- user name or integration name
+  run_id uuid NULL, -- discovery run that made the change
+  reason text NULL,
+  before jsonb NULL, -- NULL when nothing was recorded before
+  after jsonb NOT NULL,
+  summary text NOT NULL,
+  occurred_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS device_events_device_occurred_at_idx
+  ON device_events (device_id, occurred_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS device_events_occurred_at_idx
+  ON device_events (occurred_at DESC, id DESC);
+
+-- Backfill: the current values become each device's first event, dated when they were last written.
+INSERT INTO device_events (device_id, kind, actor_type, reason, after, summary, occurred_at)
+SELECT id,
+       'display_name',
+       'system',
+       'backfill',
+       jsonb_build_object('display_name', display_name),
+       'named ' || display_name,
+       updated_at
+FROM devices
+WHERE display_name IS NOT NULL
+  AND btrim(display_name) <> '';
+
+INSERT INTO device_events (device_id, kind, actor_type, reason, after, summary, occurred_at)
+SELECT device_id,
+       'metadata',
+       'system',
+       'backfill',
+       jsonb_build_object('owner', owner, 'location', location, 'notes', notes),
+       'metadata updated: ' || concat_ws(
+         ', ',
+         CASE WHEN owner IS NOT NULL THEN 'owner' END,
+         CASE WHEN location IS NOT NULL THEN 'location' END,
+         CASE WHEN notes IS NOT NULL THEN 'notes' END
+       ),
+       updated_at
+FROM device_metadata
+WHERE owner IS NOT NULL
+   OR location IS NOT NULL
+   OR notes IS NOT NULL;
+
+-- A failed poll clears the identity, so only snapshots from a successful poll are history.
+INSERT INTO device_events (device_id, kind, actor_type, reason, after, summary, occurred_at)
+SELECT device_id,
+       'snmp',
+       'system',
+       'backfill',
+       jsonb_build_object(
+         'address', host(address),
+         'sys_name', sys_name,
+         'sys_descr', sys_descr,
+         'sys_object_id', sys_object_id,
+         'sys_contact', sys_contact,
+         'sys_location', sys_location
+       ),
+       'snmp identity observed',
+       last_success_at
+FROM device_snmp
+WHERE last_success_at IS NOT NULL;
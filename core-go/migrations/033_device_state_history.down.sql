@@ -0,0 +1,13 @@
+-- +migrate Down
+
+DROP FUNCTION IF EXISTS device_state_at(uuid, text, timestamptz);
+
+DELETE FROM device_events
+WHERE kind IN ('tags', 'vlans', 'links');
+
+ALTER TABLE device_events
+  DROP CONSTRAINT IF EXISTS device_events_kind_check;
+
+ALTER TABLE device_events
+  ADD CONSTRAINT device_events_kind_check
+  CHECK (kind IN ('display_name', 'metadata', 'snmp'));
@@ -0,0 +1,112 @@
+-- +migrate Up
+
+-- Phase 17: point-in-time reads. Tags, VLAN memberships and links are overwritten in place, so each device's
+-- set is also snapshotted into device_events whenever it changes; as_of reads replay those snapshots.
+
+ALTER TABLE device_events
+  DROP CONSTRAINT IF EXISTS device_events_kind_check;
+
+ALTER TABLE device_events
+  ADD CONSTRAINT device_events_kind_check
+  CHECK (kind IN ('display_name', 'metadata', 'snmp', 'tags', 'vlans', 'links'));
+
+-- device_state_at returns a device's value for one event kind at an instant: the latest change at or before it,
+-- otherwise the value the first later change replaced. A backfilled first event stands in for the time before
+-- it, since nothing older was recorded. NULL means the kind has no history and callers read the live rows.
+CREATE OR REPLACE FUNCTION device_state_at(p_device_id uuid, p_kind text, p_at timestamptz)
+RETURNS jsonb
+LANGUAGE sql
+STABLE
+AS $$
+  SELECT COALESCE(
+    (
+      SELECT e.after
+      FROM device_events e
+      WHERE e.device_id = p_device_id
+        AND e.kind = p_kind
+        AND e.occurred_at <= p_at
+      ORDER BY e.occurred_at DESC, e.id DESC
+      LIMIT 1
+    ),
+    (
+      SELECT CASE
+               WHEN e.actor_type = 'system' AND e.reason = 'backfill' THEN e.after
+               ELSE COALESCE(e.before, '{}'::jsonb)
+             END
+      FROM device_events e
+      WHERE e.device_id = p_device_id
+        AND e.kind = p_kind
+        AND e.occurred_at > p_at
+      ORDER BY e.occurred_at ASC, e.id ASC
+      LIMIT 1
+    )
+  )
+$$;
+
+-- Backfill: each device's current sets become its first snapshots.
+INSERT INTO device_events (device_id, kind, actor_type, reason, after, summary, occurred_at)
+SELECT dt.device_id,
+       'tags',
+       'system',
+       'backfill',
+       jsonb_build_object('tags', jsonb_agg(DISTINCT dt.tag ORDER BY dt.tag)),
+       'tags: ' || string_agg(DISTINCT '+' || dt.tag, ', ' ORDER BY '+' || dt.tag),
+       max(dt.created_at)
+FROM device_tags dt
+GROUP BY dt.device_id;
+
+INSERT INTO device_events (device_id, kind, actor_type, reason, after, summary, occurred_at)
+SELECT i.device_id,
+       'vlans',
+       'system',
+       'backfill',
+       jsonb_build_object(
+         'vlans',
+         jsonb_agg(
+           jsonb_build_object('interface_id', iv.interface_id, 'vlan_id', iv.vlan_id, 'role', iv.role)
+           ORDER BY iv.interface_id, iv.role, iv.vlan_id
+         )
+       ),
+       'vlan memberships: ' || count(*)::text,
+       max(iv.observed_at)
+FROM interface_vlans iv
+JOIN interfaces i ON i.id = iv.interface_id
+GROUP BY i.device_id;
+
+INSERT INTO device_events (device_id, kind, actor_type, reason, after, summary, occurred_at)
+SELECT s.device_id,
+       'links',
+       'system',
+       'backfill',
+       jsonb_build_object(
+         'links',
+         jsonb_agg(
+           jsonb_build_object(
+             'link_key', s.link_key,
+             'peer_device_id', s.peer_device_id,
+             'local_interface_id', s.local_interface_id,
+             'peer_interface_id', s.peer_interface_id,
+             'link_type', s.link_type,
+             'source', s.source,
+             'state', s.state,
+             'local_as', s.local_as,
+             'peer_as', s.peer_as,
+             'area', s.area
+           )
+           ORDER BY s.link_key
+         )
+       ),
+       'links: ' || count(*)::text,
+       max(s.created_at)
+FROM (
+  SELECT l.a_device_id AS device_id, l.link_key, l.b_device_id AS peer_device_id,
+         l.a_interface_id AS local_interface_id, l.b_interface_id AS peer_interface_id,
+         l.link_type, l.source, l.state, l.a_as AS local_as, l.b_as AS peer_as, l.area, l.created_at
+  FROM links l
+  UNION ALL
+  SELECT l.b_device_id, l.link_key, l.a_device_id,
+         l.b_interface_id, l.a_interface_id,
+         l.link_type, l.source, l.state, l.b_as, l.a_as, l.area, l.created_at
+  FROM links l
+) s
+GROUP BY s.device_id;
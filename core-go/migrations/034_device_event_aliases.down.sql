@@ -0,0 +1,12 @@
+-- +migrate Down
+
+DROP TRIGGER IF EXISTS devices_delete_events ON devices;
+
+DROP FUNCTION IF EXISTS delete_device_events();
+
+DELETE FROM device_events e
+WHERE NOT EXISTS (SELECT 1 FROM devices d WHERE d.id = e.device_id);
+
+ALTER TABLE device_events
+  ADD CONSTRAINT device_events_device_id_fkey
+  FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE;
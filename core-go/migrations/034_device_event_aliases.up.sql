@@ -0,0 +1,31 @@
+-- +migrate Up
+
+-- Phase 17: a merge leaves the source's device_events on the source ID, so the survivor's point-in-time reads
+-- only replay its own state; history views reach them through device_aliases. The events therefore outlive the
+-- source's devices row and are removed by trigger instead of by cascade: with the device when it is purged, and
+-- with every device merged into it.
+
+ALTER TABLE device_events
+  DROP CONSTRAINT IF EXISTS device_events_device_id_fkey;
+
+CREATE OR REPLACE FUNCTION delete_device_events() RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+  -- A merge source is already an alias of the survivor when its row is deleted; its events stay.
+  IF NOT EXISTS (SELECT 1 FROM device_aliases a WHERE a.alias_id = OLD.id) THEN
+    DELETE FROM device_events e
+    WHERE e.device_id = OLD.id
+       OR e.device_id IN (SELECT a.alias_id FROM device_aliases a WHERE a.device_id = OLD.id);
+  END IF;
+  RETURN OLD;
+END;
+$$;
+
+DROP TRIGGER IF EXISTS devices_delete_events ON devices;
+
+-- BEFORE, so the aliases are still there to follow.
+CREATE TRIGGER devices_delete_events
+  BEFORE DELETE ON devices
+  FOR EACH ROW
+  EXECUTE FUNCTION delete_device_events();
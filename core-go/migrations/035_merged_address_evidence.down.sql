@@ -0,0 +1,9 @@
+-- +migrate Down
+
+DROP FUNCTION IF EXISTS device_owner_at(uuid, uuid, timestamptz);
+
+ALTER TABLE fact_detachments DROP COLUMN IF EXISTS merged_from;
+ALTER TABLE mac_observations DROP COLUMN IF EXISTS merged_from;
+ALTER TABLE ip_observations DROP COLUMN IF EXISTS merged_from;
+ALTER TABLE mac_addresses DROP COLUMN IF EXISTS merged_from;
+ALTER TABLE ip_addresses DROP COLUMN IF EXISTS merged_from;
@@ -0,0 +1,30 @@
+-- +migrate Up
+
+-- A merge repoints the source's addresses, sightings and detachments onto the survivor. merged_from keeps the
+-- device a row was merged away from, so point-in-time reads can leave it with that device until the merge
+-- recorded in device_aliases.
+
+ALTER TABLE ip_addresses ADD COLUMN IF NOT EXISTS merged_from uuid NULL;
+ALTER TABLE mac_addresses ADD COLUMN IF NOT EXISTS merged_from uuid NULL;
+ALTER TABLE ip_observations ADD COLUMN IF NOT EXISTS merged_from uuid NULL;
+ALTER TABLE mac_observations ADD COLUMN IF NOT EXISTS merged_from uuid NULL;
+ALTER TABLE fact_detachments ADD COLUMN IF NOT EXISTS merged_from uuid NULL;
+
+-- device_owner_at returns the device a row belonged to at an instant: its current device once the merge from
+-- p_merged_from had happened, the merged-away device before that.
+CREATE OR REPLACE FUNCTION device_owner_at(p_device_id uuid, p_merged_from uuid, p_at timestamptz)
+RETURNS uuid
+LANGUAGE sql
+STABLE
+AS $$
+  SELECT CASE
+           WHEN p_merged_from IS NULL THEN p_device_id
+           WHEN EXISTS (
+             SELECT 1
+             FROM device_aliases a
+             WHERE a.alias_id = p_merged_from
+               AND a.merged_at <= p_at
+           ) THEN p_device_id
+           ELSE p_merged_from
+         END
+$$;
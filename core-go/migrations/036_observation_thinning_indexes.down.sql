@@ -0,0 +1,6 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS mac_observations_device_mac_observed_at_idx;
+DROP INDEX IF EXISTS mac_observations_observed_at_id_idx;
+DROP INDEX IF EXISTS ip_observations_device_ip_observed_at_idx;
+DROP INDEX IF EXISTS ip_observations_observed_at_id_idx;
@@ -0,0 +1,16 @@
+-- +migrate Up
+
+-- Retention thinning pages through observations by (observed_at, id) and checks each row against the other
+-- sightings of its device and address on the same day.
+
+CREATE INDEX IF NOT EXISTS ip_observations_observed_at_id_idx
+  ON ip_observations (observed_at, id);
+
+CREATE INDEX IF NOT EXISTS ip_observations_device_ip_observed_at_idx
+  ON ip_observations (device_id, ip, observed_at);
+
+CREATE INDEX IF NOT EXISTS mac_observations_observed_at_id_idx
+  ON mac_observations (observed_at, id);
+
+CREATE INDEX IF NOT EXISTS mac_observations_device_mac_observed_at_idx
+  ON mac_observations (device_id, mac, observed_at);
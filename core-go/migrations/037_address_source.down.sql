@@ -0,0 +1,4 @@
+-- +migrate Down
+
+ALTER TABLE mac_addresses DROP COLUMN IF EXISTS source;
+ALTER TABLE ip_addresses DROP COLUMN IF EXISTS source;
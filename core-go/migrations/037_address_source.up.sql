@@ -0,0 +1,9 @@
+-- +migrate Up
+
+-- Fact aging only ages addresses that discovery keeps re-observing. Rows last written by an import (inventory,
+-- scan or pcap upload) are not refreshed on the discovery cadence and are left alone until discovery sees them.
+
+ALTER TABLE ip_addresses ADD COLUMN IF NOT EXISTS source text NOT NULL DEFAULT 'discovery'
+  CHECK (source IN ('discovery', 'import'));
+ALTER TABLE mac_addresses ADD COLUMN IF NOT EXISTS source text NOT NULL DEFAULT 'discovery'
+  CHECK (source IN ('discovery', 'import'));
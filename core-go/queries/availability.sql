@@ -0,0 +1,100 @@
+-- name: RecordDeviceAvailability :many
+-- A device is up when any of its facts was observed at or after $1 (the evaluation time $2 minus the down window).
+-- Only changes are written. A device that went down is recorded as down from the moment its last observation
+-- left the window; devices that were never observed and archived devices are skipped. So are devices the
+-- reachability monitor probed within the window: their state comes from the monitor. With a scope ($5), only
+-- devices holding an IP inside it are evaluated, since a run says nothing about devices it did not scan.
+WITH seen AS (
+  SELECT d.id AS device_id,
+         GREATEST(
+           (
+             SELECT MAX(ia.last_seen_at)
+             FROM ip_addresses ia
+             LEFT JOIN interfaces i ON i.id = ia.interface_id
+             WHERE ia.device_id = d.id OR i.device_id = d.id
+           ),
+           (
+             SELECT MAX(ma.last_seen_at)
+             FROM mac_addresses ma
+             LEFT JOIN interfaces i ON i.id = ma.interface_id
+             WHERE ma.device_id = d.id OR i.device_id = d.id
+           ),
+           (SELECT MAX(s.observed_at) FROM services s WHERE s.device_id = d.id),
+           (SELECT MAX(ds.last_success_at) FROM device_snmp ds WHERE ds.device_id = d.id)
+         ) AS last_seen_at
+  FROM devices d
+  WHERE d.archived_at IS NULL
+    AND NOT EXISTS (
+      SELECT 1
+      FROM device_monitors m
+      WHERE m.device_id = d.id
+        AND m.enabled
+        AND m.last_probe_at >= $1
+    )
+    AND (
+      $5::cidr IS NULL
+      OR EXISTS (
+        SELECT 1
+        FROM ip_addresses ia
+        LEFT JOIN interfaces i ON i.id = ia.interface_id
+        WHERE (ia.device_id = d.id OR i.device_id = d.id)
+          AND ia.ip <<= $5::cidr
+      )
+    )
+), latest AS (
+  SELECT DISTINCT ON (device_id) device_id, state, changed_at
+  FROM device_availability
+  ORDER BY device_id, changed_at DESC, id DESC
+), next AS (
+  SELECT s.device_id,
+         s.last_seen_at,
+         l.state AS previous_state,
+         l.changed_at AS previous_changed_at,
+         CASE WHEN s.last_seen_at >= $1 THEN 'up' ELSE 'down' END AS state
+  FROM seen s
+  LEFT JOIN latest l ON l.device_id = s.device_id
+  WHERE s.last_seen_at IS NOT NULL OR l.state IS NOT NULL
+)
+INSERT INTO device_availability (device_id, state, previous_state, changed_at, run_id, evidence)
+SELECT n.device_id,
+       n.state,
+       n.previous_state,
+       GREATEST(
+         CASE
+           WHEN n.state = 'up' THEN n.last_seen_at
+           ELSE COALESCE(n.last_seen_at + ($2::timestamptz - $1::timestamptz), $2::timestamptz)
+         END,
+         n.previous_changed_at
+       ),
+       $3::uuid,
+       jsonb_build_object(
+         'source', $4::text,
+         'last_seen_at', n.last_seen_at,
+         'down_after_seconds', EXTRACT(EPOCH FROM ($2::timestamptz - $1::timestamptz))::bigint
+       )
+FROM next n
+WHERE n.previous_state IS DISTINCT FROM n.state
+RETURNING device_id, state, previous_state, changed_at;
+
+-- name: ListDeviceAvailability :many
+-- Transitions in ($2, $3] plus the last one at or before $2, which gives the state at the start of the window.
+SELECT id, device_id, state, previous_state, changed_at, run_id, evidence
+FROM (
+  (
+    SELECT id, device_id, state, previous_state, changed_at, run_id, evidence
+    FROM device_availability
+    WHERE device_id = $1
+      AND changed_at <= $2
+    ORDER BY changed_at DESC, id DESC
+    LIMIT 1
+  )
+  UNION ALL
+  (
+    SELECT id, device_id, state, previous_state, changed_at, run_id, evidence
+    FROM device_availability
+    WHERE device_id = $1
+      AND changed_at > $2
+      AND changed_at <= $3
+  )
+) t
+ORDER BY changed_at ASC, id ASC;
@@ -0,0 +1,51 @@
+-- name: UpsertSTPBridge :exec
+INSERT INTO stp_bridges (
+  device_id,
+  instance,
+  bridge_address,
+  priority,
+  root_address,
+  root_priority,
+  root_cost,
+  root_interface_id,
+  observed_at
+)
+VALUES ($1::uuid, $2, $3::macaddr, $4, $5::macaddr, $6, $7, $8::uuid, $9)
+ON CONFLICT (device_id, instance) DO UPDATE
+SET bridge_address = EXCLUDED.bridge_address,
+    priority = EXCLUDED.priority,
+    root_address = EXCLUDED.root_address,
+    root_priority = EXCLUDED.root_priority,
+    root_cost = EXCLUDED.root_cost,
+    root_interface_id = EXCLUDED.root_interface_id,
+    observed_at = EXCLUDED.observed_at,
+    updated_at = now();
+
+-- name: UpsertSTPPort :exec
+INSERT INTO stp_ports (interface_id, instance, device_id, state, path_cost, observed_at)
+VALUES ($1::uuid, $2, $3::uuid, $4, $5, $6)
+ON CONFLICT (interface_id, instance) DO UPDATE
+SET state = EXCLUDED.state,
+    path_cost = EXCLUDED.path_cost,
+    observed_at = EXCLUDED.observed_at,
+    updated_at = now();
+
+-- name: DeleteStaleSTPPorts :execrows
+DELETE FROM stp_ports
+WHERE device_id = $1::uuid
+  AND observed_at < $2;
+
+-- name: SyncInterfaceAggregates :execrows
+-- Sets aggregate_interface_id for the given member interfaces of a device and clears it on every other
+-- interface of that device.
+UPDATE interfaces i
+SET aggregate_interface_id = m.aggregate_id,
+    updated_at = now()
+FROM (
+  SELECT d.id, a.aggregate_id
+  FROM interfaces d
+  LEFT JOIN unnest($2::uuid[], $3::uuid[]) AS a(member_id, aggregate_id) ON a.member_id = d.id
+  WHERE d.device_id = $1::uuid
+) m
+WHERE i.id = m.id
+  AND i.aggregate_interface_id IS DISTINCT FROM m.aggregate_id;
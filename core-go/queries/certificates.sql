@@ -0,0 +1,83 @@
+-- name: ListOpenTCPServicesForDevice :many
+SELECT id,
+       port,
+       name,
+       summary
+FROM services
+WHERE device_id = $1::uuid
+  AND protocol = 'tcp'
+  AND state = 'open'
+  AND port IS NOT NULL
+ORDER BY port ASC
+LIMIT $2;
+
+-- name: UpsertServiceCertificate :exec
+INSERT INTO service_certificates (
+  service_id,
+  device_id,
+  fingerprint_sha256,
+  subject,
+  common_name,
+  sans,
+  issuer,
+  serial_number,
+  not_before,
+  not_after,
+  key_type,
+  self_signed,
+  first_seen_at,
+  last_seen_at
+)
+VALUES (
+  $1::uuid,
+  $2::uuid,
+  $3,
+  $4,
+  $5,
+  $6::text[],
+  $7,
+  $8,
+  $9::timestamptz,
+  $10::timestamptz,
+  $11,
+  $12,
+  $13::timestamptz,
+  $13::timestamptz
+)
+ON CONFLICT (service_id, fingerprint_sha256)
+DO UPDATE
+SET last_seen_at = EXCLUDED.last_seen_at,
+    updated_at = now();
+
+-- name: ListCertificates :many
+WITH latest AS (
+  SELECT DISTINCT ON (service_id) *
+  FROM service_certificates
+  ORDER BY service_id, last_seen_at DESC, id
+)
+SELECT c.id,
+       c.device_id,
+       d.display_name,
+       c.service_id,
+       s.protocol,
+       s.port,
+       s.state,
+       c.fingerprint_sha256,
+       c.subject,
+       c.common_name,
+       c.sans,
+       c.issuer,
+       c.serial_number,
+       c.not_before,
+       c.not_after,
+       c.key_type,
+       c.self_signed,
+       c.first_seen_at,
+       c.last_seen_at
+FROM latest c
+JOIN services s ON s.id = c.service_id
+JOIN devices d ON d.id = c.device_id
+WHERE ($1::timestamptz IS NULL OR c.not_after <= $1::timestamptz)
+  AND ($2::timestamptz IS NULL OR (c.not_after, c.id) > ($2::timestamptz, $3::uuid))
+ORDER BY c.not_after ASC, c.id ASC
+LIMIT $4;
@@ -0,0 +1,48 @@
+-- name: ArchiveDevice :execrows
+WITH archived AS (
+  UPDATE devices
+  SET archived_at = now()
+  WHERE id = $1
+    AND archived_at IS NULL
+  RETURNING id, archived_at
+)
+INSERT INTO device_archive_events (device_id, action, actor, reason, changed_at)
+SELECT id, 'archived', $2, $3, archived_at
+FROM archived;
+
+-- name: RestoreDevice :execrows
+WITH restored AS (
+  UPDATE devices
+  SET archived_at = NULL
+  WHERE id = $1
+    AND archived_at IS NOT NULL
+  RETURNING id
+)
+INSERT INTO device_archive_events (device_id, action, actor, reason)
+SELECT id, 'restored', $2, $3
+FROM restored;
+
+-- name: UnarchiveReseenDevice :execrows
+-- Discovery saw an archived device again (by MAC or DHCP client ID); it becomes active again.
+WITH reappeared AS (
+  UPDATE devices
+  SET archived_at = NULL
+  WHERE id = $1
+    AND archived_at IS NOT NULL
+  RETURNING id
+)
+INSERT INTO device_archive_events (device_id, action, run_id)
+SELECT id, 'reappeared', $2::uuid
+FROM reappeared;
+
+-- name: LockDeviceForPurge :one
+SELECT id,
+       display_name,
+       archived_at
+FROM devices
+WHERE id = $1
+FOR UPDATE;
+
+-- name: PurgeDevice :exec
+DELETE FROM devices
+WHERE id = $1;
@@ -0,0 +1,1001 @@
+-- name: RecordDeviceStateSnapshots :execrows
+-- Snapshots each device's tag set, VLAN memberships and links into device_events when they differ from the
+-- last snapshot of that kind. $1 limits the devices (NULL = all); the actor is $2..$5. Empty sets are not
+-- recorded until the device has a snapshot to compare with. Link ids and inferred confidence change without the
+-- topology changing, so links are keyed by link_key and confidence is left out. Summaries list the tags, VLANs and
+-- link peers added (+) and removed (-); a change that adds or removes none (a role, interface or link state) gets a
+-- generic one.
+WITH scoped AS (
+  SELECT d.id
+  FROM devices d
+  WHERE $1::uuid[] IS NULL OR d.id = ANY($1::uuid[])
+), live AS (
+  SELECT s.id AS device_id,
+         'tags' AS kind,
+         jsonb_build_object(
+           'tags',
+           COALESCE(
+             (SELECT jsonb_agg(DISTINCT dt.tag ORDER BY dt.tag) FROM device_tags dt WHERE dt.device_id = s.id),
+             '[]'::jsonb
+           )
+         ) AS state
+  FROM scoped s
+  UNION ALL
+  SELECT s.id,
+         'vlans',
+         jsonb_build_object(
+           'vlans',
+           COALESCE(
+             (
+               SELECT jsonb_agg(
+                        jsonb_build_object('interface_id', iv.interface_id, 'vlan_id', iv.vlan_id, 'role', iv.role)
+                        ORDER BY iv.interface_id, iv.role, iv.vlan_id
+                      )
+               FROM interface_vlans iv
+               JOIN interfaces i ON i.id = iv.interface_id
+               WHERE i.device_id = s.id
+             ),
+             '[]'::jsonb
+           )
+         )
+  FROM scoped s
+  UNION ALL
+  SELECT s.id,
+         'links',
+         jsonb_build_object(
+           'links',
+           COALESCE(
+             (
+               SELECT jsonb_agg(
+                        jsonb_build_object(
+                          'link_key', l.link_key,
+                          'peer_device_id', CASE WHEN l.a_device_id = s.id THEN l.b_device_id ELSE l.a_device_id END,
+                          'local_interface_id', CASE WHEN l.a_device_id = s.id THEN l.a_interface_id ELSE l.b_interface_id END,
+                          'peer_interface_id', CASE WHEN l.a_device_id = s.id THEN l.b_interface_id ELSE l.a_interface_id END,
+                          'link_type', l.link_type,
+                          'source', l.source,
+                          'state', l.state,
+                          'local_as', CASE WHEN l.a_device_id = s.id THEN l.a_as ELSE l.b_as END,
+                          'peer_as', CASE WHEN l.a_device_id = s.id THEN l.b_as ELSE l.a_as END,
+                          'area', l.area
+                        )
+                        ORDER BY l.link_key
+                      )
+               FROM links l
+               WHERE l.a_device_id = s.id OR l.b_device_id = s.id
+             ),
+             '[]'::jsonb
+           )
+         )
+  FROM scoped s
+), previous AS (
+  SELECT DISTINCT ON (e.device_id, e.kind) e.device_id, e.kind, e.after
+  FROM device_events e
+  JOIN scoped s ON s.id = e.device_id
+  WHERE e.kind IN ('tags', 'vlans', 'links')
+  ORDER BY e.device_id, e.kind, e.occurred_at DESC, e.id DESC
+)
+INSERT INTO device_events (device_id, kind, actor_type, actor, run_id, reason, before, after, summary)
+SELECT l.device_id,
+       l.kind,
+       COALESCE(NULLIF($2::text, ''), 'system'),
+       $3::text,
+       $4::uuid,
+       $5::text,
+       p.after,
+       l.state,
+       CASE l.kind
+         WHEN 'tags' THEN 'tags: ' || concat_ws(
+           ', ',
+           (
+             SELECT string_agg('+' || t.tag, ', ' ORDER BY t.tag)
+             FROM jsonb_array_elements_text(l.state->'tags') AS t(tag)
+             WHERE NOT COALESCE(p.after->'tags', '[]'::jsonb) ? t.tag
+           ),
+           (
+             SELECT string_agg('-' || t.tag, ', ' ORDER BY t.tag)
+             FROM jsonb_array_elements_text(COALESCE(p.after->'tags', '[]'::jsonb)) AS t(tag)
+             WHERE NOT l.state->'tags' ? t.tag
+           )
+         )
+         WHEN 'vlans' THEN 'vlans: ' || COALESCE(
+           NULLIF(
+             concat_ws(
+               ', ',
+               (
+                 SELECT string_agg('+' || v.vlan_id::text, ', ' ORDER BY v.vlan_id)
+                 FROM (
+                   SELECT DISTINCT (x->>'vlan_id')::int AS vlan_id
+                   FROM jsonb_array_elements(l.state->'vlans') AS x
+                 ) v
+                 WHERE NOT EXISTS (
+                   SELECT 1
+                   FROM jsonb_array_elements(COALESCE(p.after->'vlans', '[]'::jsonb)) AS y
+                   WHERE (y->>'vlan_id')::int = v.vlan_id
+                 )
+               ),
+               (
+                 SELECT string_agg('-' || v.vlan_id::text, ', ' ORDER BY v.vlan_id)
+                 FROM (
+                   SELECT DISTINCT (x->>'vlan_id')::int AS vlan_id
+                   FROM jsonb_array_elements(COALESCE(p.after->'vlans', '[]'::jsonb)) AS x
+                 ) v
+                 WHERE NOT EXISTS (
+                   SELECT 1
+                   FROM jsonb_array_elements(l.state->'vlans') AS y
+                   WHERE (y->>'vlan_id')::int = v.vlan_id
+                 )
+               )
+             ),
+             ''
+           ),
+           'memberships changed'
+         )
+         ELSE 'links: ' || COALESCE(
+           NULLIF(
+             concat_ws(
+               ', ',
+               (
+                 SELECT string_agg('+link to ' || (x->>'peer_device_id'), ', ' ORDER BY x->>'link_key')
+                 FROM jsonb_array_elements(l.state->'links') AS x
+                 WHERE NOT EXISTS (
+                   SELECT 1
+                   FROM jsonb_array_elements(COALESCE(p.after->'links', '[]'::jsonb)) AS y
+                   WHERE y->>'link_key' = x->>'link_key'
+                 )
+               ),
+               (
+                 SELECT string_agg('-link to ' || (x->>'peer_device_id'), ', ' ORDER BY x->>'link_key')
+                 FROM jsonb_array_elements(COALESCE(p.after->'links', '[]'::jsonb)) AS x
+                 WHERE NOT EXISTS (
+                   SELECT 1
+                   FROM jsonb_array_elements(l.state->'links') AS y
+                   WHERE y->>'link_key' = x->>'link_key'
+                 )
+               )
+             ),
+             ''
+           ),
+           'changed'
+         )
+       END
+FROM live l
+LEFT JOIN previous p ON p.device_id = l.device_id AND p.kind = l.kind
+WHERE p.after IS DISTINCT FROM l.state
+  AND (p.after IS NOT NULL OR jsonb_array_length(l.state->l.kind) > 0);
+
+-- name: GetDeviceAsOf :one
+-- The device as it was at $2: name and metadata from device_events, archive state from device_archive_events.
+-- Devices created after $2 are not found.
+SELECT d.id,
+       CASE WHEN n.state IS NULL THEN d.display_name ELSE n.state->>'display_name' END AS display_name,
+       CASE WHEN md.state IS NULL THEN m.owner ELSE md.state->>'owner' END AS owner,
+       CASE WHEN md.state IS NULL THEN m.location ELSE md.state->>'location' END AS location,
+       CASE WHEN md.state IS NULL THEN m.notes ELSE md.state->>'notes' END AS notes,
+       (
+         SELECT CASE WHEN ae.action = 'archived' THEN ae.changed_at END
+         FROM device_archive_events ae
+         WHERE ae.device_id = d.id
+           AND ae.changed_at <= $2::timestamptz
+         ORDER BY ae.changed_at DESC, ae.id DESC
+         LIMIT 1
+       ) AS archived_at
+FROM devices d
+LEFT JOIN device_metadata m ON m.device_id = d.id
+CROSS JOIN LATERAL device_state_at(d.id, 'display_name', $2::timestamptz) AS n(state)
+CROSS JOIN LATERAL device_state_at(d.id, 'metadata', $2::timestamptz) AS md(state)
+WHERE d.id = $1::uuid
+  AND d.created_at <= $2::timestamptz;
+
+-- name: ListDevicesPageAsOf :many
+-- ListDevicesPage as of $10. IPs and MACs are rebuilt from observations and the live rows' first and last seen
+-- times; an address stops being held once it is detached, seen on another device or marked stale (see
+-- ListDeviceIPsAsOf). Last seen is the newest of the sightings and last change the newest recorded event, new fact
+-- or detachment.
+WITH ip_held AS (
+  SELECT e.device_id, e.ip, min(e.seen_at) AS first_seen_at, max(e.seen_at) AS last_seen_at
+  FROM (
+    SELECT device_owner_at(o.device_id, o.merged_from, $10::timestamptz) AS device_id,
+           o.ip,
+           o.observed_at AS seen_at
+    FROM ip_observations o
+    WHERE o.observed_at <= $10::timestamptz
+    UNION ALL
+    SELECT device_owner_at(COALESCE(ia.device_id, i.device_id), ia.merged_from, $10::timestamptz),
+           ia.ip,
+           ia.created_at
+    FROM ip_addresses ia
+    LEFT JOIN interfaces i ON i.id = ia.interface_id
+    WHERE ia.created_at <= $10::timestamptz
+    UNION ALL
+    SELECT device_owner_at(COALESCE(ia.device_id, i.device_id), ia.merged_from, $10::timestamptz),
+           ia.ip,
+           ia.last_seen_at
+    FROM ip_addresses ia
+    LEFT JOIN interfaces i ON i.id = ia.interface_id
+    WHERE ia.last_seen_at <= $10::timestamptz
+  ) e
+  WHERE e.device_id IS NOT NULL
+  GROUP BY e.device_id, e.ip
+), ips_at AS (
+  SELECT h.*
+  FROM ip_held h
+  WHERE NOT EXISTS (
+    SELECT 1
+    FROM fact_detachments fd
+    WHERE device_owner_at(fd.device_id, fd.merged_from, $10::timestamptz) = h.device_id
+      AND fd.kind = 'ip'
+      AND fd.value = host(h.ip)
+      AND fd.detached_at > h.last_seen_at
+      AND fd.detached_at <= $10::timestamptz
+  )
+    AND NOT EXISTS (
+      SELECT 1
+      FROM ip_held o
+      WHERE o.ip = h.ip
+        AND o.device_id <> h.device_id
+        AND o.last_seen_at > h.last_seen_at
+    )
+    AND NOT EXISTS (
+      SELECT 1
+      FROM ip_addresses ia
+      LEFT JOIN interfaces i ON i.id = ia.interface_id
+      WHERE ia.ip = h.ip
+        AND device_owner_at(COALESCE(ia.device_id, i.device_id), ia.merged_from, $10::timestamptz) = h.device_id
+        AND ia.stale_at <= $10::timestamptz
+    )
+), mac_held AS (
+  SELECT e.device_id, e.mac, min(e.seen_at) AS first_seen_at, max(e.seen_at) AS last_seen_at
+  FROM (
+    SELECT device_owner_at(o.device_id, o.merged_from, $10::timestamptz) AS device_id,
+           o.mac,
+           o.observed_at AS seen_at
+    FROM mac_observations o
+    WHERE o.observed_at <= $10::timestamptz
+    UNION ALL
+    SELECT device_owner_at(COALESCE(ma.device_id, i.device_id), ma.merged_from, $10::timestamptz),
+           ma.mac,
+           ma.created_at
+    FROM mac_addresses ma
+    LEFT JOIN interfaces i ON i.id = ma.interface_id
+    WHERE ma.created_at <= $10::timestamptz
+    UNION ALL
+    SELECT device_owner_at(COALESCE(ma.device_id, i.device_id), ma.merged_from, $10::timestamptz),
+           ma.mac,
+           ma.last_seen_at
+    FROM mac_addresses ma
+    LEFT JOIN interfaces i ON i.id = ma.interface_id
+    WHERE ma.last_seen_at <= $10::timestamptz
+  ) e
+  WHERE e.device_id IS NOT NULL
+  GROUP BY e.device_id, e.mac
+), macs_at AS (
+  SELECT h.*
+  FROM mac_held h
+  WHERE NOT EXISTS (
+    SELECT 1
+    FROM fact_detachments fd
+    WHERE device_owner_at(fd.device_id, fd.merged_from, $10::timestamptz) = h.device_id
+      AND fd.kind = 'mac'
+      AND fd.value = h.mac::text
+      AND fd.detached_at > h.last_seen_at
+      AND fd.detached_at <= $10::timestamptz
+  )
+    AND NOT EXISTS (
+      SELECT 1
+      FROM mac_held o
+      WHERE o.mac = h.mac
+        AND o.device_id <> h.device_id
+        AND o.last_seen_at > h.last_seen_at
+    )
+    AND NOT EXISTS (
+      SELECT 1
+      FROM mac_addresses ma
+      LEFT JOIN interfaces i ON i.id = ma.interface_id
+      WHERE ma.mac = h.mac
+        AND device_owner_at(COALESCE(ma.device_id, i.device_id), ma.merged_from, $10::timestamptz) = h.device_id
+        AND ma.stale_at <= $10::timestamptz
+    )
+), computed AS (
+  SELECT
+    d.id,
+    CASE WHEN n.state IS NULL THEN d.display_name ELSE n.state->>'display_name' END AS display_name,
+    (
+      SELECT ip.ip::text
+      FROM ips_at ip
+      WHERE ip.device_id = d.id
+      ORDER BY ip.last_seen_at DESC, ip.ip::text ASC
+      LIMIT 1
+    ) AS primary_ip,
+    CASE WHEN md.state IS NULL THEN m.owner ELSE md.state->>'owner' END AS owner,
+    CASE WHEN md.state IS NULL THEN m.location ELSE md.state->>'location' END AS location,
+    CASE WHEN md.state IS NULL THEN m.notes ELSE md.state->>'notes' END AS notes,
+    d.created_at,
+    GREATEST(
+      d.created_at,
+      (
+        SELECT MAX(e.occurred_at)
+        FROM device_events e
+        WHERE e.device_id = d.id
+          AND e.kind = 'display_name'
+          AND e.occurred_at <= $10::timestamptz
+      )
+    ) AS updated_at,
+    (
+      SELECT CASE WHEN ae.action = 'archived' THEN ae.changed_at END
+      FROM device_archive_events ae
+      WHERE ae.device_id = d.id
+        AND ae.changed_at <= $10::timestamptz
+      ORDER BY ae.changed_at DESC, ae.id DESC
+      LIMIT 1
+    ) AS archived_at,
+    GREATEST(
+      (SELECT MAX(ip.last_seen_at) FROM ip_held ip WHERE ip.device_id = d.id),
+      (SELECT MAX(ma.last_seen_at) FROM mac_held ma WHERE ma.device_id = d.id)
+    ) AS last_seen_at,
+    GREATEST(
+      d.created_at,
+      (
+        SELECT MAX(e.occurred_at)
+        FROM device_events e
+        WHERE e.device_id = d.id
+          AND e.occurred_at <= $10::timestamptz
+      ),
+      (SELECT MAX(ip.first_seen_at) FROM ips_at ip WHERE ip.device_id = d.id),
+      (SELECT MAX(ma.first_seen_at) FROM macs_at ma WHERE ma.device_id = d.id),
+      (
+        SELECT MAX(t.changed_at)
+        FROM service_transitions t
+        WHERE t.device_id = d.id
+          AND t.changed_at <= $10::timestamptz
+      ),
+      (
+        SELECT MAX(fd.detached_at)
+        FROM fact_detachments fd
+        WHERE device_owner_at(fd.device_id, fd.merged_from, $10::timestamptz) = d.id
+          AND fd.detached_at <= $10::timestamptz
+      )
+    ) AS last_change_at
+  FROM devices d
+  LEFT JOIN device_metadata m ON m.device_id = d.id
+  CROSS JOIN LATERAL device_state_at(d.id, 'display_name', $10::timestamptz) AS n(state)
+  CROSS JOIN LATERAL device_state_at(d.id, 'metadata', $10::timestamptz) AS md(state)
+  WHERE d.created_at <= $10::timestamptz
+)
+SELECT
+  q.id,
+  q.display_name,
+  q.primary_ip,
+  q.owner,
+  q.location,
+  q.notes,
+  q.created_at,
+  q.updated_at,
+  q.last_seen_at,
+  q.last_change_at,
+  q.archived_at,
+  q.sort_ts
+FROM (
+  SELECT
+    c.*,
+    CASE
+      WHEN $3 = 'last_seen_desc' THEN COALESCE(c.last_seen_at, '1970-01-01T00:00:00Z'::timestamptz)
+      WHEN $3 = 'last_change_desc' THEN COALESCE(c.last_change_at, '1970-01-01T00:00:00Z'::timestamptz)
+      ELSE c.created_at
+    END AS sort_ts
+  FROM computed c
+  WHERE
+    (
+      $1::text IS NULL
+      OR (
+        c.id::text ILIKE $1::text
+        OR COALESCE(c.display_name, '') ILIKE $1::text
+        OR COALESCE(c.owner, '') ILIKE $1::text
+        OR COALESCE(c.location, '') ILIKE $1::text
+        OR COALESCE(c.notes, '') ILIKE $1::text
+        OR COALESCE(c.primary_ip, '') ILIKE $1::text
+        OR EXISTS (SELECT 1 FROM ips_at ip WHERE ip.device_id = c.id AND ip.ip::text ILIKE $1::text)
+        OR EXISTS (SELECT 1 FROM macs_at ma WHERE ma.device_id = c.id AND ma.mac::text ILIKE $1::text)
+        OR EXISTS (
+          SELECT 1
+          FROM device_state_at(c.id, 'snmp', $10::timestamptz) AS ds(state)
+          WHERE COALESCE(ds.state->>'sys_name', '') ILIKE $1::text
+             OR COALESCE(ds.state->>'sys_descr', '') ILIKE $1::text
+             OR COALESCE(ds.state->>'sys_location', '') ILIKE $1::text
+             OR COALESCE(ds.state->>'sys_contact', '') ILIKE $1::text
+        )
+      )
+    )
+    AND (
+      $2::text IS NULL
+      OR $2::text = ''
+      OR ($2::text = 'online' AND c.last_seen_at IS NOT NULL AND c.last_seen_at >= $4)
+      OR ($2::text = 'offline' AND (c.last_seen_at IS NULL OR c.last_seen_at < $4))
+      OR ($2::text = 'changed' AND c.last_change_at >= $5)
+    )
+    AND (
+      ($9::text = 'include')
+      OR ($9::text = 'only' AND c.archived_at IS NOT NULL)
+      OR ($9::text <> 'only' AND c.archived_at IS NULL)
+    )
+) q
+WHERE
+  ($6::timestamptz IS NULL OR (q.sort_ts < $6::timestamptz OR (q.sort_ts = $6::timestamptz AND q.id < $7::uuid)))
+ORDER BY q.sort_ts DESC, q.id DESC
+LIMIT $8;
+
+-- name: ListDeviceEffectiveTagsAsOf :many
+-- Tags from the snapshot in effect at $2; devices without tag history fall back to their live tags.
+WITH snapshot AS (
+  SELECT device_state_at($1::uuid, 'tags', $2::timestamptz) AS state
+)
+SELECT DISTINCT x.tag
+FROM (
+  SELECT t.tag
+  FROM snapshot s
+  CROSS JOIN LATERAL jsonb_array_elements_text(s.state->'tags') AS t(tag)
+  UNION ALL
+  SELECT dt.tag
+  FROM device_tags dt
+  CROSS JOIN snapshot s
+  WHERE s.state IS NULL
+    AND dt.device_id = $1::uuid
+    AND dt.created_at <= $2::timestamptz
+) x
+ORDER BY x.tag ASC;
+
+-- name: ListDeviceIPsAsOf :many
+-- IPs the device held at $2, rebuilt from observations and the live rows' first and last seen times. An IP is held
+-- from its first sighting until, before $2, it was detached, seen on another device after its last sighting here, or
+-- marked stale.
+-- Rows a merge brought over count for the device they were merged from until the merge (device_owner_at).
+WITH evidence AS (
+  SELECT o.ip, o.observed_at AS seen_at
+  FROM ip_observations o
+  WHERE o.device_id = $1::uuid
+    AND device_owner_at(o.device_id, o.merged_from, $2::timestamptz) = $1::uuid
+    AND o.observed_at <= $2::timestamptz
+  UNION ALL
+  SELECT ia.ip, ia.created_at
+  FROM ip_addresses ia
+  LEFT JOIN interfaces i ON i.id = ia.interface_id
+  WHERE (ia.device_id = $1::uuid OR i.device_id = $1::uuid)
+    AND device_owner_at(COALESCE(ia.device_id, i.device_id), ia.merged_from, $2::timestamptz) = $1::uuid
+    AND ia.created_at <= $2::timestamptz
+  UNION ALL
+  SELECT ia.ip, ia.last_seen_at
+  FROM ip_addresses ia
+  LEFT JOIN interfaces i ON i.id = ia.interface_id
+  WHERE (ia.device_id = $1::uuid OR i.device_id = $1::uuid)
+    AND device_owner_at(COALESCE(ia.device_id, i.device_id), ia.merged_from, $2::timestamptz) = $1::uuid
+    AND ia.last_seen_at <= $2::timestamptz
+), held AS (
+  SELECT e.ip, min(e.seen_at) AS first_seen_at, max(e.seen_at) AS last_seen_at
+  FROM evidence e
+  GROUP BY e.ip
+), elsewhere AS (
+  SELECT o.ip, o.observed_at AS seen_at
+  FROM ip_observations o
+  WHERE o.ip IN (SELECT ip FROM held)
+    AND device_owner_at(o.device_id, o.merged_from, $2::timestamptz) <> $1::uuid
+    AND o.observed_at <= $2::timestamptz
+  UNION ALL
+  SELECT ia.ip, CASE WHEN ia.last_seen_at <= $2::timestamptz THEN ia.last_seen_at ELSE ia.created_at END
+  FROM ip_addresses ia
+  LEFT JOIN interfaces i ON i.id = ia.interface_id
+  WHERE ia.ip IN (SELECT ip FROM held)
+    AND device_owner_at(COALESCE(ia.device_id, i.device_id), ia.merged_from, $2::timestamptz) <> $1::uuid
+    AND ia.created_at <= $2::timestamptz
+)
+SELECT h.ip::text,
+       cur.interface_id::text,
+       cur.interface_name,
+       h.first_seen_at AS created_at,
+       h.last_seen_at AS updated_at,
+       h.last_seen_at,
+       NULL::timestamptz AS stale_at
+FROM held h
+LEFT JOIN LATERAL (
+  SELECT ia.interface_id, i.name AS interface_name, ia.stale_at
+  FROM ip_addresses ia
+  LEFT JOIN interfaces i ON i.id = ia.interface_id
+  WHERE ia.ip = h.ip
+    AND (ia.device_id = $1::uuid OR i.device_id = $1::uuid)
+    AND device_owner_at(COALESCE(ia.device_id, i.device_id), ia.merged_from, $2::timestamptz) = $1::uuid
+  LIMIT 1
+) cur ON true
+WHERE NOT EXISTS (
+  SELECT 1
+  FROM fact_detachments fd
+  WHERE fd.device_id = $1::uuid
+    AND device_owner_at(fd.device_id, fd.merged_from, $2::timestamptz) = $1::uuid
+    AND fd.kind = 'ip'
+    AND fd.value = host(h.ip)
+    AND fd.detached_at > h.last_seen_at
+    AND fd.detached_at <= $2::timestamptz
+)
+  AND NOT EXISTS (SELECT 1 FROM elsewhere x WHERE x.ip = h.ip AND x.seen_at > h.last_seen_at)
+  AND (cur.stale_at IS NULL OR cur.stale_at > $2::timestamptz)
+ORDER BY h.last_seen_at DESC, h.ip::text ASC;
+
+-- name: ListDeviceMACsAsOf :many
+-- MACs the device held at $2; see ListDeviceIPsAsOf.
+WITH evidence AS (
+  SELECT o.mac, o.observed_at AS seen_at
+  FROM mac_observations o
+  WHERE o.device_id = $1::uuid
+    AND device_owner_at(o.device_id, o.merged_from, $2::timestamptz) = $1::uuid
+    AND o.observed_at <= $2::timestamptz
+  UNION ALL
+  SELECT ma.mac, ma.created_at
+  FROM mac_addresses ma
+  LEFT JOIN interfaces i ON i.id = ma.interface_id
+  WHERE (ma.device_id = $1::uuid OR i.device_id = $1::uuid)
+    AND device_owner_at(COALESCE(ma.device_id, i.device_id), ma.merged_from, $2::timestamptz) = $1::uuid
+    AND ma.created_at <= $2::timestamptz
+  UNION ALL
+  SELECT ma.mac, ma.last_seen_at
+  FROM mac_addresses ma
+  LEFT JOIN interfaces i ON i.id = ma.interface_id
+  WHERE (ma.device_id = $1::uuid OR i.device_id = $1::uuid)
+    AND device_owner_at(COALESCE(ma.device_id, i.device_id), ma.merged_from, $2::timestamptz) = $1::uuid
+    AND ma.last_seen_at <= $2::timestamptz
+), held AS (
+  SELECT e.mac, min(e.seen_at) AS first_seen_at, max(e.seen_at) AS last_seen_at
+  FROM evidence e
+  GROUP BY e.mac
+), elsewhere AS (
+  SELECT o.mac, o.observed_at AS seen_at
+  FROM mac_observations o
+  WHERE o.mac IN (SELECT mac FROM held)
+    AND device_owner_at(o.device_id, o.merged_from, $2::timestamptz) <> $1::uuid
+    AND o.observed_at <= $2::timestamptz
+  UNION ALL
+  SELECT ma.mac, CASE WHEN ma.last_seen_at <= $2::timestamptz THEN ma.last_seen_at ELSE ma.created_at END
+  FROM mac_addresses ma
+  LEFT JOIN interfaces i ON i.id = ma.interface_id
+  WHERE ma.mac IN (SELECT mac FROM held)
+    AND device_owner_at(COALESCE(ma.device_id, i.device_id), ma.merged_from, $2::timestamptz) <> $1::uuid
+    AND ma.created_at <= $2::timestamptz
+)
+SELECT h.mac::text,
+       cur.interface_id::text,
+       cur.interface_name,
+       h.first_seen_at AS created_at,
+       h.last_seen_at AS updated_at,
+       h.last_seen_at,
+       NULL::timestamptz AS stale_at
+FROM held h
+LEFT JOIN LATERAL (
+  SELECT ma.interface_id, i.name AS interface_name, ma.stale_at
+  FROM mac_addresses ma
+  LEFT JOIN interfaces i ON i.id = ma.interface_id
+  WHERE ma.mac = h.mac
+    AND (ma.device_id = $1::uuid OR i.device_id = $1::uuid)
+    AND device_owner_at(COALESCE(ma.device_id, i.device_id), ma.merged_from, $2::timestamptz) = $1::uuid
+  LIMIT 1
+) cur ON true
+WHERE NOT EXISTS (
+  SELECT 1
+  FROM fact_detachments fd
+  WHERE fd.device_id = $1::uuid
+    AND device_owner_at(fd.device_id, fd.merged_from, $2::timestamptz) = $1::uuid
+    AND fd.kind = 'mac'
+    AND fd.value = h.mac::text
+    AND fd.detached_at > h.last_seen_at
+    AND fd.detached_at <= $2::timestamptz
+)
+  AND NOT EXISTS (SELECT 1 FROM elsewhere x WHERE x.mac = h.mac AND x.seen_at > h.last_seen_at)
+  AND (cur.stale_at IS NULL OR cur.stale_at > $2::timestamptz)
+ORDER BY h.last_seen_at DESC, h.mac::text ASC;
+
+-- name: ListDeviceInterfacesAsOf :many
+-- Interfaces created by $2, with the PVID from the VLAN snapshot in effect then. Counters and status are live.
+WITH snapshot AS (
+  SELECT device_state_at($1::uuid, 'vlans', $2::timestamptz) AS state
+)
+SELECT i.id,
+       i.name,
+       i.ifindex,
+       i.descr,
+       i.alias,
+       i.mac::text,
+       i.admin_status,
+       i.oper_status,
+       i.mtu,
+       i.speed_bps,
+       p.vlan_id AS pvid,
+       CASE WHEN iv.vlan_id = p.vlan_id AND iv.observed_at <= $2::timestamptz THEN iv.observed_at END AS pvid_observed_at,
+       i.created_at,
+       LEAST(i.updated_at, $2::timestamptz) AS updated_at
+FROM interfaces i
+CROSS JOIN snapshot s
+LEFT JOIN interface_vlans iv ON iv.interface_id = i.id AND iv.role = 'pvid'
+LEFT JOIN LATERAL (
+  SELECT CASE
+           WHEN s.state IS NULL THEN iv.vlan_id
+           ELSE (
+             SELECT (v.item->>'vlan_id')::integer
+             FROM jsonb_array_elements(s.state->'vlans') AS v(item)
+             WHERE v.item->>'interface_id' = i.id::text
+               AND v.item->>'role' = 'pvid'
+             LIMIT 1
+           )
+         END AS vlan_id
+) p ON true
+WHERE i.device_id = $1::uuid
+  AND i.created_at <= $2::timestamptz
+ORDER BY i.name IS NULL, i.name ASC, i.ifindex IS NULL, i.ifindex ASC, i.id ASC;
+
+-- name: ListDeviceServicesAsOf :many
+-- Services first seen by $2, with the state from the latest transition at or before it. HTTP details observed
+-- after $2 are left out; version and banner fields are live.
+SELECT s.protocol,
+       s.port,
+       s.name,
+       COALESCE(t.to_state, s.state) AS state,
+       s.source,
+       s.summary,
+       CASE
+         WHEN s.observed_at <= $2::timestamptz THEN s.observed_at
+         ELSE GREATEST(COALESCE(s.first_seen_at, s.created_at), t.changed_at)
+       END AS observed_at,
+       s.first_seen_at,
+       CASE WHEN s.last_seen_at <= $2::timestamptz THEN s.last_seen_at END AS last_seen_at,
+       CASE
+         WHEN t.to_state IS NULL AND s.closed_at <= $2::timestamptz THEN s.closed_at
+         WHEN t.to_state <> 'open' THEN t.changed_at
+       END AS closed_at,
+       CASE WHEN s.http_observed_at <= $2::timestamptz THEN s.http_status END AS http_status,
+       CASE WHEN s.http_observed_at <= $2::timestamptz THEN s.http_title END AS http_title,
+       CASE WHEN s.http_observed_at <= $2::timestamptz THEN s.http_server END AS http_server,
+       CASE WHEN s.http_observed_at <= $2::timestamptz THEN s.http_powered_by END AS http_powered_by,
+       CASE WHEN s.http_observed_at <= $2::timestamptz THEN s.http_favicon_hash END AS http_favicon_hash,
+       CASE WHEN s.http_observed_at <= $2::timestamptz THEN s.http_final_url END AS http_final_url,
+       CASE WHEN s.http_observed_at <= $2::timestamptz THEN s.http_observed_at END AS http_observed_at,
+       s.product,
+       s.version,
+       s.extra_info,
+       s.cpe,
+       s.created_at,
+       LEAST(s.updated_at, $2::timestamptz) AS updated_at
+FROM services s
+LEFT JOIN LATERAL (
+  SELECT st.to_state, st.changed_at
+  FROM service_transitions st
+  WHERE st.service_id = s.id
+    AND st.changed_at <= $2::timestamptz
+  ORDER BY st.changed_at DESC, st.id DESC
+  LIMIT 1
+) t ON true
+WHERE s.device_id = $1::uuid
+  AND COALESCE(s.first_seen_at, s.created_at) <= $2::timestamptz
+ORDER BY observed_at DESC, protocol ASC NULLS LAST, port ASC NULLS LAST, name ASC NULLS LAST;
+
+-- name: GetDeviceSNMPAsOf :one
+-- The SNMP identity in effect at $2. last_success_at is when that identity was recorded.
+WITH snapshot AS (
+  SELECT device_state_at($1::uuid, 'snmp', $2::timestamptz) AS state,
+         (
+           SELECT MAX(e.occurred_at)
+           FROM device_events e
+           WHERE e.device_id = $1::uuid
+             AND e.kind = 'snmp'
+             AND e.occurred_at <= $2::timestamptz
+         ) AS recorded_at
+)
+SELECT $1::uuid AS device_id,
+       s.state->>'address',
+       s.state->>'sys_name',
+       s.state->>'sys_descr',
+       s.state->>'sys_object_id',
+       s.state->>'sys_contact',
+       s.state->>'sys_location',
+       s.recorded_at AS last_success_at,
+       NULL::text AS last_error,
+       COALESCE(s.recorded_at, $2::timestamptz) AS updated_at
+FROM snapshot s
+WHERE s.state IS NOT NULL
+  AND s.state <> '{}'::jsonb;
+
+-- name: ListDeviceLinksAsOf :many
+-- Links from the snapshot in effect at $2; devices without link history fall back to live links created by then.
+-- Snapshots key links by link_key: the id and confidence come from the live link, and a link that no longer
+-- exists reports its link_key as id.
+WITH snapshot AS (
+  SELECT device_state_at($1::uuid, 'links', $2::timestamptz) AS state
+), links_at AS (
+  SELECT v.item->>'link_key' AS link_key,
+         v.item->>'peer_device_id' AS peer_device_id,
+         v.item->>'local_interface_id' AS local_interface_id,
+         v.item->>'peer_interface_id' AS peer_interface_id,
+         v.item->>'link_type' AS link_type,
+         v.item->>'source' AS source,
+         v.item->>'state' AS state,
+         (v.item->>'local_as')::bigint AS local_as,
+         (v.item->>'peer_as')::bigint AS peer_as,
+         v.item->>'area' AS area
+  FROM snapshot s
+  CROSS JOIN LATERAL jsonb_array_elements(s.state->'links') AS v(item)
+  UNION ALL
+  SELECT l.link_key,
+         CASE WHEN l.a_device_id = $1::uuid THEN l.b_device_id::text ELSE l.a_device_id::text END,
+         CASE WHEN l.a_device_id = $1::uuid THEN l.a_interface_id::text ELSE l.b_interface_id::text END,
+         CASE WHEN l.a_device_id = $1::uuid THEN l.b_interface_id::text ELSE l.a_interface_id::text END,
+         l.link_type,
+         l.source,
+         l.state,
+         CASE WHEN l.a_device_id = $1::uuid THEN l.a_as ELSE l.b_as END,
+         CASE WHEN l.a_device_id = $1::uuid THEN l.b_as ELSE l.a_as END,
+         l.area
+  FROM links l
+  CROSS JOIN snapshot s
+  WHERE s.state IS NULL
+    AND (l.a_device_id = $1::uuid OR l.b_device_id = $1::uuid)
+    AND l.created_at <= $2::timestamptz
+)
+SELECT COALESCE(l.id::text, la.link_key) AS id,
+       la.link_key,
+       la.peer_device_id,
+       la.local_interface_id,
+       la.peer_interface_id,
+       la.link_type,
+       la.source,
+       l.confidence,
+       la.state,
+       la.local_as,
+       la.peer_as,
+       la.area,
+       CASE WHEN l.last_change_at <= $2::timestamptz THEN l.last_change_at END AS last_change_at,
+       CASE WHEN l.observed_at <= $2::timestamptz THEN l.observed_at END AS observed_at,
+       LEAST(COALESCE(l.updated_at, $2::timestamptz), $2::timestamptz) AS updated_at
+FROM links_at la
+LEFT JOIN links l ON l.link_key = la.link_key
+ORDER BY COALESCE(CASE WHEN l.observed_at <= $2::timestamptz THEN l.observed_at END, LEAST(COALESCE(l.updated_at, $2::timestamptz), $2::timestamptz)) DESC,
+         COALESCE(l.id::text, la.link_key) DESC;
+
+-- name: ListDevicesInCIDRAsOf :many
+-- Devices holding an IP inside $1 at $4 (see ListDeviceIPsAsOf), excluding $2 when set, with names as of $4.
+WITH evidence AS (
+  SELECT device_owner_at(o.device_id, o.merged_from, $4::timestamptz) AS device_id,
+         o.ip,
+         o.observed_at AS seen_at
+  FROM ip_observations o
+  WHERE o.ip << $1::cidr
+    AND o.observed_at <= $4::timestamptz
+  UNION ALL
+  SELECT device_owner_at(COALESCE(ia.device_id, i.device_id), ia.merged_from, $4::timestamptz),
+         ia.ip,
+         CASE WHEN ia.last_seen_at <= $4::timestamptz THEN ia.last_seen_at ELSE ia.created_at END
+  FROM ip_addresses ia
+  LEFT JOIN interfaces i ON i.id = ia.interface_id
+  WHERE ia.ip << $1::cidr
+    AND ia.created_at <= $4::timestamptz
+), held AS (
+  SELECT e.device_id, e.ip, max(e.seen_at) AS last_seen_at
+  FROM evidence e
+  WHERE e.device_id IS NOT NULL
+  GROUP BY e.device_id, e.ip
+)
+SELECT DISTINCT d.id,
+                CASE WHEN n.state IS NULL THEN d.display_name ELSE n.state->>'display_name' END AS display_name
+FROM held h
+JOIN devices d ON d.id = h.device_id
+CROSS JOIN LATERAL device_state_at(d.id, 'display_name', $4::timestamptz) AS n(state)
+WHERE d.created_at <= $4::timestamptz
+  AND ($2::uuid IS NULL OR d.id <> $2::uuid)
+  AND NOT EXISTS (
+    SELECT 1
+    FROM fact_detachments fd
+    WHERE device_owner_at(fd.device_id, fd.merged_from, $4::timestamptz) = h.device_id
+      AND fd.kind = 'ip'
+      AND fd.value = host(h.ip)
+      AND fd.detached_at > h.last_seen_at
+      AND fd.detached_at <= $4::timestamptz
+  )
+  AND NOT EXISTS (
+    SELECT 1
+    FROM held o
+    WHERE o.ip = h.ip
+      AND o.device_id <> h.device_id
+      AND o.last_seen_at > h.last_seen_at
+  )
+  AND NOT EXISTS (
+    SELECT 1
+    FROM ip_addresses ia
+    LEFT JOIN interfaces i ON i.id = ia.interface_id
+    WHERE ia.ip = h.ip
+      AND device_owner_at(COALESCE(ia.device_id, i.device_id), ia.merged_from, $4::timestamptz) = h.device_id
+      AND ia.stale_at <= $4::timestamptz
+  )
+  AND NOT COALESCE(
+    (
+      SELECT ae.action = 'archived'
+      FROM device_archive_events ae
+      WHERE ae.device_id = d.id
+        AND ae.changed_at <= $4::timestamptz
+      ORDER BY ae.changed_at DESC, ae.id DESC
+      LIMIT 1
+    ),
+    false
+  )
+ORDER BY d.id ASC
+LIMIT $3;
+
+-- name: ListDevicePVIDsAsOf :many
+WITH snapshot AS (
+  SELECT device_state_at($1::uuid, 'vlans', $2::timestamptz) AS state
+)
+SELECT (v.item->>'vlan_id')::integer AS vlan_id
+FROM snapshot s
+CROSS JOIN LATERAL jsonb_array_elements(s.state->'vlans') AS v(item)
+WHERE v.item->>'role' = 'pvid'
+UNION
+SELECT iv.vlan_id
+FROM interfaces i
+JOIN interface_vlans iv ON iv.interface_id = i.id AND iv.role = 'pvid'
+CROSS JOIN snapshot s
+WHERE s.state IS NULL
+  AND i.device_id = $1::uuid
+  AND i.created_at <= $2::timestamptz
+ORDER BY vlan_id ASC;
+
+-- name: ListDevicesInVLANAsOf :many
+-- Devices with a port whose PVID was $1 at $4, excluding $2 when set, with names as of $4.
+SELECT d.id,
+       CASE WHEN n.state IS NULL THEN d.display_name ELSE n.state->>'display_name' END AS display_name
+FROM devices d
+CROSS JOIN LATERAL device_state_at(d.id, 'vlans', $4::timestamptz) AS vs(state)
+CROSS JOIN LATERAL device_state_at(d.id, 'display_name', $4::timestamptz) AS n(state)
+WHERE d.created_at <= $4::timestamptz
+  AND ($2::uuid IS NULL OR d.id <> $2::uuid)
+  AND (
+    (
+      vs.state IS NOT NULL
+      AND EXISTS (
+        SELECT 1
+        FROM jsonb_array_elements(vs.state->'vlans') AS v(item)
+        WHERE v.item->>'role' = 'pvid'
+          AND (v.item->>'vlan_id')::integer = $1
+      )
+    )
+    OR (
+      vs.state IS NULL
+      AND EXISTS (
+        SELECT 1
+        FROM interfaces i
+        JOIN interface_vlans iv ON iv.interface_id = i.id AND iv.role = 'pvid'
+        WHERE i.device_id = d.id
+          AND iv.vlan_id = $1
+          AND i.created_at <= $4::timestamptz
+      )
+    )
+  )
+  AND NOT COALESCE(
+    (
+      SELECT ae.action = 'archived'
+      FROM device_archive_events ae
+      WHERE ae.device_id = d.id
+        AND ae.changed_at <= $4::timestamptz
+      ORDER BY ae.changed_at DESC, ae.id DESC
+      LIMIT 1
+    ),
+    false
+  )
+ORDER BY d.id ASC
+LIMIT $3;
+
+-- name: ListDeviceLinkPeersAsOf :many
+-- ListDeviceLinkPeers from the link snapshot in effect at $3. Interface names and aggregates are live; STP
+-- state has no history and is left empty. Link ids are resolved by link_key as in ListDeviceLinksAsOf.
+WITH snapshot AS (
+  SELECT device_state_at($1::uuid, 'links', $3::timestamptz) AS state
+), links_at AS (
+  SELECT v.item->>'link_key' AS link_key,
+         (v.item->>'peer_device_id')::uuid AS peer_device_uuid,
+         (v.item->>'local_interface_id')::uuid AS local_interface_uuid,
+         (v.item->>'peer_interface_id')::uuid AS peer_interface_uuid,
+         v.item->>'link_type' AS link_type,
+         v.item->>'source' AS source
+  FROM snapshot s
+  CROSS JOIN LATERAL jsonb_array_elements(s.state->'links') AS v(item)
+  UNION ALL
+  SELECT l.link_key,
+         CASE WHEN l.a_device_id = $1::uuid THEN l.b_device_id ELSE l.a_device_id END,
+         CASE WHEN l.a_device_id = $1::uuid THEN l.a_interface_id ELSE l.b_interface_id END,
+         CASE WHEN l.a_device_id = $1::uuid THEN l.b_interface_id ELSE l.a_interface_id END,
+         l.link_type,
+         l.source
+  FROM links l
+  CROSS JOIN snapshot s
+  WHERE s.state IS NULL
+    AND (l.a_device_id = $1::uuid OR l.b_device_id = $1::uuid)
+    AND l.created_at <= $3::timestamptz
+)
+SELECT COALESCE(l.id::text, la.link_key) AS link_id,
+       la.link_key,
+       la.peer_device_uuid::text AS peer_device_id,
+       CASE WHEN n.state IS NULL THEN d.display_name ELSE n.state->>'display_name' END AS peer_display_name,
+       la.link_type,
+       la.source,
+       LEAST(COALESCE(l.observed_at, l.updated_at, $3::timestamptz), $3::timestamptz) AS last_seen_at,
+       la.local_interface_uuid::text AS local_interface_id,
+       li.name AS local_interface_name,
+       li.aggregate_interface_id::text AS local_aggregate_id,
+       agg.name AS local_aggregate_name,
+       la.peer_interface_uuid::text AS peer_interface_id,
+       pi.aggregate_interface_id::text AS peer_aggregate_id,
+       NULL::text AS local_stp_state,
+       NULL::text AS peer_stp_state
+FROM links_at la
+JOIN devices d ON d.id = la.peer_device_uuid AND d.created_at <= $3::timestamptz
+CROSS JOIN LATERAL device_state_at(d.id, 'display_name', $3::timestamptz) AS n(state)
+LEFT JOIN links l ON l.link_key = la.link_key
+LEFT JOIN interfaces li ON li.id = la.local_interface_uuid
+LEFT JOIN interfaces agg ON agg.id = li.aggregate_interface_id
+LEFT JOIN interfaces pi ON pi.id = la.peer_interface_uuid
+WHERE COALESCE(la.link_type, '') NOT IN ('ospf', 'bgp')
+  AND NOT COALESCE(
+    (
+      SELECT ae.action = 'archived'
+      FROM device_archive_events ae
+      WHERE ae.device_id = d.id
+        AND ae.changed_at <= $3::timestamptz
+      ORDER BY ae.changed_at DESC, ae.id DESC
+      LIMIT 1
+    ),
+    false
+  )
+ORDER BY peer_device_id ASC, link_id ASC
+LIMIT $2;
+
+-- name: ListServicesForDeviceAsOf :many
+-- ListServicesForDevice limited to services first seen by $3, with the state in effect then.
+SELECT s.id,
+       s.device_id,
+       s.protocol,
+       s.port,
+       s.name,
+       COALESCE(t.to_state, s.state) AS state,
+       s.source,
+       CASE
+         WHEN s.observed_at <= $3::timestamptz THEN s.observed_at
+         ELSE GREATEST(COALESCE(s.first_seen_at, s.created_at), t.changed_at)
+       END AS observed_at
+FROM services s
+LEFT JOIN LATERAL (
+  SELECT st.to_state, st.changed_at
+  FROM service_transitions st
+  WHERE st.service_id = s.id
+    AND st.changed_at <= $3::timestamptz
+  ORDER BY st.changed_at DESC, st.id DESC
+  LIMIT 1
+) t ON true
+WHERE s.device_id = $1::uuid
+  AND COALESCE(s.first_seen_at, s.created_at) <= $3::timestamptz
+ORDER BY observed_at DESC, protocol ASC NULLS LAST, port ASC NULLS LAST, name ASC NULLS LAST, s.id ASC
+LIMIT $2;
+
+-- name: GetServiceByIDAsOf :one
+SELECT s.id,
+       s.device_id,
+       s.protocol,
+       s.port,
+       s.name,
+       COALESCE(t.to_state, s.state) AS state,
+       s.source,
+       CASE
+         WHEN s.observed_at <= $2::timestamptz THEN s.observed_at
+         ELSE GREATEST(COALESCE(s.first_seen_at, s.created_at), t.changed_at)
+       END AS observed_at
+FROM services s
+LEFT JOIN LATERAL (
+  SELECT st.to_state, st.changed_at
+  FROM service_transitions st
+  WHERE st.service_id = s.id
+    AND st.changed_at <= $2::timestamptz
+  ORDER BY st.changed_at DESC, st.id DESC
+  LIMIT 1
+) t ON true
+WHERE s.id = $1::uuid
+  AND COALESCE(s.first_seen_at, s.created_at) <= $2::timestamptz;
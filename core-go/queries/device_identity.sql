@@ -0,0 +1,40 @@
+-- name: UpsertDeviceClientID :exec
+INSERT INTO device_client_ids (device_id, client_id, first_seen_at, last_seen_at)
+VALUES ($1, $2, $3, $3)
+ON CONFLICT (device_id, client_id) DO UPDATE
+SET last_seen_at = GREATEST(device_client_ids.last_seen_at, EXCLUDED.last_seen_at);
+
+-- name: FindDeviceIDByClientID :one
+SELECT device_id
+FROM device_client_ids
+WHERE client_id = $1
+ORDER BY last_seen_at DESC
+LIMIT 1;
+
+-- name: FindDeviceIDByHostName :one
+-- Only answers when exactly one active device claims the name (generic names like "iPhone" match nothing).
+SELECT min(c.device_id::text)
+FROM device_name_candidates c
+JOIN devices d ON d.id = c.device_id
+WHERE c.source IN ('dhcp', 'mdns', 'netbios')
+  AND lower(split_part(c.name, '.', 1)) = $1
+  AND d.archived_at IS NULL
+HAVING count(DISTINCT c.device_id) = 1;
+
+-- name: FindRotatingDeviceIDByIP :one
+-- The device most recently seen on the IP since $2, provided it is itself known by a locally
+-- administered (randomized) unicast MAC; the second hex digit carries the U/L and group bits, matching
+-- identity.IsLocallyAdministered.
+SELECT o.device_id
+FROM ip_observations o
+JOIN devices d ON d.id = o.device_id
+WHERE o.ip = $1::inet
+  AND o.observed_at >= $2
+  AND d.archived_at IS NULL
+  AND EXISTS (
+    SELECT 1 FROM mac_addresses m
+    WHERE m.device_id = o.device_id
+      AND substr(m.mac::text, 2, 1) IN ('2', '6', 'a', 'e')
+  )
+ORDER BY o.observed_at DESC
+LIMIT 1;
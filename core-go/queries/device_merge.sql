@@ -0,0 +1,545 @@
+-- Device merge: every statement moves one kind of fact from a source device ($2) onto the survivor ($1).
+-- The statements run in this order inside one transaction, once per source device; rows that would
+-- duplicate a survivor row are left on the source and disappear with it (survivor wins).
+
+-- name: LockMergeDevices :many
+SELECT id
+FROM devices
+WHERE id = ANY($1::uuid[])
+ORDER BY id
+FOR UPDATE;
+
+-- name: CreateDeviceMergeInterfaceMap :exec
+CREATE TEMP TABLE IF NOT EXISTS device_merge_interfaces (
+  source_id uuid PRIMARY KEY,
+  target_id uuid NOT NULL
+) ON COMMIT DROP;
+
+-- name: ClearDeviceMergeInterfaceMap :exec
+DELETE FROM device_merge_interfaces;
+
+-- name: MapDeviceMergeInterfaces :execrows
+-- Pairs each source interface with the survivor interface it duplicates (same ifIndex first, then same name).
+INSERT INTO device_merge_interfaces (source_id, target_id)
+SELECT DISTINCT ON (s.id) s.id, t.id
+FROM interfaces s
+JOIN interfaces t
+  ON t.device_id = $1
+ AND ((s.ifindex IS NOT NULL AND t.ifindex = s.ifindex) OR (s.name IS NOT NULL AND t.name = s.name))
+WHERE s.device_id = $2
+ORDER BY s.id, (t.ifindex IS NOT DISTINCT FROM s.ifindex) DESC, t.id;
+
+-- name: MarkMergedInterfaceAddresses :execrows
+-- Addresses bound to the source's interfaces reach the survivor with those interfaces (below), so they are marked
+-- as merged from the source first.
+WITH ips AS (
+  UPDATE ip_addresses a
+  SET merged_from = $2
+  FROM interfaces i
+  WHERE i.id = a.interface_id
+    AND i.device_id = $2
+    AND i.device_id <> $1::uuid
+  RETURNING 1
+),
+macs AS (
+  UPDATE mac_addresses a
+  SET merged_from = $2
+  FROM interfaces i
+  WHERE i.id = a.interface_id
+    AND i.device_id = $2
+    AND i.device_id <> $1::uuid
+  RETURNING 1
+)
+SELECT 1 FROM ips
+UNION ALL
+SELECT 1 FROM macs;
+
+-- name: MergeInterfaceIPs :execrows
+UPDATE ip_addresses a
+SET interface_id = m.target_id,
+    updated_at = now()
+FROM device_merge_interfaces m
+WHERE a.interface_id = m.source_id
+  AND NOT EXISTS (SELECT 1 FROM ip_addresses x WHERE x.interface_id = m.target_id AND x.ip = a.ip);
+
+-- name: MergeInterfaceMACs :execrows
+UPDATE mac_addresses a
+SET interface_id = m.target_id,
+    updated_at = now()
+FROM device_merge_interfaces m
+WHERE a.interface_id = m.source_id
+  AND NOT EXISTS (SELECT 1 FROM mac_addresses x WHERE x.interface_id = m.target_id AND x.mac = a.mac);
+
+-- name: MergeInterfaceLinks :execrows
+UPDATE links l
+SET a_interface_id = COALESCE((SELECT m.target_id FROM device_merge_interfaces m WHERE m.source_id = l.a_interface_id), l.a_interface_id),
+    b_interface_id = COALESCE((SELECT m.target_id FROM device_merge_interfaces m WHERE m.source_id = l.b_interface_id), l.b_interface_id),
+    updated_at = now()
+WHERE l.a_interface_id IN (SELECT source_id FROM device_merge_interfaces)
+   OR l.b_interface_id IN (SELECT source_id FROM device_merge_interfaces);
+
+-- name: MergeInterfaceAggregates :execrows
+UPDATE interfaces i
+SET aggregate_interface_id = m.target_id,
+    updated_at = now()
+FROM device_merge_interfaces m
+WHERE i.aggregate_interface_id = m.source_id;
+
+-- name: MergeInterfaceSTPRoots :execrows
+UPDATE stp_bridges b
+SET root_interface_id = m.target_id,
+    updated_at = now()
+FROM device_merge_interfaces m
+WHERE b.root_interface_id = m.source_id;
+
+-- name: DeleteMergedDuplicateInterfaces :execrows
+DELETE FROM interfaces
+WHERE id IN (SELECT source_id FROM device_merge_interfaces);
+
+-- name: MergeDeviceInterfaces :execrows
+UPDATE interfaces
+SET device_id = $1,
+    updated_at = now()
+WHERE device_id = $2;
+
+-- name: MergeDeviceSTPPorts :execrows
+UPDATE stp_ports
+SET device_id = $1,
+    updated_at = now()
+WHERE device_id = $2;
+
+-- name: MergeDeviceSTPBridges :execrows
+UPDATE stp_bridges s
+SET device_id = $1,
+    updated_at = now()
+WHERE s.device_id = $2
+  AND NOT EXISTS (SELECT 1 FROM stp_bridges t WHERE t.device_id = $1 AND t.instance = s.instance);
+
+-- name: MergeDevicePoE :execrows
+UPDATE interface_poe
+SET device_id = CASE WHEN device_id = $2 THEN $1 ELSE device_id END,
+    powered_device_id = CASE WHEN powered_device_id = $2 THEN $1 ELSE powered_device_id END,
+    updated_at = now()
+WHERE device_id = $2 OR powered_device_id = $2;
+
+-- name: MergeDeviceIPs :execrows
+UPDATE ip_addresses s
+SET device_id = $1,
+    merged_from = $2,
+    updated_at = now()
+WHERE s.device_id = $2
+  AND NOT EXISTS (SELECT 1 FROM ip_addresses t WHERE t.device_id = $1 AND t.ip = s.ip);
+
+-- name: MergeDeviceMACs :execrows
+UPDATE mac_addresses s
+SET device_id = $1,
+    merged_from = $2,
+    updated_at = now()
+WHERE s.device_id = $2
+  AND NOT EXISTS (SELECT 1 FROM mac_addresses t WHERE t.device_id = $1 AND t.mac = s.mac);
+
+-- name: MergeDeviceIPObservations :execrows
+UPDATE ip_observations s
+SET device_id = $1,
+    merged_from = $2
+WHERE s.device_id = $2
+  AND NOT EXISTS (SELECT 1 FROM ip_observations t WHERE t.device_id = $1 AND t.run_id = s.run_id AND t.ip = s.ip);
+
+-- name: MergeDeviceMACObservations :execrows
+UPDATE mac_observations s
+SET device_id = $1,
+    merged_from = $2
+WHERE s.device_id = $2
+  AND NOT EXISTS (SELECT 1 FROM mac_observations t WHERE t.device_id = $1 AND t.run_id = s.run_id AND t.mac = s.mac);
+
+-- name: FillMergedDuplicateServices :execrows
+-- Survivor services keep their state; lifecycle bounds widen and missing detection details are filled in.
+UPDATE services t
+SET first_seen_at = LEAST(t.first_seen_at, s.first_seen_at),
+    last_seen_at = GREATEST(t.last_seen_at, s.last_seen_at),
+    name = COALESCE(t.name, s.name),
+    product = COALESCE(t.product, s.product),
+    version = COALESCE(t.version, s.version),
+    extra_info = COALESCE(t.extra_info, s.extra_info),
+    cpe = COALESCE(t.cpe, s.cpe),
+    summary = COALESCE(t.summary, s.summary),
+    updated_at = now()
+FROM services s
+WHERE t.device_id = $1
+  AND s.device_id = $2
+  AND s.protocol = t.protocol
+  AND s.port = t.port;
+
+-- name: MergeDuplicateServiceCertificates :execrows
+UPDATE service_certificates c
+SET service_id = t.id,
+    device_id = $1,
+    updated_at = now()
+FROM services s
+JOIN services t ON t.device_id = $1 AND t.protocol = s.protocol AND t.port = s.port
+WHERE s.device_id = $2
+  AND c.service_id = s.id
+  AND NOT EXISTS (SELECT 1 FROM service_certificates x WHERE x.service_id = t.id AND x.fingerprint_sha256 = c.fingerprint_sha256);
+
+-- name: MergeDuplicateServiceRefs :execrows
+WITH pairs AS (
+  SELECT s.id AS source_id, t.id AS target_id
+  FROM services s
+  JOIN services t ON t.device_id = $1 AND t.protocol = s.protocol AND t.port = s.port
+  WHERE s.device_id = $2
+),
+keys AS (
+  UPDATE ssh_host_keys k
+  SET service_id = p.target_id
+  FROM pairs p
+  WHERE k.service_id = p.source_id
+  RETURNING 1
+),
+transitions AS (
+  UPDATE service_transitions st
+  SET service_id = p.target_id
+  FROM pairs p
+  WHERE st.service_id = p.source_id
+  RETURNING 1
+)
+SELECT 1 FROM keys
+UNION ALL
+SELECT 1 FROM transitions;
+
+-- name: MergeDeviceServices :execrows
+UPDATE services s
+SET device_id = $1,
+    updated_at = now()
+WHERE s.device_id = $2
+  AND NOT EXISTS (
+    SELECT 1 FROM services t
+    WHERE t.device_id = $1 AND t.protocol = s.protocol AND t.port = s.port
+  );
+
+-- name: MergeDeviceServiceCertificates :execrows
+UPDATE service_certificates
+SET device_id = $1,
+    updated_at = now()
+WHERE device_id = $2;
+
+-- name: MergeDeviceServiceTransitions :execrows
+UPDATE service_transitions
+SET device_id = $1
+WHERE device_id = $2;
+
+-- name: FillMergedDuplicateSSHHostKeys :execrows
+UPDATE ssh_host_keys t
+SET first_seen_at = LEAST(t.first_seen_at, s.first_seen_at),
+    last_seen_at = GREATEST(t.last_seen_at, s.last_seen_at),
+    banner = COALESCE(t.banner, s.banner),
+    updated_at = now()
+FROM ssh_host_keys s
+WHERE t.device_id = $1
+  AND s.device_id = $2
+  AND s.key_type = t.key_type
+  AND s.fingerprint_sha256 = t.fingerprint_sha256;
+
+-- name: MergeDeviceSSHHostKeys :execrows
+UPDATE ssh_host_keys s
+SET device_id = $1,
+    updated_at = now()
+WHERE s.device_id = $2
+  AND NOT EXISTS (
+    SELECT 1 FROM ssh_host_keys t
+    WHERE t.device_id = $1 AND t.key_type = s.key_type AND t.fingerprint_sha256 = s.fingerprint_sha256
+  );
+
+-- name: MergeDeviceOSGuesses :execrows
+-- OS guesses are a ranked set per source, so a source only moves when the survivor has none from it.
+UPDATE device_os_guesses s
+SET device_id = $1,
+    updated_at = now()
+WHERE s.device_id = $2
+  AND NOT EXISTS (SELECT 1 FROM device_os_guesses t WHERE t.device_id = $1 AND t.source = s.source);
+
+-- name: FillMergedDuplicateTags :execrows
+UPDATE device_tags t
+SET confidence = GREATEST(t.confidence, s.confidence),
+    evidence = COALESCE(t.evidence, s.evidence),
+    updated_at = now()
+FROM device_tags s
+WHERE t.device_id = $1
+  AND s.device_id = $2
+  AND s.tag = t.tag
+  AND s.source = t.source;
+
+-- name: MergeDeviceTags :execrows
+UPDATE device_tags s
+SET device_id = $1,
+    updated_at = now()
+WHERE s.device_id = $2
+  AND NOT EXISTS (SELECT 1 FROM device_tags t WHERE t.device_id = $1 AND t.tag = s.tag AND t.source = s.source);
+
+-- name: MergeDeviceNameCandidates :execrows
+UPDATE device_name_candidates s
+SET device_id = $1
+WHERE s.device_id = $2
+  AND NOT EXISTS (
+    SELECT 1 FROM device_name_candidates t
+    WHERE t.device_id = $1 AND t.source = s.source AND t.name = s.name AND t.address IS NOT DISTINCT FROM s.address
+  );
+
+-- name: MergeDeviceClientIDs :execrows
+UPDATE device_client_ids s
+SET device_id = $1
+WHERE s.device_id = $2
+  AND NOT EXISTS (SELECT 1 FROM device_client_ids t WHERE t.device_id = $1 AND t.client_id = s.client_id);
+
+-- name: DeleteStaleSurvivorSNMP :execrows
+-- The SNMP snapshot with the most recent successful poll wins.
+DELETE FROM device_snmp t
+USING device_snmp s
+WHERE t.device_id = $1
+  AND s.device_id = $2
+  AND COALESCE(s.last_success_at, '-infinity') > COALESCE(t.last_success_at, '-infinity');
+
+-- name: MergeDeviceSNMP :execrows
+-- A source snapshot that becomes the survivor's is recorded as an snmp event by actor $3..$6 when its identity
+-- differs from the survivor's last one.
+WITH moved AS (
+  UPDATE device_snmp s
+  SET device_id = $1,
+      updated_at = now()
+  WHERE s.device_id = $2
+    AND NOT EXISTS (SELECT 1 FROM device_snmp t WHERE t.device_id = $1)
+  RETURNING s.device_id,
+            s.last_success_at,
+            jsonb_build_object(
+              'address', host(s.address),
+              'sys_name', s.sys_name,
+              'sys_descr', s.sys_descr,
+              'sys_object_id', s.sys_object_id,
+              'sys_contact', s.sys_contact,
+              'sys_location', s.sys_location
+            ) AS identity
+), previous AS (
+  SELECT e.after
+  FROM device_events e
+  WHERE e.device_id = $1::uuid
+    AND e.kind = 'snmp'
+  ORDER BY e.occurred_at DESC, e.id DESC
+  LIMIT 1
+), event AS (
+  INSERT INTO device_events (device_id, kind, actor_type, actor, run_id, reason, before, after, summary)
+  SELECT u.device_id,
+         'snmp',
+         COALESCE(NULLIF($3::text, ''), 'system'),
+         $4::text,
+         $5::uuid,
+         $6::text,
+         p.after,
+         u.identity,
+         CASE
+           WHEN p.after IS NULL THEN 'snmp identity observed'
+           ELSE 'snmp identity changed: ' || concat_ws(
+             ', ',
+             CASE WHEN p.after->'address' IS DISTINCT FROM u.identity->'address' THEN 'address' END,
+             CASE WHEN p.after->'sys_name' IS DISTINCT FROM u.identity->'sys_name' THEN 'sys_name' END,
+             CASE WHEN p.after->'sys_descr' IS DISTINCT FROM u.identity->'sys_descr' THEN 'sys_descr' END,
+             CASE WHEN p.after->'sys_object_id' IS DISTINCT FROM u.identity->'sys_object_id' THEN 'sys_object_id' END,
+             CASE WHEN p.after->'sys_contact' IS DISTINCT FROM u.identity->'sys_contact' THEN 'sys_contact' END,
+             CASE WHEN p.after->'sys_location' IS DISTINCT FROM u.identity->'sys_location' THEN 'sys_location' END
+           )
+         END
+  FROM moved u
+  LEFT JOIN previous p ON true
+  WHERE u.last_success_at IS NOT NULL
+    AND p.after IS DISTINCT FROM u.identity
+)
+SELECT 1 FROM moved;
+
+-- name: MergeDeviceMetadata :execrows
+-- Survivor metadata wins field by field; empty fields are filled from the source. A change is recorded as a
+-- metadata event by actor $3..$6.
+WITH prev AS (
+  SELECT device_id, owner, location, notes
+  FROM device_metadata
+  WHERE device_id = $1::uuid
+  FOR UPDATE
+), upserted AS (
+  INSERT INTO device_metadata (device_id, owner, location, notes)
+  SELECT $1::uuid, s.owner, s.location, s.notes
+  FROM device_metadata s, (SELECT count(*) FROM prev) locked
+  WHERE s.device_id = $2::uuid
+  ON CONFLICT (device_id) DO UPDATE
+  SET owner = COALESCE(device_metadata.owner, EXCLUDED.owner),
+      location = COALESCE(device_metadata.location, EXCLUDED.location),
+      notes = COALESCE(device_metadata.notes, EXCLUDED.notes),
+      updated_at = now()
+  RETURNING device_id, owner, location, notes
+), event AS (
+  INSERT INTO device_events (device_id, kind, actor_type, actor, run_id, reason, before, after, summary)
+  SELECT u.device_id,
+         'metadata',
+         COALESCE(NULLIF($3::text, ''), 'system'),
+         $4::text,
+         $5::uuid,
+         $6::text,
+         CASE WHEN p.device_id IS NOT NULL THEN jsonb_build_object('owner', p.owner, 'location', p.location, 'notes', p.notes) END,
+         jsonb_build_object('owner', u.owner, 'location', u.location, 'notes', u.notes),
+         'metadata updated: ' || concat_ws(
+           ', ',
+           CASE WHEN p.owner IS DISTINCT FROM u.owner THEN 'owner' END,
+           CASE WHEN p.location IS DISTINCT FROM u.location THEN 'location' END,
+           CASE WHEN p.notes IS DISTINCT FROM u.notes THEN 'notes' END
+         )
+  FROM upserted u
+  LEFT JOIN prev p ON true
+  WHERE (p.owner, p.location, p.notes) IS DISTINCT FROM (u.owner, u.location, u.notes)
+)
+SELECT 1 FROM upserted;
+
+-- name: MergeDeviceDisplayName :execrows
+-- Names an unnamed survivor after the source and records a display_name event by actor $3..$6.
+WITH updated AS (
+  UPDATE devices t
+  SET display_name = s.display_name,
+      updated_at = now()
+  FROM devices s
+  WHERE t.id = $1::uuid
+    AND s.id = $2::uuid
+    AND t.display_name IS NULL
+    AND s.display_name IS NOT NULL
+  RETURNING t.id, t.display_name
+)
+INSERT INTO device_events (device_id, kind, actor_type, actor, run_id, reason, before, after, summary)
+SELECT u.id,
+       'display_name',
+       COALESCE(NULLIF($3::text, ''), 'system'),
+       $4::text,
+       $5::uuid,
+       $6::text,
+       NULL,
+       jsonb_build_object('display_name', u.display_name),
+       'named ' || u.display_name
+FROM updated u;
+
+-- name: DeleteMergedPairLinks :execrows
+-- Links between the source and the survivor would become self-links.
+DELETE FROM links
+WHERE (a_device_id = $2 AND b_device_id IN ($1, $2))
+   OR (b_device_id = $2 AND a_device_id = $1);
+
+-- name: MergeDeviceLinks :execrows
+UPDATE links l
+SET a_device_id = CASE WHEN l.a_device_id = $2 THEN $1 ELSE l.a_device_id END,
+    b_device_id = CASE WHEN l.b_device_id = $2 THEN $1 ELSE l.b_device_id END,
+    link_key = replace(l.link_key, $2::uuid::text, $1::uuid::text),
+    updated_at = now()
+WHERE (l.a_device_id = $2 OR l.b_device_id = $2)
+  AND NOT EXISTS (SELECT 1 FROM links x WHERE x.link_key = replace(l.link_key, $2::uuid::text, $1::uuid::text));
+
+-- name: MergeDeviceLinkTransitions :execrows
+UPDATE link_state_transitions
+SET a_device_id = CASE WHEN a_device_id = $2 THEN $1 ELSE a_device_id END,
+    b_device_id = CASE WHEN b_device_id = $2 THEN $1 ELSE b_device_id END
+WHERE a_device_id = $2 OR b_device_id = $2;
+
+-- name: MergeDeviceTracerouteHops :execrows
+UPDATE traceroute_hops
+SET device_id = $1
+WHERE device_id = $2;
+
+-- name: MergeDeviceCustomFacts :execrows
+UPDATE device_custom_facts s
+SET device_id = $1
+WHERE s.device_id = $2
+  AND NOT EXISTS (
+    SELECT 1 FROM device_custom_facts t
+    WHERE t.device_id = $1 AND t.profile_id = s.profile_id AND t.fact_key = s.fact_key AND t.instance = s.instance
+  );
+
+-- name: MergeDeviceCustomFactHistory :execrows
+UPDATE device_custom_fact_history
+SET device_id = $1
+WHERE device_id = $2;
+
+-- name: MergeDeviceArchiveEvents :execrows
+UPDATE device_archive_events
+SET device_id = $1
+WHERE device_id = $2;
+
+-- name: MergeDeviceFactDetachments :execrows
+UPDATE fact_detachments
+SET device_id = $1,
+    merged_from = $2
+WHERE device_id = $2;
+
+-- name: MergeDeviceAvailability :execrows
+-- The survivor's current state wins: source transitions move only when they precede the survivor's latest one, so
+-- they become history instead of overriding it. With no survivor history, the source's state carries over as is.
+UPDATE device_availability s
+SET device_id = $1
+WHERE s.device_id = $2
+  AND s.changed_at < COALESCE(
+    (SELECT max(t.changed_at) FROM device_availability t WHERE t.device_id = $1),
+    'infinity'::timestamptz
+  );
+
+-- name: MergeDeviceMonitor :execrows
+-- The survivor's monitor config wins; the source's is only kept when the survivor is not monitored.
+UPDATE device_monitors s
+SET device_id = $1,
+    updated_at = now()
+WHERE s.device_id = $2
+  AND NOT EXISTS (SELECT 1 FROM device_monitors t WHERE t.device_id = $1);
+
+-- name: MergeDeviceReachabilitySamples :execrows
+UPDATE reachability_samples
+SET device_id = $1
+WHERE device_id = $2;
+
+-- name: MergeDeviceReachabilityRollups :execrows
+-- Hours both devices were probed in are folded together.
+INSERT INTO reachability_rollups (device_id, bucket_start, probes, failures, rtt_sum_ms, rtt_min_ms, rtt_max_ms)
+SELECT $1::uuid, s.bucket_start, s.probes, s.failures, s.rtt_sum_ms, s.rtt_min_ms, s.rtt_max_ms
+FROM reachability_rollups s
+WHERE s.device_id = $2
+ON CONFLICT (device_id, bucket_start) DO UPDATE
+SET probes = reachability_rollups.probes + EXCLUDED.probes,
+    failures = reachability_rollups.failures + EXCLUDED.failures,
+    rtt_sum_ms = reachability_rollups.rtt_sum_ms + EXCLUDED.rtt_sum_ms,
+    rtt_min_ms = LEAST(reachability_rollups.rtt_min_ms, EXCLUDED.rtt_min_ms),
+    rtt_max_ms = GREATEST(reachability_rollups.rtt_max_ms, EXCLUDED.rtt_max_ms);
+
+-- name: MergeDeviceAliases :execrows
+-- Aliases of the source follow it, and the source itself becomes an alias of the survivor.
+WITH moved AS (
+  UPDATE device_aliases
+  SET device_id = $1
+  WHERE device_id = $2
+  RETURNING 1
+),
+added AS (
+  INSERT INTO device_aliases (alias_id, device_id, merged_at)
+  VALUES ($2, $1, now())
+  ON CONFLICT (alias_id) DO UPDATE
+  SET device_id = EXCLUDED.device_id,
+      merged_at = EXCLUDED.merged_at
+  RETURNING 1
+)
+SELECT 1 FROM moved
+UNION ALL
+SELECT 1 FROM added;
+
+-- name: DeleteMergedDevice :execrows
+-- Never the survivor, which also gives $1 a use: every step is prepared with both arguments.
+DELETE FROM devices
+WHERE id = $2
+  AND id <> $1::uuid;
+
+-- name: GetDeviceAlias :one
+SELECT device_id
+FROM device_aliases
+WHERE alias_id = $1;
+
+-- name: ListDeviceAliases :many
+SELECT alias_id
+FROM device_aliases
+WHERE device_id = $1
+ORDER BY merged_at, alias_id;
@@ -3,9 +3,11 @@ SELECT d.id,
        d.display_name,
        m.owner,
        m.location,
-       m.notes
+       m.notes,
+       d.archived_at
 FROM devices d
 LEFT JOIN device_metadata m ON m.device_id = d.id
+WHERE d.archived_at IS NULL
 ORDER BY d.created_at DESC;
 
 -- name: GetDevice :one
@@ -13,7 +15,8 @@ SELECT d.id,
        d.display_name,
        m.owner,
        m.location,
-       m.notes
+       m.notes,
+       d.archived_at
 FROM devices d
 LEFT JOIN device_metadata m ON m.device_id = d.id
 WHERE d.id = $1;
@@ -22,28 +25,54 @@ WHERE d.id = $1;
 WITH inserted AS (
   INSERT INTO devices (display_name)
   VALUES ($1)
-  RETURNING id, display_name
+  RETURNING id, display_name, archived_at
 )
 SELECT i.id,
        i.display_name,
        m.owner,
        m.location,
-       m.notes
+       m.notes,
+       i.archived_at
 FROM inserted i
 LEFT JOIN device_metadata m ON m.device_id = i.id;
 
 -- name: UpdateDevice :one
-WITH updated AS (
-  UPDATE devices
+-- Renames a device. A changed name is recorded as a display_name event by actor $3..$6 in the same statement.
+WITH prev AS (
+  SELECT id, display_name
+  FROM devices
+  WHERE id = $1
+  FOR UPDATE
+), updated AS (
+  UPDATE devices d
   SET display_name = $2,
       updated_at = now()
-  WHERE id = $1
-  RETURNING id, display_name
+  FROM prev p
+  WHERE d.id = p.id
+  RETURNING d.id, d.display_name, d.archived_at, p.display_name AS previous_name
+), event AS (
+  INSERT INTO device_events (device_id, kind, actor_type, actor, run_id, reason, before, after, summary)
+  SELECT u.id,
+         'display_name',
+         COALESCE(NULLIF($3::text, ''), 'system'),
+         $4::text,
+         $5::uuid,
+         $6::text,
+         CASE WHEN u.previous_name IS NOT NULL THEN jsonb_build_object('display_name', u.previous_name) END,
+         jsonb_build_object('display_name', u.display_name),
+         CASE
+           WHEN u.display_name IS NULL THEN 'name cleared'
+           WHEN u.previous_name IS NULL THEN 'named ' || u.display_name
+           ELSE 'renamed from ' || u.previous_name || ' to ' || u.display_name
+         END
+  FROM updated u
+  WHERE u.display_name IS DISTINCT FROM u.previous_name
 )
 SELECT u.id,
        u.display_name,
        m.owner,
        m.location,
-       m.notes
+       m.notes,
+       u.archived_at
 FROM updated u
 LEFT JOIN device_metadata m ON m.device_id = u.id;
@@ -1,27 +1,59 @@
 -- name: FindDeviceIDByMAC :one
+-- A stale MAC still matches so a device that was away for a while keeps its identity, but a device that
+-- currently holds the MAC wins.
 SELECT device_id
 FROM mac_addresses
 WHERE mac = $1::macaddr
   AND device_id IS NOT NULL
-ORDER BY created_at ASC
+ORDER BY (stale_at IS NOT NULL) ASC, created_at ASC
 LIMIT 1;
 
 -- name: FindDeviceIDByIP :one
-SELECT device_id
-FROM ip_addresses
-WHERE ip = $1::inet
-  AND device_id IS NOT NULL
-ORDER BY created_at ASC
+-- Stale addresses are ignored: the IP has probably moved on since the device last held it. When several
+-- devices still hold it, the one that answered on it most recently wins.
+SELECT a.device_id
+FROM ip_addresses a
+JOIN devices d ON d.id = a.device_id
+WHERE a.ip = $1::inet
+  AND a.stale_at IS NULL
+  AND d.archived_at IS NULL
+ORDER BY a.last_seen_at DESC, a.created_at ASC
 LIMIT 1;
 
 -- name: UpsertDeviceIP :exec
+-- Discovery sightings hand the address (back) to fact aging.
 INSERT INTO ip_addresses (device_id, ip)
 VALUES ($1::uuid, $2::inet)
 ON CONFLICT (device_id, ip) WHERE device_id IS NOT NULL
-DO UPDATE SET updated_at = now();
+DO UPDATE SET updated_at = now(),
+              last_seen_at = now(),
+              stale_at = NULL,
+              source = 'discovery';
 
 -- name: UpsertDeviceMAC :exec
 INSERT INTO mac_addresses (device_id, mac)
 VALUES ($1::uuid, $2::macaddr)
 ON CONFLICT (device_id, mac) WHERE device_id IS NOT NULL
-DO UPDATE SET updated_at = now();
+DO UPDATE SET updated_at = now(),
+              last_seen_at = now(),
+              stale_at = NULL,
+              source = 'discovery';
+
+-- name: UpsertImportedDeviceIP :exec
+-- Imports are not repeated on the discovery cadence, so their addresses are kept out of fact aging.
+INSERT INTO ip_addresses (device_id, ip, source)
+VALUES ($1::uuid, $2::inet, 'import')
+ON CONFLICT (device_id, ip) WHERE device_id IS NOT NULL
+DO UPDATE SET updated_at = now(),
+              last_seen_at = now(),
+              stale_at = NULL,
+              source = 'import';
+
+-- name: UpsertImportedDeviceMAC :exec
+INSERT INTO mac_addresses (device_id, mac, source)
+VALUES ($1::uuid, $2::macaddr, 'import')
+ON CONFLICT (device_id, mac) WHERE device_id IS NOT NULL
+DO UPDATE SET updated_at = now(),
+              last_seen_at = now(),
+              stale_at = NULL,
+              source = 'import';
@@ -15,36 +15,103 @@ WHERE device_id = $1::uuid
 ORDER BY observed_at DESC, source ASC, name ASC;
 
 -- name: SetDeviceDisplayNameIfUnset :execrows
-UPDATE devices
-SET display_name = $2,
-    updated_at = now()
-WHERE id = $1::uuid
-  AND (display_name IS NULL OR btrim(display_name) = '');
+-- Names an unnamed device and records a display_name event by actor $3..$6. Affects 0 rows when it already has a name.
+WITH prev AS (
+  SELECT id, display_name
+  FROM devices
+  WHERE id = $1::uuid
+    AND (display_name IS NULL OR btrim(display_name) = '')
+  FOR UPDATE
+), updated AS (
+  UPDATE devices d
+  SET display_name = $2,
+      updated_at = now()
+  FROM prev p
+  WHERE d.id = p.id
+  RETURNING d.id, d.display_name, p.display_name AS previous_name
+)
+INSERT INTO device_events (device_id, kind, actor_type, actor, run_id, reason, before, after, summary)
+SELECT u.id,
+       'display_name',
+       COALESCE(NULLIF($3::text, ''), 'system'),
+       $4::text,
+       $5::uuid,
+       $6::text,
+       CASE WHEN u.previous_name IS NOT NULL THEN jsonb_build_object('display_name', u.previous_name) END,
+       jsonb_build_object('display_name', u.display_name),
+       'named ' || u.display_name
+FROM updated u;
 
 -- name: UpsertDeviceSNMP :exec
-INSERT INTO device_snmp (
-  device_id,
-  address,
-  sys_name,
-  sys_descr,
-  sys_object_id,
-  sys_contact,
-  sys_location,
-  last_success_at,
-  last_error,
-  updated_at
+-- Stores the latest SNMP poll. A successful poll whose identity differs from the last snmp event is recorded as
+-- a new one by actor $10..$13; failed polls only update the error.
+WITH upserted AS (
+  INSERT INTO device_snmp (
+    device_id,
+    address,
+    sys_name,
+    sys_descr,
+    sys_object_id,
+    sys_contact,
+    sys_location,
+    last_success_at,
+    last_error,
+    updated_at
+  )
+  VALUES ($1::uuid, $2::inet, $3, $4, $5, $6, $7, $8, $9, now())
+  ON CONFLICT (device_id) DO UPDATE
+  SET address = EXCLUDED.address,
+      sys_name = EXCLUDED.sys_name,
+      sys_descr = EXCLUDED.sys_descr,
+      sys_object_id = EXCLUDED.sys_object_id,
+      sys_contact = EXCLUDED.sys_contact,
+      sys_location = EXCLUDED.sys_location,
+      last_success_at = EXCLUDED.last_success_at,
+      last_error = EXCLUDED.last_error,
+      updated_at = now()
+  RETURNING device_id,
+            last_success_at,
+            jsonb_build_object(
+              'address', host(address),
+              'sys_name', sys_name,
+              'sys_descr', sys_descr,
+              'sys_object_id', sys_object_id,
+              'sys_contact', sys_contact,
+              'sys_location', sys_location
+            ) AS identity
+), previous AS (
+  SELECT e.after
+  FROM device_events e
+  WHERE e.device_id = $1::uuid
+    AND e.kind = 'snmp'
+  ORDER BY e.occurred_at DESC, e.id DESC
+  LIMIT 1
 )
-VALUES ($1::uuid, $2::inet, $3, $4, $5, $6, $7, $8, $9, now())
-ON CONFLICT (device_id) DO UPDATE
-SET address = EXCLUDED.address,
-    sys_name = EXCLUDED.sys_name,
-    sys_descr = EXCLUDED.sys_descr,
-    sys_object_id = EXCLUDED.sys_object_id,
-    sys_contact = EXCLUDED.sys_contact,
-    sys_location = EXCLUDED.sys_location,
-    last_success_at = EXCLUDED.last_success_at,
-    last_error = EXCLUDED.last_error,
-    updated_at = now();
+INSERT INTO device_events (device_id, kind, actor_type, actor, run_id, reason, before, after, summary)
+SELECT u.device_id,
+       'snmp',
+       COALESCE(NULLIF($10::text, ''), 'system'),
+       $11::text,
+       $12::uuid,
+       $13::text,
+       p.after,
+       u.identity,
+       CASE
+         WHEN p.after IS NULL THEN 'snmp identity observed'
+         ELSE 'snmp identity changed: ' || concat_ws(
+           ', ',
+           CASE WHEN p.after->'address' IS DISTINCT FROM u.identity->'address' THEN 'address' END,
+           CASE WHEN p.after->'sys_name' IS DISTINCT FROM u.identity->'sys_name' THEN 'sys_name' END,
+           CASE WHEN p.after->'sys_descr' IS DISTINCT FROM u.identity->'sys_descr' THEN 'sys_descr' END,
+           CASE WHEN p.after->'sys_object_id' IS DISTINCT FROM u.identity->'sys_object_id' THEN 'sys_object_id' END,
+           CASE WHEN p.after->'sys_contact' IS DISTINCT FROM u.identity->'sys_contact' THEN 'sys_contact' END,
+           CASE WHEN p.after->'sys_location' IS DISTINCT FROM u.identity->'sys_location' THEN 'sys_location' END
+         )
+       END
+FROM upserted u
+LEFT JOIN previous p ON true
+WHERE u.last_success_at IS NOT NULL
+  AND p.after IS DISTINCT FROM u.identity;
 
 -- name: UpsertInterfaceFromSNMP :one
 INSERT INTO interfaces (
@@ -77,7 +144,9 @@ INSERT INTO mac_addresses (device_id, interface_id, mac)
 VALUES ($1::uuid, $2::uuid, $3::macaddr)
 ON CONFLICT (interface_id, mac) WHERE interface_id IS NOT NULL
 DO UPDATE SET device_id = EXCLUDED.device_id,
-              updated_at = now();
+              updated_at = now(),
+              last_seen_at = now(),
+              stale_at = NULL;
 
 -- name: LinkDeviceMACToInterface :execrows
 UPDATE mac_addresses
@@ -19,102 +19,185 @@ WITH events AS (
   FROM mac_observations
   UNION ALL
   SELECT
-    'interface_vlan:' || iv.id::text AS event_id,
-    i.device_id,
-    iv.observed_at AS event_at,
-    'vlan' AS kind,
-    'vlan ' || iv.vlan_id::text AS summary,
+    'device_event:' || e.id::text AS event_id,
+    COALESCE(da.device_id, e.device_id) AS device_id,
+    e.occurred_at AS event_at,
+    CASE e.kind WHEN 'vlans' THEN 'vlan' WHEN 'links' THEN 'link' ELSE e.kind END AS kind,
+    e.summary,
     jsonb_build_object(
-      'interface_id', iv.interface_id,
-      'vlan_id', iv.vlan_id,
-      'role', iv.role,
-      'source', iv.source
+      'actor_type', e.actor_type,
+      'actor', e.actor,
+      'run_id', e.run_id,
+      'reason', e.reason,
+      'before', e.before,
+      'after', e.after
     ) AS details
-  FROM interface_vlans iv
-  JOIN interfaces i ON i.id = iv.interface_id
+  FROM device_events e
+  -- Events of merged devices stay on their own IDs and are shown with the device they were merged into.
+  LEFT JOIN device_aliases da ON da.alias_id = e.device_id
   UNION ALL
   SELECT
-    'link:' || l.id::text AS event_id,
-    l.a_device_id AS device_id,
-    COALESCE(l.observed_at, l.updated_at) AS event_at,
-    'link' AS kind,
-    'link to ' || l.b_device_id::text AS summary,
+    'service_transition:' || t.id::text AS event_id,
+    t.device_id,
+    t.changed_at AS event_at,
+    'service' AS kind,
+    CONCAT(
+      COALESCE(s.name, CONCAT(COALESCE(t.protocol, 'unknown'), '/', COALESCE(t.port::text, '0'))),
+      ' ',
+      CASE t.to_state WHEN 'open' THEN 'opened' ELSE t.to_state END
+    ) AS summary,
     jsonb_build_object(
-      'a_device_id', l.a_device_id,
-      'a_interface_id', l.a_interface_id,
-      'b_device_id', l.b_device_id,
-      'b_interface_id', l.b_interface_id,
-      'link_type', l.link_type,
-      'source', l.source
+      'service_id', t.service_id,
+      'port', t.port,
+      'protocol', t.protocol,
+      'state', t.to_state,
+      'from_state', t.from_state,
+      'source', t.source,
+      'name', s.name
     ) AS details
-  FROM links l
+  FROM service_transitions t
+  JOIN services s ON s.id = t.service_id
   UNION ALL
   SELECT
-    'link:' || l.id::text AS event_id,
-    l.b_device_id AS device_id,
-    COALESCE(l.observed_at, l.updated_at) AS event_at,
-    'link' AS kind,
-    'link to ' || l.a_device_id::text AS summary,
+    'certificate:' || c.id::text AS event_id,
+    c.device_id,
+    c.first_seen_at AS event_at,
+    'certificate' AS kind,
+    CONCAT(
+      COALESCE(s.protocol, 'tcp'),
+      '/',
+      COALESCE(s.port::text, '0'),
+      CASE WHEN prev.fingerprint_sha256 IS NULL THEN ' certificate observed' ELSE ' certificate changed' END,
+      COALESCE(': ' || c.common_name, '')
+    ) AS summary,
     jsonb_build_object(
-      'a_device_id', l.a_device_id,
-      'a_interface_id', l.a_interface_id,
-      'b_device_id', l.b_device_id,
-      'b_interface_id', l.b_interface_id,
-      'link_type', l.link_type,
-      'source', l.source
+      'certificate_id', c.id,
+      'service_id', c.service_id,
+      'port', s.port,
+      'fingerprint_sha256', c.fingerprint_sha256,
+      'previous_fingerprint_sha256', prev.fingerprint_sha256,
+      'subject', c.subject,
+      'issuer', c.issuer,
+      'not_after', c.not_after
     ) AS details
-  FROM links l
+  FROM service_certificates c
+  JOIN services s ON s.id = c.service_id
+  LEFT JOIN LATERAL (
+    SELECT p.fingerprint_sha256
+    FROM service_certificates p
+    WHERE p.service_id = c.service_id
+      AND p.first_seen_at < c.first_seen_at
+    ORDER BY p.first_seen_at DESC
+    LIMIT 1
+  ) prev ON true
   UNION ALL
   SELECT
-    'metadata:' || id::text AS event_id,
-    device_id,
-    updated_at AS event_at,
-    'metadata' AS kind,
-    COALESCE(owner, location, notes, 'metadata updated') AS summary,
-    jsonb_build_object('owner', owner, 'location', location, 'notes', notes) AS details
-  FROM device_metadata
+    'ssh_host_key:' || k.id::text AS event_id,
+    k.device_id,
+    k.first_seen_at AS event_at,
+    'ssh_host_key' AS kind,
+    CONCAT(
+      k.key_type,
+      CASE WHEN prev.fingerprint_sha256 IS NULL THEN ' host key observed' ELSE ' host key changed' END,
+      CASE WHEN shared.device_ids IS NULL THEN '' ELSE ' (also seen on another device: possible duplicate or moved host)' END
+    ) AS summary,
+    jsonb_build_object(
+      'ssh_host_key_id', k.id,
+      'service_id', k.service_id,
+      'key_type', k.key_type,
+      'fingerprint_sha256', k.fingerprint_sha256,
+      'previous_fingerprint_sha256', prev.fingerprint_sha256,
+      'shared_with_device_ids', COALESCE(shared.device_ids, '[]'::jsonb)
+    ) AS details
+  FROM ssh_host_keys k
+  LEFT JOIN LATERAL (
+    SELECT p.fingerprint_sha256
+    FROM ssh_host_keys p
+    WHERE p.device_id = k.device_id
+      AND p.key_type = k.key_type
+      AND p.first_seen_at < k.first_seen_at
+    ORDER BY p.first_seen_at DESC
+    LIMIT 1
+  ) prev ON true
+  LEFT JOIN LATERAL (
+    SELECT jsonb_agg(DISTINCT o.device_id) AS device_ids
+    FROM ssh_host_keys o
+    WHERE o.fingerprint_sha256 = k.fingerprint_sha256
+      AND o.device_id <> k.device_id
+      AND o.first_seen_at <= k.first_seen_at
+  ) shared ON true
   UNION ALL
   SELECT
-    'device_display_name:' || id::text AS event_id,
-    id AS device_id,
-    updated_at AS event_at,
-    'display_name' AS kind,
-    COALESCE(display_name, 'device updated') AS summary,
-    jsonb_build_object('display_name', display_name) AS details
-  FROM devices
+    'link_state:' || t.id::text || ':' || e.device_id::text AS event_id,
+    e.device_id,
+    t.changed_at AS event_at,
+    'adjacency' AS kind,
+    CONCAT(
+      UPPER(COALESCE(t.link_type, 'routing')),
+      ' adjacency ',
+      t.to_state,
+      CASE WHEN t.from_state IS NULL THEN '' ELSE ' (was ' || t.from_state || ')' END
+    ) AS summary,
+    jsonb_build_object(
+      'link_id', t.link_id,
+      'link_type', t.link_type,
+      'peer_device_id', e.peer_device_id,
+      'state', t.to_state,
+      'from_state', t.from_state
+    ) AS details
+  FROM link_state_transitions t
+  CROSS JOIN LATERAL (
+    VALUES (t.a_device_id, t.b_device_id), (t.b_device_id, t.a_device_id)
+  ) AS e(device_id, peer_device_id)
   UNION ALL
   SELECT
-    'snmp:' || device_id::text AS event_id,
-    device_id,
-    updated_at AS event_at,
-    'snmp' AS kind,
-    COALESCE(sys_name, 'snmp updated') AS summary,
+    'device_archive:' || a.id::text AS event_id,
+    a.device_id,
+    a.changed_at AS event_at,
+    'archive' AS kind,
+    CASE a.action
+      WHEN 'archived' THEN 'device archived'
+      WHEN 'restored' THEN 'device restored'
+      ELSE 'archived device seen again (unarchived)'
+    END AS summary,
     jsonb_build_object(
-      'address', address::text,
-      'sys_name', sys_name,
-      'sys_descr', sys_descr,
-      'sys_object_id', sys_object_id,
-      'sys_contact', sys_contact,
-      'sys_location', sys_location,
-      'last_success_at', last_success_at,
-      'last_error', last_error
+      'action', a.action,
+      'actor', a.actor,
+      'reason', a.reason,
+      'run_id', a.run_id
     ) AS details
-  FROM device_snmp
+  FROM device_archive_events a
   UNION ALL
   SELECT
-    'service:' || id::text AS event_id,
-    device_id,
-    observed_at AS event_at,
-    'service' AS kind,
-    COALESCE(name, CONCAT(COALESCE(protocol, 'unknown'), '/', COALESCE(port::text, '0'))) AS summary,
+    'fact_detachment:' || f.id::text AS event_id,
+    f.device_id,
+    f.detached_at AS event_at,
+    'detached' AS kind,
+    f.kind || ' ' || f.value || ' detached' AS summary,
+    jsonb_build_object(
+      'kind', f.kind,
+      'value', f.value,
+      'interface_id', f.interface_id,
+      'last_seen_at', f.last_seen_at,
+      'run_id', f.run_id
+    ) AS details
+  FROM fact_detachments f
+  UNION ALL
+  SELECT
+    'availability:' || v.id::text AS event_id,
+    v.device_id,
+    v.changed_at AS event_at,
+    'availability' AS kind,
+    'device ' || v.state || ' (was ' || v.previous_state || ')' AS summary,
     jsonb_build_object(
-      'port', port,
-      'protocol', protocol,
-      'state', state,
-      'source', source,
-      'name', name
+      'state', v.state,
+      'previous_state', v.previous_state,
+      'run_id', v.run_id,
+      'evidence', v.evidence
     ) AS details
-  FROM services
+  FROM device_availability v
+  -- The first known state of a device is not a change.
+  WHERE v.previous_state IS NOT NULL
 )
 SELECT
   event_id,
@@ -151,102 +234,185 @@ WITH events AS (
   FROM mac_observations
   UNION ALL
   SELECT
-    'interface_vlan:' || iv.id::text AS event_id,
-    i.device_id,
-    iv.observed_at AS event_at,
-    'vlan' AS kind,
-    'vlan ' || iv.vlan_id::text AS summary,
+    'device_event:' || e.id::text AS event_id,
+    COALESCE(da.device_id, e.device_id) AS device_id,
+    e.occurred_at AS event_at,
+    CASE e.kind WHEN 'vlans' THEN 'vlan' WHEN 'links' THEN 'link' ELSE e.kind END AS kind,
+    e.summary,
     jsonb_build_object(
-      'interface_id', iv.interface_id,
-      'vlan_id', iv.vlan_id,
-      'role', iv.role,
-      'source', iv.source
+      'actor_type', e.actor_type,
+      'actor', e.actor,
+      'run_id', e.run_id,
+      'reason', e.reason,
+      'before', e.before,
+      'after', e.after
     ) AS details
-  FROM interface_vlans iv
-  JOIN interfaces i ON i.id = iv.interface_id
+  FROM device_events e
+  -- Events of merged devices stay on their own IDs and are shown with the device they were merged into.
+  LEFT JOIN device_aliases da ON da.alias_id = e.device_id
   UNION ALL
   SELECT
-    'link:' || l.id::text AS event_id,
-    l.a_device_id AS device_id,
-    COALESCE(l.observed_at, l.updated_at) AS event_at,
-    'link' AS kind,
-    'link to ' || l.b_device_id::text AS summary,
+    'service_transition:' || t.id::text AS event_id,
+    t.device_id,
+    t.changed_at AS event_at,
+    'service' AS kind,
+    CONCAT(
+      COALESCE(s.name, CONCAT(COALESCE(t.protocol, 'unknown'), '/', COALESCE(t.port::text, '0'))),
+      ' ',
+      CASE t.to_state WHEN 'open' THEN 'opened' ELSE t.to_state END
+    ) AS summary,
     jsonb_build_object(
-      'a_device_id', l.a_device_id,
-      'a_interface_id', l.a_interface_id,
-      'b_device_id', l.b_device_id,
-      'b_interface_id', l.b_interface_id,
-      'link_type', l.link_type,
-      'source', l.source
+      'service_id', t.service_id,
+      'port', t.port,
+      'protocol', t.protocol,
+      'state', t.to_state,
+      'from_state', t.from_state,
+      'source', t.source,
+      'name', s.name
     ) AS details
-  FROM links l
+  FROM service_transitions t
+  JOIN services s ON s.id = t.service_id
   UNION ALL
   SELECT
-    'link:' || l.id::text AS event_id,
-    l.b_device_id AS device_id,
-    COALESCE(l.observed_at, l.updated_at) AS event_at,
-    'link' AS kind,
-    'link to ' || l.a_device_id::text AS summary,
+    'certificate:' || c.id::text AS event_id,
+    c.device_id,
+    c.first_seen_at AS event_at,
+    'certificate' AS kind,
+    CONCAT(
+      COALESCE(s.protocol, 'tcp'),
+      '/',
+      COALESCE(s.port::text, '0'),
+      CASE WHEN prev.fingerprint_sha256 IS NULL THEN ' certificate observed' ELSE ' certificate changed' END,
+      COALESCE(': ' || c.common_name, '')
+    ) AS summary,
     jsonb_build_object(
-      'a_device_id', l.a_device_id,
-      'a_interface_id', l.a_interface_id,
-      'b_device_id', l.b_device_id,
-      'b_interface_id', l.b_interface_id,
-      'link_type', l.link_type,
-      'source', l.source
+      'certificate_id', c.id,
+      'service_id', c.service_id,
+      'port', s.port,
+      'fingerprint_sha256', c.fingerprint_sha256,
+      'previous_fingerprint_sha256', prev.fingerprint_sha256,
+      'subject', c.subject,
+      'issuer', c.issuer,
+      'not_after', c.not_after
     ) AS details
-  FROM links l
+  FROM service_certificates c
+  JOIN services s ON s.id = c.service_id
+  LEFT JOIN LATERAL (
+    SELECT p.fingerprint_sha256
+    FROM service_certificates p
+    WHERE p.service_id = c.service_id
+      AND p.first_seen_at < c.first_seen_at
+    ORDER BY p.first_seen_at DESC
+    LIMIT 1
+  ) prev ON true
   UNION ALL
   SELECT
-    'metadata:' || id::text AS event_id,
-    device_id,
-    updated_at AS event_at,
-    'metadata' AS kind,
-    COALESCE(owner, location, notes, 'metadata updated') AS summary,
-    jsonb_build_object('owner', owner, 'location', location, 'notes', notes) AS details
-  FROM device_metadata
+    'ssh_host_key:' || k.id::text AS event_id,
+    k.device_id,
+    k.first_seen_at AS event_at,
+    'ssh_host_key' AS kind,
+    CONCAT(
+      k.key_type,
+      CASE WHEN prev.fingerprint_sha256 IS NULL THEN ' host key observed' ELSE ' host key changed' END,
+      CASE WHEN shared.device_ids IS NULL THEN '' ELSE ' (also seen on another device: possible duplicate or moved host)' END
+    ) AS summary,
+    jsonb_build_object(
+      'ssh_host_key_id', k.id,
+      'service_id', k.service_id,
+      'key_type', k.key_type,
+      'fingerprint_sha256', k.fingerprint_sha256,
+      'previous_fingerprint_sha256', prev.fingerprint_sha256,
+      'shared_with_device_ids', COALESCE(shared.device_ids, '[]'::jsonb)
+    ) AS details
+  FROM ssh_host_keys k
+  LEFT JOIN LATERAL (
+    SELECT p.fingerprint_sha256
+    FROM ssh_host_keys p
+    WHERE p.device_id = k.device_id
+      AND p.key_type = k.key_type
+      AND p.first_seen_at < k.first_seen_at
+    ORDER BY p.first_seen_at DESC
+    LIMIT 1
+  ) prev ON true
+  LEFT JOIN LATERAL (
+    SELECT jsonb_agg(DISTINCT o.device_id) AS device_ids
+    FROM ssh_host_keys o
+    WHERE o.fingerprint_sha256 = k.fingerprint_sha256
+      AND o.device_id <> k.device_id
+      AND o.first_seen_at <= k.first_seen_at
+  ) shared ON true
   UNION ALL
   SELECT
-    'device_display_name:' || id::text AS event_id,
-    id AS device_id,
-    updated_at AS event_at,
-    'display_name' AS kind,
-    COALESCE(display_name, 'device updated') AS summary,
-    jsonb_build_object('display_name', display_name) AS details
-  FROM devices
+    'link_state:' || t.id::text || ':' || e.device_id::text AS event_id,
+    e.device_id,
+    t.changed_at AS event_at,
+    'adjacency' AS kind,
+    CONCAT(
+      UPPER(COALESCE(t.link_type, 'routing')),
+      ' adjacency ',
+      t.to_state,
+      CASE WHEN t.from_state IS NULL THEN '' ELSE ' (was ' || t.from_state || ')' END
+    ) AS summary,
+    jsonb_build_object(
+      'link_id', t.link_id,
+      'link_type', t.link_type,
+      'peer_device_id', e.peer_device_id,
+      'state', t.to_state,
+      'from_state', t.from_state
+    ) AS details
+  FROM link_state_transitions t
+  CROSS JOIN LATERAL (
+    VALUES (t.a_device_id, t.b_device_id), (t.b_device_id, t.a_device_id)
+  ) AS e(device_id, peer_device_id)
   UNION ALL
   SELECT
-    'snmp:' || device_id::text AS event_id,
-    device_id,
-    updated_at AS event_at,
-    'snmp' AS kind,
-    COALESCE(sys_name, 'snmp updated') AS summary,
+    'device_archive:' || a.id::text AS event_id,
+    a.device_id,
+    a.changed_at AS event_at,
+    'archive' AS kind,
+    CASE a.action
+      WHEN 'archived' THEN 'device archived'
+      WHEN 'restored' THEN 'device restored'
+      ELSE 'archived device seen again (unarchived)'
+    END AS summary,
     jsonb_build_object(
-      'address', address::text,
-      'sys_name', sys_name,
-      'sys_descr', sys_descr,
-      'sys_object_id', sys_object_id,
-      'sys_contact', sys_contact,
-      'sys_location', sys_location,
-      'last_success_at', last_success_at,
-      'last_error', last_error
+      'action', a.action,
+      'actor', a.actor,
+      'reason', a.reason,
+      'run_id', a.run_id
     ) AS details
-  FROM device_snmp
+  FROM device_archive_events a
   UNION ALL
   SELECT
-    'service:' || id::text AS event_id,
-    device_id,
-    observed_at AS event_at,
-    'service' AS kind,
-    COALESCE(name, CONCAT(COALESCE(protocol, 'unknown'), '/', COALESCE(port::text, '0'))) AS summary,
+    'fact_detachment:' || f.id::text AS event_id,
+    f.device_id,
+    f.detached_at AS event_at,
+    'detached' AS kind,
+    f.kind || ' ' || f.value || ' detached' AS summary,
+    jsonb_build_object(
+      'kind', f.kind,
+      'value', f.value,
+      'interface_id', f.interface_id,
+      'last_seen_at', f.last_seen_at,
+      'run_id', f.run_id
+    ) AS details
+  FROM fact_detachments f
+  UNION ALL
+  SELECT
+    'availability:' || v.id::text AS event_id,
+    v.device_id,
+    v.changed_at AS event_at,
+    'availability' AS kind,
+    'device ' || v.state || ' (was ' || v.previous_state || ')' AS summary,
     jsonb_build_object(
-      'port', port,
-      'protocol', protocol,
-      'state', state,
-      'source', source,
-      'name', name
+      'state', v.state,
+      'previous_state', v.previous_state,
+      'run_id', v.run_id,
+      'evidence', v.evidence
     ) AS details
-  FROM services
+  FROM device_availability v
+  -- The first known state of a device is not a change.
+  WHERE v.previous_state IS NOT NULL
 )
 SELECT
   event_id,
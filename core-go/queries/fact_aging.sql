@@ -0,0 +1,61 @@
+-- name: MarkStaleIPAddresses :execrows
+-- Only addresses discovery keeps re-observing age; imported ones are left alone.
+UPDATE ip_addresses
+SET stale_at = now()
+WHERE stale_at IS NULL
+  AND source = 'discovery'
+  AND last_seen_at < $1;
+
+-- name: MarkStaleMACAddresses :execrows
+UPDATE mac_addresses
+SET stale_at = now()
+WHERE stale_at IS NULL
+  AND source = 'discovery'
+  AND last_seen_at < $1;
+
+-- name: DetachStaleIPAddresses :execrows
+-- Archived devices keep their facts so they can be recognised when they come back.
+WITH expired AS (
+  SELECT a.id,
+         d.id AS device_id,
+         host(a.ip) AS value,
+         a.interface_id,
+         a.last_seen_at
+  FROM ip_addresses a
+  LEFT JOIN interfaces i ON i.id = a.interface_id
+  JOIN devices d ON d.id = COALESCE(a.device_id, i.device_id)
+  WHERE a.last_seen_at < $1
+    AND a.source = 'discovery'
+    AND d.archived_at IS NULL
+), detached AS (
+  DELETE FROM ip_addresses a
+  USING expired x
+  WHERE a.id = x.id
+  RETURNING x.device_id, x.value, x.interface_id, x.last_seen_at
+)
+INSERT INTO fact_detachments (device_id, kind, value, interface_id, last_seen_at, run_id)
+SELECT device_id, 'ip', value, interface_id, last_seen_at, $2::uuid
+FROM detached;
+
+-- name: DetachStaleMACAddresses :execrows
+WITH expired AS (
+  SELECT m.id,
+         d.id AS device_id,
+         m.mac::text AS value,
+         m.interface_id,
+         m.last_seen_at
+  FROM mac_addresses m
+  LEFT JOIN interfaces i ON i.id = m.interface_id
+  JOIN devices d ON d.id = COALESCE(m.device_id, i.device_id)
+  WHERE m.last_seen_at < $1
+    AND m.source = 'discovery'
+    AND d.archived_at IS NULL
+), detached AS (
+  DELETE FROM mac_addresses m
+  USING expired x
+  WHERE m.id = x.id
+  RETURNING x.device_id, x.value, x.interface_id, x.last_seen_at
+)
+INSERT INTO fact_detachments (device_id, kind, value, interface_id, last_seen_at, run_id)
+SELECT device_id, 'mac', value, interface_id, last_seen_at, $2::uuid
+FROM detached;
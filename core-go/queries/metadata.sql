@@ -1,28 +1,88 @@
 -- name: UpsertDeviceMetadata :one
-INSERT INTO device_metadata (device_id, owner, location, notes)
-VALUES ($1, $2, $3, $4)
-ON CONFLICT (device_id) DO UPDATE
-SET owner = EXCLUDED.owner,
-    location = EXCLUDED.location,
-    notes = EXCLUDED.notes,
-    updated_at = now()
-RETURNING device_id, owner, location, notes;
+-- Replaces a device's metadata. Changed fields are recorded as a metadata event by actor $5..$8.
+WITH prev AS (
+  SELECT device_id, owner, location, notes
+  FROM device_metadata
+  WHERE device_id = $1::uuid
+  FOR UPDATE
+), upserted AS (
+  INSERT INTO device_metadata (device_id, owner, location, notes)
+  SELECT $1::uuid, $2::text, $3::text, $4::text
+  FROM (SELECT count(*) FROM prev) locked
+  ON CONFLICT (device_id) DO UPDATE
+  SET owner = EXCLUDED.owner,
+      location = EXCLUDED.location,
+      notes = EXCLUDED.notes,
+      updated_at = now()
+  RETURNING device_id, owner, location, notes
+), event AS (
+  INSERT INTO device_events (device_id, kind, actor_type, actor, run_id, reason, before, after, summary)
+  SELECT u.device_id,
+         'metadata',
+         COALESCE(NULLIF($5::text, ''), 'system'),
+         $6::text,
+         $7::uuid,
+         $8::text,
+         CASE WHEN p.device_id IS NOT NULL THEN jsonb_build_object('owner', p.owner, 'location', p.location, 'notes', p.notes) END,
+         jsonb_build_object('owner', u.owner, 'location', u.location, 'notes', u.notes),
+         'metadata updated: ' || concat_ws(
+           ', ',
+           CASE WHEN p.owner IS DISTINCT FROM u.owner THEN 'owner' END,
+           CASE WHEN p.location IS DISTINCT FROM u.location THEN 'location' END,
+           CASE WHEN p.notes IS DISTINCT FROM u.notes THEN 'notes' END
+         )
+  FROM upserted u
+  LEFT JOIN prev p ON true
+  WHERE (p.owner, p.location, p.notes) IS DISTINCT FROM (u.owner, u.location, u.notes)
+)
+SELECT device_id, owner, location, notes
+FROM upserted;
 
 -- name: UpsertDeviceMetadataFillBlank :one
-INSERT INTO device_metadata (device_id, owner, location, notes)
-VALUES ($1, $2, $3, $4)
-ON CONFLICT (device_id) DO UPDATE
-SET owner = CASE
-              WHEN device_metadata.owner IS NULL OR btrim(device_metadata.owner) = '' THEN EXCLUDED.owner
-              ELSE device_metadata.owner
-            END,
-    location = CASE
-                 WHEN device_metadata.location IS NULL OR btrim(device_metadata.location) = '' THEN EXCLUDED.location
-                 ELSE device_metadata.location
-               END,
-    notes = CASE
-              WHEN device_metadata.notes IS NULL OR btrim(device_metadata.notes) = '' THEN EXCLUDED.notes
-              ELSE device_metadata.notes
-            END,
-    updated_at = now()
-RETURNING device_id, owner, location, notes;
+-- Like UpsertDeviceMetadata, but only fills fields that are empty.
+WITH prev AS (
+  SELECT device_id, owner, location, notes
+  FROM device_metadata
+  WHERE device_id = $1::uuid
+  FOR UPDATE
+), upserted AS (
+  INSERT INTO device_metadata (device_id, owner, location, notes)
+  SELECT $1::uuid, $2::text, $3::text, $4::text
+  FROM (SELECT count(*) FROM prev) locked
+  ON CONFLICT (device_id) DO UPDATE
+  SET owner = CASE
+                WHEN device_metadata.owner IS NULL OR btrim(device_metadata.owner) = '' THEN EXCLUDED.owner
+                ELSE device_metadata.owner
+              END,
+      location = CASE
+                   WHEN device_metadata.location IS NULL OR btrim(device_metadata.location) = '' THEN EXCLUDED.location
+                   ELSE device_metadata.location
+                 END,
+      notes = CASE
+                WHEN device_metadata.notes IS NULL OR btrim(device_metadata.notes) = '' THEN EXCLUDED.notes
+                ELSE device_metadata.notes
+              END,
+      updated_at = now()
+  RETURNING device_id, owner, location, notes
+), event AS (
+  INSERT INTO device_events (device_id, kind, actor_type, actor, run_id, reason, before, after, summary)
+  SELECT u.device_id,
+         'metadata',
+         COALESCE(NULLIF($5::text, ''), 'system'),
+         $6::text,
+         $7::uuid,
+         $8::text,
+         CASE WHEN p.device_id IS NOT NULL THEN jsonb_build_object('owner', p.owner, 'location', p.location, 'notes', p.notes) END,
+         jsonb_build_object('owner', u.owner, 'location', u.location, 'notes', u.notes),
+         'metadata updated: ' || concat_ws(
+           ', ',
+           CASE WHEN p.owner IS DISTINCT FROM u.owner THEN 'owner' END,
+           CASE WHEN p.location IS DISTINCT FROM u.location THEN 'location' END,
+           CASE WHEN p.notes IS DISTINCT FROM u.notes THEN 'notes' END
+         )
+  FROM upserted u
+  LEFT JOIN prev p ON true
+  WHERE (p.owner, p.location, p.notes) IS DISTINCT FROM (u.owner, u.location, u.notes)
+)
+SELECT device_id, owner, location, notes
+FROM upserted;
@@ -0,0 +1,192 @@
+-- name: SyncTaggedDeviceMonitors :one
+-- Makes the "tag" monitors match the active devices carrying any of $1, probing with method $2.
+-- Manual monitors are never touched, so a manual row (even a disabled one) overrides the tag selection.
+WITH tagged AS (
+  SELECT DISTINCT dt.device_id
+  FROM device_tags dt
+  JOIN devices d ON d.id = dt.device_id
+  WHERE dt.tag = ANY($1::text[])
+    AND d.archived_at IS NULL
+), removed AS (
+  DELETE FROM device_monitors m
+  WHERE m.source = 'tag'
+    AND m.device_id NOT IN (SELECT device_id FROM tagged)
+  RETURNING m.device_id
+), upserted AS (
+  INSERT INTO device_monitors (device_id, source, method)
+  SELECT device_id, 'tag', $2
+  FROM tagged
+  ON CONFLICT (device_id) DO UPDATE
+  SET method = EXCLUDED.method,
+      port = NULL,
+      updated_at = now()
+  WHERE device_monitors.source = 'tag'
+    AND device_monitors.method <> EXCLUDED.method
+  RETURNING device_id
+)
+SELECT (SELECT count(*) FROM removed) + (SELECT count(*) FROM upserted);
+
+-- name: ListMonitorTargets :many
+-- Enabled monitors of active devices with the address to probe: the freshest non-stale IP, falling back to
+-- stale ones. A tcp monitor without a port uses the device's lowest open TCP service port.
+SELECT m.device_id,
+       m.method,
+       COALESCE(
+         m.port,
+         (
+           SELECT MIN(s.port)
+           FROM services s
+           WHERE s.device_id = m.device_id
+             AND s.protocol = 'tcp'
+             AND s.state = 'open'
+         )
+       ) AS port,
+       (
+         SELECT host(ia.ip)
+         FROM ip_addresses ia
+         LEFT JOIN interfaces i ON i.id = ia.interface_id
+         WHERE ia.device_id = m.device_id OR i.device_id = m.device_id
+         ORDER BY ia.stale_at IS NOT NULL, ia.last_seen_at DESC, family(ia.ip) ASC, ia.ip ASC
+         LIMIT 1
+       ) AS address,
+       m.consecutive_failures
+FROM device_monitors m
+JOIN devices d ON d.id = m.device_id
+WHERE m.enabled
+  AND d.archived_at IS NULL
+ORDER BY m.device_id ASC;
+
+-- name: RecordReachabilitySample :one
+-- Stores one probe, folds it into its hourly rollup and updates the monitor status in a single statement.
+WITH sample AS (
+  INSERT INTO reachability_samples (device_id, probed_at, method, address, port, success, rtt_ms)
+  VALUES ($1::uuid, $2::timestamptz, $3, $4, $5, $6::boolean, $7::double precision)
+  RETURNING device_id
+), rollup AS (
+  INSERT INTO reachability_rollups (device_id, bucket_start, probes, failures, rtt_sum_ms, rtt_min_ms, rtt_max_ms)
+  VALUES (
+    $1::uuid,
+    date_trunc('hour', $2::timestamptz, 'UTC'),
+    1,
+    CASE WHEN $6::boolean THEN 0 ELSE 1 END,
+    COALESCE($7::double precision, 0),
+    $7::double precision,
+    $7::double precision
+  )
+  ON CONFLICT (device_id, bucket_start) DO UPDATE
+  SET probes = reachability_rollups.probes + 1,
+      failures = reachability_rollups.failures + EXCLUDED.failures,
+      rtt_sum_ms = reachability_rollups.rtt_sum_ms + EXCLUDED.rtt_sum_ms,
+      rtt_min_ms = LEAST(reachability_rollups.rtt_min_ms, EXCLUDED.rtt_min_ms),
+      rtt_max_ms = GREATEST(reachability_rollups.rtt_max_ms, EXCLUDED.rtt_max_ms)
+  RETURNING device_id
+)
+UPDATE device_monitors
+SET last_probe_at = $2::timestamptz,
+    last_success_at = CASE WHEN $6::boolean THEN $2::timestamptz ELSE last_success_at END,
+    last_rtt_ms = CASE WHEN $6::boolean THEN $7::double precision ELSE last_rtt_ms END,
+    consecutive_failures = CASE WHEN $6::boolean THEN 0 ELSE consecutive_failures + 1 END
+WHERE device_id = $1::uuid
+RETURNING consecutive_failures, last_success_at;
+
+-- name: RecordMonitorAvailability :execrows
+-- Writes a transition to $2 unless that is already the device's latest state. changed_at never moves before
+-- the previous transition, so the history stays ordered when discovery and the monitor interleave.
+INSERT INTO device_availability (device_id, state, previous_state, changed_at, evidence)
+SELECT $1::uuid, $2::text, l.state, GREATEST($3::timestamptz, l.changed_at), $4::jsonb
+FROM (SELECT 1) one
+LEFT JOIN LATERAL (
+  SELECT state, changed_at
+  FROM device_availability
+  WHERE device_id = $1::uuid
+  ORDER BY changed_at DESC, id DESC
+  LIMIT 1
+) l ON true
+WHERE l.state IS DISTINCT FROM $2::text;
+
+-- name: DeleteReachabilitySamplesBefore :execrows
+DELETE FROM reachability_samples
+WHERE id IN (
+  SELECT id
+  FROM reachability_samples
+  WHERE probed_at < $1
+  ORDER BY probed_at ASC
+  LIMIT $2
+);
+
+-- name: DeleteReachabilityRollupsBefore :execrows
+DELETE FROM reachability_rollups
+WHERE (device_id, bucket_start) IN (
+  SELECT device_id, bucket_start
+  FROM reachability_rollups
+  WHERE bucket_start < $1
+  ORDER BY bucket_start ASC
+  LIMIT $2
+);
+
+-- name: GetDeviceMonitor :one
+SELECT device_id,
+       source,
+       enabled,
+       method,
+       port,
+       last_probe_at,
+       last_success_at,
+       last_rtt_ms,
+       consecutive_failures,
+       created_at,
+       updated_at
+FROM device_monitors
+WHERE device_id = $1;
+
+-- name: UpsertDeviceMonitor :one
+-- Saving through the API turns a tag monitor into a manual one. Changing the probe resets the failure streak.
+INSERT INTO device_monitors (device_id, source, enabled, method, port)
+VALUES ($1, 'manual', $2, $3, $4)
+ON CONFLICT (device_id) DO UPDATE
+SET source = 'manual',
+    enabled = EXCLUDED.enabled,
+    method = EXCLUDED.method,
+    port = EXCLUDED.port,
+    consecutive_failures = CASE
+      WHEN device_monitors.method = EXCLUDED.method AND device_monitors.port IS NOT DISTINCT FROM EXCLUDED.port
+        THEN device_monitors.consecutive_failures
+      ELSE 0
+    END,
+    updated_at = now()
+RETURNING device_id,
+          source,
+          enabled,
+          method,
+          port,
+          last_probe_at,
+          last_success_at,
+          last_rtt_ms,
+          consecutive_failures,
+          created_at,
+          updated_at;
+
+-- name: DeleteDeviceMonitor :execrows
+DELETE FROM device_monitors
+WHERE device_id = $1;
+
+-- name: ListReachabilitySamples :many
+SELECT probed_at, method, address, port, success, rtt_ms
+FROM reachability_samples
+WHERE device_id = $1
+  AND probed_at >= $2
+  AND probed_at < $3
+ORDER BY probed_at ASC, id ASC;
+
+-- name: ListReachabilityRollups :many
+SELECT bucket_start,
+       probes,
+       failures,
+       CASE WHEN probes > failures THEN rtt_sum_ms / (probes - failures) END AS rtt_avg_ms,
+       rtt_min_ms,
+       rtt_max_ms
+FROM reachability_rollups
+WHERE device_id = $1
+  AND bucket_start >= $2
+  AND bucket_start < $3
+ORDER BY bucket_start ASC;
@@ -0,0 +1,59 @@
+-- name: UpsertDeviceOSGuess :exec
+INSERT INTO device_os_guesses (
+  device_id,
+  source,
+  rank,
+  name,
+  accuracy,
+  vendor,
+  os_family,
+  os_generation,
+  device_type,
+  cpe,
+  observed_at
+)
+VALUES (
+  $1::uuid,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7,
+  $8,
+  $9,
+  $10::text[],
+  $11::timestamptz
+)
+ON CONFLICT (device_id, source, rank)
+DO UPDATE
+SET name = EXCLUDED.name,
+    accuracy = EXCLUDED.accuracy,
+    vendor = EXCLUDED.vendor,
+    os_family = EXCLUDED.os_family,
+    os_generation = EXCLUDED.os_generation,
+    device_type = EXCLUDED.device_type,
+    cpe = EXCLUDED.cpe,
+    observed_at = EXCLUDED.observed_at,
+    updated_at = now();
+
+-- name: DeleteDeviceOSGuessesAboveRank :exec
+DELETE FROM device_os_guesses
+WHERE device_id = $1::uuid
+  AND source = $2
+  AND rank > $3;
+
+-- name: ListDeviceOSGuesses :many
+SELECT source,
+       rank,
+       name,
+       accuracy,
+       vendor,
+       os_family,
+       os_generation,
+       device_type,
+       cpe,
+       observed_at
+FROM device_os_guesses
+WHERE device_id = $1::uuid
+ORDER BY source ASC, rank ASC;
@@ -0,0 +1,98 @@
+-- name: UpsertInterfacePoE :exec
+INSERT INTO interface_poe (
+  interface_id,
+  device_id,
+  pse_group,
+  pse_port,
+  admin_enabled,
+  detection_status,
+  power_class,
+  power_mw,
+  observed_at
+)
+VALUES ($1::uuid, $2::uuid, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (interface_id) DO UPDATE
+SET device_id = EXCLUDED.device_id,
+    pse_group = EXCLUDED.pse_group,
+    pse_port = EXCLUDED.pse_port,
+    admin_enabled = EXCLUDED.admin_enabled,
+    detection_status = EXCLUDED.detection_status,
+    power_class = EXCLUDED.power_class,
+    power_mw = EXCLUDED.power_mw,
+    observed_at = EXCLUDED.observed_at,
+    updated_at = now();
+
+-- name: DeleteStaleInterfacePoE :execrows
+DELETE FROM interface_poe
+WHERE device_id = $1::uuid
+  AND observed_at < $2;
+
+-- name: SyncPoEPoweredDevices :execrows
+-- Points each PoE port of the given switches that is delivering power at the device on the other end of
+-- its link (manual, then LLDP/CDP, then inferred by confidence, then most recent). Routing adjacencies
+-- carry no interfaces and are ignored. Ports that are not delivering power, or have no link, are cleared.
+UPDATE interface_poe p
+SET powered_device_id = m.peer_device_id,
+    updated_at = now()
+FROM (
+  SELECT ip.interface_id,
+         (
+           SELECT CASE WHEN l.a_interface_id = ip.interface_id THEN l.b_device_id ELSE l.a_device_id END
+           FROM links l
+           WHERE (l.a_interface_id = ip.interface_id OR l.b_interface_id = ip.interface_id)
+             AND COALESCE(l.link_type, '') NOT IN ('ospf', 'bgp')
+             AND l.a_device_id <> l.b_device_id
+           ORDER BY CASE l.source WHEN 'manual' THEN 0 WHEN 'lldp' THEN 1 WHEN 'cdp' THEN 1 ELSE 2 END,
+                    l.confidence DESC NULLS LAST,
+                    l.observed_at DESC NULLS LAST
+           LIMIT 1
+         ) AS peer_device_id
+  FROM interface_poe ip
+  WHERE ip.device_id = ANY($1::uuid[])
+    AND ip.detection_status = 'delivering_power'
+  UNION ALL
+  SELECT ip.interface_id, NULL::uuid
+  FROM interface_poe ip
+  WHERE ip.device_id = ANY($1::uuid[])
+    AND ip.detection_status <> 'delivering_power'
+) m
+WHERE p.interface_id = m.interface_id
+  AND p.powered_device_id IS DISTINCT FROM m.peer_device_id;
+
+-- name: ListPoweredDevices :many
+SELECT p.device_id::text,
+       ds.display_name,
+       p.powered_device_id::text,
+       dp.display_name,
+       p.interface_id::text,
+       i.name,
+       p.admin_enabled,
+       p.detection_status,
+       p.power_class,
+       p.power_mw,
+       p.observed_at
+FROM interface_poe p
+JOIN devices ds ON ds.id = p.device_id
+JOIN devices dp ON dp.id = p.powered_device_id
+JOIN interfaces i ON i.id = p.interface_id
+WHERE p.device_id = $1::uuid
+ORDER BY i.ifindex ASC NULLS LAST, i.name ASC NULLS LAST;
+
+-- name: ListPowerSources :many
+SELECT p.device_id::text,
+       ds.display_name,
+       p.powered_device_id::text,
+       dp.display_name,
+       p.interface_id::text,
+       i.name,
+       p.admin_enabled,
+       p.detection_status,
+       p.power_class,
+       p.power_mw,
+       p.observed_at
+FROM interface_poe p
+JOIN devices ds ON ds.id = p.device_id
+JOIN devices dp ON dp.id = p.powered_device_id
+JOIN interfaces i ON i.id = p.interface_id
+WHERE p.powered_device_id = $1::uuid
+ORDER BY p.observed_at DESC;
@@ -0,0 +1,194 @@
+-- name: ListRetentionPolicies :many
+SELECT table_name,
+       keep_raw_days,
+       keep_daily_days,
+       updated_at
+FROM retention_policies
+ORDER BY table_name ASC;
+
+-- name: UpsertRetentionPolicy :one
+INSERT INTO retention_policies (table_name, keep_raw_days, keep_daily_days)
+VALUES ($1, $2, $3)
+ON CONFLICT (table_name) DO UPDATE
+SET keep_raw_days = EXCLUDED.keep_raw_days,
+    keep_daily_days = EXCLUDED.keep_daily_days,
+    updated_at = now()
+RETURNING table_name, keep_raw_days, keep_daily_days, updated_at;
+
+-- name: DeleteRetentionPolicy :execrows
+DELETE FROM retention_policies
+WHERE table_name = $1;
+
+-- name: DeleteIPObservationsBefore :execrows
+DELETE FROM ip_observations
+WHERE id IN (
+  SELECT id
+  FROM ip_observations
+  WHERE observed_at < $1
+  ORDER BY observed_at ASC
+  LIMIT $2
+);
+
+-- name: ThinIPObservations :one
+-- Scans up to $5 observations in [$1, $2) after the ($3, $4) keyset and deletes those that are neither the first
+-- nor the last of their device, address and UTC day. Returns the rows scanned and deleted and the key to resume from.
+WITH page AS (
+  SELECT id,
+         device_id,
+         ip,
+         observed_at,
+         date_trunc('day', observed_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS day_start
+  FROM ip_observations
+  WHERE observed_at >= $1
+    AND observed_at < $2
+    AND (observed_at, id) > ($3::timestamptz, $4::bigint)
+  ORDER BY observed_at ASC, id ASC
+  LIMIT $5
+),
+thinned AS (
+  DELETE FROM ip_observations o
+  USING page p
+  WHERE o.id = p.id
+    AND EXISTS (
+      SELECT 1
+      FROM ip_observations e
+      WHERE e.device_id = p.device_id
+        AND e.ip = p.ip
+        AND e.observed_at >= p.day_start
+        AND (e.observed_at, e.id) < (p.observed_at, p.id)
+    )
+    AND EXISTS (
+      SELECT 1
+      FROM ip_observations e
+      WHERE e.device_id = p.device_id
+        AND e.ip = p.ip
+        AND e.observed_at < p.day_start + interval '1 day'
+        AND (e.observed_at, e.id) > (p.observed_at, p.id)
+    )
+  RETURNING o.id
+),
+last AS (
+  SELECT observed_at, id
+  FROM page
+  ORDER BY observed_at DESC, id DESC
+  LIMIT 1
+)
+SELECT (SELECT count(*) FROM page) AS scanned,
+       (SELECT count(*) FROM thinned) AS thinned,
+       COALESCE((SELECT observed_at FROM last), $3::timestamptz) AS last_observed_at,
+       COALESCE((SELECT id FROM last), $4::bigint) AS last_id;
+
+-- name: CountIPObservationsRetention :one
+-- Dry run: $1 is the drop cutoff, $2 the end of the daily tier. Later rows of a day still count as its last.
+SELECT count(*) AS total,
+       count(*) FILTER (WHERE observed_at < $1) AS to_drop,
+       (
+         SELECT count(*)
+         FROM (
+           SELECT observed_at,
+                  row_number() OVER (day_rows ORDER BY observed_at ASC, id ASC) AS from_first,
+                  row_number() OVER (day_rows ORDER BY observed_at DESC, id DESC) AS from_last
+           FROM ip_observations
+           WHERE observed_at >= $1
+             AND observed_at < $2 + interval '1 day'
+           WINDOW day_rows AS (PARTITION BY device_id, ip, (observed_at AT TIME ZONE 'UTC')::date)
+         ) ranked
+         WHERE observed_at < $2
+           AND from_first > 1
+           AND from_last > 1
+       ) AS to_thin
+FROM ip_observations;
+
+-- name: DeleteMACObservationsBefore :execrows
+DELETE FROM mac_observations
+WHERE id IN (
+  SELECT id
+  FROM mac_observations
+  WHERE observed_at < $1
+  ORDER BY observed_at ASC
+  LIMIT $2
+);
+
+-- name: ThinMACObservations :one
+WITH page AS (
+  SELECT id,
+         device_id,
+         mac,
+         observed_at,
+         date_trunc('day', observed_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS day_start
+  FROM mac_observations
+  WHERE observed_at >= $1
+    AND observed_at < $2
+    AND (observed_at, id) > ($3::timestamptz, $4::bigint)
+  ORDER BY observed_at ASC, id ASC
+  LIMIT $5
+),
+thinned AS (
+  DELETE FROM mac_observations o
+  USING page p
+  WHERE o.id = p.id
+    AND EXISTS (
+      SELECT 1
+      FROM mac_observations e
+      WHERE e.device_id = p.device_id
+        AND e.mac = p.mac
+        AND e.observed_at >= p.day_start
+        AND (e.observed_at, e.id) < (p.observed_at, p.id)
+    )
+    AND EXISTS (
+      SELECT 1
+      FROM mac_observations e
+      WHERE e.device_id = p.device_id
+        AND e.mac = p.mac
+        AND e.observed_at < p.day_start + interval '1 day'
+        AND (e.observed_at, e.id) > (p.observed_at, p.id)
+    )
+  RETURNING o.id
+),
+last AS (
+  SELECT observed_at, id
+  FROM page
+  ORDER BY observed_at DESC, id DESC
+  LIMIT 1
+)
+SELECT (SELECT count(*) FROM page) AS scanned,
+       (SELECT count(*) FROM thinned) AS thinned,
+       COALESCE((SELECT observed_at FROM last), $3::timestamptz) AS last_observed_at,
+       COALESCE((SELECT id FROM last), $4::bigint) AS last_id;
+
+-- name: CountMACObservationsRetention :one
+SELECT count(*) AS total,
+       count(*) FILTER (WHERE observed_at < $1) AS to_drop,
+       (
+         SELECT count(*)
+         FROM (
+           SELECT observed_at,
+                  row_number() OVER (day_rows ORDER BY observed_at ASC, id ASC) AS from_first,
+                  row_number() OVER (day_rows ORDER BY observed_at DESC, id DESC) AS from_last
+           FROM mac_observations
+           WHERE observed_at >= $1
+             AND observed_at < $2 + interval '1 day'
+           WINDOW day_rows AS (PARTITION BY device_id, mac, (observed_at AT TIME ZONE 'UTC')::date)
+         ) ranked
+         WHERE observed_at < $2
+           AND from_first > 1
+           AND from_last > 1
+       ) AS to_thin
+FROM mac_observations;
+
+-- name: DeleteDiscoveryRunLogsBefore :execrows
+DELETE FROM discovery_run_logs
+WHERE id IN (
+  SELECT id
+  FROM discovery_run_logs
+  WHERE created_at < $1
+  ORDER BY created_at ASC
+  LIMIT $2
+);
+
+-- name: CountDiscoveryRunLogsRetention :one
+-- Run logs have no daily tier.
+SELECT count(*) AS total,
+       count(*) FILTER (WHERE created_at < $1) AS to_drop,
+       0::bigint AS to_thin
+FROM discovery_run_logs;
@@ -0,0 +1,61 @@
+-- name: UpsertRoutingAdjacency :execrows
+-- Upserts an OSPF/BGP adjacency link (one row per device pair and protocol) and appends a
+-- link_state_transitions row when its state changed, including the first sighting. Returns the number of
+-- transitions written.
+WITH prev AS (
+  SELECT l.state
+  FROM links l
+  WHERE l.link_key = $1
+),
+upserted AS (
+  INSERT INTO links (
+    link_key,
+    a_device_id,
+    b_device_id,
+    link_type,
+    source,
+    state,
+    a_as,
+    b_as,
+    area,
+    last_change_at,
+    observed_at
+  )
+  VALUES ($1, $2::uuid, $3::uuid, $4, 'snmp', $5, $6, $7, $8, COALESCE($9::timestamptz, $10::timestamptz), $10::timestamptz)
+  ON CONFLICT (link_key) DO UPDATE
+  SET state = EXCLUDED.state,
+      a_as = COALESCE(EXCLUDED.a_as, links.a_as),
+      b_as = COALESCE(EXCLUDED.b_as, links.b_as),
+      area = COALESCE(EXCLUDED.area, links.area),
+      last_change_at = CASE
+        WHEN $9::timestamptz IS NOT NULL THEN $9::timestamptz
+        WHEN links.state IS DISTINCT FROM EXCLUDED.state THEN EXCLUDED.observed_at
+        ELSE links.last_change_at
+      END,
+      observed_at = EXCLUDED.observed_at,
+      updated_at = now()
+  RETURNING id, state, last_change_at
+)
+INSERT INTO link_state_transitions (link_id, a_device_id, b_device_id, link_type, from_state, to_state, changed_at)
+SELECT u.id, $2::uuid, $3::uuid, $4, (SELECT p.state FROM prev p), u.state, COALESCE(u.last_change_at, $10::timestamptz)
+FROM upserted u
+WHERE (SELECT p.state FROM prev p) IS DISTINCT FROM u.state;
+
+-- name: MarkStaleRoutingAdjacenciesDown :execrows
+-- Adjacencies of the polled routers that no router reported during this run are marked down.
+WITH stale AS (
+  UPDATE links l
+  SET state = 'down',
+      last_change_at = $3,
+      updated_at = now()
+  FROM links prev
+  WHERE prev.id = l.id
+    AND l.link_type = $2
+    AND (l.a_device_id = ANY($1::uuid[]) OR l.b_device_id = ANY($1::uuid[]))
+    AND l.observed_at < $3
+    AND l.state IS DISTINCT FROM 'down'
+  RETURNING l.id, l.a_device_id, l.b_device_id, l.link_type, prev.state AS from_state
+)
+INSERT INTO link_state_transitions (link_id, a_device_id, b_device_id, link_type, from_state, to_state, changed_at)
+SELECT s.id, s.a_device_id, s.b_device_id, s.link_type, s.from_state, 'down', $3
+FROM stale s;
@@ -1,19 +1,127 @@
 -- name: UpsertServiceFromScan :exec
-INSERT INTO services (
+WITH prev AS (
+  SELECT state
+  FROM services
+  WHERE device_id = $1::uuid
+    AND protocol = $2
+    AND port = $3
+),
+upserted AS (
+  INSERT INTO services (
+    device_id,
+    protocol,
+    port,
+    name,
+    state,
+    source,
+    summary,
+    observed_at,
+    first_seen_at,
+    last_seen_at,
+    closed_at,
+    product,
+    version,
+    extra_info,
+    cpe
+  )
+  VALUES (
+    $1::uuid,
+    $2,
+    $3,
+    $4,
+    $5::text,
+    $6,
+    $7,
+    $8::timestamptz,
+    $8::timestamptz,
+    CASE WHEN $5::text = 'open' THEN $8::timestamptz END,
+    CASE WHEN $5::text IN ('closed', 'filtered') THEN $8::timestamptz END,
+    $9,
+    $10,
+    $11,
+    $12::text[]
+  )
+  ON CONFLICT (device_id, protocol, port) WHERE protocol IS NOT NULL AND port IS NOT NULL
+  DO UPDATE
+  SET name = COALESCE(EXCLUDED.name, services.name),
+      state = EXCLUDED.state,
+      source = EXCLUDED.source,
+      summary = COALESCE(EXCLUDED.summary, services.summary),
+      product = COALESCE(EXCLUDED.product, services.product),
+      version = COALESCE(EXCLUDED.version, services.version),
+      extra_info = COALESCE(EXCLUDED.extra_info, services.extra_info),
+      cpe = COALESCE(EXCLUDED.cpe, services.cpe),
+      observed_at = EXCLUDED.observed_at,
+      first_seen_at = COALESCE(services.first_seen_at, EXCLUDED.observed_at),
+      last_seen_at = CASE
+        WHEN EXCLUDED.state = 'open' THEN EXCLUDED.observed_at
+        ELSE services.last_seen_at
+      END,
+      closed_at = CASE
+        WHEN EXCLUDED.state = 'open' THEN NULL
+        WHEN services.state IS DISTINCT FROM EXCLUDED.state THEN EXCLUDED.observed_at
+        ELSE services.closed_at
+      END,
+      updated_at = now()
+  RETURNING id, device_id, protocol, port, state, source, observed_at
+)
+INSERT INTO service_transitions (
+  service_id,
+  device_id,
+  protocol,
+  port,
+  from_state,
+  to_state,
+  source,
+  changed_at
+)
+SELECT u.id,
+       u.device_id,
+       u.protocol,
+       u.port,
+       (SELECT state FROM prev),
+       u.state,
+       u.source,
+       u.observed_at
+FROM upserted u
+WHERE u.state IS NOT NULL
+  AND u.state IS DISTINCT FROM (SELECT state FROM prev);
+
+-- name: MarkServiceNotOpen :execrows
+WITH updated AS (
+  UPDATE services
+  SET state = $4::text,
+      source = COALESCE($5, source),
+      observed_at = $6::timestamptz,
+      closed_at = $6::timestamptz,
+      updated_at = now()
+  WHERE device_id = $1::uuid
+    AND protocol = $2
+    AND port = $3
+    AND state = 'open'
+  RETURNING id, device_id, protocol, port, state, source, observed_at
+)
+INSERT INTO service_transitions (
+  service_id,
   device_id,
   protocol,
   port,
-  name,
-  state,
+  from_state,
+  to_state,
   source,
-  observed_at
+  changed_at
 )
-VALUES ($1::uuid, $2, $3, $4, $5, $6, $7)
-ON CONFLICT (device_id, protocol, port) WHERE protocol IS NOT NULL AND port IS NOT NULL
-DO UPDATE
-SET name = EXCLUDED.name,
-    state = EXCLUDED.state,
-    source = EXCLUDED.source,
-    observed_at = EXCLUDED.observed_at,
-    updated_at = now();
+SELECT id, device_id, protocol, port, 'open', state, source, observed_at
+FROM updated;
 
+-- name: UpdateServiceHTTPFingerprint :exec
+UPDATE services
+SET http_status = $2,
+    http_title = $3,
+    http_server = $4,
+    http_powered_by = $5,
+    http_favicon_hash = $6,
+    http_final_url = $7,
+    http_observed_at = $8::timestamptz,
+    updated_at = now()
+WHERE id = $1::uuid;
@@ -0,0 +1,95 @@
+-- name: ListSNMPProfiles :many
+SELECT id::text, name, description, tags, items, enabled, created_at, updated_at
+FROM snmp_profiles
+ORDER BY name ASC;
+
+-- name: GetSNMPProfile :one
+SELECT id::text, name, description, tags, items, enabled, created_at, updated_at
+FROM snmp_profiles
+WHERE id = $1::uuid;
+
+-- name: CreateSNMPProfile :one
+INSERT INTO snmp_profiles (name, description, tags, items, enabled)
+VALUES ($1, $2, $3::text[], $4::jsonb, $5)
+RETURNING id::text, name, description, tags, items, enabled, created_at, updated_at;
+
+-- name: UpdateSNMPProfile :one
+UPDATE snmp_profiles
+SET name = $2,
+    description = $3,
+    tags = $4::text[],
+    items = $5::jsonb,
+    enabled = $6,
+    updated_at = now()
+WHERE id = $1::uuid
+RETURNING id::text, name, description, tags, items, enabled, created_at, updated_at;
+
+-- name: DeleteSNMPProfile :execrows
+DELETE FROM snmp_profiles
+WHERE id = $1::uuid;
+
+-- name: ListSNMPProfilesForDevice :many
+-- Enabled profiles bound to any tag the device currently carries (manual or auto).
+SELECT p.id::text, p.name, p.description, p.tags, p.items, p.enabled, p.created_at, p.updated_at
+FROM snmp_profiles p
+WHERE p.enabled
+  AND p.tags && ARRAY(SELECT t.tag FROM device_tags t WHERE t.device_id = $1::uuid)
+ORDER BY p.name ASC;
+
+-- name: UpsertDeviceCustomFact :execrows
+-- Stores the current value and appends a history row when the value is new or changed; the affected row
+-- count is 1 only when history was written.
+WITH prev AS (
+  SELECT value
+  FROM device_custom_facts
+  WHERE device_id = $1::uuid AND profile_id = $2::uuid AND fact_key = $3 AND instance = $4
+), upserted AS (
+  INSERT INTO device_custom_facts (
+    device_id, profile_id, fact_key, instance, label, oid, value_type, value, numeric_value,
+    first_seen_at, observed_at, changed_at
+  )
+  VALUES ($1::uuid, $2::uuid, $3, $4, $5, $6, $7, $8, $9, $10, $10, $10)
+  ON CONFLICT (device_id, profile_id, fact_key, instance) DO UPDATE
+  SET label = EXCLUDED.label,
+      oid = EXCLUDED.oid,
+      value_type = EXCLUDED.value_type,
+      value = EXCLUDED.value,
+      numeric_value = EXCLUDED.numeric_value,
+      observed_at = EXCLUDED.observed_at,
+      changed_at = CASE
+        WHEN device_custom_facts.value IS DISTINCT FROM EXCLUDED.value THEN EXCLUDED.observed_at
+        ELSE device_custom_facts.changed_at
+      END
+  RETURNING 1
+)
+INSERT INTO device_custom_fact_history (device_id, profile_id, fact_key, instance, value, numeric_value, observed_at)
+SELECT $1::uuid, $2::uuid, $3, $4, $8, $9, $10
+FROM upserted
+WHERE NOT EXISTS (SELECT 1 FROM prev WHERE prev.value = $8);
+
+-- name: DeleteStaleDeviceCustomFacts :execrows
+-- Drops current values a successful poll of the profile no longer reported (history is kept).
+DELETE FROM device_custom_facts
+WHERE device_id = $1::uuid
+  AND profile_id = $2::uuid
+  AND observed_at < $3;
+
+-- name: ListDeviceCustomFacts :many
+SELECT f.profile_id::text, p.name, f.fact_key, f.instance, f.label, f.oid, f.value_type, f.value,
+       f.numeric_value, f.first_seen_at, f.observed_at, f.changed_at
+FROM device_custom_facts f
+JOIN snmp_profiles p ON p.id = f.profile_id
+WHERE f.device_id = $1::uuid
+ORDER BY p.name ASC, f.fact_key ASC, f.instance ASC;
+
+-- name: ListDeviceCustomFactHistory :many
+-- The most recent value changes per fact, newest first.
+SELECT profile_id, fact_key, instance, value, numeric_value, observed_at
+FROM (
+  SELECT h.profile_id::text AS profile_id, h.fact_key, h.instance, h.value, h.numeric_value, h.observed_at,
+         row_number() OVER (PARTITION BY h.profile_id, h.fact_key, h.instance ORDER BY h.observed_at DESC, h.id DESC) AS rn
+  FROM device_custom_fact_history h
+  WHERE h.device_id = $1::uuid
+) ranked
+WHERE rn <= $2
+ORDER BY profile_id ASC, fact_key ASC, instance ASC, observed_at DESC;
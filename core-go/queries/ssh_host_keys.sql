@@ -0,0 +1,63 @@
+-- name: UpsertSSHHostKey :one
+WITH upserted AS (
+  INSERT INTO ssh_host_keys (
+    device_id,
+    service_id,
+    key_type,
+    fingerprint_sha256,
+    public_key,
+    banner,
+    first_seen_at,
+    last_seen_at
+  )
+  VALUES (
+    $1::uuid,
+    $2::uuid,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7::timestamptz,
+    $7::timestamptz
+  )
+  ON CONFLICT (device_id, key_type, fingerprint_sha256)
+  DO UPDATE
+  SET service_id = EXCLUDED.service_id,
+      banner = COALESCE(EXCLUDED.banner, ssh_host_keys.banner),
+      last_seen_at = EXCLUDED.last_seen_at,
+      updated_at = now()
+  RETURNING id
+)
+SELECT upserted.id,
+       (
+         SELECT count(DISTINCT o.device_id)
+         FROM ssh_host_keys o
+         WHERE o.fingerprint_sha256 = $4
+           AND o.device_id <> $1::uuid
+       )::int AS shared_device_count
+FROM upserted;
+
+-- name: ListDeviceSSHHostKeys :many
+SELECT DISTINCT ON (k.key_type)
+       k.id,
+       k.service_id,
+       s.port,
+       k.key_type,
+       k.fingerprint_sha256,
+       k.public_key,
+       k.banner,
+       k.first_seen_at,
+       k.last_seen_at,
+       COALESCE(
+         (
+           SELECT array_agg(DISTINCT o.device_id::text)
+           FROM ssh_host_keys o
+           WHERE o.fingerprint_sha256 = k.fingerprint_sha256
+             AND o.device_id <> k.device_id
+         ),
+         '{}'
+       )::text[] AS shared_with_device_ids
+FROM ssh_host_keys k
+LEFT JOIN services s ON s.id = k.service_id
+WHERE k.device_id = $1::uuid
+ORDER BY k.key_type ASC, k.last_seen_at DESC, k.id ASC;
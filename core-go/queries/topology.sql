@@ -1,4 +1,18 @@
 -- name: UpsertLink :exec
+-- Observed (LLDP/CDP/manual) links always win: inferred links that touch the same device pair or either
+-- interface are removed when an observed link is written.
+WITH superseded AS (
+  DELETE FROM links l
+  WHERE l.source = 'inferred'
+    AND $7 <> 'inferred'
+    AND l.link_key <> $1
+    AND (
+      (l.a_device_id = $2::uuid AND l.b_device_id = $4::uuid)
+      OR (l.a_device_id = $4::uuid AND l.b_device_id = $2::uuid)
+      OR ($3::uuid IS NOT NULL AND $3::uuid IN (l.a_interface_id, l.b_interface_id))
+      OR ($5::uuid IS NOT NULL AND $5::uuid IN (l.a_interface_id, l.b_interface_id))
+    )
+)
 INSERT INTO links (
   link_key,
   a_device_id,
@@ -20,6 +34,49 @@ SET a_device_id = EXCLUDED.a_device_id,
     observed_at = EXCLUDED.observed_at,
     updated_at = now();
 
+-- name: UpsertInferredLink :execrows
+-- Skipped (0 rows) when an observed physical link already covers the device pair or either interface
+-- (routing adjacencies do not count).
+INSERT INTO links (
+  link_key,
+  a_device_id,
+  a_interface_id,
+  b_device_id,
+  b_interface_id,
+  link_type,
+  source,
+  confidence,
+  observed_at
+)
+SELECT $1, $2::uuid, $3::uuid, $4::uuid, $5::uuid, $6, 'inferred', $7, $8
+WHERE NOT EXISTS (
+  SELECT 1
+  FROM links l
+  WHERE l.source <> 'inferred'
+    AND COALESCE(l.link_type, '') NOT IN ('ospf', 'bgp')
+    AND (
+      (l.a_device_id = $2::uuid AND l.b_device_id = $4::uuid)
+      OR (l.a_device_id = $4::uuid AND l.b_device_id = $2::uuid)
+      OR ($3::uuid IS NOT NULL AND $3::uuid IN (l.a_interface_id, l.b_interface_id))
+      OR ($5::uuid IS NOT NULL AND $5::uuid IN (l.a_interface_id, l.b_interface_id))
+    )
+)
+ON CONFLICT (link_key) DO UPDATE
+SET a_interface_id = EXCLUDED.a_interface_id,
+    b_interface_id = EXCLUDED.b_interface_id,
+    link_type = EXCLUDED.link_type,
+    confidence = EXCLUDED.confidence,
+    observed_at = EXCLUDED.observed_at,
+    updated_at = now()
+WHERE links.source = 'inferred';
+
+-- name: DeleteStaleInferredLinks :execrows
+-- Removes inferred links touching the given (re-polled) devices that were not re-inferred since $2.
+DELETE FROM links
+WHERE source = 'inferred'
+  AND (a_device_id = ANY($1::uuid[]) OR b_device_id = ANY($1::uuid[]))
+  AND COALESCE(observed_at, updated_at) < $2;
+
 -- name: UpsertInterfaceByName :one
 INSERT INTO interfaces (device_id, name)
 VALUES ($1::uuid, $2)
@@ -0,0 +1,10 @@
+-- name: InsertTraceroutePath :exec
+-- Stores one traced path with its hops; the hop arrays are parallel (TTL order, NULL ip for silent hops).
+WITH path AS (
+  INSERT INTO traceroute_paths (run_id, scope, target, mode, reached, hop_count, observed_at)
+  VALUES ($1::uuid, $2::cidr, $3::inet, $4, $5, cardinality($6::int[]), $10)
+  RETURNING id
+)
+INSERT INTO traceroute_hops (path_id, ttl, ip, device_id, rtt_ms)
+SELECT path.id, h.ttl, h.ip::inet, h.device_id::uuid, h.rtt_ms
+FROM path, unnest($6::int[], $7::text[], $8::text[], $9::real[]) AS h(ttl, ip, device_id, rtt_ms);
@@ -86,6 +86,7 @@ export async function createDevice(
   if (Object.keys(metadata).length > 0) {
     payload.metadata = metadata;
   }
+  payload.actor = session.username;
 
   const reqId = (await headers()).get('x-request-id') ?? randomUUID();
 
@@ -231,6 +232,7 @@ export async function updateDeviceMetadata(
   if (Object.keys(payload).length === 0) {
     return { status: 'error', message: 'nothing to update' };
   }
+  payload.actor = session.username;
 
   const reqId = (await headers()).get('x-request-id') ?? randomUUID();
   const res = await fetch(`${apiBase()}/api/v1/devices/${deviceId}`, {
@@ -298,7 +300,7 @@ export async function updateDeviceDisplayName(
       'X-Request-ID': reqId
     },
     cache: 'no-store',
-    body: JSON.stringify({ display_name: displayName })
+    body: JSON.stringify({ display_name: displayName, actor: session.username })
   });
 
   if (!res.ok) {
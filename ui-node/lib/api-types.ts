@@ -28,6 +28,13 @@ export interface paths {
                     seen_within_seconds?: number;
                     /** @description Lookback window for changed filtering (defaults to 24 hours). */
                     changed_within_seconds?: number;
+                    /** @description Whether archived devices are hidden (default), listed alongside active devices, or listed alone. */
+                    archived?: "exclude" | "include" | "only";
+                    /**
+                     * Format: date-time
+                     * @description List devices as they were at this instant (not in the future); the seen/changed windows end at it. While the retention job runs, instants older than the retained IP/MAC observations are rejected with `earliest_as_of` in the error details.
+                     */
+                    as_of?: string;
                 };
                 header?: never;
                 path?: never;
@@ -96,10 +103,19 @@ export interface paths {
             };
             cookie?: never;
         };
-        /** Get device by ID */
+        /**
+         * Get device by ID
+         * @description IDs of devices that were merged into another device keep resolving: the surviving device is returned (with its own `id`).
+         */
         get: {
             parameters: {
-                query?: never;
+                query?: {
+                    /**
+                     * Format: date-time
+                     * @description Return the name, metadata, tags and archive state at this instant; 404 when the device did not exist yet. While the retention job runs, instants older than the retained IP/MAC observations are rejected with `earliest_as_of` in the error details.
+                     */
+                    as_of?: string;
+                };
                 header?: never;
                 path: {
                     id: string;
@@ -183,6 +199,194 @@ export interface paths {
             };
         };
         post?: never;
+        /**
+         * Purge an archived device
+         * @description Permanently deletes an archived device together with its facts, history and aliases, and writes a `device.purge` audit event.
+         *     Active devices must be archived first.
+         */
+        delete: {
+            parameters: {
+                query?: {
+                    /** @description Recorded on the audit event (defaults to `api`). */
+                    actor?: string;
+                    actor_role?: string;
+                    reason?: string;
+                };
+                header?: never;
+                path: {
+                    id: string;
+                };
+                cookie?: never;
+            };
+            requestBody?: never;
+            responses: {
+                /** @description Purged */
+                204: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content?: never;
+                };
+                /** @description Invalid ID */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Not found */
+                404: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Device is not archived */
+                409: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/devices/{id}/archive": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                id: string;
+            };
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        /**
+         * Archive a device
+         * @description Hides the device from device lists, exports and maps, and stops IP and host-name matching from attaching new observations to it.
+         *     Discovery unarchives the device (with an `archive` change event) when its MAC address or DHCP client ID is seen again.
+         *     Archiving an archived device is a no-op.
+         */
+        post: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path: {
+                    id: string;
+                };
+                cookie?: never;
+            };
+            requestBody?: {
+                content: {
+                    "application/json": components["schemas"]["DeviceArchiveRequest"];
+                };
+            };
+            responses: {
+                /** @description OK */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["Device"];
+                    };
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Not found */
+                404: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/devices/{id}/restore": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                id: string;
+            };
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        /**
+         * Restore an archived device
+         * @description Makes an archived device active again. Restoring an active device is a no-op.
+         */
+        post: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path: {
+                    id: string;
+                };
+                cookie?: never;
+            };
+            requestBody?: {
+                content: {
+                    "application/json": components["schemas"]["DeviceArchiveRequest"];
+                };
+            };
+            responses: {
+                /** @description OK */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["Device"];
+                    };
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Not found */
+                404: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
         delete?: never;
         options?: never;
         head?: never;
@@ -253,10 +457,21 @@ export interface paths {
         /**
          * Get current device facts
          * @description Returns current discovery/enrichment facts for a device (IPs, MACs, interfaces, services, SNMP snapshot, and adjacency links).
+         *
+         *     With `as_of`, IPs, MACs, services, links, interface PVIDs and SNMP identity are rebuilt from observations and
+         *     device_events as they were at that instant. An IP or MAC stops counting once it was detached, seen on another
+         *     device or marked stale before that instant, and one a merge brought over counts only from the merge on.
+         *     Interface status, OS guesses and custom facts stay current.
          */
         get: {
             parameters: {
-                query?: never;
+                query?: {
+                    /**
+                     * Format: date-time
+                     * @description Rebuild the facts as they were at this instant (not in the future). While the retention job runs, instants older than the retained IP/MAC observations are rejected with `earliest_as_of` in the error details.
+                     */
+                    as_of?: string;
+                };
                 header?: never;
                 path: {
                     id: string;
@@ -395,39 +610,41 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
-    "/v1/discovery/run": {
+    "/v1/devices/duplicates": {
         parameters: {
             query?: never;
             header?: never;
             path?: never;
             cookie?: never;
         };
-        get?: never;
-        put?: never;
-        /** Trigger a discovery pass */
-        post: {
+        /**
+         * List likely duplicate devices
+         * @description Returns device pairs the background duplicate analyzer scored as probably the same device, strongest first.
+         *     Evidence lists each matching signal (`serial`, `ssh_host_key`, `mac`, `interface_mac`, `sys_name`, `name_candidate`, `ip_handoff`)
+         *     with its weight and the shared values. Scores are the capped sum of distinct signal weights (0-100). Dismissed pairs are never listed.
+         */
+        get: {
             parameters: {
-                query?: never;
+                query?: {
+                    min_score?: number;
+                    limit?: number;
+                };
                 header?: never;
                 path?: never;
                 cookie?: never;
             };
-            requestBody?: {
-                content: {
-                    "application/json": components["schemas"]["DiscoveryRunRequest"];
-                };
-            };
+            requestBody?: never;
             responses: {
-                /** @description Accepted */
-                202: {
+                /** @description Duplicate suggestions */
+                200: {
                     headers: {
                         [name: string]: unknown;
                     };
                     content: {
-                        "application/json": components["schemas"]["DiscoveryRun"];
+                        "application/json": components["schemas"]["DeviceDuplicateList"];
                     };
                 };
-                /** @description Bad request */
+                /** @description Invalid request */
                 400: {
                     headers: {
                         [name: string]: unknown;
@@ -438,72 +655,185 @@ export interface paths {
                 };
             };
         };
+        put?: never;
+        post?: never;
         delete?: never;
         options?: never;
         head?: never;
         patch?: never;
         trace?: never;
     };
-    "/v1/discovery/status": {
+    "/v1/devices/duplicates/dismissals": {
         parameters: {
             query?: never;
             header?: never;
             path?: never;
             cookie?: never;
         };
-        /** Get discovery status */
-        get: {
+        get?: never;
+        put?: never;
+        /**
+         * Dismiss a duplicate suggestion
+         * @description Records that two devices are distinct. The pair is removed from the suggestions and the analyzer will not suggest it again.
+         *     Dismissing the same pair twice updates the actor and reason.
+         */
+        post: {
             parameters: {
                 query?: never;
                 header?: never;
                 path?: never;
                 cookie?: never;
             };
-            requestBody?: never;
+            requestBody: {
+                content: {
+                    "application/json": components["schemas"]["DeviceDuplicateDismissRequest"];
+                };
+            };
             responses: {
-                /** @description OK */
-                200: {
+                /** @description Dismissed */
+                201: {
                     headers: {
                         [name: string]: unknown;
                     };
                     content: {
-                        "application/json": components["schemas"]["DiscoveryStatus"];
+                        "application/json": components["schemas"]["DeviceDuplicateDismissal"];
+                    };
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Device not found */
+                404: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
                     };
                 };
             };
         };
-        put?: never;
-        post?: never;
         delete?: never;
         options?: never;
         head?: never;
         patch?: never;
         trace?: never;
     };
-    "/v1/discovery/scope-suggestions": {
+    "/v1/discovery/run": {
         parameters: {
             query?: never;
             header?: never;
             path?: never;
             cookie?: never;
         };
-        /** Suggest local interface scopes */
-        get: {
+        get?: never;
+        put?: never;
+        /** Trigger a discovery pass */
+        post: {
             parameters: {
                 query?: never;
                 header?: never;
                 path?: never;
                 cookie?: never;
             };
-            requestBody?: never;
+            requestBody?: {
+                content: {
+                    "application/json": components["schemas"]["DiscoveryRunRequest"];
+                };
+            };
             responses: {
-                /** @description Scope suggestions derived from local interfaces */
-                200: {
+                /** @description Accepted */
+                202: {
                     headers: {
                         [name: string]: unknown;
                     };
                     content: {
-                        "application/json": components["schemas"]["DiscoveryScopeSuggestions"];
+                        "application/json": components["schemas"]["DiscoveryRun"];
+                    };
+                };
+                /** @description Bad request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/discovery/status": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /** Get discovery status */
+        get: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path?: never;
+                cookie?: never;
+            };
+            requestBody?: never;
+            responses: {
+                /** @description OK */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["DiscoveryStatus"];
+                    };
+                };
+            };
+        };
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/discovery/scope-suggestions": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /** Suggest local interface scopes */
+        get: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path?: never;
+                cookie?: never;
+            };
+            requestBody?: never;
+            responses: {
+                /** @description Scope suggestions derived from local interfaces */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["DiscoveryScopeSuggestions"];
                     };
                 };
             };
@@ -863,52 +1193,1147 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
-    "/v1/inventory/nautobot/import": {
+    "/v1/devices/{id}/availability": {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
+            path: {
+                id: string;
+            };
             cookie?: never;
         };
-        get?: never;
+        /**
+         * Device availability and uptime
+         * @description Splits the window into up/down intervals from the transitions recorded after each discovery run and by the
+         *     reachability monitor, and reports uptime over the part of the window with a known state. Time before the first recorded transition is `unknown`.
+         */
+        get: {
+            parameters: {
+                query?: {
+                    /** @description Window start (RFC3339). Defaults to 7 days before `to`. */
+                    from?: string;
+                    /** @description Window end (RFC3339). Defaults to now. The window may span at most 366 days. */
+                    to?: string;
+                };
+                header?: never;
+                path: {
+                    id: string;
+                };
+                cookie?: never;
+            };
+            requestBody?: never;
+            responses: {
+                /** @description Availability */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["DeviceAvailability"];
+                    };
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Device not found */
+                404: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
         put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/devices/{id}/monitor": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                id: string;
+            };
+            cookie?: never;
+        };
+        /** Get the reachability monitor of a device */
+        get: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path: {
+                    id: string;
+                };
+                cookie?: never;
+            };
+            requestBody?: never;
+            responses: {
+                /** @description Monitor configuration and last probe status */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["DeviceMonitor"];
+                    };
+                };
+                /** @description Device not found or not monitored */
+                404: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
         /**
-         * Import devices from a Nautobot export payload
-         * @description Accepts a Nautobot API payload (or an extracted device list) and imports devices into the local catalog.
+         * Flag a device for the reachability monitor
+         * @description Stores a manual monitor. It takes precedence over a monitor selected through `MONITOR_TAGS`, so
+         *     `enabled: false` keeps a tagged device unprobed.
          */
-        post: {
+        put: {
             parameters: {
                 query?: never;
                 header?: never;
-                path?: never;
+                path: {
+                    id: string;
+                };
                 cookie?: never;
             };
             requestBody: {
                 content: {
-                    "application/json": components["schemas"]["InventoryImportRequest"];
+                    "application/json": components["schemas"]["DeviceMonitorUpdate"];
+                };
+            };
+            responses: {
+                /** @description Saved monitor */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["DeviceMonitor"];
+                    };
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Device not found */
+                404: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        post?: never;
+        /**
+         * Stop monitoring a device
+         * @description A device carrying a `MONITOR_TAGS` tag is picked up again on the next monitor cycle.
+         */
+        delete: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path: {
+                    id: string;
+                };
+                cookie?: never;
+            };
+            requestBody?: never;
+            responses: {
+                /** @description Removed */
+                204: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content?: never;
+                };
+                /** @description Device not found or not monitored */
+                404: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/devices/{id}/reachability": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                id: string;
+            };
+            cookie?: never;
+        };
+        /**
+         * Reachability RTT and loss series
+         * @description Probes recorded by the reachability monitor. Raw samples are kept for `MONITOR_RAW_RETENTION`; hourly
+         *     rollups outlive them.
+         */
+        get: {
+            parameters: {
+                query?: {
+                    /** @description Window start (RFC3339). Defaults to 24 hours before `to`. */
+                    from?: string;
+                    /** @description Window end (RFC3339). Defaults to now. The window may span at most 366 days. */
+                    to?: string;
+                    /** @description One point per probe (at most 7 days) or per hour. Defaults to raw for windows up to 24 hours. */
+                    resolution?: "raw" | "hour";
+                };
+                header?: never;
+                path: {
+                    id: string;
+                };
+                cookie?: never;
+            };
+            requestBody?: never;
+            responses: {
+                /** @description Reachability series */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["DeviceReachability"];
+                    };
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Device not found */
+                404: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/devices/{id}/powered-devices": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                id: string;
+            };
+            cookie?: never;
+        };
+        /**
+         * Devices powered by a PoE switch
+         * @description Returns one row per PoE port of this device (POWER-ETHERNET-MIB) that is delivering power to a linked device.
+         *     The powered device is resolved from the link on the port (manual, then LLDP/CDP, then inferred).
+         */
+        get: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path: {
+                    id: string;
+                };
+                cookie?: never;
+            };
+            requestBody?: never;
+            responses: {
+                /** @description Powered devices */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["PoweredDevice"][];
+                    };
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Device not found */
+                404: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/inventory/nautobot/import": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        /**
+         * Import devices from a Nautobot export payload
+         * @description Accepts a Nautobot API payload (or an extracted device list) and imports devices into the local catalog.
+         */
+        post: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path?: never;
+                cookie?: never;
+            };
+            requestBody: {
+                content: {
+                    "application/json": components["schemas"]["InventoryImportRequest"];
+                };
+            };
+            responses: {
+                /** @description Imported inventory */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["InventoryImportResult"];
+                    };
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Database not configured */
+                503: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/inventory/scan-import": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        /**
+         * Import offline scan results as a discovery run
+         * @description Accepts scanner output captured elsewhere (e.g. from a jump host core-go cannot reach): nmap XML (`-oX`),
+         *     masscan JSON (`-oJ`) or arp-scan text. The results are replayed as a synthetic, already-completed discovery
+         *     run (`stats.method = "import"`) with logs and IP/MAC observations, so imported hosts go through the same
+         *     device matching (MAC, then IP), history and change feed as native runs. Only positive results are applied;
+         *     an import never marks services closed.
+         */
+        post: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path?: never;
+                cookie?: never;
+            };
+            requestBody: {
+                content: {
+                    "application/json": components["schemas"]["ScanImportRequest"];
+                };
+            };
+            responses: {
+                /** @description Completed import run */
+                201: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["DiscoveryRun"];
+                    };
+                };
+                /** @description Invalid request or unparseable scan content (no run is created) */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Import failed while writing (the run is marked failed; `details.run_id` when available) */
+                500: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Database not configured */
+                503: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/inventory/pcap-import": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        /**
+         * Import a packet capture as a discovery run
+         * @description Accepts a raw pcap or pcapng capture (e.g. from incident response at a remote site) and extracts passive
+         *     evidence in pure Go: ARP and DHCP bindings, DHCP/mDNS/NetBIOS names, LLDP/CDP advertisements and TCP SYN-ACK
+         *     service evidence. The body is spooled to disk (bounded by `PCAP_IMPORT_MAX_BYTES`), a discovery run with
+         *     `stats.method = "pcap"` is returned immediately and the capture is streamed in the background; poll the run
+         *     for completion. When `capture_device_id` is given, LLDP/CDP neighbors are linked to that device.
+         */
+        post: {
+            parameters: {
+                query?: {
+                    /** @description Free-form label for where the capture was taken (site, ticket). */
+                    origin?: string;
+                    /** @description Device the capture was taken on; LLDP/CDP neighbors become links from it. */
+                    capture_device_id?: string;
+                    /** @description Interface name on the capture device (requires `capture_device_id`). */
+                    capture_interface?: string;
+                };
+                header?: never;
+                path?: never;
+                cookie?: never;
+            };
+            requestBody: {
+                content: {
+                    "application/vnd.tcpdump.pcap": string;
+                    "application/octet-stream": string;
+                };
+            };
+            responses: {
+                /** @description Capture accepted; the run is processing */
+                202: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["DiscoveryRun"];
+                    };
+                };
+                /** @description Not a pcap/pcapng capture or invalid parameters (no run is created) */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Capture exceeds the configured size limit */
+                413: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Failed to buffer the capture or create the run */
+                500: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Database not configured */
+                503: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/audit/events": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        /**
+         * Record an audit event
+         * @description Minimal audit log write endpoint intended to be called by the UI (which owns auth/sessions).
+         */
+        post: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path?: never;
+                cookie?: never;
+            };
+            requestBody: {
+                content: {
+                    "application/json": components["schemas"]["AuditEventCreate"];
+                };
+            };
+            responses: {
+                /** @description Created */
+                201: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": {
+                            ok?: boolean;
+                        };
+                    };
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Database not configured */
+                503: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/certificates": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * List TLS certificates
+         * @description Returns the current leaf certificate for every TLS-speaking service, ordered by `not_after` (soonest expiry first).
+         *
+         *     Certificates are collected by the discovery worker's TLS inventory stage. Already-expired certificates are included and flagged with `expired=true`.
+         */
+        get: {
+            parameters: {
+                query?: {
+                    /** @description Only return certificates expiring within this window (e.g. `30d`, `12h`). Includes already-expired certificates. */
+                    expires_within?: string;
+                    limit?: number;
+                    /** @description Cursor from a previous page (`not_after|id`). */
+                    cursor?: string;
+                };
+                header?: never;
+                path?: never;
+                cookie?: never;
+            };
+            requestBody?: never;
+            responses: {
+                /** @description Certificate inventory page */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["CertificatePage"];
+                    };
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Database not configured */
+                503: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/devices/{id}/merge": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description The surviving device. */
+                id: string;
+            };
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        /**
+         * Merge duplicate devices into this device
+         * @description Moves IPs, MACs, interfaces, services (with certificates and transitions), links, tags, name candidates, observations,
+         *     SSH host keys, OS guesses, SNMP facts, custom facts and metadata from every source device onto this device in one transaction.
+         *
+         *     Conflict rules: the survivor wins. Rows that would duplicate a survivor row are dropped; duplicate services and SSH host keys
+         *     widen the survivor's first/last seen and fill missing details; duplicate tags keep the higher confidence; metadata and
+         *     `display_name` are only filled where the survivor has none; the SNMP snapshot with the most recent successful poll is kept.
+         *
+         *     History moves too: archive events, fact detachments and reachability samples and rollups. Availability
+         *     transitions move when they precede the survivor's latest one, and the source's monitor config only when the survivor has none.
+         *     Names, metadata and SNMP identity filled from a source, and the survivor's merged tags, VLANs and links, are recorded
+         *     as device events by the merge actor. The sources' own device events stay on their IDs and show in the survivor's history.
+         *
+         *     Source devices are deleted, their IDs become aliases that still resolve on `GET /devices/{id}`, and a `device.merge` audit event is written.
+         */
+        post: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path: {
+                    /** @description The surviving device. */
+                    id: string;
+                };
+                cookie?: never;
+            };
+            requestBody: {
+                content: {
+                    "application/json": components["schemas"]["DeviceMergeRequest"];
+                };
+            };
+            responses: {
+                /** @description Merged */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["DeviceMergeResult"];
+                    };
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Survivor or a source device not found */
+                404: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/snmp-profiles": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * List custom SNMP polling profiles
+         * @description Returns every admin-defined SNMP polling profile, ordered by name.
+         *     A profile is a named set of scalar OIDs and table columns polled by the enrichment stage on every device carrying one of its tags.
+         */
+        get: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path?: never;
+                cookie?: never;
+            };
+            requestBody?: never;
+            responses: {
+                /** @description Profiles */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["SNMPProfileList"];
+                    };
+                };
+                /** @description Database not configured */
+                503: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        put?: never;
+        /** Create a custom SNMP polling profile */
+        post: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path?: never;
+                cookie?: never;
+            };
+            requestBody: {
+                content: {
+                    "application/json": components["schemas"]["SNMPProfileWrite"];
+                };
+            };
+            responses: {
+                /** @description Created */
+                201: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["SNMPProfile"];
+                    };
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description A profile with this name already exists */
+                409: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Database not configured */
+                503: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/snmp-profiles/{id}": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                id: string;
+            };
+            cookie?: never;
+        };
+        /** Get a custom SNMP polling profile */
+        get: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path: {
+                    id: string;
+                };
+                cookie?: never;
+            };
+            requestBody?: never;
+            responses: {
+                /** @description Profile */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["SNMPProfile"];
+                    };
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Profile not found */
+                404: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        /**
+         * Replace a custom SNMP polling profile
+         * @description Replaces the profile definition. Facts for items that are no longer in the profile are removed after the next successful poll.
+         */
+        put: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path: {
+                    id: string;
+                };
+                cookie?: never;
+            };
+            requestBody: {
+                content: {
+                    "application/json": components["schemas"]["SNMPProfileWrite"];
+                };
+            };
+            responses: {
+                /** @description Updated */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["SNMPProfile"];
+                    };
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Profile not found */
+                404: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description A profile with this name already exists */
+                409: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        post?: never;
+        /**
+         * Delete a custom SNMP polling profile
+         * @description Deletes the profile and every custom fact (including history) it produced.
+         */
+        delete: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path: {
+                    id: string;
+                };
+                cookie?: never;
+            };
+            requestBody?: never;
+            responses: {
+                /** @description Deleted */
+                204: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content?: never;
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Profile not found */
+                404: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/retention/policies": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * List effective retention policies
+         * @description One policy per pruned table. API overrides (`source: api`) take precedence over the env defaults (`source: env`).
+         *     `keep_raw_days: 0` keeps the table forever.
+         */
+        get: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path?: never;
+                cookie?: never;
+            };
+            requestBody?: never;
+            responses: {
+                /** @description Policies */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["RetentionPolicyList"];
+                    };
+                };
+            };
+        };
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/v1/retention/policies/{table}": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                table: "ip_observations" | "mac_observations" | "discovery_run_logs";
+            };
+            cookie?: never;
+        };
+        get?: never;
+        /**
+         * Override the retention policy of a table
+         * @description Keeps every row for `keep_raw_days`, then the first and last row per device, address and UTC day until `keep_daily_days`, then drops it.
+         *     `keep_daily_days` must be 0 or greater than `keep_raw_days`; discovery_run_logs has no daily tier.
+         */
+        put: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path: {
+                    table: "ip_observations" | "mac_observations" | "discovery_run_logs";
+                };
+                cookie?: never;
+            };
+            requestBody: {
+                content: {
+                    "application/json": components["schemas"]["RetentionPolicyWrite"];
+                };
+            };
+            responses: {
+                /** @description Stored override */
+                200: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["RetentionPolicy"];
+                    };
+                };
+                /** @description Invalid request */
+                400: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+                /** @description Table is not managed by retention */
+                404: {
+                    headers: {
+                        [name: string]: unknown;
+                    };
+                    content: {
+                        "application/json": components["schemas"]["ErrorResponse"];
+                    };
+                };
+            };
+        };
+        post?: never;
+        /**
+         * Remove the API override of a table
+         * @description The table reverts to its env default, which is returned.
+         */
+        delete: {
+            parameters: {
+                query?: never;
+                header?: never;
+                path: {
+                    table: "ip_observations" | "mac_observations" | "discovery_run_logs";
                 };
+                cookie?: never;
             };
+            requestBody?: never;
             responses: {
-                /** @description Imported inventory */
+                /** @description Effective policy after the revert */
                 200: {
                     headers: {
                         [name: string]: unknown;
                     };
                     content: {
-                        "application/json": components["schemas"]["InventoryImportResult"];
-                    };
-                };
-                /** @description Invalid request */
-                400: {
-                    headers: {
-                        [name: string]: unknown;
-                    };
-                    content: {
-                        "application/json": components["schemas"]["ErrorResponse"];
+                        "application/json": components["schemas"]["RetentionPolicy"];
                     };
                 };
-                /** @description Database not configured */
-                503: {
+                /** @description Table is not managed by retention */
+                404: {
                     headers: {
                         [name: string]: unknown;
                     };
@@ -918,69 +2343,44 @@ export interface paths {
                 };
             };
         };
-        delete?: never;
         options?: never;
         head?: never;
         patch?: never;
         trace?: never;
     };
-    "/v1/audit/events": {
+    "/v1/retention/report": {
         parameters: {
             query?: never;
             header?: never;
             path?: never;
             cookie?: never;
         };
-        get?: never;
-        put?: never;
         /**
-         * Record an audit event
-         * @description Minimal audit log write endpoint intended to be called by the UI (which owns auth/sessions).
+         * Dry-run retention report
+         * @description Counts, without deleting, how many rows the retention job would drop or thin right now.
          */
-        post: {
+        get: {
             parameters: {
                 query?: never;
                 header?: never;
                 path?: never;
                 cookie?: never;
             };
-            requestBody: {
-                content: {
-                    "application/json": components["schemas"]["AuditEventCreate"];
-                };
-            };
+            requestBody?: never;
             responses: {
-                /** @description Created */
-                201: {
-                    headers: {
-                        [name: string]: unknown;
-                    };
-                    content: {
-                        "application/json": {
-                            ok?: boolean;
-                        };
-                    };
-                };
-                /** @description Invalid request */
-                400: {
-                    headers: {
-                        [name: string]: unknown;
-                    };
-                    content: {
-                        "application/json": components["schemas"]["ErrorResponse"];
-                    };
-                };
-                /** @description Database not configured */
-                503: {
+                /** @description Report */
+                200: {
                     headers: {
                         [name: string]: unknown;
                     };
                     content: {
-                        "application/json": components["schemas"]["ErrorResponse"];
+                        "application/json": components["schemas"]["RetentionReport"];
                     };
                 };
             };
         };
+        put?: never;
+        post?: never;
         delete?: never;
         options?: never;
         head?: never;
@@ -1015,6 +2415,11 @@ export interface paths {
                     depth?: number;
                     /** @description Optional hard cap hint (the API may clamp further). */
                     limit?: number;
+                    /**
+                     * Format: date-time
+                     * @description Project membership as it was at this instant, echoed in `meta.as_of`; live-only overlays (STP roots, PoE, routing adjacencies, traceroute paths) are omitted. While the retention job runs, instants older than the retained IP/MAC observations are rejected with `earliest_as_of` in the error details.
+                     */
+                    as_of?: string;
                 };
                 header?: never;
                 path: {
@@ -1118,7 +2523,7 @@ export interface components {
         MapNode: {
             /** @description Stable node identifier within the projection. */
             id: string;
-            /** @description Node kind (e.g. device, interface, service). */
+            /** @description Node kind (e.g. device, interface, service, or `vantage` for core-go itself at the start of traced paths). */
             kind: string;
             label?: string | null;
             /** @description Deterministic primary region placement (when applicable). */
@@ -1131,13 +2536,14 @@ export interface components {
         };
         MapEdge: {
             id: string;
-            /** @description Edge kind (layer-defined; edges are rare/intentional). */
+            /** @description Edge kind (layer-defined; edges are rare/intentional). L3 `hop` edges chain traceroute hops in TTL order. */
             kind: string;
             /** @description Source node id. */
             from: string;
             /** @description Target node id. */
             to: string;
             label?: string | null;
+            /** @description Layer-defined edge details. Physical links carry `stp_state`/`stp_blocked` when spanning tree state is known; link aggregates collapse into one edge (`id` `lag:<interface id>`) with `aggregate` and `members[]`. */
             meta?: {
                 [key: string]: unknown;
             } | null;
@@ -1168,6 +2574,10 @@ export interface components {
             edges: components["schemas"]["MapEdge"][];
             inspector?: components["schemas"]["MapInspector"];
             truncation: components["schemas"]["MapTruncation"];
+            /** @description Projection-wide details. Physical and L2 projections list the spanning tree root bridge(s) reported by the projected devices as `stp_roots[]`. L3 subnet projections list the latest traced path toward each overlapping scope as `traceroute_paths[]`. */
+            meta?: {
+                [key: string]: unknown;
+            } | null;
         };
         Device: {
             /** Format: uuid */
@@ -1186,6 +2596,11 @@ export interface components {
              * @description Latest structural change timestamp across known facts (best-effort).
              */
             last_change_at?: string | null;
+            /**
+             * Format: date-time
+             * @description Set while the device is archived.
+             */
+            archived_at?: string | null;
             metadata?: components["schemas"]["DeviceMetadata"];
         };
         DevicePage: {
@@ -1202,6 +2617,9 @@ export interface components {
             services: components["schemas"]["DeviceService"][];
             snmp?: components["schemas"]["DeviceSNMP"];
             links: components["schemas"]["DeviceLink"][];
+            ssh_host_keys: components["schemas"]["DeviceSSHHostKey"][];
+            os_guesses: components["schemas"]["DeviceOSGuess"][];
+            custom_facts: components["schemas"]["DeviceCustomFact"][];
         };
         DeviceIP: {
             ip: string;
@@ -1212,6 +2630,16 @@ export interface components {
             created_at: string;
             /** Format: date-time */
             updated_at: string;
+            /**
+             * Format: date-time
+             * @description When discovery, an import or SNMP last observed the address.
+             */
+            last_seen_at: string;
+            /**
+             * Format: date-time
+             * @description Set once the address has not been seen for the stale window; stale addresses are ignored by matching and maps.
+             */
+            stale_at?: string | null;
         };
         DeviceMAC: {
             mac: string;
@@ -1222,6 +2650,16 @@ export interface components {
             created_at: string;
             /** Format: date-time */
             updated_at: string;
+            /**
+             * Format: date-time
+             * @description When discovery, an import or SNMP last observed the address.
+             */
+            last_seen_at: string;
+            /**
+             * Format: date-time
+             * @description Set once the address has not been seen for the stale window; stale addresses are ignored by matching and maps.
+             */
+            stale_at?: string | null;
         };
         DeviceInterface: {
             /** Format: uuid */
@@ -1249,16 +2687,59 @@ export interface components {
             protocol?: "tcp" | "udp" | null;
             port?: number | null;
             name?: string | null;
-            /** @enum {string|null} */
-            state?: "open" | "closed" | null;
+            /**
+             * @description Last reconciled state; previously open ports become `closed` (refused) or `filtered` (no reply) when a scan no longer sees them.
+             * @enum {string|null}
+             */
+            state?: "open" | "closed" | "filtered" | null;
+            /** @description Scanner that produced the fact (e.g. `nmap`, `udp_probe`). */
             source?: string | null;
+            /** @description Parsed response summary from protocol-aware probes (e.g. `ntp v4 stratum=2`). */
+            summary?: string | null;
             /** Format: date-time */
             observed_at: string;
             /** Format: date-time */
+            first_seen_at?: string | null;
+            /**
+             * Format: date-time
+             * @description Last time the port was observed open.
+             */
+            last_seen_at?: string | null;
+            /**
+             * Format: date-time
+             * @description When the port was last seen transitioning away from `open` (null while open).
+             */
+            closed_at?: string | null;
+            /** @description Product reported by nmap version detection (e.g. `OpenSSH`). */
+            product?: string | null;
+            version?: string | null;
+            extra_info?: string | null;
+            /** @description CPE identifiers reported by version detection. */
+            cpe?: string[];
+            http?: components["schemas"]["DeviceServiceHTTP"];
+            /** Format: date-time */
             created_at: string;
             /** Format: date-time */
             updated_at: string;
         };
+        /** @description HTTP fingerprint of a web service (present once the service has been fetched). */
+        DeviceServiceHTTP: {
+            /** @description Status code of the final same-host response (redirects are followed up to 3 hops). */
+            status?: number | null;
+            title?: string | null;
+            /** @description `Server` response header. */
+            server?: string | null;
+            /** @description `X-Powered-By` response header. */
+            powered_by?: string | null;
+            /**
+             * Format: int32
+             * @description Shodan-compatible favicon hash (MurmurHash3 of the base64-encoded icon).
+             */
+            favicon_hash?: number | null;
+            final_url?: string | null;
+            /** Format: date-time */
+            observed_at: string;
+        };
         DeviceSNMP: {
             address?: string | null;
             sys_name?: string | null;
@@ -1283,31 +2764,205 @@ export interface components {
             /** Format: uuid */
             peer_interface_id?: string | null;
             link_type?: string | null;
+            /** @description `manual`, `lldp`, `cdp`, `inferred` (bridge FDB/ARP inference) or `snmp` (OSPF/BGP adjacencies, `link_type` `ospf` or `bgp`). */
             source: string;
+            /** @description Set for `inferred` links only; observed links are authoritative and replace inferred ones on the same port or device pair. */
+            confidence?: number | null;
+            /** @description Routing adjacency state (OSPF `full`, `two_way`, ...; BGP `established`, `active`, ...). `down` when no polled router reports the adjacency any more. */
+            state?: string | null;
+            /**
+             * Format: int64
+             * @description BGP AS number of this device.
+             */
+            local_as?: number | null;
+            /**
+             * Format: int64
+             * @description BGP AS number of the peer device.
+             */
+            peer_as?: number | null;
+            /** @description OSPF area ID (dotted quad) of the adjacency. */
+            area?: string | null;
+            /**
+             * Format: date-time
+             * @description When the adjacency last changed state (from BGP session timers when available, otherwise when discovery saw the change).
+             */
+            last_change_at?: string | null;
             /** Format: date-time */
             observed_at?: string | null;
             /** Format: date-time */
             updated_at: string;
         };
+        /** @description Current SSH host key per key type, collected via key exchange only (no authentication). */
+        DeviceSSHHostKey: {
+            /** @description Key blob format (e.g. `ssh-ed25519`, `ecdsa-sha2-nistp256`, `ssh-rsa`). */
+            key_type: string;
+            /** @description OpenSSH-style fingerprint (`SHA256:<unpadded base64>`). */
+            fingerprint_sha256: string;
+            /** @description Base64-encoded public key blob (authorized_keys form). */
+            public_key: string;
+            port?: number | null;
+            /** @description Server identification string (e.g. `SSH-2.0-OpenSSH_9.6`). */
+            banner?: string | null;
+            /** @description Other devices that presented the same key (possible duplicate device or moved host). */
+            shared_with_device_ids: string[];
+            /** Format: date-time */
+            first_seen_at: string;
+            /** Format: date-time */
+            last_seen_at: string;
+        };
+        /** @description OS fingerprint match (nmap `-O`), best match first. */
+        DeviceOSGuess: {
+            /** @description Detector that produced the guess (e.g. `nmap`). */
+            source: string;
+            /** @description 1 for the best match. */
+            rank: number;
+            /** @description Match name (e.g. `Linux 5.0 - 5.14`). */
+            name: string;
+            accuracy: number;
+            vendor?: string | null;
+            os_family?: string | null;
+            os_generation?: string | null;
+            /** @description nmap device class (e.g. `general purpose`, `router`, `printer`). */
+            device_type?: string | null;
+            cpe: string[];
+            /** Format: date-time */
+            observed_at: string;
+        };
+        /** @description Value polled from a custom SNMP profile item. Table columns produce one fact per row `instance`. */
+        DeviceCustomFact: {
+            /** Format: uuid */
+            profile_id: string;
+            profile_name: string;
+            key: string;
+            /** @description Row index below the column OID; empty for scalars. */
+            instance: string;
+            label?: string | null;
+            /** @description OID that was polled (column OID plus instance for table rows). */
+            oid: string;
+            /** @enum {string} */
+            value_type: "string" | "integer" | "gauge" | "counter" | "timeticks" | "hex" | "oid";
+            value: string;
+            numeric_value?: number | null;
+            /** Format: date-time */
+            first_seen_at: string;
+            /** Format: date-time */
+            observed_at: string;
+            /** Format: date-time */
+            changed_at: string;
+            /** @description Most recent value changes (newest first, at most 10). */
+            history: components["schemas"]["DeviceCustomFactChange"][];
+        };
+        DeviceCustomFactChange: {
+            value: string;
+            numeric_value?: number | null;
+            /** Format: date-time */
+            observed_at: string;
+        };
+        DeviceArchiveRequest: {
+            /** @description Recorded on the archive event. */
+            actor?: string;
+            reason?: string;
+        };
+        DeviceMergeRequest: {
+            source_ids: string[];
+            /** @description Recorded on the audit event (defaults to `api`). */
+            actor?: string;
+            actor_role?: string;
+        };
+        DeviceMergeResult: {
+            device: components["schemas"]["Device"];
+            merged_ids: string[];
+            /** @description Rows moved onto the survivor per kind (e.g. `ips`, `services`, `links`, `interfaces_deduplicated`). */
+            moved: {
+                [key: string]: number;
+            };
+        };
+        DeviceRef: {
+            /** Format: uuid */
+            id: string;
+            display_name?: string;
+        };
+        DeviceDuplicateEvidence: {
+            /** @enum {string} */
+            signal: "serial" | "ssh_host_key" | "mac" | "interface_mac" | "sys_name" | "name_candidate" | "ip_handoff";
+            weight: number;
+            /** @description Shared values (at most 5 per signal). */
+            values: string[];
+        };
+        DeviceDuplicate: {
+            device_a: components["schemas"]["DeviceRef"];
+            device_b: components["schemas"]["DeviceRef"];
+            score: number;
+            evidence: components["schemas"]["DeviceDuplicateEvidence"][];
+            /** Format: date-time */
+            first_detected_at: string;
+            /** Format: date-time */
+            detected_at: string;
+        };
+        DeviceDuplicateList: {
+            duplicates: components["schemas"]["DeviceDuplicate"][];
+        };
+        DeviceDuplicateDismissRequest: {
+            device_ids: string[];
+            actor?: string;
+            reason?: string;
+        };
+        DeviceDuplicateDismissal: {
+            /** @description The pair in canonical (ascending) order. */
+            device_ids: string[];
+            actor?: string;
+            reason?: string;
+            /** Format: date-time */
+            dismissed_at: string;
+        };
         /** @description Minimal create payload. Additional fields will be added as the data model is finalized. */
         DeviceCreate: {
             display_name?: string;
             metadata?: components["schemas"]["DeviceMetadata"];
+            /** @description Who is making the change; recorded on the resulting device events. */
+            actor?: string;
+            reason?: string;
         };
         /** @description Minimal update payload. Additional fields will be added as the data model is finalized. */
         DeviceUpdate: {
             display_name?: string;
             metadata?: components["schemas"]["DeviceMetadata"];
+            /** @description Who is making the change; recorded on the resulting device events. */
+            actor?: string;
+            reason?: string;
         };
         DeviceNameCandidate: {
             name: string;
-            /** @description Candidate source (e.g. snmp, reverse_dns). */
+            /** @description Candidate source (e.g. snmp, reverse_dns, http_title). */
             source: string;
             /** @description Optional IP address the name was observed on. */
             address?: string;
             /** Format: date-time */
             observed_at: string;
         };
+        PoweredDevice: {
+            /**
+             * Format: uuid
+             * @description The powered device.
+             */
+            device_id: string;
+            display_name?: string;
+            /**
+             * Format: uuid
+             * @description Switch interface delivering power.
+             */
+            interface_id: string;
+            interface_name?: string;
+            admin_enabled?: boolean;
+            /** @enum {string} */
+            detection_status: "disabled" | "searching" | "delivering_power" | "fault" | "test" | "other_fault";
+            /** @enum {string} */
+            power_class?: "class0" | "class1" | "class2" | "class3" | "class4";
+            /** @description Power drawn in milliwatts, when the switch exposes it (CISCO-POWER-ETHERNET-EXT-MIB). */
+            power_mw?: number;
+            /** Format: date-time */
+            observed_at: string;
+        };
         DeviceMetadata: {
             owner?: string;
             location?: string;
@@ -1321,6 +2976,9 @@ export interface components {
         };
         DeviceImportPayload: {
             devices: components["schemas"]["DeviceImport"][];
+            /** @description Who is running the import; recorded on every device event it writes. */
+            actor?: string;
+            reason?: string;
         };
         DeviceImportResult: {
             created?: number;
@@ -1341,6 +2999,16 @@ export interface components {
             metadata_written?: number;
             skipped?: number;
         };
+        ScanImportRequest: {
+            /** @enum {string} */
+            format: "nmap_xml" | "masscan_json" | "arp_scan";
+            /** @description Raw scanner output (max 32 MiB request body, 16384 hosts). */
+            content: string;
+            /** @description Optional CIDR or single IP; hosts outside it are skipped and counted in `stats.import.hosts_skipped`. */
+            scope?: string | null;
+            /** @description Free-form label for where the scan was taken (recorded in `stats.import.origin`). */
+            origin?: string | null;
+        };
         AuditEventCreate: {
             actor: string;
             actor_role?: string | null;
@@ -1391,6 +3059,14 @@ export interface components {
             status: string;
             latest_run?: components["schemas"]["DiscoveryRun"];
         };
+        /** @description `display_name`, `metadata` and `snmp` events come from the append-only device_events table; their details
+         *     carry `actor_type` (user, run, integration, system), `actor`, `run_id`, `reason` and the `before`/`after` values.
+         *     `tags` events come from the same table and hold the whole effective tag set in `before`/`after.tags`.
+         *
+         *     `ip_observation` and `mac_observation` events are sightings, one per address per discovery run, not changes.
+         *     `vlan` and `link` events come from the same table: VLAN membership and link snapshots, one per change, with the
+         *     VLANs or link peers added and removed in the summary and the whole set in `before`/`after.vlans` or
+         *     `before`/`after.links`. */
         DeviceChangeEvent: {
             event_id: string;
             /** Format: uuid */
@@ -1422,6 +3098,242 @@ export interface components {
             logs?: components["schemas"]["DiscoveryRunLogEntry"][];
             cursor?: string | null;
         };
+        Certificate: {
+            /** Format: uuid */
+            id: string;
+            /** Format: uuid */
+            device_id: string;
+            device_display_name?: string | null;
+            /** Format: uuid */
+            service_id: string;
+            protocol?: string | null;
+            port?: number | null;
+            /** @description Current state of the linked service (`open`, `closed`, `filtered`). */
+            service_state?: string | null;
+            /** @description Lowercase hex SHA-256 of the DER-encoded certificate. */
+            fingerprint_sha256: string;
+            subject: string;
+            common_name?: string | null;
+            /** @description Subject alternative names (DNS names, IPs, emails, URIs). */
+            sans: string[];
+            issuer: string;
+            /** @description Lowercase hex serial number. */
+            serial_number: string;
+            /** Format: date-time */
+            not_before: string;
+            /** Format: date-time */
+            not_after: string;
+            /** @description Public key algorithm and size (e.g. `rsa-2048`, `ecdsa-p256`, `ed25519`). */
+            key_type: string;
+            self_signed: boolean;
+            expired: boolean;
+            /** Format: date-time */
+            first_seen_at: string;
+            /** Format: date-time */
+            last_seen_at: string;
+        };
+        CertificatePage: {
+            certificates?: components["schemas"]["Certificate"][];
+            cursor?: string | null;
+        };
+        SNMPProfileItem: {
+            /** @description Fact key, unique within the profile. */
+            key: string;
+            label?: string | null;
+            /** @description Numeric dotted OID (a leading dot is accepted). */
+            oid: string;
+            /**
+             * @description `scalar` is fetched with GET; `column` walks the table column and yields one fact per row.
+             * @default scalar
+             * @enum {string}
+             */
+            kind?: "scalar" | "column";
+            /**
+             * @default string
+             * @enum {string}
+             */
+            type?: "string" | "integer" | "gauge" | "counter" | "timeticks" | "hex" | "oid";
+        };
+        SNMPProfileWrite: {
+            name: string;
+            description?: string | null;
+            /** @description Device tags the profile is bound to. */
+            tags: string[];
+            items: components["schemas"]["SNMPProfileItem"][];
+            /** @default true */
+            enabled?: boolean;
+        };
+        SNMPProfile: {
+            /** Format: uuid */
+            id: string;
+            name: string;
+            description?: string | null;
+            tags: string[];
+            items: components["schemas"]["SNMPProfileItem"][];
+            enabled: boolean;
+            /** Format: date-time */
+            created_at: string;
+            /** Format: date-time */
+            updated_at: string;
+        };
+        SNMPProfileList: {
+            profiles: components["schemas"]["SNMPProfile"][];
+        };
+        RetentionPolicy: {
+            /** @enum {string} */
+            table: "ip_observations" | "mac_observations" | "discovery_run_logs";
+            keep_raw_days: number;
+            keep_daily_days: number;
+            /** @enum {string} */
+            source: "env" | "api";
+            /** @description False when keep_raw_days is 0 and the table is kept forever. */
+            enabled: boolean;
+            /**
+             * Format: date-time
+             * @description When the API override was last set; absent for env defaults.
+             */
+            updated_at?: string;
+        };
+        RetentionPolicyWrite: {
+            keep_raw_days: number;
+            /** @default 0 */
+            keep_daily_days?: number;
+        };
+        RetentionPolicyList: {
+            policies: components["schemas"]["RetentionPolicy"][];
+        };
+        RetentionReportTable: components["schemas"]["RetentionPolicy"] & {
+            /**
+             * Format: date-time
+             * @description Rows older than this are deleted. Absent when the policy is disabled.
+             */
+            drop_before?: string;
+            /**
+             * Format: date-time
+             * @description Rows between drop_before and this are thinned to the first and last per device, address and day. Absent without a daily tier.
+             */
+            thin_before?: string;
+            /** Format: int64 */
+            total_rows: number;
+            /** Format: int64 */
+            rows_to_drop: number;
+            /** Format: int64 */
+            rows_to_thin: number;
+        };
+        RetentionReport: {
+            /** Format: date-time */
+            generated_at: string;
+            tables: components["schemas"]["RetentionReportTable"][];
+        };
+        AvailabilityInterval: {
+            /** @enum {string} */
+            state: "up" | "down" | "unknown";
+            /** Format: date-time */
+            from: string;
+            /** Format: date-time */
+            to: string;
+        };
+        AvailabilityTransition: {
+            /** @enum {string} */
+            state: "up" | "down";
+            /**
+             * @description Absent for the first known state of the device.
+             * @enum {string}
+             */
+            previous_state?: "up" | "down";
+            /** Format: date-time */
+            changed_at: string;
+            /** Format: uuid */
+            run_id?: string;
+            /** @description What the state was derived from, e.g. `source`, `last_seen_at` and `down_after_seconds`. */
+            evidence: {
+                [key: string]: unknown;
+            };
+        };
+        DeviceAvailability: {
+            /** Format: uuid */
+            device_id: string;
+            /** Format: date-time */
+            from: string;
+            /** Format: date-time */
+            to: string;
+            /**
+             * @description State at `to`.
+             * @enum {string}
+             */
+            state: "up" | "down" | "unknown";
+            /** @description Up time as a percentage of the known (up + down) time in the window; null when none is known. */
+            uptime_percent: number | null;
+            /** Format: int64 */
+            up_seconds: number;
+            /** Format: int64 */
+            down_seconds: number;
+            /** Format: int64 */
+            unknown_seconds: number;
+            intervals: components["schemas"]["AvailabilityInterval"][];
+            /** @description Transitions inside the window, oldest first. */
+            transitions: components["schemas"]["AvailabilityTransition"][];
+        };
+        DeviceMonitor: {
+            /** Format: uuid */
+            device_id: string;
+            /**
+             * @description `tag` monitors are synced from `MONITOR_TAGS`; saving through the API makes them manual.
+             * @enum {string}
+             */
+            source: "manual" | "tag";
+            enabled: boolean;
+            /** @enum {string} */
+            method: "icmp" | "tcp";
+            /** @description TCP port to connect to. Null uses the lowest open TCP service, or ICMP when there is none. */
+            port: number | null;
+            /** Format: date-time */
+            last_probe_at: string | null;
+            /** Format: date-time */
+            last_success_at: string | null;
+            last_rtt_ms: number | null;
+            consecutive_failures: number;
+            /** Format: date-time */
+            created_at: string;
+            /** Format: date-time */
+            updated_at: string;
+        };
+        DeviceMonitorUpdate: {
+            /**
+             * @default icmp
+             * @enum {string}
+             */
+            method?: "icmp" | "tcp";
+            /** @description Only valid with `tcp`. */
+            port?: number;
+            /** @default true */
+            enabled?: boolean;
+        };
+        ReachabilityPoint: {
+            /**
+             * Format: date-time
+             * @description Probe time, or the start of the hour for hourly points.
+             */
+            at: string;
+            probes: number;
+            failures: number;
+            loss_percent: number;
+            /** @description Over successful probes; null when none succeeded. */
+            rtt_avg_ms: number | null;
+            rtt_min_ms: number | null;
+            rtt_max_ms: number | null;
+        };
+        DeviceReachability: {
+            /** Format: uuid */
+            device_id: string;
+            /** Format: date-time */
+            from: string;
+            /** Format: date-time */
+            to: string;
+            /** @enum {string} */
+            resolution: "raw" | "hour";
+            points: components["schemas"]["ReachabilityPoint"][];
+        };
         ErrorResponse: {
             error: {
                 code: string;